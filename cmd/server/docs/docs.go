@@ -413,66 +413,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/rent-actions": {
-            "post": {
-                "description": "Creates a new rent action (reservation).",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "RentActions"
-                ],
-                "summary": "Create Rent Action",
-                "parameters": [
-                    {
-                        "description": "Rent Action Data",
-                        "name": "rent_action",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.RentAction"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/domain.RentAction"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid request",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/rent-actions/{id}": {
+        "/logistics/reservations/{id}/transitions": {
             "get": {
-                "description": "Retrieves a rent action by its ID.",
+                "description": "Returns the reservation's status, every action a user may request from it (with the reason a guard refuses it, if any) and its transition history.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "RentActions"
+                    "Logistics"
                 ],
-                "summary": "Get Rent Action",
+                "summary": "Get Reservation Transitions",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Rent Action ID",
+                        "description": "Reservation ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -482,7 +436,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.RentAction"
+                            "$ref": "#/definitions/domain.ReservationLifecycle"
                         }
                     },
                     "404": {
@@ -490,191 +444,48 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
                     }
                 }
-            }
-        },
-        "/rent-actions/{id}/approve": {
+            },
             "post": {
-                "description": "Transitions a rent action from Pending to Approved. Checks availability.",
-                "tags": [
-                    "RentActions"
+                "description": "Submits, approves, rejects or cancels a reservation. Approval re-checks availability and allocates assets.",
+                "consumes": [
+                    "application/json"
                 ],
-                "summary": "Approve Rent Action",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Rent Action ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                "produces": [
+                    "application/json"
                 ],
-                "responses": {
-                    "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid State Transition",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "409": {
-                        "description": "Insufficient Inventory",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/rent-actions/{id}/cancel": {
-            "post": {
-                "description": "Cancels a rent action.",
                 "tags": [
-                    "RentActions"
+                    "Logistics"
                 ],
-                "summary": "Cancel Rent Action",
+                "summary": "Transition Rental Reservation",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Rent Action ID",
+                        "description": "Reservation ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "type": "string"
-                        }
                     },
-                    "400": {
-                        "description": "Invalid State Transition",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/rent-actions/{id}/reject": {
-            "post": {
-                "description": "Transitions a rent action from Pending to Rejected.",
-                "tags": [
-                    "RentActions"
-                ],
-                "summary": "Reject Rent Action",
-                "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Rent Action ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid State Transition",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                        "description": "Action",
+                        "name": "transition",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/domain.ReservationTransitionRequest"
                         }
                     }
-                }
-            }
-        },
-        "/rent-actions/{id}/submit": {
-            "post": {
-                "description": "Transitions a rent action from Draft to Pending.",
-                "tags": [
-                    "RentActions"
-                ],
-                "summary": "Submit Rent Action",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Rent Action ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid State Transition",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/domain.ReservationTransitionRecord"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Transition not allowed or insufficient inventory",
                         "schema": {
                             "type": "string"
                         }
@@ -706,6 +517,23 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.AllowedTransition": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/domain.ReservationAction"
+                },
+                "allowed": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "$ref": "#/definitions/domain.RentalReservationStatus"
+                }
+            }
+        },
         "domain.Asset": {
             "type": "object",
             "properties": {
@@ -885,128 +713,110 @@ const docTemplate = `{
                 "ProvisioningReady"
             ]
         },
-        "domain.RentAction": {
+        "domain.RentalReservationStatus": {
+            "type": "string",
+            "enum": [
+                "ReservationDraft",
+                "ReservationPending",
+                "ReservationConfirmed",
+                "ReservationCancelled",
+                "ReservationPartiallyFulfilled",
+                "ReservationFulfilled"
+            ],
+            "x-enum-varnames": [
+                "ReservationStatusDraft",
+                "ReservationStatusPending",
+                "ReservationStatusConfirmed",
+                "ReservationStatusCancelled",
+                "ReservationStatusPartiallyFulfilled",
+                "ReservationStatusFulfilled"
+            ]
+        },
+        "domain.ReservationAction": {
+            "type": "string",
+            "enum": [
+                "submit",
+                "approve",
+                "reject",
+                "cancel",
+                "requeue",
+                "partially_fulfill",
+                "fulfill",
+                "reopen"
+            ],
+            "x-enum-varnames": [
+                "ReservationActionSubmit",
+                "ReservationActionApprove",
+                "ReservationActionReject",
+                "ReservationActionCancel",
+                "ReservationActionRequeue",
+                "ReservationActionPartiallyFulfill",
+                "ReservationActionFulfill",
+                "ReservationActionReopen"
+            ]
+        },
+        "domain.ReservationLifecycle": {
             "type": "object",
             "properties": {
-                "approved_at": {
-                    "type": "string"
-                },
-                "approved_by_ref": {
-                    "type": "string"
-                },
-                "cancelled_at": {
-                    "type": "string"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "created_by_ref": {
-                    "type": "string"
-                },
-                "description": {
-                    "description": "Maps to schema.org/description",
-                    "type": "string"
-                },
-                "end_time": {
-                    "description": "Maps to schema.org/endTime",
-                    "type": "string"
-                },
-                "external_ref": {
-                    "type": "string"
-                },
-                "external_source": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "is_asap": {
-                    "type": "boolean"
-                },
-                "items": {
+                "allowed": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/domain.RentActionItem"
+                        "$ref": "#/definitions/domain.AllowedTransition"
                     }
                 },
-                "metadata": {
-                    "type": "string",
-                    "example": "{}"
-                },
-                "priority": {
-                    "type": "string"
-                },
-                "rejected_at": {
-                    "type": "string"
-                },
-                "requester_ref": {
-                    "type": "string"
-                },
-                "schema_org": {
-                    "type": "string",
-                    "example": "{}"
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ReservationTransitionRecord"
+                    }
                 },
-                "start_time": {
-                    "description": "Maps to schema.org/startTime",
-                    "type": "string"
+                "reservationId": {
+                    "type": "integer"
                 },
                 "status": {
-                    "$ref": "#/definitions/domain.RentActionStatus"
-                },
-                "updated_at": {
-                    "type": "string"
+                    "$ref": "#/definitions/domain.RentalReservationStatus"
                 }
             }
         },
-        "domain.RentActionItem": {
+        "domain.ReservationTransitionRecord": {
             "type": "object",
             "properties": {
-                "allocated_quantity": {
-                    "type": "integer"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "item_id": {
-                    "type": "integer"
+                "action": {
+                    "$ref": "#/definitions/domain.ReservationAction"
                 },
-                "item_kind": {
-                    "description": "'item_type','kit_template'",
+                "createdAt": {
                     "type": "string"
                 },
-                "metadata": {
-                    "type": "string",
-                    "example": "{}"
+                "fromStatus": {
+                    "$ref": "#/definitions/domain.RentalReservationStatus"
+                },
+                "id": {
+                    "type": "integer"
                 },
-                "notes": {
+                "reason": {
                     "type": "string"
                 },
-                "rent_action_id": {
+                "reservationId": {
                     "type": "integer"
                 },
-                "requested_quantity": {
+                "toStatus": {
+                    "$ref": "#/definitions/domain.RentalReservationStatus"
+                },
+                "userId": {
                     "type": "integer"
                 }
             }
         },
-        "domain.RentActionStatus": {
-            "type": "string",
-            "enum": [
-                "draft",
-                "pending",
-                "approved",
-                "rejected",
-                "cancelled",
-                "fulfilled"
-            ],
-            "x-enum-varnames": [
-                "RentActionStatusDraft",
-                "RentActionStatusPending",
-                "RentActionStatusApproved",
-                "RentActionStatusRejected",
-                "RentActionStatusCancelled",
-                "RentActionStatusFulfilled"
-            ]
+        "domain.ReservationTransitionRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/domain.ReservationAction"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
         },
         "domain.User": {
             "type": "object",
@@ -10,6 +10,7 @@ import (
 
 	_ "github.com/desmond/rental-management-system/cmd/server/docs" // Setup for Swagger docs
 	"github.com/desmond/rental-management-system/internal/api"
+	"github.com/desmond/rental-management-system/internal/carrier"
 	"github.com/desmond/rental-management-system/internal/db"
 	"github.com/desmond/rental-management-system/internal/domain"
 	"github.com/desmond/rental-management-system/internal/fleet"
@@ -124,6 +125,17 @@ func main() {
 	restMgr := fleet.NewRESTRemoteManager("https://api.remote-monitoring.test", "monitoring-secret")
 	registry.Register("generic-rest-utility", restMgr)
 
+	// Carrier Setup
+	carriers := carrier.NewRegistry()
+	carriers.Register("stub", carrier.NewStubCarrier("stub", os.Getenv("CARRIER_STUB_DIR")))
+	if apiURL := os.Getenv("CARRIER_API_URL"); apiURL != "" {
+		name := os.Getenv("CARRIER_API_NAME")
+		if name == "" {
+			name = "rest"
+		}
+		carriers.Register(name, carrier.NewRESTCarrier(name, apiURL, os.Getenv("CARRIER_API_KEY")))
+	}
+
 	// Third-Party Integration Setup
 	is := integration.NewIntegrationService()
 	restProv := integration.NewRESTProvider("External-CRM", "https://api.external-crm.test", "secret-key", repo)
@@ -142,7 +154,29 @@ func main() {
 	ingestWorker := worker.NewIngestWorker(repo)
 	go ingestWorker.Start(context.Background(), 1*time.Minute)
 
+	waitlistWorker := worker.NewWaitlistWorker(repo)
+	go waitlistWorker.Start(context.Background(), 1*time.Minute)
+
+	invoiceWorker := worker.NewInvoiceWorker(repo, 30*24*time.Hour)
+	go invoiceWorker.Start(context.Background(), 1*time.Hour)
+
+	overdueSteps := domain.DefaultOverdueEscalations
+	if v := os.Getenv("OVERDUE_ESCALATIONS"); v != "" {
+		steps, err := domain.ParseEscalationSteps(v)
+		if err != nil {
+			log.Printf("Warning: Ignoring OVERDUE_ESCALATIONS: %v", err)
+		} else {
+			overdueSteps = steps
+		}
+	}
+	overdueWorker := worker.NewOverdueWorker(repo, overdueSteps)
+	go overdueWorker.Start(context.Background(), 5*time.Minute)
+
+	trackingWorker := worker.NewTrackingWorker(repo, carriers)
+	go trackingWorker.Start(context.Background(), 15*time.Minute)
+
 	handler := api.NewHandler(repo, registry)
+	handler.SetCarrierRegistry(carriers)
 	router := api.NewRouter(handler)
 
 	// Swagger UI
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/carrier"
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// SetCarrierRegistry gives the handler the carriers it can quote, label and track with.
+func (h *Handler) SetCarrierRegistry(carriers *carrier.Registry) {
+	h.carriers = carriers
+}
+
+func (h *Handler) getCarrier(name string) (domain.Carrier, error) {
+	if h.carriers == nil {
+		return nil, errors.New("no carriers configured")
+	}
+	if name == "" {
+		return nil, errors.New("carrier is required")
+	}
+	return h.carriers.Get(name)
+}
+
+// fillRateAddresses completes a rate request with the postal addresses of its places.
+func (h *Handler) fillRateAddresses(r *http.Request, req *domain.RateRequest) error {
+	for _, side := range []struct {
+		placeID int64
+		addr    **domain.PostalAddress
+	}{{req.FromPlaceID, &req.From}, {req.ToPlaceID, &req.To}} {
+		if *side.addr != nil || side.placeID == 0 {
+			continue
+		}
+		p, err := h.repo.GetPlace(r.Context(), side.placeID)
+		if err != nil {
+			return err
+		}
+		if p != nil {
+			*side.addr = p.Address
+		}
+	}
+	return nil
+}
+
+// shipmentFromPath loads the shipment a /v1/logistics/shipments/{id}{suffix} path names,
+// writing the error response itself when it cannot.
+func (h *Handler) shipmentFromPath(w http.ResponseWriter, r *http.Request, suffix string) (*domain.Shipment, bool) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid shipment id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	shipment, err := h.repo.GetShipmentByID(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get shipment: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if shipment == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return nil, false
+	}
+	return shipment, true
+}
+
+// QuoteRates asks one carrier, or every registered carrier, to price a shipment.
+func (h *Handler) QuoteRates(w http.ResponseWriter, r *http.Request) {
+	var req domain.RateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if h.carriers == nil {
+		http.Error(w, "no carriers configured", http.StatusServiceUnavailable)
+		return
+	}
+	names := h.carriers.Names()
+	if req.Carrier != "" {
+		names = []string{req.Carrier}
+	}
+	if err := h.fillRateAddresses(r, &req); err != nil {
+		log.Printf("failed to load places for rate request: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	quotes := []domain.RateQuote{}
+	var lastErr error
+	for _, name := range names {
+		c, err := h.getCarrier(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q, err := c.QuoteRates(r.Context(), &req)
+		if err != nil {
+			log.Printf("carrier %s failed to quote rates: %v", name, err)
+			lastErr = err
+			continue
+		}
+		for i := range q {
+			q[i].Carrier = name
+		}
+		quotes = append(quotes, q...)
+	}
+	if len(quotes) == 0 && lastErr != nil {
+		http.Error(w, lastErr.Error(), http.StatusBadGateway)
+		return
+	}
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Amount < quotes[j].Amount })
+
+	json.NewEncoder(w).Encode(quotes)
+}
+
+// CreateShipmentLabel buys a label for a shipment and adopts its tracking number. The
+// route's first and last places are used when the request names none.
+func (h *Handler) CreateShipmentLabel(w http.ResponseWriter, r *http.Request) {
+	shipment, ok := h.shipmentFromPath(w, r, "/label")
+	if !ok {
+		return
+	}
+
+	var req domain.LabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if shipment.Label != nil {
+		http.Error(w, domain.ErrLabelExists.Error(), http.StatusConflict)
+		return
+	}
+	if req.Carrier == "" {
+		req.Carrier = shipment.Carrier
+	}
+	c, err := h.getCarrier(req.Carrier)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.ShipmentID = shipment.ID
+	if req.ShipDate.IsZero() {
+		req.ShipDate = shipment.ShipDate
+	}
+	if n := len(shipment.Legs); n > 0 {
+		if req.FromPlaceID == 0 {
+			req.FromPlaceID = shipment.Legs[0].FromPlaceID
+		}
+		if req.ToPlaceID == 0 {
+			req.ToPlaceID = shipment.Legs[n-1].ToPlaceID
+		}
+	}
+	if err := h.fillRateAddresses(r, &req.RateRequest); err != nil {
+		log.Printf("failed to load places for label request: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	label, err := c.CreateLabel(r.Context(), &req)
+	if err != nil {
+		log.Printf("carrier %s failed to create label for shipment %d: %v", req.Carrier, shipment.ID, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	label.ShipmentID = shipment.ID
+	label.Carrier = req.Carrier
+
+	if err := h.repo.RecordShipmentLabel(r.Context(), label); err != nil {
+		// The label is paid for but not ours; give it back rather than leak it
+		if verr := c.VoidLabel(r.Context(), label.TrackingNumber); verr != nil {
+			log.Printf("failed to void orphaned label %s: %v", label.TrackingNumber, verr)
+		}
+		if errors.Is(err, domain.ErrLabelExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to record shipment label: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(label)
+}
+
+// VoidShipmentLabel voids a shipment's label with its carrier before it is picked up.
+func (h *Handler) VoidShipmentLabel(w http.ResponseWriter, r *http.Request) {
+	shipment, ok := h.shipmentFromPath(w, r, "/label")
+	if !ok {
+		return
+	}
+	if shipment.Label == nil {
+		http.Error(w, domain.ErrNoActiveLabel.Error(), http.StatusConflict)
+		return
+	}
+	if shipment.Dispatched() {
+		http.Error(w, "shipment has already been dispatched", http.StatusConflict)
+		return
+	}
+	c, err := h.getCarrier(shipment.Label.Carrier)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.VoidLabel(r.Context(), shipment.Label.TrackingNumber); err != nil {
+		log.Printf("carrier %s failed to void label %s: %v", shipment.Label.Carrier, shipment.Label.TrackingNumber, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := h.repo.VoidShipmentLabel(r.Context(), shipment.Label.ID); err != nil {
+		if errors.Is(err, domain.ErrNoActiveLabel) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to void shipment label: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTrackingEvents returns a shipment's tracking history.
+func (h *Handler) ListTrackingEvents(w http.ResponseWriter, r *http.Request) {
+	shipment, ok := h.shipmentFromPath(w, r, "/tracking")
+	if !ok {
+		return
+	}
+
+	events, err := h.repo.ListTrackingEvents(r.Context(), shipment.ID)
+	if err != nil {
+		log.Printf("failed to list tracking events: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(events)
+}
+
+// RecordTrackingEvents accepts tracking events pushed by a carrier webhook or entered
+// by hand, and returns the shipment with its updated status.
+func (h *Handler) RecordTrackingEvents(w http.ResponseWriter, r *http.Request) {
+	shipment, ok := h.shipmentFromPath(w, r, "/tracking")
+	if !ok {
+		return
+	}
+
+	var events []domain.TrackingEvent
+	if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, e := range events {
+		if e.Code == "" {
+			http.Error(w, "every event needs a code", http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.writeTrackedShipment(w, r, shipment.ID, events)
+}
+
+// RefreshShipmentTracking polls the shipment's carrier for tracking now rather than
+// waiting for the tracking worker.
+func (h *Handler) RefreshShipmentTracking(w http.ResponseWriter, r *http.Request) {
+	shipment, ok := h.shipmentFromPath(w, r, "/tracking/refresh")
+	if !ok {
+		return
+	}
+	if shipment.TrackingNumber == "" {
+		http.Error(w, "shipment has no tracking number", http.StatusConflict)
+		return
+	}
+	c, err := h.getCarrier(shipment.Carrier)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := c.GetTrackingEvents(r.Context(), shipment.TrackingNumber)
+	if err != nil {
+		log.Printf("carrier %s failed to return tracking for %s: %v", shipment.Carrier, shipment.TrackingNumber, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	h.writeTrackedShipment(w, r, shipment.ID, events)
+}
+
+func (h *Handler) writeTrackedShipment(w http.ResponseWriter, r *http.Request, shipmentID int64, events []domain.TrackingEvent) {
+	updated, err := h.repo.RecordTrackingEvents(r.Context(), shipmentID, events)
+	if err != nil {
+		log.Printf("failed to record tracking events: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if updated == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(updated)
+}
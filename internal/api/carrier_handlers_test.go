@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/desmond/rental-management-system/internal/carrier"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ShipmentLabelAndTracking(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+	stub := carrier.NewStubCarrier("stub", "")
+	carriers := carrier.NewRegistry()
+	carriers.Register("stub", stub)
+	h.SetCarrierRegistry(carriers)
+
+	shipment := &domain.Shipment{ID: 42, Status: domain.DeliveryPreparing, Direction: "outbound",
+		Legs: []domain.ShipmentLeg{{FromPlaceID: 1, ToPlaceID: 2}, {FromPlaceID: 2, ToPlaceID: 3}}}
+	repo.On("GetShipmentByID", mock.Anything, int64(42)).Return(shipment, nil)
+	repo.On("RecordShipmentLabel", mock.Anything, mock.MatchedBy(func(l *domain.ShippingLabel) bool {
+		return l.ShipmentID == 42 && l.Carrier == "stub" && l.TrackingNumber != ""
+	})).Return(nil)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"carrier": "stub", "service": "express", "parcels": []domain.Parcel{{WeightGrams: 1200}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/shipments/42/label", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.CreateShipmentLabel(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var label domain.ShippingLabel
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &label))
+	assert.Equal(t, "express", label.Service)
+
+	// Refreshing pulls the carrier's events into the shipment
+	stub.Push(label.TrackingNumber, domain.TrackingEvent{Code: domain.TrackingPickedUp})
+	shipment.Carrier, shipment.TrackingNumber = "stub", label.TrackingNumber
+	repo.On("RecordTrackingEvents", mock.Anything, int64(42), mock.MatchedBy(func(events []domain.TrackingEvent) bool {
+		return len(events) == 2 && events[1].Code == domain.TrackingPickedUp
+	})).Return(&domain.Shipment{ID: 42, Status: domain.DeliveryShipped}, nil)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/shipments/42/tracking/refresh", nil)
+	w = httptest.NewRecorder()
+	h.RefreshShipmentTracking(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A dispatched shipment's label cannot be voided
+	shipment.Status = domain.DeliveryShipped
+	shipment.Label = &label
+	req = httptest.NewRequest(http.MethodDelete, "/v1/logistics/shipments/42/label", nil)
+	w = httptest.NewRecorder()
+	h.VoidShipmentLabel(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_QuoteRates(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	body, _ := json.Marshal(domain.RateRequest{Parcels: []domain.Parcel{{WeightGrams: 500}}})
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/rates", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.QuoteRates(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	carriers := carrier.NewRegistry()
+	carriers.Register("stub", carrier.NewStubCarrier("stub", ""))
+	h.SetCarrierRegistry(carriers)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/rates", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	h.QuoteRates(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var quotes []domain.RateQuote
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &quotes))
+	require.Len(t, quotes, 2)
+	assert.Equal(t, "ground", quotes[0].Service)
+	assert.LessOrEqual(t, quotes[0].Amount, quotes[1].Amount)
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// CreateClaim opens a damage or loss claim.
+// @Summary Create Claim
+// @Description Damage claims reference the inspection that recorded the damage and pick up its photos as evidence; loss claims reference an asset that is still checked out.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param claim body domain.Claim true "Claim"
+// @Success 201 {object} domain.Claim
+// @Failure 422 {string} string "No inspection damage or outstanding check-out backs the claim"
+// @Router /logistics/claims [post]
+func (h *Handler) CreateClaim(w http.ResponseWriter, r *http.Request) {
+	var c domain.Claim
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateClaim(r.Context(), &c, h.getUserIDFromContext(r)); err != nil {
+		if errors.Is(err, domain.ErrInvalidClaim) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// ListClaims lists claims, newest first.
+// @Summary List Claims
+// @Tags Logistics
+// @Produce json
+// @Param reservation_id query int false "Reservation ID"
+// @Param asset_id query int false "Asset ID"
+// @Param status query string false "open, under_review, settled or denied"
+// @Success 200 {array} domain.Claim
+// @Router /logistics/claims [get]
+func (h *Handler) ListClaims(w http.ResponseWriter, r *http.Request) {
+	filter := domain.ClaimFilter{Status: domain.ClaimStatus(r.URL.Query().Get("status"))}
+	for param, dst := range map[string]**int64{"reservation_id": &filter.ReservationID, "asset_id": &filter.AssetID} {
+		v := r.URL.Query().Get(param)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid "+param, http.StatusBadRequest)
+			return
+		}
+		*dst = &id
+	}
+
+	claims, err := h.repo.ListClaims(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claims)
+}
+
+// GetClaim returns a claim with its evidence.
+// @Summary Get Claim
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Claim ID"
+// @Success 200 {object} domain.Claim
+// @Failure 404 {string} string "Not Found"
+// @Router /logistics/claims/{id} [get]
+func (h *Handler) GetClaim(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/claims/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.repo.GetClaim(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// AddClaimEvidence attaches a photo URL or note to a claim.
+// @Summary Add Claim Evidence
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Claim ID"
+// @Param evidence body domain.ClaimEvidence true "Evidence"
+// @Success 201 {object} domain.ClaimEvidence
+// @Failure 404 {string} string "Not Found"
+// @Router /logistics/claims/{id}/evidence [post]
+func (h *Handler) AddClaimEvidence(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/claims/")
+	idStr = strings.TrimSuffix(idStr, "/evidence")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var ev domain.ClaimEvidence
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if ev.URL == "" && ev.Note == "" {
+		http.Error(w, "url or note is required", http.StatusBadRequest)
+		return
+	}
+	ev.ClaimID = id
+	ev.InspectionResponseID = nil
+	ev.AddedByUserID = h.getUserIDFromContext(r)
+
+	if err := h.repo.AddClaimEvidence(r.Context(), &ev); err != nil {
+		if errors.Is(err, domain.ErrInvalidClaim) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ev)
+}
+
+// UpdateClaimStatus moves a claim to review, settlement or denial.
+// @Summary Update Claim Status
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Claim ID"
+// @Param update body domain.ClaimStatusUpdate true "New status"
+// @Success 200 {object} domain.Claim
+// @Failure 409 {string} string "Transition not allowed"
+// @Router /logistics/claims/{id}/status [post]
+func (h *Handler) UpdateClaimStatus(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/claims/")
+	idStr = strings.TrimSuffix(idStr, "/status")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var upd domain.ClaimStatusUpdate
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.repo.UpdateClaimStatus(r.Context(), id, &upd, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClaim) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultConflictHorizon is how far ahead the earliest window that fits is searched for.
+const defaultConflictHorizon = 90 * 24 * time.Hour
+
+// GetReservationConflicts explains why a reservation does not fit its window.
+// @Summary Reservation Conflicts
+// @Description For each demand, lists the confirmed reservations, holds and deployed or maintenance assets using the inventory in the reservation's window, and the earliest window of the same length in which the whole reservation would fit.
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Param horizon_days query int false "How many days ahead to search for a window that fits (default 90, max 365)"
+// @Success 200 {object} domain.ReservationConflicts
+// @Failure 404 {string} string "Not Found"
+// @Router /logistics/reservations/{id}/conflicts [get]
+func (h *Handler) GetReservationConflicts(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/conflicts")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	horizon := defaultConflictHorizon
+	if v := r.URL.Query().Get("horizon_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 || days > 365 {
+			http.Error(w, "horizon_days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		horizon = time.Duration(days) * 24 * time.Hour
+	}
+
+	conflicts, err := h.repo.GetReservationConflicts(r.Context(), id, horizon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if conflicts == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conflicts)
+}
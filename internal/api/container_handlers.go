@@ -0,0 +1,314 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// CreateContainer registers a case, pallet or crate.
+func (h *Handler) CreateContainer(w http.ResponseWriter, r *http.Request) {
+	var c domain.Container
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := c.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateContainer(r.Context(), &c); err != nil {
+		if errors.Is(err, domain.ErrContainer) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to create container: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// ListContainers lists containers, optionally those at one place.
+func (h *Handler) ListContainers(w http.ResponseWriter, r *http.Request) {
+	var placeID *int64
+	if v := r.URL.Query().Get("place_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid place_id", http.StatusBadRequest)
+			return
+		}
+		placeID = &id
+	}
+
+	containers, err := h.repo.ListContainers(r.Context(), placeID)
+	if err != nil {
+		log.Printf("failed to list containers: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(containers)
+}
+
+// containerID parses the id out of /v1/logistics/containers/{id}{suffix}.
+func containerID(w http.ResponseWriter, r *http.Request, suffix string) (int64, bool) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/containers/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid container id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeContainer writes the outcome of a container operation.
+func writeContainer(w http.ResponseWriter, c *domain.Container, err error, action string) {
+	if err != nil {
+		if errors.Is(err, domain.ErrContainer) || errors.Is(err, domain.ErrHoldConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to %s container: %v", action, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(c)
+}
+
+// GetContainer returns a container with everything nested in it.
+func (h *Handler) GetContainer(w http.ResponseWriter, r *http.Request) {
+	id, ok := containerID(w, r, "")
+	if !ok {
+		return
+	}
+
+	c, err := h.repo.GetContainer(r.Context(), id)
+	writeContainer(w, c, err, "get")
+}
+
+// UpdateContainerContents puts assets and containers into a container (POST) or takes
+// them out (DELETE).
+func (h *Handler) UpdateContainerContents(w http.ResponseWriter, r *http.Request) {
+	id, ok := containerID(w, r, "/contents")
+	if !ok {
+		return
+	}
+
+	var req domain.ContainerContentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.AssetIDs) == 0 && len(req.ContainerIDs) == 0 {
+		http.Error(w, "assetIds or containerIds is required", http.StatusBadRequest)
+		return
+	}
+
+	var c *domain.Container
+	var err error
+	if r.Method == http.MethodDelete {
+		c, err = h.repo.RemoveFromContainer(r.Context(), id, &req)
+	} else {
+		c, err = h.repo.AddToContainer(r.Context(), id, &req)
+	}
+	writeContainer(w, c, err, "update")
+}
+
+// MoveContainer checks a container and all its contents out to a reservation, or
+// returns them, depending on the path.
+func (h *Handler) MoveContainer(w http.ResponseWriter, r *http.Request) {
+	returning := strings.HasSuffix(r.URL.Path, "/return")
+	suffix := "/check-out"
+	if returning {
+		suffix = "/return"
+	}
+	id, ok := containerID(w, r, suffix)
+	if !ok {
+		return
+	}
+
+	var req domain.ContainerMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ReservationID == 0 {
+		http.Error(w, "reservationId is required", http.StatusBadRequest)
+		return
+	}
+
+	agentIDVal := h.getUserIDFromContext(r)
+	if agentIDVal == nil {
+		http.Error(w, "agent id missing from context", http.StatusUnauthorized)
+		return
+	}
+
+	var c *domain.Container
+	var err error
+	if returning {
+		c, err = h.repo.ReturnContainer(r.Context(), id, &req, *agentIDVal)
+	} else {
+		c, err = h.repo.CheckOutContainer(r.Context(), id, &req, *agentIDVal)
+	}
+	writeContainer(w, c, err, "move")
+}
+
+// PackShipment records a scanned asset or container tag while packing a shipment and
+// returns the updated manifest.
+func (h *Handler) PackShipment(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
+	idStr = strings.TrimSuffix(idStr, "/pack")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	var scan domain.PackScan
+	if err := json.NewDecoder(r.Body).Decode(&scan); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	scan.Code = strings.TrimSpace(scan.Code)
+	if scan.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	agentIDVal := h.getUserIDFromContext(r)
+	if agentIDVal == nil {
+		http.Error(w, "agent id missing from context", http.StatusUnauthorized)
+		return
+	}
+
+	m, err := h.repo.PackShipment(r.Context(), id, &scan, *agentIDVal)
+	if err != nil {
+		if errors.Is(err, domain.ErrContainer) || errors.Is(err, domain.ErrHoldConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to pack shipment: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if m == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(m)
+}
+
+// GetShipmentManifest returns a shipment's packing manifest as JSON, CSV or printable
+// HTML, chosen by the format query parameter.
+func (h *Handler) GetShipmentManifest(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
+	idStr = strings.TrimSuffix(idStr, "/manifest")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.repo.GetShipmentManifest(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get shipment manifest: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if m == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		json.NewEncoder(w).Encode(m)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("manifest-%d.csv", m.ShipmentID)))
+		writeManifestCSV(w, m)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := manifestHTML.Execute(w, m); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	default:
+		http.Error(w, "format must be json, csv or html", http.StatusBadRequest)
+	}
+}
+
+func writeManifestCSV(w io.Writer, m *domain.Manifest) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"shipment", "container", "asset_id", "asset_tag", "serial_number", "item_type", "packed"})
+	shipment := strconv.FormatInt(m.ShipmentID, 10)
+	for _, row := range m.Rows() {
+		cw.Write([]string{shipment, row.ContainerPath, strconv.FormatInt(row.Asset.AssetID, 10), row.Asset.AssetTag,
+			row.Asset.SerialNumber, row.Asset.ItemTypeName, strconv.FormatBool(row.Packed)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var manifestHTML = template.Must(template.New("manifest").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Manifest for shipment #{{.ShipmentID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+.container { border: 1px solid #999; padding: 8px 12px; margin: 10px 0; page-break-inside: avoid; }
+.container .container { margin-left: 1.5em; }
+h3 { margin: 0 0 6px; }
+table { width: 100%; border-collapse: collapse; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; }
+.unpacked { color: #a00; }
+@media print { body { margin: 0; } @page { margin: 1.5cm; } }
+</style>
+</head>
+<body>
+<h1>Packing manifest: shipment #{{.ShipmentID}}</h1>
+<p>{{.Direction}}{{if .Carrier}} via {{.Carrier}}{{end}}{{if .TrackingNumber}}, tracking {{.TrackingNumber}}{{end}}<br>
+Status: {{.Status}}<br>
+Packed {{.PackedCount}} of {{.TotalCount}} assets, as of {{date .GeneratedAt}}</p>
+{{define "assets"}}{{if .}}<table>
+<thead><tr><th>Tag</th><th>Serial</th><th>Item type</th></tr></thead>
+<tbody>
+{{range .}}<tr><td>{{.AssetTag}}</td><td>{{.SerialNumber}}</td><td>{{.ItemTypeName}}</td></tr>
+{{end}}</tbody>
+</table>{{end}}{{end}}
+{{define "container"}}<div class="container">
+<h3>{{.Kind}} {{.Tag}}: {{.Name}}</h3>
+{{template "assets" .Assets}}
+{{range .Containers}}{{template "container" .}}{{end}}
+</div>{{end}}
+{{range .Containers}}{{template "container" .}}{{end}}
+{{if .Loose}}<h2>Loose</h2>
+{{template "assets" .Loose}}{{end}}
+{{if .Unpacked}}<h2 class="unpacked">Not yet packed</h2>
+{{template "assets" .Unpacked}}{{end}}
+</body>
+</html>
+`))
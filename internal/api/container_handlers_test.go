@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_MoveContainer(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	venue := int64(8)
+	moveReq := &domain.ContainerMoveRequest{ReservationID: 5, ToLocationID: &venue}
+	repo.On("CheckOutContainer", mock.Anything, int64(3), moveReq, int64(1)).
+		Return(&domain.Container{ID: 3, PlaceID: &venue}, nil)
+	repo.On("ReturnContainer", mock.Anything, int64(3), moveReq, int64(1)).
+		Return(nil, fmt.Errorf("%w: container 3 is empty", domain.ErrContainer))
+
+	for path, code := range map[string]int{
+		"/v1/logistics/containers/3/check-out": http.StatusOK,
+		"/v1/logistics/containers/3/return":    http.StatusConflict,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"reservationId":5,"toLocationId":8}`))
+		req = req.WithContext(context.WithValue(req.Context(), UserContextKey, map[string]interface{}{"user_id": float64(1)}))
+		w := httptest.NewRecorder()
+		h.MoveContainer(w, req)
+		assert.Equal(t, code, w.Code, path)
+	}
+
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_PackShipment(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	caseID := int64(3)
+	repo.On("PackShipment", mock.Anything, int64(42), &domain.PackScan{Code: "MIC-1", ContainerID: &caseID}, int64(1)).
+		Return(&domain.Manifest{ShipmentID: 42, PackedCount: 1, TotalCount: 2}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/shipments/42/pack", bytes.NewBufferString(`{"code":"MIC-1","containerId":3}`))
+	w := httptest.NewRecorder()
+	h.PackShipment(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/shipments/42/pack", bytes.NewBufferString(`{"code":"MIC-1","containerId":3}`))
+	req = req.WithContext(context.WithValue(req.Context(), UserContextKey, map[string]interface{}{"user_id": float64(1)}))
+	w = httptest.NewRecorder()
+	h.PackShipment(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"packedCount":1`)
+
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_GetShipmentManifest(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	caseID := int64(3)
+	m := &domain.Manifest{
+		ShipmentID: 42,
+		Direction:  "outbound",
+		Status:     domain.DeliveryPreparing,
+		Containers: []domain.Container{{ID: caseID, Tag: "CASE-1", Name: "Mic case", Kind: domain.ContainerCase,
+			Assets: []domain.ContainerAsset{{AssetID: 10, AssetTag: "MIC-1", ItemTypeName: "Wireless mic", ContainerID: &caseID}}}},
+		Unpacked:    []domain.ContainerAsset{{AssetID: 11, AssetTag: "MIC-2", ItemTypeName: "Wireless mic"}},
+		PackedCount: 1,
+		TotalCount:  2,
+	}
+	repo.On("GetShipmentManifest", mock.Anything, int64(42)).Return(m, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/logistics/shipments/42/manifest?format=csv", nil)
+	w := httptest.NewRecorder()
+	h.GetShipmentManifest(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "42,CASE-1,10,MIC-1,,Wireless mic,true", lines[1])
+	assert.Equal(t, "42,,11,MIC-2,,Wireless mic,false", lines[2])
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/shipments/42/manifest?format=html", nil)
+	w = httptest.NewRecorder()
+	h.GetShipmentManifest(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "CASE-1")
+	assert.Contains(t, w.Body.String(), "Not yet packed")
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/shipments/42/manifest?format=pdf", nil)
+	w = httptest.NewRecorder()
+	h.GetShipmentManifest(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.AssertExpectations(t)
+}
@@ -2,12 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/desmond/rental-management-system/internal/carrier"
 	"github.com/desmond/rental-management-system/internal/db"
 	"github.com/desmond/rental-management-system/internal/domain"
 	"github.com/desmond/rental-management-system/internal/fleet"
@@ -16,6 +18,7 @@ import (
 type Handler struct {
 	repo           db.Repository
 	remoteRegistry *fleet.RemoteRegistry
+	carriers       *carrier.Registry
 }
 
 func NewHandler(repo db.Repository, remoteRegistry *fleet.RemoteRegistry) *Handler {
@@ -539,20 +542,32 @@ func (h *Handler) CreateRentalReservation(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := h.repo.CreateRentalReservation(r.Context(), &rr); err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Append Outbox Event
+	h.announceSubmitted(r, &rr)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rr)
+}
+
+// announceSubmitted appends rental.submitted for a reservation created as pending.
+// Drafts announce themselves when they are submitted.
+func (h *Handler) announceSubmitted(r *http.Request, rr *domain.RentalReservation) {
+	if rr.ReservationStatus != domain.ReservationStatusPending {
+		return
+	}
 	payload, _ := json.Marshal(rr)
 	h.repo.AppendEvent(r.Context(), nil, &domain.OutboxEvent{
 		Type:    domain.EventRentalSubmitted,
 		Payload: payload,
 	})
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(rr)
 }
 
 // GetRentalReservation retrieves a reservation by ID.
@@ -592,7 +607,12 @@ func (h *Handler) GetRentalReservation(w http.ResponseWriter, r *http.Request) {
 // @Description Transitions a reservation to Confirmed status. Checks availability.
 // @Tags Logistics
 // @Param id path int true "Reservation ID"
+// @Param waitlist query bool false "Queue the reservation instead of failing when inventory is short"
+// @Param priority query int false "Waitlist priority (higher first)"
+// @Param auto_approve query bool false "Approve automatically once inventory frees up"
 // @Success 204 {string} string "No Content"
+// @Success 202 {array} domain.WaitlistEntry
+// @Failure 409 {string} string "Insufficient inventory or not pending"
 // @Router /logistics/reservations/{id}/approve [post]
 func (h *Handler) ApproveRentalReservation(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
@@ -603,80 +623,125 @@ func (h *Handler) ApproveRentalReservation(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	rr, err := h.repo.GetRentalReservationByID(r.Context(), id)
+	// Availability is re-validated, the status written, the outbox event appended and
+	// assets pinned in one transaction serialized per item type.
+	result, err := h.repo.ApproveRentalReservation(r.Context(), id, h.getUserIDFromContext(r))
 	if err != nil {
+		var shortage *domain.InsufficientInventoryError
+		if errors.As(err, &shortage) && r.URL.Query().Get("waitlist") == "true" {
+			h.enqueueWaitlist(w, r, id, shortage.Shortfalls)
+			return
+		}
+		if errors.Is(err, domain.ErrInsufficientInventory) || errors.Is(err, domain.ErrInvalidTransition) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if rr == nil {
+	if result == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	// Availability Check
-	for _, d := range rr.Demands {
-		if d.ItemKind == "item_type" {
-			avail, err := h.repo.GetAvailableQuantity(r.Context(), d.ItemID, rr.StartTime, rr.EndTime)
-			if err != nil {
-				http.Error(w, "availability check failed: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-			if avail < d.Quantity {
-				http.Error(w, fmt.Sprintf("insufficient inventory for item_type %d: requested %d, available %d", d.ItemID, d.Quantity, avail), http.StatusConflict)
-				return
-			}
-		}
-	}
-
-	if err := h.repo.UpdateRentalReservationStatus(r.Context(), id, domain.ReservationStatusConfirmed); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Append Outbox Event
-	payload, _ := json.Marshal(map[string]interface{}{"reservation_id": id, "status": domain.ReservationStatusConfirmed})
-	h.repo.AppendEvent(r.Context(), nil, &domain.OutboxEvent{
-		Type:    domain.EventRentalApproved,
-		Payload: payload,
-	})
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // RejectRentalReservation rejects a pending reservation.
+// @Summary Reject Rental Reservation
+// @Description Applies the reject transition. An optional {"reason": "..."} body is kept in the transition history.
+// @Tags Logistics
+// @Param id path int true "Reservation ID"
+// @Success 204 {string} string "No Content"
+// @Failure 409 {string} string "Transition not allowed"
+// @Router /logistics/reservations/{id}/reject [post]
 func (h *Handler) RejectRentalReservation(w http.ResponseWriter, r *http.Request) {
+	h.transitionAction(w, r, domain.ReservationActionReject)
+}
+
+// CancelRentalReservation cancels a reservation that has nothing checked out.
+// @Summary Cancel Rental Reservation
+// @Description Applies the cancel transition and releases the reservation's holds. An optional {"reason": "..."} body is kept in the transition history.
+// @Tags Logistics
+// @Param id path int true "Reservation ID"
+// @Success 204 {string} string "No Content"
+// @Failure 409 {string} string "Transition not allowed"
+// @Router /logistics/reservations/{id}/cancel [post]
+func (h *Handler) CancelRentalReservation(w http.ResponseWriter, r *http.Request) {
+	h.transitionAction(w, r, domain.ReservationActionCancel)
+}
+
+// ModifyRentalReservation applies a partial update to a reservation and its demands.
+// @Summary Modify Rental Reservation
+// @Description Changes dates, name, metadata or demand lines. Confirmed reservations are re-checked for the added capacity only and return to pending if it is not available.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Param patch body domain.ReservationPatch true "Changes"
+// @Success 200 {object} domain.ReservationChangeResult
+// @Failure 409 {string} string "Reservation cannot be modified"
+// @Router /logistics/reservations/{id} [patch]
+func (h *Handler) ModifyRentalReservation(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
-	idStr = strings.TrimSuffix(idStr, "/reject")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		http.Error(w, "invalid id", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.repo.UpdateRentalReservationStatus(r.Context(), id, domain.ReservationStatusCancelled); err != nil {
+	var patch domain.ReservationPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.repo.ModifyRentalReservation(r.Context(), id, &patch, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidReservationChange) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrReservationNotModifiable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if result == nil {
+		http.NotFound(w, r)
+		return
+	}
 
-	w.WriteHeader(http.StatusNoContent)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
 
-// CancelRentalReservation cancels a reservation.
-func (h *Handler) CancelRentalReservation(w http.ResponseWriter, r *http.Request) {
+// ListReservationChanges returns the versioned change history of a reservation.
+// @Summary Reservation Change History
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {array} domain.ReservationChange
+// @Router /logistics/reservations/{id}/history [get]
+func (h *Handler) ListReservationChanges(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
-	idStr = strings.TrimSuffix(idStr, "/cancel")
+	idStr = strings.TrimSuffix(idStr, "/history")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		http.Error(w, "invalid id", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.repo.UpdateRentalReservationStatus(r.Context(), id, domain.ReservationStatusCancelled); err != nil {
+	changes, err := h.repo.ListReservationChanges(r.Context(), id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
 }
 
 // Inspection Handlers
@@ -1147,52 +1212,77 @@ func (h *Handler) ApplyAssetRemotePower(w http.ResponseWriter, r *http.Request)
 
 // Intelligence Handlers
 
+// parseDateParam accepts either an RFC3339 timestamp or a plain date.
+func parseDateParam(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Parse("2006-01-02", s)
+	}
+	return t, nil
+}
+
 func (h *Handler) GetAvailabilityTimeline(w http.ResponseWriter, r *http.Request) {
+	id, start, end, granularity, ok := parseAvailabilityQuery(w, r)
+	if !ok {
+		return
+	}
+
+	results, err := h.repo.GetAvailabilityTimeline(r.Context(), id, start, end, granularity)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// parseAvailabilityQuery reads the item_type_id, start, end and granularity of an
+// availability timeline request, writing a 400 if any is missing or malformed.
+func parseAvailabilityQuery(w http.ResponseWriter, r *http.Request) (int64, time.Time, time.Time, domain.AvailabilityGranularity, bool) {
 	idStr := r.URL.Query().Get("item_type_id")
 	startStr := r.URL.Query().Get("start")
 	endStr := r.URL.Query().Get("end")
 
 	if idStr == "" || startStr == "" || endStr == "" {
 		http.Error(w, "missing required parameters (item_type_id, start, end)", http.StatusBadRequest)
-		return
+		return 0, time.Time{}, time.Time{}, "", false
 	}
 
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		http.Error(w, "invalid id", http.StatusBadRequest)
-		return
+		return 0, time.Time{}, time.Time{}, "", false
 	}
 
-	start, err := time.Parse(time.RFC3339, startStr)
+	start, err := parseDateParam(startStr)
 	if err != nil {
-		start, err = time.Parse("2006-01-02", startStr)
-		if err != nil {
-			http.Error(w, "invalid start date", http.StatusBadRequest)
-			return
-		}
+		http.Error(w, "invalid start date", http.StatusBadRequest)
+		return 0, time.Time{}, time.Time{}, "", false
 	}
 
-	end, err := time.Parse(time.RFC3339, endStr)
+	end, err := parseDateParam(endStr)
 	if err != nil {
-		end, err = time.Parse("2006-01-02", endStr)
-		if err != nil {
-			http.Error(w, "invalid end date", http.StatusBadRequest)
-			return
-		}
+		http.Error(w, "invalid end date", http.StatusBadRequest)
+		return 0, time.Time{}, time.Time{}, "", false
 	}
 
-	results, err := h.repo.GetAvailabilityTimeline(r.Context(), id, start, end)
+	granularity, err := domain.ParseAvailabilityGranularity(r.URL.Query().Get("granularity"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, time.Time{}, time.Time{}, "", false
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+	return id, start, end, granularity, true
 }
 
 func (h *Handler) GetShortageAlerts(w http.ResponseWriter, r *http.Request) {
-	results, err := h.repo.GetShortageAlerts(r.Context())
+	granularity, err := domain.ParseAvailabilityGranularity(r.URL.Query().Get("granularity"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.repo.GetShortageAlerts(r.Context(), granularity)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -5,8 +5,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -119,6 +122,27 @@ func (m *MockRepository) UpdateRentalReservationStatus(ctx context.Context, id i
 	return args.Error(0)
 }
 
+func (m *MockRepository) ApproveRentalReservation(ctx context.Context, id int64, userID *int64) (*domain.AllocationResult, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AllocationResult), args.Error(1)
+}
+
+func (m *MockRepository) ModifyRentalReservation(ctx context.Context, id int64, patch *domain.ReservationPatch, userID *int64) (*domain.ReservationChangeResult, error) {
+	args := m.Called(ctx, id, patch, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReservationChangeResult), args.Error(1)
+}
+
+func (m *MockRepository) ListReservationChanges(ctx context.Context, reservationID int64) ([]domain.ReservationChange, error) {
+	args := m.Called(ctx, reservationID)
+	return args.Get(0).([]domain.ReservationChange), args.Error(1)
+}
+
 func (m *MockRepository) GetAvailableQuantity(ctx context.Context, itemTypeID int64, startTime, endTime time.Time) (int, error) {
 	args := m.Called(ctx, itemTypeID, startTime, endTime)
 	return args.Int(0), args.Error(1)
@@ -271,20 +295,7 @@ func TestHandler_ApproveRentalReservation(t *testing.T) {
 	repo := new(MockRepository)
 	h := NewHandler(repo, nil)
 
-	rr := &domain.RentalReservation{
-		ID:                1,
-		ReservationStatus: domain.ReservationStatusPending,
-		StartTime:         time.Now(),
-		EndTime:           time.Now().Add(time.Hour),
-		Demands: []domain.Demand{
-			{ItemKind: "item_type", ItemID: 10, Quantity: 1},
-		},
-	}
-
-	repo.On("GetRentalReservationByID", mock.Anything, int64(1)).Return(rr, nil)
-	repo.On("GetAvailableQuantity", mock.Anything, int64(10), mock.Anything, mock.Anything).Return(5, nil)
-	repo.On("UpdateRentalReservationStatus", mock.Anything, int64(1), domain.ReservationStatusConfirmed).Return(nil)
-	repo.On("AppendEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	repo.On("ApproveRentalReservation", mock.Anything, int64(1), mock.Anything).Return(&domain.AllocationResult{ReservationID: 1}, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/1/approve", nil)
 	w := httptest.NewRecorder()
@@ -295,6 +306,526 @@ func TestHandler_ApproveRentalReservation(t *testing.T) {
 	repo.AssertExpectations(t)
 }
 
+func TestHandler_ApproveRentalReservation_InsufficientInventory(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	err := fmt.Errorf("%w for item_type 10: requested 5, available 4", domain.ErrInsufficientInventory)
+	repo.On("ApproveRentalReservation", mock.Anything, int64(2), mock.Anything).Return(nil, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/2/approve", nil)
+	w := httptest.NewRecorder()
+
+	h.ApproveRentalReservation(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "item_type 10")
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_ApproveRentalReservation_Waitlist(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	shortfalls := []domain.Shortfall{{ItemTypeID: 10, Requested: 5, Available: 4}}
+	repo.On("ApproveRentalReservation", mock.Anything, int64(2), mock.Anything).
+		Return(nil, &domain.InsufficientInventoryError{Shortfalls: shortfalls})
+	repo.On("EnqueueWaitlist", mock.Anything, int64(2), shortfalls, 3, true, mock.Anything).
+		Return([]domain.WaitlistEntry{{ID: 1, ReservationID: 2, ItemTypeID: 10, Priority: 3, AutoApprove: true}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/2/approve?waitlist=true&priority=3&auto_approve=true", nil)
+	w := httptest.NewRecorder()
+
+	h.ApproveRentalReservation(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_ApproveRentalReservation_NotFound(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("ApproveRentalReservation", mock.Anything, int64(3), mock.Anything).Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/3/approve", nil)
+	w := httptest.NewRecorder()
+
+	h.ApproveRentalReservation(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_ModifyRentalReservation_Requeued(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	result := &domain.ReservationChangeResult{
+		Reservation: &domain.RentalReservation{ID: 4, ReservationStatus: domain.ReservationStatusPending},
+		Change:      &domain.ReservationChange{ReservationID: 4, Version: 2},
+		Requeued:    true,
+		Shortfalls:  []domain.Shortfall{{ItemTypeID: 10, Requested: 2, Available: 0}},
+	}
+	repo.On("ModifyRentalReservation", mock.Anything, int64(4), mock.MatchedBy(func(p *domain.ReservationPatch) bool {
+		return len(p.Demands) == 1 && p.Demands[0].ID == 9 && *p.Demands[0].Quantity == 5
+	}), mock.Anything).Return(result, nil)
+
+	body := []byte(`{"demands":[{"id":9,"requestedQuantity":5}]}`)
+	req := httptest.NewRequest(http.MethodPatch, "/v1/logistics/reservations/4", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.ModifyRentalReservation(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp domain.ReservationChangeResult
+	json.NewDecoder(w.Body).Decode(&resp)
+	assert.True(t, resp.Requeued)
+	assert.Equal(t, 2, resp.Change.Version)
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_ModifyRentalReservation_NotModifiable(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	err := fmt.Errorf("%w: %s", domain.ErrReservationNotModifiable, domain.ReservationStatusCancelled)
+	repo.On("ModifyRentalReservation", mock.Anything, int64(5), mock.Anything, mock.Anything).Return(nil, err)
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/logistics/reservations/5", bytes.NewReader([]byte(`{"reservationName":"x"}`)))
+	w := httptest.NewRecorder()
+
+	h.ModifyRentalReservation(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestHandler_CreateSubstitutionRule(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("CreateSubstitutionRule", mock.Anything, mock.MatchedBy(func(rule *domain.SubstitutionRule) bool {
+		return rule.ItemTypeID == 10 && rule.SubstituteItemTypeID == 11 && rule.IsActive && *rule.MaxQuantity == 2
+	})).Return(nil)
+
+	body := `{"item_type_id":10,"substitute_item_type_id":11,"two_way":true,"max_quantity":2}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/catalog/substitution-rules", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	h.CreateSubstitutionRule(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// An item type cannot replace itself
+	req = httptest.NewRequest(http.MethodPost, "/v1/catalog/substitution-rules", bytes.NewReader([]byte(`{"item_type_id":10,"substitute_item_type_id":10}`)))
+	w = httptest.NewRecorder()
+	h.CreateSubstitutionRule(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.AssertNumberOfCalls(t, "CreateSubstitutionRule", 1)
+}
+
+func TestHandler_ApplySubstitution_NotAllowed(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	err := fmt.Errorf("%w: item_type 11 cannot replace item_type 10", domain.ErrSubstitutionNotAllowed)
+	repo.On("ApplySubstitution", mock.Anything, int64(5), int64(7), int64(10), int64(11), 2, mock.Anything).Return(nil, err)
+
+	body := `{"demand_id":7,"item_type_id":10,"substitute_item_type_id":11,"quantity":2}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/5/substitutions", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	h.ApplySubstitution(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestHandler_ReallocateAndSwapHolds(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	// A hold of another reservation is not found through this one
+	repo.On("ReallocateHold", mock.Anything, int64(5), int64(9), (*int64)(nil), mock.Anything).Return(nil, nil)
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/5/holds/9/reallocate", nil)
+	w := httptest.NewRecorder()
+	h.ReallocateHold(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	repo.On("ReallocateHold", mock.Anything, int64(5), int64(8), (*int64)(nil), mock.Anything).
+		Return(nil, fmt.Errorf("%w: hold 8 is released", domain.ErrHoldNotActive))
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/5/holds/8/reallocate", nil)
+	w = httptest.NewRecorder()
+	h.ReallocateHold(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	repo.On("SwapHolds", mock.Anything, int64(5), int64(8), int64(12)).Return(fmt.Errorf("%w: hold 8", domain.ErrHoldNotFound))
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/5/holds/swap", bytes.NewReader([]byte(`{"hold_id":8,"other_hold_id":12}`)))
+	w = httptest.NewRecorder()
+	h.SwapHolds(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Failures other than the hold's own state are server errors
+	repo.On("SwapHolds", mock.Anything, int64(5), int64(7), int64(12)).Return(errors.New("connection reset"))
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/5/holds/swap", bytes.NewReader([]byte(`{"hold_id":7,"other_hold_id":12}`)))
+	w = httptest.NewRecorder()
+	h.SwapHolds(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_EditReservationSeries(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("EditReservationSeries", mock.Anything, int64(3), mock.MatchedBy(func(e *domain.SeriesEdit) bool {
+		return e.Scope == domain.SeriesEditFollowing && e.ReservationID == 21 && *e.Patch.ReservationName == "Late rehearsal"
+	}), mock.Anything).Return(&domain.SeriesEditResult{Series: &domain.ReservationSeries{ID: 4}}, nil)
+
+	body := `{"scope":"following","reservationId":21,"patch":{"reservationName":"Late rehearsal"}}`
+	req := httptest.NewRequest(http.MethodPatch, "/v1/logistics/series/3", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	h.EditReservationSeries(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err := fmt.Errorf("%w: unsupported scope \"some\"", domain.ErrInvalidReservationChange)
+	repo.On("EditReservationSeries", mock.Anything, int64(5), mock.Anything, mock.Anything).Return(nil, err)
+	req = httptest.NewRequest(http.MethodPatch, "/v1/logistics/series/5", bytes.NewReader([]byte(`{"scope":"some","reservationId":1}`)))
+	w = httptest.NewRecorder()
+	h.EditReservationSeries(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_ApproveReservationSeries_Shortage(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	short := []domain.OccurrenceShortfall{{ReservationID: 22, Shortfalls: []domain.Shortfall{{ItemTypeID: 10, Requested: 2, Available: 1}}}}
+	repo.On("ApproveReservationSeries", mock.Anything, int64(3), mock.Anything).
+		Return(nil, &domain.InsufficientInventoryError{Shortfalls: short[0].Shortfalls, Occurrences: short})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/series/3/approve", nil)
+	w := httptest.NewRecorder()
+	h.ApproveReservationSeries(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "1 occurrences short")
+}
+
+func TestHandler_CancelRentalReservation(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("TransitionRentalReservation", mock.Anything, int64(6), &domain.ReservationTransitionRequest{
+		Action: domain.ReservationActionCancel, Reason: "event moved",
+	}, mock.Anything).Return(&domain.ReservationTransitionRecord{ID: 1, ReservationID: 6}, nil)
+	repo.On("TransitionRentalReservation", mock.Anything, int64(7), &domain.ReservationTransitionRequest{
+		Action: domain.ReservationActionCancel,
+	}, mock.Anything).Return(nil, fmt.Errorf("%w: cannot cancel: 1 assets are still checked out", domain.ErrInvalidTransition))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/6/cancel", strings.NewReader(`{"reason":"event moved"}`))
+	w := httptest.NewRecorder()
+	h.CancelRentalReservation(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// The body is optional
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/reservations/7/cancel", nil)
+	w = httptest.NewRecorder()
+	h.CancelRentalReservation(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "still checked out")
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_GetReservationLifecycle(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("GetReservationLifecycle", mock.Anything, int64(4)).Return(&domain.ReservationLifecycle{
+		ReservationID: 4,
+		Status:        domain.ReservationStatusPending,
+		Allowed: []domain.AllowedTransition{
+			{Action: domain.ReservationActionApprove, To: domain.ReservationStatusConfirmed, Allowed: true},
+		},
+	}, nil)
+	repo.On("GetReservationLifecycle", mock.Anything, int64(5)).Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/logistics/reservations/4/transitions", nil)
+	w := httptest.NewRecorder()
+	h.GetReservationLifecycle(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var lifecycle domain.ReservationLifecycle
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&lifecycle))
+	assert.Equal(t, domain.ReservationActionApprove, lifecycle.Allowed[0].Action)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/reservations/5/transitions", nil)
+	w = httptest.NewRecorder()
+	h.GetReservationLifecycle(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_QuoteReservation(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	companyID := int64(3)
+	repo.On("QuoteReservation", mock.Anything, mock.MatchedBy(func(rr *domain.RentalReservation) bool {
+		return len(rr.Demands) == 1 && rr.Demands[0].ItemID == 10
+	}), &companyID).Return(&domain.Quote{Currency: "USD", Total: 1600, Lines: []domain.QuoteLine{{ItemTypeID: 10, Quantity: 2, Amount: 1600}}}, nil)
+
+	body := `{"startTime":"2026-03-02T09:00:00Z","endTime":"2026-03-03T09:00:00Z","demands":[{"itemKind":"item_type","itemId":10,"quantity":2}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/quotes?company_id=3", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.QuoteReservation(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var quote domain.Quote
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&quote))
+	assert.Equal(t, int64(1600), quote.Total)
+
+	body = `{"startTime":"2026-03-03T09:00:00Z","endTime":"2026-03-02T09:00:00Z"}`
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/quotes", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	h.QuoteReservation(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_CreateRateCard_Validation(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/catalog/rate-cards", strings.NewReader(`{"item_type_id":4,"daily_rate":0}`))
+	w := httptest.NewRecorder()
+	h.CreateRateCard(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.On("CreateRateCard", mock.Anything, mock.MatchedBy(func(rc *domain.RateCard) bool {
+		return rc.Currency == "USD" && rc.WeekendRule == domain.WeekendStandard && rc.IsActive
+	})).Return(nil)
+	req = httptest.NewRequest(http.MethodPost, "/v1/catalog/rate-cards", strings.NewReader(`{"item_type_id":4,"daily_rate":2500}`))
+	w = httptest.NewRecorder()
+	h.CreateRateCard(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_GetInvoice_Formats(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+	repo.On("GetInvoice", mock.Anything, int64(8)).Return(&domain.Invoice{
+		ID: 8, Number: "INV-000008", ReservationID: 3, Currency: "USD", PeriodStart: start, PeriodEnd: end,
+		Lines: []domain.InvoiceLine{
+			{Kind: domain.InvoiceLineUsage, Description: "Rental of CAM-1", AssetID: 1, Start: &start, End: &end, Days: 2, Amount: 2000},
+			{Kind: domain.InvoiceLineDamage, Description: "Damage to asset 1: Lens <intact>?", AssetID: 1, Amount: 12050},
+		},
+		Total: 14050,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/logistics/invoices/8?format=csv", nil)
+	w := httptest.NewRecorder()
+	h.GetInvoice(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "INV-000008,usage,Rental of CAM-1,1,2026-03-02T09:00:00Z,2026-03-04T09:00:00Z,2,,20.00,USD,")
+	assert.Contains(t, w.Body.String(), "INV-000008,total,,,,,,,140.50,USD,")
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/invoices/8?format=html", nil)
+	w = httptest.NewRecorder()
+	h.GetInvoice(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Lens &lt;intact&gt;?")
+	assert.Contains(t, w.Body.String(), "140.50")
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/invoices/8?format=pdf", nil)
+	w = httptest.NewRecorder()
+	h.GetInvoice(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_Claims(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	// Damage claims must name the inspection before they reach the repository
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/claims", strings.NewReader(`{"type":"damage","reservationId":3,"assetId":1}`))
+	w := httptest.NewRecorder()
+	h.CreateClaim(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.On("CreateClaim", mock.Anything, mock.MatchedBy(func(c *domain.Claim) bool {
+		return c.Type == domain.ClaimLoss && c.AssetID == 2
+	}), mock.Anything).Return(fmt.Errorf("%w: asset 2 is not checked out on reservation 3", domain.ErrInvalidClaim))
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/claims", strings.NewReader(`{"type":"loss","reservationId":3,"assetId":2}`))
+	w = httptest.NewRecorder()
+	h.CreateClaim(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	reservationID := int64(3)
+	repo.On("ListClaims", mock.Anything, domain.ClaimFilter{ReservationID: &reservationID, Status: domain.ClaimOpen}).
+		Return([]domain.Claim{{ID: 5, ReservationID: 3, AssetID: 1, Type: domain.ClaimDamage, Status: domain.ClaimOpen}}, nil)
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/claims?reservation_id=3&status=open", nil)
+	w = httptest.NewRecorder()
+	h.ListClaims(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":5`)
+
+	repo.On("UpdateClaimStatus", mock.Anything, int64(5), &domain.ClaimStatusUpdate{Status: domain.ClaimOpen}, mock.Anything).
+		Return(nil, fmt.Errorf("%w: cannot move a open claim to open", domain.ErrInvalidClaim))
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/claims/5/status", strings.NewReader(`{"status":"open"}`))
+	w = httptest.NewRecorder()
+	h.UpdateClaimStatus(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/claims/5/evidence", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	h.AddClaimEvidence(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_GetOverdueReport(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	due := time.Now().Add(-48 * time.Hour)
+	repo.On("ListOverdueAssets", mock.Anything, mock.Anything).Return([]domain.OverdueAsset{
+		{ReservationID: 1, AssetID: 10, CheckOutID: 100, DueAt: due, EscalationLevel: 2},
+		{ReservationID: 1, AssetID: 11, CheckOutID: 101, DueAt: due, EscalationLevel: 2},
+		{ReservationID: 2, AssetID: 12, CheckOutID: 102, DueAt: due.Add(47 * time.Hour)},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/logistics/overdue?min_level=1", nil)
+	w := httptest.NewRecorder()
+	h.GetOverdueReport(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report domain.OverdueReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Reservations)
+	assert.Len(t, report.Assets, 2)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/overdue?min_level=x", nil)
+	w = httptest.NewRecorder()
+	h.GetOverdueReport(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_GetReservationConflicts(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("GetReservationConflicts", mock.Anything, int64(3), 14*24*time.Hour).Return(&domain.ReservationConflicts{
+		ReservationID: 3,
+		Demands:       []domain.DemandConflict{{DemandID: 1, ItemTypes: []domain.ItemTypeConflict{{ItemTypeID: 10, Requested: 2, Short: 1}}}},
+	}, nil)
+	repo.On("GetReservationConflicts", mock.Anything, int64(4), 90*24*time.Hour).Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/logistics/reservations/3/conflicts?horizon_days=14", nil)
+	w := httptest.NewRecorder()
+	h.GetReservationConflicts(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"short":1`)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/reservations/4/conflicts", nil)
+	w = httptest.NewRecorder()
+	h.GetReservationConflicts(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/reservations/3/conflicts?horizon_days=1000", nil)
+	w = httptest.NewRecorder()
+	h.GetReservationConflicts(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_ScheduleReservation(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	start := time.Date(2026, 7, 3, 9, 0, 0, 0, time.UTC)
+	slot := &domain.TimeWindow{Start: start, End: start.Add(4 * time.Hour)}
+	repo.On("FindEarliestSlot", mock.Anything, mock.MatchedBy(func(req *domain.ScheduleRequest) bool {
+		return req.DurationMinutes == 240 && *req.SourcePlaceID == 2
+	})).Return(&domain.ScheduleResult{Slot: slot}, nil)
+	repo.On("CreateRentalReservation", mock.Anything, mock.MatchedBy(func(rr *domain.RentalReservation) bool {
+		return rr.StartTime.Equal(slot.Start) && rr.EndTime.Equal(slot.End) && len(rr.Demands) == 1 &&
+			rr.ReservationStatus == domain.ReservationStatusPending
+	})).Return(nil)
+	repo.On("AppendEvent", mock.Anything, mock.Anything, mock.MatchedBy(func(e *domain.OutboxEvent) bool {
+		return e.Type == domain.EventRentalSubmitted
+	})).Return(nil)
+
+	body := `{"demands":[{"itemKind":"item_type","itemId":10,"requestedQuantity":2}],"durationMinutes":240,"sourcePlaceId":2,"create":true}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/schedule", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ScheduleReservation(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	repo.AssertExpectations(t)
+
+	repo = new(MockRepository)
+	h = NewHandler(repo, nil)
+	repo.On("FindEarliestSlot", mock.Anything, mock.Anything).Return(&domain.ScheduleResult{}, nil)
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/schedule", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	h.ScheduleReservation(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	repo.AssertNotCalled(t, "CreateRentalReservation", mock.Anything, mock.Anything)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/schedule", strings.NewReader(`{"demands":[],"durationMinutes":60}`))
+	w = httptest.NewRecorder()
+	h.ScheduleReservation(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_CustomerPortal(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+	router := NewRouter(h)
+
+	token := &domain.CustomerToken{ID: 1, CompanyID: 7, PersonID: 42}
+	repo.On("AuthenticateCustomerToken", mock.Anything, domain.HashCustomerToken("rms_ct_good"), mock.Anything).Return(token, nil)
+	repo.On("AuthenticateCustomerToken", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	repo.On("GetCompanyReservation", mock.Anything, int64(7), int64(3)).Return(&domain.RentalReservation{ID: 3, ReservationStatus: domain.ReservationStatusDraft}, nil)
+	repo.On("GetCompanyReservation", mock.Anything, int64(7), int64(4)).Return(nil, nil)
+	repo.On("CreateRentalReservation", mock.Anything, mock.MatchedBy(func(rr *domain.RentalReservation) bool {
+		return rr.ReservationStatus == domain.ReservationStatusDraft && *rr.UnderNameID == 42 && rr.ProviderID == nil
+	})).Return(nil)
+	repo.On("GetItemTypeByID", mock.Anything, int64(10)).Return(&domain.ItemType{ID: 10, IsActive: true}, nil)
+	repo.On("GetItemTypeByID", mock.Anything, int64(11)).Return(&domain.ItemType{ID: 11}, nil)
+	repo.On("GetAvailabilityTimeline", mock.Anything, int64(10), mock.Anything, mock.Anything, domain.GranularityDay).Return([]domain.AvailabilityPoint{}, nil)
+
+	do := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Staff JWTs and unknown customer tokens are both turned away
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/v1/portal/reservations/3", "eyJhbGciOi", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/v1/portal/reservations/3", "rms_ct_revoked", "").Code)
+
+	w := do(http.MethodGet, "/v1/portal/reservations/3", "rms_ct_good", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":3`)
+
+	// Another company's reservation looks like a missing one
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/v1/portal/reservations/4/fulfillment", "rms_ct_good", "").Code)
+
+	body := `{"reservationStatus":"ReservationPending","underNameId":9,"providerId":1,"startTime":"2026-07-01T09:00:00Z","endTime":"2026-07-02T09:00:00Z",
+		"demands":[{"itemKind":"item_type","itemId":10,"requestedQuantity":2}]}`
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/v1/portal/reservations", "rms_ct_good", body).Code)
+
+	// Availability is limited to active catalog item types over a bounded window
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/v1/portal/availability?item_type_id=10&start=2026-07-01&end=2026-08-01", "rms_ct_good", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/v1/portal/availability?item_type_id=11&start=2026-07-01&end=2026-08-01", "rms_ct_good", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/v1/portal/availability?item_type_id=10&start=2026-07-01&end=2029-07-01", "rms_ct_good", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/v1/portal/availability?item_type_id=10&start=2026-07-01&end=2026-08-01&granularity=hour", "rms_ct_good", "").Code)
+	repo.AssertExpectations(t)
+}
+
 func (m *MockRepository) CreateUser(ctx context.Context, u *domain.User) error {
 	args := m.Called(ctx, u)
 	return args.Error(0)
@@ -342,13 +873,13 @@ func (m *MockRepository) MarkEventFailed(ctx context.Context, id int64, errMessa
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetAvailabilityTimeline(ctx context.Context, itemTypeID int64, start, end time.Time) ([]domain.AvailabilityPoint, error) {
-	args := m.Called(ctx, itemTypeID, start, end)
+func (m *MockRepository) GetAvailabilityTimeline(ctx context.Context, itemTypeID int64, start, end time.Time, granularity domain.AvailabilityGranularity) ([]domain.AvailabilityPoint, error) {
+	args := m.Called(ctx, itemTypeID, start, end, granularity)
 	return args.Get(0).([]domain.AvailabilityPoint), args.Error(1)
 }
 
-func (m *MockRepository) GetShortageAlerts(ctx context.Context) ([]domain.ShortageAlert, error) {
-	args := m.Called(ctx)
+func (m *MockRepository) GetShortageAlerts(ctx context.Context, granularity domain.AvailabilityGranularity) ([]domain.ShortageAlert, error) {
+	args := m.Called(ctx, granularity)
 	return args.Get(0).([]domain.ShortageAlert), args.Error(1)
 }
 
@@ -701,3 +1232,610 @@ func (m *MockRepository) AllocateAssetsToShipment(ctx context.Context, shipmentI
 	args := m.Called(ctx, shipmentID, assetIDs, agentID)
 	return args.Error(0)
 }
+
+func (m *MockRepository) SetShipmentLegs(ctx context.Context, shipmentID int64, legs []domain.ShipmentLeg) (*domain.Shipment, error) {
+	args := m.Called(ctx, shipmentID, legs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Shipment), args.Error(1)
+}
+
+func (m *MockRepository) ConsolidateShipment(ctx context.Context, shipmentID int64, reservationIDs []int64) (*domain.ConsolidationResult, error) {
+	args := m.Called(ctx, shipmentID, reservationIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ConsolidationResult), args.Error(1)
+}
+
+func (m *MockRepository) TransferAssets(ctx context.Context, req *domain.TransferRequest, agentID int64) ([]domain.AssetTransfer, error) {
+	args := m.Called(ctx, req, agentID)
+	return args.Get(0).([]domain.AssetTransfer), args.Error(1)
+}
+
+func (m *MockRepository) ListAssetTransfers(ctx context.Context, reservationID int64) ([]domain.AssetTransfer, error) {
+	args := m.Called(ctx, reservationID)
+	return args.Get(0).([]domain.AssetTransfer), args.Error(1)
+}
+
+func (m *MockRepository) TransitionShipment(ctx context.Context, shipmentID int64, req *domain.ShipmentTransitionRequest) (*domain.Shipment, error) {
+	args := m.Called(ctx, shipmentID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Shipment), args.Error(1)
+}
+
+func (m *MockRepository) GetActiveShipmentLabel(ctx context.Context, shipmentID int64) (*domain.ShippingLabel, error) {
+	args := m.Called(ctx, shipmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ShippingLabel), args.Error(1)
+}
+
+func (m *MockRepository) RecordShipmentLabel(ctx context.Context, l *domain.ShippingLabel) error {
+	args := m.Called(ctx, l)
+	return args.Error(0)
+}
+
+func (m *MockRepository) VoidShipmentLabel(ctx context.Context, labelID int64) error {
+	args := m.Called(ctx, labelID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListTrackableShipments(ctx context.Context) ([]domain.Shipment, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Shipment), args.Error(1)
+}
+
+func (m *MockRepository) RecordTrackingEvents(ctx context.Context, shipmentID int64, events []domain.TrackingEvent) (*domain.Shipment, error) {
+	args := m.Called(ctx, shipmentID, events)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Shipment), args.Error(1)
+}
+
+func (m *MockRepository) ListTrackingEvents(ctx context.Context, shipmentID int64) ([]domain.TrackingEvent, error) {
+	args := m.Called(ctx, shipmentID)
+	return args.Get(0).([]domain.TrackingEvent), args.Error(1)
+}
+
+func (m *MockRepository) CreatePickList(ctx context.Context, deliveryID int64, req *domain.PickListRequest, userID *int64) (*domain.PickList, error) {
+	args := m.Called(ctx, deliveryID, req, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PickList), args.Error(1)
+}
+
+func (m *MockRepository) GetPickList(ctx context.Context, id int64) (*domain.PickList, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PickList), args.Error(1)
+}
+
+func (m *MockRepository) ListPickLists(ctx context.Context, deliveryID *int64) ([]domain.PickList, error) {
+	args := m.Called(ctx, deliveryID)
+	return args.Get(0).([]domain.PickList), args.Error(1)
+}
+
+func (m *MockRepository) ScanPickList(ctx context.Context, id int64, code string, userID *int64) (*domain.PickList, error) {
+	args := m.Called(ctx, id, code, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PickList), args.Error(1)
+}
+
+func (m *MockRepository) CompletePickList(ctx context.Context, id int64, shipmentID *int64, agentID int64) (*domain.PickList, error) {
+	args := m.Called(ctx, id, shipmentID, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PickList), args.Error(1)
+}
+
+func (m *MockRepository) CancelPickList(ctx context.Context, id int64) (*domain.PickList, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PickList), args.Error(1)
+}
+
+func (m *MockRepository) CreateContainer(ctx context.Context, c *domain.Container) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetContainer(ctx context.Context, id int64) (*domain.Container, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Container), args.Error(1)
+}
+
+func (m *MockRepository) ListContainers(ctx context.Context, placeID *int64) ([]domain.Container, error) {
+	args := m.Called(ctx, placeID)
+	return args.Get(0).([]domain.Container), args.Error(1)
+}
+
+func (m *MockRepository) AddToContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Container), args.Error(1)
+}
+
+func (m *MockRepository) RemoveFromContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Container), args.Error(1)
+}
+
+func (m *MockRepository) CheckOutContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, agentID int64) (*domain.Container, error) {
+	args := m.Called(ctx, id, req, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Container), args.Error(1)
+}
+
+func (m *MockRepository) ReturnContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, agentID int64) (*domain.Container, error) {
+	args := m.Called(ctx, id, req, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Container), args.Error(1)
+}
+
+func (m *MockRepository) PackShipment(ctx context.Context, shipmentID int64, scan *domain.PackScan, agentID int64) (*domain.Manifest, error) {
+	args := m.Called(ctx, shipmentID, scan, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Manifest), args.Error(1)
+}
+
+func (m *MockRepository) GetShipmentManifest(ctx context.Context, shipmentID int64) (*domain.Manifest, error) {
+	args := m.Called(ctx, shipmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Manifest), args.Error(1)
+}
+
+func (m *MockRepository) OpenReceivingSession(ctx context.Context, shipmentID int64, req *domain.ReceivingSessionRequest, userID *int64) (*domain.ReceivingSession, error) {
+	args := m.Called(ctx, shipmentID, req, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReceivingSession), args.Error(1)
+}
+
+func (m *MockRepository) GetReceivingSession(ctx context.Context, id int64) (*domain.ReceivingSession, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReceivingSession), args.Error(1)
+}
+
+func (m *MockRepository) ListReceivingSessions(ctx context.Context, shipmentID int64) ([]domain.ReceivingSession, error) {
+	args := m.Called(ctx, shipmentID)
+	return args.Get(0).([]domain.ReceivingSession), args.Error(1)
+}
+
+func (m *MockRepository) ScanReceiving(ctx context.Context, id int64, code string, userID *int64) (*domain.ReceivingSession, error) {
+	args := m.Called(ctx, id, code, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReceivingSession), args.Error(1)
+}
+
+func (m *MockRepository) CloseReceivingSession(ctx context.Context, id int64, agentID int64) (*domain.ReceivingSession, error) {
+	args := m.Called(ctx, id, agentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReceivingSession), args.Error(1)
+}
+
+func (m *MockRepository) PlanDeliveryRoutes(ctx context.Context, req *domain.RoutePlanRequest) (*domain.RoutePlan, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RoutePlan), args.Error(1)
+}
+
+func (m *MockRepository) RecordStockMovement(ctx context.Context, req *domain.StockMovementRequest, userID *int64) ([]domain.StockMovement, error) {
+	args := m.Called(ctx, req, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.StockMovement), args.Error(1)
+}
+
+func (m *MockRepository) ListStockMovements(ctx context.Context, itemTypeID, reservationID *int64) ([]domain.StockMovement, error) {
+	args := m.Called(ctx, itemTypeID, reservationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.StockMovement), args.Error(1)
+}
+
+func (m *MockRepository) GetStockSummary(ctx context.Context, itemTypeID int64) (*domain.StockSummary, error) {
+	args := m.Called(ctx, itemTypeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.StockSummary), args.Error(1)
+}
+
+func (m *MockRepository) ListStockLots(ctx context.Context, itemTypeID int64) ([]domain.StockLot, error) {
+	args := m.Called(ctx, itemTypeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.StockLot), args.Error(1)
+}
+
+// Kit Templates
+func (m *MockRepository) GetKitAvailableQuantity(ctx context.Context, kitTemplateID int64, startTime, endTime time.Time) (int, error) {
+	args := m.Called(ctx, kitTemplateID, startTime, endTime)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockRepository) CreateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error {
+	args := m.Called(ctx, kt)
+	return args.Error(0)
+}
+func (m *MockRepository) GetKitTemplate(ctx context.Context, id int64) (*domain.KitTemplate, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.KitTemplate), args.Error(1)
+}
+func (m *MockRepository) ListKitTemplates(ctx context.Context) ([]domain.KitTemplate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.KitTemplate), args.Error(1)
+}
+func (m *MockRepository) UpdateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error {
+	args := m.Called(ctx, kt)
+	return args.Error(0)
+}
+func (m *MockRepository) DeleteKitTemplate(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockRepository) CheckOutKit(ctx context.Context, reservationID, demandID int64, assetIDs []int64, agentID int64, fromLocationID, toLocationID *int64) (*domain.KitInstance, error) {
+	args := m.Called(ctx, reservationID, demandID, assetIDs, agentID, fromLocationID, toLocationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.KitInstance), args.Error(1)
+}
+func (m *MockRepository) ListKitInstances(ctx context.Context, reservationID int64) ([]domain.KitInstance, error) {
+	args := m.Called(ctx, reservationID)
+	return args.Get(0).([]domain.KitInstance), args.Error(1)
+}
+
+// Substitution Rules
+func (m *MockRepository) CreateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetSubstitutionRule(ctx context.Context, id int64) (*domain.SubstitutionRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SubstitutionRule), args.Error(1)
+}
+
+func (m *MockRepository) ListSubstitutionRules(ctx context.Context, itemTypeID *int64) ([]domain.SubstitutionRule, error) {
+	args := m.Called(ctx, itemTypeID)
+	return args.Get(0).([]domain.SubstitutionRule), args.Error(1)
+}
+
+func (m *MockRepository) UpdateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteSubstitutionRule(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// Reservation Series
+func (m *MockRepository) CreateReservationSeries(ctx context.Context, s *domain.ReservationSeries) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetReservationSeries(ctx context.Context, id int64) (*domain.ReservationSeries, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReservationSeries), args.Error(1)
+}
+
+func (m *MockRepository) ListReservationSeries(ctx context.Context) ([]domain.ReservationSeries, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.ReservationSeries), args.Error(1)
+}
+
+func (m *MockRepository) ApproveReservationSeries(ctx context.Context, id int64, userID *int64) (*domain.SeriesApproval, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SeriesApproval), args.Error(1)
+}
+
+func (m *MockRepository) CheckSeriesAvailability(ctx context.Context, id int64) ([]domain.OccurrenceShortfall, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]domain.OccurrenceShortfall), args.Error(1)
+}
+
+func (m *MockRepository) EditReservationSeries(ctx context.Context, id int64, edit *domain.SeriesEdit, userID *int64) (*domain.SeriesEditResult, error) {
+	args := m.Called(ctx, id, edit, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.SeriesEditResult), args.Error(1)
+}
+
+// Reservation State Machine
+func (m *MockRepository) TransitionRentalReservation(ctx context.Context, id int64, req *domain.ReservationTransitionRequest, userID *int64) (*domain.ReservationTransitionRecord, error) {
+	args := m.Called(ctx, id, req, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReservationTransitionRecord), args.Error(1)
+}
+
+func (m *MockRepository) GetReservationLifecycle(ctx context.Context, id int64) (*domain.ReservationLifecycle, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReservationLifecycle), args.Error(1)
+}
+
+// Pricing
+func (m *MockRepository) CreateRateCard(ctx context.Context, rc *domain.RateCard) error {
+	args := m.Called(ctx, rc)
+	return args.Error(0)
+}
+func (m *MockRepository) GetRateCard(ctx context.Context, id int64) (*domain.RateCard, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RateCard), args.Error(1)
+}
+func (m *MockRepository) ListRateCards(ctx context.Context, itemTypeID *int64) ([]domain.RateCard, error) {
+	args := m.Called(ctx, itemTypeID)
+	return args.Get(0).([]domain.RateCard), args.Error(1)
+}
+func (m *MockRepository) UpdateRateCard(ctx context.Context, rc *domain.RateCard) error {
+	args := m.Called(ctx, rc)
+	return args.Error(0)
+}
+func (m *MockRepository) DeleteRateCard(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+func (m *MockRepository) QuoteReservation(ctx context.Context, rr *domain.RentalReservation, companyID *int64) (*domain.Quote, error) {
+	args := m.Called(ctx, rr, companyID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Quote), args.Error(1)
+}
+func (m *MockRepository) GetReservationQuote(ctx context.Context, reservationID int64) (*domain.Quote, error) {
+	args := m.Called(ctx, reservationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Quote), args.Error(1)
+}
+
+// Invoicing
+func (m *MockRepository) CreateInvoice(ctx context.Context, reservationID int64, req *domain.InvoiceRequest, userID *int64) (*domain.Invoice, error) {
+	args := m.Called(ctx, reservationID, req, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invoice), args.Error(1)
+}
+func (m *MockRepository) GetInvoice(ctx context.Context, id int64) (*domain.Invoice, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invoice), args.Error(1)
+}
+func (m *MockRepository) ListInvoices(ctx context.Context, reservationID int64) ([]domain.Invoice, error) {
+	args := m.Called(ctx, reservationID)
+	return args.Get(0).([]domain.Invoice), args.Error(1)
+}
+func (m *MockRepository) ListReservationsDueForInvoice(ctx context.Context, before time.Time) ([]int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+// Conflicts
+func (m *MockRepository) GetReservationConflicts(ctx context.Context, id int64, horizon time.Duration) (*domain.ReservationConflicts, error) {
+	args := m.Called(ctx, id, horizon)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ReservationConflicts), args.Error(1)
+}
+func (m *MockRepository) FindEarliestSlot(ctx context.Context, req *domain.ScheduleRequest) (*domain.ScheduleResult, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ScheduleResult), args.Error(1)
+}
+
+// Overdue Rentals
+func (m *MockRepository) ListOverdueAssets(ctx context.Context, now time.Time) ([]domain.OverdueAsset, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]domain.OverdueAsset), args.Error(1)
+}
+func (m *MockRepository) EscalateOverdueAsset(ctx context.Context, a *domain.OverdueAsset, level int, now time.Time) error {
+	args := m.Called(ctx, a, level, now)
+	return args.Error(0)
+}
+func (m *MockRepository) SyncOverdueReservations(ctx context.Context, now time.Time) (int64, int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+// Claims
+func (m *MockRepository) CreateClaim(ctx context.Context, c *domain.Claim, userID *int64) error {
+	args := m.Called(ctx, c, userID)
+	return args.Error(0)
+}
+func (m *MockRepository) GetClaim(ctx context.Context, id int64) (*domain.Claim, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Claim), args.Error(1)
+}
+func (m *MockRepository) ListClaims(ctx context.Context, filter domain.ClaimFilter) ([]domain.Claim, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.Claim), args.Error(1)
+}
+func (m *MockRepository) AddClaimEvidence(ctx context.Context, ev *domain.ClaimEvidence) error {
+	args := m.Called(ctx, ev)
+	return args.Error(0)
+}
+func (m *MockRepository) UpdateClaimStatus(ctx context.Context, id int64, upd *domain.ClaimStatusUpdate, userID *int64) (*domain.Claim, error) {
+	args := m.Called(ctx, id, upd, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Claim), args.Error(1)
+}
+
+// Customer Portal
+func (m *MockRepository) CreateCustomerToken(ctx context.Context, t *domain.CustomerToken, hash string) error {
+	args := m.Called(ctx, t, hash)
+	return args.Error(0)
+}
+func (m *MockRepository) ListCustomerTokens(ctx context.Context, companyID *int64) ([]domain.CustomerToken, error) {
+	args := m.Called(ctx, companyID)
+	return args.Get(0).([]domain.CustomerToken), args.Error(1)
+}
+func (m *MockRepository) RevokeCustomerToken(ctx context.Context, id int64) (*domain.CustomerToken, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CustomerToken), args.Error(1)
+}
+func (m *MockRepository) AuthenticateCustomerToken(ctx context.Context, hash string, now time.Time) (*domain.CustomerToken, error) {
+	args := m.Called(ctx, hash, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CustomerToken), args.Error(1)
+}
+func (m *MockRepository) ListCompanyReservations(ctx context.Context, companyID int64) ([]domain.RentalReservation, error) {
+	args := m.Called(ctx, companyID)
+	return args.Get(0).([]domain.RentalReservation), args.Error(1)
+}
+func (m *MockRepository) GetCompanyReservation(ctx context.Context, companyID, id int64) (*domain.RentalReservation, error) {
+	args := m.Called(ctx, companyID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RentalReservation), args.Error(1)
+}
+func (m *MockRepository) ListCompanyShipments(ctx context.Context, companyID int64, reservationID *int64) ([]domain.Shipment, error) {
+	args := m.Called(ctx, companyID, reservationID)
+	return args.Get(0).([]domain.Shipment), args.Error(1)
+}
+
+// Allocation Holds
+func (m *MockRepository) AllocateReservation(ctx context.Context, reservationID int64, userID *int64) (*domain.AllocationResult, error) {
+	args := m.Called(ctx, reservationID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AllocationResult), args.Error(1)
+}
+func (m *MockRepository) ListAssetHolds(ctx context.Context, reservationID int64) ([]domain.AssetHold, error) {
+	args := m.Called(ctx, reservationID)
+	return args.Get(0).([]domain.AssetHold), args.Error(1)
+}
+func (m *MockRepository) ReallocateHold(ctx context.Context, reservationID, holdID int64, assetID *int64, userID *int64) (*domain.AssetHold, error) {
+	args := m.Called(ctx, reservationID, holdID, assetID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AssetHold), args.Error(1)
+}
+func (m *MockRepository) SwapHolds(ctx context.Context, reservationID, holdID, otherHoldID int64) error {
+	args := m.Called(ctx, reservationID, holdID, otherHoldID)
+	return args.Error(0)
+}
+func (m *MockRepository) ReleaseReservationHolds(ctx context.Context, reservationID int64) error {
+	args := m.Called(ctx, reservationID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ApplySubstitution(ctx context.Context, reservationID, demandID, itemTypeID, substituteItemTypeID int64, quantity int, userID *int64) (*domain.AllocationResult, error) {
+	args := m.Called(ctx, reservationID, demandID, itemTypeID, substituteItemTypeID, quantity, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AllocationResult), args.Error(1)
+}
+
+// Waitlist
+
+func (m *MockRepository) CheckReservationAvailability(ctx context.Context, id int64) ([]domain.Shortfall, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]domain.Shortfall), args.Error(1)
+}
+
+func (m *MockRepository) EnqueueWaitlist(ctx context.Context, reservationID int64, shortfalls []domain.Shortfall, priority int, autoApprove bool, userID *int64) ([]domain.WaitlistEntry, error) {
+	args := m.Called(ctx, reservationID, shortfalls, priority, autoApprove, userID)
+	return args.Get(0).([]domain.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockRepository) ListWaitlist(ctx context.Context, itemTypeID *int64) ([]domain.WaitlistEntry, error) {
+	args := m.Called(ctx, itemTypeID)
+	return args.Get(0).([]domain.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockRepository) UpdateWaitlistPriority(ctx context.Context, id int64, priority int) error {
+	args := m.Called(ctx, id, priority)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetWaitlistStatus(ctx context.Context, ids []int64, status domain.WaitlistStatus) error {
+	args := m.Called(ctx, ids, status)
+	return args.Error(0)
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// AllocateReservation pins concrete assets to a reservation's demand lines.
+// @Summary Allocate Assets to Reservation
+// @Description Creates time-bounded holds on specific serialized assets for every demand line not yet held.
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} domain.AllocationResult
+// @Failure 404 {string} string "Reservation not found"
+// @Failure 409 {string} string "Reservation is not confirmed"
+// @Router /logistics/reservations/{id}/allocate [post]
+func (h *Handler) AllocateReservation(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/allocate")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.repo.AllocateReservation(r.Context(), id, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result == nil {
+		http.Error(w, "reservation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListAssetHolds lists the holds recorded for a reservation.
+// @Summary List Asset Holds
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {array} domain.AssetHold
+// @Router /logistics/reservations/{id}/holds [get]
+func (h *Handler) ListAssetHolds(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/holds")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	holds, err := h.repo.ListAssetHolds(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+// ReleaseAssetHolds releases every active hold of a reservation.
+func (h *Handler) ReleaseAssetHolds(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/holds")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.ReleaseReservationHolds(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReallocateHold moves a hold to another asset.
+// @Summary Reallocate Hold
+// @Description Releases the hold and pins either the given asset or the next free asset of the same item type.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Param holdId path int true "Hold ID"
+// @Param request body object{asset_id=int64} false "Target asset"
+// @Success 200 {object} domain.AssetHold
+// @Failure 404 {string} string "Hold not found on the reservation"
+// @Failure 409 {string} string "Asset is held or unavailable"
+// @Failure 422 {string} string "Hold is not active or asset is the wrong item type"
+// @Router /logistics/reservations/{id}/holds/{holdId}/reallocate [post]
+func (h *Handler) ReallocateHold(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	path = strings.TrimSuffix(path, "/reallocate")
+	parts := strings.Split(path, "/holds/")
+	if len(parts) != 2 {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	holdID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid hold id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		AssetID *int64 `json:"asset_id"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	hold, err := h.repo.ReallocateHold(r.Context(), id, holdID, req.AssetID, h.getUserIDFromContext(r))
+	if err != nil {
+		writeHoldError(w, err)
+		return
+	}
+	if hold == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// SwapHolds exchanges the assets of two holds.
+// @Summary Swap Holds
+// @Tags Logistics
+// @Accept json
+// @Param id path int true "Reservation ID"
+// @Param request body object{hold_id=int64,other_hold_id=int64} true "Holds to swap"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "Hold not found on the reservation"
+// @Failure 409 {string} string "Asset is held for the other window"
+// @Failure 422 {string} string "Hold is not active or assets differ in item type"
+// @Router /logistics/reservations/{id}/holds/swap [post]
+func (h *Handler) SwapHolds(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/holds/swap")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		HoldID      int64 `json:"hold_id"`
+		OtherHoldID int64 `json:"other_hold_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SwapHolds(r.Context(), id, req.HoldID, req.OtherHoldID); err != nil {
+		writeHoldError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeHoldError maps the errors of changing a hold to a response.
+func writeHoldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrHoldNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrHoldConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrHoldNotActive), errors.Is(err, domain.ErrHoldItemType):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
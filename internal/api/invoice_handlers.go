@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// CreateInvoice issues the next invoice of a reservation.
+// @Summary Create Invoice
+// @Description Bills actual check-out to return time since the previous invoice, late-return fees and damage found by inspections. Pass through for a partial invoice, or final once every asset is back.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Param request body domain.InvoiceRequest false "Period"
+// @Success 201 {object} domain.Invoice
+// @Failure 409 {string} string "Nothing to bill or assets still out"
+// @Router /logistics/reservations/{id}/invoices [post]
+func (h *Handler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/invoices")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	req := domain.InvoiceRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.repo.CreateInvoice(r.Context(), id, &req, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInvoice) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if inv == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(inv)
+}
+
+// ListInvoices lists a reservation's invoices.
+// @Summary List Reservation Invoices
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {array} domain.Invoice
+// @Router /logistics/reservations/{id}/invoices [get]
+func (h *Handler) ListInvoices(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/invoices")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	invoices, err := h.repo.ListInvoices(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoices)
+}
+
+// GetInvoice returns an invoice as JSON, CSV or printable HTML.
+// @Summary Get Invoice
+// @Description The html format is laid out for printing, or saving as PDF from the browser's print dialog.
+// @Tags Logistics
+// @Produce json,text/csv,text/html
+// @Param id path int true "Invoice ID"
+// @Param format query string false "json (default), csv or html"
+// @Success 200 {object} domain.Invoice
+// @Failure 404 {string} string "Not Found"
+// @Router /logistics/invoices/{id} [get]
+func (h *Handler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/invoices/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.repo.GetInvoice(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if inv == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(inv)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", inv.Number+".csv"))
+		writeInvoiceCSV(w, inv)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := invoiceHTML.Execute(w, inv); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	default:
+		http.Error(w, "format must be json, csv or html", http.StatusBadRequest)
+	}
+}
+
+// formatAmount renders minor currency units as a decimal amount, e.g. 12345 as 123.45.
+func formatAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func writeInvoiceCSV(w io.Writer, inv *domain.Invoice) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"invoice", "kind", "description", "asset_id", "start", "end", "days", "breakdown", "amount", "currency", "note"})
+	for _, l := range inv.Lines {
+		start, end := "", ""
+		if l.Start != nil {
+			start = l.Start.Format(time.RFC3339)
+		}
+		if l.End != nil {
+			end = l.End.Format(time.RFC3339)
+		}
+		cw.Write([]string{inv.Number, string(l.Kind), l.Description, strconv.FormatInt(l.AssetID, 10), start, end,
+			strconv.Itoa(l.Days), l.Breakdown, formatAmount(l.Amount), inv.Currency, l.Note})
+	}
+	cw.Write([]string{inv.Number, "total", "", "", "", "", "", "", formatAmount(inv.Total), inv.Currency, ""})
+	cw.Flush()
+	return cw.Error()
+}
+
+var invoiceHTML = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount": formatAmount,
+	"date":   func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { width: 100%; border-collapse: collapse; margin-top: 1.5em; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #ddd; }
+td.num, th.num { text-align: right; }
+tfoot td { font-weight: bold; border-top: 2px solid #222; }
+.note { color: #a00; font-size: 0.9em; }
+@media print { body { margin: 0; } @page { margin: 1.5cm; } }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}{{if .IsFinal}} (final){{end}}</h1>
+<p>Reservation #{{.ReservationID}}<br>
+Period: {{date .PeriodStart}} to {{date .PeriodEnd}}<br>
+Issued: {{date .CreatedAt}}</p>
+<table>
+<thead><tr><th>Description</th><th>From</th><th>To</th><th class="num">Days</th><th class="num">Amount ({{.Currency}})</th></tr></thead>
+<tbody>
+{{range .Lines}}<tr>
+<td>{{.Description}}{{if .Breakdown}}<br><small>{{.Breakdown}}</small>{{end}}{{if .Note}}<br><span class="note">{{.Note}}</span>{{end}}</td>
+<td>{{if .Start}}{{date .Start}}{{end}}</td>
+<td>{{if .End}}{{date .End}}{{end}}</td>
+<td class="num">{{if .Days}}{{.Days}}{{end}}</td>
+<td class="num">{{amount .Amount}}</td>
+</tr>
+{{end}}</tbody>
+<tfoot><tr><td colspan="4">Total</td><td class="num">{{amount .Total}}</td></tr></tfoot>
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+func (h *Handler) validateKitTemplate(kt *domain.KitTemplate) error {
+	if kt.Code == "" {
+		return fmt.Errorf("code is required")
+	}
+	if kt.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(kt.Components) == 0 {
+		return fmt.Errorf("at least one component is required")
+	}
+	for _, c := range kt.Components {
+		if c.ItemTypeID == 0 {
+			return fmt.Errorf("component item_type_id is required")
+		}
+		if c.Quantity <= 0 {
+			return fmt.Errorf("component quantity must be positive")
+		}
+	}
+	return nil
+}
+
+// CreateKitTemplate creates a new kit template.
+// @Summary Create Kit Template
+// @Description Defines a kit as a set of component item types with quantities.
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Param kit body domain.KitTemplate true "Kit Template"
+// @Success 201 {object} domain.KitTemplate
+// @Router /catalog/kit-templates [post]
+func (h *Handler) CreateKitTemplate(w http.ResponseWriter, r *http.Request) {
+	var kt domain.KitTemplate
+	if err := json.NewDecoder(r.Body).Decode(&kt); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validateKitTemplate(&kt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateKitTemplate(r.Context(), &kt); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(kt)
+}
+
+// ListKitTemplates lists all kit templates.
+// @Summary List Kit Templates
+// @Tags Catalog
+// @Produce json
+// @Success 200 {array} domain.KitTemplate
+// @Router /catalog/kit-templates [get]
+func (h *Handler) ListKitTemplates(w http.ResponseWriter, r *http.Request) {
+	kits, err := h.repo.ListKitTemplates(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kits)
+}
+
+func (h *Handler) GetKitTemplate(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/kit-templates/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	kt, err := h.repo.GetKitTemplate(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if kt == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kt)
+}
+
+func (h *Handler) UpdateKitTemplate(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/kit-templates/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var kt domain.KitTemplate
+	if err := json.NewDecoder(r.Body).Decode(&kt); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	kt.ID = id
+	if err := h.validateKitTemplate(&kt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.UpdateKitTemplate(r.Context(), &kt); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kt)
+}
+
+func (h *Handler) DeleteKitTemplate(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/kit-templates/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.DeleteKitTemplate(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetKitAvailability returns how many complete kits can be built in a window.
+// @Summary Kit Availability
+// @Tags Catalog
+// @Produce json
+// @Param id path int true "Kit Template ID"
+// @Param start query string true "Window start (RFC3339 or YYYY-MM-DD)"
+// @Param end query string true "Window end (RFC3339 or YYYY-MM-DD)"
+// @Success 200 {object} object{kit_template_id=int64,available=int}
+// @Router /catalog/kit-templates/{id}/availability [get]
+func (h *Handler) GetKitAvailability(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/kit-templates/")
+	idStr = strings.TrimSuffix(idStr, "/availability")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	start, err := parseDateParam(r.URL.Query().Get("start"))
+	if err != nil {
+		http.Error(w, "invalid start date", http.StatusBadRequest)
+		return
+	}
+	end, err := parseDateParam(r.URL.Query().Get("end"))
+	if err != nil {
+		http.Error(w, "invalid end date", http.StatusBadRequest)
+		return
+	}
+
+	avail, err := h.repo.GetKitAvailableQuantity(r.Context(), id, start, end)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"kit_template_id": id,
+		"available":       avail,
+	})
+}
+
+// DispatchKit checks out the assets that make up one instance of a kit demand.
+// @Summary Dispatch Kit
+// @Description Validates the assets against the kit template and records a kit instance with its CheckOutActions.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Param request body object{demand_id=int64,asset_ids=[]int64,to_location_id=int64} true "Kit Dispatch Data"
+// @Success 201 {object} domain.KitInstance
+// @Router /logistics/reservations/{id}/dispatch-kit [post]
+func (h *Handler) DispatchKit(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/dispatch-kit")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		DemandID       int64   `json:"demand_id"`
+		AssetIDs       []int64 `json:"asset_ids"`
+		FromLocationID *int64  `json:"from_location_id"`
+		ToLocationID   *int64  `json:"to_location_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	agentIDVal := h.getUserIDFromContext(r)
+	if agentIDVal == nil {
+		http.Error(w, "agent id missing from context", http.StatusUnauthorized)
+		return
+	}
+
+	ki, err := h.repo.CheckOutKit(r.Context(), id, req.DemandID, req.AssetIDs, *agentIDVal, req.FromLocationID, req.ToLocationID)
+	if err != nil {
+		if errors.Is(err, domain.ErrKitMismatch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrHoldConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"reservation_id":  id,
+		"kit_instance_id": ki.ID,
+		"kit_template_id": ki.KitTemplateID,
+		"asset_count":     len(ki.AssetIDs),
+		"agent_id":        agentIDVal,
+	})
+	h.repo.AppendEvent(r.Context(), nil, &domain.OutboxEvent{
+		Type:    domain.EventAssetTransitioned,
+		Payload: payload,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ki)
+}
+
+// ListKitInstances lists the kit instances checked out for a reservation.
+// @Summary List Kit Instances
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {array} domain.KitInstance
+// @Router /logistics/reservations/{id}/kit-instances [get]
+func (h *Handler) ListKitInstances(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/kit-instances")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	instances, err := h.repo.ListKitInstances(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(instances)
+}
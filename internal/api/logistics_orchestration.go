@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -71,6 +72,10 @@ func (h *Handler) BatchDispatchAssets(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.repo.BatchCheckOut(r.Context(), id, req.AssetIDs, *agentIDVal, req.FromLocationID, req.ToLocationID); err != nil {
+		if errors.Is(err, domain.ErrHoldConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// GetOverdueReport lists assets still out after their reservation ended.
+// @Summary Overdue Report
+// @Description Assets checked out on reservations whose end time has passed with no return, longest overdue first, with how far each has been escalated.
+// @Tags Logistics
+// @Produce json
+// @Param min_level query int false "Only assets escalated at least this many steps"
+// @Success 200 {object} domain.OverdueReport
+// @Router /logistics/overdue [get]
+func (h *Handler) GetOverdueReport(w http.ResponseWriter, r *http.Request) {
+	minLevel := 0
+	if v := r.URL.Query().Get("min_level"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid min_level", http.StatusBadRequest)
+			return
+		}
+		minLevel = n
+	}
+
+	now := time.Now()
+	assets, err := h.repo.ListOverdueAssets(r.Context(), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if minLevel > 0 {
+		filtered := []domain.OverdueAsset{}
+		for _, a := range assets {
+			if a.EscalationLevel >= minLevel {
+				filtered = append(filtered, a)
+			}
+		}
+		assets = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.NewOverdueReport(assets, now))
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// CreatePickList generates a pick list for a scheduled delivery.
+func (h *Handler) CreatePickList(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/deliveries/")
+	idStr = strings.TrimSuffix(idStr, "/pick-lists")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid delivery id", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional: without one, pick from anywhere for no shipment yet
+	var req domain.PickListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	pl, err := h.repo.CreatePickList(r.Context(), id, &req, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrPickList) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to create pick list: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pl == nil {
+		http.Error(w, "delivery not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pl)
+}
+
+// ListPickLists lists pick lists, optionally for one delivery.
+func (h *Handler) ListPickLists(w http.ResponseWriter, r *http.Request) {
+	var deliveryID *int64
+	if v := r.URL.Query().Get("delivery_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid delivery_id", http.StatusBadRequest)
+			return
+		}
+		deliveryID = &id
+	}
+
+	lists, err := h.repo.ListPickLists(r.Context(), deliveryID)
+	if err != nil {
+		log.Printf("failed to list pick lists: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(lists)
+}
+
+// pickListID parses the id out of /v1/logistics/pick-lists/{id}{suffix}.
+func pickListID(w http.ResponseWriter, r *http.Request, suffix string) (int64, bool) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/pick-lists/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid pick list id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writePickList writes the outcome of a pick list operation.
+func writePickList(w http.ResponseWriter, pl *domain.PickList, err error, action string) {
+	if err != nil {
+		if errors.Is(err, domain.ErrPickList) || errors.Is(err, domain.ErrHoldConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to %s pick list: %v", action, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pl == nil {
+		http.Error(w, "pick list not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(pl)
+}
+
+// GetPickList returns a pick list with its lines, grouped stops and progress.
+func (h *Handler) GetPickList(w http.ResponseWriter, r *http.Request) {
+	id, ok := pickListID(w, r, "")
+	if !ok {
+		return
+	}
+
+	pl, err := h.repo.GetPickList(r.Context(), id)
+	writePickList(w, pl, err, "get")
+}
+
+// ScanPickList records a scanned asset tag or serial number against a pick list.
+func (h *Handler) ScanPickList(w http.ResponseWriter, r *http.Request) {
+	id, ok := pickListID(w, r, "/scan")
+	if !ok {
+		return
+	}
+
+	var req domain.PickScan
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	pl, err := h.repo.ScanPickList(r.Context(), id, req.Code, h.getUserIDFromContext(r))
+	writePickList(w, pl, err, "scan")
+}
+
+// CompletePickList closes a pick list and allocates the picked assets to its shipment.
+func (h *Handler) CompletePickList(w http.ResponseWriter, r *http.Request) {
+	id, ok := pickListID(w, r, "/complete")
+	if !ok {
+		return
+	}
+
+	var req domain.CompletePickRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	agentIDVal := h.getUserIDFromContext(r)
+	if agentIDVal == nil {
+		http.Error(w, "agent id missing from context", http.StatusUnauthorized)
+		return
+	}
+
+	pl, err := h.repo.CompletePickList(r.Context(), id, req.ShipmentID, *agentIDVal)
+	writePickList(w, pl, err, "complete")
+}
+
+// CancelPickList abandons an open pick list.
+func (h *Handler) CancelPickList(w http.ResponseWriter, r *http.Request) {
+	id, ok := pickListID(w, r, "/cancel")
+	if !ok {
+		return
+	}
+
+	pl, err := h.repo.CancelPickList(r.Context(), id)
+	writePickList(w, pl, err, "cancel")
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_ScanPickList(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("ScanPickList", mock.Anything, int64(11), "CAM-001", (*int64)(nil)).
+		Return(&domain.PickList{ID: 11, Status: domain.PickListOpen}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/pick-lists/11/scan", bytes.NewBufferString(`{"code":" CAM-001 "}`))
+	w := httptest.NewRecorder()
+	h.ScanPickList(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/pick-lists/11/scan", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	h.ScanPickList(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_CompletePickList(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/pick-lists/11/complete", nil)
+	w := httptest.NewRecorder()
+	h.CompletePickList(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	shipmentID := int64(42)
+	repo.On("CompletePickList", mock.Anything, int64(11), &shipmentID, int64(1)).
+		Return(&domain.PickList{ID: 11, ShipmentID: &shipmentID, Status: domain.PickListCompleted}, nil)
+
+	body, _ := json.Marshal(domain.CompletePickRequest{ShipmentID: &shipmentID})
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/pick-lists/11/complete", bytes.NewBuffer(body))
+	ctx := context.WithValue(req.Context(), UserContextKey, map[string]interface{}{"user_id": float64(1)})
+	req = req.WithContext(ctx)
+	w = httptest.NewRecorder()
+	h.CompletePickList(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,386 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// CustomerContextKey holds the *domain.CustomerToken a portal request authenticated with.
+const CustomerContextKey contextKey = "customer"
+
+// CustomerAuthMiddleware authenticates portal requests by company-scoped customer
+// token. Staff JWTs are not accepted here, and customer tokens are not accepted
+// anywhere else.
+func (h *Handler) CustomerAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(token, domain.CustomerTokenPrefix) {
+			http.Error(w, "customer token required", http.StatusUnauthorized)
+			return
+		}
+
+		t, err := h.repo.AuthenticateCustomerToken(r.Context(), domain.HashCustomerToken(token), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if t == nil {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), CustomerContextKey, t)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func customerFromContext(r *http.Request) *domain.CustomerToken {
+	t, _ := r.Context().Value(CustomerContextKey).(*domain.CustomerToken)
+	return t
+}
+
+// Customer Tokens (Admin)
+
+// CreateCustomerToken issues a portal token for a person at a customer company.
+// @Summary Create Customer Token
+// @Description The plaintext token is only returned in this response. The person must currently hold a role at the company.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param token body domain.CustomerToken true "companyId, personId, name and optional expiresAt"
+// @Success 201 {object} domain.CustomerToken
+// @Failure 422 {string} string "Person has no current role at the company"
+// @Router /admin/customer-tokens [post]
+func (h *Handler) CreateCustomerToken(w http.ResponseWriter, r *http.Request) {
+	var req domain.CustomerToken
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t := domain.CustomerToken{
+		CompanyID:       req.CompanyID,
+		PersonID:        req.PersonID,
+		Name:            req.Name,
+		ExpiresAt:       req.ExpiresAt,
+		CreatedByUserID: h.getUserIDFromContext(r),
+	}
+	hash, err := t.Issue()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.repo.CreateCustomerToken(r.Context(), &t, hash); err != nil {
+		if errors.Is(err, domain.ErrCustomerScope) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// ListCustomerTokens lists issued customer tokens, without their secrets.
+// @Summary List Customer Tokens
+// @Tags Admin
+// @Produce json
+// @Param company_id query int false "Company ID"
+// @Success 200 {array} domain.CustomerToken
+// @Router /admin/customer-tokens [get]
+func (h *Handler) ListCustomerTokens(w http.ResponseWriter, r *http.Request) {
+	var companyID *int64
+	if v := r.URL.Query().Get("company_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid company_id", http.StatusBadRequest)
+			return
+		}
+		companyID = &id
+	}
+
+	tokens, err := h.repo.ListCustomerTokens(r.Context(), companyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokeCustomerToken revokes a customer token.
+// @Summary Revoke Customer Token
+// @Tags Admin
+// @Param id path int true "Token ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "Not Found"
+// @Router /admin/customer-tokens/{id} [delete]
+func (h *Handler) RevokeCustomerToken(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/admin/customer-tokens/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.repo.RevokeCustomerToken(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if t == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Portal
+
+// PortalCatalog lists the active item types customers can request.
+// @Summary Portal Catalog
+// @Tags Portal
+// @Produce json
+// @Success 200 {array} domain.ItemType
+// @Router /portal/catalog [get]
+func (h *Handler) PortalCatalog(w http.ResponseWriter, r *http.Request) {
+	results, err := h.repo.ListItemTypes(r.Context(), false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// PortalAvailability samples the availability of an active catalog item type over a
+// bounded window.
+// @Summary Portal Availability
+// @Description The window may span at most 180 days and 400 buckets.
+// @Tags Portal
+// @Produce json
+// @Param item_type_id query int true "Item Type ID"
+// @Param start query string true "Start date (YYYY-MM-DD or RFC3339)"
+// @Param end query string true "End date (YYYY-MM-DD or RFC3339)"
+// @Param granularity query string false "hour, day (default) or week"
+// @Success 200 {array} domain.AvailabilityPoint
+// @Failure 404 {string} string "Not in the catalog"
+// @Router /portal/availability [get]
+func (h *Handler) PortalAvailability(w http.ResponseWriter, r *http.Request) {
+	id, start, end, granularity, ok := parseAvailabilityQuery(w, r)
+	if !ok {
+		return
+	}
+	if err := domain.ValidatePortalAvailability(start, end, granularity); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Inactive item types are not in the catalog, so they look like missing ones
+	it, err := h.repo.GetItemTypeByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if it == nil || !it.IsActive {
+		http.NotFound(w, r)
+		return
+	}
+
+	results, err := h.repo.GetAvailabilityTimeline(r.Context(), id, start, end, granularity)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// PortalListReservations lists the company's reservations.
+// @Summary Portal List Reservations
+// @Tags Portal
+// @Produce json
+// @Success 200 {array} domain.RentalReservation
+// @Router /portal/reservations [get]
+func (h *Handler) PortalListReservations(w http.ResponseWriter, r *http.Request) {
+	results, err := h.repo.ListCompanyReservations(r.Context(), customerFromContext(r).CompanyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// PortalCreateReservation creates a draft reservation under the token's person.
+// @Summary Portal Create Reservation
+// @Description Only the name, window, metadata and demands are taken from the body. The draft is reviewed once it is submitted.
+// @Tags Portal
+// @Accept json
+// @Produce json
+// @Param reservation body domain.RentalReservation true "Reservation"
+// @Success 201 {object} domain.RentalReservation
+// @Router /portal/reservations [post]
+func (h *Handler) PortalCreateReservation(w http.ResponseWriter, r *http.Request) {
+	var rr domain.RentalReservation
+	if err := json.NewDecoder(r.Body).Decode(&rr); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := customerFromContext(r).PrepareDraft(&rr); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateRentalReservation(r.Context(), &rr); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rr)
+}
+
+// PortalGetReservation returns one of the company's reservations.
+// @Summary Portal Get Reservation
+// @Tags Portal
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} domain.RentalReservation
+// @Failure 404 {string} string "Not Found"
+// @Router /portal/reservations/{id} [get]
+func (h *Handler) PortalGetReservation(w http.ResponseWriter, r *http.Request) {
+	rr, ok := h.portalReservation(w, r, "")
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rr)
+}
+
+// PortalSubmitReservation submits one of the company's drafts for review.
+// @Summary Portal Submit Reservation
+// @Tags Portal
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} domain.RentalReservation
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Not a draft"
+// @Router /portal/reservations/{id}/submit [post]
+func (h *Handler) PortalSubmitReservation(w http.ResponseWriter, r *http.Request) {
+	rr, ok := h.portalReservation(w, r, "/submit")
+	if !ok {
+		return
+	}
+
+	req := domain.ReservationTransitionRequest{Action: domain.ReservationActionSubmit}
+	if _, ok := h.transitionReservation(w, r, rr.ID, &req); !ok {
+		return
+	}
+	rr, err := h.repo.GetRentalReservationByID(r.Context(), rr.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rr)
+}
+
+// PortalGetFulfillment reports what has been sent out and returned against one of the
+// company's reservations.
+// @Summary Portal Get Fulfillment
+// @Tags Portal
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} domain.RentalFulfillmentStatus
+// @Failure 404 {string} string "Not Found"
+// @Router /portal/reservations/{id}/fulfillment [get]
+func (h *Handler) PortalGetFulfillment(w http.ResponseWriter, r *http.Request) {
+	rr, ok := h.portalReservation(w, r, "/fulfillment")
+	if !ok {
+		return
+	}
+
+	status, err := h.repo.GetRentalFulfillmentStatus(r.Context(), rr.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// PortalListShipments lists the shipments carrying the company's assets, or those of
+// one of its reservations.
+// @Summary Portal List Shipments
+// @Tags Portal
+// @Produce json
+// @Param id path int false "Reservation ID"
+// @Success 200 {array} domain.Shipment
+// @Router /portal/shipments [get]
+// @Router /portal/reservations/{id}/shipments [get]
+func (h *Handler) PortalListShipments(w http.ResponseWriter, r *http.Request) {
+	var reservationID *int64
+	if strings.HasPrefix(r.URL.Path, "/v1/portal/reservations/") {
+		rr, ok := h.portalReservation(w, r, "/shipments")
+		if !ok {
+			return
+		}
+		reservationID = &rr.ID
+	}
+
+	results, err := h.repo.ListCompanyShipments(r.Context(), customerFromContext(r).CompanyID, reservationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// portalReservation loads the reservation a /v1/portal/reservations/{id}{suffix} path
+// names, writing a 404 unless it belongs to the caller's company.
+func (h *Handler) portalReservation(w http.ResponseWriter, r *http.Request, suffix string) (*domain.RentalReservation, bool) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/portal/reservations/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	rr, err := h.repo.GetCompanyReservation(r.Context(), customerFromContext(r).CompanyID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if rr == nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return rr, true
+}
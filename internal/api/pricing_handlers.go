@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// CreateRateCard creates a rate card for an item type.
+// @Summary Create Rate Card
+// @Description Sets daily, weekly and monthly rates for an item type. Cards with a company_id override the general card for that company.
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Param card body domain.RateCard true "Rate Card"
+// @Success 201 {object} domain.RateCard
+// @Router /catalog/rate-cards [post]
+func (h *Handler) CreateRateCard(w http.ResponseWriter, r *http.Request) {
+	rc := domain.RateCard{Currency: "USD", WeekendRule: domain.WeekendStandard, IsActive: true}
+	if err := json.NewDecoder(r.Body).Decode(&rc); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := rc.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateRateCard(r.Context(), &rc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rc)
+}
+
+// ListRateCards lists rate cards.
+// @Summary List Rate Cards
+// @Tags Catalog
+// @Produce json
+// @Param item_type_id query int false "Only cards for this item type"
+// @Success 200 {array} domain.RateCard
+// @Router /catalog/rate-cards [get]
+func (h *Handler) ListRateCards(w http.ResponseWriter, r *http.Request) {
+	var itemTypeID *int64
+	if s := r.URL.Query().Get("item_type_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid item_type_id", http.StatusBadRequest)
+			return
+		}
+		itemTypeID = &id
+	}
+
+	cards, err := h.repo.ListRateCards(r.Context(), itemTypeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cards)
+}
+
+func (h *Handler) GetRateCard(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/rate-cards/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	rc, err := h.repo.GetRateCard(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rc)
+}
+
+func (h *Handler) UpdateRateCard(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/rate-cards/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var rc domain.RateCard
+	if err := json.NewDecoder(r.Body).Decode(&rc); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	rc.ID = id
+	if err := rc.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.UpdateRateCard(r.Context(), &rc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rc)
+}
+
+func (h *Handler) DeleteRateCard(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/rate-cards/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.DeleteRateCard(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// QuoteReservation prices a draft reservation without saving anything.
+// @Summary Quote Reservation
+// @Description Prices each demand line, kits included, from the rate cards of the customer's company (or company_id). Lines without a usable rate card are listed under unpriced.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param reservation body domain.RentalReservation true "Draft Reservation"
+// @Param company_id query int false "Price with this company's rate cards"
+// @Success 200 {object} domain.Quote
+// @Router /logistics/quotes [post]
+func (h *Handler) QuoteReservation(w http.ResponseWriter, r *http.Request) {
+	var rr domain.RentalReservation
+	if err := json.NewDecoder(r.Body).Decode(&rr); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !rr.EndTime.After(rr.StartTime) {
+		http.Error(w, "endTime must be after startTime", http.StatusBadRequest)
+		return
+	}
+
+	var companyID *int64
+	if s := r.URL.Query().Get("company_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid company_id", http.StatusBadRequest)
+			return
+		}
+		companyID = &id
+	}
+
+	quote, err := h.repo.QuoteReservation(r.Context(), &rr, companyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+// GetReservationQuote returns a reservation's quote.
+// @Summary Get Reservation Quote
+// @Description Returns the quote stored when the reservation was approved, or a quote at current rates before approval.
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} domain.Quote
+// @Failure 404 {string} string "Not Found"
+// @Router /logistics/reservations/{id}/quote [get]
+func (h *Handler) GetReservationQuote(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/quote")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.repo.GetReservationQuote(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if quote == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
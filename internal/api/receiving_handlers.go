@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// ShipmentReceiving opens a receiving session for an inbound shipment (POST) or lists
+// its sessions (GET).
+func (h *Handler) ShipmentReceiving(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
+	idStr = strings.TrimSuffix(idStr, "/receiving")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		sessions, err := h.repo.ListReceivingSessions(r.Context(), id)
+		if err != nil {
+			log.Printf("failed to list receiving sessions: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(sessions)
+		return
+	}
+
+	var req domain.ReceivingSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s, err := h.repo.OpenReceivingSession(r.Context(), id, &req, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrReceiving) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to open receiving session: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// receivingSessionID parses the id out of /v1/logistics/receiving/{id}{suffix}.
+func receivingSessionID(w http.ResponseWriter, r *http.Request, suffix string) (int64, bool) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/receiving/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid session id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeReceivingSession writes the outcome of a receiving session operation.
+func writeReceivingSession(w http.ResponseWriter, s *domain.ReceivingSession, err error, action string) {
+	if err != nil {
+		if errors.Is(err, domain.ErrReceiving) || errors.Is(err, domain.ErrInvalidShipmentTransition) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to %s receiving session: %v", action, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s == nil {
+		http.Error(w, "receiving session not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s)
+}
+
+// GetReceivingSession returns a session with its scans and discrepancy report.
+func (h *Handler) GetReceivingSession(w http.ResponseWriter, r *http.Request) {
+	id, ok := receivingSessionID(w, r, "")
+	if !ok {
+		return
+	}
+
+	s, err := h.repo.GetReceivingSession(r.Context(), id)
+	writeReceivingSession(w, s, err, "get")
+}
+
+// ScanReceiving records a scanned asset or container tag in a receiving session.
+func (h *Handler) ScanReceiving(w http.ResponseWriter, r *http.Request) {
+	id, ok := receivingSessionID(w, r, "/scan")
+	if !ok {
+		return
+	}
+
+	var req domain.PickScan
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	s, err := h.repo.ScanReceiving(r.Context(), id, req.Code, h.getUserIDFromContext(r))
+	writeReceivingSession(w, s, err, "scan")
+}
+
+// CloseReceivingSession returns what was received and closes the session with its
+// discrepancy report.
+func (h *Handler) CloseReceivingSession(w http.ResponseWriter, r *http.Request) {
+	id, ok := receivingSessionID(w, r, "/close")
+	if !ok {
+		return
+	}
+
+	agentIDVal := h.getUserIDFromContext(r)
+	if agentIDVal == nil {
+		http.Error(w, "agent id missing from context", http.StatusUnauthorized)
+		return
+	}
+
+	s, err := h.repo.CloseReceivingSession(r.Context(), id, *agentIDVal)
+	writeReceivingSession(w, s, err, "close")
+}
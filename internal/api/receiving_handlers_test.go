@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_ShipmentReceiving(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	dock := int64(2)
+	repo.On("OpenReceivingSession", mock.Anything, int64(42), &domain.ReceivingSessionRequest{ToPlaceID: &dock}, (*int64)(nil)).
+		Return(&domain.ReceivingSession{ID: 5, ShipmentID: 42, ReservationID: 9, Status: domain.ReceivingOpen}, nil)
+	repo.On("ListReceivingSessions", mock.Anything, int64(42)).Return([]domain.ReceivingSession{{ID: 5}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/shipments/42/receiving", bytes.NewBufferString(`{"toPlaceId":2}`))
+	w := httptest.NewRecorder()
+	h.ShipmentReceiving(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/logistics/shipments/42/receiving", nil)
+	w = httptest.NewRecorder()
+	h.ShipmentReceiving(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_CloseReceivingSession(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("CloseReceivingSession", mock.Anything, int64(5), int64(1)).
+		Return(&domain.ReceivingSession{ID: 5, Status: domain.ReceivingClosed, Report: &domain.DiscrepancyReport{
+			ExpectedCount: 2, ReceivedCount: 1, Missing: []domain.ReceivingAsset{{AssetID: 2, AssetTag: "MIC-2"}},
+		}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/receiving/5/close", nil)
+	w := httptest.NewRecorder()
+	h.CloseReceivingSession(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/receiving/5/close", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserContextKey, map[string]interface{}{"user_id": float64(1)}))
+	w = httptest.NewRecorder()
+	h.CloseReceivingSession(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"missing":[{"assetId":2,"assetTag":"MIC-2"`)
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// PlanDeliveryRoutes plans a day's scheduled deliveries into an ordered list of stops
+// for each vehicle.
+func (h *Handler) PlanDeliveryRoutes(w http.ResponseWriter, r *http.Request) {
+	var req domain.RoutePlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.repo.PlanDeliveryRoutes(r.Context(), &req)
+	if err != nil {
+		log.Printf("failed to plan delivery routes: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if plan == nil {
+		http.Error(w, "depot not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(plan)
+}
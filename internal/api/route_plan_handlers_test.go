@@ -0,0 +1,36 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_PlanDeliveryRoutes(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("PlanDeliveryRoutes", mock.Anything, mock.MatchedBy(func(req *domain.RoutePlanRequest) bool {
+		return req.DepotPlaceID == 1 && req.ServiceMinutes == domain.DefaultServiceMinutes
+	})).Return(&domain.RoutePlan{DepotPlaceID: 1, Routes: []domain.VehicleRoute{}, Unrouted: []domain.UnroutedStop{}}, nil)
+
+	body := `{"date":"2026-10-16T00:00:00Z","depotPlaceId":1,"vehicles":[{"name":"Van 1","maxWeightKg":800}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/logistics/route-plans", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	h.PlanDeliveryRoutes(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"depotPlaceId":1`)
+
+	// No vehicles
+	body = `{"date":"2026-10-16T00:00:00Z","depotPlaceId":1,"vehicles":[]}`
+	req = httptest.NewRequest(http.MethodPost, "/v1/logistics/route-plans", bytes.NewBufferString(body))
+	w = httptest.NewRecorder()
+	h.PlanDeliveryRoutes(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	repo.AssertNumberOfCalls(t, "PlanDeliveryRoutes", 1)
+}
@@ -104,6 +104,36 @@ func NewRouter(h *Handler) http.Handler {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/v1/catalog/kit-templates", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateKitTemplate(w, r)
+		case http.MethodGet:
+			h.ListKitTemplates(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/catalog/substitution-rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateSubstitutionRule(w, r)
+		case http.MethodGet:
+			h.ListSubstitutionRules(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/catalog/rate-cards", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateRateCard(w, r)
+		case http.MethodGet:
+			h.ListRateCards(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/v1/fleet/build-specs", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -142,6 +172,23 @@ func NewRouter(h *Handler) http.Handler {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/v1/admin/customer-tokens", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.ListCustomerTokens(w, r)
+		case http.MethodPost:
+			h.CreateCustomerToken(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/admin/customer-tokens/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			h.RevokeCustomerToken(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
 	mux.HandleFunc("/v1/admin/ingest/test-auth", h.TestAuth)
 	mux.HandleFunc("/v1/admin/ingest/sources", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -257,6 +304,54 @@ func NewRouter(h *Handler) http.Handler {
 		}
 	})
 
+	mux.HandleFunc("/v1/catalog/substitution-rules/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetSubstitutionRule(w, r)
+		case http.MethodPut:
+			h.UpdateSubstitutionRule(w, r)
+		case http.MethodDelete:
+			h.DeleteSubstitutionRule(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/catalog/rate-cards/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetRateCard(w, r)
+		case http.MethodPut:
+			h.UpdateRateCard(w, r)
+		case http.MethodDelete:
+			h.DeleteRateCard(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/catalog/kit-templates/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/availability") {
+			if r.Method == http.MethodGet {
+				h.GetKitAvailability(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			h.GetKitTemplate(w, r)
+		case http.MethodPut:
+			h.UpdateKitTemplate(w, r)
+		case http.MethodDelete:
+			h.DeleteKitTemplate(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
 	mux.HandleFunc("/v1/fleet/item-types/", func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/recall") {
 			if r.Method == http.MethodPost {
@@ -293,6 +388,31 @@ func NewRouter(h *Handler) http.Handler {
 		}
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	})
+	mux.HandleFunc("/v1/inventory/stock/movements", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.RecordStockMovement(w, r)
+		case http.MethodGet:
+			h.ListStockMovements(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/inventory/stock/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/lots") {
+			if r.Method == http.MethodGet {
+				h.ListStockLots(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodGet {
+			h.GetStockSummary(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
 	mux.HandleFunc("/v1/inventory/assets/", func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/status") {
 			if r.Method == http.MethodPatch {
@@ -415,6 +535,17 @@ func NewRouter(h *Handler) http.Handler {
 			h.CancelRentalReservation(w, r)
 			return
 		}
+		if strings.HasSuffix(r.URL.Path, "/transitions") {
+			switch r.Method {
+			case http.MethodGet:
+				h.GetReservationLifecycle(w, r)
+			case http.MethodPost:
+				h.TransitionRentalReservation(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
 		if strings.HasSuffix(r.URL.Path, "/fulfillment") {
 			h.GetRentalFulfillment(w, r)
 			return
@@ -435,10 +566,238 @@ func NewRouter(h *Handler) http.Handler {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
+		if strings.HasSuffix(r.URL.Path, "/dispatch-kit") {
+			if r.Method == http.MethodPost {
+				h.DispatchKit(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/kit-instances") {
+			if r.Method == http.MethodGet {
+				h.ListKitInstances(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/allocate") {
+			if r.Method == http.MethodPost {
+				h.AllocateReservation(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/substitutions") {
+			if r.Method == http.MethodPost {
+				h.ApplySubstitution(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/holds/swap") {
+			if r.Method == http.MethodPost {
+				h.SwapHolds(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/reallocate") {
+			if r.Method == http.MethodPost {
+				h.ReallocateHold(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/holds") {
+			switch r.Method {
+			case http.MethodGet:
+				h.ListAssetHolds(w, r)
+			case http.MethodDelete:
+				h.ReleaseAssetHolds(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/history") {
+			if r.Method == http.MethodGet {
+				h.ListReservationChanges(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/conflicts") {
+			if r.Method == http.MethodGet {
+				h.GetReservationConflicts(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/quote") {
+			if r.Method == http.MethodGet {
+				h.GetReservationQuote(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/invoices") {
+			switch r.Method {
+			case http.MethodPost:
+				h.CreateInvoice(w, r)
+			case http.MethodGet:
+				h.ListInvoices(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
 
 		switch r.Method {
 		case http.MethodGet:
 			h.GetRentalReservation(w, r)
+		case http.MethodPatch:
+			h.ModifyRentalReservation(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Logistics (Scheduling)
+	mux.HandleFunc("/v1/logistics/schedule", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.ScheduleReservation(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Logistics (Pricing & Invoicing)
+	mux.HandleFunc("/v1/logistics/quotes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.QuoteReservation(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/v1/logistics/invoices/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.GetInvoice(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Logistics (Overdue)
+	mux.HandleFunc("/v1/logistics/overdue", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.GetOverdueReport(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Logistics (Claims)
+	mux.HandleFunc("/v1/logistics/claims", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateClaim(w, r)
+		case http.MethodGet:
+			h.ListClaims(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/logistics/claims/", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if strings.HasSuffix(path, "/evidence") {
+			if r.Method == http.MethodPost {
+				h.AddClaimEvidence(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(path, "/status") {
+			if r.Method == http.MethodPost {
+				h.UpdateClaimStatus(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodGet {
+			h.GetClaim(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Logistics (Waitlist)
+	mux.HandleFunc("/v1/logistics/waitlist", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.ListWaitlist(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/logistics/waitlist/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			h.UpdateWaitlistEntry(w, r)
+		case http.MethodDelete:
+			h.RemoveWaitlistEntry(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Logistics (Recurring Reservations)
+	mux.HandleFunc("/v1/logistics/series", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateReservationSeries(w, r)
+		case http.MethodGet:
+			h.ListReservationSeries(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/logistics/series/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/approve") {
+			if r.Method == http.MethodPost {
+				h.ApproveReservationSeries(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/availability") {
+			if r.Method == http.MethodGet {
+				h.CheckSeriesAvailability(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.GetReservationSeries(w, r)
+		case http.MethodPatch:
+			h.EditReservationSeries(w, r)
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
@@ -456,6 +815,14 @@ func NewRouter(h *Handler) http.Handler {
 		}
 	})
 	mux.HandleFunc("/v1/logistics/deliveries/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/pick-lists") {
+			if r.Method == http.MethodPost {
+				h.CreatePickList(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 		switch r.Method {
 		case http.MethodGet:
 			h.GetScheduledDelivery(w, r)
@@ -464,6 +831,115 @@ func NewRouter(h *Handler) http.Handler {
 		}
 	})
 
+	// Logistics (Containers)
+	mux.HandleFunc("/v1/logistics/containers", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateContainer(w, r)
+		case http.MethodGet:
+			h.ListContainers(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/logistics/containers/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/contents") {
+			if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+				h.UpdateContainerContents(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/check-out") || strings.HasSuffix(r.URL.Path, "/return") {
+			if r.Method == http.MethodPost {
+				h.MoveContainer(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodGet {
+			h.GetContainer(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Logistics (Receiving)
+	mux.HandleFunc("/v1/logistics/receiving/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/scan") {
+			if r.Method == http.MethodPost {
+				h.ScanReceiving(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/close") {
+			if r.Method == http.MethodPost {
+				h.CloseReceivingSession(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodGet {
+			h.GetReceivingSession(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Logistics (Picking)
+	mux.HandleFunc("/v1/logistics/pick-lists", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.ListPickLists(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc("/v1/logistics/pick-lists/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/scan") {
+			if r.Method == http.MethodPost {
+				h.ScanPickList(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/complete") {
+			if r.Method == http.MethodPost {
+				h.CompletePickList(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/cancel") {
+			if r.Method == http.MethodPost {
+				h.CancelPickList(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodGet {
+			h.GetPickList(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Logistics (Route Planning)
+	mux.HandleFunc("/v1/logistics/route-plans", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.PlanDeliveryRoutes(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
 	// Logistics (Shipments)
 	mux.HandleFunc("/v1/logistics/shipments", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -476,6 +952,85 @@ func NewRouter(h *Handler) http.Handler {
 		}
 	})
 	mux.HandleFunc("/v1/logistics/shipments/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/pack") {
+			if r.Method == http.MethodPost {
+				h.PackShipment(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/receiving") {
+			if r.Method == http.MethodPost || r.Method == http.MethodGet {
+				h.ShipmentReceiving(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/manifest") {
+			if r.Method == http.MethodGet {
+				h.GetShipmentManifest(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/legs") {
+			if r.Method == http.MethodPut {
+				h.SetShipmentLegs(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/consolidate") {
+			if r.Method == http.MethodPost {
+				h.ConsolidateShipment(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/status") {
+			if r.Method == http.MethodPost {
+				h.TransitionShipment(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/label") {
+			switch r.Method {
+			case http.MethodPost:
+				h.CreateShipmentLabel(w, r)
+			case http.MethodDelete:
+				h.VoidShipmentLabel(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/tracking/refresh") {
+			if r.Method == http.MethodPost {
+				h.RefreshShipmentTracking(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/tracking") {
+			switch r.Method {
+			case http.MethodGet:
+				h.ListTrackingEvents(w, r)
+			case http.MethodPost:
+				h.RecordTrackingEvents(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			h.GetShipment(w, r)
@@ -493,6 +1048,23 @@ func NewRouter(h *Handler) http.Handler {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/v1/logistics/rates", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.QuoteRates(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc("/v1/logistics/transfers", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.TransferAssets(w, r)
+		case http.MethodGet:
+			h.ListAssetTransfers(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
 
 	// Intelligence
 	mux.HandleFunc("/v1/intelligence/availability", func(w http.ResponseWriter, r *http.Request) {
@@ -629,6 +1201,71 @@ func NewRouter(h *Handler) http.Handler {
 		}
 	})
 
+	// Customer Portal (customer tokens)
+	mux.HandleFunc("/v1/portal/catalog", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.PortalCatalog(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc("/v1/portal/availability", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.PortalAvailability(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc("/v1/portal/reservations", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.PortalListReservations(w, r)
+		case http.MethodPost:
+			h.PortalCreateReservation(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/portal/reservations/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/submit") {
+			if r.Method == http.MethodPost {
+				h.PortalSubmitReservation(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/fulfillment") {
+			if r.Method == http.MethodGet {
+				h.PortalGetFulfillment(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/shipments") {
+			if r.Method == http.MethodGet {
+				h.PortalListShipments(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.PortalGetReservation(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	mux.HandleFunc("/v1/portal/shipments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.PortalListShipments(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
 	// Swagger UI (Public)
 	mux.HandleFunc("/swagger/", httpSwagger.WrapHandler)
 
@@ -644,6 +1281,12 @@ func NewRouter(h *Handler) http.Handler {
 			return
 		}
 
+		// The customer portal takes company-scoped customer tokens instead
+		if strings.HasPrefix(path, "/v1/portal/") {
+			h.CustomerAuthMiddleware(handler).ServeHTTP(w, r)
+			return
+		}
+
 		// Require auth for all other /v1 routes
 		if strings.HasPrefix(path, "/v1/") {
 			h.AuthMiddleware(handler).ServeHTTP(w, r)
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// ScheduleReservation finds the earliest slot at which a set of demands fits.
+// @Summary Schedule As Soon As Possible
+// @Description Searches the availability model for the earliest start, within the optional window, at which every demand fits for the duration. With sourcePlaceId only assets at that place or inside it are drawn on. With create the reservation is created at the slot found; it is submitted for approval unless draft is set.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param request body domain.ScheduleRequest true "Demands, duration and window"
+// @Success 200 {object} domain.ScheduleResult
+// @Success 201 {object} domain.ScheduleResult
+// @Failure 409 {string} string "Nothing fits in the window"
+// @Router /logistics/schedule [post]
+func (h *Handler) ScheduleReservation(w http.ResponseWriter, r *http.Request) {
+	var req domain.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.repo.FindEarliestSlot(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !req.Create {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	if result.Slot == nil {
+		http.Error(w, "no slot fits before "+result.SearchUntil.Format("2006-01-02 15:04 MST"), http.StatusConflict)
+		return
+	}
+
+	rr := req.Reservation(result.Slot)
+	if err := h.repo.CreateRentalReservation(r.Context(), rr); err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.announceSubmitted(r, rr)
+	result.Reservation = rr
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// CreateReservationSeries creates a recurring reservation.
+// @Summary Create Reservation Series
+// @Description Expands an RFC 5545 RRULE (with EXDATE exceptions) into pending reservations linked to a new series.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param series body domain.ReservationSeries true "Reservation Series"
+// @Success 201 {object} domain.ReservationSeries
+// @Failure 400 {string} string "Invalid recurrence rule"
+// @Router /logistics/series [post]
+func (h *Handler) CreateReservationSeries(w http.ResponseWriter, r *http.Request) {
+	var s domain.ReservationSeries
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateReservationSeries(r.Context(), &s); err != nil {
+		if errors.Is(err, domain.ErrInvalidRecurrence) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, rr := range s.Occurrences {
+		payload, _ := json.Marshal(rr)
+		h.repo.AppendEvent(r.Context(), nil, &domain.OutboxEvent{
+			Type:    domain.EventRentalSubmitted,
+			Payload: payload,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// ListReservationSeries lists reservation series without their occurrences.
+// @Summary List Reservation Series
+// @Tags Logistics
+// @Produce json
+// @Success 200 {array} domain.ReservationSeries
+// @Router /logistics/series [get]
+func (h *Handler) ListReservationSeries(w http.ResponseWriter, r *http.Request) {
+	series, err := h.repo.ListReservationSeries(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+// GetReservationSeries returns a series with its occurrences.
+// @Summary Get Reservation Series
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Series ID"
+// @Success 200 {object} domain.ReservationSeries
+// @Router /logistics/series/{id} [get]
+func (h *Handler) GetReservationSeries(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/series/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	s, err := h.repo.GetReservationSeries(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// EditReservationSeries edits one occurrence, it and every later one, or the whole series.
+// @Summary Edit Reservation Series
+// @Description Applies a reservation patch (or a cancellation) made on one occurrence with scope "this", "following" or "all".
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Series ID"
+// @Param edit body domain.SeriesEdit true "Series Edit"
+// @Success 200 {object} domain.SeriesEditResult
+// @Failure 400 {string} string "Invalid change"
+// @Failure 409 {string} string "Occurrence cannot be modified"
+// @Router /logistics/series/{id} [patch]
+func (h *Handler) EditReservationSeries(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/series/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var edit domain.SeriesEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.repo.EditReservationSeries(r.Context(), id, &edit, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidReservationChange) || errors.Is(err, domain.ErrInvalidRecurrence) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrReservationNotModifiable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ApproveReservationSeries approves every pending occurrence of a series.
+// @Summary Approve Reservation Series
+// @Description Confirms all pending occurrences in one transaction, or none if any occurrence is short.
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Series ID"
+// @Success 200 {object} domain.SeriesApproval
+// @Failure 409 {string} string "Insufficient inventory"
+// @Router /logistics/series/{id}/approve [post]
+func (h *Handler) ApproveReservationSeries(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/series/")
+	idStr = strings.TrimSuffix(idStr, "/approve")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	approval, err := h.repo.ApproveReservationSeries(r.Context(), id, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInsufficientInventory) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if approval == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(approval)
+}
+
+// CheckSeriesAvailability reports the occurrences that would be short on approval.
+// @Summary Check Reservation Series Availability
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Series ID"
+// @Success 200 {array} domain.OccurrenceShortfall
+// @Router /logistics/series/{id}/availability [get]
+func (h *Handler) CheckSeriesAvailability(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/series/")
+	idStr = strings.TrimSuffix(idStr, "/availability")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	short, err := h.repo.CheckSeriesAvailability(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if short == nil {
+		short = []domain.OccurrenceShortfall{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(short)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -79,6 +80,16 @@ func (h *Handler) CreateShipment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if domain.NormalizeDeliveryStatus(shipment.Status) != domain.DeliveryPreparing {
+		http.Error(w, "new shipments start as DeliveryPreparing", http.StatusBadRequest)
+		return
+	}
+	if len(shipment.Legs) > 0 {
+		if err := domain.ValidateRoute(shipment.Legs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	if err := h.repo.CreateShipment(r.Context(), &shipment); err != nil {
 		log.Printf("failed to create shipment: %v", err)
@@ -149,6 +160,23 @@ func (h *Handler) UpdateShipment(w http.ResponseWriter, r *http.Request) {
 	}
 	shipment.ID = id
 
+	current, err := h.repo.GetShipmentByID(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get shipment: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+	// Status changes have side effects and go through TransitionShipment
+	if shipment.Status != "" && domain.NormalizeDeliveryStatus(shipment.Status) != domain.NormalizeDeliveryStatus(current.Status) {
+		http.Error(w, "use POST /v1/logistics/shipments/{id}/status to change deliveryStatus", http.StatusConflict)
+		return
+	}
+	shipment.Status = current.Status
+
 	if err := h.repo.UpdateShipment(r.Context(), &shipment); err != nil {
 		log.Printf("failed to update shipment: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -158,6 +186,45 @@ func (h *Handler) UpdateShipment(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(shipment)
 }
 
+// TransitionShipment moves a shipment to a new delivery status, applying the side
+// effects of shipping and delivery.
+func (h *Handler) TransitionShipment(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
+	idStr = strings.TrimSuffix(idStr, "/status")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	var req domain.ShipmentTransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Status == "" {
+		http.Error(w, "deliveryStatus is required", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.repo.TransitionShipment(r.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidShipmentTransition) || errors.Is(err, domain.ErrHoldConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to transition shipment: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if shipment == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(shipment)
+}
+
 // AllocateAssets handles bulk allocation of assets to a shipment.
 func (h *Handler) AllocateAssets(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
@@ -190,3 +257,130 @@ func (h *Handler) AllocateAssets(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// SetShipmentLegs replaces a shipment's route with an ordered list of legs.
+func (h *Handler) SetShipmentLegs(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
+	idStr = strings.TrimSuffix(idStr, "/legs")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	var legs []domain.ShipmentLeg
+	if err := json.NewDecoder(r.Body).Decode(&legs); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := domain.ValidateRoute(legs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.repo.SetShipmentLegs(r.Context(), id, legs)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidRoute) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to set shipment legs: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if shipment == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(shipment)
+}
+
+// ConsolidateShipment puts the unshipped check-outs of several reservations on one shipment.
+func (h *Handler) ConsolidateShipment(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/shipments/")
+	idStr = strings.TrimSuffix(idStr, "/consolidate")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid shipment id", http.StatusBadRequest)
+		return
+	}
+
+	var req domain.ConsolidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.ReservationIDs) == 0 {
+		http.Error(w, "at least one reservation is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.repo.ConsolidateShipment(r.Context(), id, req.ReservationIDs)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidRoute) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to consolidate shipment: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result == nil {
+		http.Error(w, "shipment not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// TransferAssets moves assets out on one reservation straight onto another.
+func (h *Handler) TransferAssets(w http.ResponseWriter, r *http.Request) {
+	var req domain.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	agentIDVal := h.getUserIDFromContext(r)
+	if agentIDVal == nil {
+		http.Error(w, "agent id missing from context", http.StatusUnauthorized)
+		return
+	}
+
+	transfers, err := h.repo.TransferAssets(r.Context(), &req, *agentIDVal)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTransfer) || errors.Is(err, domain.ErrHoldConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to transfer assets: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// ListAssetTransfers lists the transfers into or out of a reservation.
+func (h *Handler) ListAssetTransfers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("reservation_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "reservation_id is required", http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := h.repo.ListAssetTransfers(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to list asset transfers: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(transfers)
+}
@@ -76,3 +76,57 @@ func TestHandler_AllocateAssets(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Code)
 	repo.AssertExpectations(t)
 }
+
+func TestHandler_SetShipmentLegs(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	legs := []domain.ShipmentLeg{
+		{FromPlaceID: 1, ToPlaceID: 2, Carrier: "Freight Co"},
+		{FromPlaceID: 2, ToPlaceID: 3},
+	}
+	body, _ := json.Marshal(legs)
+
+	repo.On("SetShipmentLegs", mock.Anything, int64(42), mock.MatchedBy(func(l []domain.ShipmentLeg) bool {
+		return len(l) == 2 && l[1].Sequence == 2 && l[1].Status == domain.DeliveryPreparing
+	})).Return(&domain.Shipment{ID: 42}, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/logistics/shipments/42/legs", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.SetShipmentLegs(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Legs that don't chain are rejected before the repository is called.
+	body, _ = json.Marshal([]domain.ShipmentLeg{{FromPlaceID: 1, ToPlaceID: 2}, {FromPlaceID: 3, ToPlaceID: 4}})
+	req = httptest.NewRequest(http.MethodPut, "/v1/logistics/shipments/42/legs", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	h.SetShipmentLegs(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.AssertExpectations(t)
+}
+
+func TestHandler_UpdateShipment_RejectsStatusChange(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("GetShipmentByID", mock.Anything, int64(42)).Return(&domain.Shipment{ID: 42, Status: domain.DeliveryPreparing}, nil)
+
+	body, _ := json.Marshal(domain.Shipment{Status: domain.DeliveryDelivered, Direction: "outbound"})
+	req := httptest.NewRequest(http.MethodPut, "/v1/logistics/shipments/42", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.UpdateShipment(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	repo.On("UpdateShipment", mock.Anything, mock.MatchedBy(func(s *domain.Shipment) bool {
+		return s.ID == 42 && s.Status == domain.DeliveryPreparing && s.Notes == "Dock 3"
+	})).Return(nil)
+
+	body, _ = json.Marshal(domain.Shipment{Notes: "Dock 3", Direction: "outbound"})
+	req = httptest.NewRequest(http.MethodPut, "/v1/logistics/shipments/42", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	h.UpdateShipment(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// RecordStockMovement records fungible stock being received, written off, checked out
+// to a reservation, returned or consumed, and returns the ledger entries written.
+func (h *Handler) RecordStockMovement(w http.ResponseWriter, r *http.Request) {
+	var req domain.StockMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	movements, err := h.repo.RecordStockMovement(r.Context(), &req, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrStock) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to record stock movement: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if movements == nil {
+		http.Error(w, "item type not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movements)
+}
+
+// ListStockMovements lists the stock ledger, optionally for one item type and one
+// reservation.
+func (h *Handler) ListStockMovements(w http.ResponseWriter, r *http.Request) {
+	var itemTypeID, reservationID *int64
+	if v := r.URL.Query().Get("item_type_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid item_type_id", http.StatusBadRequest)
+			return
+		}
+		itemTypeID = &id
+	}
+	if v := r.URL.Query().Get("reservation_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid reservation_id", http.StatusBadRequest)
+			return
+		}
+		reservationID = &id
+	}
+
+	movements, err := h.repo.ListStockMovements(r.Context(), itemTypeID, reservationID)
+	if err != nil {
+		log.Printf("failed to list stock movements: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(movements)
+}
+
+// stockItemTypeID parses the item type id out of /v1/inventory/stock/{id}{suffix}.
+func stockItemTypeID(w http.ResponseWriter, r *http.Request, suffix string) (int64, bool) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/inventory/stock/")
+	idStr = strings.TrimSuffix(idStr, suffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid item type id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// GetStockSummary returns a fungible item type's stock on hand per place and lot, what
+// is out per reservation, and what has been consumed or written off.
+func (h *Handler) GetStockSummary(w http.ResponseWriter, r *http.Request) {
+	id, ok := stockItemTypeID(w, r, "")
+	if !ok {
+		return
+	}
+
+	summary, err := h.repo.GetStockSummary(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to get stock summary: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if summary == nil {
+		http.Error(w, "item type not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(summary)
+}
+
+// ListStockLots lists an item type's lots with their expiry and what is on hand.
+func (h *Handler) ListStockLots(w http.ResponseWriter, r *http.Request) {
+	id, ok := stockItemTypeID(w, r, "/lots")
+	if !ok {
+		return
+	}
+
+	lots, err := h.repo.ListStockLots(r.Context(), id)
+	if err != nil {
+		log.Printf("failed to list stock lots: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(lots)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_RecordStockMovement(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	repo.On("RecordStockMovement", mock.Anything, mock.Anything, (*int64)(nil)).
+		Return(nil, fmt.Errorf("%w: 80 requested but only 50 available", domain.ErrStock))
+
+	// A check-out needs a reservation, which is caught before the ledger is touched
+	body := `{"kind":"check_out","itemTypeId":10,"quantity":5,"placeId":1}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/inventory/stock/movements", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	h.RecordStockMovement(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	repo.AssertNotCalled(t, "RecordStockMovement", mock.Anything, mock.Anything, mock.Anything)
+
+	body = `{"kind":"consumption","itemTypeId":10,"quantity":80,"reservationId":7}`
+	req = httptest.NewRequest(http.MethodPost, "/v1/inventory/stock/movements", bytes.NewBufferString(body))
+	w = httptest.NewRecorder()
+	h.RecordStockMovement(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestHandler_ListStockMovements_Filters(t *testing.T) {
+	repo := new(MockRepository)
+	h := NewHandler(repo, nil)
+
+	itemType := int64(10)
+	repo.On("ListStockMovements", mock.Anything, &itemType, (*int64)(nil)).Return([]domain.StockMovement{{ID: 1}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/inventory/stock/movements?item_type_id=10", nil)
+	w := httptest.NewRecorder()
+	h.ListStockMovements(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/inventory/stock/movements?item_type_id=abc", nil)
+	w = httptest.NewRecorder()
+	h.ListStockMovements(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	repo.AssertNumberOfCalls(t, "ListStockMovements", 1)
+}
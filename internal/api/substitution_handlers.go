@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+func (h *Handler) validateSubstitutionRule(rule *domain.SubstitutionRule) error {
+	if rule.ItemTypeID == 0 || rule.SubstituteItemTypeID == 0 {
+		return fmt.Errorf("item_type_id and substitute_item_type_id are required")
+	}
+	if rule.ItemTypeID == rule.SubstituteItemTypeID {
+		return fmt.Errorf("an item type cannot substitute for itself")
+	}
+	if rule.MaxQuantity != nil && *rule.MaxQuantity <= 0 {
+		return fmt.Errorf("max_quantity must be positive")
+	}
+	return nil
+}
+
+// CreateSubstitutionRule creates a substitution rule between two item types.
+// @Summary Create Substitution Rule
+// @Description Allows one item type to stand in for another during approval and allocation.
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Param rule body domain.SubstitutionRule true "Substitution Rule"
+// @Success 201 {object} domain.SubstitutionRule
+// @Router /catalog/substitution-rules [post]
+func (h *Handler) CreateSubstitutionRule(w http.ResponseWriter, r *http.Request) {
+	rule := domain.SubstitutionRule{IsActive: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validateSubstitutionRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.CreateSubstitutionRule(r.Context(), &rule); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// ListSubstitutionRules lists substitution rules.
+// @Summary List Substitution Rules
+// @Tags Catalog
+// @Produce json
+// @Param item_type_id query int false "Only rules that can replace this item type"
+// @Success 200 {array} domain.SubstitutionRule
+// @Router /catalog/substitution-rules [get]
+func (h *Handler) ListSubstitutionRules(w http.ResponseWriter, r *http.Request) {
+	var itemTypeID *int64
+	if s := r.URL.Query().Get("item_type_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid item_type_id", http.StatusBadRequest)
+			return
+		}
+		itemTypeID = &id
+	}
+
+	rules, err := h.repo.ListSubstitutionRules(r.Context(), itemTypeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+func (h *Handler) GetSubstitutionRule(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/substitution-rules/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.repo.GetSubstitutionRule(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rule == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *Handler) UpdateSubstitutionRule(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/substitution-rules/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var rule domain.SubstitutionRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+	if err := h.validateSubstitutionRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.UpdateSubstitutionRule(r.Context(), &rule); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *Handler) DeleteSubstitutionRule(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/catalog/substitution-rules/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.DeleteSubstitutionRule(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApplySubstitution accepts a substitution proposed during allocation.
+// @Summary Apply Substitution
+// @Description Pins assets of a substitute item type to a demand line and records the substitution on the demand.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Param request body object{demand_id=int,item_type_id=int,substitute_item_type_id=int,quantity=int} true "Substitution"
+// @Success 200 {object} domain.AllocationResult
+// @Router /logistics/reservations/{id}/substitutions [post]
+func (h *Handler) ApplySubstitution(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/substitutions")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		DemandID             int64 `json:"demand_id"`
+		ItemTypeID           int64 `json:"item_type_id"`
+		SubstituteItemTypeID int64 `json:"substitute_item_type_id"`
+		Quantity             int   `json:"quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DemandID == 0 || req.ItemTypeID == 0 || req.SubstituteItemTypeID == 0 || req.Quantity <= 0 {
+		http.Error(w, "demand_id, item_type_id, substitute_item_type_id and a positive quantity are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.repo.ApplySubstitution(r.Context(), id, req.DemandID, req.ItemTypeID, req.SubstituteItemTypeID, req.Quantity, h.getUserIDFromContext(r))
+	if errors.Is(err, domain.ErrSubstitutionNotAllowed) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, domain.ErrInsufficientInventory) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
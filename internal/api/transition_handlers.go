@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// GetReservationLifecycle lists the transitions a reservation can take next.
+// @Summary Get Reservation Transitions
+// @Description Returns the reservation's status, every action a user may request from it (with the reason a guard refuses it, if any) and its transition history.
+// @Tags Logistics
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} domain.ReservationLifecycle
+// @Failure 404 {string} string "Not Found"
+// @Router /logistics/reservations/{id}/transitions [get]
+func (h *Handler) GetReservationLifecycle(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/transitions")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	lifecycle, err := h.repo.GetReservationLifecycle(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if lifecycle == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lifecycle)
+}
+
+// TransitionRentalReservation applies an action from the reservation state machine.
+// @Summary Transition Rental Reservation
+// @Description Submits, approves, rejects or cancels a reservation. Approval re-checks availability and allocates assets.
+// @Tags Logistics
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Param transition body domain.ReservationTransitionRequest true "Action"
+// @Success 200 {object} domain.ReservationTransitionRecord
+// @Failure 409 {string} string "Transition not allowed or insufficient inventory"
+// @Router /logistics/reservations/{id}/transitions [post]
+func (h *Handler) TransitionRentalReservation(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/transitions")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req domain.ReservationTransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rec, ok := h.transitionReservation(w, r, id, &req)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// transitionAction handles the fixed-action routes such as /reject and /cancel, which
+// take an optional {"reason": "..."} body.
+func (h *Handler) transitionAction(w http.ResponseWriter, r *http.Request, action domain.ReservationAction) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/reservations/")
+	idStr = strings.TrimSuffix(idStr, "/"+string(action))
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	req := domain.ReservationTransitionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Action = action
+
+	if _, ok := h.transitionReservation(w, r, id, &req); ok {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// transitionReservation applies req and writes the error response if it fails.
+func (h *Handler) transitionReservation(w http.ResponseWriter, r *http.Request, id int64, req *domain.ReservationTransitionRequest) (*domain.ReservationTransitionRecord, bool) {
+	rec, err := h.repo.TransitionRentalReservation(r.Context(), id, req, h.getUserIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) || errors.Is(err, domain.ErrInsufficientInventory) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if rec == nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return rec, true
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// enqueueWaitlist queues a reservation that failed approval and answers 202 with the
+// waitlist entries. Priority and auto-approval come from the approve query string.
+func (h *Handler) enqueueWaitlist(w http.ResponseWriter, r *http.Request, reservationID int64, shortfalls []domain.Shortfall) {
+	priority := 0
+	if p := r.URL.Query().Get("priority"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			http.Error(w, "invalid priority", http.StatusBadRequest)
+			return
+		}
+		priority = n
+	}
+	autoApprove := r.URL.Query().Get("auto_approve") == "true"
+
+	entries, err := h.repo.EnqueueWaitlist(r.Context(), reservationID, shortfalls, priority, autoApprove, h.getUserIDFromContext(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// ListWaitlist returns the open waitlist in service order.
+// @Summary List Waitlist
+// @Tags Logistics
+// @Produce json
+// @Param item_type_id query int false "Only entries queued behind this item type"
+// @Success 200 {array} domain.WaitlistEntry
+// @Router /logistics/waitlist [get]
+func (h *Handler) ListWaitlist(w http.ResponseWriter, r *http.Request) {
+	var itemTypeID *int64
+	if s := r.URL.Query().Get("item_type_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid item_type_id", http.StatusBadRequest)
+			return
+		}
+		itemTypeID = &id
+	}
+
+	entries, err := h.repo.ListWaitlist(r.Context(), itemTypeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// UpdateWaitlistEntry changes the priority of an open waitlist entry.
+// @Summary Reprioritize Waitlist Entry
+// @Tags Logistics
+// @Accept json
+// @Param id path int true "Waitlist Entry ID"
+// @Param request body object{priority=int} true "New priority"
+// @Success 204 {string} string "No Content"
+// @Router /logistics/waitlist/{id} [patch]
+func (h *Handler) UpdateWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/waitlist/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Priority int `json:"priority"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.UpdateWaitlistPriority(r.Context(), id, req.Priority); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveWaitlistEntry takes an entry off the waitlist.
+// @Summary Remove Waitlist Entry
+// @Tags Logistics
+// @Param id path int true "Waitlist Entry ID"
+// @Success 204 {string} string "No Content"
+// @Router /logistics/waitlist/{id} [delete]
+func (h *Handler) RemoveWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/v1/logistics/waitlist/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SetWaitlistStatus(r.Context(), []int64{id}, domain.WaitlistRemoved); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,79 @@
+package carrier
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubCarrier_LabelAndTracking(t *testing.T) {
+	dir := t.TempDir()
+	stub := NewStubCarrier("stub", dir)
+	ctx := context.Background()
+
+	req := &domain.LabelRequest{
+		ShipmentID:  7,
+		RateRequest: domain.RateRequest{Parcels: []domain.Parcel{{WeightGrams: 2500}}},
+		Service:     "ground",
+	}
+	label, err := stub.CreateLabel(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "STUB0000000001", label.TrackingNumber)
+	assert.Equal(t, int64(900+150*3), label.Amount)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, label.TrackingNumber+".json"),
+		[]byte(`[{"code":"InTransit","occurredAt":"2026-07-01T12:00:00Z","location":"Hub"}]`), 0o644))
+	stub.Push(label.TrackingNumber, domain.TrackingEvent{Code: domain.TrackingPickedUp, OccurredAt: time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)})
+
+	events, err := stub.GetTrackingEvents(ctx, label.TrackingNumber)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, domain.TrackingInTransit, events[2].Code)
+	assert.Equal(t, label.TrackingNumber, events[2].TrackingNumber)
+
+	// Scanned labels cannot be voided
+	assert.Error(t, stub.VoidLabel(ctx, label.TrackingNumber))
+}
+
+func TestRESTCarrier_AgainstStub(t *testing.T) {
+	stub := NewStubCarrier("upstream", "")
+	srv := httptest.NewServer(stub.Handler())
+	defer srv.Close()
+
+	reg := NewRegistry()
+	reg.Register("rest", NewRESTCarrier("rest", srv.URL, "key"))
+	c, err := reg.Get("rest")
+	require.NoError(t, err)
+	_, err = reg.Get("missing")
+	assert.Error(t, err)
+	ctx := context.Background()
+
+	quotes, err := c.QuoteRates(ctx, &domain.RateRequest{Parcels: []domain.Parcel{{WeightGrams: 800}}})
+	require.NoError(t, err)
+	require.Len(t, quotes, 2)
+	assert.Equal(t, "rest", quotes[0].Carrier)
+
+	label, err := c.CreateLabel(ctx, &domain.LabelRequest{
+		ShipmentID:  9,
+		RateRequest: domain.RateRequest{Parcels: []domain.Parcel{{WeightGrams: 800}}},
+		Service:     "express",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "rest", label.Carrier)
+	assert.Equal(t, int64(9), label.ShipmentID)
+
+	events, err := c.GetTrackingEvents(ctx, label.TrackingNumber)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.TrackingLabelCreated, events[0].Code)
+
+	require.NoError(t, c.VoidLabel(ctx, label.TrackingNumber))
+	assert.Error(t, c.VoidLabel(ctx, "NOPE"))
+}
@@ -0,0 +1,50 @@
+package carrier
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// Registry holds the carriers shipments can be labelled and tracked with, by the name
+// stored in Shipment.Carrier.
+type Registry struct {
+	mu       sync.RWMutex
+	carriers map[string]domain.Carrier
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		carriers: make(map[string]domain.Carrier),
+	}
+}
+
+func (r *Registry) Register(name string, c domain.Carrier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.carriers[name] = c
+}
+
+func (r *Registry) Get(name string) (domain.Carrier, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.carriers[name]
+	if !ok {
+		return nil, fmt.Errorf("carrier not found: %s", name)
+	}
+	return c, nil
+}
+
+// Names lists the registered carriers in alphabetical order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.carriers))
+	for name := range r.carriers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
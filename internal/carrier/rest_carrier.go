@@ -0,0 +1,109 @@
+package carrier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// RESTCarrier implements domain.Carrier against a carrier, or a carrier aggregator,
+// exposing POST /rates, POST /labels, DELETE /labels/{trackingNumber} and
+// GET /tracking/{trackingNumber}.
+type RESTCarrier struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewRESTCarrier(name, baseURL, apiKey string) *RESTCarrier {
+	return &RESTCarrier{
+		name:    name,
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+func (c *RESTCarrier) QuoteRates(ctx context.Context, req *domain.RateRequest) ([]domain.RateQuote, error) {
+	var quotes []domain.RateQuote
+	if err := c.do(ctx, http.MethodPost, "/rates", req, &quotes); err != nil {
+		return nil, err
+	}
+	for i := range quotes {
+		quotes[i].Carrier = c.name
+	}
+	return quotes, nil
+}
+
+func (c *RESTCarrier) CreateLabel(ctx context.Context, req *domain.LabelRequest) (*domain.ShippingLabel, error) {
+	var label domain.ShippingLabel
+	if err := c.do(ctx, http.MethodPost, "/labels", req, &label); err != nil {
+		return nil, err
+	}
+	label.Carrier = c.name
+	label.ShipmentID = req.ShipmentID
+	return &label, nil
+}
+
+func (c *RESTCarrier) VoidLabel(ctx context.Context, trackingNumber string) error {
+	return c.do(ctx, http.MethodDelete, "/labels/"+url.PathEscape(trackingNumber), nil, nil)
+}
+
+func (c *RESTCarrier) GetTrackingEvents(ctx context.Context, trackingNumber string) ([]domain.TrackingEvent, error) {
+	var events []domain.TrackingEvent
+	if err := c.do(ctx, http.MethodGet, "/tracking/"+url.PathEscape(trackingNumber), nil, &events); err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Carrier = c.name
+		events[i].TrackingNumber = trackingNumber
+	}
+	return events, nil
+}
+
+func (c *RESTCarrier) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("carrier %s returned status %d: %s", c.name, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
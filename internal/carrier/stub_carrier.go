@@ -0,0 +1,222 @@
+package carrier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// StubCarrier is an offline carrier for development and tests. It quotes fixed rates,
+// issues sequential STUB tracking numbers and reports the tracking events pushed to
+// it. When given a directory it also reads events for a tracking number from
+// <dir>/<trackingNumber>.json, so fixtures can drive a shipment through its statuses.
+type StubCarrier struct {
+	name string
+	dir  string
+	now  func() time.Time
+
+	mu     sync.Mutex
+	seq    int
+	labels map[string]*domain.ShippingLabel
+	events map[string][]domain.TrackingEvent
+}
+
+func NewStubCarrier(name, dir string) *StubCarrier {
+	return &StubCarrier{
+		name:   name,
+		dir:    dir,
+		now:    time.Now,
+		labels: make(map[string]*domain.ShippingLabel),
+		events: make(map[string][]domain.TrackingEvent),
+	}
+}
+
+// stubServices are the services the stub offers: a base charge plus a charge per
+// started kilogram, in minor units, and the days in transit.
+var stubServices = []struct {
+	name        string
+	base, kg    int64
+	transitDays int
+}{
+	{"ground", 900, 150, 3},
+	{"express", 2400, 300, 1},
+}
+
+func (s *StubCarrier) QuoteRates(ctx context.Context, req *domain.RateRequest) ([]domain.RateQuote, error) {
+	if len(req.Parcels) == 0 {
+		return nil, errors.New("at least one parcel is required")
+	}
+	kg := int64(0)
+	for _, p := range req.Parcels {
+		kg += int64((p.WeightGrams + 999) / 1000)
+	}
+	shipDate := req.ShipDate
+	if shipDate.IsZero() {
+		shipDate = s.now()
+	}
+
+	quotes := make([]domain.RateQuote, 0, len(stubServices))
+	for _, svc := range stubServices {
+		eta := shipDate.AddDate(0, 0, svc.transitDays)
+		quotes = append(quotes, domain.RateQuote{
+			Carrier:           s.name,
+			Service:           svc.name,
+			Amount:            svc.base + svc.kg*kg,
+			Currency:          "USD",
+			EstimatedDelivery: &eta,
+		})
+	}
+	return quotes, nil
+}
+
+func (s *StubCarrier) CreateLabel(ctx context.Context, req *domain.LabelRequest) (*domain.ShippingLabel, error) {
+	quotes, err := s.QuoteRates(ctx, &req.RateRequest)
+	if err != nil {
+		return nil, err
+	}
+	var quote *domain.RateQuote
+	for i := range quotes {
+		if quotes[i].Service == req.Service {
+			quote = &quotes[i]
+		}
+	}
+	if quote == nil {
+		return nil, fmt.Errorf("unknown service: %s", req.Service)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	now := s.now()
+	tn := fmt.Sprintf("STUB%010d", s.seq)
+	label := &domain.ShippingLabel{
+		ShipmentID:     req.ShipmentID,
+		Carrier:        s.name,
+		Service:        quote.Service,
+		TrackingNumber: tn,
+		LabelURL:       "stub://labels/" + tn + ".pdf",
+		Amount:         quote.Amount,
+		Currency:       quote.Currency,
+		CreatedAt:      now,
+	}
+	s.labels[tn] = label
+	s.events[tn] = append(s.events[tn], domain.TrackingEvent{
+		Carrier: s.name, TrackingNumber: tn, Code: domain.TrackingLabelCreated, Description: "Label created", OccurredAt: now,
+	})
+	return label, nil
+}
+
+func (s *StubCarrier) VoidLabel(ctx context.Context, trackingNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	label, ok := s.labels[trackingNumber]
+	if !ok {
+		return fmt.Errorf("unknown tracking number: %s", trackingNumber)
+	}
+	if label.VoidedAt != nil {
+		return nil
+	}
+	for _, e := range s.events[trackingNumber] {
+		if e.Code != domain.TrackingLabelCreated {
+			return fmt.Errorf("label %s has already been scanned", trackingNumber)
+		}
+	}
+	now := s.now()
+	label.VoidedAt = &now
+	return nil
+}
+
+func (s *StubCarrier) GetTrackingEvents(ctx context.Context, trackingNumber string) ([]domain.TrackingEvent, error) {
+	s.mu.Lock()
+	events := append([]domain.TrackingEvent(nil), s.events[trackingNumber]...)
+	s.mu.Unlock()
+
+	if s.dir != "" {
+		data, err := os.ReadFile(filepath.Join(s.dir, filepath.Base(trackingNumber)+".json"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			var fixture []domain.TrackingEvent
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				return nil, fmt.Errorf("decode tracking fixture %s: %w", trackingNumber, err)
+			}
+			events = append(events, fixture...)
+		}
+	}
+
+	for i := range events {
+		events[i].Carrier = s.name
+		events[i].TrackingNumber = trackingNumber
+	}
+	return events, nil
+}
+
+// Push adds a tracking event for a tracking number, as if the carrier had scanned it.
+func (s *StubCarrier) Push(trackingNumber string, e domain.TrackingEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[trackingNumber] = append(s.events[trackingNumber], e)
+}
+
+// Handler serves the stub over the API RESTCarrier speaks, so the REST client can be
+// exercised without a real carrier.
+func (s *StubCarrier) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		var req domain.RateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		quotes, err := s.QuoteRates(r.Context(), &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		json.NewEncoder(w).Encode(quotes)
+	})
+	mux.HandleFunc("/labels", func(w http.ResponseWriter, r *http.Request) {
+		var req domain.LabelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		label, err := s.CreateLabel(r.Context(), &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(label)
+	})
+	mux.HandleFunc("/labels/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.VoidLabel(r.Context(), strings.TrimPrefix(r.URL.Path, "/labels/")); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/tracking/", func(w http.ResponseWriter, r *http.Request) {
+		events, err := s.GetTrackingEvents(r.Context(), strings.TrimPrefix(r.URL.Path, "/tracking/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(events)
+	})
+	return mux
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// ApproveRentalReservation confirms a reservation in a single transaction. Advisory
+// locks on every item type the reservation draws from serialize concurrent approvals,
+// so availability is re-validated against everything confirmed before it. The status
+// change, its history entry, the rental.approved outbox event and the asset holds
+// commit together.
+// It returns nil, nil when the reservation does not exist.
+func (r *SqlRepository) ApproveRentalReservation(ctx context.Context, id int64, userID *int64) (*domain.AllocationResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := r.approveReservationTx(ctx, tx, id, "", userID)
+	if err != nil || result == nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// approveReservationTx confirms and allocates one reservation inside tx through the
+// state machine's approve transition. When the reservation is short nothing is
+// written and tx stays usable.
+func (r *SqlRepository) approveReservationTx(ctx context.Context, tx *sql.Tx, id int64, reason string, userID *int64) (*domain.AllocationResult, error) {
+	var start, end time.Time
+	var status domain.RentalReservationStatus
+	err := tx.QueryRowContext(ctx, "SELECT start_time, end_time, reservation_status FROM rental_reservations WHERE id = $1 FOR UPDATE", id).Scan(&start, &end, &status)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock reservation: %w", err)
+	}
+
+	// Re-approving a confirmed reservation only tops up its allocation
+	if status != domain.ReservationStatusConfirmed {
+		now := time.Now()
+		rr := &domain.RentalReservation{ID: id, ReservationStatus: status, StartTime: start, EndTime: end}
+		t, err := domain.FindReservationTransition(domain.ReservationActionApprove, &domain.ReservationTransitionState{Reservation: rr, Now: now})
+		if err != nil {
+			return nil, err
+		}
+
+		lines, err := r.allocationLines(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockItemTypes(ctx, tx, linesItemTypes(lines)); err != nil {
+			return nil, err
+		}
+		shortfalls, err := r.linesShortfalls(ctx, tx, lines, start, end, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(shortfalls) > 0 {
+			return nil, &domain.InsufficientInventoryError{Shortfalls: shortfalls}
+		}
+
+		if _, err := r.applyReservationTransition(ctx, tx, rr, t, reason, userID, now); err != nil {
+			return nil, err
+		}
+		if err := r.snapshotReservationQuote(ctx, tx, id, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.allocateReservationTx(ctx, tx, id, userID)
+}
+
+// CheckReservationAvailability reports what a reservation would be short of if it
+// were approved now, without locking or changing anything.
+func (r *SqlRepository) CheckReservationAvailability(ctx context.Context, id int64) ([]domain.Shortfall, error) {
+	var start, end time.Time
+	err := r.db.QueryRowContext(ctx, "SELECT start_time, end_time FROM rental_reservations WHERE id = $1", id).Scan(&start, &end)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("reservation %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reservation: %w", err)
+	}
+	lines, err := r.allocationLines(ctx, r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.linesShortfalls(ctx, r.db, lines, start, end, 0)
+}
+
+// linesShortfalls checks allocation lines against availability over [start, end) and
+// its turnaround buffers, drawing on substitutes where a primary item type runs out.
+// The booking of excludeReservationID, if set, is not counted against them.
+func (r *SqlRepository) linesShortfalls(ctx context.Context, q queryer, lines []allocationLine, start, end time.Time, excludeReservationID int64) ([]domain.Shortfall, error) {
+	itemTypeIDs := linesItemTypes(lines)
+	if len(itemTypeIDs) == 0 {
+		return nil, nil
+	}
+	transit := linesTransit(lines)
+	timelines, err := r.loadAvailabilityTimelines(ctx, q, itemTypeIDs, start, end, transit, excludeReservationID)
+	if err != nil {
+		return nil, err
+	}
+	avail := make(map[int64]int, len(timelines))
+	for itemTypeID, tl := range timelines {
+		avail[itemTypeID] = tl.MinAvailableForRental(start, end, transit)
+	}
+
+	reqs := make([]domain.ComponentRequirement, 0, len(lines))
+	for _, line := range lines {
+		reqs = append(reqs, line.requirement())
+	}
+	_, shortfalls := domain.AllocateRequirements(reqs, avail)
+	return shortfalls, nil
+}
+
+// linesItemTypes returns the distinct item types, substitutes included, that the
+// lines may draw from, in ascending order.
+func linesItemTypes(lines []allocationLine) []int64 {
+	seen := make(map[int64]bool)
+	var itemTypeIDs []int64
+	for _, line := range lines {
+		for _, itemTypeID := range append([]int64{line.itemTypeID}, line.requirement().Substitutes...) {
+			if !seen[itemTypeID] {
+				seen[itemTypeID] = true
+				itemTypeIDs = append(itemTypeIDs, itemTypeID)
+			}
+		}
+	}
+	sort.Slice(itemTypeIDs, func(i, j int) bool { return itemTypeIDs[i] < itemTypeIDs[j] })
+	return itemTypeIDs
+}
+
+// lockItemTypes takes a transaction-scoped advisory lock per item type. Locks are
+// taken in ascending order so two transactions sharing item types cannot deadlock.
+func lockItemTypes(ctx context.Context, tx *sql.Tx, itemTypeIDs []int64) error {
+	sort.Slice(itemTypeIDs, func(i, j int) bool { return itemTypeIDs[i] < itemTypeIDs[j] })
+	for _, itemTypeID := range itemTypeIDs {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", itemTypeID); err != nil {
+			return fmt.Errorf("lock item_type %d: %w", itemTypeID, err)
+		}
+	}
+	return nil
+}
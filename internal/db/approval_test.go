@@ -0,0 +1,437 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_ApproveRentalReservation_InsufficientInventory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(4 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT start_time, end_time, reservation_status FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"start_time", "end_time", "reservation_status"}).
+			AddRow(startTime, endTime, domain.ReservationStatusPending))
+	mock.ExpectQuery("SELECT id, item_kind, item_id, requested_quantity, place_id FROM demands WHERE reservation_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_kind", "item_id", "requested_quantity", "place_id"}).
+			AddRow(1, "item_type", 12, 2, nil).
+			AddRow(2, "item_type", 10, 1, nil))
+	mock.ExpectQuery("SELECT (.+) FROM substitution_rules WHERE is_active").
+		WithArgs("{12,10}").
+		WillReturnRows(sqlmock.NewRows(substitutionRuleCols))
+
+	// Advisory locks are taken in ascending item type order
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{10,12}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}))
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets").
+		WithArgs("{10,12}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(10, 5).AddRow(12, 1))
+	mock.ExpectQuery("SELECT d.item_id, (.+) FROM demands d JOIN rental_reservations rr").
+		WithArgs("{10,12}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{10,12}", startTime).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM asset_holds h JOIN assets a").
+		WithArgs("{10,12}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{10,12}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols))
+	mock.ExpectRollback()
+
+	result, err := repo.ApproveRentalReservation(ctx, 1, nil)
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, domain.ErrInsufficientInventory))
+	assert.Contains(t, err.Error(), "item_type 12: requested 2, available 1")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var substitutionRuleCols = []string{"id", "item_type_id", "substitute_item_type_id", "two_way", "priority", "max_quantity", "auto_apply", "is_active", "notes", "created_at", "updated_at"}
+
+func TestSqlRepository_CheckReservationAvailability_Substitutes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(4 * time.Hour)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT start_time, end_time FROM rental_reservations WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"start_time", "end_time"}).AddRow(startTime, endTime))
+	mock.ExpectQuery("SELECT id, item_kind, item_id, requested_quantity, place_id FROM demands WHERE reservation_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_kind", "item_id", "requested_quantity", "place_id"}).AddRow(1, "item_type", 12, 4, nil))
+	// 13 may stand in for at most one unit of 12; 14 is two-way with 12 and uncapped
+	mock.ExpectQuery("SELECT (.+) FROM substitution_rules WHERE is_active").
+		WithArgs("{12}").
+		WillReturnRows(sqlmock.NewRows(substitutionRuleCols).
+			AddRow(1, 12, 13, false, 10, 1, true, true, "", now, now).
+			AddRow(2, 14, 12, true, 5, nil, false, true, "", now, now))
+
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{12,13,14}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}))
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets").
+		WithArgs("{12,13,14}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(12, 1).AddRow(13, 3).AddRow(14, 1))
+	mock.ExpectQuery("SELECT d.item_id, (.+) FROM demands d JOIN rental_reservations rr").
+		WithArgs("{12,13,14}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{12,13,14}", startTime).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM asset_holds h JOIN assets a").
+		WithArgs("{12,13,14}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{12,13,14}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols))
+
+	shortfalls, err := repo.CheckReservationAvailability(ctx, 1)
+	assert.NoError(t, err)
+	// 1 of 12, 1 of 13 (capped) and 1 of 14 leave the line one short
+	assert.Equal(t, []domain.Shortfall{{ItemTypeID: 12, Requested: 4, Available: 3}}, shortfalls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_AllocateReservation_RequiresConfirmed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(4 * time.Hour)
+	cols := []string{"start_time", "end_time", "reservation_status"}
+
+	// A pending reservation cannot pin assets
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT start_time, end_time, reservation_status FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(startTime, endTime, domain.ReservationStatusPending))
+	mock.ExpectRollback()
+
+	result, err := repo.AllocateReservation(ctx, 1, nil)
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, domain.ErrInvalidTransition))
+
+	// A missing reservation is not an error
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT start_time, end_time, reservation_status FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(cols))
+	mock.ExpectRollback()
+
+	result, err = repo.AllocateReservation(ctx, 2, nil)
+	assert.Nil(t, result)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_AllocateReservation_LocksItemTypes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(4 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT start_time, end_time, reservation_status FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"start_time", "end_time", "reservation_status"}).
+			AddRow(startTime, endTime, domain.ReservationStatusConfirmed))
+	mock.ExpectQuery("SELECT id, item_kind, item_id, requested_quantity, place_id FROM demands WHERE reservation_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_kind", "item_id", "requested_quantity", "place_id"}).AddRow(3, "item_type", 10, 1, nil))
+	mock.ExpectQuery("SELECT (.+) FROM substitution_rules WHERE is_active").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows(substitutionRuleCols))
+
+	// The item type is locked before any asset is picked, as approval does
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT h.demand_id, h.item_type_id, a.item_type_id, COUNT(.+) FROM asset_holds h").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"demand_id", "item_type_id", "asset_item_type_id", "count"}))
+	mock.ExpectQuery("SELECT a.id FROM assets a").
+		WithArgs(10, startTime, endTime, 1, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectQuery("INSERT INTO asset_holds").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("SELECT metadata FROM demands WHERE id = \\$1 FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"metadata"}).AddRow(nil))
+	mock.ExpectQuery("SELECT (.+) FROM asset_holds WHERE reservation_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "demand_id", "asset_id", "item_type_id", "start_time", "end_time", "status", "created_by_user_id", "created_at", "updated_at"}).
+			AddRow(7, 1, 3, 42, 10, startTime, endTime, domain.HoldActive, nil, startTime, startTime))
+	mock.ExpectCommit()
+
+	result, err := repo.AllocateReservation(ctx, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Holds, 1)
+	assert.Equal(t, int64(42), result.Holds[0].AssetID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_SwapHolds_DeployedAsset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	first := time.Now().Add(24 * time.Hour)
+	second := first.Add(48 * time.Hour)
+	holdCols := []string{"id", "reservation_id", "demand_id", "asset_id", "item_type_id", "start_time", "end_time", "status", "created_by_user_id", "created_at", "updated_at"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM asset_holds WHERE id = ANY\\(\\$1\\) ORDER BY id FOR UPDATE").
+		WithArgs("{7,8}").
+		WillReturnRows(sqlmock.NewRows(holdCols).
+			AddRow(7, 1, 3, 42, 10, first, first.Add(8*time.Hour), domain.HoldActive, nil, first, first).
+			AddRow(8, 2, 4, 43, 10, second, second.Add(8*time.Hour), domain.HoldActive, nil, first, first))
+	mock.ExpectQuery("SELECT \\(SELECT item_type_id FROM assets").
+		WithArgs(42, 43).
+		WillReturnRows(sqlmock.NewRows([]string{"same"}).AddRow(true))
+	for _, demandID := range []int{3, 4} {
+		mock.ExpectQuery("SELECT COALESCE\\(p.transit_minutes, 0\\) FROM demands d").
+			WithArgs(demandID).
+			WillReturnRows(sqlmock.NewRows([]string{"transit_minutes"}).AddRow(0))
+	}
+	// Asset 42 is out on an ad-hoc rental that is not back before hold 8 starts
+	mock.ExpectQuery("SELECT \\(a.status = 'available'(.+) FROM assets a WHERE a.id = \\$1").
+		WithArgs(42, second).
+		WillReturnRows(sqlmock.NewRows([]string{"holdable"}).AddRow(false))
+	mock.ExpectRollback()
+
+	err = repo.SwapHolds(ctx, 1, 7, 8)
+	assert.True(t, errors.Is(err, domain.ErrHoldConflict))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// openTestSchema connects to TEST_DATABASE_URL with a fresh schema built by the
+// migrations, dropped again when the test ends. The test is skipped when the variable
+// is not set.
+func openTestSchema(t *testing.T, prefix string) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	admin, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	schema := fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano())
+	_, err = admin.ExecContext(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close()
+	})
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	conn, err := sql.Open("postgres", dsn+sep+"search_path="+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, RunMigrations(ctx, conn))
+	return conn
+}
+
+// TestSqlRepository_ApproveRentalReservation_Concurrent races many approvals for the
+// same item type against a real Postgres. It runs only when TEST_DATABASE_URL is set.
+func TestSqlRepository_ApproveRentalReservation_Concurrent(t *testing.T) {
+	conn := openTestSchema(t, "approval_race")
+	ctx := context.Background()
+
+	const stock, contenders = 3, 12
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	end := start.Add(8 * time.Hour)
+	_, err := conn.ExecContext(ctx, "INSERT INTO item_types (id, code, name, kind) VALUES (1, 'CAM', 'Camera', 'serialized')")
+	require.NoError(t, err)
+	for i := 0; i < stock; i++ {
+		_, err := conn.ExecContext(ctx, "INSERT INTO assets (item_type_id, status, metadata) VALUES (1, 'available', '{}')")
+		require.NoError(t, err)
+	}
+	ids := make([]int64, contenders)
+	for i := range ids {
+		err := conn.QueryRowContext(ctx, "INSERT INTO rental_reservations (reservation_status, start_time, end_time) VALUES ($1, $2, $3) RETURNING id",
+			domain.ReservationStatusPending, start, end).Scan(&ids[i])
+		require.NoError(t, err)
+		_, err = conn.ExecContext(ctx, "INSERT INTO demands (reservation_id, item_kind, item_id, requested_quantity) VALUES ($1, 'item_type', 1, 1)", ids[i])
+		require.NoError(t, err)
+	}
+
+	repo := NewSqlRepository(conn)
+	var wg sync.WaitGroup
+	errs := make([]error, contenders)
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id int64) {
+			defer wg.Done()
+			_, errs[i] = repo.ApproveRentalReservation(ctx, id, nil)
+		}(i, id)
+	}
+	wg.Wait()
+
+	approved := 0
+	for _, err := range errs {
+		if err == nil {
+			approved++
+			continue
+		}
+		assert.True(t, errors.Is(err, domain.ErrInsufficientInventory), "unexpected error: %v", err)
+	}
+	assert.Equal(t, stock, approved)
+
+	var confirmed, events, holds int
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM rental_reservations WHERE reservation_status = $1", domain.ReservationStatusConfirmed).Scan(&confirmed))
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM outbox_events WHERE event_type = $1", domain.EventRentalApproved).Scan(&events))
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM asset_holds WHERE status = 'active'").Scan(&holds))
+	assert.Equal(t, stock, confirmed)
+	assert.Equal(t, stock, events)
+	assert.Equal(t, stock, holds)
+}
+
+func TestSqlRepository_CheckReservationDelta_Substitutes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(4 * time.Hour)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	// 12 stands in for 10 without limit
+	mock.ExpectQuery("SELECT (.+) FROM substitution_rules WHERE is_active").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows(substitutionRuleCols).AddRow(1, 10, 12, false, 10, nil, true, true, "", now, now))
+
+	// The substitute is locked along with the primary item type
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").WithArgs(10).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{10,12}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}))
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets").
+		WithArgs("{10,12}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(10, 2).AddRow(12, 2))
+	// The reservation's own booking is left out; another takes one unit of 10
+	mock.ExpectQuery("SELECT d.item_id, (.+) FROM demands d JOIN rental_reservations rr (.+) AND rr.id != \\$4").
+		WithArgs("{10,12}", startTime, endTime, 1).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols).AddRow(10, startTime, endTime, 1, 2, 20, ""))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{10,12}", startTime).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM asset_holds h JOIN assets a (.+) AND h.reservation_id != \\$4").
+		WithArgs("{10,12}", startTime, endTime, 1).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{10,12}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	before := &domain.RentalReservation{ID: 1, StartTime: startTime, EndTime: endTime,
+		Demands: []domain.Demand{{ID: 1, ReservationID: 1, ItemKind: domain.DemandKindItemType, ItemID: 10, Quantity: 2}}}
+	after := *before
+	after.Demands = []domain.Demand{{ID: 1, ReservationID: 1, ItemKind: domain.DemandKindItemType, ItemID: 10, Quantity: 3}}
+
+	// One free unit of 10 and two of 12 cover the three now asked for
+	shortfalls, err := repo.checkReservationDelta(ctx, tx, before, &after)
+	assert.NoError(t, err)
+	assert.Empty(t, shortfalls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_GetRentalFulfillmentStatus_HeldSubstitutions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	// The metadata still carries an applied total from before the hold was released
+	meta := `{"substitutions":[{"itemTypeId":10,"substituteItemTypeId":11,"quantity":3,"applied":true},{"itemTypeId":10,"substituteItemTypeId":13,"quantity":1,"applied":false}]}`
+	mock.ExpectQuery("SELECT id, reservation_id, event_id, (.+) FROM demands WHERE reservation_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "event_id", "item_kind", "item_id", "requested_quantity",
+			"business_function", "eligible_duration", "place_id", "metadata", "created_at", "updated_at"}).
+			AddRow(5, 1, 1, "item_type", 10, 4, "", "", nil, []byte(meta), now, now))
+	mock.ExpectQuery("SELECT h.demand_id, h.item_type_id, a.item_type_id, COUNT(.+) FROM asset_holds h JOIN assets a (.+) h.status IN \\('active', 'consumed'\\)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"demand_id", "item_type_id", "asset_item_type_id", "count", "applied_at"}).AddRow(5, 10, 12, 1, now))
+	mock.ExpectQuery("FROM check_out_actions co").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count", "substituted"}))
+	mock.ExpectQuery("FROM return_actions ret").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}))
+
+	status, err := repo.GetRentalFulfillmentStatus(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, status.Lines, 1)
+	assert.Equal(t, []domain.Substitution{
+		{ItemTypeID: 10, SubstituteItemTypeID: 12, Quantity: 1, Applied: true, AppliedAt: &now},
+		{ItemTypeID: 10, SubstituteItemTypeID: 13, Quantity: 1},
+	}, status.Lines[0].Substitutions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -28,14 +28,14 @@ func TestSqlRepository_CreateAsset_Lifecycle(t *testing.T) {
 
 		// 1. GetDefaultInternalPlace - Query existing
 		mock.ExpectQuery("SELECT (.+) FROM places WHERE is_internal = TRUE LIMIT 1").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "presumed_demands", "metadata", "created_at", "updated_at"}).
-				AddRow(101, "Main Warehouse", nil, nil, nil, "site", []byte("{}"), true, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "transit_minutes", "presumed_demands", "metadata", "created_at", "updated_at"}).
+				AddRow(101, "Main Warehouse", nil, nil, nil, "site", []byte("{}"), true, 0, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
 
 		// 2. GetPlace (for status inference)
 		mock.ExpectQuery("SELECT (.+) FROM places WHERE id = \\$1").
 			WithArgs(int64(101)).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "presumed_demands", "metadata", "created_at", "updated_at"}).
-				AddRow(101, "Main Warehouse", nil, nil, nil, "site", []byte("{}"), true, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "transit_minutes", "presumed_demands", "metadata", "created_at", "updated_at"}).
+				AddRow(101, "Main Warehouse", nil, nil, nil, "site", []byte("{}"), true, 0, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
 
 		// 3. Insert Asset
 		mock.ExpectQuery("INSERT INTO assets").
@@ -57,8 +57,8 @@ func TestSqlRepository_CreateAsset_Lifecycle(t *testing.T) {
 		// 1. GetPlace (for status inference)
 		mock.ExpectQuery("SELECT (.+) FROM places WHERE id = \\$1").
 			WithArgs(int64(202)).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "presumed_demands", "metadata", "created_at", "updated_at"}).
-				AddRow(202, "Client Site", nil, nil, nil, "site", []byte("{}"), false, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "transit_minutes", "presumed_demands", "metadata", "created_at", "updated_at"}).
+				AddRow(202, "Client Site", nil, nil, nil, "site", []byte("{}"), false, 0, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
 
 		// 2. Insert Asset
 		mock.ExpectQuery("INSERT INTO assets").
@@ -82,8 +82,8 @@ func TestSqlRepository_CreateAsset_Lifecycle(t *testing.T) {
 		// 1. GetPlace (for status inference)
 		mock.ExpectQuery("SELECT (.+) FROM places WHERE id = \\$1").
 			WithArgs(int64(101)).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "presumed_demands", "metadata", "created_at", "updated_at"}).
-				AddRow(101, "Main Warehouse", nil, nil, nil, "site", []byte("{}"), true, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "contained_in_place_id", "owner_id", "category", "address", "is_internal", "transit_minutes", "presumed_demands", "metadata", "created_at", "updated_at"}).
+				AddRow(101, "Main Warehouse", nil, nil, nil, "site", []byte("{}"), true, 0, []byte("{}"), []byte("{}"), time.Now(), time.Now()))
 
 		// 2. Insert Asset (Metadata should contain components)
 		mock.ExpectQuery("INSERT INTO assets").
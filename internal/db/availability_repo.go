@@ -0,0 +1,342 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx so availability can be
+// evaluated inside or outside a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rentalPadding is a lateral subquery giving the time a rental of item type "it" at the
+// demand's place "p" keeps a unit out of circulation before and after its window.
+const rentalPadding = `SELECT (it.pre_buffer_minutes + COALESCE(p.transit_minutes, 0)) * interval '1 minute' AS pre,
+	       (it.post_buffer_minutes + COALESCE(p.transit_minutes, 0)) * interval '1 minute' AS post`
+
+// loadAvailabilityTimelines builds an availability timeline for every requested
+// item type using a fixed number of queries, regardless of window length. Every
+// usage interval is widened by its item type's turnaround buffer and the transit
+// time to its place; start, end and transit describe the rental being checked, so
+// usage that only touches its widened window is loaded as well. When
+// excludeReservationID is set, that reservation's own booking (its confirmed demand
+// and active holds) is left out, so a change to it can be checked against the rest.
+func (r *SqlRepository) loadAvailabilityTimelines(ctx context.Context, q queryer, itemTypeIDs []int64, start, end time.Time, transit time.Duration, excludeReservationID int64) (map[int64]*domain.AvailabilityTimeline, error) {
+	owned, buffers, err := itemTypeInventory(ctx, q, itemTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+	windowStart, windowEnd := start, end
+	for _, b := range buffers {
+		s, e := b.Occupied(start, end, transit)
+		if s.Before(windowStart) {
+			windowStart = s
+		}
+		if e.After(windowEnd) {
+			windowEnd = e
+		}
+	}
+	consumers, err := loadInventoryConsumers(ctx, q, itemTypeIDs, windowStart, windowEnd, excludeReservationID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.BuildConsumerTimelines(itemTypeIDs, owned, buffers, consumers), nil
+}
+
+// itemTypeInventory returns the units owned of each item type, retired ones excluded,
+// and its turnaround buffer, which widens the window a rental of it occupies.
+func itemTypeInventory(ctx context.Context, q queryer, itemTypeIDs []int64) (map[int64]int, map[int64]domain.TurnaroundBuffer, error) {
+	owned := make(map[int64]int)
+	buffers := make(map[int64]domain.TurnaroundBuffer)
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types
+		WHERE id = ANY($1)`, pq.Array(itemTypeIDs))
+	if err != nil {
+		return nil, nil, fmt.Errorf("query item_type buffers: %w", err)
+	}
+	for rows.Next() {
+		var it domain.ItemType
+		if err := rows.Scan(&it.ID, &it.PreBufferMinutes, &it.PostBufferMinutes); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("scan item_type buffers: %w", err)
+		}
+		buffers[it.ID] = it.Buffer()
+	}
+	rows.Close()
+
+	// Fungible item types are counted from the stock ledger, on hand or out, rather
+	// than by asset rows
+	rows, err = q.QueryContext(ctx, `
+		SELECT item_type_id, COUNT(*) FROM assets
+		WHERE item_type_id = ANY($1) AND status != 'retired'
+		  AND item_type_id NOT IN (SELECT id FROM item_types WHERE kind = 'fungible')
+		GROUP BY item_type_id
+		UNION ALL
+		SELECT m.item_type_id, SUM(`+stockOnHand+` + `+stockOut+`)
+		FROM stock_movements m JOIN item_types it ON it.id = m.item_type_id
+		WHERE m.item_type_id = ANY($1) AND it.kind = 'fungible'
+		GROUP BY m.item_type_id`, pq.Array(itemTypeIDs))
+	if err != nil {
+		return nil, nil, fmt.Errorf("count assets: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, nil, fmt.Errorf("scan asset count: %w", err)
+		}
+		owned[id] += count
+	}
+	return owned, buffers, rows.Err()
+}
+
+// loadInventoryConsumers loads the usage of the item types over [windowStart,
+// windowEnd), one consumer per reservation demand, asset, hold, stock lot or stock
+// out, so availability can both count it and show what is behind it. When
+// excludeReservationID is set, that reservation's confirmed demand and active holds
+// are left out.
+func loadInventoryConsumers(ctx context.Context, q queryer, itemTypeIDs []int64, windowStart, windowEnd time.Time, excludeReservationID int64) ([]domain.InventoryConsumer, error) {
+	consumers := []domain.InventoryConsumer{}
+
+	bookingArgs := []interface{}{pq.Array(itemTypeIDs), windowStart, windowEnd}
+	var excludeReservation, excludeHolds string
+	if excludeReservationID != 0 {
+		excludeReservation = " AND rr.id != $4"
+		excludeHolds = " AND h.reservation_id != $4"
+		bookingArgs = append(bookingArgs, excludeReservationID)
+	}
+
+	// 1. Overlapping CONFIRMED reservations (Schema.org: ReservationConfirmed).
+	// Kit demands are expanded into their required components. Units already pinned
+	// by an active hold are left out here and counted from the hold in step 3.
+	rows, err := q.QueryContext(ctx, `
+		SELECT d.item_id, rr.start_time - pad.pre, rr.end_time + pad.post,
+		       GREATEST(d.requested_quantity - (
+		           SELECT COUNT(*) FROM asset_holds h
+		           WHERE h.demand_id = d.id AND h.item_type_id = d.item_id AND h.status = 'active'), 0),
+		       rr.id, d.id, COALESCE(rr.reservation_name, '')
+		FROM demands d
+		JOIN rental_reservations rr ON d.reservation_id = rr.id
+		JOIN item_types it ON it.id = d.item_id
+		LEFT JOIN places p ON p.id = d.place_id
+		CROSS JOIN LATERAL (`+rentalPadding+`) pad
+		WHERE d.item_kind = 'item_type'
+		  AND d.item_id = ANY($1)
+		  AND rr.reservation_status = 'ReservationConfirmed'
+		  AND rr.start_time - pad.pre < $3
+		  AND rr.end_time + pad.post > $2`+excludeReservation+`
+		UNION ALL
+		SELECT kc.item_type_id, rr.start_time - pad.pre, rr.end_time + pad.post,
+		       GREATEST(d.requested_quantity * kc.quantity - (
+		           SELECT COUNT(*) FROM asset_holds h
+		           WHERE h.demand_id = d.id AND h.item_type_id = kc.item_type_id AND h.status = 'active'), 0),
+		       rr.id, d.id, COALESCE(rr.reservation_name, '')
+		FROM demands d
+		JOIN rental_reservations rr ON d.reservation_id = rr.id
+		JOIN kit_template_components kc ON kc.kit_template_id = d.item_id
+		JOIN item_types it ON it.id = kc.item_type_id
+		LEFT JOIN places p ON p.id = d.place_id
+		CROSS JOIN LATERAL (`+rentalPadding+`) pad
+		WHERE d.item_kind = 'kit_template'
+		  AND kc.item_type_id = ANY($1)
+		  AND NOT kc.is_optional
+		  AND rr.reservation_status = 'ReservationConfirmed'
+		  AND rr.start_time - pad.pre < $3
+		  AND rr.end_time + pad.post > $2`+excludeReservation, bookingArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("query reserved intervals: %w", err)
+	}
+	for rows.Next() {
+		c := domain.InventoryConsumer{Kind: domain.ConsumerReservation}
+		var start, end time.Time
+		var reservationID, demandID int64
+		if err := rows.Scan(&c.ItemTypeID, &start, &end, &c.Quantity, &reservationID, &demandID, &c.ReservationName); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan reserved interval: %w", err)
+		}
+		if c.Quantity == 0 {
+			continue
+		}
+		c.Start, c.End, c.ReservationID, c.DemandID = &start, &end, &reservationID, &demandID
+		consumers = append(consumers, c)
+	}
+	rows.Close()
+
+	// 2. Ad-hoc usage: deployed or in maintenance until the estimated return, plus
+	// the post-rental buffer once it is back
+	rows, err = q.QueryContext(ctx, `
+		SELECT a.item_type_id, (a.metadata->>'estimated_return_at')::timestamp + it.post_buffer_minutes * interval '1 minute',
+		       a.id, COALESCE(a.asset_tag, ''), a.status
+		FROM assets a
+		JOIN item_types it ON it.id = a.item_type_id
+		WHERE a.item_type_id = ANY($1)
+		  AND a.status IN ('deployed', 'maintenance')
+		  AND (a.metadata->>'estimated_return_at' IS NULL
+		       OR (a.metadata->>'estimated_return_at')::timestamp + it.post_buffer_minutes * interval '1 minute' > $2)`,
+		pq.Array(itemTypeIDs), windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("query ad-hoc usage: %w", err)
+	}
+	for rows.Next() {
+		c := domain.InventoryConsumer{Kind: domain.ConsumerDeployed, Quantity: 1}
+		var returnAt sql.NullTime
+		var assetID int64
+		var status domain.AssetStatus
+		if err := rows.Scan(&c.ItemTypeID, &returnAt, &assetID, &c.AssetTag, &status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan ad-hoc usage: %w", err)
+		}
+		if status == domain.AssetStatusMaintenance {
+			c.Kind = domain.ConsumerMaintenance
+		}
+		c.AssetID = &assetID
+		if returnAt.Valid {
+			c.End = &returnAt.Time
+		}
+		consumers = append(consumers, c)
+	}
+	rows.Close()
+
+	// 3. Hard allocations: each active hold takes its asset for the hold window
+	rows, err = q.QueryContext(ctx, `
+		SELECT a.item_type_id, h.start_time - pad.pre, h.end_time + pad.post,
+		       h.id, h.reservation_id, h.demand_id, a.id, COALESCE(a.asset_tag, ''), COALESCE(rr.reservation_name, '')
+		FROM asset_holds h
+		JOIN assets a ON a.id = h.asset_id
+		JOIN item_types it ON it.id = a.item_type_id
+		JOIN rental_reservations rr ON rr.id = h.reservation_id
+		LEFT JOIN demands d ON d.id = h.demand_id
+		LEFT JOIN places p ON p.id = d.place_id
+		CROSS JOIN LATERAL (`+rentalPadding+`) pad
+		WHERE a.item_type_id = ANY($1)
+		  AND h.status = 'active'
+		  AND h.start_time - pad.pre < $3
+		  AND h.end_time + pad.post > $2`+excludeHolds, bookingArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("query asset holds: %w", err)
+	}
+	for rows.Next() {
+		c := domain.InventoryConsumer{Kind: domain.ConsumerHold, Quantity: 1}
+		var start, end time.Time
+		var holdID, reservationID, demandID, assetID int64
+		if err := rows.Scan(&c.ItemTypeID, &start, &end, &holdID, &reservationID, &demandID, &assetID, &c.AssetTag, &c.ReservationName); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan asset hold: %w", err)
+		}
+		c.Start, c.End, c.HoldID, c.ReservationID, c.DemandID, c.AssetID = &start, &end, &holdID, &reservationID, &demandID, &assetID
+		consumers = append(consumers, c)
+	}
+	rows.Close()
+
+	// 4. Fungible stock: lots on hand drop out once they expire, and whatever is still
+	// out on a reservation that has ended stays out until it is returned
+	rows, err = q.QueryContext(ctx, `
+		SELECT m.item_type_id, l.expires_at, SUM(`+stockOnHand+`), 'expiry', l.id, l.lot_number, NULL, ''
+		FROM stock_movements m JOIN stock_lots l ON l.id = m.lot_id
+		WHERE m.item_type_id = ANY($1) AND l.expires_at IS NOT NULL
+		GROUP BY m.item_type_id, l.id, l.expires_at, l.lot_number
+		HAVING SUM(`+stockOnHand+`) > 0
+		UNION ALL
+		SELECT m.item_type_id, NULL, SUM(`+stockOut+`), 'stock_out', NULL, '', rr.id, COALESCE(rr.reservation_name, '')
+		FROM stock_movements m JOIN rental_reservations rr ON rr.id = m.reservation_id
+		WHERE m.item_type_id = ANY($1) AND rr.end_time < NOW()
+		GROUP BY m.item_type_id, rr.id, rr.reservation_name
+		HAVING SUM(`+stockOut+`) > 0`, pq.Array(itemTypeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("query stock usage: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c domain.InventoryConsumer
+		var start sql.NullTime
+		if err := rows.Scan(&c.ItemTypeID, &start, &c.Quantity, &c.Kind, &c.LotID, &c.LotNumber, &c.ReservationID, &c.ReservationName); err != nil {
+			return nil, fmt.Errorf("scan stock usage: %w", err)
+		}
+		if start.Valid {
+			c.Start = &start.Time
+		}
+		consumers = append(consumers, c)
+	}
+	return consumers, rows.Err()
+}
+
+// GetAvailableQuantity calculates the available inventory for an item type in a given time window.
+// The result is the lowest point of the availability timeline across the window and its
+// turnaround buffers, so reservations that do not overlap each other are not double counted.
+func (r *SqlRepository) GetAvailableQuantity(ctx context.Context, itemTypeID int64, startTime, endTime time.Time) (int, error) {
+	timelines, err := r.loadAvailabilityTimelines(ctx, r.db, []int64{itemTypeID}, startTime, endTime, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	return timelines[itemTypeID].MinAvailableForRental(startTime, endTime, 0), nil
+}
+
+// GetAvailabilityTimeline returns availability data points over a range of dates.
+func (r *SqlRepository) GetAvailabilityTimeline(ctx context.Context, itemTypeID int64, start, end time.Time, granularity domain.AvailabilityGranularity) ([]domain.AvailabilityPoint, error) {
+	timelines, err := r.loadAvailabilityTimelines(ctx, r.db, []int64{itemTypeID}, start, granularity.Next(end), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return timelines[itemTypeID].Sample(start, end, granularity), nil
+}
+
+// GetShortageAlerts identifies future bottlenecks in inventory.
+func (r *SqlRepository) GetShortageAlerts(ctx context.Context, granularity domain.AvailabilityGranularity) ([]domain.ShortageAlert, error) {
+	// Check the next 14 days for all active item types
+	start := time.Now()
+	end := start.AddDate(0, 0, 14)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name FROM item_types WHERE is_active = true")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	names := make(map[int64]string)
+	for rows.Next() {
+		var itID int64
+		var itName string
+		if err := rows.Scan(&itID, &itName); err != nil {
+			return nil, err
+		}
+		ids = append(ids, itID)
+		names[itID] = itName
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	timelines, err := r.loadAvailabilityTimelines(ctx, r.db, ids, start, granularity.Next(end), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []domain.ShortageAlert
+	for _, itID := range ids {
+		for _, p := range timelines[itID].Sample(start, end, granularity) {
+			if p.Available < 0 {
+				alerts = append(alerts, domain.ShortageAlert{
+					ItemTypeID:    itID,
+					ItemTypeName:  names[itID],
+					Date:          p.Date,
+					ShortageCount: -p.Available,
+					TotalNeeded:   p.Total - p.Available,
+					TotalOwned:    p.Total,
+				})
+			}
+		}
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,386 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const claimColumns = `id, reservation_id, asset_id, claim_type, status, inspection_submission_id, check_out_id, responsible_person_id, responsible_company_id,
+	COALESCE(description, ''), COALESCE(currency, ''), claimed_amount, settled_amount, COALESCE(resolution_note, ''), created_by_user_id, settled_at, created_at, updated_at`
+
+func scanClaim(scanner interface{ Scan(...any) error }) (*domain.Claim, error) {
+	var c domain.Claim
+	err := scanner.Scan(&c.ID, &c.ReservationID, &c.AssetID, &c.Type, &c.Status, &c.InspectionSubmissionID, &c.CheckOutID,
+		&c.ResponsiblePersonID, &c.ResponsibleCompanyID, &c.Description, &c.Currency, &c.ClaimedAmount, &c.SettledAmount,
+		&c.ResolutionNote, &c.CreatedByUserID, &c.SettledAt, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.Evidence = []domain.ClaimEvidence{}
+	return &c, nil
+}
+
+// CreateClaim opens a claim. Damage claims must point at an inspection, made after the
+// asset came back from the reservation, that recorded damage; its image responses are
+// attached as evidence. Loss claims attach to the asset's outstanding check-out.
+func (r *SqlRepository) CreateClaim(ctx context.Context, c *domain.Claim, userID *int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var underNameID *int64
+	err = tx.QueryRowContext(ctx, "SELECT under_name_id FROM rental_reservations WHERE id = $1 FOR UPDATE", c.ReservationID).Scan(&underNameID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: reservation %d not found", domain.ErrInvalidClaim, c.ReservationID)
+	}
+	if err != nil {
+		return fmt.Errorf("lock reservation: %w", err)
+	}
+
+	var evidence []domain.ClaimEvidence
+	switch c.Type {
+	case domain.ClaimDamage:
+		if evidence, err = checkDamageClaim(ctx, tx, c); err != nil {
+			return err
+		}
+	case domain.ClaimLoss:
+		if err := checkLossClaim(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+
+	if c.ResponsiblePersonID == nil {
+		c.ResponsiblePersonID = underNameID
+	}
+	if c.ResponsibleCompanyID == nil {
+		if c.ResponsibleCompanyID, err = customerCompany(ctx, tx, c.ResponsiblePersonID, now); err != nil {
+			return err
+		}
+	}
+	c.Status = domain.ClaimOpen
+	c.CreatedByUserID = userID
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO claims (reservation_id, asset_id, claim_type, status, inspection_submission_id, check_out_id,
+	                                   responsible_person_id, responsible_company_id, description, currency, claimed_amount, created_by_user_id, created_at, updated_at)
+	                               VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11, $12, $13, $14) RETURNING id`,
+		c.ReservationID, c.AssetID, c.Type, c.Status, c.InspectionSubmissionID, c.CheckOutID,
+		c.ResponsiblePersonID, c.ResponsibleCompanyID, c.Description, c.Currency, c.ClaimedAmount, c.CreatedByUserID, c.CreatedAt, c.UpdatedAt,
+	).Scan(&c.ID)
+	if err != nil {
+		return fmt.Errorf("insert claim: %w", err)
+	}
+
+	c.Evidence = []domain.ClaimEvidence{}
+	for i := range evidence {
+		ev := &evidence[i]
+		ev.ClaimID = c.ID
+		ev.AddedByUserID = userID
+		ev.CreatedAt = now
+		if err := insertClaimEvidence(ctx, tx, ev); err != nil {
+			return err
+		}
+		c.Evidence = append(c.Evidence, *ev)
+	}
+
+	if err := r.appendClaimEvent(ctx, tx, c, domain.EventClaimOpened, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkDamageClaim verifies the claim's inspection, fills in the claimed amount from
+// the inspection's damage charges when none was given and returns the inspection's
+// photos as evidence.
+func checkDamageClaim(ctx context.Context, tx *sql.Tx, c *domain.Claim) ([]domain.ClaimEvidence, error) {
+	submissionID := *c.InspectionSubmissionID
+	var assetID int64
+	var inspectedAt time.Time
+	err := tx.QueryRowContext(ctx, "SELECT asset_id, created_at FROM inspection_submissions WHERE id = $1", submissionID).Scan(&assetID, &inspectedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: inspection %d not found", domain.ErrInvalidClaim, submissionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get inspection_submission: %w", err)
+	}
+	if assetID != c.AssetID {
+		return nil, fmt.Errorf("%w: inspection %d is of asset %d", domain.ErrInvalidClaim, submissionID, assetID)
+	}
+
+	var returned bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM return_actions WHERE reservation_id = $1 AND asset_id = $2 AND start_time <= $3)",
+		c.ReservationID, c.AssetID, inspectedAt).Scan(&returned)
+	if err != nil {
+		return nil, fmt.Errorf("check return: %w", err)
+	}
+	if !returned {
+		return nil, fmt.Errorf("%w: inspection %d was not made after asset %d came back from reservation %d", domain.ErrInvalidClaim, submissionID, c.AssetID, c.ReservationID)
+	}
+
+	var existing int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM claims WHERE inspection_submission_id = $1 AND status <> $2 LIMIT 1", submissionID, domain.ClaimDenied).Scan(&existing)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("check existing claims: %w", err)
+	}
+	if err == nil {
+		return nil, fmt.Errorf("%w: inspection %d already has claim %d", domain.ErrInvalidClaim, submissionID, existing)
+	}
+
+	var findings int
+	var charges int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(f.damage_charge), 0)
+		FROM inspection_responses ir JOIN inspection_fields f ON f.id = ir.field_id
+		WHERE ir.submission_id = $1 AND f.damage_value IS NOT NULL AND ir.response_value = f.damage_value`, submissionID).Scan(&findings, &charges)
+	if err != nil {
+		return nil, fmt.Errorf("sum damage charges: %w", err)
+	}
+	if findings == 0 {
+		return nil, fmt.Errorf("%w: inspection %d did not record any damage", domain.ErrInvalidClaim, submissionID)
+	}
+	if c.ClaimedAmount == 0 {
+		c.ClaimedAmount = charges
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT ir.id, ir.response_value, f.label
+		FROM inspection_responses ir JOIN inspection_fields f ON f.id = ir.field_id
+		WHERE ir.submission_id = $1 AND f.field_type = $2 AND COALESCE(ir.response_value, '') <> ''
+		ORDER BY f.display_order, ir.id`, submissionID, domain.FieldTypeImage)
+	if err != nil {
+		return nil, fmt.Errorf("query inspection images: %w", err)
+	}
+	defer rows.Close()
+
+	var evidence []domain.ClaimEvidence
+	for rows.Next() {
+		var ev domain.ClaimEvidence
+		var responseID int64
+		if err := rows.Scan(&responseID, &ev.URL, &ev.Note); err != nil {
+			return nil, fmt.Errorf("scan inspection image: %w", err)
+		}
+		ev.InspectionResponseID = &responseID
+		evidence = append(evidence, ev)
+	}
+	return evidence, rows.Err()
+}
+
+// checkLossClaim ties a loss claim to the asset's outstanding check-out on the reservation.
+func checkLossClaim(ctx context.Context, tx *sql.Tx, c *domain.Claim) error {
+	var checkOutID int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT co.id FROM check_out_actions co
+		WHERE co.reservation_id = $1 AND co.asset_id = $2 AND co.action_status = 'Completed'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM return_actions ret
+		      WHERE ret.reservation_id = co.reservation_id AND ret.asset_id = co.asset_id AND ret.start_time >= co.start_time
+		  )
+		ORDER BY co.start_time DESC LIMIT 1`, c.ReservationID, c.AssetID).Scan(&checkOutID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: asset %d is not checked out on reservation %d", domain.ErrInvalidClaim, c.AssetID, c.ReservationID)
+	}
+	if err != nil {
+		return fmt.Errorf("get outstanding check-out: %w", err)
+	}
+
+	var existing int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM claims WHERE check_out_id = $1 AND status <> $2 LIMIT 1", checkOutID, domain.ClaimDenied).Scan(&existing)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("check existing claims: %w", err)
+	}
+	if err == nil {
+		return fmt.Errorf("%w: the check-out of asset %d already has claim %d", domain.ErrInvalidClaim, c.AssetID, existing)
+	}
+	c.CheckOutID = &checkOutID
+	return nil
+}
+
+func insertClaimEvidence(ctx context.Context, q queryer, ev *domain.ClaimEvidence) error {
+	err := q.QueryRowContext(ctx, `INSERT INTO claim_evidence (claim_id, url, note, inspection_response_id, added_by_user_id, created_at)
+	                              VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6) RETURNING id`,
+		ev.ClaimID, ev.URL, ev.Note, ev.InspectionResponseID, ev.AddedByUserID, ev.CreatedAt,
+	).Scan(&ev.ID)
+	if err != nil {
+		return fmt.Errorf("insert claim_evidence: %w", err)
+	}
+	return nil
+}
+
+func (r *SqlRepository) appendClaimEvent(ctx context.Context, tx *sql.Tx, c *domain.Claim, event domain.EventType, extra map[string]interface{}) error {
+	data := map[string]interface{}{
+		"claim_id":       c.ID,
+		"reservation_id": c.ReservationID,
+		"asset_id":       c.AssetID,
+		"type":           c.Type,
+		"status":         c.Status,
+		"claimed_amount": c.ClaimedAmount,
+		"settled_amount": c.SettledAmount,
+		"currency":       c.Currency,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	payload, _ := json.Marshal(data)
+	return r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: event, Payload: payload})
+}
+
+// GetClaim returns a claim with its evidence, or nil if it does not exist.
+func (r *SqlRepository) GetClaim(ctx context.Context, id int64) (*domain.Claim, error) {
+	claims, err := r.queryClaims(ctx, `SELECT `+claimColumns+` FROM claims WHERE id = $1`, id)
+	if err != nil || len(claims) == 0 {
+		return nil, err
+	}
+	return &claims[0], nil
+}
+
+// ListClaims returns the claims matching filter with their evidence, newest first.
+func (r *SqlRepository) ListClaims(ctx context.Context, filter domain.ClaimFilter) ([]domain.Claim, error) {
+	var conds []string
+	var args []any
+	if filter.ReservationID != nil {
+		args = append(args, *filter.ReservationID)
+		conds = append(conds, fmt.Sprintf("reservation_id = $%d", len(args)))
+	}
+	if filter.AssetID != nil {
+		args = append(args, *filter.AssetID)
+		conds = append(conds, fmt.Sprintf("asset_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+	query := `SELECT ` + claimColumns + ` FROM claims`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+	return r.queryClaims(ctx, query, args...)
+}
+
+// AddClaimEvidence attaches a photo or note to a claim.
+func (r *SqlRepository) AddClaimEvidence(ctx context.Context, ev *domain.ClaimEvidence) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c, err := scanClaim(tx.QueryRowContext(ctx, `SELECT `+claimColumns+` FROM claims WHERE id = $1`, ev.ClaimID))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: claim %d not found", domain.ErrInvalidClaim, ev.ClaimID)
+	}
+	if err != nil {
+		return fmt.Errorf("get claim: %w", err)
+	}
+
+	ev.CreatedAt = time.Now()
+	if err := insertClaimEvidence(ctx, tx, ev); err != nil {
+		return err
+	}
+	if err := r.appendClaimEvent(ctx, tx, c, domain.EventClaimEvidenceAdded, map[string]interface{}{"evidence_id": ev.ID}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateClaimStatus moves a claim through review, settlement or denial. It returns
+// nil, nil when the claim does not exist.
+func (r *SqlRepository) UpdateClaimStatus(ctx context.Context, id int64, upd *domain.ClaimStatusUpdate, userID *int64) (*domain.Claim, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c, err := scanClaim(tx.QueryRowContext(ctx, `SELECT `+claimColumns+` FROM claims WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get claim: %w", err)
+	}
+
+	from := c.Status
+	if err := c.ApplyStatus(upd, time.Now()); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE claims SET status = $1, settled_amount = $2, settled_at = $3, resolution_note = NULLIF($4, ''), updated_at = $5
+	                              WHERE id = $6`, c.Status, c.SettledAmount, c.SettledAt, c.ResolutionNote, c.UpdatedAt, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("update claim: %w", err)
+	}
+	if err := r.appendClaimEvent(ctx, tx, c, c.Event(), map[string]interface{}{"from": from, "user_id": userID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	evidence, err := r.listClaimEvidence(ctx, []int64{c.ID})
+	if err != nil {
+		return nil, err
+	}
+	c.Evidence = append(c.Evidence, evidence[c.ID]...)
+	return c, nil
+}
+
+func (r *SqlRepository) queryClaims(ctx context.Context, query string, args ...any) ([]domain.Claim, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list claims: %w", err)
+	}
+	defer rows.Close()
+
+	claims := []domain.Claim{}
+	var ids []int64
+	for rows.Next() {
+		c, err := scanClaim(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan claim: %w", err)
+		}
+		claims = append(claims, *c)
+		ids = append(ids, c.ID)
+	}
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return claims, err
+	}
+
+	evidence, err := r.listClaimEvidence(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range claims {
+		claims[i].Evidence = append(claims[i].Evidence, evidence[claims[i].ID]...)
+	}
+	return claims, nil
+}
+
+func (r *SqlRepository) listClaimEvidence(ctx context.Context, claimIDs []int64) (map[int64][]domain.ClaimEvidence, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, claim_id, COALESCE(url, ''), COALESCE(note, ''), inspection_response_id, added_by_user_id, created_at
+	                                     FROM claim_evidence WHERE claim_id = ANY($1) ORDER BY claim_id, id`, pq.Array(claimIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list claim_evidence: %w", err)
+	}
+	defer rows.Close()
+
+	results := make(map[int64][]domain.ClaimEvidence)
+	for rows.Next() {
+		var ev domain.ClaimEvidence
+		if err := rows.Scan(&ev.ID, &ev.ClaimID, &ev.URL, &ev.Note, &ev.InspectionResponseID, &ev.AddedByUserID, &ev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan claim_evidence: %w", err)
+		}
+		results[ev.ClaimID] = append(results[ev.ClaimID], ev)
+	}
+	return results, rows.Err()
+}
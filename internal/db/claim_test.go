@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_CreateClaim_Damage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	inspectedAt := time.Now().Add(-time.Hour)
+	submissionID, userID := int64(30), int64(1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT under_name_id FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"under_name_id"}).AddRow(20))
+	mock.ExpectQuery("SELECT asset_id, created_at FROM inspection_submissions WHERE id = \\$1").
+		WithArgs(submissionID).
+		WillReturnRows(sqlmock.NewRows([]string{"asset_id", "created_at"}).AddRow(1, inspectedAt))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM return_actions WHERE reservation_id = \\$1 AND asset_id = \\$2 AND start_time <= \\$3\\)").
+		WithArgs(3, 1, inspectedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT id FROM claims WHERE inspection_submission_id = \\$1").
+		WithArgs(submissionID, domain.ClaimDenied).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// Two damage findings, charged at 150.00 between them
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), COALESCE\\(SUM\\(f.damage_charge\\), 0\\)").
+		WithArgs(submissionID).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(2, 15000))
+	mock.ExpectQuery("SELECT ir.id, ir.response_value, f.label").
+		WithArgs(submissionID, domain.FieldTypeImage).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "response_value", "label"}).AddRow(301, "https://img.example/dent.jpg", "Left side"))
+	mock.ExpectQuery("SELECT organization_id FROM organization_roles").
+		WithArgs(20, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow(40))
+	mock.ExpectQuery("INSERT INTO claims").
+		WithArgs(3, 1, domain.ClaimDamage, domain.ClaimOpen, submissionID, nil, 20, 40, "", "", 15000, userID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery("INSERT INTO claim_evidence").
+		WithArgs(5, "https://img.example/dent.jpg", "Left side", 301, userID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO outbox_events").
+		WithArgs(domain.EventClaimOpened, sqlmock.AnyArg(), domain.OutboxPending, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	c := &domain.Claim{Type: domain.ClaimDamage, ReservationID: 3, AssetID: 1, InspectionSubmissionID: &submissionID}
+	require.NoError(t, repo.CreateClaim(ctx, c, &userID))
+	assert.Equal(t, int64(5), c.ID)
+	assert.Equal(t, int64(15000), c.ClaimedAmount)
+	require.NotNil(t, c.ResponsibleCompanyID)
+	assert.Equal(t, int64(40), *c.ResponsibleCompanyID)
+	require.Len(t, c.Evidence, 1)
+	assert.Equal(t, int64(5), c.Evidence[0].ClaimID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_CreateClaim_LossAlreadyClaimed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT under_name_id FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"under_name_id"}).AddRow(nil))
+	mock.ExpectQuery("SELECT co.id FROM check_out_actions co").
+		WithArgs(3, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(70))
+	// A claim still stands against the check-out
+	mock.ExpectQuery("SELECT id FROM claims WHERE check_out_id = \\$1").
+		WithArgs(70, domain.ClaimDenied).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectRollback()
+
+	err = repo.CreateClaim(ctx, &domain.Claim{Type: domain.ClaimLoss, ReservationID: 3, AssetID: 2}, nil)
+	assert.True(t, errors.Is(err, domain.ErrInvalidClaim))
+	assert.Contains(t, err.Error(), "already has claim 4")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// GetReservationConflicts explains what stands between a reservation and approval:
+// per demand, the reservations, holds, deployed or maintenance assets and expiring or
+// unreturned stock using the inventory in its window, and the earliest window of the
+// same length, starting no later than horizon from now, in which it would fit. The
+// reservation's own usage is left out. It returns nil, nil when the reservation does
+// not exist.
+func (r *SqlRepository) GetReservationConflicts(ctx context.Context, id int64, horizon time.Duration) (*domain.ReservationConflicts, error) {
+	rr, err := getRentalReservation(ctx, r.db, id, false)
+	if err != nil || rr == nil {
+		return nil, err
+	}
+	lines, err := r.expandDemands(ctx, r.db, rr.Demands)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	in := domain.ConflictInput{Reservation: rr, SearchFrom: rr.StartTime, SearchUntil: now.Add(horizon)}
+	if in.SearchFrom.Before(now) {
+		in.SearchFrom = now
+	}
+	for _, line := range lines {
+		in.Lines = append(in.Lines, domain.ConflictLine{DemandID: line.demandID, Requirement: line.requirement(), Transit: line.transit})
+	}
+	itemTypeIDs := linesItemTypes(lines)
+	if len(itemTypeIDs) == 0 {
+		return domain.ExplainConflicts(in), nil
+	}
+
+	in.Owned, in.Buffers, err = itemTypeInventory(ctx, r.db, itemTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Load usage from the start of the reservation's own window through the end of the
+	// last window the search may try, widened by the longest buffers and transit
+	last := in.SearchUntil
+	if last.Before(rr.StartTime) {
+		last = rr.StartTime
+	}
+	transit := linesTransit(lines)
+	windowStart, windowEnd := rr.StartTime, last.Add(rr.EndTime.Sub(rr.StartTime))
+	for _, b := range in.Buffers {
+		s, _ := b.Occupied(rr.StartTime, rr.EndTime, transit)
+		_, e := b.Occupied(last, last.Add(rr.EndTime.Sub(rr.StartTime)), transit)
+		if s.Before(windowStart) {
+			windowStart = s
+		}
+		if e.After(windowEnd) {
+			windowEnd = e
+		}
+	}
+	if in.Consumers, err = loadInventoryConsumers(ctx, r.db, itemTypeIDs, windowStart, windowEnd, id); err != nil {
+		return nil, err
+	}
+	return domain.ExplainConflicts(in), nil
+}
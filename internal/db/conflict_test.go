@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_GetReservationConflicts_FungibleStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	end := start.Add(24 * time.Hour)
+
+	// Twelve of fungible item type 5
+	expectRentalReservation(mock, 3, domain.ReservationStatusPending, start, end, false,
+		sqlmock.NewRows(demandCols).AddRow(30, 3, 0, "item_type", 5, 12, "", "", nil, nil, start, start))
+	mock.ExpectQuery("SELECT (.+) FROM substitution_rules WHERE is_active").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows(substitutionRuleCols))
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}).AddRow(5, 0, 0))
+	// Twenty owned according to the stock ledger
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets (.+) UNION ALL SELECT m.item_type_id, SUM(.+) FROM stock_movements m").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(5, 20))
+	// Another reservation takes six over the same window
+	mock.ExpectQuery("SELECT d.item_id, (.+) FROM demands d JOIN rental_reservations rr (.+) AND rr.id != \\$4").
+		WithArgs("{5}", sqlmock.AnyArg(), sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols).AddRow(5, start, end, 6, 4, 40, "Gala"))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{5}", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM asset_holds h JOIN assets a (.+) AND h.reservation_id != \\$4").
+		WithArgs("{5}", sqlmock.AnyArg(), sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
+	// Five on hand expire halfway through, and two are still out on a reservation that has ended
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols).
+			AddRow(5, start.Add(12*time.Hour), 5, domain.ConsumerExpiry, 1, "L1", nil, "").
+			AddRow(5, nil, 2, domain.ConsumerStockOut, nil, "", 2, "Launch"))
+
+	out, err := repo.GetReservationConflicts(ctx, 3, 30*24*time.Hour)
+	require.NoError(t, err)
+	assert.False(t, out.Fits)
+	require.Len(t, out.Demands, 1)
+	require.Len(t, out.Demands[0].ItemTypes, 1)
+	stock := out.Demands[0].ItemTypes[0]
+	assert.Equal(t, 20, stock.Owned)
+	assert.Equal(t, 7, stock.Available)
+	assert.Equal(t, 5, stock.Short)
+	require.Len(t, stock.Consumers, 3)
+	assert.Equal(t, domain.ConsumerReservation, stock.Consumers[0].Kind)
+	assert.Equal(t, "L1", stock.Consumers[1].LotNumber)
+	assert.Equal(t, domain.ConsumerStockOut, stock.Consumers[2].Kind)
+
+	// Thirteen are left once the other reservation is over
+	require.NotNil(t, out.EarliestFit)
+	assert.True(t, out.EarliestFit.Start.Equal(end))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
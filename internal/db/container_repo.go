@@ -0,0 +1,622 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// containerTree is a recursive CTE "container_tree" of the containers in $1 and every
+// container nested in them, however deep.
+const containerTree = `WITH RECURSIVE container_tree AS (
+	SELECT id FROM containers WHERE id = ANY($1)
+	UNION
+	SELECT c.id FROM containers c JOIN container_tree t ON c.parent_container_id = t.id
+)`
+
+const containerColumns = `id, tag, name, kind, parent_container_id, place_id, created_at, updated_at`
+
+func scanContainer(scanner interface{ Scan(...any) error }) (*domain.Container, error) {
+	var c domain.Container
+	if err := scanner.Scan(&c.ID, &c.Tag, &c.Name, &c.Kind, &c.ParentContainerID, &c.PlaceID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// containerAssetIDs returns every asset inside the containers, nested ones included.
+func containerAssetIDs(ctx context.Context, q queryer, containerIDs []int64) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, containerTree+`
+		SELECT a.id FROM assets a JOIN container_tree t ON t.id = a.container_id ORDER BY a.id`, pq.Array(containerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("query container assets: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan container asset: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// moveContainers puts the containers, everything nested in them and all their assets
+// at a place.
+func moveContainers(ctx context.Context, tx *sql.Tx, containerIDs []int64, placeID *int64, now time.Time) error {
+	if len(containerIDs) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, containerTree+`
+		UPDATE containers SET place_id = $2, updated_at = $3 WHERE id IN (SELECT id FROM container_tree)`,
+		pq.Array(containerIDs), placeID, now); err != nil {
+		return fmt.Errorf("move containers: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, containerTree+`
+		UPDATE assets SET place_id = $2, updated_at = $3 WHERE container_id IN (SELECT id FROM container_tree)`,
+		pq.Array(containerIDs), placeID, now); err != nil {
+		return fmt.Errorf("move container contents: %w", err)
+	}
+	return nil
+}
+
+// loadContainer reads a container with everything nested in it.
+func loadContainer(ctx context.Context, q queryer, id int64) (*domain.Container, error) {
+	rows, err := q.QueryContext(ctx, containerTree+`
+		SELECT `+containerColumns+` FROM containers WHERE id IN (SELECT id FROM container_tree)`, pq.Array([]int64{id}))
+	if err != nil {
+		return nil, fmt.Errorf("query containers: %w", err)
+	}
+	var containers []domain.Container
+	for rows.Next() {
+		c, err := scanContainer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan container: %w", err)
+		}
+		containers = append(containers, *c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+
+	rows, err = q.QueryContext(ctx, containerTree+`
+		SELECT a.id, COALESCE(a.asset_tag, ''), COALESCE(a.serial_number, ''), a.item_type_id, it.name, a.container_id
+		FROM assets a JOIN container_tree t ON t.id = a.container_id JOIN item_types it ON it.id = a.item_type_id
+		ORDER BY a.asset_tag, a.id`, pq.Array([]int64{id}))
+	if err != nil {
+		return nil, fmt.Errorf("query container assets: %w", err)
+	}
+	defer rows.Close()
+	var assets []domain.ContainerAsset
+	for rows.Next() {
+		var a domain.ContainerAsset
+		if err := rows.Scan(&a.AssetID, &a.AssetTag, &a.SerialNumber, &a.ItemTypeID, &a.ItemTypeName, &a.ContainerID); err != nil {
+			return nil, fmt.Errorf("scan container asset: %w", err)
+		}
+		assets = append(assets, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	roots, _ := domain.BuildContainerTree(containers, assets)
+	for i := range roots {
+		if roots[i].ID == id {
+			return &roots[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateContainer registers a case, pallet or crate. Tags are unique.
+func (r *SqlRepository) CreateContainer(ctx context.Context, c *domain.Container) error {
+	now := time.Now()
+	c.CreatedAt, c.UpdatedAt = now, now
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO containers (tag, name, kind, place_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (tag) DO NOTHING RETURNING id`, c.Tag, c.Name, c.Kind, c.PlaceID, now).Scan(&c.ID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: tag %q is already in use", domain.ErrContainer, c.Tag)
+	}
+	if err != nil {
+		return fmt.Errorf("insert container: %w", err)
+	}
+	return nil
+}
+
+// GetContainer returns a container with its nested contents, or nil if it does not exist.
+func (r *SqlRepository) GetContainer(ctx context.Context, id int64) (*domain.Container, error) {
+	return loadContainer(ctx, r.db, id)
+}
+
+// ListContainers returns container headers, optionally those at one place.
+func (r *SqlRepository) ListContainers(ctx context.Context, placeID *int64) ([]domain.Container, error) {
+	query := `SELECT ` + containerColumns + ` FROM containers`
+	var args []interface{}
+	if placeID != nil {
+		query += ` WHERE place_id = $1`
+		args = append(args, *placeID)
+	}
+	query += ` ORDER BY tag`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query containers: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.Container{}
+	for rows.Next() {
+		c, err := scanContainer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan container: %w", err)
+		}
+		results = append(results, *c)
+	}
+	return results, rows.Err()
+}
+
+// nestInContainer puts assets and containers into a container, at its place. A
+// container cannot go inside itself or anything nested in it.
+func nestInContainer(ctx context.Context, tx *sql.Tx, containerID int64, assetIDs, containerIDs []int64, now time.Time) error {
+	var placeID *int64
+	err := tx.QueryRowContext(ctx, "SELECT place_id FROM containers WHERE id = $1 FOR UPDATE", containerID).Scan(&placeID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: container %d not found", domain.ErrContainer, containerID)
+	}
+	if err != nil {
+		return fmt.Errorf("lock container: %w", err)
+	}
+
+	if len(containerIDs) > 0 {
+		var cycle bool
+		if err := tx.QueryRowContext(ctx, containerTree+`
+			SELECT EXISTS (SELECT 1 FROM container_tree WHERE id = $2)`, pq.Array(containerIDs), containerID).Scan(&cycle); err != nil {
+			return fmt.Errorf("check container nesting: %w", err)
+		}
+		if cycle {
+			return fmt.Errorf("%w: container %d cannot go inside itself", domain.ErrContainer, containerID)
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE containers SET parent_container_id = $1, updated_at = $2 WHERE id = ANY($3)",
+			containerID, now, pq.Array(containerIDs))
+		if err != nil {
+			return fmt.Errorf("nest containers: %w", err)
+		}
+		if n, _ := res.RowsAffected(); int(n) != len(containerIDs) {
+			return fmt.Errorf("%w: unknown container in %v", domain.ErrContainer, containerIDs)
+		}
+		if err := moveContainers(ctx, tx, containerIDs, placeID, now); err != nil {
+			return err
+		}
+	}
+	if len(assetIDs) > 0 {
+		res, err := tx.ExecContext(ctx, "UPDATE assets SET container_id = $1, place_id = $2, updated_at = $3 WHERE id = ANY($4)",
+			containerID, placeID, now, pq.Array(assetIDs))
+		if err != nil {
+			return fmt.Errorf("pack assets: %w", err)
+		}
+		if n, _ := res.RowsAffected(); int(n) != len(assetIDs) {
+			return fmt.Errorf("%w: unknown asset in %v", domain.ErrContainer, assetIDs)
+		}
+	}
+	return nil
+}
+
+// AddToContainer puts assets and containers into a container; they move to wherever it
+// is. It returns nil, nil when the container does not exist.
+func (r *SqlRepository) AddToContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM containers WHERE id = $1)", id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check container: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	if err := nestInContainer(ctx, tx, id, req.AssetIDs, req.ContainerIDs, time.Now()); err != nil {
+		return nil, err
+	}
+
+	c, err := loadContainer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return c, tx.Commit()
+}
+
+// RemoveFromContainer takes assets and containers directly inside a container out of
+// it. They stay where they are. It returns nil, nil when the container does not exist.
+func (r *SqlRepository) RemoveFromContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if len(req.AssetIDs) > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE assets SET container_id = NULL, updated_at = $1 WHERE container_id = $2 AND id = ANY($3)",
+			now, id, pq.Array(req.AssetIDs)); err != nil {
+			return nil, fmt.Errorf("unpack assets: %w", err)
+		}
+	}
+	if len(req.ContainerIDs) > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE containers SET parent_container_id = NULL, updated_at = $1 WHERE parent_container_id = $2 AND id = ANY($3)",
+			now, id, pq.Array(req.ContainerIDs)); err != nil {
+			return nil, fmt.Errorf("unnest containers: %w", err)
+		}
+	}
+
+	c, err := loadContainer(ctx, tx, id)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	return c, tx.Commit()
+}
+
+// CheckOutContainer checks out every asset in a container, nested ones included, to a
+// reservation and sends the container along with them. A nested container leaves the
+// one it was in. It returns nil, nil when the container does not exist.
+func (r *SqlRepository) CheckOutContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, agentID int64) (*domain.Container, error) {
+	return r.moveContainer(ctx, id, req, func(tx *sql.Tx, assetIDs []int64, now time.Time) error {
+		return checkOutAssets(ctx, tx, req.ReservationID, assetIDs, agentID, req.FromLocationID, req.ToLocationID, nil, now)
+	})
+}
+
+// ReturnContainer returns every asset in a container from a reservation and brings the
+// container back with them. It returns nil, nil when the container does not exist.
+func (r *SqlRepository) ReturnContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, agentID int64) (*domain.Container, error) {
+	return r.moveContainer(ctx, id, req, func(tx *sql.Tx, assetIDs []int64, now time.Time) error {
+		return returnAssets(ctx, tx, req.ReservationID, assetIDs, agentID, req.ToLocationID, now)
+	})
+}
+
+// moveContainer runs a check-out or return for a container's assets and moves the
+// container and everything in it to the request's destination.
+func (r *SqlRepository) moveContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, move func(tx *sql.Tx, assetIDs []int64, now time.Time) error) (*domain.Container, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM containers WHERE id = $1)", id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check container: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	assetIDs, err := containerAssetIDs(ctx, tx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(assetIDs) == 0 {
+		return nil, fmt.Errorf("%w: container %d is empty", domain.ErrContainer, id)
+	}
+
+	now := time.Now()
+	if err := move(tx, assetIDs, now); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE containers SET parent_container_id = NULL, updated_at = $1 WHERE id = $2", now, id); err != nil {
+		return nil, fmt.Errorf("unnest container: %w", err)
+	}
+	if err := moveContainers(ctx, tx, []int64{id}, req.ToLocationID, now); err != nil {
+		return nil, err
+	}
+
+	c, err := loadContainer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.syncFulfillment(ctx, []int64{req.ReservationID})
+	return c, nil
+}
+
+// PackShipment records a scan while packing an outbound shipment. Scanning a container
+// loads it and everything in it; scanning an asset packs it loose or into a container
+// already on the shipment. Anything scanned that is not yet allocated to the shipment
+// is allocated to it. It returns the updated manifest, or nil, nil when the shipment
+// does not exist.
+func (r *SqlRepository) PackShipment(ctx context.Context, shipmentID int64, scan *domain.PackScan, agentID int64) (*domain.Manifest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status domain.DeliveryStatus
+	var direction string
+	err = tx.QueryRowContext(ctx, "SELECT status, direction FROM shipments WHERE id = $1 FOR UPDATE", shipmentID).Scan(&status, &direction)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock shipment: %w", err)
+	}
+	if domain.NormalizeDeliveryStatus(status) != domain.DeliveryPreparing {
+		return nil, fmt.Errorf("%w: shipment %d has already left", domain.ErrContainer, shipmentID)
+	}
+	if direction == "inbound" {
+		return nil, fmt.Errorf("%w: shipment %d is inbound", domain.ErrContainer, shipmentID)
+	}
+
+	if scan.ContainerID != nil {
+		var onBoard bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM shipment_containers WHERE shipment_id = $1 AND container_id = $2)",
+			shipmentID, *scan.ContainerID).Scan(&onBoard); err != nil {
+			return nil, fmt.Errorf("check packed container: %w", err)
+		}
+		if !onBoard {
+			return nil, fmt.Errorf("%w: container %d is not packed on shipment %d", domain.ErrContainer, *scan.ContainerID, shipmentID)
+		}
+	}
+
+	now := time.Now()
+	var containerID int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM containers WHERE tag = $1", scan.Code).Scan(&containerID)
+	switch {
+	case err == nil:
+		err = packContainer(ctx, tx, shipmentID, containerID, scan.ContainerID, agentID, now)
+	case err == sql.ErrNoRows:
+		err = packAsset(ctx, tx, shipmentID, scan, agentID, now)
+	default:
+		err = fmt.Errorf("look up scanned container: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := loadManifest(ctx, tx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	return m, tx.Commit()
+}
+
+// packContainer loads a container onto a shipment, inside another one when parentID is
+// set, and packs every asset in it.
+func packContainer(ctx context.Context, tx *sql.Tx, shipmentID, containerID int64, parentID *int64, agentID int64, now time.Time) error {
+	if parentID != nil {
+		if err := nestInContainer(ctx, tx, *parentID, nil, []int64{containerID}, now); err != nil {
+			return err
+		}
+	} else if _, err := tx.ExecContext(ctx, "UPDATE containers SET parent_container_id = NULL, updated_at = $1 WHERE id = $2", now, containerID); err != nil {
+		return fmt.Errorf("unnest container: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, containerTree+`
+		INSERT INTO shipment_containers (shipment_id, container_id, parent_container_id, packed_at, packed_by_user_id)
+		SELECT $2, c.id, c.parent_container_id, $3, $4 FROM containers c JOIN container_tree t ON t.id = c.id
+		ON CONFLICT (shipment_id, container_id) DO UPDATE
+		SET parent_container_id = EXCLUDED.parent_container_id, packed_at = EXCLUDED.packed_at, packed_by_user_id = EXCLUDED.packed_by_user_id`,
+		pq.Array([]int64{containerID}), shipmentID, now, agentID); err != nil {
+		return fmt.Errorf("load containers onto shipment: %w", err)
+	}
+
+	assetIDs, err := containerAssetIDs(ctx, tx, []int64{containerID})
+	if err != nil {
+		return err
+	}
+	if err := allocateForPacking(ctx, tx, shipmentID, assetIDs, agentID, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE check_out_actions co SET packed_at = $1, packed_container_id = a.container_id
+		FROM assets a
+		WHERE a.id = co.asset_id AND co.shipment_id = $2 AND co.action_status = 'Potential' AND co.asset_id = ANY($3)`,
+		now, shipmentID, pq.Array(assetIDs)); err != nil {
+		return fmt.Errorf("mark container contents packed: %w", err)
+	}
+	return nil
+}
+
+// packAsset packs a single scanned asset onto a shipment, loose or into a container
+// already loaded. Packed loose, it leaves any container that is not on the shipment.
+func packAsset(ctx context.Context, tx *sql.Tx, shipmentID int64, scan *domain.PackScan, agentID int64, now time.Time) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, container_id FROM assets WHERE asset_tag = $1 OR serial_number = $1 LIMIT 2", scan.Code)
+	if err != nil {
+		return fmt.Errorf("look up scanned asset: %w", err)
+	}
+	var matches int
+	var assetID int64
+	var containerID *int64
+	for rows.Next() {
+		matches++
+		if err := rows.Scan(&assetID, &containerID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan scanned asset: %w", err)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	switch {
+	case matches == 0:
+		return fmt.Errorf("%w: no asset or container with tag %q", domain.ErrContainer, scan.Code)
+	case matches > 1:
+		return fmt.Errorf("%w: %q matches more than one asset", domain.ErrContainer, scan.Code)
+	}
+
+	switch {
+	case scan.ContainerID != nil:
+		if err := nestInContainer(ctx, tx, *scan.ContainerID, []int64{assetID}, nil, now); err != nil {
+			return err
+		}
+		containerID = scan.ContainerID
+	case containerID != nil:
+		var onBoard bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM shipment_containers WHERE shipment_id = $1 AND container_id = $2)",
+			shipmentID, *containerID).Scan(&onBoard); err != nil {
+			return fmt.Errorf("check packed container: %w", err)
+		}
+		if !onBoard {
+			if _, err := tx.ExecContext(ctx, "UPDATE assets SET container_id = NULL, updated_at = $1 WHERE id = $2", now, assetID); err != nil {
+				return fmt.Errorf("unpack asset: %w", err)
+			}
+			containerID = nil
+		}
+	}
+
+	if err := allocateForPacking(ctx, tx, shipmentID, []int64{assetID}, agentID, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE check_out_actions SET packed_at = $1, packed_container_id = $2
+		WHERE shipment_id = $3 AND asset_id = $4 AND action_status = 'Potential'`,
+		now, containerID, shipmentID, assetID); err != nil {
+		return fmt.Errorf("mark asset packed: %w", err)
+	}
+	return nil
+}
+
+// allocateForPacking allocates to the shipment any of the assets that are not on it
+// yet. Assets already waiting to go out on another shipment cannot be packed.
+func allocateForPacking(ctx context.Context, tx *sql.Tx, shipmentID int64, assetIDs []int64, agentID int64, now time.Time) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT co.asset_id, co.shipment_id FROM check_out_actions co
+		WHERE co.asset_id = ANY($1) AND co.action_status = 'Potential'`, pq.Array(assetIDs))
+	if err != nil {
+		return fmt.Errorf("query allocated assets: %w", err)
+	}
+	allocated := make(map[int64]bool)
+	for rows.Next() {
+		var assetID int64
+		var onShipment sql.NullInt64
+		if err := rows.Scan(&assetID, &onShipment); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan allocated asset: %w", err)
+		}
+		if !onShipment.Valid || onShipment.Int64 != shipmentID {
+			rows.Close()
+			return fmt.Errorf("%w: asset %d is allocated elsewhere", domain.ErrContainer, assetID)
+		}
+		allocated[assetID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var missing []int64
+	for _, id := range assetIDs {
+		if !allocated[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if _, err := allocateAssetsToShipment(ctx, tx, shipmentID, missing, agentID, now); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrContainer, err)
+	}
+	return nil
+}
+
+// loadManifest lists a shipment's check-outs by the container they were packed in.
+func loadManifest(ctx context.Context, q queryer, shipmentID int64) (*domain.Manifest, error) {
+	m := &domain.Manifest{ShipmentID: shipmentID, GeneratedAt: time.Now()}
+	var carrier, tracking sql.NullString
+	err := q.QueryRowContext(ctx, "SELECT direction, carrier, tracking_number, status FROM shipments WHERE id = $1", shipmentID).
+		Scan(&m.Direction, &carrier, &tracking, &m.Status)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query shipment: %w", err)
+	}
+	m.Carrier, m.TrackingNumber = carrier.String, tracking.String
+	m.Status = domain.NormalizeDeliveryStatus(m.Status)
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.id, c.tag, c.name, c.kind, sc.parent_container_id, c.place_id, c.created_at, c.updated_at
+		FROM shipment_containers sc JOIN containers c ON c.id = sc.container_id
+		WHERE sc.shipment_id = $1`, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("query shipment containers: %w", err)
+	}
+	var containers []domain.Container
+	for rows.Next() {
+		c, err := scanContainer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan shipment container: %w", err)
+		}
+		containers = append(containers, *c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, `
+		SELECT a.id, COALESCE(a.asset_tag, ''), COALESCE(a.serial_number, ''), a.item_type_id, it.name, co.packed_container_id, co.packed_at
+		FROM check_out_actions co
+		JOIN assets a ON a.id = co.asset_id
+		JOIN item_types it ON it.id = a.item_type_id
+		WHERE co.shipment_id = $1
+		ORDER BY a.asset_tag, a.id`, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("query shipment assets: %w", err)
+	}
+	defer rows.Close()
+	var packed []domain.ContainerAsset
+	for rows.Next() {
+		var a domain.ContainerAsset
+		if err := rows.Scan(&a.AssetID, &a.AssetTag, &a.SerialNumber, &a.ItemTypeID, &a.ItemTypeName, &a.ContainerID, &a.PackedAt); err != nil {
+			return nil, fmt.Errorf("scan shipment asset: %w", err)
+		}
+		m.TotalCount++
+		if a.PackedAt == nil {
+			m.Unpacked = append(m.Unpacked, a)
+			continue
+		}
+		m.PackedCount++
+		packed = append(packed, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	m.Containers, m.Loose = domain.BuildContainerTree(containers, packed)
+	if m.Containers == nil {
+		m.Containers = []domain.Container{}
+	}
+	if m.Loose == nil {
+		m.Loose = []domain.ContainerAsset{}
+	}
+	if m.Unpacked == nil {
+		m.Unpacked = []domain.ContainerAsset{}
+	}
+	return m, nil
+}
+
+// GetShipmentManifest returns what a shipment carries, container by container, or nil
+// if the shipment does not exist.
+func (r *SqlRepository) GetShipmentManifest(ctx context.Context, shipmentID int64) (*domain.Manifest, error) {
+	return loadManifest(ctx, r.db, shipmentID)
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectPackScan expects PackShipment to lock outbound shipment 42, find container
+// parentID already on it, and look up the scanned container tag.
+func expectPackScan(mock sqlmock.Sqlmock, tag string, parentID, containerID int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status, direction FROM shipments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "direction"}).AddRow("DeliveryPreparing", "outbound"))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM shipment_containers WHERE shipment_id = \\$1 AND container_id = \\$2\\)").
+		WithArgs(42, parentID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT id FROM containers WHERE tag = \\$1").
+		WithArgs(tag).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(containerID))
+}
+
+func TestSqlRepository_PackShipment_NestsContainer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	pallet := int64(2)
+
+	// Case 3 is scanned onto pallet 2, which is at place 1
+	expectPackScan(mock, "CASE-3", pallet, 3)
+	mock.ExpectQuery("SELECT place_id FROM containers WHERE id = \\$1 FOR UPDATE").
+		WithArgs(pallet).
+		WillReturnRows(sqlmock.NewRows([]string{"place_id"}).AddRow(1))
+	mock.ExpectQuery("WITH RECURSIVE container_tree (.+) SELECT EXISTS \\(SELECT 1 FROM container_tree WHERE id = \\$2\\)").
+		WithArgs("{3}", pallet).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("UPDATE containers SET parent_container_id = \\$1, updated_at = \\$2 WHERE id = ANY\\(\\$3\\)").
+		WithArgs(pallet, sqlmock.AnyArg(), "{3}").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("WITH RECURSIVE container_tree (.+) UPDATE containers SET place_id = \\$2").
+		WithArgs("{3}", 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("WITH RECURSIVE container_tree (.+) UPDATE assets SET place_id = \\$2").
+		WithArgs("{3}", 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// The case goes on board under the pallet, with its contents
+	mock.ExpectExec("WITH RECURSIVE container_tree (.+) INSERT INTO shipment_containers").
+		WithArgs("{3}", 42, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("WITH RECURSIVE container_tree (.+) SELECT a.id FROM assets a").
+		WithArgs("{3}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11).AddRow(12))
+	mock.ExpectQuery("SELECT co.asset_id, co.shipment_id FROM check_out_actions co").
+		WithArgs("{11,12}").
+		WillReturnRows(sqlmock.NewRows([]string{"asset_id", "shipment_id"}).AddRow(11, 42).AddRow(12, 42))
+	mock.ExpectExec("UPDATE check_out_actions co SET packed_at = \\$1, packed_container_id = a.container_id").
+		WithArgs(sqlmock.AnyArg(), 42, "{11,12}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	mock.ExpectQuery("SELECT direction, carrier, tracking_number, status FROM shipments WHERE id = \\$1").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"direction", "carrier", "tracking_number", "status"}).
+			AddRow("outbound", nil, nil, "DeliveryPreparing"))
+	mock.ExpectQuery("SELECT (.+) FROM shipment_containers sc JOIN containers c").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tag", "name", "kind", "parent_container_id", "place_id", "created_at", "updated_at"}).
+			AddRow(2, "PAL-2", "Pallet", "pallet", nil, 1, now, now).
+			AddRow(3, "CASE-3", "Mic case", "case", 2, 1, now, now))
+	mock.ExpectQuery("SELECT (.+) FROM check_out_actions co JOIN assets a").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "asset_tag", "serial_number", "item_type_id", "name", "packed_container_id", "packed_at"}).
+			AddRow(11, "MIC-1", "", 7, "Wireless mic", 3, now).
+			AddRow(12, "MIC-2", "", 7, "Wireless mic", 3, now))
+	mock.ExpectCommit()
+
+	m, err := repo.PackShipment(ctx, 42, &domain.PackScan{Code: "CASE-3", ContainerID: &pallet}, 1)
+	require.NoError(t, err)
+	require.Len(t, m.Containers, 1)
+	assert.Equal(t, pallet, m.Containers[0].ID)
+	require.Len(t, m.Containers[0].Containers, 1)
+	assert.Equal(t, int64(3), m.Containers[0].Containers[0].ID)
+	assert.Equal(t, 2, m.Containers[0].AssetCount())
+	assert.Equal(t, 2, m.PackedCount)
+	assert.Empty(t, m.Loose)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_PackShipment_RejectsCycle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	caseID := int64(3)
+
+	// Pallet 2 is scanned into case 3, which is already on the pallet
+	expectPackScan(mock, "PAL-2", caseID, 2)
+	mock.ExpectQuery("SELECT place_id FROM containers WHERE id = \\$1 FOR UPDATE").
+		WithArgs(caseID).
+		WillReturnRows(sqlmock.NewRows([]string{"place_id"}).AddRow(1))
+	mock.ExpectQuery("WITH RECURSIVE container_tree (.+) SELECT EXISTS \\(SELECT 1 FROM container_tree WHERE id = \\$2\\)").
+		WithArgs("{2}", caseID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	m, err := repo.PackShipment(ctx, 42, &domain.PackScan{Code: "PAL-2", ContainerID: &caseID}, 1)
+	assert.Nil(t, m)
+	assert.True(t, errors.Is(err, domain.ErrContainer))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_AddToContainer_UnknownContent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM containers WHERE id = \\$1\\)").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT place_id FROM containers WHERE id = \\$1 FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"place_id"}).AddRow(nil))
+	// Only one of the two assets exists
+	mock.ExpectExec("UPDATE assets SET container_id = \\$1, place_id = \\$2, updated_at = \\$3 WHERE id = ANY\\(\\$4\\)").
+		WithArgs(2, nil, sqlmock.AnyArg(), "{11,99}").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	c, err := repo.AddToContainer(ctx, 2, &domain.ContainerContentsRequest{AssetIDs: []int64{11, 99}})
+	assert.Nil(t, c)
+	assert.True(t, errors.Is(err, domain.ErrContainer))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+const customerTokenColumns = `id, company_id, person_id, COALESCE(name, ''), hint, expires_at, revoked_at, last_used_at, created_by_user_id, created_at`
+
+func scanCustomerToken(scanner interface{ Scan(...any) error }) (*domain.CustomerToken, error) {
+	var t domain.CustomerToken
+	if err := scanner.Scan(&t.ID, &t.CompanyID, &t.PersonID, &t.Name, &t.Hint, &t.ExpiresAt, &t.RevokedAt, &t.LastUsedAt, &t.CreatedByUserID, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// companyReservation restricts rental_reservations rr to those booked under a person
+// who held a role at company $1 at the time; the company keeps seeing them after the
+// person moves on.
+const companyReservation = `EXISTS (SELECT 1 FROM organization_roles o
+	WHERE o.person_id = rr.under_name_id AND o.organization_id = $1
+	  AND (o.start_date IS NULL OR o.start_date <= rr.booking_time) AND (o.end_date IS NULL OR o.end_date > rr.booking_time))`
+
+// CreateCustomerToken stores a token issued by t.Issue under hash. The person must
+// currently hold a role at the company.
+func (r *SqlRepository) CreateCustomerToken(ctx context.Context, t *domain.CustomerToken, hash string) error {
+	now := time.Now()
+	var ok bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM organization_roles
+		WHERE person_id = $1 AND organization_id = $2
+		  AND (start_date IS NULL OR start_date <= $3) AND (end_date IS NULL OR end_date > $3))`,
+		t.PersonID, t.CompanyID, now).Scan(&ok)
+	if err != nil {
+		return fmt.Errorf("check organization role: %w", err)
+	}
+	if !ok {
+		return domain.ErrCustomerScope
+	}
+
+	t.CreatedAt = now
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO customer_tokens (company_id, person_id, name, token_hash, hint, expires_at, created_by_user_id, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8) RETURNING id`,
+		t.CompanyID, t.PersonID, t.Name, hash, t.Hint, t.ExpiresAt, t.CreatedByUserID, t.CreatedAt).Scan(&t.ID)
+}
+
+// ListCustomerTokens returns the tokens issued, optionally for one company, newest first.
+func (r *SqlRepository) ListCustomerTokens(ctx context.Context, companyID *int64) ([]domain.CustomerToken, error) {
+	query := `SELECT ` + customerTokenColumns + ` FROM customer_tokens`
+	var args []interface{}
+	if companyID != nil {
+		query += ` WHERE company_id = $1`
+		args = append(args, *companyID)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query customer_tokens: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.CustomerToken{}
+	for rows.Next() {
+		t, err := scanCustomerToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan customer_token: %w", err)
+		}
+		results = append(results, *t)
+	}
+	return results, rows.Err()
+}
+
+// RevokeCustomerToken revokes a token; revoking it again keeps the first revocation
+// time. It returns nil, nil when the token does not exist.
+func (r *SqlRepository) RevokeCustomerToken(ctx context.Context, id int64) (*domain.CustomerToken, error) {
+	t, err := scanCustomerToken(r.db.QueryRowContext(ctx, `
+		UPDATE customer_tokens SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1
+		RETURNING `+customerTokenColumns, id, time.Now()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("revoke customer_token: %w", err)
+	}
+	return t, nil
+}
+
+// AuthenticateCustomerToken looks a presented token up by its hash and records its use.
+// It returns nil, nil unless the token is unrevoked, unexpired and its person still
+// holds a role at its company.
+func (r *SqlRepository) AuthenticateCustomerToken(ctx context.Context, hash string, now time.Time) (*domain.CustomerToken, error) {
+	t, err := scanCustomerToken(r.db.QueryRowContext(ctx, `
+		UPDATE customer_tokens t SET last_used_at = $2
+		WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND (t.expires_at IS NULL OR t.expires_at > $2)
+		  AND EXISTS (SELECT 1 FROM organization_roles o
+		      WHERE o.person_id = t.person_id AND o.organization_id = t.company_id
+		        AND (o.start_date IS NULL OR o.start_date <= $2) AND (o.end_date IS NULL OR o.end_date > $2))
+		RETURNING `+customerTokenColumns, hash, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("authenticate customer_token: %w", err)
+	}
+	return t, nil
+}
+
+// ListCompanyReservations returns a company's reservations, newest first.
+func (r *SqlRepository) ListCompanyReservations(ctx context.Context, companyID int64) ([]domain.RentalReservation, error) {
+	query := `SELECT id, reservation_name, reservation_status, under_name_id, booking_time,
+	                 start_time, end_time, provider_id, series_id, recurrence_id, metadata,
+	                 submitted_at, approved_at, rejected_at, cancelled_at, fulfilled_at, overdue_at, updated_by_user_id, created_at, updated_at
+	          FROM rental_reservations rr WHERE ` + companyReservation + ` ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("query company rental_reservations: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.RentalReservation{}
+	for rows.Next() {
+		var rr domain.RentalReservation
+		var metadataJSON []byte
+		err := rows.Scan(
+			&rr.ID, &rr.ReservationName, &rr.ReservationStatus, &rr.UnderNameID, &rr.BookingTime,
+			&rr.StartTime, &rr.EndTime, &rr.ProviderID, &rr.SeriesID, &rr.RecurrenceID, &metadataJSON,
+			&rr.SubmittedAt, &rr.ApprovedAt, &rr.RejectedAt, &rr.CancelledAt, &rr.FulfilledAt, &rr.OverdueAt, &rr.UpdatedByUserID, &rr.CreatedAt, &rr.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan rental_reservation: %w", err)
+		}
+		rr.Metadata = json.RawMessage(metadataJSON)
+		results = append(results, rr)
+	}
+	return results, rows.Err()
+}
+
+// GetCompanyReservation returns a reservation with its demands if it belongs to the
+// company, and nil, nil otherwise, so other companies' reservations look like missing ones.
+func (r *SqlRepository) GetCompanyReservation(ctx context.Context, companyID, id int64) (*domain.RentalReservation, error) {
+	var ok bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM rental_reservations rr WHERE rr.id = $2 AND `+companyReservation+`)`,
+		companyID, id).Scan(&ok)
+	if err != nil {
+		return nil, fmt.Errorf("check company reservation: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return getRentalReservation(ctx, r.db, id, false)
+}
+
+// ListCompanyShipments returns the shipments carrying a company's assets, i.e. those
+// its reservations were checked out or returned on.
+func (r *SqlRepository) ListCompanyShipments(ctx context.Context, companyID int64, reservationID *int64) ([]domain.Shipment, error) {
+	onReservation := `rr.id = a.reservation_id`
+	args := []interface{}{companyID}
+	if reservationID != nil {
+		onReservation += ` AND rr.id = $2`
+		args = append(args, *reservationID)
+	}
+	query := `SELECT s.id, s.scheduled_delivery_id, s.provider_id, s.ship_date, COALESCE(s.carrier, ''), COALESCE(s.tracking_number, ''),
+	                 s.status, COALESCE(s.notes, ''), s.direction, s.created_at, s.updated_at
+	          FROM shipments s
+	          WHERE EXISTS (
+	              SELECT 1 FROM check_out_actions a JOIN rental_reservations rr ON ` + onReservation + `
+	              WHERE a.shipment_id = s.id AND ` + companyReservation + `
+	              UNION ALL
+	              SELECT 1 FROM return_actions a JOIN rental_reservations rr ON ` + onReservation + `
+	              WHERE a.shipment_id = s.id AND ` + companyReservation + `)`
+	query += ` ORDER BY s.ship_date`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query company shipments: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.Shipment{}
+	for rows.Next() {
+		var s domain.Shipment
+		if err := rows.Scan(&s.ID, &s.ScheduledDeliveryID, &s.ProviderID, &s.ShipDate, &s.Carrier, &s.TrackingNumber, &s.Status, &s.Notes, &s.Direction, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan shipment: %w", err)
+		}
+		results = append(results, s)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,793 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// holdableAssetCondition selects assets that can be pinned for a window starting at $2:
+// free now, or out on an ad-hoc basis with a known return, plus the item type's
+// turnaround, before the window opens.
+const holdableAssetCondition = `(a.status = 'available'
+	OR (a.status IN ('deployed', 'maintenance') AND (a.metadata->>'estimated_return_at')::timestamp
+		+ (SELECT (pre_buffer_minutes + post_buffer_minutes) * interval '1 minute' FROM item_types WHERE id = a.item_type_id) <= $2))`
+
+// holdTurnaround is a lateral subquery giving the gap an active hold "h" keeps clear on
+// both sides of its window: the asset's item type buffers plus transit to the hold's place.
+const holdTurnaround = `SELECT (it.pre_buffer_minutes + it.post_buffer_minutes + COALESCE(p.transit_minutes, 0)) * interval '1 minute' AS gap
+	FROM assets ha
+	JOIN item_types it ON it.id = ha.item_type_id
+	LEFT JOIN demands hd ON hd.id = h.demand_id
+	LEFT JOIN places p ON p.id = hd.place_id
+	WHERE ha.id = h.asset_id`
+
+// allocationLine is one per-item-type requirement of a demand that needs pinned assets.
+type allocationLine struct {
+	demandID    int64
+	itemTypeID  int64
+	quantity    int
+	substitutes []domain.SubstituteOption
+	transit     time.Duration // To the demand's place, each way
+}
+
+const holdColumns = `id, reservation_id, demand_id, asset_id, item_type_id, start_time, end_time, status, created_by_user_id, created_at, updated_at`
+
+func scanAssetHold(scanner interface{ Scan(...any) error }) (*domain.AssetHold, error) {
+	var h domain.AssetHold
+	err := scanner.Scan(&h.ID, &h.ReservationID, &h.DemandID, &h.AssetID, &h.ItemTypeID, &h.StartTime, &h.EndTime, &h.Status, &h.CreatedByUserID, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// AllocateReservation pins concrete assets to every demand line of a reservation that
+// is not yet fully held. Lines that cannot be covered are reported as shortfalls.
+// It returns nil, nil when the reservation does not exist.
+func (r *SqlRepository) AllocateReservation(ctx context.Context, reservationID int64, userID *int64) (*domain.AllocationResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := r.allocateReservationTx(ctx, tx, reservationID, userID)
+	if err != nil || result == nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// allocateReservationTx pins assets for a confirmed reservation inside tx. Holds on
+// anything not confirmed would take units out of availability without a commitment
+// behind them, so other statuses are refused. The item types are locked as approval
+// locks them, so a hold committed by a concurrent allocation is always seen. It
+// returns nil, nil when the reservation does not exist.
+func (r *SqlRepository) allocateReservationTx(ctx context.Context, tx *sql.Tx, reservationID int64, userID *int64) (*domain.AllocationResult, error) {
+	var start, end time.Time
+	var status domain.RentalReservationStatus
+	err := tx.QueryRowContext(ctx, "SELECT start_time, end_time, reservation_status FROM rental_reservations WHERE id = $1 FOR UPDATE", reservationID).Scan(&start, &end, &status)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock reservation: %w", err)
+	}
+	if status != domain.ReservationStatusConfirmed {
+		return nil, fmt.Errorf("%w: cannot allocate a %s reservation", domain.ErrInvalidTransition, status)
+	}
+
+	lines, err := r.allocationLines(ctx, tx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockItemTypes(ctx, tx, linesItemTypes(lines)); err != nil {
+		return nil, err
+	}
+	held, substituted, err := countActiveHolds(ctx, tx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.AllocationResult{ReservationID: reservationID}
+	subs := make(map[int64][]domain.Substitution)
+	var demandIDs []int64
+	now := time.Now()
+	for _, line := range lines {
+		if _, ok := subs[line.demandID]; !ok {
+			subs[line.demandID] = nil
+			demandIDs = append(demandIDs, line.demandID)
+		}
+
+		key := [2]int64{line.demandID, line.itemTypeID}
+		missing := line.quantity - held[key]
+		if missing <= 0 {
+			held[key] -= line.quantity
+			continue
+		}
+		held[key] = 0
+
+		pin := func(candidateType int64, limit int) (int, error) {
+			assetIDs, err := pickHoldableAssets(ctx, tx, candidateType, start, end, line.transit, limit, 0)
+			if err != nil {
+				return 0, err
+			}
+			for _, assetID := range assetIDs {
+				h := &domain.AssetHold{
+					ReservationID:   reservationID,
+					DemandID:        line.demandID,
+					AssetID:         assetID,
+					ItemTypeID:      line.itemTypeID,
+					StartTime:       start,
+					EndTime:         end,
+					Status:          domain.HoldActive,
+					CreatedByUserID: userID,
+					CreatedAt:       now,
+					UpdatedAt:       now,
+				}
+				if err := insertAssetHold(ctx, tx, h); err != nil {
+					return 0, err
+				}
+			}
+			return len(assetIDs), nil
+		}
+
+		n, err := pin(line.itemTypeID, missing)
+		if err != nil {
+			return nil, err
+		}
+		missing -= n
+
+		// Substitutes that apply automatically are pinned first; the others are only
+		// proposed for whatever is still missing, for a dispatcher to accept.
+		for _, auto := range []bool{true, false} {
+			for _, opt := range line.substitutes {
+				if missing == 0 {
+					break
+				}
+				if opt.AutoApply != auto {
+					continue
+				}
+				want := substituteLimit(opt, missing, substituted[[3]int64{line.demandID, line.itemTypeID, opt.ItemTypeID}])
+				if want <= 0 {
+					continue
+				}
+
+				sub := domain.Substitution{ItemTypeID: line.itemTypeID, SubstituteItemTypeID: opt.ItemTypeID, RuleID: opt.RuleID}
+				if auto {
+					n, err := pin(opt.ItemTypeID, want)
+					if err != nil {
+						return nil, err
+					}
+					if n == 0 {
+						continue
+					}
+					missing -= n
+					sub.Quantity, sub.Applied, sub.AppliedAt = n, true, &now
+				} else {
+					// Only counted here; the assets are picked again when the proposal is accepted.
+					assetIDs, err := pickHoldableAssets(ctx, tx, opt.ItemTypeID, start, end, line.transit, want, 0)
+					if err != nil {
+						return nil, err
+					}
+					if len(assetIDs) == 0 {
+						continue
+					}
+					sub.Quantity = len(assetIDs)
+				}
+				subs[line.demandID] = append(subs[line.demandID], sub)
+				result.Substitutions = append(result.Substitutions, sub)
+			}
+		}
+
+		if missing > 0 {
+			result.Shortfalls = append(result.Shortfalls, domain.Shortfall{
+				ItemTypeID: line.itemTypeID,
+				Requested:  line.quantity,
+				Available:  line.quantity - missing,
+			})
+		}
+	}
+
+	for _, demandID := range demandIDs {
+		if err := recordSubstitutions(ctx, tx, demandID, subs[demandID], true); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Holds, err = listAssetHolds(ctx, tx, reservationID, true)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// allocationLines expands a reservation's demands into per-item-type lines, with kit
+// demands broken down into their required components.
+func (r *SqlRepository) allocationLines(ctx context.Context, q queryer, reservationID int64) ([]allocationLine, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, item_kind, item_id, requested_quantity, place_id FROM demands WHERE reservation_id = $1 ORDER BY id", reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("query demands: %w", err)
+	}
+	var demands []domain.Demand
+	for rows.Next() {
+		var d domain.Demand
+		if err := rows.Scan(&d.ID, &d.ItemKind, &d.ItemID, &d.Quantity, &d.PlaceID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan demand: %w", err)
+		}
+		demands = append(demands, d)
+	}
+	rows.Close()
+
+	return r.expandDemands(ctx, q, demands)
+}
+
+// expandDemands turns demand lines into per-item-type allocation lines, looking up
+// kit components for kit demands. Each line lists its kit substitutes first, then
+// those allowed by substitution rules in rule priority order.
+func (r *SqlRepository) expandDemands(ctx context.Context, q queryer, demands []domain.Demand) ([]allocationLine, error) {
+	var kitIDs, placeIDs []int64
+	for _, d := range demands {
+		if d.ItemKind == domain.DemandKindKitTemplate {
+			kitIDs = append(kitIDs, d.ItemID)
+		}
+		if d.PlaceID != nil {
+			placeIDs = append(placeIDs, *d.PlaceID)
+		}
+	}
+	components := map[int64][]domain.KitComponent{}
+	if len(kitIDs) > 0 {
+		var err error
+		components, err = r.listKitComponents(ctx, q, kitIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	transits := map[int64]time.Duration{}
+	if len(placeIDs) > 0 {
+		var err error
+		transits, err = placeTransits(ctx, q, placeIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var lines []allocationLine
+	for _, d := range demands {
+		var transit time.Duration
+		if d.PlaceID != nil {
+			transit = transits[*d.PlaceID]
+		}
+		switch d.ItemKind {
+		case domain.DemandKindItemType:
+			lines = append(lines, allocationLine{demandID: d.ID, itemTypeID: d.ItemID, quantity: d.Quantity, transit: transit})
+		case domain.DemandKindKitTemplate:
+			kit := domain.KitTemplate{ID: d.ItemID, Components: components[d.ItemID]}
+			for _, req := range kit.Requirements(d.Quantity, false) {
+				line := allocationLine{demandID: d.ID, itemTypeID: req.ItemTypeID, quantity: req.Quantity, transit: transit}
+				for _, sub := range req.Substitutes {
+					line.substitutes = append(line.substitutes, domain.SubstituteOption{ItemTypeID: sub, AutoApply: true})
+				}
+				lines = append(lines, line)
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return lines, nil
+	}
+
+	requested := make([]int64, 0, len(lines))
+	for _, line := range lines {
+		requested = append(requested, line.itemTypeID)
+	}
+	rules, err := activeSubstitutionRules(ctx, q, requested)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lines {
+		line := &lines[i]
+		for _, opt := range domain.SubstitutesFor(line.itemTypeID, rules) {
+			if !line.hasSubstitute(opt.ItemTypeID) {
+				line.substitutes = append(line.substitutes, opt)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// placeTransits returns the one-way transit time to each of the given places.
+func placeTransits(ctx context.Context, q queryer, placeIDs []int64) (map[int64]time.Duration, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, transit_minutes FROM places WHERE id = ANY($1)", pq.Array(placeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("query place transit: %w", err)
+	}
+	defer rows.Close()
+
+	transits := make(map[int64]time.Duration)
+	for rows.Next() {
+		var id int64
+		var minutes int
+		if err := rows.Scan(&id, &minutes); err != nil {
+			return nil, fmt.Errorf("scan place transit: %w", err)
+		}
+		transits[id] = time.Duration(minutes) * time.Minute
+	}
+	return transits, nil
+}
+
+// demandTransit returns the transit time to a demand's place, zero when it has none.
+func demandTransit(ctx context.Context, q queryer, demandID int64) (time.Duration, error) {
+	var minutes int
+	err := q.QueryRowContext(ctx, `SELECT COALESCE(p.transit_minutes, 0) FROM demands d
+		LEFT JOIN places p ON p.id = d.place_id WHERE d.id = $1`, demandID).Scan(&minutes)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get demand transit: %w", err)
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// linesTransit is the longest transit among the lines, used when their availability is
+// checked together.
+func linesTransit(lines []allocationLine) time.Duration {
+	var transit time.Duration
+	for _, line := range lines {
+		if line.transit > transit {
+			transit = line.transit
+		}
+	}
+	return transit
+}
+
+func (l allocationLine) hasSubstitute(itemTypeID int64) bool {
+	for _, opt := range l.substitutes {
+		if opt.ItemTypeID == itemTypeID {
+			return true
+		}
+	}
+	return false
+}
+
+// requirement expresses the line as a ComponentRequirement for availability checks.
+func (l allocationLine) requirement() domain.ComponentRequirement {
+	req := domain.ComponentRequirement{ItemTypeID: l.itemTypeID, Quantity: l.quantity}
+	for _, opt := range l.substitutes {
+		req.Substitutes = append(req.Substitutes, opt.ItemTypeID)
+		if opt.MaxQuantity > 0 {
+			if req.SubstituteMax == nil {
+				req.SubstituteMax = make(map[int64]int)
+			}
+			req.SubstituteMax[opt.ItemTypeID] = opt.MaxQuantity
+		}
+	}
+	return req
+}
+
+// substituteLimit is how many of missing units a substitute may still cover on a line
+// that already has used substitute units pinned.
+func substituteLimit(opt domain.SubstituteOption, missing, used int) int {
+	if opt.MaxQuantity == 0 {
+		return missing
+	}
+	if left := opt.MaxQuantity - used; left < missing {
+		return left
+	}
+	return missing
+}
+
+// countActiveHolds counts a reservation's active holds per (demand, line item type), and
+// separately those filled by an asset of another item type, keyed (demand, line item type,
+// asset item type).
+func countActiveHolds(ctx context.Context, tx *sql.Tx, reservationID int64) (map[[2]int64]int, map[[3]int64]int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT h.demand_id, h.item_type_id, a.item_type_id, COUNT(*)
+	                                  FROM asset_holds h JOIN assets a ON a.id = h.asset_id
+	                                  WHERE h.reservation_id = $1 AND h.status = 'active'
+	                                  GROUP BY h.demand_id, h.item_type_id, a.item_type_id`, reservationID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("count holds: %w", err)
+	}
+	defer rows.Close()
+
+	held := make(map[[2]int64]int)
+	substituted := make(map[[3]int64]int)
+	for rows.Next() {
+		var demandID, itemTypeID, assetItemTypeID int64
+		var n int
+		if err := rows.Scan(&demandID, &itemTypeID, &assetItemTypeID, &n); err != nil {
+			return nil, nil, fmt.Errorf("scan hold count: %w", err)
+		}
+		held[[2]int64{demandID, itemTypeID}] += n
+		if assetItemTypeID != itemTypeID {
+			substituted[[3]int64{demandID, itemTypeID, assetItemTypeID}] += n
+		}
+	}
+	return held, substituted, nil
+}
+
+// heldSubstitutions returns the substitutions applied to a reservation's demand lines,
+// per demand: the holds, active or checked out, whose asset is of another item type
+// than the line asked for.
+func heldSubstitutions(ctx context.Context, q queryer, reservationID int64) (map[int64][]domain.Substitution, error) {
+	rows, err := q.QueryContext(ctx, `SELECT h.demand_id, h.item_type_id, a.item_type_id, COUNT(*), MAX(h.created_at)
+	                                  FROM asset_holds h JOIN assets a ON a.id = h.asset_id
+	                                  WHERE h.reservation_id = $1 AND h.status IN ('active', 'consumed') AND a.item_type_id <> h.item_type_id
+	                                  GROUP BY h.demand_id, h.item_type_id, a.item_type_id
+	                                  ORDER BY h.demand_id, h.item_type_id, a.item_type_id`, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("query held substitutions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := make(map[int64][]domain.Substitution)
+	for rows.Next() {
+		var demandID int64
+		var appliedAt time.Time
+		s := domain.Substitution{Applied: true}
+		if err := rows.Scan(&demandID, &s.ItemTypeID, &s.SubstituteItemTypeID, &s.Quantity, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan held substitution: %w", err)
+		}
+		s.AppliedAt = &appliedAt
+		subs[demandID] = append(subs[demandID], s)
+	}
+	return subs, rows.Err()
+}
+
+// recordSubstitutions merges substitutions into the proposals on a demand's metadata.
+// Nothing is written when there is nothing to add and no stale proposal to clear.
+func recordSubstitutions(ctx context.Context, tx *sql.Tx, demandID int64, subs []domain.Substitution, clearProposals bool) error {
+	var metadata []byte
+	if err := tx.QueryRowContext(ctx, "SELECT metadata FROM demands WHERE id = $1 FOR UPDATE", demandID).Scan(&metadata); err != nil {
+		return fmt.Errorf("get demand metadata: %w", err)
+	}
+
+	if len(subs) == 0 && (!clearProposals || len(domain.DemandSubstitutions(metadata)) == 0) {
+		return nil
+	}
+
+	merged, err := domain.MergeSubstitutions(metadata, subs, clearProposals)
+	if err != nil {
+		return fmt.Errorf("merge substitutions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE demands SET metadata = $1, updated_at = $2 WHERE id = $3", merged, time.Now(), demandID); err != nil {
+		return fmt.Errorf("record substitutions: %w", err)
+	}
+	return nil
+}
+
+// pickHoldableAssets locks and returns up to limit assets of an item type that have no
+// active hold, with its turnaround, overlapping [start, end) widened by transit on both
+// sides. excludeHoldID ignores one hold (used when moving it).
+func pickHoldableAssets(ctx context.Context, tx *sql.Tx, itemTypeID int64, start, end time.Time, transit time.Duration, limit int, excludeHoldID int64) ([]int64, error) {
+	query := `SELECT a.id FROM assets a
+	          WHERE a.item_type_id = $1 AND ` + holdableAssetCondition + `
+	            AND NOT EXISTS (
+	                SELECT 1 FROM asset_holds h
+	                CROSS JOIN LATERAL (` + holdTurnaround + `) t
+	                WHERE h.asset_id = a.id AND h.status = 'active' AND h.id != $5
+	                  AND h.start_time - t.gap < $3 AND h.end_time + t.gap > $2
+	            )
+	          ORDER BY (a.status = 'available') DESC, a.id
+	          LIMIT $4
+	          FOR UPDATE OF a SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, itemTypeID, start.Add(-transit), end.Add(transit), limit, excludeHoldID)
+	if err != nil {
+		return nil, fmt.Errorf("pick holdable assets: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan holdable asset: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// assetHoldable reports whether an asset meets holdableAssetCondition for a window
+// starting at start, less transit.
+func assetHoldable(ctx context.Context, tx *sql.Tx, assetID int64, start time.Time, transit time.Duration) (bool, error) {
+	var holdable bool
+	err := tx.QueryRowContext(ctx, `SELECT `+holdableAssetCondition+` FROM assets a WHERE a.id = $1`, assetID, start.Add(-transit)).Scan(&holdable)
+	if err != nil {
+		return false, fmt.Errorf("get asset: %w", err)
+	}
+	return holdable, nil
+}
+
+// assetHeldElsewhere reports whether an asset has an active hold other than the holds
+// listed in ignore that, with its turnaround, overlaps [start, end) widened by transit.
+func assetHeldElsewhere(ctx context.Context, tx *sql.Tx, assetID int64, start, end time.Time, transit time.Duration, ignore ...int64) (bool, error) {
+	if ignore == nil {
+		ignore = []int64{}
+	}
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (
+		SELECT 1 FROM asset_holds h
+		CROSS JOIN LATERAL (`+holdTurnaround+`) t
+		WHERE h.asset_id = $1 AND h.status = 'active' AND NOT (h.id = ANY($4))
+		  AND h.start_time - t.gap < $3 AND h.end_time + t.gap > $2)`, assetID, start.Add(-transit), end.Add(transit), pq.Array(ignore)).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check asset holds: %w", err)
+	}
+	return exists, nil
+}
+
+func insertAssetHold(ctx context.Context, tx *sql.Tx, h *domain.AssetHold) error {
+	query := `INSERT INTO asset_holds (reservation_id, demand_id, asset_id, item_type_id, start_time, end_time, status, created_by_user_id, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	err := tx.QueryRowContext(ctx, query, h.ReservationID, h.DemandID, h.AssetID, h.ItemTypeID, h.StartTime, h.EndTime, h.Status, h.CreatedByUserID, h.CreatedAt, h.UpdatedAt).Scan(&h.ID)
+	if err != nil {
+		return fmt.Errorf("insert asset_hold: %w", err)
+	}
+	return nil
+}
+
+func listAssetHolds(ctx context.Context, q queryer, reservationID int64, activeOnly bool) ([]domain.AssetHold, error) {
+	query := `SELECT ` + holdColumns + ` FROM asset_holds WHERE reservation_id = $1`
+	if activeOnly {
+		query += ` AND status = 'active'`
+	}
+	query += ` ORDER BY demand_id, id`
+	rows, err := q.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("list asset_holds: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.AssetHold{}
+	for rows.Next() {
+		h, err := scanAssetHold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan asset_hold: %w", err)
+		}
+		results = append(results, *h)
+	}
+	return results, nil
+}
+
+// ListAssetHolds returns every hold (active or not) recorded for a reservation.
+func (r *SqlRepository) ListAssetHolds(ctx context.Context, reservationID int64) ([]domain.AssetHold, error) {
+	return listAssetHolds(ctx, r.db, reservationID, false)
+}
+
+// ReallocateHold moves an active hold of a reservation to a different asset. When
+// assetID is nil the first free asset of the hold's item type is picked. A hold of
+// another reservation is treated as missing.
+func (r *SqlRepository) ReallocateHold(ctx context.Context, reservationID, holdID int64, assetID *int64, userID *int64) (*domain.AssetHold, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	old, err := scanAssetHold(tx.QueryRowContext(ctx, `SELECT `+holdColumns+` FROM asset_holds WHERE id = $1 FOR UPDATE`, holdID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get asset_hold: %w", err)
+	}
+	if old.ReservationID != reservationID {
+		return nil, nil
+	}
+	if old.Status != domain.HoldActive {
+		return nil, fmt.Errorf("%w: hold %d is %s", domain.ErrHoldNotActive, holdID, old.Status)
+	}
+
+	var currentType int64
+	if err := tx.QueryRowContext(ctx, "SELECT item_type_id FROM assets WHERE id = $1", old.AssetID).Scan(&currentType); err != nil {
+		return nil, fmt.Errorf("get held asset: %w", err)
+	}
+	transit, err := demandTransit(ctx, tx, old.DemandID)
+	if err != nil {
+		return nil, err
+	}
+
+	var newAssetID int64
+	if assetID != nil {
+		var itemTypeID int64
+		var holdable bool
+		err := tx.QueryRowContext(ctx, `SELECT a.item_type_id, `+holdableAssetCondition+` FROM assets a WHERE a.id = $1 FOR UPDATE`,
+			*assetID, old.StartTime.Add(-transit)).Scan(&itemTypeID, &holdable)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: asset %d not found", domain.ErrHoldItemType, *assetID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get asset: %w", err)
+		}
+		if itemTypeID != currentType && itemTypeID != old.ItemTypeID {
+			return nil, fmt.Errorf("%w: asset %d is item_type %d, hold requires item_type %d", domain.ErrHoldItemType, *assetID, itemTypeID, old.ItemTypeID)
+		}
+		if !holdable {
+			return nil, fmt.Errorf("%w: asset %d is not available for the hold window", domain.ErrHoldConflict, *assetID)
+		}
+		busy, err := assetHeldElsewhere(ctx, tx, *assetID, old.StartTime, old.EndTime, transit, old.ID)
+		if err != nil {
+			return nil, err
+		}
+		if busy {
+			return nil, fmt.Errorf("%w: asset %d", domain.ErrHoldConflict, *assetID)
+		}
+		newAssetID = *assetID
+	} else {
+		ids, err := pickHoldableAssets(ctx, tx, old.ItemTypeID, old.StartTime, old.EndTime, transit, 2, old.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if id != old.AssetID {
+				newAssetID = id
+				break
+			}
+		}
+		if newAssetID == 0 {
+			return nil, fmt.Errorf("%w: no other asset of item_type %d is free for the hold window", domain.ErrHoldConflict, old.ItemTypeID)
+		}
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, "UPDATE asset_holds SET status = $1, updated_at = $2 WHERE id = $3", domain.HoldReleased, now, old.ID); err != nil {
+		return nil, fmt.Errorf("release asset_hold: %w", err)
+	}
+	h := &domain.AssetHold{
+		ReservationID:   old.ReservationID,
+		DemandID:        old.DemandID,
+		AssetID:         newAssetID,
+		ItemTypeID:      old.ItemTypeID,
+		StartTime:       old.StartTime,
+		EndTime:         old.EndTime,
+		Status:          domain.HoldActive,
+		CreatedByUserID: userID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := insertAssetHold(ctx, tx, h); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SwapHolds exchanges the asset of an active hold of a reservation with that of
+// another active hold, which may belong to a different reservation. Both assets must
+// be of the same item type.
+func (r *SqlRepository) SwapHolds(ctx context.Context, reservationID, holdID, otherHoldID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock in id order so concurrent swaps cannot deadlock
+	rows, err := tx.QueryContext(ctx, `SELECT `+holdColumns+` FROM asset_holds WHERE id = ANY($1) ORDER BY id FOR UPDATE`, pq.Array([]int64{holdID, otherHoldID}))
+	if err != nil {
+		return fmt.Errorf("lock asset_holds: %w", err)
+	}
+	var holds []*domain.AssetHold
+	for rows.Next() {
+		h, err := scanAssetHold(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("scan asset_hold: %w", err)
+		}
+		holds = append(holds, h)
+	}
+	rows.Close()
+	if len(holds) != 2 {
+		return fmt.Errorf("%w: both holds must exist", domain.ErrHoldNotFound)
+	}
+	a, b := holds[0], holds[1]
+	own := a
+	if b.ID == holdID {
+		own = b
+	}
+	if own.ReservationID != reservationID {
+		return fmt.Errorf("%w: hold %d", domain.ErrHoldNotFound, holdID)
+	}
+	if a.Status != domain.HoldActive || b.Status != domain.HoldActive {
+		return fmt.Errorf("%w: only active holds can be swapped", domain.ErrHoldNotActive)
+	}
+
+	var sameType bool
+	err = tx.QueryRowContext(ctx, `SELECT (SELECT item_type_id FROM assets WHERE id = $1) = (SELECT item_type_id FROM assets WHERE id = $2)`, a.AssetID, b.AssetID).Scan(&sameType)
+	if err != nil {
+		return fmt.Errorf("compare assets: %w", err)
+	}
+	if !sameType {
+		return fmt.Errorf("%w: held assets %d and %d are not the same item type", domain.ErrHoldItemType, a.AssetID, b.AssetID)
+	}
+
+	transitA, err := demandTransit(ctx, tx, a.DemandID)
+	if err != nil {
+		return err
+	}
+	transitB, err := demandTransit(ctx, tx, b.DemandID)
+	if err != nil {
+		return err
+	}
+
+	// Each asset must be holdable for the other hold's window, and free of holds other
+	// than the two being swapped
+	if ok, err := assetHoldable(ctx, tx, a.AssetID, b.StartTime, transitB); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("%w: asset %d is not available for the hold window", domain.ErrHoldConflict, a.AssetID)
+	}
+	if ok, err := assetHoldable(ctx, tx, b.AssetID, a.StartTime, transitA); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("%w: asset %d is not available for the hold window", domain.ErrHoldConflict, b.AssetID)
+	}
+	if busy, err := assetHeldElsewhere(ctx, tx, a.AssetID, b.StartTime, b.EndTime, transitB, a.ID, b.ID); err != nil {
+		return err
+	} else if busy {
+		return fmt.Errorf("%w: asset %d", domain.ErrHoldConflict, a.AssetID)
+	}
+	if busy, err := assetHeldElsewhere(ctx, tx, b.AssetID, a.StartTime, a.EndTime, transitA, a.ID, b.ID); err != nil {
+		return err
+	} else if busy {
+		return fmt.Errorf("%w: asset %d", domain.ErrHoldConflict, b.AssetID)
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, "UPDATE asset_holds SET asset_id = $1, updated_at = $2 WHERE id = $3", b.AssetID, now, a.ID); err != nil {
+		return fmt.Errorf("swap asset_hold %d: %w", a.ID, err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE asset_holds SET asset_id = $1, updated_at = $2 WHERE id = $3", a.AssetID, now, b.ID); err != nil {
+		return fmt.Errorf("swap asset_hold %d: %w", b.ID, err)
+	}
+	return tx.Commit()
+}
+
+// ReleaseReservationHolds frees every active hold of a reservation.
+func (r *SqlRepository) ReleaseReservationHolds(ctx context.Context, reservationID int64) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE asset_holds SET status = $1, updated_at = $2 WHERE reservation_id = $3 AND status = 'active'",
+		domain.HoldReleased, time.Now(), reservationID)
+	if err != nil {
+		return fmt.Errorf("release asset_holds: %w", err)
+	}
+	return nil
+}
+
+// consumeHold checks an asset being checked out against the holds of other reservations
+// (including their turnaround) and marks the reservation's own hold on it as consumed.
+func consumeHold(ctx context.Context, tx *sql.Tx, reservationID, assetID int64, now time.Time) error {
+	var other sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT h.reservation_id FROM asset_holds h
+		JOIN rental_reservations rr ON rr.id = $2
+		CROSS JOIN LATERAL (`+holdTurnaround+`) t
+		WHERE h.asset_id = $1 AND h.status = 'active' AND h.reservation_id != $2
+		  AND h.start_time - t.gap < rr.end_time AND h.end_time + t.gap > $3
+		LIMIT 1`, assetID, reservationID, now).Scan(&other)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("check asset holds: %w", err)
+	}
+	if other.Valid {
+		return fmt.Errorf("%w: asset %d is held for reservation %d", domain.ErrHoldConflict, assetID, other.Int64)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE asset_holds SET status = $1, updated_at = $2 WHERE reservation_id = $3 AND asset_id = $4 AND status = 'active'",
+		domain.HoldConsumed, now, reservationID, assetID)
+	if err != nil {
+		return fmt.Errorf("consume asset_hold: %w", err)
+	}
+	return nil
+}
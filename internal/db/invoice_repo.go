@@ -0,0 +1,373 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const invoiceColumns = `id, COALESCE(number, ''), reservation_id, company_id, COALESCE(currency, ''), period_start, period_end, is_final, total, created_by_user_id, created_at`
+
+const invoiceLineColumns = `id, invoice_id, kind, description, asset_id, item_type_id, rate_card_id, check_out_id, return_id, inspection_submission_id,
+	start_time, end_time, days, COALESCE(breakdown, ''), amount, COALESCE(note, '')`
+
+// CreateInvoice issues a reservation's next invoice. Its period starts where the
+// previous invoice ended, or at the first check-out, and runs to req.Through (default
+// now); a final invoice runs to the last return. It returns nil, nil when the
+// reservation does not exist.
+//
+// Only serialized assets are billed. Quantities checked out or consumed through the
+// stock ledger are not invoiced yet: invoice lines name an asset and a check_out_actions
+// row, and rate cards have no price for stock that is used up.
+func (r *SqlRepository) CreateInvoice(ctx context.Context, reservationID int64, req *domain.InvoiceRequest, userID *int64) (*domain.Invoice, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	in := domain.InvoiceInput{ReservationID: reservationID, Final: req.Final}
+	var underNameID *int64
+	err = tx.QueryRowContext(ctx, "SELECT end_time, under_name_id FROM rental_reservations WHERE id = $1 FOR UPDATE", reservationID).
+		Scan(&in.DueBack, &underNameID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock reservation: %w", err)
+	}
+
+	var lastEnd time.Time
+	var lastFinal bool
+	err = tx.QueryRowContext(ctx, "SELECT period_end, is_final FROM invoices WHERE reservation_id = $1 ORDER BY period_end DESC, id DESC LIMIT 1",
+		reservationID).Scan(&lastEnd, &lastFinal)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get last invoice: %w", err)
+	}
+	if lastFinal {
+		return nil, fmt.Errorf("%w: reservation %d already has a final invoice", domain.ErrInvalidInvoice, reservationID)
+	}
+
+	if in.Usage, err = reservationAssetUsage(ctx, tx, reservationID); err != nil {
+		return nil, err
+	}
+	if len(in.Usage) == 0 {
+		return nil, fmt.Errorf("%w: no assets have been checked out on reservation %d", domain.ErrInvalidInvoice, reservationID)
+	}
+
+	in.PeriodStart = lastEnd
+	if lastEnd.IsZero() {
+		in.PeriodStart = in.Usage[0].Out
+	}
+	in.PeriodEnd = now
+	if req.Through != nil {
+		if req.Through.After(now) {
+			return nil, fmt.Errorf("%w: cannot invoice beyond the current time", domain.ErrInvalidInvoice)
+		}
+		in.PeriodEnd = *req.Through
+	} else if req.Final {
+		// Close at the last return; BuildInvoice refuses if something is still out
+		in.PeriodEnd = in.PeriodStart
+		for _, u := range in.Usage {
+			if u.In == nil {
+				in.PeriodEnd = now
+				break
+			}
+			if u.In.After(in.PeriodEnd) {
+				in.PeriodEnd = *u.In
+			}
+		}
+	}
+
+	if in.CompanyID, err = customerCompany(ctx, tx, underNameID, now); err != nil {
+		return nil, err
+	}
+	if in.Quote, err = storedQuote(ctx, tx, reservationID); err != nil {
+		return nil, err
+	}
+	itemTypeIDs := make([]int64, 0, len(in.Usage))
+	for _, u := range in.Usage {
+		itemTypeIDs = append(itemTypeIDs, u.ItemTypeID)
+	}
+	// The cards the quote was priced on still apply once deactivated
+	quotedCardIDs := []int64{}
+	if in.Quote != nil {
+		for _, l := range in.Quote.Lines {
+			quotedCardIDs = append(quotedCardIDs, l.RateCardID)
+		}
+	}
+	in.RateCards, err = queryRateCards(ctx, tx, `SELECT `+rateCardColumns+` FROM rate_cards
+	                                             WHERE (is_active AND item_type_id = ANY($1) AND (company_id IS NULL OR company_id = $2))
+	                                                OR id = ANY($3)`,
+		pq.Array(itemTypeIDs), in.CompanyID, pq.Array(quotedCardIDs))
+	if err != nil {
+		return nil, err
+	}
+	if in.Damages, err = unbilledDamage(ctx, tx, reservationID, now); err != nil {
+		return nil, err
+	}
+
+	inv, err := domain.BuildInvoice(in)
+	if err != nil {
+		return nil, err
+	}
+	inv.CreatedByUserID = userID
+	inv.CreatedAt = now
+	if err := insertInvoice(ctx, tx, inv); err != nil {
+		return nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"invoice_id":     inv.ID,
+		"number":         inv.Number,
+		"reservation_id": inv.ReservationID,
+		"currency":       inv.Currency,
+		"total":          inv.Total,
+		"is_final":       inv.IsFinal,
+	})
+	if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: domain.EventInvoiceCreated, Payload: payload}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// storedQuote returns the quote stored when a reservation was approved, or nil if it
+// has none.
+func storedQuote(ctx context.Context, q queryer, reservationID int64) (*domain.Quote, error) {
+	var id int64
+	var quoteJSON []byte
+	err := q.QueryRowContext(ctx, `SELECT id, quote FROM reservation_quotes WHERE reservation_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`,
+		reservationID).Scan(&id, &quoteJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reservation_quote: %w", err)
+	}
+	var quote domain.Quote
+	if err := json.Unmarshal(quoteJSON, &quote); err != nil {
+		return nil, fmt.Errorf("decode reservation_quote %d: %w", id, err)
+	}
+	quote.ID = id
+	return &quote, nil
+}
+
+// reservationAssetUsage pairs each completed check-out on a reservation with the first
+// return of that asset after it and what earlier invoices billed for its usage, in
+// check-out order. Ledger movements of fungible stock are not included. The demand
+// line comes from the kit instance the asset went out in,
+// or else from the hold the check-out consumed.
+func reservationAssetUsage(ctx context.Context, q queryer, reservationID int64) ([]domain.AssetUsage, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT co.id, co.asset_id, COALESCE(a.asset_tag, ''), a.item_type_id, COALESCE(ki.demand_id, hold.demand_id), co.kit_instance_id,
+		       co.start_time, ret.id, ret.start_time, billed.days, billed.amount
+		FROM check_out_actions co
+		JOIN assets a ON a.id = co.asset_id
+		LEFT JOIN kit_instances ki ON ki.id = co.kit_instance_id
+		LEFT JOIN LATERAL (
+		    SELECT h.demand_id FROM asset_holds h
+		    WHERE h.reservation_id = co.reservation_id AND h.asset_id = co.asset_id AND h.status = 'consumed'
+		    ORDER BY h.updated_at DESC, h.id DESC LIMIT 1
+		) hold ON true
+		LEFT JOIN LATERAL (
+		    SELECT r.id, r.start_time FROM return_actions r
+		    WHERE r.reservation_id = co.reservation_id AND r.asset_id = co.asset_id AND r.start_time >= co.start_time
+		    ORDER BY r.start_time LIMIT 1
+		) ret ON true
+		CROSS JOIN LATERAL (
+		    SELECT COALESCE(SUM(l.days), 0) AS days, COALESCE(SUM(l.amount), 0) AS amount
+		    FROM invoice_lines l WHERE l.check_out_id = co.id AND l.kind = 'usage'
+		) billed
+		WHERE co.reservation_id = $1 AND co.action_status = 'Completed'
+		ORDER BY co.start_time, co.id`, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("query asset usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []domain.AssetUsage{}
+	for rows.Next() {
+		var u domain.AssetUsage
+		if err := rows.Scan(&u.CheckOutID, &u.AssetID, &u.AssetTag, &u.ItemTypeID, &u.DemandID, &u.KitInstanceID,
+			&u.Out, &u.ReturnID, &u.In, &u.BilledDays, &u.Billed); err != nil {
+			return nil, fmt.Errorf("scan asset usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// unbilledDamage finds inspection responses matching their field's damage value that
+// were given after an asset came back from the reservation, before it went out again,
+// and that no invoice has billed yet. Inspections under a live claim are settled
+// through the claim instead.
+func unbilledDamage(ctx context.Context, q queryer, reservationID int64, now time.Time) ([]domain.DamageFinding, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT DISTINCT s.id, s.asset_id, a.item_type_id, f.label, f.damage_charge, s.created_at, f.display_order
+		FROM return_actions ret
+		JOIN inspection_submissions s ON s.asset_id = ret.asset_id AND s.created_at >= ret.start_time AND s.created_at <= $2
+		JOIN inspection_responses ir ON ir.submission_id = s.id
+		JOIN inspection_fields f ON f.id = ir.field_id
+		JOIN assets a ON a.id = s.asset_id
+		WHERE ret.reservation_id = $1
+		  AND f.damage_value IS NOT NULL AND f.damage_charge > 0 AND ir.response_value = f.damage_value
+		  AND NOT EXISTS (
+		      SELECT 1 FROM check_out_actions co
+		      WHERE co.asset_id = ret.asset_id AND co.start_time > ret.start_time AND co.start_time <= s.created_at
+		  )
+		  AND NOT EXISTS (SELECT 1 FROM invoice_lines il WHERE il.inspection_submission_id = s.id)
+		  AND NOT EXISTS (SELECT 1 FROM claims c WHERE c.inspection_submission_id = s.id AND c.status <> 'denied')
+		ORDER BY s.created_at, s.id, f.display_order`, reservationID, now)
+	if err != nil {
+		return nil, fmt.Errorf("query damage findings: %w", err)
+	}
+	defer rows.Close()
+
+	var findings []domain.DamageFinding
+	for rows.Next() {
+		var d domain.DamageFinding
+		var displayOrder int
+		if err := rows.Scan(&d.SubmissionID, &d.AssetID, &d.ItemTypeID, &d.Field, &d.Charge, &d.InspectedAt, &displayOrder); err != nil {
+			return nil, fmt.Errorf("scan damage finding: %w", err)
+		}
+		findings = append(findings, d)
+	}
+	return findings, rows.Err()
+}
+
+func insertInvoice(ctx context.Context, tx *sql.Tx, inv *domain.Invoice) error {
+	var currency interface{}
+	if inv.Currency != "" {
+		currency = inv.Currency
+	}
+	err := tx.QueryRowContext(ctx, `INSERT INTO invoices (reservation_id, company_id, currency, period_start, period_end, is_final, total, created_by_user_id, created_at)
+	                                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		inv.ReservationID, inv.CompanyID, currency, inv.PeriodStart, inv.PeriodEnd, inv.IsFinal, inv.Total, inv.CreatedByUserID, inv.CreatedAt,
+	).Scan(&inv.ID)
+	if err != nil {
+		return fmt.Errorf("insert invoice: %w", err)
+	}
+	inv.Number = fmt.Sprintf("INV-%06d", inv.ID)
+	if _, err := tx.ExecContext(ctx, "UPDATE invoices SET number = $1 WHERE id = $2", inv.Number, inv.ID); err != nil {
+		return fmt.Errorf("number invoice: %w", err)
+	}
+
+	for i := range inv.Lines {
+		l := &inv.Lines[i]
+		err := tx.QueryRowContext(ctx, `INSERT INTO invoice_lines (invoice_id, kind, description, asset_id, item_type_id, rate_card_id, check_out_id,
+		                                    return_id, inspection_submission_id, start_time, end_time, days, breakdown, amount, note)
+		                                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, NULLIF($15, '')) RETURNING id`,
+			inv.ID, l.Kind, l.Description, l.AssetID, l.ItemTypeID, l.RateCardID, l.CheckOutID,
+			l.ReturnID, l.InspectionSubmissionID, l.Start, l.End, l.Days, l.Breakdown, l.Amount, l.Note,
+		).Scan(&l.ID)
+		if err != nil {
+			return fmt.Errorf("insert invoice_line: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetInvoice returns an invoice with its lines, or nil if it does not exist.
+func (r *SqlRepository) GetInvoice(ctx context.Context, id int64) (*domain.Invoice, error) {
+	invoices, err := r.queryInvoices(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE id = $1`, id)
+	if err != nil || len(invoices) == 0 {
+		return nil, err
+	}
+	return &invoices[0], nil
+}
+
+// ListInvoices returns a reservation's invoices with their lines, oldest first.
+func (r *SqlRepository) ListInvoices(ctx context.Context, reservationID int64) ([]domain.Invoice, error) {
+	return r.queryInvoices(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE reservation_id = $1 ORDER BY period_start, id`, reservationID)
+}
+
+// ListReservationsDueForInvoice returns the reservations with assets still checked out
+// whose last invoice, or first check-out if they have none, ended by before.
+func (r *SqlRepository) ListReservationsDueForInvoice(ctx context.Context, before time.Time) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rr.id FROM rental_reservations rr
+		WHERE EXISTS (
+		        SELECT 1 FROM check_out_actions co
+		        WHERE co.reservation_id = rr.id AND co.action_status = 'Completed'
+		          AND NOT EXISTS (
+		              SELECT 1 FROM return_actions ret
+		              WHERE ret.reservation_id = co.reservation_id AND ret.asset_id = co.asset_id AND ret.start_time >= co.start_time
+		          )
+		      )
+		  AND COALESCE(
+		        (SELECT MAX(i.period_end) FROM invoices i WHERE i.reservation_id = rr.id),
+		        (SELECT MIN(co.start_time) FROM check_out_actions co WHERE co.reservation_id = rr.id AND co.action_status = 'Completed')
+		      ) <= $1
+		ORDER BY rr.id`, before)
+	if err != nil {
+		return nil, fmt.Errorf("query reservations due for invoice: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *SqlRepository) queryInvoices(ctx context.Context, query string, args ...any) ([]domain.Invoice, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []domain.Invoice{}
+	var ids []int64
+	for rows.Next() {
+		var inv domain.Invoice
+		if err := rows.Scan(&inv.ID, &inv.Number, &inv.ReservationID, &inv.CompanyID, &inv.Currency, &inv.PeriodStart, &inv.PeriodEnd,
+			&inv.IsFinal, &inv.Total, &inv.CreatedByUserID, &inv.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan invoice: %w", err)
+		}
+		inv.Lines = []domain.InvoiceLine{}
+		invoices = append(invoices, inv)
+		ids = append(ids, inv.ID)
+	}
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return invoices, err
+	}
+
+	lineRows, err := r.db.QueryContext(ctx, `SELECT `+invoiceLineColumns+` FROM invoice_lines WHERE invoice_id = ANY($1) ORDER BY invoice_id, id`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("list invoice_lines: %w", err)
+	}
+	defer lineRows.Close()
+
+	byID := make(map[int64]*domain.Invoice, len(invoices))
+	for i := range invoices {
+		byID[invoices[i].ID] = &invoices[i]
+	}
+	for lineRows.Next() {
+		var l domain.InvoiceLine
+		var invoiceID int64
+		if err := lineRows.Scan(&l.ID, &invoiceID, &l.Kind, &l.Description, &l.AssetID, &l.ItemTypeID, &l.RateCardID, &l.CheckOutID, &l.ReturnID,
+			&l.InspectionSubmissionID, &l.Start, &l.End, &l.Days, &l.Breakdown, &l.Amount, &l.Note); err != nil {
+			return nil, fmt.Errorf("scan invoice_line: %w", err)
+		}
+		if inv := byID[invoiceID]; inv != nil {
+			inv.Lines = append(inv.Lines, l)
+		}
+	}
+	return invoices, lineRows.Err()
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_CreateInvoice_FinalClosesAtLastReturn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	out := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+	firstBack, lastBack := out.Add(24*time.Hour), out.Add(48*time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT end_time, under_name_id FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"end_time", "under_name_id"}).AddRow(lastBack, nil))
+	mock.ExpectQuery("SELECT period_end, is_final FROM invoices WHERE reservation_id = \\$1").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"period_end", "is_final"}))
+	mock.ExpectQuery("SELECT co.id, co.asset_id, (.+) FROM check_out_actions co").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "asset_id", "asset_tag", "item_type_id", "demand_id", "kit_instance_id", "start_time", "ret_id", "ret_start_time", "billed_days", "billed"}).
+			AddRow(100, 1, "CAM-1", 7, nil, nil, out, 200, firstBack, 0, 0).
+			AddRow(101, 2, "CAM-2", 7, nil, nil, out, 201, lastBack, 0, 0))
+	// Never approved, so there is no quote to price from
+	mock.ExpectQuery("SELECT id, quote FROM reservation_quotes WHERE reservation_id = \\$1").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "quote"}))
+	mock.ExpectQuery("SELECT (.+) FROM rate_cards WHERE \\(is_active AND item_type_id = ANY\\(\\$1\\)").
+		WithArgs("{7,7}", nil, "{}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// A scratch found when CAM-1 came back, not yet billed or claimed
+	mock.ExpectQuery("SELECT DISTINCT s.id, s.asset_id, (.+) FROM return_actions ret").
+		WithArgs(3, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "asset_id", "item_type_id", "label", "damage_charge", "created_at", "display_order"}).
+			AddRow(30, 1, 7, "Scratched lens", 2500, firstBack.Add(time.Hour), 1))
+	mock.ExpectQuery("INSERT INTO invoices").
+		WithArgs(3, nil, nil, out, lastBack, true, 2500, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec("UPDATE invoices SET number = \\$1 WHERE id = \\$2").
+		WithArgs("INV-000012", 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for id := 1; id <= 3; id++ {
+		mock.ExpectQuery("INSERT INTO invoice_lines").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	}
+	mock.ExpectQuery("INSERT INTO outbox_events").
+		WithArgs(domain.EventInvoiceCreated, sqlmock.AnyArg(), domain.OutboxPending, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	inv, err := repo.CreateInvoice(ctx, 3, &domain.InvoiceRequest{Final: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000012", inv.Number)
+	assert.True(t, inv.PeriodEnd.Equal(lastBack))
+	require.Len(t, inv.Lines, 3)
+	assert.Equal(t, "no rate card for item_type 7", inv.Lines[0].Note)
+	assert.Equal(t, domain.InvoiceLineDamage, inv.Lines[2].Kind)
+	assert.Equal(t, int64(2500), inv.Total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_CreateInvoice_AfterFinal(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT end_time, under_name_id FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"end_time", "under_name_id"}).AddRow(time.Now(), nil))
+	mock.ExpectQuery("SELECT period_end, is_final FROM invoices WHERE reservation_id = \\$1").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"period_end", "is_final"}).AddRow(time.Now(), true))
+	mock.ExpectRollback()
+
+	inv, err := repo.CreateInvoice(ctx, 3, &domain.InvoiceRequest{}, nil)
+	assert.Nil(t, inv)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInvoice))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,325 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// CreateKitTemplate creates a kit template together with its components.
+func (r *SqlRepository) CreateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	kt.CreatedAt = now
+	kt.UpdatedAt = now
+	query := `INSERT INTO kit_templates (item_type_id, code, name, description, is_active, metadata, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	err = tx.QueryRowContext(ctx, query, kt.ItemTypeID, kt.Code, kt.Name, kt.Description, kt.IsActive, kt.Metadata, kt.CreatedAt, kt.UpdatedAt).Scan(&kt.ID)
+	if err != nil {
+		return fmt.Errorf("insert kit_template: %w", err)
+	}
+
+	if err := insertKitComponents(ctx, tx, kt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertKitComponents(ctx context.Context, tx *sql.Tx, kt *domain.KitTemplate) error {
+	for i := range kt.Components {
+		c := &kt.Components[i]
+		c.KitTemplateID = kt.ID
+		if c.SubstituteItemTypeIDs == nil {
+			c.SubstituteItemTypeIDs = []int64{}
+		}
+		query := `INSERT INTO kit_template_components (kit_template_id, item_type_id, quantity, is_optional, substitute_item_type_ids)
+		          VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		err := tx.QueryRowContext(ctx, query, c.KitTemplateID, c.ItemTypeID, c.Quantity, c.IsOptional, pq.Array(c.SubstituteItemTypeIDs)).Scan(&c.ID)
+		if err != nil {
+			return fmt.Errorf("insert kit component: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetKitTemplate retrieves a kit template and its components.
+func (r *SqlRepository) GetKitTemplate(ctx context.Context, id int64) (*domain.KitTemplate, error) {
+	query := `SELECT id, item_type_id, code, name, COALESCE(description, ''), is_active, metadata, created_at, updated_at
+	          FROM kit_templates WHERE id = $1`
+	var kt domain.KitTemplate
+	var metaJSON []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&kt.ID, &kt.ItemTypeID, &kt.Code, &kt.Name, &kt.Description, &kt.IsActive, &metaJSON, &kt.CreatedAt, &kt.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get kit_template: %w", err)
+	}
+	kt.Metadata = json.RawMessage(metaJSON)
+
+	components, err := r.listKitComponents(ctx, r.db, []int64{kt.ID})
+	if err != nil {
+		return nil, err
+	}
+	kt.Components = components[kt.ID]
+	return &kt, nil
+}
+
+// ListKitTemplates returns all kit templates with their components.
+func (r *SqlRepository) ListKitTemplates(ctx context.Context) ([]domain.KitTemplate, error) {
+	query := `SELECT id, item_type_id, code, name, COALESCE(description, ''), is_active, metadata, created_at, updated_at
+	          FROM kit_templates ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list kit_templates: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.KitTemplate{}
+	var ids []int64
+	for rows.Next() {
+		var kt domain.KitTemplate
+		var metaJSON []byte
+		if err := rows.Scan(&kt.ID, &kt.ItemTypeID, &kt.Code, &kt.Name, &kt.Description, &kt.IsActive, &metaJSON, &kt.CreatedAt, &kt.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan kit_template: %w", err)
+		}
+		kt.Metadata = json.RawMessage(metaJSON)
+		results = append(results, kt)
+		ids = append(ids, kt.ID)
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	components, err := r.listKitComponents(ctx, r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Components = components[results[i].ID]
+	}
+	return results, nil
+}
+
+func (r *SqlRepository) listKitComponents(ctx context.Context, q queryer, kitIDs []int64) (map[int64][]domain.KitComponent, error) {
+	query := `SELECT id, kit_template_id, item_type_id, quantity, is_optional, substitute_item_type_ids
+	          FROM kit_template_components WHERE kit_template_id = ANY($1) ORDER BY id`
+	rows, err := q.QueryContext(ctx, query, pq.Array(kitIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list kit components: %w", err)
+	}
+	defer rows.Close()
+
+	results := make(map[int64][]domain.KitComponent)
+	for rows.Next() {
+		var c domain.KitComponent
+		var subs pq.Int64Array
+		if err := rows.Scan(&c.ID, &c.KitTemplateID, &c.ItemTypeID, &c.Quantity, &c.IsOptional, &subs); err != nil {
+			return nil, fmt.Errorf("scan kit component: %w", err)
+		}
+		c.SubstituteItemTypeIDs = []int64(subs)
+		results[c.KitTemplateID] = append(results[c.KitTemplateID], c)
+	}
+	return results, nil
+}
+
+// UpdateKitTemplate updates a kit template and replaces its components.
+func (r *SqlRepository) UpdateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	kt.UpdatedAt = time.Now()
+	query := `UPDATE kit_templates SET item_type_id = $1, code = $2, name = $3, description = $4, is_active = $5, metadata = $6, updated_at = $7
+	          WHERE id = $8`
+	if _, err := tx.ExecContext(ctx, query, kt.ItemTypeID, kt.Code, kt.Name, kt.Description, kt.IsActive, kt.Metadata, kt.UpdatedAt, kt.ID); err != nil {
+		return fmt.Errorf("update kit_template: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM kit_template_components WHERE kit_template_id = $1", kt.ID); err != nil {
+		return fmt.Errorf("clear kit components: %w", err)
+	}
+	if err := insertKitComponents(ctx, tx, kt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteKitTemplate soft-deletes a kit template so existing demands keep resolving.
+func (r *SqlRepository) DeleteKitTemplate(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE kit_templates SET is_active = false, updated_at = $1 WHERE id = $2", time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("delete kit_template: %w", err)
+	}
+	return nil
+}
+
+// GetKitAvailableQuantity returns how many complete kits can be assembled in the window,
+// expanding the kit into its components (and their substitutes).
+func (r *SqlRepository) GetKitAvailableQuantity(ctx context.Context, kitTemplateID int64, startTime, endTime time.Time) (int, error) {
+	kt, err := r.GetKitTemplate(ctx, kitTemplateID)
+	if err != nil {
+		return 0, err
+	}
+	if kt == nil {
+		return 0, fmt.Errorf("kit_template %d not found", kitTemplateID)
+	}
+
+	ids := kt.ItemTypeIDs()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	timelines, err := r.loadAvailabilityTimelines(ctx, r.db, ids, startTime, endTime, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	avail := make(map[int64]int, len(ids))
+	for _, id := range ids {
+		avail[id] = timelines[id].MinAvailableForRental(startTime, endTime, 0)
+	}
+	return kt.Buildable(avail), nil
+}
+
+// CheckOutKit dispatches the assets that make up one instance of a kit demand and
+// records the instance so the kit can be traced back to its physical assets. A demand
+// never has more kits out at once than it asked for.
+func (r *SqlRepository) CheckOutKit(ctx context.Context, reservationID, demandID int64, assetIDs []int64, agentID int64, fromLocationID, toLocationID *int64) (*domain.KitInstance, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the reservation so concurrent dispatches of the same demand count each other's kits
+	var status domain.RentalReservationStatus
+	err = tx.QueryRowContext(ctx, "SELECT reservation_status FROM rental_reservations WHERE id = $1 FOR UPDATE", reservationID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: reservation %d not found", domain.ErrKitMismatch, reservationID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reservation: %w", err)
+	}
+	if !domain.CanReceiveTransfer(status) {
+		return nil, fmt.Errorf("%w: reservation %d is %s", domain.ErrKitMismatch, reservationID, status)
+	}
+
+	var itemKind string
+	var kitID int64
+	var requested, open int
+	demandQuery := `SELECT d.item_kind, d.item_id, d.requested_quantity,
+	                       (SELECT COUNT(*) FROM kit_instances ki WHERE ki.demand_id = d.id AND ki.returned_at IS NULL)
+	                FROM demands d WHERE d.id = $1 AND d.reservation_id = $2`
+	err = tx.QueryRowContext(ctx, demandQuery, demandID, reservationID).Scan(&itemKind, &kitID, &requested, &open)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: demand %d does not belong to reservation %d", domain.ErrKitMismatch, demandID, reservationID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get demand: %w", err)
+	}
+	if itemKind != domain.DemandKindKitTemplate {
+		return nil, fmt.Errorf("%w: demand %d is not a kit demand", domain.ErrKitMismatch, demandID)
+	}
+	if open >= requested {
+		return nil, fmt.Errorf("%w: demand %d already has %d of %d kits out", domain.ErrKitMismatch, demandID, open, requested)
+	}
+
+	kt, err := r.GetKitTemplate(ctx, kitID)
+	if err != nil {
+		return nil, err
+	}
+	if kt == nil {
+		return nil, fmt.Errorf("kit_template %d not found", kitID)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, item_type_id FROM assets WHERE id = ANY($1) FOR UPDATE", pq.Array(assetIDs))
+	if err != nil {
+		return nil, fmt.Errorf("query kit assets: %w", err)
+	}
+	var itemTypes []int64
+	for rows.Next() {
+		var id, itemTypeID int64
+		if err := rows.Scan(&id, &itemTypeID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan kit asset: %w", err)
+		}
+		itemTypes = append(itemTypes, itemTypeID)
+	}
+	rows.Close()
+	if len(itemTypes) != len(assetIDs) {
+		return nil, fmt.Errorf("%w: one or more assets not found", domain.ErrKitMismatch)
+	}
+	if err := kt.MatchAssets(itemTypes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ki := &domain.KitInstance{
+		ReservationID: reservationID,
+		DemandID:      demandID,
+		KitTemplateID: kt.ID,
+		AssetIDs:      assetIDs,
+		CheckedOutAt:  now,
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO kit_instances (reservation_id, demand_id, kit_template_id, checked_out_at)
+	                              VALUES ($1, $2, $3, $4) RETURNING id`,
+		ki.ReservationID, ki.DemandID, ki.KitTemplateID, ki.CheckedOutAt).Scan(&ki.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert kit_instance: %w", err)
+	}
+
+	if err := checkOutAssets(ctx, tx, reservationID, assetIDs, agentID, fromLocationID, toLocationID, &ki.ID, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Re-evaluate overall status after commit
+	fStatus, err := r.GetRentalFulfillmentStatus(ctx, reservationID)
+	if err == nil {
+		r.UpdateRentalReservationStatus(ctx, reservationID, domain.RentalReservationStatus(fStatus.Status))
+	}
+
+	return ki, nil
+}
+
+// ListKitInstances returns the kit instances checked out for a reservation, with their assets.
+func (r *SqlRepository) ListKitInstances(ctx context.Context, reservationID int64) ([]domain.KitInstance, error) {
+	query := `SELECT ki.id, ki.reservation_id, ki.demand_id, ki.kit_template_id, ki.checked_out_at, ki.returned_at,
+	                 COALESCE(array_agg(co.asset_id ORDER BY co.asset_id) FILTER (WHERE co.asset_id IS NOT NULL), '{}')
+	          FROM kit_instances ki
+	          LEFT JOIN check_out_actions co ON co.kit_instance_id = ki.id
+	          WHERE ki.reservation_id = $1
+	          GROUP BY ki.id
+	          ORDER BY ki.id`
+	rows, err := r.db.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("list kit_instances: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.KitInstance{}
+	for rows.Next() {
+		var ki domain.KitInstance
+		var assetIDs pq.Int64Array
+		if err := rows.Scan(&ki.ID, &ki.ReservationID, &ki.DemandID, &ki.KitTemplateID, &ki.CheckedOutAt, &ki.ReturnedAt, &assetIDs); err != nil {
+			return nil, fmt.Errorf("scan kit_instance: %w", err)
+		}
+		ki.AssetIDs = []int64(assetIDs)
+		results = append(results, ki)
+	}
+	return results, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqlRepository_CheckOutKit_DemandAlreadyOut(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT reservation_status FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_status"}).AddRow(domain.ReservationStatusPartiallyFulfilled))
+	// The demand asked for two kits and both are still out
+	mock.ExpectQuery("SELECT d.item_kind, d.item_id, d.requested_quantity, (.+) FROM kit_instances ki (.+) FROM demands d").
+		WithArgs(30, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"item_kind", "item_id", "requested_quantity", "open"}).
+			AddRow(domain.DemandKindKitTemplate, 8, 2, 2))
+	mock.ExpectRollback()
+
+	ki, err := repo.CheckOutKit(ctx, 3, 30, []int64{1, 2}, 9, nil, nil)
+	assert.Nil(t, ki)
+	assert.True(t, errors.Is(err, domain.ErrKitMismatch))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_CheckOutKit_ReservationNotConfirmed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT reservation_status FROM rental_reservations WHERE id = \\$1 FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_status"}).AddRow(domain.ReservationStatusPending))
+	mock.ExpectRollback()
+
+	ki, err := repo.CheckOutKit(ctx, 3, 30, []int64{1, 2}, 9, nil, nil)
+	assert.Nil(t, ki)
+	assert.True(t, errors.Is(err, domain.ErrKitMismatch))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
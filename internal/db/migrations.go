@@ -34,7 +34,8 @@ func RunMigrations(ctx context.Context, db *sql.DB) error {
 
 	var files []string
 	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+		// Down migrations are applied by hand when rolling back, never here
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") && !strings.HasSuffix(entry.Name(), ".down.sql") {
 			files = append(files, entry.Name())
 		}
 	}
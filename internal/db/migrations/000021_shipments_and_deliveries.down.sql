@@ -1,5 +1,5 @@
-ALTER TABLE checkout_actions DROP COLUMN scheduled_delivery_id;
-ALTER TABLE checkout_actions DROP COLUMN shipment_id;
+ALTER TABLE check_out_actions DROP COLUMN scheduled_delivery_id;
+ALTER TABLE check_out_actions DROP COLUMN shipment_id;
 
 ALTER TABLE return_actions DROP COLUMN shipment_id;
 
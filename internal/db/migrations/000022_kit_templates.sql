@@ -0,0 +1,42 @@
+-- Migration 000022: Kit Templates
+-- A kit template lists the component item types that make up a rentable kit.
+-- Demands with item_kind = 'kit_template' reference kit_templates.id.
+
+CREATE TABLE kit_templates (
+    id BIGSERIAL PRIMARY KEY,
+    item_type_id BIGINT REFERENCES item_types(id) ON DELETE SET NULL, -- Catalog entry of kind 'kit'
+    code VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(191) NOT NULL,
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    metadata JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE kit_template_components (
+    id BIGSERIAL PRIMARY KEY,
+    kit_template_id BIGINT NOT NULL REFERENCES kit_templates(id) ON DELETE CASCADE,
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    is_optional BOOLEAN NOT NULL DEFAULT false,
+    substitute_item_type_ids BIGINT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX idx_kit_components_template ON kit_template_components(kit_template_id);
+CREATE INDEX idx_kit_components_item_type ON kit_template_components(item_type_id);
+
+-- One row per physical kit checked out against a kit_template demand
+CREATE TABLE kit_instances (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id),
+    demand_id BIGINT NOT NULL REFERENCES demands(id),
+    kit_template_id BIGINT NOT NULL REFERENCES kit_templates(id),
+    checked_out_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    returned_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_kit_instances_reservation ON kit_instances(reservation_id);
+
+ALTER TABLE check_out_actions ADD COLUMN kit_instance_id BIGINT REFERENCES kit_instances(id);
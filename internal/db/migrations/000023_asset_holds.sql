@@ -0,0 +1,21 @@
+-- Migration 000023: Hard Allocation Holds
+-- Pins specific serialized assets to the demand lines of a reservation.
+
+CREATE TABLE asset_holds (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    demand_id BIGINT NOT NULL REFERENCES demands(id) ON DELETE CASCADE,
+    asset_id BIGINT NOT NULL REFERENCES assets(id),
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id), -- Demand line item type being satisfied
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'active', -- 'active', 'released', 'consumed'
+    created_by_user_id BIGINT REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_time > start_time)
+);
+
+CREATE INDEX idx_asset_holds_asset_active ON asset_holds(asset_id, start_time, end_time) WHERE status = 'active';
+CREATE INDEX idx_asset_holds_reservation ON asset_holds(reservation_id);
+CREATE INDEX idx_asset_holds_demand ON asset_holds(demand_id);
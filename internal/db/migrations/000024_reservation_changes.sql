@@ -0,0 +1,16 @@
+-- Migration 000024: Reservation Change History
+-- One row per applied modification, with before/after snapshots of the reservation and its demands.
+
+CREATE TABLE reservation_changes (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    changed_by_user_id BIGINT REFERENCES users(id),
+    changed_fields TEXT[] NOT NULL DEFAULT '{}',
+    status_before VARCHAR(50) NOT NULL,
+    status_after VARCHAR(50) NOT NULL,
+    before_snapshot JSONB NOT NULL,
+    after_snapshot JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (reservation_id, version)
+);
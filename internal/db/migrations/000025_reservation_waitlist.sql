@@ -0,0 +1,24 @@
+-- Migration 000025: Reservation Waitlist
+-- Pending reservations that failed approval for lack of inventory, queued per short item type.
+
+CREATE TABLE reservation_waitlist (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id),
+    priority INTEGER NOT NULL DEFAULT 0,
+    auto_approve BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(32) NOT NULL DEFAULT 'waiting', -- 'waiting', 'notified', 'promoted', 'removed'
+    requested INTEGER NOT NULL,
+    available INTEGER NOT NULL,
+    created_by_user_id BIGINT REFERENCES users(id),
+    notified_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A reservation is queued at most once per item type while its entry is open
+CREATE UNIQUE INDEX idx_waitlist_open ON reservation_waitlist(reservation_id, item_type_id)
+    WHERE status IN ('waiting', 'notified');
+CREATE INDEX idx_waitlist_queue ON reservation_waitlist(item_type_id, priority DESC, created_at)
+    WHERE status IN ('waiting', 'notified');
@@ -0,0 +1,20 @@
+-- Migration 000026: Substitution Rules
+-- Item types that may stand in for another when the requested one is short.
+
+CREATE TABLE substitution_rules (
+    id BIGSERIAL PRIMARY KEY,
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id) ON DELETE CASCADE,
+    substitute_item_type_id BIGINT NOT NULL REFERENCES item_types(id) ON DELETE CASCADE,
+    two_way BOOLEAN NOT NULL DEFAULT false,
+    priority INTEGER NOT NULL DEFAULT 0,
+    max_quantity INTEGER, -- Per demand line; NULL is unlimited
+    auto_apply BOOLEAN NOT NULL DEFAULT false,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (item_type_id <> substitute_item_type_id),
+    UNIQUE (item_type_id, substitute_item_type_id)
+);
+
+CREATE INDEX idx_substitution_rules_substitute ON substitution_rules(substitute_item_type_id);
@@ -0,0 +1,7 @@
+-- Migration 000027: Turnaround Buffers
+-- Time a unit is out of circulation around each rental, and transit time to each place.
+
+ALTER TABLE item_types ADD COLUMN pre_buffer_minutes INTEGER NOT NULL DEFAULT 0 CHECK (pre_buffer_minutes >= 0);
+ALTER TABLE item_types ADD COLUMN post_buffer_minutes INTEGER NOT NULL DEFAULT 0 CHECK (post_buffer_minutes >= 0);
+
+ALTER TABLE places ADD COLUMN transit_minutes INTEGER NOT NULL DEFAULT 0 CHECK (transit_minutes >= 0);
@@ -0,0 +1,25 @@
+-- Migration 000028: Recurring Reservations
+-- A series holds the RRULE, its exceptions and the demand template; each occurrence is
+-- materialized as a rental_reservations row linked back to it.
+
+CREATE TABLE reservation_series (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_name VARCHAR(191),
+    recurrence_rule TEXT NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    exceptions JSONB NOT NULL DEFAULT '[]', -- EXDATE occurrence starts
+    under_name_id BIGINT REFERENCES people(id),
+    provider_id BIGINT REFERENCES companies(id),
+    demands JSONB NOT NULL DEFAULT '[]',
+    metadata JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_time > start_time)
+);
+
+ALTER TABLE rental_reservations ADD COLUMN series_id BIGINT REFERENCES reservation_series(id);
+ALTER TABLE rental_reservations ADD COLUMN recurrence_id TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_rental_reservations_series ON rental_reservations(series_id, recurrence_id)
+    WHERE series_id IS NOT NULL;
@@ -0,0 +1,76 @@
+-- Migration 000029: Reservation State Machine
+-- Transition history for rental reservations, and a one-time fold of the RentAction
+-- columns that 000012 carried over when it renamed rent_actions.
+
+ALTER TABLE rental_reservations ADD COLUMN submitted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE rental_reservations ADD COLUMN fulfilled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE rental_reservations ALTER COLUMN reservation_status SET DEFAULT 'ReservationPending';
+
+CREATE TABLE reservation_transitions (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    action VARCHAR(32) NOT NULL,
+    from_status VARCHAR(64) NOT NULL,
+    to_status VARCHAR(64) NOT NULL,
+    reason TEXT,
+    user_id BIGINT REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reservation_transitions_reservation ON reservation_transitions(reservation_id, created_at);
+
+-- Every row that still has a requester_ref was written as a RentAction.
+-- 000012 left drafts untouched and mapped rejected onto cancelled.
+UPDATE rental_reservations SET reservation_status = CASE reservation_status
+        WHEN 'draft' THEN 'ReservationDraft'
+        WHEN 'pending' THEN 'ReservationPending'
+        WHEN 'approved' THEN 'ReservationConfirmed'
+        WHEN 'rejected' THEN 'ReservationCancelled'
+        WHEN 'cancelled' THEN 'ReservationCancelled'
+        WHEN 'fulfilled' THEN 'ReservationFulfilled'
+    END
+WHERE reservation_status IN ('draft', 'pending', 'approved', 'rejected', 'cancelled', 'fulfilled');
+
+UPDATE rental_reservations SET submitted_at = created_at
+WHERE requester_ref IS NOT NULL AND reservation_status <> 'ReservationDraft';
+
+UPDATE rental_reservations SET reservation_name = LEFT(description, 191)
+WHERE requester_ref IS NOT NULL AND reservation_name IS NULL AND description IS NOT NULL;
+
+UPDATE rental_reservations SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('rentAction', jsonb_strip_nulls(jsonb_build_object(
+        'requesterRef', requester_ref,
+        'createdByRef', created_by_ref,
+        'approvedByRef', approved_by_ref,
+        'priority', priority,
+        'isAsap', is_asap,
+        'description', description,
+        'externalSource', external_source,
+        'externalRef', external_ref,
+        'schemaOrg', schema_org
+    )))
+WHERE requester_ref IS NOT NULL;
+
+INSERT INTO reservation_transitions (reservation_id, action, from_status, to_status, reason, created_at)
+SELECT id, 'approve', 'ReservationPending', 'ReservationConfirmed',
+       'migrated from rent_actions' || COALESCE('; approved by ' || approved_by_ref, ''), approved_at
+FROM rental_reservations WHERE requester_ref IS NOT NULL AND approved_at IS NOT NULL;
+
+INSERT INTO reservation_transitions (reservation_id, action, from_status, to_status, reason, created_at)
+SELECT id, 'reject', 'ReservationPending', 'ReservationCancelled', 'migrated from rent_actions', rejected_at
+FROM rental_reservations WHERE requester_ref IS NOT NULL AND rejected_at IS NOT NULL;
+
+INSERT INTO reservation_transitions (reservation_id, action, from_status, to_status, reason, created_at)
+SELECT id, 'cancel', CASE WHEN approved_at IS NOT NULL AND approved_at < cancelled_at THEN 'ReservationConfirmed' ELSE 'ReservationPending' END,
+       'ReservationCancelled', 'migrated from rent_actions', cancelled_at
+FROM rental_reservations WHERE requester_ref IS NOT NULL AND cancelled_at IS NOT NULL;
+
+-- The requester and free-text fields now live in metadata.rentAction
+ALTER TABLE rental_reservations DROP COLUMN requester_ref;
+ALTER TABLE rental_reservations DROP COLUMN created_by_ref;
+ALTER TABLE rental_reservations DROP COLUMN approved_by_ref;
+ALTER TABLE rental_reservations DROP COLUMN priority;
+ALTER TABLE rental_reservations DROP COLUMN is_asap;
+ALTER TABLE rental_reservations DROP COLUMN description;
+ALTER TABLE rental_reservations DROP COLUMN external_source;
+ALTER TABLE rental_reservations DROP COLUMN external_ref;
+ALTER TABLE rental_reservations DROP COLUMN schema_org;
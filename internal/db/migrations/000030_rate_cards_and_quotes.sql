@@ -0,0 +1,34 @@
+-- Migration 000030: Rate Cards and Quotes
+-- Per item type pricing, with per-company overrides, and the quotes stored when a
+-- reservation is approved.
+
+CREATE TABLE rate_cards (
+    id BIGSERIAL PRIMARY KEY,
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id) ON DELETE CASCADE,
+    company_id BIGINT REFERENCES companies(id) ON DELETE CASCADE, -- NULL is the general card
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    daily_rate BIGINT NOT NULL, -- Minor currency units
+    weekly_rate BIGINT NOT NULL DEFAULT 0, -- 0 when the tier is not offered
+    monthly_rate BIGINT NOT NULL DEFAULT 0,
+    minimum_charge BIGINT NOT NULL DEFAULT 0,
+    weekend_rule VARCHAR(16) NOT NULL DEFAULT 'standard',
+    charge_buffers BOOLEAN NOT NULL DEFAULT false,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (daily_rate > 0 AND weekly_rate >= 0 AND monthly_rate >= 0 AND minimum_charge >= 0)
+);
+
+CREATE INDEX idx_rate_cards_item_type ON rate_cards(item_type_id, company_id);
+
+CREATE TABLE reservation_quotes (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    currency CHAR(3),
+    total BIGINT NOT NULL,
+    quote JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reservation_quotes_reservation ON reservation_quotes(reservation_id, created_at DESC);
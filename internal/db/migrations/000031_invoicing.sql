@@ -0,0 +1,49 @@
+-- Migration 000031: Invoicing
+-- Invoices built from actual check-out and return times, with late-return fees from
+-- rate cards and damage charges from inspection fields.
+
+ALTER TABLE rate_cards ADD COLUMN late_fee_per_day BIGINT NOT NULL DEFAULT 0 CHECK (late_fee_per_day >= 0);
+ALTER TABLE rate_cards ADD COLUMN late_grace_minutes INTEGER NOT NULL DEFAULT 0;
+
+-- The response that means the asset came back damaged, and what that costs the renter
+ALTER TABLE inspection_fields ADD COLUMN damage_value TEXT;
+ALTER TABLE inspection_fields ADD COLUMN damage_charge BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE invoices (
+    id BIGSERIAL PRIMARY KEY,
+    number VARCHAR(32) UNIQUE,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    company_id BIGINT REFERENCES companies(id) ON DELETE SET NULL,
+    currency CHAR(3),
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    is_final BOOLEAN NOT NULL DEFAULT false,
+    total BIGINT NOT NULL,
+    created_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (period_end >= period_start) -- A final invoice may only carry charges found after the last period
+);
+
+CREATE INDEX idx_invoices_reservation ON invoices(reservation_id, period_end DESC);
+
+CREATE TABLE invoice_lines (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL, -- 'usage', 'late_return', 'damage'
+    description TEXT NOT NULL,
+    asset_id BIGINT NOT NULL REFERENCES assets(id),
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id),
+    rate_card_id BIGINT REFERENCES rate_cards(id) ON DELETE SET NULL,
+    check_out_id BIGINT REFERENCES check_out_actions(id),
+    return_id BIGINT REFERENCES return_actions(id),
+    inspection_submission_id BIGINT REFERENCES inspection_submissions(id) ON DELETE SET NULL,
+    start_time TIMESTAMP WITH TIME ZONE,
+    end_time TIMESTAMP WITH TIME ZONE,
+    days INTEGER NOT NULL DEFAULT 0,
+    breakdown TEXT,
+    amount BIGINT NOT NULL,
+    note TEXT
+);
+
+CREATE INDEX idx_invoice_lines_invoice ON invoice_lines(invoice_id);
+CREATE INDEX idx_invoice_lines_inspection ON invoice_lines(inspection_submission_id) WHERE inspection_submission_id IS NOT NULL;
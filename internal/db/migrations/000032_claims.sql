@@ -0,0 +1,41 @@
+-- Migration 000032: Damage and Loss Claims
+-- Claims against a reservation for an asset that failed its return inspection or was
+-- never returned, with the evidence behind them.
+
+CREATE TABLE claims (
+    id BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    asset_id BIGINT NOT NULL REFERENCES assets(id),
+    claim_type VARCHAR(16) NOT NULL, -- 'damage', 'loss'
+    status VARCHAR(16) NOT NULL DEFAULT 'open', -- 'open', 'under_review', 'settled', 'denied'
+    inspection_submission_id BIGINT REFERENCES inspection_submissions(id) ON DELETE SET NULL,
+    check_out_id BIGINT REFERENCES check_out_actions(id),
+    responsible_person_id BIGINT REFERENCES people(id) ON DELETE SET NULL,
+    responsible_company_id BIGINT REFERENCES companies(id) ON DELETE SET NULL,
+    description TEXT,
+    currency CHAR(3),
+    claimed_amount BIGINT NOT NULL DEFAULT 0,
+    settled_amount BIGINT,
+    resolution_note TEXT,
+    created_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    settled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_claims_reservation ON claims(reservation_id);
+CREATE INDEX idx_claims_asset ON claims(asset_id);
+CREATE INDEX idx_claims_submission ON claims(inspection_submission_id) WHERE inspection_submission_id IS NOT NULL;
+
+CREATE TABLE claim_evidence (
+    id BIGSERIAL PRIMARY KEY,
+    claim_id BIGINT NOT NULL REFERENCES claims(id) ON DELETE CASCADE,
+    url TEXT,
+    note TEXT,
+    inspection_response_id BIGINT REFERENCES inspection_responses(id) ON DELETE SET NULL,
+    added_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (url IS NOT NULL OR note IS NOT NULL)
+);
+
+CREATE INDEX idx_claim_evidence_claim ON claim_evidence(claim_id);
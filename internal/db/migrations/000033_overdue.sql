@@ -0,0 +1,17 @@
+-- Migration 000033: Overdue Rentals
+-- Reservations with assets still out past their end time, and how far each overdue
+-- check-out has been escalated.
+
+ALTER TABLE rental_reservations ADD COLUMN overdue_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE overdue_escalations (
+    check_out_id BIGINT PRIMARY KEY REFERENCES check_out_actions(id) ON DELETE CASCADE,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    escalation_level INTEGER NOT NULL DEFAULT 0, -- Number of escalation steps reached
+    last_escalated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_overdue_escalations_reservation ON overdue_escalations(reservation_id);
+CREATE INDEX idx_rental_reservations_overdue ON rental_reservations(overdue_at) WHERE overdue_at IS NOT NULL;
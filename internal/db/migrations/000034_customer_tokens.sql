@@ -0,0 +1,19 @@
+-- Migration 000034: Customer Portal Tokens
+-- Tokens that let a person at a customer company use the portal API, scoped to that
+-- company's reservations. Only a SHA-256 hash of each token is kept.
+
+CREATE TABLE customer_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    company_id BIGINT NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    person_id BIGINT NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+    name VARCHAR(255),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    hint VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_customer_tokens_company ON customer_tokens(company_id);
@@ -0,0 +1,40 @@
+-- Migration 000035: Multi-leg Shipments and Transfers
+-- Ordered legs for shipments that go through hubs or several venues, and a record of
+-- assets handed straight from one reservation to the next.
+
+CREATE TABLE shipment_legs (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    sequence INT NOT NULL,
+    from_place_id BIGINT NOT NULL REFERENCES places(id),
+    to_place_id BIGINT NOT NULL REFERENCES places(id),
+    carrier VARCHAR(255),
+    tracking_number VARCHAR(255),
+    estimated_departure TIMESTAMP WITH TIME ZONE,
+    estimated_arrival TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(32) NOT NULL DEFAULT 'DeliveryPreparing',
+    departed_at TIMESTAMP WITH TIME ZONE,
+    arrived_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (shipment_id, sequence)
+);
+
+CREATE TABLE asset_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    asset_id BIGINT NOT NULL REFERENCES assets(id),
+    from_reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    to_reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    return_action_id BIGINT NOT NULL REFERENCES return_actions(id) ON DELETE CASCADE,
+    check_out_action_id BIGINT NOT NULL REFERENCES check_out_actions(id) ON DELETE CASCADE,
+    from_place_id BIGINT REFERENCES places(id),
+    to_place_id BIGINT REFERENCES places(id),
+    shipment_id BIGINT REFERENCES shipments(id) ON DELETE SET NULL,
+    agent_id BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_shipment_legs_shipment ON shipment_legs(shipment_id);
+CREATE INDEX idx_asset_transfers_from ON asset_transfers(from_reservation_id);
+CREATE INDEX idx_asset_transfers_to ON asset_transfers(to_reservation_id);
+CREATE INDEX idx_check_out_actions_shipment ON check_out_actions(shipment_id) WHERE shipment_id IS NOT NULL;
@@ -0,0 +1,34 @@
+-- Migration 000036: Carrier Labels and Tracking
+-- Labels bought from carriers for shipments, and the tracking events carriers report.
+
+CREATE TABLE shipment_labels (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    carrier VARCHAR(255) NOT NULL,
+    service VARCHAR(255) NOT NULL,
+    tracking_number VARCHAR(255) NOT NULL,
+    label_url TEXT,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    voided_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A shipment carries at most one label that has not been voided
+CREATE UNIQUE INDEX idx_shipment_labels_active ON shipment_labels(shipment_id) WHERE voided_at IS NULL;
+
+CREATE TABLE shipment_tracking_events (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    carrier VARCHAR(255) NOT NULL,
+    tracking_number VARCHAR(255) NOT NULL,
+    code VARCHAR(32) NOT NULL,
+    description TEXT,
+    location VARCHAR(255),
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- Carriers repeat events on every poll; each is stored once
+    UNIQUE (shipment_id, tracking_number, code, occurred_at)
+);
+
+CREATE INDEX idx_shipment_tracking_events_shipment ON shipment_tracking_events(shipment_id, occurred_at);
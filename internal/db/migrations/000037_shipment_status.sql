@@ -0,0 +1,6 @@
+-- Migration 000037: Shipment Status
+-- Shipments were created as 'Preparing' while the API speaks schema.org-style
+-- DeliveryStatus values; bring old rows and the default in line.
+
+UPDATE shipments SET status = 'DeliveryPreparing' WHERE status = 'Preparing';
+ALTER TABLE shipments ALTER COLUMN status SET DEFAULT 'DeliveryPreparing';
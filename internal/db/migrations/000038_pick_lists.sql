@@ -0,0 +1,39 @@
+-- Migration 000038: Pick Lists
+-- Warehouse pick lists for scheduled deliveries: a line per item type needed and a
+-- task per asset to pull, ticked off as assets are scanned.
+
+CREATE TABLE pick_lists (
+    id BIGSERIAL PRIMARY KEY,
+    scheduled_delivery_id BIGINT NOT NULL REFERENCES scheduled_deliveries(id) ON DELETE CASCADE,
+    shipment_id BIGINT REFERENCES shipments(id) ON DELETE SET NULL,
+    source_place_id BIGINT REFERENCES places(id),
+    status VARCHAR(32) NOT NULL DEFAULT 'Open',
+    created_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One open pick list per delivery
+CREATE UNIQUE INDEX idx_pick_lists_open_delivery ON pick_lists(scheduled_delivery_id) WHERE status = 'Open';
+
+CREATE TABLE pick_list_lines (
+    id BIGSERIAL PRIMARY KEY,
+    pick_list_id BIGINT NOT NULL REFERENCES pick_lists(id) ON DELETE CASCADE,
+    delivery_item_id BIGINT NOT NULL REFERENCES scheduled_delivery_items(id) ON DELETE CASCADE,
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id),
+    requested_quantity INT NOT NULL
+);
+
+CREATE TABLE pick_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    pick_list_id BIGINT NOT NULL REFERENCES pick_lists(id) ON DELETE CASCADE,
+    line_id BIGINT NOT NULL REFERENCES pick_list_lines(id) ON DELETE CASCADE,
+    asset_id BIGINT NOT NULL REFERENCES assets(id),
+    place_id BIGINT REFERENCES places(id),
+    picked_at TIMESTAMP WITH TIME ZONE,
+    picked_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE (pick_list_id, asset_id)
+);
+
+CREATE INDEX idx_pick_list_lines_list ON pick_list_lines(pick_list_id);
+CREATE INDEX idx_pick_tasks_asset ON pick_tasks(asset_id);
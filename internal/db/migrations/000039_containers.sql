@@ -0,0 +1,35 @@
+-- Migration 000039: Containers and Packing
+-- Road cases, pallets and crates that hold assets and other containers, and what was
+-- packed into what on each shipment, for its manifest.
+
+CREATE TABLE containers (
+    id BIGSERIAL PRIMARY KEY,
+    tag VARCHAR(128) NOT NULL UNIQUE,   -- Barcode/RFID on the container itself
+    name VARCHAR(191) NOT NULL,
+    kind VARCHAR(32) NOT NULL,          -- 'case', 'pallet', 'crate'
+    parent_container_id BIGINT REFERENCES containers(id) ON DELETE SET NULL,
+    place_id BIGINT REFERENCES places(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (parent_container_id IS NULL OR parent_container_id != id)
+);
+
+ALTER TABLE assets ADD COLUMN container_id BIGINT REFERENCES containers(id) ON DELETE SET NULL;
+
+-- Containers loaded onto a shipment, with the container each was packed in at the time
+CREATE TABLE shipment_containers (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    container_id BIGINT NOT NULL REFERENCES containers(id) ON DELETE CASCADE,
+    parent_container_id BIGINT REFERENCES containers(id) ON DELETE SET NULL,
+    packed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    packed_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE (shipment_id, container_id)
+);
+
+ALTER TABLE check_out_actions ADD COLUMN packed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE check_out_actions ADD COLUMN packed_container_id BIGINT REFERENCES containers(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_containers_parent ON containers(parent_container_id);
+CREATE INDEX idx_assets_container ON assets(container_id) WHERE container_id IS NOT NULL;
+CREATE INDEX idx_shipment_containers_shipment ON shipment_containers(shipment_id);
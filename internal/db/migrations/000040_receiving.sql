@@ -0,0 +1,32 @@
+-- Migration 000040: Return Receiving
+-- Dock-side sessions that scan what comes off an inbound shipment and reconcile it with
+-- what the reservation had out.
+
+CREATE TABLE receiving_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    reservation_id BIGINT NOT NULL REFERENCES rental_reservations(id) ON DELETE CASCADE,
+    to_place_id BIGINT REFERENCES places(id),
+    status VARCHAR(32) NOT NULL DEFAULT 'Open',
+    report JSONB,                               -- Discrepancy report, written on close
+    opened_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One open session per shipment
+CREATE UNIQUE INDEX idx_receiving_sessions_open_shipment ON receiving_sessions(shipment_id) WHERE status = 'Open';
+
+CREATE TABLE receiving_scans (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES receiving_sessions(id) ON DELETE CASCADE,
+    code VARCHAR(128) NOT NULL,
+    asset_id BIGINT REFERENCES assets(id),
+    outcome VARCHAR(32) NOT NULL,               -- 'expected', 'wrong_reservation', 'extra', 'unknown'
+    reservation_id BIGINT REFERENCES rental_reservations(id) ON DELETE SET NULL,
+    scanned_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    scanned_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE (session_id, asset_id)
+);
+
+CREATE INDEX idx_receiving_scans_session ON receiving_scans(session_id);
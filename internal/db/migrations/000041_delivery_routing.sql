@@ -0,0 +1,7 @@
+-- Migration 000041: Delivery Routing
+-- Where a scheduled delivery is dropped off and the window the customer can receive it in,
+-- for same-day route planning.
+
+ALTER TABLE scheduled_deliveries ADD COLUMN destination_place_id BIGINT REFERENCES places(id);
+ALTER TABLE scheduled_deliveries ADD COLUMN window_start TIMESTAMP WITH TIME ZONE;
+ALTER TABLE scheduled_deliveries ADD COLUMN window_end TIMESTAMP WITH TIME ZONE;
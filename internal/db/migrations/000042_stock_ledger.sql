@@ -0,0 +1,28 @@
+-- Migration 000042: Fungible Stock Ledger
+-- Bulk items are counted by quantity per place instead of one assets row per unit.
+-- Every change is a movement in the ledger; levels are its sums.
+
+CREATE TABLE stock_lots (
+    id BIGSERIAL PRIMARY KEY,
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id) ON DELETE CASCADE,
+    lot_number VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (item_type_id, lot_number)
+);
+
+CREATE TABLE stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    item_type_id BIGINT NOT NULL REFERENCES item_types(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,                  -- receipt, write_off, check_out, return, consumption
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    place_id BIGINT REFERENCES places(id),      -- NULL for consumption, which happens away from stock
+    lot_id BIGINT REFERENCES stock_lots(id),    -- NULL for stock not tracked by lot
+    reservation_id BIGINT REFERENCES rental_reservations(id),
+    note TEXT,
+    created_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_movements_item_type ON stock_movements(item_type_id, place_id);
+CREATE INDEX idx_stock_movements_reservation ON stock_movements(reservation_id) WHERE reservation_id IS NOT NULL;
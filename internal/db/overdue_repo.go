@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// outstandingCheckOut matches completed check-outs, aliased co, that have no return
+// of the asset against the same reservation after them.
+const outstandingCheckOut = `co.action_status = 'Completed' AND NOT EXISTS (
+	SELECT 1 FROM return_actions ret
+	WHERE ret.reservation_id = co.reservation_id AND ret.asset_id = co.asset_id AND ret.start_time >= co.start_time
+)`
+
+// ListOverdueAssets returns the assets still out on reservations that ended before
+// now, longest overdue first.
+func (r *SqlRepository) ListOverdueAssets(ctx context.Context, now time.Time) ([]domain.OverdueAsset, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rr.id, COALESCE(rr.reservation_name, ''), rr.under_name_id, co.asset_id, COALESCE(a.asset_tag, ''), co.id, co.start_time, rr.end_time,
+		       COALESCE(e.escalation_level, 0), e.last_escalated_at
+		FROM check_out_actions co
+		JOIN rental_reservations rr ON rr.id = co.reservation_id
+		JOIN assets a ON a.id = co.asset_id
+		LEFT JOIN overdue_escalations e ON e.check_out_id = co.id
+		WHERE rr.end_time < $1 AND `+outstandingCheckOut+`
+		ORDER BY rr.end_time, rr.id, co.id`, now)
+	if err != nil {
+		return nil, fmt.Errorf("query overdue assets: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.OverdueAsset{}
+	for rows.Next() {
+		var a domain.OverdueAsset
+		if err := rows.Scan(&a.ReservationID, &a.ReservationName, &a.UnderNameID, &a.AssetID, &a.AssetTag, &a.CheckOutID, &a.CheckedOutAt, &a.DueAt,
+			&a.EscalationLevel, &a.LastEscalatedAt); err != nil {
+			return nil, fmt.Errorf("scan overdue asset: %w", err)
+		}
+		a.OverdueMinutes = int64(now.Sub(a.DueAt) / time.Minute)
+		results = append(results, a)
+	}
+	return results, rows.Err()
+}
+
+// EscalateOverdueAsset records that an overdue check-out reached escalation level and
+// announces it with a rental.overdue event. It does nothing when the check-out was
+// already escalated that far, so concurrent workers announce each level once.
+func (r *SqlRepository) EscalateOverdueAsset(ctx context.Context, a *domain.OverdueAsset, level int, now time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO overdue_escalations (check_out_id, reservation_id, asset_id, escalation_level, last_escalated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (check_out_id) DO UPDATE SET escalation_level = EXCLUDED.escalation_level, last_escalated_at = EXCLUDED.last_escalated_at
+		WHERE overdue_escalations.escalation_level < EXCLUDED.escalation_level`,
+		a.CheckOutID, a.ReservationID, a.AssetID, level, now)
+	if err != nil {
+		return fmt.Errorf("upsert overdue_escalation: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"reservation_id":   a.ReservationID,
+		"under_name_id":    a.UnderNameID,
+		"asset_id":         a.AssetID,
+		"asset_tag":        a.AssetTag,
+		"check_out_id":     a.CheckOutID,
+		"due_at":           a.DueAt,
+		"overdue_minutes":  int64(now.Sub(a.DueAt) / time.Minute),
+		"escalation_level": level,
+	})
+	if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: domain.EventRentalOverdue, Payload: payload}); err != nil {
+		return err
+	}
+
+	a.EscalationLevel = level
+	a.LastEscalatedAt = &now
+	return tx.Commit()
+}
+
+// SyncOverdueReservations marks reservations that ended before now with assets still
+// out as overdue, and clears the mark once everything is back or the reservation was
+// extended. It returns how many reservations were marked and cleared.
+func (r *SqlRepository) SyncOverdueReservations(ctx context.Context, now time.Time) (marked, cleared int64, err error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE rental_reservations rr SET overdue_at = $1
+		WHERE rr.overdue_at IS NULL AND rr.end_time < $1
+		  AND EXISTS (SELECT 1 FROM check_out_actions co WHERE co.reservation_id = rr.id AND `+outstandingCheckOut+`)`, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("mark overdue reservations: %w", err)
+	}
+	if marked, err = res.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	res, err = r.db.ExecContext(ctx, `
+		UPDATE rental_reservations rr SET overdue_at = NULL
+		WHERE rr.overdue_at IS NOT NULL
+		  AND (rr.end_time >= $1 OR NOT EXISTS (SELECT 1 FROM check_out_actions co WHERE co.reservation_id = rr.id AND `+outstandingCheckOut+`))`, now)
+	if err != nil {
+		return marked, 0, fmt.Errorf("clear overdue reservations: %w", err)
+	}
+	if cleared, err = res.RowsAffected(); err != nil {
+		return marked, 0, err
+	}
+
+	// An extended reservation starts its escalations over from the new end time
+	_, err = r.db.ExecContext(ctx, `DELETE FROM overdue_escalations e USING rental_reservations rr
+	                                WHERE rr.id = e.reservation_id AND rr.end_time >= $1`, now)
+	if err != nil {
+		return marked, cleared, fmt.Errorf("reset overdue escalations: %w", err)
+	}
+	return marked, cleared, nil
+}
@@ -0,0 +1,506 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const pickListColumns = `id, scheduled_delivery_id, shipment_id, source_place_id, status, created_by_user_id, created_at, completed_at`
+
+func scanPickList(scanner interface{ Scan(...any) error }) (*domain.PickList, error) {
+	var pl domain.PickList
+	err := scanner.Scan(&pl.ID, &pl.ScheduledDeliveryID, &pl.ShipmentID, &pl.SourcePlaceID, &pl.Status, &pl.CreatedByUserID, &pl.CreatedAt, &pl.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &pl, nil
+}
+
+// pickableAsset filters assets (alias a) to those free to be picked for the reservation
+// in $2 at $3: in stock, not on an open pick list or a shipment already, and not held
+// for another reservation.
+const pickableAsset = `a.status IN ('available', 'reserved')
+	AND NOT EXISTS (
+		SELECT 1 FROM pick_tasks pt JOIN pick_lists pl ON pl.id = pt.pick_list_id
+		WHERE pt.asset_id = a.id AND pl.status = 'Open'
+	)
+	AND NOT EXISTS (SELECT 1 FROM check_out_actions co WHERE co.asset_id = a.id AND co.action_status = 'Potential')
+	AND NOT EXISTS (
+		SELECT 1 FROM asset_holds h
+		WHERE h.asset_id = a.id AND h.status = 'active' AND h.reservation_id != $2 AND h.end_time > $3
+	)`
+
+// placePaths returns the full "Site / Room / Shelf" path of each place.
+func placePaths(ctx context.Context, q queryer, placeIDs []int64) (map[int64]string, error) {
+	paths := make(map[int64]string, len(placeIDs))
+	if len(placeIDs) == 0 {
+		return paths, nil
+	}
+	rows, err := q.QueryContext(ctx, `
+		WITH RECURSIVE up AS (
+			SELECT id AS leaf, name, contained_in_place_id, 0 AS depth FROM places WHERE id = ANY($1)
+			UNION ALL
+			SELECT up.leaf, p.name, p.contained_in_place_id, up.depth + 1
+			FROM places p JOIN up ON p.id = up.contained_in_place_id
+		)
+		SELECT leaf, string_agg(name, ' / ' ORDER BY depth DESC) FROM up GROUP BY leaf`, pq.Array(placeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("query place paths: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, fmt.Errorf("scan place path: %w", err)
+		}
+		paths[id] = path
+	}
+	return paths, rows.Err()
+}
+
+// loadPickList reads a pick list with its lines and tasks, tallied and grouped by place.
+func loadPickList(ctx context.Context, q queryer, id int64, forUpdate bool) (*domain.PickList, error) {
+	query := `SELECT ` + pickListColumns + ` FROM pick_lists WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	pl, err := scanPickList(q.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query pick_list: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, pick_list_id, delivery_item_id, item_type_id, requested_quantity
+		FROM pick_list_lines WHERE pick_list_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query pick_list_lines: %w", err)
+	}
+	lineIndex := make(map[int64]int)
+	for rows.Next() {
+		var l domain.PickLine
+		if err := rows.Scan(&l.ID, &l.PickListID, &l.DeliveryItemID, &l.ItemTypeID, &l.Requested); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan pick_list_line: %w", err)
+		}
+		lineIndex[l.ID] = len(pl.Lines)
+		pl.Lines = append(pl.Lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, `
+		SELECT pt.id, pt.line_id, pt.asset_id, COALESCE(a.asset_tag, ''), pt.place_id, pt.picked_at, pt.picked_by_user_id
+		FROM pick_tasks pt JOIN assets a ON a.id = pt.asset_id
+		WHERE pt.pick_list_id = $1 ORDER BY pt.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query pick_tasks: %w", err)
+	}
+	var placeIDs []int64
+	for rows.Next() {
+		var t domain.PickTask
+		if err := rows.Scan(&t.ID, &t.LineID, &t.AssetID, &t.AssetTag, &t.PlaceID, &t.PickedAt, &t.PickedByUserID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan pick_task: %w", err)
+		}
+		if t.PlaceID != nil {
+			placeIDs = append(placeIDs, *t.PlaceID)
+		}
+		line := &pl.Lines[lineIndex[t.LineID]]
+		line.Tasks = append(line.Tasks, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paths, err := placePaths(ctx, q, placeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pl.Lines {
+		for j := range pl.Lines[i].Tasks {
+			if t := &pl.Lines[i].Tasks[j]; t.PlaceID != nil {
+				t.PlacePath = paths[*t.PlaceID]
+			}
+		}
+	}
+	pl.Tally()
+	pl.GroupStops()
+	return pl, nil
+}
+
+// CreatePickList generates the pick list for a scheduled delivery: each item, kits
+// expanded, is resolved to specific pickable assets, preferring ones already held for
+// the delivery's reservation, within the source place when one is given. Items that
+// cannot be covered are left short. It returns nil, nil when the delivery does not exist.
+func (r *SqlRepository) CreatePickList(ctx context.Context, deliveryID int64, req *domain.PickListRequest, userID *int64) (*domain.PickList, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var reservationID int64
+	err = tx.QueryRowContext(ctx, "SELECT event_id FROM scheduled_deliveries WHERE id = $1 FOR UPDATE", deliveryID).Scan(&reservationID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock scheduled delivery: %w", err)
+	}
+
+	var open bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pick_lists WHERE scheduled_delivery_id = $1 AND status = 'Open')", deliveryID).Scan(&open); err != nil {
+		return nil, fmt.Errorf("check open pick lists: %w", err)
+	}
+	if open {
+		return nil, fmt.Errorf("%w: delivery %d already has an open pick list", domain.ErrPickList, deliveryID)
+	}
+	if req.ShipmentID != nil {
+		if err := checkDeliveryShipment(ctx, tx, deliveryID, *req.ShipmentID); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, item_kind, item_id, quantity FROM scheduled_delivery_items WHERE scheduled_delivery_id = $1 ORDER BY id", deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("query delivery items: %w", err)
+	}
+	var demands []domain.Demand
+	for rows.Next() {
+		var d domain.Demand
+		if err := rows.Scan(&d.ID, &d.ItemKind, &d.ItemID, &d.Quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan delivery item: %w", err)
+		}
+		demands = append(demands, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(demands) == 0 {
+		return nil, fmt.Errorf("%w: delivery %d has no items", domain.ErrPickList, deliveryID)
+	}
+	expanded, err := r.expandDemands(ctx, tx, demands)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var listID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO pick_lists (scheduled_delivery_id, shipment_id, source_place_id, status, created_by_user_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		deliveryID, req.ShipmentID, req.SourcePlaceID, domain.PickListOpen, userID, now).Scan(&listID)
+	if err != nil {
+		return nil, fmt.Errorf("insert pick_list: %w", err)
+	}
+
+	// Kits sharing a component type on one item pick from a single line
+	type lineKey struct{ itemID, itemTypeID int64 }
+	lineIDs := make(map[lineKey]int64)
+	for _, l := range expanded {
+		key := lineKey{l.demandID, l.itemTypeID}
+		lineID, ok := lineIDs[key]
+		if ok {
+			if _, err := tx.ExecContext(ctx, "UPDATE pick_list_lines SET requested_quantity = requested_quantity + $1 WHERE id = $2", l.quantity, lineID); err != nil {
+				return nil, fmt.Errorf("update pick_list_line: %w", err)
+			}
+		} else {
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO pick_list_lines (pick_list_id, delivery_item_id, item_type_id, requested_quantity)
+				VALUES ($1, $2, $3, $4) RETURNING id`, listID, l.demandID, l.itemTypeID, l.quantity).Scan(&lineID)
+			if err != nil {
+				return nil, fmt.Errorf("insert pick_list_line: %w", err)
+			}
+			lineIDs[key] = lineID
+		}
+
+		// $1 is the source place for placeTree; without one the tree is empty and unused
+		_, err := tx.ExecContext(ctx, placeTree+`
+			INSERT INTO pick_tasks (pick_list_id, line_id, asset_id, place_id)
+			SELECT $4, $5, a.id, a.place_id FROM assets a
+			LEFT JOIN place_tree t ON t.id = a.place_id
+			WHERE a.item_type_id = $6 AND ($1::BIGINT IS NULL OR t.id IS NOT NULL) AND `+pickableAsset+`
+			ORDER BY EXISTS (
+				SELECT 1 FROM asset_holds h WHERE h.asset_id = a.id AND h.reservation_id = $2 AND h.status = 'active'
+			) DESC, a.place_id NULLS LAST, a.id
+			LIMIT $7`,
+			req.SourcePlaceID, reservationID, now, listID, lineID, l.itemTypeID, l.quantity)
+		if err != nil {
+			return nil, fmt.Errorf("assign assets to pick list: %w", err)
+		}
+	}
+
+	pl, err := loadPickList(ctx, tx, listID, false)
+	if err != nil {
+		return nil, err
+	}
+	return pl, tx.Commit()
+}
+
+// checkDeliveryShipment makes sure a shipment serves the scheduled delivery.
+func checkDeliveryShipment(ctx context.Context, tx *sql.Tx, deliveryID, shipmentID int64) error {
+	var shipmentDelivery sql.NullInt64
+	err := tx.QueryRowContext(ctx, "SELECT scheduled_delivery_id FROM shipments WHERE id = $1", shipmentID).Scan(&shipmentDelivery)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: shipment %d not found", domain.ErrPickList, shipmentID)
+	}
+	if err != nil {
+		return fmt.Errorf("check shipment: %w", err)
+	}
+	if !shipmentDelivery.Valid || shipmentDelivery.Int64 != deliveryID {
+		return fmt.Errorf("%w: shipment %d is not for delivery %d", domain.ErrPickList, shipmentID, deliveryID)
+	}
+	return nil
+}
+
+// GetPickList returns a pick list with its progress, or nil if it does not exist.
+func (r *SqlRepository) GetPickList(ctx context.Context, id int64) (*domain.PickList, error) {
+	return loadPickList(ctx, r.db, id, false)
+}
+
+// ListPickLists returns pick list headers, newest first, optionally for one delivery.
+func (r *SqlRepository) ListPickLists(ctx context.Context, deliveryID *int64) ([]domain.PickList, error) {
+	query := `SELECT ` + pickListColumns + ` FROM pick_lists`
+	var args []interface{}
+	if deliveryID != nil {
+		query += ` WHERE scheduled_delivery_id = $1`
+		args = append(args, *deliveryID)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query pick_lists: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.PickList{}
+	for rows.Next() {
+		pl, err := scanPickList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan pick_list: %w", err)
+		}
+		results = append(results, *pl)
+	}
+	return results, rows.Err()
+}
+
+// ScanPickList records a scanned asset tag or serial number against an open pick list.
+// Scanning an assigned asset ticks its task off. Scanning another pickable asset of a
+// line's type takes the place of one still unpicked, or fills a short. It returns
+// nil, nil when the pick list does not exist.
+func (r *SqlRepository) ScanPickList(ctx context.Context, id int64, code string, userID *int64) (*domain.PickList, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pl, err := loadPickList(ctx, tx, id, true)
+	if err != nil || pl == nil {
+		return nil, err
+	}
+	if pl.Status != domain.PickListOpen {
+		return nil, fmt.Errorf("%w: pick list %d is %s", domain.ErrPickList, id, pl.Status)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, item_type_id, place_id FROM assets WHERE asset_tag = $1 OR serial_number = $1 LIMIT 2", code)
+	if err != nil {
+		return nil, fmt.Errorf("look up scanned asset: %w", err)
+	}
+	var matches int
+	var assetID, itemTypeID int64
+	var placeID *int64
+	for rows.Next() {
+		matches++
+		if err := rows.Scan(&assetID, &itemTypeID, &placeID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan scanned asset: %w", err)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	switch {
+	case matches == 0:
+		return nil, fmt.Errorf("%w: no asset with tag or serial %q", domain.ErrPickList, code)
+	case matches > 1:
+		return nil, fmt.Errorf("%w: %q matches more than one asset", domain.ErrPickList, code)
+	}
+
+	now := time.Now()
+	var taskID int64
+	var line *domain.PickLine
+	for i := range pl.Lines {
+		for _, t := range pl.Lines[i].Tasks {
+			if t.AssetID != assetID {
+				continue
+			}
+			if t.PickedAt != nil {
+				return nil, fmt.Errorf("%w: asset %q is already picked", domain.ErrPickList, code)
+			}
+			taskID = t.ID
+		}
+		if taskID == 0 && line == nil && pl.Lines[i].ItemTypeID == itemTypeID && pl.Lines[i].Picked < pl.Lines[i].Requested {
+			line = &pl.Lines[i]
+		}
+	}
+
+	switch {
+	case taskID != 0:
+		_, err = tx.ExecContext(ctx, "UPDATE pick_tasks SET picked_at = $1, picked_by_user_id = $2 WHERE id = $3", now, userID, taskID)
+	case line == nil:
+		return nil, fmt.Errorf("%w: asset %q is not needed on pick list %d", domain.ErrPickList, code, id)
+	default:
+		var reservationID int64
+		if err := tx.QueryRowContext(ctx, "SELECT event_id FROM scheduled_deliveries WHERE id = $1", pl.ScheduledDeliveryID).Scan(&reservationID); err != nil {
+			return nil, fmt.Errorf("get delivery reservation: %w", err)
+		}
+		var pickable bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM assets a WHERE a.id = $1 AND `+pickableAsset+`)`,
+			assetID, reservationID, now).Scan(&pickable); err != nil {
+			return nil, fmt.Errorf("check scanned asset: %w", err)
+		}
+		if !pickable {
+			return nil, fmt.Errorf("%w: asset %q is not available to pick", domain.ErrPickList, code)
+		}
+
+		// Swap out an assigned asset nobody has picked yet; otherwise the line was short
+		var swap int64
+		for _, t := range line.Tasks {
+			if t.PickedAt == nil {
+				swap = t.ID
+			}
+		}
+		if swap != 0 {
+			_, err = tx.ExecContext(ctx, "UPDATE pick_tasks SET asset_id = $1, place_id = $2, picked_at = $3, picked_by_user_id = $4 WHERE id = $5",
+				assetID, placeID, now, userID, swap)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO pick_tasks (pick_list_id, line_id, asset_id, place_id, picked_at, picked_by_user_id)
+				VALUES ($1, $2, $3, $4, $5, $6)`, id, line.ID, assetID, placeID, now, userID)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("record pick: %w", err)
+	}
+
+	if pl, err = loadPickList(ctx, tx, id, false); err != nil {
+		return nil, err
+	}
+	return pl, tx.Commit()
+}
+
+// CompletePickList closes an open pick list and allocates its picked assets to the
+// shipment, as potential check-outs that go out when it ships. Unpicked assignments
+// are released and the lines left short are reported on the pick_list.completed
+// event. It returns nil, nil when the pick list does not exist.
+func (r *SqlRepository) CompletePickList(ctx context.Context, id int64, shipmentID *int64, agentID int64) (*domain.PickList, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pl, err := loadPickList(ctx, tx, id, true)
+	if err != nil || pl == nil {
+		return nil, err
+	}
+	if pl.Status != domain.PickListOpen {
+		return nil, fmt.Errorf("%w: pick list %d is %s", domain.ErrPickList, id, pl.Status)
+	}
+	if shipmentID == nil {
+		shipmentID = pl.ShipmentID
+	}
+	if shipmentID == nil {
+		return nil, fmt.Errorf("%w: a shipment is required to complete pick list %d", domain.ErrPickList, id)
+	}
+	if err := checkDeliveryShipment(ctx, tx, pl.ScheduledDeliveryID, *shipmentID); err != nil {
+		return nil, err
+	}
+	picked := pl.PickedAssetIDs()
+	if len(picked) == 0 {
+		return nil, fmt.Errorf("%w: nothing has been picked on pick list %d", domain.ErrPickList, id)
+	}
+
+	now := time.Now()
+	reservationID, err := allocateAssetsToShipment(ctx, tx, *shipmentID, picked, agentID, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pick_tasks WHERE pick_list_id = $1 AND picked_at IS NULL", id); err != nil {
+		return nil, fmt.Errorf("release unpicked tasks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE pick_lists SET status = $1, shipment_id = $2, completed_at = $3 WHERE id = $4",
+		domain.PickListCompleted, *shipmentID, now, id); err != nil {
+		return nil, fmt.Errorf("complete pick list: %w", err)
+	}
+
+	if pl, err = loadPickList(ctx, tx, id, false); err != nil {
+		return nil, err
+	}
+	var shorts []map[string]interface{}
+	for _, line := range pl.Shorts() {
+		shorts = append(shorts, map[string]interface{}{
+			"delivery_item_id": line.DeliveryItemID,
+			"item_type_id":     line.ItemTypeID,
+			"requested":        line.Requested,
+			"picked":           line.Picked,
+		})
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"pick_list_id":          id,
+		"scheduled_delivery_id": pl.ScheduledDeliveryID,
+		"shipment_id":           *shipmentID,
+		"asset_ids":             picked,
+		"shorts":                shorts,
+	})
+	if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: domain.EventPickListCompleted, Payload: payload}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.syncFulfillment(ctx, []int64{reservationID})
+	return pl, nil
+}
+
+// CancelPickList abandons an open pick list, freeing its assets for other lists. It
+// returns nil, nil when the pick list does not exist.
+func (r *SqlRepository) CancelPickList(ctx context.Context, id int64) (*domain.PickList, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pl, err := loadPickList(ctx, tx, id, true)
+	if err != nil || pl == nil {
+		return nil, err
+	}
+	if pl.Status != domain.PickListOpen {
+		return nil, fmt.Errorf("%w: pick list %d is %s", domain.ErrPickList, id, pl.Status)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE pick_lists SET status = $1 WHERE id = $2", domain.PickListCancelled, id); err != nil {
+		return nil, fmt.Errorf("cancel pick list: %w", err)
+	}
+	pl.Status = domain.PickListCancelled
+	return pl, tx.Commit()
+}
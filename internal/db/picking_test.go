@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pickTaskCols = []string{"id", "line_id", "asset_id", "asset_tag", "place_id", "picked_at", "picked_by_user_id"}
+
+// expectPickList expects loadPickList to read open pick list 5 for delivery 20, with
+// one line of two of item type 7 and the given tasks, stored at the given places.
+func expectPickList(mock sqlmock.Sqlmock, forUpdate bool, tasks *sqlmock.Rows, placeIDs string) {
+	query := "SELECT (.+) FROM pick_lists WHERE id = \\$1"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	mock.ExpectQuery(query).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scheduled_delivery_id", "shipment_id", "source_place_id", "status", "created_by_user_id", "created_at", "completed_at"}).
+			AddRow(5, 20, nil, nil, "Open", nil, time.Now(), nil))
+	mock.ExpectQuery("SELECT (.+) FROM pick_list_lines WHERE pick_list_id = \\$1").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pick_list_id", "delivery_item_id", "item_type_id", "requested_quantity"}).
+			AddRow(1, 5, 100, 7, 2))
+	mock.ExpectQuery("SELECT (.+) FROM pick_tasks pt JOIN assets a ON a.id = pt.asset_id").
+		WithArgs(5).
+		WillReturnRows(tasks)
+	mock.ExpectQuery("WITH RECURSIVE up AS").
+		WithArgs(placeIDs).
+		WillReturnRows(sqlmock.NewRows([]string{"leaf", "path"}).AddRow(3, "Warehouse / Shelf A").AddRow(4, "Warehouse / Shelf B"))
+}
+
+func TestSqlRepository_ScanPickList_SwapsUnpickedTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	userID := int64(1)
+
+	mock.ExpectBegin()
+	expectPickList(mock, true, sqlmock.NewRows(pickTaskCols).
+		AddRow(10, 1, 11, "MIC-1", 3, now, userID).
+		AddRow(11, 1, 12, "MIC-2", 3, nil, nil), "{3,3}")
+	// MIC-9 was not assigned, but is another mic free to pick
+	mock.ExpectQuery("SELECT id, item_type_id, place_id FROM assets WHERE asset_tag = \\$1 OR serial_number = \\$1").
+		WithArgs("MIC-9").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_type_id", "place_id"}).AddRow(19, 7, 4))
+	mock.ExpectQuery("SELECT event_id FROM scheduled_deliveries WHERE id = \\$1").
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id"}).AddRow(9))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM assets a WHERE a.id = \\$1 AND a.status IN").
+		WithArgs(19, 9, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	// It takes the place of MIC-2, which nobody has picked
+	mock.ExpectExec("UPDATE pick_tasks SET asset_id = \\$1, place_id = \\$2, picked_at = \\$3, picked_by_user_id = \\$4 WHERE id = \\$5").
+		WithArgs(19, 4, sqlmock.AnyArg(), userID, 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPickList(mock, false, sqlmock.NewRows(pickTaskCols).
+		AddRow(10, 1, 11, "MIC-1", 3, now, userID).
+		AddRow(11, 1, 19, "MIC-9", 4, now, userID), "{3,4}")
+	mock.ExpectCommit()
+
+	pl, err := repo.ScanPickList(ctx, 5, "MIC-9", &userID)
+	require.NoError(t, err)
+	require.Len(t, pl.Lines, 1)
+	assert.Equal(t, 2, pl.Lines[0].Picked)
+	assert.Equal(t, 0, pl.Lines[0].Short)
+	assert.Equal(t, "Warehouse / Shelf B", pl.Lines[0].Tasks[1].PlacePath)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_ScanPickList_NotPickable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	expectPickList(mock, true, sqlmock.NewRows(pickTaskCols).
+		AddRow(10, 1, 11, "MIC-1", 3, nil, nil), "{3}")
+	// MIC-8 is held for another reservation
+	mock.ExpectQuery("SELECT id, item_type_id, place_id FROM assets WHERE asset_tag = \\$1 OR serial_number = \\$1").
+		WithArgs("MIC-8").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_type_id", "place_id"}).AddRow(18, 7, 4))
+	mock.ExpectQuery("SELECT event_id FROM scheduled_deliveries WHERE id = \\$1").
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id"}).AddRow(9))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM assets a WHERE a.id = \\$1 AND a.status IN").
+		WithArgs(18, 9, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	pl, err := repo.ScanPickList(ctx, 5, "MIC-8", nil)
+	assert.Nil(t, pl)
+	assert.True(t, errors.Is(err, domain.ErrPickList))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,273 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const rateCardColumns = `id, item_type_id, company_id, currency, daily_rate, weekly_rate, monthly_rate, minimum_charge, weekend_rule, charge_buffers, late_fee_per_day, late_grace_minutes, is_active, COALESCE(notes, ''), created_at, updated_at`
+
+func scanRateCard(scanner interface{ Scan(...any) error }) (*domain.RateCard, error) {
+	var rc domain.RateCard
+	err := scanner.Scan(&rc.ID, &rc.ItemTypeID, &rc.CompanyID, &rc.Currency, &rc.DailyRate, &rc.WeeklyRate, &rc.MonthlyRate,
+		&rc.MinimumCharge, &rc.WeekendRule, &rc.ChargeBuffers, &rc.LateFeePerDay, &rc.LateGraceMinutes, &rc.IsActive, &rc.Notes, &rc.CreatedAt, &rc.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// CreateRateCard stores a new rate card.
+func (r *SqlRepository) CreateRateCard(ctx context.Context, rc *domain.RateCard) error {
+	now := time.Now()
+	rc.CreatedAt = now
+	rc.UpdatedAt = now
+	query := `INSERT INTO rate_cards (item_type_id, company_id, currency, daily_rate, weekly_rate, monthly_rate, minimum_charge, weekend_rule,
+	                                  charge_buffers, late_fee_per_day, late_grace_minutes, is_active, notes, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, rc.ItemTypeID, rc.CompanyID, rc.Currency, rc.DailyRate, rc.WeeklyRate, rc.MonthlyRate,
+		rc.MinimumCharge, rc.WeekendRule, rc.ChargeBuffers, rc.LateFeePerDay, rc.LateGraceMinutes, rc.IsActive, rc.Notes, rc.CreatedAt, rc.UpdatedAt).Scan(&rc.ID)
+	if err != nil {
+		return fmt.Errorf("insert rate_card: %w", err)
+	}
+	return nil
+}
+
+// GetRateCard returns one rate card, or nil if it does not exist.
+func (r *SqlRepository) GetRateCard(ctx context.Context, id int64) (*domain.RateCard, error) {
+	rc, err := scanRateCard(r.db.QueryRowContext(ctx, `SELECT `+rateCardColumns+` FROM rate_cards WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rate_card: %w", err)
+	}
+	return rc, nil
+}
+
+// ListRateCards returns all rate cards, or those of one item type when itemTypeID is set.
+func (r *SqlRepository) ListRateCards(ctx context.Context, itemTypeID *int64) ([]domain.RateCard, error) {
+	query := `SELECT ` + rateCardColumns + ` FROM rate_cards`
+	var args []any
+	if itemTypeID != nil {
+		query += ` WHERE item_type_id = $1`
+		args = append(args, *itemTypeID)
+	}
+	query += ` ORDER BY item_type_id, company_id NULLS FIRST, id`
+	return queryRateCards(ctx, r.db, query, args...)
+}
+
+// UpdateRateCard overwrites a rate card. Quotes already stored keep the prices they
+// were made with.
+func (r *SqlRepository) UpdateRateCard(ctx context.Context, rc *domain.RateCard) error {
+	rc.UpdatedAt = time.Now()
+	query := `UPDATE rate_cards SET item_type_id = $1, company_id = $2, currency = $3, daily_rate = $4, weekly_rate = $5, monthly_rate = $6,
+	                 minimum_charge = $7, weekend_rule = $8, charge_buffers = $9, late_fee_per_day = $10, late_grace_minutes = $11,
+	                 is_active = $12, notes = $13, updated_at = $14
+	          WHERE id = $15`
+	_, err := r.db.ExecContext(ctx, query, rc.ItemTypeID, rc.CompanyID, rc.Currency, rc.DailyRate, rc.WeeklyRate, rc.MonthlyRate,
+		rc.MinimumCharge, rc.WeekendRule, rc.ChargeBuffers, rc.LateFeePerDay, rc.LateGraceMinutes, rc.IsActive, rc.Notes, rc.UpdatedAt, rc.ID)
+	if err != nil {
+		return fmt.Errorf("update rate_card: %w", err)
+	}
+	return nil
+}
+
+// DeleteRateCard removes a rate card.
+func (r *SqlRepository) DeleteRateCard(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM rate_cards WHERE id = $1", id); err != nil {
+		return fmt.Errorf("delete rate_card: %w", err)
+	}
+	return nil
+}
+
+func queryRateCards(ctx context.Context, q queryer, query string, args ...any) ([]domain.RateCard, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list rate_cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := []domain.RateCard{}
+	for rows.Next() {
+		rc, err := scanRateCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan rate_card: %w", err)
+		}
+		cards = append(cards, *rc)
+	}
+	return cards, nil
+}
+
+// QuoteReservation prices a reservation, which need not be stored, without saving the
+// quote. Prices follow companyID's rate cards, or those of the company the
+// reservation's customer belongs to when companyID is nil.
+func (r *SqlRepository) QuoteReservation(ctx context.Context, rr *domain.RentalReservation, companyID *int64) (*domain.Quote, error) {
+	return r.quoteReservation(ctx, r.db, rr, companyID, time.Now())
+}
+
+// GetReservationQuote returns the quote stored when the reservation was approved, or
+// a fresh quote at today's rates if it has none. It returns nil, nil when the
+// reservation does not exist.
+func (r *SqlRepository) GetReservationQuote(ctx context.Context, reservationID int64) (*domain.Quote, error) {
+	q, err := storedQuote(ctx, r.db, reservationID)
+	if err != nil || q != nil {
+		return q, err
+	}
+
+	rr, err := getRentalReservation(ctx, r.db, reservationID, false)
+	if err != nil || rr == nil {
+		return nil, err
+	}
+	return r.quoteReservation(ctx, r.db, rr, nil, time.Now())
+}
+
+// snapshotReservationQuote prices a reservation inside tx and stores the quote, so the
+// prices agreed at approval survive later rate card changes.
+func (r *SqlRepository) snapshotReservationQuote(ctx context.Context, tx *sql.Tx, id int64, now time.Time) error {
+	rr := &domain.RentalReservation{ID: id}
+	err := tx.QueryRowContext(ctx, "SELECT start_time, end_time, under_name_id FROM rental_reservations WHERE id = $1", id).
+		Scan(&rr.StartTime, &rr.EndTime, &rr.UnderNameID)
+	if err != nil {
+		return fmt.Errorf("get reservation for quote: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT id, item_kind, item_id, requested_quantity FROM demands WHERE reservation_id = $1 ORDER BY id", id)
+	if err != nil {
+		return fmt.Errorf("query demands for quote: %w", err)
+	}
+	for rows.Next() {
+		var d domain.Demand
+		if err := rows.Scan(&d.ID, &d.ItemKind, &d.ItemID, &d.Quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan demand for quote: %w", err)
+		}
+		rr.Demands = append(rr.Demands, d)
+	}
+	rows.Close()
+
+	q, err := r.quoteReservation(ctx, tx, rr, nil, now)
+	if err != nil {
+		return err
+	}
+	quoteJSON, err := json.Marshal(q)
+	if err != nil {
+		return fmt.Errorf("encode quote: %w", err)
+	}
+	var currency interface{}
+	if q.Currency != "" {
+		currency = q.Currency
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO reservation_quotes (reservation_id, currency, total, quote, created_at) VALUES ($1, $2, $3, $4, $5)",
+		id, currency, q.Total, quoteJSON, now)
+	if err != nil {
+		return fmt.Errorf("insert reservation_quote: %w", err)
+	}
+	return nil
+}
+
+// quoteReservation loads the rate cards, item type buffers and kit templates rr's
+// demands need and prices it.
+func (r *SqlRepository) quoteReservation(ctx context.Context, q queryer, rr *domain.RentalReservation, companyID *int64, now time.Time) (*domain.Quote, error) {
+	if companyID == nil {
+		var err error
+		if companyID, err = customerCompany(ctx, q, rr.UnderNameID, now); err != nil {
+			return nil, err
+		}
+	}
+	in := domain.PricingInput{
+		CompanyID: companyID,
+		ItemTypes: make(map[int64]*domain.ItemType),
+		Kits:      make(map[int64]*domain.KitTemplate),
+		Now:       now,
+	}
+
+	var itemTypeIDs, kitIDs []int64
+	for _, d := range rr.Demands {
+		switch d.ItemKind {
+		case domain.DemandKindItemType:
+			itemTypeIDs = append(itemTypeIDs, d.ItemID)
+		case domain.DemandKindKitTemplate:
+			kitIDs = append(kitIDs, d.ItemID)
+		}
+	}
+
+	if len(kitIDs) > 0 {
+		rows, err := q.QueryContext(ctx, "SELECT id, item_type_id FROM kit_templates WHERE id = ANY($1)", pq.Array(kitIDs))
+		if err != nil {
+			return nil, fmt.Errorf("query kit_templates for quote: %w", err)
+		}
+		for rows.Next() {
+			var kt domain.KitTemplate
+			if err := rows.Scan(&kt.ID, &kt.ItemTypeID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan kit_template for quote: %w", err)
+			}
+			in.Kits[kt.ID] = &kt
+			if kt.ItemTypeID != nil {
+				itemTypeIDs = append(itemTypeIDs, *kt.ItemTypeID)
+			}
+		}
+		rows.Close()
+
+		components, err := r.listKitComponents(ctx, q, kitIDs)
+		if err != nil {
+			return nil, err
+		}
+		for kitID, kt := range in.Kits {
+			kt.Components = components[kitID]
+			for _, c := range kt.Components {
+				itemTypeIDs = append(itemTypeIDs, c.ItemTypeID)
+			}
+		}
+	}
+
+	if len(itemTypeIDs) > 0 {
+		cards, err := queryRateCards(ctx, q, `SELECT `+rateCardColumns+` FROM rate_cards
+		                                      WHERE is_active AND item_type_id = ANY($1) AND (company_id IS NULL OR company_id = $2)`,
+			pq.Array(itemTypeIDs), companyID)
+		if err != nil {
+			return nil, err
+		}
+		in.RateCards = cards
+
+		rows, err := q.QueryContext(ctx, "SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types WHERE id = ANY($1)", pq.Array(itemTypeIDs))
+		if err != nil {
+			return nil, fmt.Errorf("query item_type buffers: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var it domain.ItemType
+			if err := rows.Scan(&it.ID, &it.PreBufferMinutes, &it.PostBufferMinutes); err != nil {
+				return nil, fmt.Errorf("scan item_type buffers: %w", err)
+			}
+			in.ItemTypes[it.ID] = &it
+		}
+	}
+
+	return domain.PriceReservation(rr, in), nil
+}
+
+// customerCompany returns the company the person a reservation is under currently
+// works for, which decides whose rate cards apply.
+func customerCompany(ctx context.Context, q queryer, underNameID *int64, now time.Time) (*int64, error) {
+	if underNameID == nil {
+		return nil, nil
+	}
+	var orgID int64
+	err := q.QueryRowContext(ctx, `SELECT organization_id FROM organization_roles
+	                               WHERE person_id = $1 AND (end_date IS NULL OR end_date > $2) ORDER BY id LIMIT 1`,
+		*underNameID, now).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get customer company: %w", err)
+	}
+	return &orgID, nil
+}
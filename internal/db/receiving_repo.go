@@ -0,0 +1,388 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+const receivingSessionColumns = `id, shipment_id, reservation_id, to_place_id, status, report, opened_by_user_id, created_at, closed_at`
+
+func scanReceivingSession(scanner interface{ Scan(...any) error }) (*domain.ReceivingSession, []byte, error) {
+	var s domain.ReceivingSession
+	var report []byte
+	err := scanner.Scan(&s.ID, &s.ShipmentID, &s.ReservationID, &s.ToPlaceID, &s.Status, &report, &s.OpenedByUserID, &s.CreatedAt, &s.ClosedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &s, report, nil
+}
+
+// expectedReturns lists the assets a reservation still has out.
+func expectedReturns(ctx context.Context, q queryer, reservationID int64) ([]domain.ReceivingAsset, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT DISTINCT a.id, COALESCE(a.asset_tag, ''), a.item_type_id
+		FROM check_out_actions co JOIN assets a ON a.id = co.asset_id
+		WHERE co.reservation_id = $1 AND `+outstandingCheckOut+`
+		ORDER BY 2, 1`, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("query outstanding check-outs: %w", err)
+	}
+	defer rows.Close()
+
+	var assets []domain.ReceivingAsset
+	for rows.Next() {
+		var a domain.ReceivingAsset
+		if err := rows.Scan(&a.AssetID, &a.AssetTag, &a.ItemTypeID); err != nil {
+			return nil, fmt.Errorf("scan outstanding check-out: %w", err)
+		}
+		assets = append(assets, a)
+	}
+	return assets, rows.Err()
+}
+
+// loadReceivingSession reads a session with its scans and report: the stored one once
+// closed, otherwise a running one against what is still out.
+func loadReceivingSession(ctx context.Context, q queryer, id int64, forUpdate bool) (*domain.ReceivingSession, error) {
+	query := `SELECT ` + receivingSessionColumns + ` FROM receiving_sessions WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	s, report, err := scanReceivingSession(q.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query receiving session: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT rs.id, rs.code, rs.asset_id, COALESCE(a.asset_tag, ''), a.item_type_id, rs.outcome, rs.reservation_id, rs.scanned_at, rs.scanned_by_user_id
+		FROM receiving_scans rs LEFT JOIN assets a ON a.id = rs.asset_id
+		WHERE rs.session_id = $1 ORDER BY rs.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query receiving scans: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sc domain.ReceivingScan
+		if err := rows.Scan(&sc.ID, &sc.Code, &sc.AssetID, &sc.AssetTag, &sc.ItemTypeID, &sc.Outcome, &sc.ReservationID, &sc.ScannedAt, &sc.ScannedByUserID); err != nil {
+			return nil, fmt.Errorf("scan receiving scan: %w", err)
+		}
+		s.Scans = append(s.Scans, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if report != nil {
+		s.Report = &domain.DiscrepancyReport{}
+		if err := json.Unmarshal(report, s.Report); err != nil {
+			return nil, fmt.Errorf("decode discrepancy report: %w", err)
+		}
+		return s, nil
+	}
+	expected, err := expectedReturns(ctx, q, s.ReservationID)
+	if err != nil {
+		return nil, err
+	}
+	s.Report = domain.BuildDiscrepancyReport(expected, s.Scans)
+	return s, nil
+}
+
+// OpenReceivingSession starts receiving an inbound shipment against a reservation,
+// by default the one its scheduled delivery is for. It returns nil, nil when the
+// shipment does not exist.
+func (r *SqlRepository) OpenReceivingSession(ctx context.Context, shipmentID int64, req *domain.ReceivingSessionRequest, userID *int64) (*domain.ReceivingSession, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var direction string
+	var deliveryReservation sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT s.direction, sd.event_id FROM shipments s
+		LEFT JOIN scheduled_deliveries sd ON sd.id = s.scheduled_delivery_id
+		WHERE s.id = $1 FOR UPDATE OF s`, shipmentID).Scan(&direction, &deliveryReservation)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock shipment: %w", err)
+	}
+	if direction != "inbound" {
+		return nil, fmt.Errorf("%w: shipment %d is not inbound", domain.ErrReceiving, shipmentID)
+	}
+
+	reservationID := req.ReservationID
+	if reservationID == nil && deliveryReservation.Valid {
+		reservationID = &deliveryReservation.Int64
+	}
+	if reservationID == nil {
+		return nil, fmt.Errorf("%w: shipment %d has no scheduled delivery; give a reservationId", domain.ErrReceiving, shipmentID)
+	}
+
+	var open bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM receiving_sessions WHERE shipment_id = $1 AND status = 'Open')", shipmentID).Scan(&open); err != nil {
+		return nil, fmt.Errorf("check open receiving sessions: %w", err)
+	}
+	if open {
+		return nil, fmt.Errorf("%w: shipment %d is already being received", domain.ErrReceiving, shipmentID)
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO receiving_sessions (shipment_id, reservation_id, to_place_id, status, opened_by_user_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		shipmentID, *reservationID, req.ToPlaceID, domain.ReceivingOpen, userID, time.Now()).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("insert receiving session: %w", err)
+	}
+
+	s, err := loadReceivingSession(ctx, tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	return s, tx.Commit()
+}
+
+// GetReceivingSession returns a session with its scans and report, or nil if it does
+// not exist.
+func (r *SqlRepository) GetReceivingSession(ctx context.Context, id int64) (*domain.ReceivingSession, error) {
+	return loadReceivingSession(ctx, r.db, id, false)
+}
+
+// ScanReceiving records a tag read off the dock. A container tag receives everything
+// packed in it. Each asset is classed against what the session's reservation has out.
+// It returns nil, nil when the session does not exist.
+func (r *SqlRepository) ScanReceiving(ctx context.Context, id int64, code string, userID *int64) (*domain.ReceivingSession, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := loadReceivingSession(ctx, tx, id, true)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if s.Status != domain.ReceivingOpen {
+		return nil, fmt.Errorf("%w: receiving session %d is closed", domain.ErrReceiving, id)
+	}
+
+	var assetIDs []int64
+	var containerID int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM containers WHERE tag = $1", code).Scan(&containerID)
+	switch {
+	case err == nil:
+		if assetIDs, err = containerAssetIDs(ctx, tx, []int64{containerID}); err != nil {
+			return nil, err
+		}
+		if len(assetIDs) == 0 {
+			return nil, fmt.Errorf("%w: container %q is empty", domain.ErrReceiving, code)
+		}
+	case err == sql.ErrNoRows:
+		rows, err := tx.QueryContext(ctx, "SELECT id FROM assets WHERE asset_tag = $1 OR serial_number = $1 LIMIT 2", code)
+		if err != nil {
+			return nil, fmt.Errorf("look up scanned asset: %w", err)
+		}
+		for rows.Next() {
+			var assetID int64
+			if err := rows.Scan(&assetID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan scanned asset: %w", err)
+			}
+			assetIDs = append(assetIDs, assetID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(assetIDs) > 1 {
+			return nil, fmt.Errorf("%w: %q matches more than one asset", domain.ErrReceiving, code)
+		}
+	default:
+		return nil, fmt.Errorf("look up scanned container: %w", err)
+	}
+
+	scanned := make(map[int64]bool)
+	for _, sc := range s.Scans {
+		if sc.AssetID != nil {
+			scanned[*sc.AssetID] = true
+		}
+	}
+	if len(assetIDs) == 1 && scanned[assetIDs[0]] {
+		return nil, fmt.Errorf("%w: %q was already received", domain.ErrReceiving, code)
+	}
+
+	now := time.Now()
+	insert := `INSERT INTO receiving_scans (session_id, code, asset_id, outcome, reservation_id, scanned_at, scanned_by_user_id)
+	           VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if len(assetIDs) == 0 {
+		if _, err := tx.ExecContext(ctx, insert, id, code, nil, domain.ReceivedUnknown, nil, now, userID); err != nil {
+			return nil, fmt.Errorf("record receiving scan: %w", err)
+		}
+	}
+	for _, assetID := range assetIDs {
+		if scanned[assetID] {
+			continue // Scanned on its own before its case came through
+		}
+		var outOn sql.NullInt64
+		err := tx.QueryRowContext(ctx, `
+			SELECT co.reservation_id FROM check_out_actions co
+			WHERE co.asset_id = $1 AND `+outstandingCheckOut+`
+			ORDER BY co.reservation_id = $2 DESC, co.start_time DESC LIMIT 1`, assetID, s.ReservationID).Scan(&outOn)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("find outstanding check-out: %w", err)
+		}
+
+		outcome, reservationID := domain.ReceivedExtra, (*int64)(nil)
+		switch {
+		case outOn.Valid && outOn.Int64 == s.ReservationID:
+			outcome = domain.ReceivedExpected
+		case outOn.Valid:
+			outcome, reservationID = domain.ReceivedWrongReservation, &outOn.Int64
+		}
+		if _, err := tx.ExecContext(ctx, insert, id, code, assetID, outcome, reservationID, now, userID); err != nil {
+			return nil, fmt.Errorf("record receiving scan: %w", err)
+		}
+	}
+
+	if s, err = loadReceivingSession(ctx, tx, id, false); err != nil {
+		return nil, err
+	}
+	return s, tx.Commit()
+}
+
+// CloseReceivingSession returns the assets received against the reservation, routing
+// each to maintenance when its item type has inspections due and to available stock
+// otherwise, and stores the discrepancy report. Missing, extra and wrong-reservation
+// assets are reported only; nothing is returned on their behalf. The shipment is
+// marked delivered when it is in transit. It returns nil, nil when the session does
+// not exist.
+func (r *SqlRepository) CloseReceivingSession(ctx context.Context, id int64, agentID int64) (*domain.ReceivingSession, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := loadReceivingSession(ctx, tx, id, true)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if s.Status != domain.ReceivingOpen {
+		return nil, fmt.Errorf("%w: receiving session %d is already closed", domain.ErrReceiving, id)
+	}
+
+	now := time.Now()
+	report := s.Report
+	routes := make(map[int64]domain.AssetStatus)
+	inspections := make(map[int64][]string)
+	for i := range report.Received {
+		a := &report.Received[i]
+		if _, ok := routes[a.ItemTypeID]; !ok {
+			templates, err := r.GetInspectionTemplatesForItemType(ctx, a.ItemTypeID)
+			if err != nil {
+				return nil, fmt.Errorf("get inspections for item type %d: %w", a.ItemTypeID, err)
+			}
+			routes[a.ItemTypeID], inspections[a.ItemTypeID] = domain.RouteReturn(templates)
+		}
+		a.RoutedStatus, a.Inspections = routes[a.ItemTypeID], inspections[a.ItemTypeID]
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO return_actions (reservation_id, asset_id, agent_id, shipment_id, start_time, to_location_id, action_status)
+			VALUES ($1, $2, $3, $4, $5, $6, 'Completed')`,
+			s.ReservationID, a.AssetID, agentID, s.ShipmentID, now, s.ToPlaceID); err != nil {
+			return nil, fmt.Errorf("return %d: %w", a.AssetID, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE assets SET status = $1, place_id = COALESCE($2, place_id), updated_at = $3 WHERE id = $4",
+			a.RoutedStatus, s.ToPlaceID, now, a.AssetID); err != nil {
+			return nil, fmt.Errorf("update asset %d: %w", a.AssetID, err)
+		}
+	}
+	if err := closeReturnedKits(ctx, tx, s.ReservationID, now); err != nil {
+		return nil, err
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("encode discrepancy report: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE receiving_sessions SET status = $1, report = $2, closed_at = $3 WHERE id = $4",
+		domain.ReceivingClosed, reportJSON, now, id); err != nil {
+		return nil, fmt.Errorf("close receiving session: %w", err)
+	}
+
+	reservations := []int64{s.ReservationID}
+	var status domain.DeliveryStatus
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM shipments WHERE id = $1 FOR UPDATE", s.ShipmentID).Scan(&status); err != nil {
+		return nil, fmt.Errorf("lock shipment: %w", err)
+	}
+	if domain.ValidateShipmentTransition(status, domain.DeliveryDelivered) == nil {
+		delivered, err := r.transitionShipment(ctx, tx, s.ShipmentID, status, domain.DeliveryDelivered, fmt.Sprintf("received in session %d", id), now)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, delivered...)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"session_id":        id,
+		"shipment_id":       s.ShipmentID,
+		"reservation_id":    s.ReservationID,
+		"expected":          report.ExpectedCount,
+		"received":          report.ReceivedCount,
+		"missing":           len(report.Missing),
+		"wrong_reservation": len(report.WrongReservation),
+		"extra":             len(report.Extra),
+		"unknown":           len(report.Unknown),
+		"clean":             report.Clean(),
+	})
+	if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: domain.EventReceivingClosed, Payload: payload}); err != nil {
+		return nil, err
+	}
+
+	if s, err = loadReceivingSession(ctx, tx, id, false); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.syncFulfillment(ctx, reservations)
+	return s, nil
+}
+
+// ListReceivingSessions returns a shipment's receiving sessions, newest first, without
+// their scans.
+func (r *SqlRepository) ListReceivingSessions(ctx context.Context, shipmentID int64) ([]domain.ReceivingSession, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+receivingSessionColumns+` FROM receiving_sessions
+		WHERE shipment_id = $1 ORDER BY created_at DESC, id DESC`, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("query receiving sessions: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.ReceivingSession{}
+	for rows.Next() {
+		s, report, err := scanReceivingSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan receiving session: %w", err)
+		}
+		if report != nil {
+			s.Report = &domain.DiscrepancyReport{}
+			if err := json.Unmarshal(report, s.Report); err != nil {
+				return nil, fmt.Errorf("decode discrepancy report: %w", err)
+			}
+		}
+		results = append(results, *s)
+	}
+	return results, rows.Err()
+}
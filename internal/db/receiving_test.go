@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var receivingScanCols = []string{"id", "code", "asset_id", "asset_tag", "item_type_id", "outcome", "reservation_id", "scanned_at", "scanned_by_user_id"}
+
+// expectReceivingSession expects loadReceivingSession to read open session 5, receiving
+// shipment 42 against reservation 9, which has MIC-1 and MIC-2 out.
+func expectReceivingSession(mock sqlmock.Sqlmock, forUpdate bool, scans *sqlmock.Rows) {
+	query := "SELECT (.+) FROM receiving_sessions WHERE id = \\$1"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	mock.ExpectQuery(query).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "shipment_id", "reservation_id", "to_place_id", "status", "report", "opened_by_user_id", "created_at", "closed_at"}).
+			AddRow(5, 42, 9, nil, "Open", nil, nil, time.Now(), nil))
+	mock.ExpectQuery("SELECT (.+) FROM receiving_scans rs LEFT JOIN assets a").
+		WithArgs(5).
+		WillReturnRows(scans)
+	mock.ExpectQuery("SELECT DISTINCT a.id, (.+) FROM check_out_actions co JOIN assets a").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "asset_tag", "item_type_id"}).
+			AddRow(11, "MIC-1", 7).
+			AddRow(12, "MIC-2", 7))
+}
+
+func TestSqlRepository_ScanReceiving_Container(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	// MIC-1 came through on its own before its case
+	mock.ExpectBegin()
+	expectReceivingSession(mock, true, sqlmock.NewRows(receivingScanCols).
+		AddRow(1, "MIC-1", 11, "MIC-1", 7, "expected", nil, now, nil))
+	mock.ExpectQuery("SELECT id FROM containers WHERE tag = \\$1").
+		WithArgs("CASE-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("WITH RECURSIVE container_tree (.+) SELECT a.id FROM assets a").
+		WithArgs("{3}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11).AddRow(12).AddRow(13).AddRow(14))
+
+	// MIC-2 is out on the reservation, MIC-3 on reservation 8, and MIC-4 not out at all
+	for _, a := range []struct {
+		assetID  int
+		outOn    *sqlmock.Rows
+		outcome  domain.ReceivingOutcome
+		wrongRes interface{}
+	}{
+		{12, sqlmock.NewRows([]string{"reservation_id"}).AddRow(9), domain.ReceivedExpected, nil},
+		{13, sqlmock.NewRows([]string{"reservation_id"}).AddRow(8), domain.ReceivedWrongReservation, int64(8)},
+		{14, sqlmock.NewRows([]string{"reservation_id"}), domain.ReceivedExtra, nil},
+	} {
+		mock.ExpectQuery("SELECT co.reservation_id FROM check_out_actions co WHERE co.asset_id = \\$1").
+			WithArgs(a.assetID, 9).
+			WillReturnRows(a.outOn)
+		mock.ExpectExec("INSERT INTO receiving_scans").
+			WithArgs(5, "CASE-1", a.assetID, a.outcome, a.wrongRes, sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	expectReceivingSession(mock, false, sqlmock.NewRows(receivingScanCols).
+		AddRow(1, "MIC-1", 11, "MIC-1", 7, "expected", nil, now, nil).
+		AddRow(2, "CASE-1", 12, "MIC-2", 7, "expected", nil, now, nil).
+		AddRow(3, "CASE-1", 13, "MIC-3", 7, "wrong_reservation", 8, now, nil).
+		AddRow(4, "CASE-1", 14, "MIC-4", 7, "extra", nil, now, nil))
+	mock.ExpectCommit()
+
+	s, err := repo.ScanReceiving(ctx, 5, "CASE-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Report.ReceivedCount)
+	assert.Empty(t, s.Report.Missing)
+	require.Len(t, s.Report.WrongReservation, 1)
+	assert.Equal(t, "MIC-3", s.Report.WrongReservation[0].AssetTag)
+	require.Len(t, s.Report.Extra, 1)
+	assert.Equal(t, "MIC-4", s.Report.Extra[0].AssetTag)
+	assert.False(t, s.Report.Clean())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -36,6 +36,21 @@ type Repository interface {
 	ListRentalReservations(ctx context.Context) ([]domain.RentalReservation, error)
 	UpdateRentalReservation(ctx context.Context, rr *domain.RentalReservation) error
 	UpdateRentalReservationStatus(ctx context.Context, id int64, status domain.RentalReservationStatus) error
+	ApproveRentalReservation(ctx context.Context, id int64, userID *int64) (*domain.AllocationResult, error)
+	ModifyRentalReservation(ctx context.Context, id int64, patch *domain.ReservationPatch, userID *int64) (*domain.ReservationChangeResult, error)
+	ListReservationChanges(ctx context.Context, reservationID int64) ([]domain.ReservationChange, error)
+
+	// Reservation State Machine
+	TransitionRentalReservation(ctx context.Context, id int64, req *domain.ReservationTransitionRequest, userID *int64) (*domain.ReservationTransitionRecord, error)
+	GetReservationLifecycle(ctx context.Context, id int64) (*domain.ReservationLifecycle, error)
+
+	// Reservation Series
+	CreateReservationSeries(ctx context.Context, s *domain.ReservationSeries) error
+	GetReservationSeries(ctx context.Context, id int64) (*domain.ReservationSeries, error)
+	ListReservationSeries(ctx context.Context) ([]domain.ReservationSeries, error)
+	ApproveReservationSeries(ctx context.Context, id int64, userID *int64) (*domain.SeriesApproval, error)
+	CheckSeriesAvailability(ctx context.Context, id int64) ([]domain.OccurrenceShortfall, error)
+	EditReservationSeries(ctx context.Context, id int64, edit *domain.SeriesEdit, userID *int64) (*domain.SeriesEditResult, error)
 
 	CreateDemand(ctx context.Context, d *domain.Demand) error
 	ListDemandsByReservation(ctx context.Context, reservationID int64) ([]domain.Demand, error)
@@ -53,6 +68,78 @@ type Repository interface {
 
 	// Inventory/Availability
 	GetAvailableQuantity(ctx context.Context, itemTypeID int64, startTime, endTime time.Time) (int, error)
+	GetKitAvailableQuantity(ctx context.Context, kitTemplateID int64, startTime, endTime time.Time) (int, error)
+
+	// Allocation Holds
+	AllocateReservation(ctx context.Context, reservationID int64, userID *int64) (*domain.AllocationResult, error)
+	ListAssetHolds(ctx context.Context, reservationID int64) ([]domain.AssetHold, error)
+	ReallocateHold(ctx context.Context, reservationID, holdID int64, assetID *int64, userID *int64) (*domain.AssetHold, error)
+	SwapHolds(ctx context.Context, reservationID, holdID, otherHoldID int64) error
+	ReleaseReservationHolds(ctx context.Context, reservationID int64) error
+	ApplySubstitution(ctx context.Context, reservationID, demandID, itemTypeID, substituteItemTypeID int64, quantity int, userID *int64) (*domain.AllocationResult, error)
+
+	// Waitlist
+	CheckReservationAvailability(ctx context.Context, id int64) ([]domain.Shortfall, error)
+	EnqueueWaitlist(ctx context.Context, reservationID int64, shortfalls []domain.Shortfall, priority int, autoApprove bool, userID *int64) ([]domain.WaitlistEntry, error)
+	ListWaitlist(ctx context.Context, itemTypeID *int64) ([]domain.WaitlistEntry, error)
+	UpdateWaitlistPriority(ctx context.Context, id int64, priority int) error
+	SetWaitlistStatus(ctx context.Context, ids []int64, status domain.WaitlistStatus) error
+
+	// Conflicts
+	GetReservationConflicts(ctx context.Context, id int64, horizon time.Duration) (*domain.ReservationConflicts, error)
+	FindEarliestSlot(ctx context.Context, req *domain.ScheduleRequest) (*domain.ScheduleResult, error)
+
+	// Kit Templates
+	CreateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error
+	GetKitTemplate(ctx context.Context, id int64) (*domain.KitTemplate, error)
+	ListKitTemplates(ctx context.Context) ([]domain.KitTemplate, error)
+	UpdateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error
+	DeleteKitTemplate(ctx context.Context, id int64) error
+	CheckOutKit(ctx context.Context, reservationID, demandID int64, assetIDs []int64, agentID int64, fromLocationID, toLocationID *int64) (*domain.KitInstance, error)
+	ListKitInstances(ctx context.Context, reservationID int64) ([]domain.KitInstance, error)
+
+	// Substitution Rules
+	CreateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error
+	GetSubstitutionRule(ctx context.Context, id int64) (*domain.SubstitutionRule, error)
+	ListSubstitutionRules(ctx context.Context, itemTypeID *int64) ([]domain.SubstitutionRule, error)
+	UpdateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error
+	DeleteSubstitutionRule(ctx context.Context, id int64) error
+
+	// Pricing
+	CreateRateCard(ctx context.Context, rc *domain.RateCard) error
+	GetRateCard(ctx context.Context, id int64) (*domain.RateCard, error)
+	ListRateCards(ctx context.Context, itemTypeID *int64) ([]domain.RateCard, error)
+	UpdateRateCard(ctx context.Context, rc *domain.RateCard) error
+	DeleteRateCard(ctx context.Context, id int64) error
+	QuoteReservation(ctx context.Context, rr *domain.RentalReservation, companyID *int64) (*domain.Quote, error)
+	GetReservationQuote(ctx context.Context, reservationID int64) (*domain.Quote, error)
+
+	// Invoicing
+	CreateInvoice(ctx context.Context, reservationID int64, req *domain.InvoiceRequest, userID *int64) (*domain.Invoice, error)
+	GetInvoice(ctx context.Context, id int64) (*domain.Invoice, error)
+	ListInvoices(ctx context.Context, reservationID int64) ([]domain.Invoice, error)
+	ListReservationsDueForInvoice(ctx context.Context, before time.Time) ([]int64, error)
+
+	// Overdue Rentals
+	ListOverdueAssets(ctx context.Context, now time.Time) ([]domain.OverdueAsset, error)
+	EscalateOverdueAsset(ctx context.Context, a *domain.OverdueAsset, level int, now time.Time) error
+	SyncOverdueReservations(ctx context.Context, now time.Time) (marked, cleared int64, err error)
+
+	// Claims
+	CreateClaim(ctx context.Context, c *domain.Claim, userID *int64) error
+	GetClaim(ctx context.Context, id int64) (*domain.Claim, error)
+	ListClaims(ctx context.Context, filter domain.ClaimFilter) ([]domain.Claim, error)
+	AddClaimEvidence(ctx context.Context, ev *domain.ClaimEvidence) error
+	UpdateClaimStatus(ctx context.Context, id int64, upd *domain.ClaimStatusUpdate, userID *int64) (*domain.Claim, error)
+
+	// Customer Portal
+	CreateCustomerToken(ctx context.Context, t *domain.CustomerToken, hash string) error
+	ListCustomerTokens(ctx context.Context, companyID *int64) ([]domain.CustomerToken, error)
+	RevokeCustomerToken(ctx context.Context, id int64) (*domain.CustomerToken, error)
+	AuthenticateCustomerToken(ctx context.Context, hash string, now time.Time) (*domain.CustomerToken, error)
+	ListCompanyReservations(ctx context.Context, companyID int64) ([]domain.RentalReservation, error)
+	GetCompanyReservation(ctx context.Context, companyID, id int64) (*domain.RentalReservation, error)
+	ListCompanyShipments(ctx context.Context, companyID int64, reservationID *int64) ([]domain.Shipment, error)
 
 	// Maintenance
 	AddMaintenanceLog(ctx context.Context, log *domain.MaintenanceLog) error
@@ -124,8 +211,8 @@ type Repository interface {
 	UpdateSetting(ctx context.Context, key string, value json.RawMessage) error
 
 	// Intelligence
-	GetAvailabilityTimeline(ctx context.Context, itemTypeID int64, start, end time.Time) ([]domain.AvailabilityPoint, error)
-	GetShortageAlerts(ctx context.Context) ([]domain.ShortageAlert, error)
+	GetAvailabilityTimeline(ctx context.Context, itemTypeID int64, start, end time.Time, granularity domain.AvailabilityGranularity) ([]domain.AvailabilityPoint, error)
+	GetShortageAlerts(ctx context.Context, granularity domain.AvailabilityGranularity) ([]domain.ShortageAlert, error)
 	GetMaintenanceForecast(ctx context.Context) ([]domain.MaintenanceForecast, error)
 
 	// Ingest Engine
@@ -188,4 +275,50 @@ type Repository interface {
 	ListShipments(ctx context.Context, deliveryID *int64) ([]domain.Shipment, error)
 	UpdateShipment(ctx context.Context, s *domain.Shipment) error
 	AllocateAssetsToShipment(ctx context.Context, shipmentID int64, assetIDs []int64, agentID int64) error
+	SetShipmentLegs(ctx context.Context, shipmentID int64, legs []domain.ShipmentLeg) (*domain.Shipment, error)
+	ConsolidateShipment(ctx context.Context, shipmentID int64, reservationIDs []int64) (*domain.ConsolidationResult, error)
+	TransferAssets(ctx context.Context, req *domain.TransferRequest, agentID int64) ([]domain.AssetTransfer, error)
+	ListAssetTransfers(ctx context.Context, reservationID int64) ([]domain.AssetTransfer, error)
+	TransitionShipment(ctx context.Context, shipmentID int64, req *domain.ShipmentTransitionRequest) (*domain.Shipment, error)
+	GetActiveShipmentLabel(ctx context.Context, shipmentID int64) (*domain.ShippingLabel, error)
+	RecordShipmentLabel(ctx context.Context, l *domain.ShippingLabel) error
+	VoidShipmentLabel(ctx context.Context, labelID int64) error
+	ListTrackableShipments(ctx context.Context) ([]domain.Shipment, error)
+	RecordTrackingEvents(ctx context.Context, shipmentID int64, events []domain.TrackingEvent) (*domain.Shipment, error)
+	ListTrackingEvents(ctx context.Context, shipmentID int64) ([]domain.TrackingEvent, error)
+
+	// Picking
+	CreatePickList(ctx context.Context, deliveryID int64, req *domain.PickListRequest, userID *int64) (*domain.PickList, error)
+	GetPickList(ctx context.Context, id int64) (*domain.PickList, error)
+	ListPickLists(ctx context.Context, deliveryID *int64) ([]domain.PickList, error)
+	ScanPickList(ctx context.Context, id int64, code string, userID *int64) (*domain.PickList, error)
+	CompletePickList(ctx context.Context, id int64, shipmentID *int64, agentID int64) (*domain.PickList, error)
+	CancelPickList(ctx context.Context, id int64) (*domain.PickList, error)
+
+	// Containers and Packing
+	CreateContainer(ctx context.Context, c *domain.Container) error
+	GetContainer(ctx context.Context, id int64) (*domain.Container, error)
+	ListContainers(ctx context.Context, placeID *int64) ([]domain.Container, error)
+	AddToContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error)
+	RemoveFromContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error)
+	CheckOutContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, agentID int64) (*domain.Container, error)
+	ReturnContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, agentID int64) (*domain.Container, error)
+	PackShipment(ctx context.Context, shipmentID int64, scan *domain.PackScan, agentID int64) (*domain.Manifest, error)
+	GetShipmentManifest(ctx context.Context, shipmentID int64) (*domain.Manifest, error)
+
+	// Return Receiving
+	OpenReceivingSession(ctx context.Context, shipmentID int64, req *domain.ReceivingSessionRequest, userID *int64) (*domain.ReceivingSession, error)
+	GetReceivingSession(ctx context.Context, id int64) (*domain.ReceivingSession, error)
+	ListReceivingSessions(ctx context.Context, shipmentID int64) ([]domain.ReceivingSession, error)
+	ScanReceiving(ctx context.Context, id int64, code string, userID *int64) (*domain.ReceivingSession, error)
+	CloseReceivingSession(ctx context.Context, id int64, agentID int64) (*domain.ReceivingSession, error)
+
+	// Route Planning
+	PlanDeliveryRoutes(ctx context.Context, req *domain.RoutePlanRequest) (*domain.RoutePlan, error)
+
+	// Fungible Stock
+	RecordStockMovement(ctx context.Context, req *domain.StockMovementRequest, userID *int64) ([]domain.StockMovement, error)
+	ListStockMovements(ctx context.Context, itemTypeID, reservationID *int64) ([]domain.StockMovement, error)
+	GetStockSummary(ctx context.Context, itemTypeID int64) (*domain.StockSummary, error)
+	ListStockLots(ctx context.Context, itemTypeID int64) ([]domain.StockLot, error)
 }
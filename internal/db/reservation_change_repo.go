@@ -0,0 +1,297 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// ModifyRentalReservation applies a patch to a reservation and its demand lines in one
+// transaction and records a new version in the change history. For confirmed
+// reservations the changed booking is re-checked against everything else booked; if
+// it cannot be satisfied the change is still applied but the reservation drops back
+// to pending and its holds are released.
+// It returns nil, nil when the reservation does not exist.
+func (r *SqlRepository) ModifyRentalReservation(ctx context.Context, id int64, patch *domain.ReservationPatch, userID *int64) (*domain.ReservationChangeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := r.modifyReservationTx(ctx, tx, id, patch, userID)
+	if err != nil || result == nil || result.Change == nil {
+		return result, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// modifyReservationTx applies a patch to one reservation inside tx.
+func (r *SqlRepository) modifyReservationTx(ctx context.Context, tx *sql.Tx, id int64, patch *domain.ReservationPatch, userID *int64) (*domain.ReservationChangeResult, error) {
+	before, err := getRentalReservation(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, nil
+	}
+	switch before.ReservationStatus {
+	case domain.ReservationStatusPending, domain.ReservationStatusConfirmed:
+	default:
+		return nil, fmt.Errorf("%w: %s", domain.ErrReservationNotModifiable, before.ReservationStatus)
+	}
+
+	after, changed, err := patch.Apply(*before)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.ReservationChangeResult{Reservation: &after}
+	if len(changed) == 0 {
+		return result, nil
+	}
+
+	if before.ReservationStatus == domain.ReservationStatusConfirmed {
+		shortfalls, err := r.checkReservationDelta(ctx, tx, before, &after)
+		if err != nil {
+			return nil, err
+		}
+		if len(shortfalls) > 0 {
+			result.Requeued = true
+			result.Shortfalls = shortfalls
+		}
+	}
+
+	now := time.Now()
+	after.UpdatedAt = now
+	_, err = tx.ExecContext(ctx, `UPDATE rental_reservations SET
+		reservation_name = $1, reservation_status = $2, start_time = $3, end_time = $4, metadata = $5, updated_at = $6
+		WHERE id = $7`,
+		after.ReservationName, after.ReservationStatus, after.StartTime, after.EndTime, after.Metadata, after.UpdatedAt, id)
+	if err != nil {
+		return nil, fmt.Errorf("update rental_reservation: %w", err)
+	}
+	if err := writeDemandChanges(ctx, tx, before.Demands, after.Demands, now); err != nil {
+		return nil, err
+	}
+
+	if result.Requeued {
+		t := domain.SystemTransitionTo(after.ReservationStatus, domain.ReservationStatusPending)
+		if _, err := r.applyReservationTransition(ctx, tx, &after, t, "change not covered by available inventory", userID, now); err != nil {
+			return nil, err
+		}
+	} else {
+		lines, err := r.allocationLines(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := reconcileHolds(ctx, tx, &after, lines, now); err != nil {
+			return nil, err
+		}
+		if after.ReservationStatus == domain.ReservationStatusConfirmed {
+			if _, err := r.allocateReservationTx(ctx, tx, id, userID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	change := &domain.ReservationChange{
+		ReservationID:   id,
+		ChangedByUserID: userID,
+		ChangedFields:   changed,
+		StatusBefore:    before.ReservationStatus,
+		StatusAfter:     after.ReservationStatus,
+		CreatedAt:       now,
+	}
+	if change.Before, err = json.Marshal(before); err != nil {
+		return nil, fmt.Errorf("marshal before snapshot: %w", err)
+	}
+	if change.After, err = json.Marshal(after); err != nil {
+		return nil, fmt.Errorf("marshal after snapshot: %w", err)
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) + 1 FROM reservation_changes WHERE reservation_id = $1", id).Scan(&change.Version); err != nil {
+		return nil, fmt.Errorf("next reservation version: %w", err)
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO reservation_changes (
+		reservation_id, version, changed_by_user_id, changed_fields, status_before, status_after, before_snapshot, after_snapshot, created_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		change.ReservationID, change.Version, change.ChangedByUserID, pq.Array(change.ChangedFields),
+		change.StatusBefore, change.StatusAfter, change.Before, change.After, change.CreatedAt,
+	).Scan(&change.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert reservation_change: %w", err)
+	}
+	result.Change = change
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"reservation_id": id,
+		"version":        change.Version,
+		"status":         after.ReservationStatus,
+		"requeued":       result.Requeued,
+	})
+	if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: domain.EventRentalModified, Payload: payload}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkReservationDelta checks a change to a confirmed reservation against everything
+// else booked. The reservation's current booking is already on the availability
+// timeline, so it is left out rather than counted against itself; substitutes are
+// drawn on, and locked, exactly as on approval.
+func (r *SqlRepository) checkReservationDelta(ctx context.Context, tx *sql.Tx, before, after *domain.RentalReservation) ([]domain.Shortfall, error) {
+	lines, err := r.expandDemands(ctx, tx, after.Demands)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockItemTypes(ctx, tx, linesItemTypes(lines)); err != nil {
+		return nil, err
+	}
+	return r.linesShortfalls(ctx, tx, lines, after.StartTime, after.EndTime, before.ID)
+}
+
+// writeDemandChanges persists the difference between two versions of a reservation's
+// demand lines. New lines (ID zero) are inserted and get their IDs assigned in place.
+// Lines that kits have been checked out against cannot be removed.
+func writeDemandChanges(ctx context.Context, tx *sql.Tx, before, after []domain.Demand, now time.Time) error {
+	prev := make(map[int64]domain.Demand, len(before))
+	for _, d := range before {
+		prev[d.ID] = d
+	}
+
+	kept := make(map[int64]bool, len(after))
+	for i := range after {
+		d := &after[i]
+		if d.ID == 0 {
+			if d.EventID == 0 && len(before) > 0 {
+				d.EventID = before[0].EventID
+			}
+			d.CreatedAt = now
+			d.UpdatedAt = now
+			err := tx.QueryRowContext(ctx, `INSERT INTO demands (
+				reservation_id, event_id, item_kind, item_id, requested_quantity,
+				business_function, eligible_duration, place_id, metadata, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+				d.ReservationID, d.EventID, d.ItemKind, d.ItemID, d.Quantity,
+				d.BusinessFunction, d.EligibleDuration, d.PlaceID, d.Metadata, d.CreatedAt, d.UpdatedAt,
+			).Scan(&d.ID)
+			if err != nil {
+				return fmt.Errorf("insert demand: %w", err)
+			}
+			continue
+		}
+
+		kept[d.ID] = true
+		p := prev[d.ID]
+		if p.ItemKind == d.ItemKind && p.ItemID == d.ItemID && p.Quantity == d.Quantity {
+			continue
+		}
+		d.UpdatedAt = now
+		_, err := tx.ExecContext(ctx, "UPDATE demands SET item_kind = $1, item_id = $2, requested_quantity = $3, updated_at = $4 WHERE id = $5",
+			d.ItemKind, d.ItemID, d.Quantity, d.UpdatedAt, d.ID)
+		if err != nil {
+			return fmt.Errorf("update demand: %w", err)
+		}
+	}
+
+	var removed []int64
+	for _, d := range before {
+		if !kept[d.ID] {
+			removed = append(removed, d.ID)
+		}
+	}
+	if len(removed) > 0 {
+		// Kits that went out against a demand keep pointing at it, so it stays on the booking
+		var kitDemandID int64
+		err := tx.QueryRowContext(ctx, "SELECT demand_id FROM kit_instances WHERE demand_id = ANY($1) LIMIT 1", pq.Array(removed)).Scan(&kitDemandID)
+		if err == nil {
+			return fmt.Errorf("%w: demand %d has kits checked out against it", domain.ErrReservationNotModifiable, kitDemandID)
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("check kit instances: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM demands WHERE id = ANY($1)", pq.Array(removed)); err != nil {
+			return fmt.Errorf("delete demands: %w", err)
+		}
+	}
+	return nil
+}
+
+// reconcileHolds brings a reservation's active holds in line with its (possibly
+// changed) window and demand lines. Holds beyond a line's quantity are released, and
+// holds whose asset is taken elsewhere in the new window are released so the next
+// allocation can pick a replacement.
+func reconcileHolds(ctx context.Context, tx *sql.Tx, rr *domain.RentalReservation, lines []allocationLine, now time.Time) error {
+	holds, err := listAssetHolds(ctx, tx, rr.ID, true)
+	if err != nil {
+		return err
+	}
+
+	remaining := make(map[[2]int64]int)
+	transits := make(map[int64]time.Duration)
+	for _, l := range lines {
+		remaining[[2]int64{l.demandID, l.itemTypeID}] += l.quantity
+		transits[l.demandID] = l.transit
+	}
+
+	for _, h := range holds {
+		key := [2]int64{h.DemandID, h.ItemTypeID}
+		release := remaining[key] <= 0
+		if !release && (!h.StartTime.Equal(rr.StartTime) || !h.EndTime.Equal(rr.EndTime)) {
+			busy, err := assetHeldElsewhere(ctx, tx, h.AssetID, rr.StartTime, rr.EndTime, transits[h.DemandID], h.ID)
+			if err != nil {
+				return err
+			}
+			if busy {
+				release = true
+			} else {
+				_, err := tx.ExecContext(ctx, "UPDATE asset_holds SET start_time = $1, end_time = $2, updated_at = $3 WHERE id = $4",
+					rr.StartTime, rr.EndTime, now, h.ID)
+				if err != nil {
+					return fmt.Errorf("move asset_hold: %w", err)
+				}
+			}
+		}
+		if release {
+			if _, err := tx.ExecContext(ctx, "UPDATE asset_holds SET status = $1, updated_at = $2 WHERE id = $3", domain.HoldReleased, now, h.ID); err != nil {
+				return fmt.Errorf("release asset_hold: %w", err)
+			}
+			continue
+		}
+		remaining[key]--
+	}
+	return nil
+}
+
+// ListReservationChanges returns the change history of a reservation, oldest first.
+func (r *SqlRepository) ListReservationChanges(ctx context.Context, reservationID int64) ([]domain.ReservationChange, error) {
+	query := `SELECT id, reservation_id, version, changed_by_user_id, changed_fields, status_before, status_after,
+	                 before_snapshot, after_snapshot, created_at
+	          FROM reservation_changes WHERE reservation_id = $1 ORDER BY version`
+	rows, err := r.db.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("query reservation_changes: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.ReservationChange{}
+	for rows.Next() {
+		var c domain.ReservationChange
+		var before, after []byte
+		if err := rows.Scan(&c.ID, &c.ReservationID, &c.Version, &c.ChangedByUserID, pq.Array(&c.ChangedFields),
+			&c.StatusBefore, &c.StatusAfter, &before, &after, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan reservation_change: %w", err)
+		}
+		c.Before = json.RawMessage(before)
+		c.After = json.RawMessage(after)
+		results = append(results, c)
+	}
+	return results, nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqlRepository_ModifyRentalReservation_RemoveDemandWithKits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	end := start.Add(24 * time.Hour)
+
+	mock.ExpectBegin()
+	expectRentalReservation(mock, 3, domain.ReservationStatusPending, start, end, true,
+		sqlmock.NewRows(demandCols).
+			AddRow(30, 3, 0, "item_type", 5, 2, "", "", nil, nil, start, start).
+			AddRow(31, 3, 0, domain.DemandKindKitTemplate, 8, 1, "", "", nil, nil, start, start))
+	mock.ExpectExec("UPDATE rental_reservations SET").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The kit went out and came back, but its instance still refers to the demand
+	mock.ExpectQuery("SELECT demand_id FROM kit_instances WHERE demand_id = ANY\\(\\$1\\)").
+		WithArgs("{31}").
+		WillReturnRows(sqlmock.NewRows([]string{"demand_id"}).AddRow(31))
+	mock.ExpectRollback()
+
+	result, err := repo.ModifyRentalReservation(ctx, 3, &domain.ReservationPatch{
+		Demands: []domain.DemandPatch{{ID: 31, Remove: true}},
+	}, nil)
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, domain.ErrReservationNotModifiable))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// PlanDeliveryRoutes plans the day's scheduled deliveries into ordered stops per vehicle.
+// Each delivery goes to its destination place, or failing that the first place the
+// reservation's demands name, within its time window (the whole day when it has none);
+// its load is the weight and volume of its items, kits expanded, from item type
+// metadata. It returns nil, nil when the depot does not exist.
+func (r *SqlRepository) PlanDeliveryRoutes(ctx context.Context, req *domain.RoutePlanRequest) (*domain.RoutePlan, error) {
+	var depotMetadata []byte
+	err := r.db.QueryRowContext(ctx, "SELECT metadata FROM places WHERE id = $1", req.DepotPlaceID).Scan(&depotMetadata)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get depot: %w", err)
+	}
+
+	y, m, d := req.Date.Date()
+	dayStart := time.Date(y, m, d, 0, 0, 0, 0, req.Date.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	query := `
+		SELECT sd.id, sd.window_start, sd.window_end,
+		       COALESCE(sd.destination_place_id, (
+		           SELECT dm.place_id FROM demands dm
+		           WHERE dm.reservation_id = sd.event_id AND dm.place_id IS NOT NULL
+		           ORDER BY dm.id LIMIT 1
+		       ))
+		FROM scheduled_deliveries sd
+		WHERE sd.target_date >= $1 AND sd.target_date < $2`
+	args := []interface{}{dayStart, dayEnd}
+	if len(req.DeliveryIDs) > 0 {
+		query += " AND sd.id = ANY($3)"
+		args = append(args, pq.Array(req.DeliveryIDs))
+	}
+	query += " ORDER BY sd.id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query scheduled deliveries: %w", err)
+	}
+	var stops []domain.RouteStop
+	var unrouted []domain.UnroutedStop
+	for rows.Next() {
+		var s domain.RouteStop
+		var windowStart, windowEnd sql.NullTime
+		var placeID sql.NullInt64
+		if err := rows.Scan(&s.DeliveryID, &windowStart, &windowEnd, &placeID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan scheduled delivery: %w", err)
+		}
+		if !placeID.Valid {
+			unrouted = append(unrouted, domain.UnroutedStop{DeliveryID: s.DeliveryID, Reason: "no destination place"})
+			continue
+		}
+		s.PlaceID = placeID.Int64
+		s.WindowStart, s.WindowEnd = dayStart, dayEnd
+		if windowStart.Valid {
+			s.WindowStart = windowStart.Time
+		}
+		if windowEnd.Valid {
+			s.WindowEnd = windowEnd.Time
+		}
+		stops = append(stops, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadRouteStops(ctx, stops); err != nil {
+		return nil, err
+	}
+
+	plan := domain.PlanRoutes(req, domain.PlaceCoordinates(depotMetadata), stops)
+	plan.Unrouted = append(plan.Unrouted, unrouted...)
+	return plan, nil
+}
+
+// loadRouteStops fills in each stop's place details and the load its delivery carries.
+func (r *SqlRepository) loadRouteStops(ctx context.Context, stops []domain.RouteStop) error {
+	if len(stops) == 0 {
+		return nil
+	}
+	deliveryIDs := make([]int64, 0, len(stops))
+	placeIDs := make([]int64, 0, len(stops))
+	for _, s := range stops {
+		deliveryIDs = append(deliveryIDs, s.DeliveryID)
+		placeIDs = append(placeIDs, s.PlaceID)
+	}
+
+	type placeInfo struct {
+		name     string
+		transit  int
+		metadata []byte
+	}
+	places := make(map[int64]placeInfo)
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, COALESCE(transit_minutes, 0), metadata FROM places WHERE id = ANY($1)", pq.Array(placeIDs))
+	if err != nil {
+		return fmt.Errorf("query destination places: %w", err)
+	}
+	for rows.Next() {
+		var id int64
+		var p placeInfo
+		if err := rows.Scan(&id, &p.name, &p.transit, &p.metadata); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan destination place: %w", err)
+		}
+		places[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = r.db.QueryContext(ctx, `SELECT id, scheduled_delivery_id, item_kind, item_id, quantity
+		FROM scheduled_delivery_items WHERE scheduled_delivery_id = ANY($1)`, pq.Array(deliveryIDs))
+	if err != nil {
+		return fmt.Errorf("query delivery items: %w", err)
+	}
+	var demands []domain.Demand
+	deliveryOf := make(map[int64]int64) // Delivery item -> delivery
+	for rows.Next() {
+		var d domain.Demand
+		var deliveryID int64
+		if err := rows.Scan(&d.ID, &deliveryID, &d.ItemKind, &d.ItemID, &d.Quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan delivery item: %w", err)
+		}
+		demands = append(demands, d)
+		deliveryOf[d.ID] = deliveryID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	lines, err := r.expandDemands(ctx, r.db, demands)
+	if err != nil {
+		return err
+	}
+
+	itemTypeIDs := make([]int64, 0, len(lines))
+	for _, line := range lines {
+		itemTypeIDs = append(itemTypeIDs, line.itemTypeID)
+	}
+	type load struct{ weightKg, volumeM3 float64 }
+	unitLoads := make(map[int64]load)
+	if len(itemTypeIDs) > 0 {
+		rows, err = r.db.QueryContext(ctx, "SELECT id, metadata FROM item_types WHERE id = ANY($1)", pq.Array(itemTypeIDs))
+		if err != nil {
+			return fmt.Errorf("query item type loads: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			var metadata []byte
+			if err := rows.Scan(&id, &metadata); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan item type load: %w", err)
+			}
+			var l load
+			l.weightKg, l.volumeM3 = domain.ItemLoad(json.RawMessage(metadata))
+			unitLoads[id] = l
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	loads := make(map[int64]load)
+	for _, line := range lines {
+		unit := unitLoads[line.itemTypeID]
+		l := loads[deliveryOf[line.demandID]]
+		l.weightKg += unit.weightKg * float64(line.quantity)
+		l.volumeM3 += unit.volumeM3 * float64(line.quantity)
+		loads[deliveryOf[line.demandID]] = l
+	}
+
+	for i := range stops {
+		s := &stops[i]
+		p := places[s.PlaceID]
+		s.PlaceName = p.name
+		s.TransitMinutes = p.transit
+		s.Point = domain.PlaceCoordinates(json.RawMessage(p.metadata))
+		s.WeightKg = loads[s.DeliveryID].weightKg
+		s.VolumeM3 = loads[s.DeliveryID].volumeM3
+	}
+	return nil
+}
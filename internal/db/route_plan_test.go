@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_PlanDeliveryRoutes_Loads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT metadata FROM places WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"metadata"}).AddRow([]byte(`{"latitude":51.5,"longitude":-0.1}`)))
+	// Delivery 2 has no destination and names no place on its demands
+	mock.ExpectQuery("SELECT sd.id, sd.window_start, sd.window_end, (.+) FROM scheduled_deliveries sd WHERE sd.target_date >= \\$1 AND sd.target_date < \\$2").
+		WithArgs(day, day.AddDate(0, 0, 1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "window_start", "window_end", "place_id"}).
+			AddRow(1, nil, nil, 3).
+			AddRow(2, nil, nil, nil))
+	mock.ExpectQuery("SELECT id, name, COALESCE\\(transit_minutes, 0\\), metadata FROM places WHERE id = ANY\\(\\$1\\)").
+		WithArgs("{3}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "transit_minutes", "metadata"}).
+			AddRow(3, "Town Hall", 30, []byte(`{"latitude":51.6,"longitude":-0.1}`)))
+	// Four of item type 7 and a kit of two of item type 8
+	mock.ExpectQuery("SELECT (.+) FROM scheduled_delivery_items WHERE scheduled_delivery_id = ANY\\(\\$1\\)").
+		WithArgs("{1}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scheduled_delivery_id", "item_kind", "item_id", "quantity"}).
+			AddRow(10, 1, domain.DemandKindItemType, 7, 4).
+			AddRow(11, 1, domain.DemandKindKitTemplate, 20, 1))
+	mock.ExpectQuery("SELECT (.+) FROM kit_template_components WHERE kit_template_id = ANY\\(\\$1\\)").
+		WithArgs("{20}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kit_template_id", "item_type_id", "quantity", "is_optional", "substitute_item_type_ids"}).
+			AddRow(1, 20, 8, 2, false, nil))
+	mock.ExpectQuery("SELECT (.+) FROM substitution_rules").
+		WithArgs("{7,8}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT id, metadata FROM item_types WHERE id = ANY\\(\\$1\\)").
+		WithArgs("{7,8}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "metadata"}).
+			AddRow(7, []byte(`{"weight_kg":10,"volume_m3":0.25}`)).
+			AddRow(8, []byte(`{"weight_kg":5}`)))
+
+	req := &domain.RoutePlanRequest{Date: day, DepotPlaceID: 1, Vehicles: []domain.Vehicle{{Name: "Van 1", MaxWeightKg: 100}}}
+	require.NoError(t, req.Validate())
+	plan, err := repo.PlanDeliveryRoutes(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, plan.Routes, 1)
+	require.Len(t, plan.Routes[0].Stops, 1)
+	stop := plan.Routes[0].Stops[0]
+	assert.Equal(t, "Town Hall", stop.PlaceName)
+	assert.Equal(t, 50.0, stop.WeightKg)
+	assert.Equal(t, 1.0, stop.VolumeM3)
+	assert.Equal(t, []domain.UnroutedStop{{DeliveryID: 2, Reason: "no destination place"}}, plan.Unrouted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// placeTree is a recursive CTE "place_tree" of place $1 and every place contained in
+// it, however deep.
+const placeTree = `WITH RECURSIVE place_tree AS (
+	SELECT id FROM places WHERE id = $1
+	UNION
+	SELECT p.id FROM places p JOIN place_tree t ON p.contained_in_place_id = t.id
+)`
+
+// FindEarliestSlot searches the availability model for the earliest start at which
+// every demand of req fits for its duration. With a source place, the assets and stock
+// at that place must cover the demands on their own as well, besides the fleet-wide
+// check approval makes.
+func (r *SqlRepository) FindEarliestSlot(ctx context.Context, req *domain.ScheduleRequest) (*domain.ScheduleResult, error) {
+	from, until := req.SearchRange(time.Now())
+	result := &domain.ScheduleResult{SearchFrom: from, SearchUntil: until}
+
+	lines, err := r.expandDemands(ctx, r.db, req.Demands)
+	if err != nil {
+		return nil, err
+	}
+	itemTypeIDs := linesItemTypes(lines)
+	if len(itemTypeIDs) == 0 || until.Before(from) {
+		return result, nil
+	}
+	reqs := make([]domain.ComponentRequirement, 0, len(lines))
+	for _, line := range lines {
+		reqs = append(reqs, line.requirement())
+	}
+	transit := linesTransit(lines)
+	length := req.Duration()
+
+	owned, buffers, err := itemTypeInventory(ctx, r.db, itemTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+	windowStart, windowEnd := from, until.Add(length)
+	for _, b := range buffers {
+		s, _ := b.Occupied(from, from.Add(length), transit)
+		_, e := b.Occupied(until, until.Add(length), transit)
+		if s.Before(windowStart) {
+			windowStart = s
+		}
+		if e.After(windowEnd) {
+			windowEnd = e
+		}
+	}
+	consumers, err := loadInventoryConsumers(ctx, r.db, itemTypeIDs, windowStart, windowEnd, 0)
+	if err != nil {
+		return nil, err
+	}
+	pools := []map[int64]*domain.AvailabilityTimeline{domain.BuildConsumerTimelines(itemTypeIDs, owned, buffers, consumers)}
+
+	if req.SourcePlaceID != nil {
+		placeOwned, atPlace, placeConsumers, err := placeInventory(ctx, r.db, *req.SourcePlaceID, itemTypeIDs)
+		if err != nil {
+			return nil, err
+		}
+		// Confirmed reservations not yet pinned to assets could be served from anywhere,
+		// so only holds and assets out of circulation count against the place
+		for _, c := range consumers {
+			if c.AssetID != nil && atPlace[*c.AssetID] {
+				placeConsumers = append(placeConsumers, c)
+			}
+		}
+		pools = append(pools, domain.BuildConsumerTimelines(itemTypeIDs, placeOwned, buffers, placeConsumers))
+	}
+
+	result.Slot = domain.EarliestFit(reqs, from, until, length, transit, pools...)
+	return result, nil
+}
+
+// placeInventory counts the units of each item type at a place or anywhere inside it,
+// however deep: assets, retired ones excluded, and fungible stock on hand there. It
+// also returns the set of those asset IDs and, for each stock lot on hand there that
+// expires, the units it takes out of circulation when it does.
+func placeInventory(ctx context.Context, q queryer, placeID int64, itemTypeIDs []int64) (map[int64]int, map[int64]bool, []domain.InventoryConsumer, error) {
+	rows, err := q.QueryContext(ctx, placeTree+`
+		SELECT a.id, a.item_type_id FROM assets a JOIN place_tree t ON t.id = a.place_id
+		WHERE a.item_type_id = ANY($2) AND a.status != 'retired'`, placeID, pq.Array(itemTypeIDs))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("query place assets: %w", err)
+	}
+	owned := make(map[int64]int)
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id, itemTypeID int64
+		if err := rows.Scan(&id, &itemTypeID); err != nil {
+			rows.Close()
+			return nil, nil, nil, fmt.Errorf("scan place asset: %w", err)
+		}
+		owned[itemTypeID]++
+		ids[id] = true
+	}
+	rows.Close()
+
+	rows, err = q.QueryContext(ctx, placeTree+`
+		SELECT m.item_type_id, l.id, COALESCE(l.lot_number, ''), l.expires_at, SUM(`+stockOnHand+`)
+		FROM stock_movements m JOIN place_tree t ON t.id = m.place_id
+		LEFT JOIN stock_lots l ON l.id = m.lot_id
+		WHERE m.item_type_id = ANY($2)
+		GROUP BY m.item_type_id, l.id, l.lot_number, l.expires_at
+		HAVING SUM(`+stockOnHand+`) > 0`, placeID, pq.Array(itemTypeIDs))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("query place stock: %w", err)
+	}
+	defer rows.Close()
+	var expiring []domain.InventoryConsumer
+	for rows.Next() {
+		c := domain.InventoryConsumer{Kind: domain.ConsumerExpiry}
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&c.ItemTypeID, &c.LotID, &c.LotNumber, &expiresAt, &c.Quantity); err != nil {
+			return nil, nil, nil, fmt.Errorf("scan place stock: %w", err)
+		}
+		owned[c.ItemTypeID] += c.Quantity
+		if expiresAt.Valid {
+			c.Start = &expiresAt.Time
+			expiring = append(expiring, c)
+		}
+	}
+	return owned, ids, expiring, rows.Err()
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_FindEarliestSlot_FungibleAtPlace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	from := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	latestEnd := from.Add(10 * 24 * time.Hour)
+	place := int64(1)
+
+	mock.ExpectQuery("SELECT (.+) FROM substitution_rules WHERE is_active").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows(substitutionRuleCols))
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}).AddRow(5, 0, 0))
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets (.+) UNION ALL SELECT m.item_type_id, SUM(.+) FROM stock_movements m").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(5, 30))
+	// Fleet-wide, another reservation takes 25 of the 30 for the first two days
+	mock.ExpectQuery("SELECT d.item_id, (.+) FROM demands d JOIN rental_reservations rr").
+		WithArgs("{5}", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols).AddRow(5, from, from.Add(48*time.Hour), 25, 4, 40, "Gala"))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{5}", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM asset_holds h JOIN assets a").
+		WithArgs("{5}", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{5}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols))
+	// The place has no asset rows, only stock: eight untracked and a lot of six that
+	// expires on the third day
+	mock.ExpectQuery("WITH RECURSIVE place_tree (.+) SELECT a.id, a.item_type_id FROM assets a").
+		WithArgs(place, "{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_type_id"}))
+	mock.ExpectQuery("WITH RECURSIVE place_tree (.+) FROM stock_movements m JOIN place_tree t").
+		WithArgs(place, "{5}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "lot_id", "lot_number", "expires_at", "quantity"}).
+			AddRow(5, nil, "", nil, 8).
+			AddRow(5, 2, "L2", from.Add(72*time.Hour), 6))
+
+	req := &domain.ScheduleRequest{
+		Demands:         []domain.Demand{{ItemKind: domain.DemandKindItemType, ItemID: 5, Quantity: 10}},
+		DurationMinutes: 8 * 60,
+		EarliestStart:   &from,
+		LatestEnd:       &latestEnd,
+		SourcePlaceID:   &place,
+	}
+	result, err := repo.FindEarliestSlot(ctx, req)
+	require.NoError(t, err)
+	// Ten are free fleet-wide once the other reservation ends, and still on hand at
+	// the place until the lot expires
+	require.NotNil(t, result.Slot)
+	assert.True(t, result.Slot.Start.Equal(from.Add(48*time.Hour)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
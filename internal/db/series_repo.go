@@ -0,0 +1,450 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const seriesColumns = `id, COALESCE(reservation_name, ''), recurrence_rule, start_time, end_time, exceptions, under_name_id, provider_id, demands, metadata, created_at, updated_at`
+
+func scanReservationSeries(scanner interface{ Scan(...any) error }) (*domain.ReservationSeries, error) {
+	var s domain.ReservationSeries
+	var exceptionsJSON, demandsJSON, metadataJSON []byte
+	err := scanner.Scan(&s.ID, &s.ReservationName, &s.RecurrenceRule, &s.StartTime, &s.EndTime, &exceptionsJSON,
+		&s.UnderNameID, &s.ProviderID, &demandsJSON, &metadataJSON, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(exceptionsJSON, &s.Exceptions); err != nil {
+		return nil, fmt.Errorf("decode series exceptions: %w", err)
+	}
+	if err := json.Unmarshal(demandsJSON, &s.Demands); err != nil {
+		return nil, fmt.Errorf("decode series demands: %w", err)
+	}
+	s.Metadata = json.RawMessage(metadataJSON)
+	return &s, nil
+}
+
+// seriesJSON encodes the exceptions and demand template of a series for storage.
+func seriesJSON(s *domain.ReservationSeries) ([]byte, []byte, error) {
+	exceptions := s.Exceptions
+	if exceptions == nil {
+		exceptions = []time.Time{}
+	}
+	demands := s.Demands
+	if demands == nil {
+		demands = []domain.Demand{}
+	}
+	exceptionsJSON, err := json.Marshal(exceptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	demandsJSON, err := json.Marshal(demands)
+	if err != nil {
+		return nil, nil, err
+	}
+	return exceptionsJSON, demandsJSON, nil
+}
+
+// CreateReservationSeries stores a series and materializes every occurrence of its
+// rule as a pending reservation, all in one transaction.
+func (r *SqlRepository) CreateReservationSeries(ctx context.Context, s *domain.ReservationSeries) error {
+	starts, err := s.OccurrenceStarts()
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if err := insertReservationSeries(ctx, tx, s, now); err != nil {
+		return err
+	}
+	s.Occurrences = make([]domain.RentalReservation, 0, len(starts))
+	for _, start := range starts {
+		rr := s.Occurrence(start)
+		if err := insertRentalReservation(ctx, tx, &rr, now); err != nil {
+			return err
+		}
+		s.Occurrences = append(s.Occurrences, rr)
+	}
+	return tx.Commit()
+}
+
+func insertReservationSeries(ctx context.Context, tx *sql.Tx, s *domain.ReservationSeries, now time.Time) error {
+	exceptionsJSON, demandsJSON, err := seriesJSON(s)
+	if err != nil {
+		return err
+	}
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	query := `INSERT INTO reservation_series (
+		reservation_name, recurrence_rule, start_time, end_time, exceptions, under_name_id, provider_id, demands, metadata, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	err = tx.QueryRowContext(ctx, query, s.ReservationName, s.RecurrenceRule, s.StartTime, s.EndTime, exceptionsJSON,
+		s.UnderNameID, s.ProviderID, demandsJSON, s.Metadata, s.CreatedAt, s.UpdatedAt).Scan(&s.ID)
+	if err != nil {
+		return fmt.Errorf("insert reservation_series: %w", err)
+	}
+	return nil
+}
+
+func updateReservationSeries(ctx context.Context, tx *sql.Tx, s *domain.ReservationSeries, now time.Time) error {
+	exceptionsJSON, demandsJSON, err := seriesJSON(s)
+	if err != nil {
+		return err
+	}
+	s.UpdatedAt = now
+	_, err = tx.ExecContext(ctx, `UPDATE reservation_series SET
+		reservation_name = $1, recurrence_rule = $2, start_time = $3, end_time = $4, exceptions = $5, demands = $6, metadata = $7, updated_at = $8
+		WHERE id = $9`,
+		s.ReservationName, s.RecurrenceRule, s.StartTime, s.EndTime, exceptionsJSON, demandsJSON, s.Metadata, s.UpdatedAt, s.ID)
+	if err != nil {
+		return fmt.Errorf("update reservation_series: %w", err)
+	}
+	return nil
+}
+
+// GetReservationSeries returns a series with its occurrences, or nil if it does not exist.
+func (r *SqlRepository) GetReservationSeries(ctx context.Context, id int64) (*domain.ReservationSeries, error) {
+	return getReservationSeries(ctx, r.db, id, false)
+}
+
+// getReservationSeries loads a series and its occurrences through q, optionally
+// locking the series and its occurrences for the rest of the transaction.
+func getReservationSeries(ctx context.Context, q queryer, id int64, forUpdate bool) (*domain.ReservationSeries, error) {
+	query := `SELECT ` + seriesColumns + ` FROM reservation_series WHERE id = $1`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	s, err := scanReservationSeries(q.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reservation_series: %w", err)
+	}
+
+	occQuery := `SELECT id FROM rental_reservations WHERE series_id = $1 ORDER BY recurrence_id, id`
+	if forUpdate {
+		occQuery += " FOR UPDATE"
+	}
+	rows, err := q.QueryContext(ctx, occQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("query series occurrences: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var occID int64
+		if err := rows.Scan(&occID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan series occurrence: %w", err)
+		}
+		ids = append(ids, occID)
+	}
+	rows.Close()
+
+	s.Occurrences = make([]domain.RentalReservation, 0, len(ids))
+	for _, occID := range ids {
+		rr, err := getRentalReservation(ctx, q, occID, false)
+		if err != nil {
+			return nil, err
+		}
+		if rr != nil {
+			s.Occurrences = append(s.Occurrences, *rr)
+		}
+	}
+	return s, nil
+}
+
+// ListReservationSeries returns every series without its occurrences.
+func (r *SqlRepository) ListReservationSeries(ctx context.Context) ([]domain.ReservationSeries, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+seriesColumns+` FROM reservation_series ORDER BY start_time DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list reservation_series: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.ReservationSeries{}
+	for rows.Next() {
+		s, err := scanReservationSeries(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan reservation_series: %w", err)
+		}
+		results = append(results, *s)
+	}
+	return results, nil
+}
+
+// ApproveReservationSeries confirms every pending occurrence of a series in one
+// transaction, or none of them: if any occurrence is short the whole approval fails
+// with an InsufficientInventoryError listing the short occurrences.
+// It returns nil, nil when the series does not exist.
+func (r *SqlRepository) ApproveReservationSeries(ctx context.Context, id int64, userID *int64) (*domain.SeriesApproval, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	approval, short, err := r.approveSeriesTx(ctx, tx, id, userID)
+	if err != nil || approval == nil {
+		return nil, err
+	}
+	if len(short) > 0 {
+		return nil, &domain.InsufficientInventoryError{Shortfalls: short[0].Shortfalls, Occurrences: short}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// CheckSeriesAvailability reports which pending occurrences of a series would be short
+// if it were approved now. The approval is run and rolled back, so occurrences of the
+// same series are checked against each other as well as against existing bookings.
+func (r *SqlRepository) CheckSeriesAvailability(ctx context.Context, id int64) ([]domain.OccurrenceShortfall, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	approval, short, err := r.approveSeriesTx(ctx, tx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if approval == nil {
+		return nil, fmt.Errorf("series %d not found", id)
+	}
+	return short, nil
+}
+
+// approveSeriesTx approves the pending occurrences of a series in start order inside
+// tx, so each one is checked against the occurrences confirmed before it. Short
+// occurrences are collected rather than aborting the loop.
+func (r *SqlRepository) approveSeriesTx(ctx context.Context, tx *sql.Tx, id int64, userID *int64) (*domain.SeriesApproval, []domain.OccurrenceShortfall, error) {
+	var seriesID int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM reservation_series WHERE id = $1 FOR UPDATE", id).Scan(&seriesID)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("lock reservation_series: %w", err)
+	}
+
+	// Occurrences that have already ended can no longer be approved and are left pending
+	rows, err := tx.QueryContext(ctx, `SELECT id, start_time FROM rental_reservations
+		WHERE series_id = $1 AND reservation_status = $2 AND end_time > $3 ORDER BY start_time, id`, id, domain.ReservationStatusPending, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("query series occurrences: %w", err)
+	}
+	type occurrence struct {
+		id    int64
+		start time.Time
+	}
+	var pending []occurrence
+	for rows.Next() {
+		var o occurrence
+		if err := rows.Scan(&o.id, &o.start); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("scan series occurrence: %w", err)
+		}
+		pending = append(pending, o)
+	}
+	rows.Close()
+
+	// Lock every item type the series draws from up front, in one ascending pass, so
+	// the per-occurrence locks taken during approval are already held.
+	seen := make(map[int64]bool)
+	var itemTypeIDs []int64
+	for _, o := range pending {
+		lines, err := r.allocationLines(ctx, tx, o.id)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, itemTypeID := range linesItemTypes(lines) {
+			if !seen[itemTypeID] {
+				seen[itemTypeID] = true
+				itemTypeIDs = append(itemTypeIDs, itemTypeID)
+			}
+		}
+	}
+	if err := lockItemTypes(ctx, tx, itemTypeIDs); err != nil {
+		return nil, nil, err
+	}
+
+	approval := &domain.SeriesApproval{SeriesID: id, Allocations: []domain.AllocationResult{}}
+	var short []domain.OccurrenceShortfall
+	for _, o := range pending {
+		result, err := r.approveReservationTx(ctx, tx, o.id, "", userID)
+		var shortage *domain.InsufficientInventoryError
+		if errors.As(err, &shortage) {
+			short = append(short, domain.OccurrenceShortfall{ReservationID: o.id, StartTime: o.start, Shortfalls: shortage.Shortfalls})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if result != nil {
+			approval.Allocations = append(approval.Allocations, *result)
+		}
+	}
+	return approval, short, nil
+}
+
+// EditReservationSeries applies an edit made on one occurrence to that occurrence, to
+// it and every later one, or to the whole series. A "following" edit splits the
+// series at the occurrence so the earlier occurrences keep the old template, and a
+// series-wide edit also updates the template. Occurrences that are already cancelled
+// or fulfilled are skipped. It returns nil, nil when the series does not exist.
+func (r *SqlRepository) EditReservationSeries(ctx context.Context, id int64, edit *domain.SeriesEdit, userID *int64) (*domain.SeriesEditResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	series, err := getReservationSeries(ctx, tx, id, true)
+	if err != nil || series == nil {
+		return nil, err
+	}
+	var target *domain.RentalReservation
+	for i := range series.Occurrences {
+		if series.Occurrences[i].ID == edit.ReservationID {
+			target = &series.Occurrences[i]
+			break
+		}
+	}
+	if target == nil || target.RecurrenceID == nil {
+		return nil, fmt.Errorf("%w: reservation %d is not an occurrence of series %d", domain.ErrInvalidReservationChange, edit.ReservationID, id)
+	}
+
+	now := time.Now()
+	result := &domain.SeriesEditResult{}
+	var targets []domain.RentalReservation
+	switch edit.Scope {
+	case domain.SeriesEditThis:
+		targets = []domain.RentalReservation{*target}
+		if edit.Cancel {
+			series.Exceptions = append(series.Exceptions, *target.RecurrenceID)
+		}
+
+	case domain.SeriesEditFollowing, domain.SeriesEditAll:
+		at := series.StartTime
+		if edit.Scope == domain.SeriesEditFollowing {
+			at = *target.RecurrenceID
+		}
+		for _, occ := range series.Occurrences {
+			if occ.RecurrenceID != nil && !occ.RecurrenceID.Before(at) {
+				targets = append(targets, occ)
+			}
+		}
+
+		if at.After(series.StartTime) {
+			tail, err := series.SplitAt(at)
+			if err != nil {
+				return nil, err
+			}
+			if err := updateReservationSeries(ctx, tx, series, now); err != nil {
+				return nil, err
+			}
+			if !edit.Cancel {
+				// The occurrences from here on move to a new series carrying the rest of the rule
+				if err := insertReservationSeries(ctx, tx, &tail, now); err != nil {
+					return nil, err
+				}
+				_, err = tx.ExecContext(ctx, "UPDATE rental_reservations SET series_id = $1, updated_at = $2 WHERE id = ANY($3)",
+					tail.ID, now, pq.Array(occurrenceIDs(targets)))
+				if err != nil {
+					return nil, fmt.Errorf("move occurrences to split series: %w", err)
+				}
+				result.SplitFrom = &series.ID
+				series = &tail
+			}
+		}
+
+		if !edit.Cancel {
+			oldStart := series.StartTime
+			if err := series.ApplyPatch(target, &edit.Patch); err != nil {
+				return nil, err
+			}
+			if shift := series.StartTime.Sub(oldStart); shift != 0 {
+				for _, occ := range targets {
+					_, err := tx.ExecContext(ctx, "UPDATE rental_reservations SET recurrence_id = $1 WHERE id = $2", occ.RecurrenceID.Add(shift), occ.ID)
+					if err != nil {
+						return nil, fmt.Errorf("shift occurrence: %w", err)
+					}
+				}
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unsupported scope %q", domain.ErrInvalidReservationChange, edit.Scope)
+	}
+
+	for i := range targets {
+		occ := &targets[i]
+		switch occ.ReservationStatus {
+		case domain.ReservationStatusPending, domain.ReservationStatusConfirmed:
+		default:
+			if edit.Scope == domain.SeriesEditThis {
+				return nil, fmt.Errorf("%w: %s", domain.ErrReservationNotModifiable, occ.ReservationStatus)
+			}
+			result.Skipped = append(result.Skipped, occ.ID)
+			continue
+		}
+
+		if edit.Cancel {
+			if _, err := r.transitionReservationTx(ctx, tx, occ, domain.ReservationActionCancel, "", userID, now); err != nil {
+				return nil, err
+			}
+			result.Cancelled = append(result.Cancelled, occ.ID)
+			continue
+		}
+
+		patch := edit.Patch
+		if occ.ID != target.ID {
+			patch = edit.Patch.ForOccurrence(target, occ)
+		}
+		change, err := r.modifyReservationTx(ctx, tx, occ.ID, &patch, userID)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil && change.Change != nil {
+			result.Changes = append(result.Changes, *change)
+		}
+	}
+
+	if edit.Scope == domain.SeriesEditThis || !edit.Cancel {
+		if err := updateReservationSeries(ctx, tx, series, now); err != nil {
+			return nil, err
+		}
+	}
+	if result.Series, err = getReservationSeries(ctx, tx, series.ID, false); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func occurrenceIDs(occurrences []domain.RentalReservation) []int64 {
+	ids := make([]int64, len(occurrences))
+	for i, occ := range occurrences {
+		ids[i] = occ.ID
+	}
+	return ids
+}
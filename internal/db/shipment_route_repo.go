@@ -0,0 +1,363 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const shipmentLegColumns = `id, shipment_id, sequence, from_place_id, to_place_id, COALESCE(carrier, ''), COALESCE(tracking_number, ''),
+	estimated_departure, estimated_arrival, status, departed_at, arrived_at, created_at, updated_at`
+
+func listShipmentLegs(ctx context.Context, q queryer, shipmentID int64) ([]domain.ShipmentLeg, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+shipmentLegColumns+` FROM shipment_legs WHERE shipment_id = $1 ORDER BY sequence`, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("query shipment_legs: %w", err)
+	}
+	defer rows.Close()
+
+	legs := []domain.ShipmentLeg{}
+	for rows.Next() {
+		var l domain.ShipmentLeg
+		if err := rows.Scan(&l.ID, &l.ShipmentID, &l.Sequence, &l.FromPlaceID, &l.ToPlaceID, &l.Carrier, &l.TrackingNumber,
+			&l.EstimatedDeparture, &l.EstimatedArrival, &l.Status, &l.DepartedAt, &l.ArrivedAt, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan shipment_leg: %w", err)
+		}
+		legs = append(legs, l)
+	}
+	return legs, rows.Err()
+}
+
+func insertShipmentLegs(ctx context.Context, tx *sql.Tx, shipmentID int64, legs []domain.ShipmentLeg, now time.Time) error {
+	for i := range legs {
+		l := &legs[i]
+		l.ShipmentID = shipmentID
+		l.CreatedAt = now
+		l.UpdatedAt = now
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO shipment_legs (shipment_id, sequence, from_place_id, to_place_id, carrier, tracking_number,
+			                           estimated_departure, estimated_arrival, status, departed_at, arrived_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+			l.ShipmentID, l.Sequence, l.FromPlaceID, l.ToPlaceID, l.Carrier, l.TrackingNumber,
+			l.EstimatedDeparture, l.EstimatedArrival, l.Status, l.DepartedAt, l.ArrivedAt, l.CreatedAt, l.UpdatedAt).Scan(&l.ID)
+		if err != nil {
+			return fmt.Errorf("insert shipment_leg: %w", err)
+		}
+	}
+	return nil
+}
+
+// shipmentReservations returns the reservations with check-outs or returns on a shipment.
+func shipmentReservations(ctx context.Context, q queryer, shipmentID int64) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT reservation_id FROM check_out_actions WHERE shipment_id = $1
+		UNION
+		SELECT reservation_id FROM return_actions WHERE shipment_id = $1
+		ORDER BY 1`, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("query shipment reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan shipment reservation: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetShipmentLegs replaces a shipment's route with legs, already validated. Legs that
+// have departed cannot be rerouted: the new route must keep them, and their progress
+// is carried over. It returns nil, nil when the shipment does not exist.
+func (r *SqlRepository) SetShipmentLegs(ctx context.Context, shipmentID int64, legs []domain.ShipmentLeg) (*domain.Shipment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM shipments WHERE id = $1 FOR UPDATE", shipmentID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock shipment: %w", err)
+	}
+
+	existing, err := listShipmentLegs(ctx, tx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, old := range existing {
+		if old.DepartedAt == nil {
+			continue
+		}
+		i := old.Sequence - 1
+		if i >= len(legs) || legs[i].FromPlaceID != old.FromPlaceID || legs[i].ToPlaceID != old.ToPlaceID {
+			return nil, fmt.Errorf("%w: leg %d has already departed and cannot be rerouted", domain.ErrInvalidRoute, old.Sequence)
+		}
+		legs[i].Status, legs[i].DepartedAt, legs[i].ArrivedAt = old.Status, old.DepartedAt, old.ArrivedAt
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shipment_legs WHERE shipment_id = $1", shipmentID); err != nil {
+		return nil, fmt.Errorf("clear shipment_legs: %w", err)
+	}
+	now := time.Now()
+	if err := insertShipmentLegs(ctx, tx, shipmentID, legs, now); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE shipments SET updated_at = $1 WHERE id = $2", now, shipmentID); err != nil {
+		return nil, fmt.Errorf("touch shipment: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetShipmentByID(ctx, shipmentID)
+}
+
+// ConsolidateShipment loads the check-outs of several reservations that are not on any
+// shipment yet, whether only allocated or already dispatched, onto one outbound
+// shipment. A shipment with a route may only carry check-outs bound for one of its
+// stops. It returns nil, nil when the shipment does not exist.
+func (r *SqlRepository) ConsolidateShipment(ctx context.Context, shipmentID int64, reservationIDs []int64) (*domain.ConsolidationResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var direction string
+	err = tx.QueryRowContext(ctx, "SELECT direction FROM shipments WHERE id = $1 FOR UPDATE", shipmentID).Scan(&direction)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock shipment: %w", err)
+	}
+	if direction != "outbound" {
+		return nil, fmt.Errorf("%w: only outbound shipments take check-outs", domain.ErrInvalidRoute)
+	}
+
+	legs, err := listShipmentLegs(ctx, tx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	stops := make(map[int64]bool)
+	for _, place := range domain.Stops(legs) {
+		stops[place] = true
+	}
+	for _, leg := range legs {
+		if leg.DepartedAt != nil {
+			return nil, fmt.Errorf("%w: shipment %d is already under way", domain.ErrInvalidRoute, shipmentID)
+		}
+	}
+
+	result := &domain.ConsolidationResult{ShipmentID: shipmentID, CheckOutIDs: make(map[int64][]int64)}
+	for _, reservationID := range reservationIDs {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT co.id, co.to_location_id FROM check_out_actions co
+			WHERE co.reservation_id = $1 AND co.shipment_id IS NULL
+			  AND (co.action_status = 'Potential' OR (`+outstandingCheckOut+`))
+			ORDER BY co.id
+			FOR UPDATE`, reservationID)
+		if err != nil {
+			return nil, fmt.Errorf("query unshipped check-outs: %w", err)
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			var to sql.NullInt64
+			if err := rows.Scan(&id, &to); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan unshipped check-out: %w", err)
+			}
+			if to.Valid && len(stops) > 0 && !stops[to.Int64] {
+				rows.Close()
+				return nil, fmt.Errorf("%w: check-out %d of reservation %d goes to place %d, where the route does not stop",
+					domain.ErrInvalidRoute, id, reservationID, to.Int64)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("%w: reservation %d has no unshipped check-outs", domain.ErrInvalidRoute, reservationID)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE check_out_actions SET shipment_id = $1 WHERE id = ANY($2)", shipmentID, pq.Array(ids)); err != nil {
+			return nil, fmt.Errorf("assign check-outs to shipment: %w", err)
+		}
+		result.CheckOutIDs[reservationID] = ids
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE shipments SET updated_at = $1 WHERE id = $2", time.Now(), shipmentID); err != nil {
+		return nil, fmt.Errorf("touch shipment: %w", err)
+	}
+	return result, tx.Commit()
+}
+
+// TransferAssets hands assets that are out on one reservation directly to another:
+// each is returned against the first and checked out against the second in one step,
+// staying deployed throughout. Without a shipment carrying them the assets are
+// placed at the destination straight away.
+func (r *SqlRepository) TransferAssets(ctx context.Context, req *domain.TransferRequest, agentID int64) ([]domain.AssetTransfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock both reservations in a fixed order so opposite transfers cannot deadlock
+	first, second := req.FromReservationID, req.ToReservationID
+	if second < first {
+		first, second = second, first
+	}
+	locked := make(map[int64]*domain.RentalReservation, 2)
+	for _, id := range []int64{first, second} {
+		rr, err := getRentalReservation(ctx, tx, id, true)
+		if err != nil {
+			return nil, err
+		}
+		if rr == nil {
+			return nil, fmt.Errorf("%w: reservation %d not found", domain.ErrInvalidTransfer, id)
+		}
+		locked[id] = rr
+	}
+	to := locked[req.ToReservationID]
+	if !domain.CanReceiveTransfer(to.ReservationStatus) {
+		return nil, fmt.Errorf("%w: reservation %d is %s", domain.ErrInvalidTransfer, to.ID, to.ReservationStatus)
+	}
+	if req.ShipmentID != nil {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM shipments WHERE id = $1)", *req.ShipmentID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("check shipment: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: shipment %d not found", domain.ErrInvalidTransfer, *req.ShipmentID)
+		}
+	}
+
+	now := time.Now()
+	transfers := make([]domain.AssetTransfer, 0, len(req.AssetIDs))
+	for _, assetID := range req.AssetIDs {
+		t := domain.AssetTransfer{
+			AssetID:           assetID,
+			FromReservationID: req.FromReservationID,
+			ToReservationID:   req.ToReservationID,
+			ShipmentID:        req.ShipmentID,
+			AgentID:           agentID,
+		}
+		var checkOutID int64
+		err := tx.QueryRowContext(ctx, `
+			SELECT co.id, co.to_location_id FROM check_out_actions co
+			WHERE co.reservation_id = $1 AND co.asset_id = $2 AND `+outstandingCheckOut+`
+			ORDER BY co.start_time DESC LIMIT 1
+			FOR UPDATE`, req.FromReservationID, assetID).Scan(&checkOutID, &t.FromPlaceID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: asset %d is not out on reservation %d", domain.ErrInvalidTransfer, assetID, req.FromReservationID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("find check-out of asset %d: %w", assetID, err)
+		}
+		t.ToPlaceID = req.ToPlaceID
+		if t.ToPlaceID == nil {
+			t.ToPlaceID = t.FromPlaceID
+		}
+
+		if err := consumeHold(ctx, tx, req.ToReservationID, assetID, now); err != nil {
+			return nil, err
+		}
+
+		retMeta, _ := json.Marshal(map[string]interface{}{"transfer_to_reservation_id": req.ToReservationID})
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO return_actions (reservation_id, asset_id, agent_id, shipment_id, start_time, from_location_id, to_location_id, action_status, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, 'Completed', $8) RETURNING id`,
+			req.FromReservationID, assetID, agentID, req.ShipmentID, now, t.FromPlaceID, t.ToPlaceID, retMeta).Scan(&t.ReturnActionID)
+		if err != nil {
+			return nil, fmt.Errorf("return asset %d: %w", assetID, err)
+		}
+		coMeta, _ := json.Marshal(map[string]interface{}{"transfer_from_reservation_id": req.FromReservationID})
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO check_out_actions (reservation_id, asset_id, agent_id, shipment_id, start_time, from_location_id, to_location_id, action_status, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, 'Completed', $8) RETURNING id`,
+			req.ToReservationID, assetID, agentID, req.ShipmentID, now, t.FromPlaceID, t.ToPlaceID, coMeta).Scan(&t.CheckOutActionID)
+		if err != nil {
+			return nil, fmt.Errorf("check out asset %d: %w", assetID, err)
+		}
+
+		if req.ShipmentID == nil && t.ToPlaceID != nil {
+			if _, err := tx.ExecContext(ctx, "UPDATE assets SET place_id = $1, updated_at = $2 WHERE id = $3", *t.ToPlaceID, now, assetID); err != nil {
+				return nil, fmt.Errorf("update asset %d: %w", assetID, err)
+			}
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO asset_transfers (asset_id, from_reservation_id, to_reservation_id, return_action_id, check_out_action_id,
+			                             from_place_id, to_place_id, shipment_id, agent_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+			t.AssetID, t.FromReservationID, t.ToReservationID, t.ReturnActionID, t.CheckOutActionID,
+			t.FromPlaceID, t.ToPlaceID, t.ShipmentID, t.AgentID, now).Scan(&t.ID)
+		if err != nil {
+			return nil, fmt.Errorf("record transfer of asset %d: %w", assetID, err)
+		}
+		t.CreatedAt = now
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"asset_id":            t.AssetID,
+			"from_reservation_id": t.FromReservationID,
+			"to_reservation_id":   t.ToReservationID,
+			"from_place_id":       t.FromPlaceID,
+			"to_place_id":         t.ToPlaceID,
+			"shipment_id":         t.ShipmentID,
+			"agent_id":            t.AgentID,
+		})
+		if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: domain.EventAssetTransferred, Payload: payload}); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+
+	if err := closeReturnedKits(ctx, tx, req.FromReservationID, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// As with a regular check-out, the receiving reservation's status follows its fulfillment
+	r.syncFulfillment(ctx, []int64{req.ToReservationID})
+	return transfers, nil
+}
+
+// ListAssetTransfers returns the transfers into or out of a reservation, oldest first.
+func (r *SqlRepository) ListAssetTransfers(ctx context.Context, reservationID int64) ([]domain.AssetTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, asset_id, from_reservation_id, to_reservation_id, return_action_id, check_out_action_id,
+		       from_place_id, to_place_id, shipment_id, agent_id, created_at
+		FROM asset_transfers WHERE from_reservation_id = $1 OR to_reservation_id = $1
+		ORDER BY created_at, id`, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("query asset_transfers: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.AssetTransfer{}
+	for rows.Next() {
+		var t domain.AssetTransfer
+		if err := rows.Scan(&t.ID, &t.AssetID, &t.FromReservationID, &t.ToReservationID, &t.ReturnActionID, &t.CheckOutActionID,
+			&t.FromPlaceID, &t.ToPlaceID, &t.ShipmentID, &t.AgentID, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan asset_transfer: %w", err)
+		}
+		results = append(results, t)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var shipmentLegCols = []string{"id", "shipment_id", "sequence", "from_place_id", "to_place_id", "carrier", "tracking_number",
+	"estimated_departure", "estimated_arrival", "status", "departed_at", "arrived_at", "created_at", "updated_at"}
+
+// expectShipmentLegs expects shipment 42's route to be read: depot 1 to venue 2, where
+// the first leg has departed, then on to venue 3.
+func expectShipmentLegs(mock sqlmock.Sqlmock, departedAt time.Time) {
+	mock.ExpectQuery("SELECT (.+) FROM shipment_legs WHERE shipment_id = \\$1 ORDER BY sequence").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(shipmentLegCols).
+			AddRow(1, 42, 1, 1, 2, "", "", nil, nil, "DeliveryShipped", departedAt, nil, departedAt, departedAt).
+			AddRow(2, 42, 2, 2, 3, "", "", nil, nil, "DeliveryPreparing", nil, nil, departedAt, departedAt))
+}
+
+func TestSqlRepository_SetShipmentLegs_KeepsDepartedLegs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	departedAt := time.Now().Add(-time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM shipments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	expectShipmentLegs(mock, departedAt)
+	mock.ExpectExec("DELETE FROM shipment_legs WHERE shipment_id = \\$1").
+		WithArgs(42).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// The departed leg keeps its progress; the second leg now ends at venue 4
+	mock.ExpectQuery("INSERT INTO shipment_legs").
+		WithArgs(42, 1, 1, 2, "", "", nil, nil, domain.DeliveryShipped, departedAt, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("INSERT INTO shipment_legs").
+		WithArgs(42, 2, 2, 4, "", "", nil, nil, domain.DeliveryPreparing, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec("UPDATE shipments SET updated_at = \\$1 WHERE id = \\$2").
+		WithArgs(sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM shipments WHERE id = \\$1").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	legs := []domain.ShipmentLeg{{FromPlaceID: 1, ToPlaceID: 2}, {FromPlaceID: 2, ToPlaceID: 4}}
+	require.NoError(t, domain.ValidateRoute(legs))
+	_, err = repo.SetShipmentLegs(ctx, 42, legs)
+	require.NoError(t, err)
+	require.NotNil(t, legs[0].DepartedAt)
+	assert.Equal(t, int64(3), legs[0].ID)
+	assert.Equal(t, domain.DeliveryShipped, legs[0].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_SetShipmentLegs_ReroutesDepartedLeg(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM shipments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	expectShipmentLegs(mock, time.Now().Add(-time.Hour))
+	mock.ExpectRollback()
+
+	legs := []domain.ShipmentLeg{{FromPlaceID: 1, ToPlaceID: 3}}
+	require.NoError(t, domain.ValidateRoute(legs))
+	s, err := repo.SetShipmentLegs(ctx, 42, legs)
+	assert.Nil(t, s)
+	assert.True(t, errors.Is(err, domain.ErrInvalidRoute))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_ConsolidateShipment_OffRoute(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	coCols := []string{"id", "to_location_id"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT direction FROM shipments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"direction"}).AddRow("outbound"))
+	mock.ExpectQuery("SELECT (.+) FROM shipment_legs WHERE shipment_id = \\$1").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(shipmentLegCols).
+			AddRow(1, 42, 1, 1, 2, "", "", nil, nil, "DeliveryPreparing", nil, nil, time.Now(), time.Now()).
+			AddRow(2, 42, 2, 2, 3, "", "", nil, nil, "DeliveryPreparing", nil, nil, time.Now(), time.Now()))
+	// Reservation 7 goes to venue 2, one of its check-outs with no destination set
+	mock.ExpectQuery("SELECT co.id, co.to_location_id FROM check_out_actions co WHERE co.reservation_id = \\$1 AND co.shipment_id IS NULL").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(coCols).AddRow(100, 2).AddRow(101, nil))
+	mock.ExpectExec("UPDATE check_out_actions SET shipment_id = \\$1 WHERE id = ANY\\(\\$2\\)").
+		WithArgs(42, "{100,101}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// Reservation 8 goes to venue 5, where the route does not stop
+	mock.ExpectQuery("SELECT co.id, co.to_location_id FROM check_out_actions co WHERE co.reservation_id = \\$1 AND co.shipment_id IS NULL").
+		WithArgs(8).
+		WillReturnRows(sqlmock.NewRows(coCols).AddRow(102, 5))
+	mock.ExpectRollback()
+
+	result, err := repo.ConsolidateShipment(ctx, 42, []int64{7, 8})
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, domain.ErrInvalidRoute))
+	assert.Contains(t, err.Error(), "check-out 102 of reservation 8 goes to place 5")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
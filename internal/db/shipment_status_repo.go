@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// transitionShipment moves a locked shipment from one status to the next, applies the
+// transition's side effects and raises its event. It returns the reservations whose
+// check-outs it completed, whose fulfillment needs re-evaluating once committed.
+func (r *SqlRepository) transitionShipment(ctx context.Context, tx *sql.Tx, shipmentID int64, from, to domain.DeliveryStatus, reason string, now time.Time) ([]int64, error) {
+	if err := domain.ValidateShipmentTransition(from, to); err != nil {
+		return nil, err
+	}
+
+	var reservations []int64
+	switch to {
+	case domain.DeliveryShipped:
+		if domain.NormalizeDeliveryStatus(from) != domain.DeliveryPreparing {
+			break // Back on the move after a delay; everything on board already went out
+		}
+		var err error
+		if reservations, err = shipCheckOuts(ctx, tx, shipmentID, now); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE shipment_legs SET status = $1, departed_at = $2, updated_at = $2
+			WHERE shipment_id = $3 AND sequence = 1 AND departed_at IS NULL`, domain.DeliveryShipped, now, shipmentID); err != nil {
+			return nil, fmt.Errorf("depart first leg: %w", err)
+		}
+	case domain.DeliveryDelivered:
+		if err := deliverShipment(ctx, tx, shipmentID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE shipments SET status = $1, updated_at = $2 WHERE id = $3", to, now, shipmentID); err != nil {
+		return nil, fmt.Errorf("update shipment status: %w", err)
+	}
+
+	eventType, _ := domain.ShipmentEventType(to)
+	payload, _ := json.Marshal(map[string]interface{}{
+		"shipment_id":     shipmentID,
+		"from_status":     domain.NormalizeDeliveryStatus(from),
+		"to_status":       to,
+		"reason":          reason,
+		"reservation_ids": reservations,
+	})
+	if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: eventType, Payload: payload}); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// shipCheckOuts completes the check-outs allocated to a shipment and deploys their
+// assets, honouring other reservations' holds as a direct check-out would.
+func shipCheckOuts(ctx context.Context, tx *sql.Tx, shipmentID int64, now time.Time) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE check_out_actions SET action_status = 'Completed', start_time = $1
+		WHERE shipment_id = $2 AND action_status = 'Potential'
+		RETURNING reservation_id, asset_id`, now, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("complete shipment check-outs: %w", err)
+	}
+	type shipped struct{ reservationID, assetID int64 }
+	var lines []shipped
+	for rows.Next() {
+		var l shipped
+		if err := rows.Scan(&l.reservationID, &l.assetID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan shipped check-out: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool)
+	var reservations, assetIDs []int64
+	for _, l := range lines {
+		if err := consumeHold(ctx, tx, l.reservationID, l.assetID, now); err != nil {
+			return nil, err
+		}
+		assetIDs = append(assetIDs, l.assetID)
+		if !seen[l.reservationID] {
+			seen[l.reservationID] = true
+			reservations = append(reservations, l.reservationID)
+		}
+	}
+	if len(assetIDs) > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE assets SET status = 'deployed', updated_at = $1 WHERE id = ANY($2)", now, pq.Array(assetIDs)); err != nil {
+			return nil, fmt.Errorf("deploy shipped assets: %w", err)
+		}
+	}
+	return reservations, nil
+}
+
+// deliverShipment places what a shipment carried at its destination: the end of its
+// route when it has one, otherwise where each check-out or return was headed.
+func deliverShipment(ctx context.Context, tx *sql.Tx, shipmentID int64, now time.Time) error {
+	var destination sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT to_place_id FROM shipment_legs WHERE shipment_id = $1 ORDER BY sequence DESC LIMIT 1`, shipmentID).Scan(&destination)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("find shipment destination: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE assets a SET place_id = COALESCE($1, co.to_location_id), updated_at = $2
+		FROM check_out_actions co
+		WHERE co.shipment_id = $3 AND co.asset_id = a.id AND COALESCE($1, co.to_location_id) IS NOT NULL
+		  AND `+outstandingCheckOut, destination, now, shipmentID); err != nil {
+		return fmt.Errorf("place delivered assets: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE assets a SET place_id = COALESCE($1, ret.to_location_id), updated_at = $2
+		FROM return_actions ret
+		WHERE ret.shipment_id = $3 AND ret.asset_id = a.id AND COALESCE($1, ret.to_location_id) IS NOT NULL`,
+		destination, now, shipmentID); err != nil {
+		return fmt.Errorf("place returned assets: %w", err)
+	}
+	if destination.Valid {
+		if err := deliverContainers(ctx, tx, shipmentID, destination.Int64, now); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE shipment_legs SET status = $1, departed_at = COALESCE(departed_at, $2), arrived_at = COALESCE(arrived_at, $2), updated_at = $2
+		WHERE shipment_id = $3`, domain.DeliveryDelivered, now, shipmentID); err != nil {
+		return fmt.Errorf("arrive shipment legs: %w", err)
+	}
+	return nil
+}
+
+// deliverContainers moves the containers loaded on a shipment, and everything in them,
+// to its destination.
+func deliverContainers(ctx context.Context, tx *sql.Tx, shipmentID, placeID int64, now time.Time) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT container_id FROM shipment_containers WHERE shipment_id = $1 AND parent_container_id IS NULL`, shipmentID)
+	if err != nil {
+		return fmt.Errorf("query shipment containers: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan shipment container: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return moveContainers(ctx, tx, ids, &placeID, now)
+}
+
+// syncFulfillment re-evaluates reservations whose check-outs changed outside the
+// usual check-out paths.
+func (r *SqlRepository) syncFulfillment(ctx context.Context, reservationIDs []int64) {
+	for _, id := range reservationIDs {
+		if fStatus, err := r.GetRentalFulfillmentStatus(ctx, id); err == nil {
+			r.UpdateRentalReservationStatus(ctx, id, domain.RentalReservationStatus(fStatus.Status))
+		}
+	}
+}
+
+// TransitionShipment moves a shipment to a new delivery status. Shipping completes the
+// check-outs allocated to it and deploys their assets; delivery places them at the
+// destination. It returns nil, nil when the shipment does not exist.
+func (r *SqlRepository) TransitionShipment(ctx context.Context, shipmentID int64, req *domain.ShipmentTransitionRequest) (*domain.Shipment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status domain.DeliveryStatus
+	err = tx.QueryRowContext(ctx, "SELECT status FROM shipments WHERE id = $1 FOR UPDATE", shipmentID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock shipment: %w", err)
+	}
+
+	reservations, err := r.transitionShipment(ctx, tx, shipmentID, status, req.Status, req.Reason, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.syncFulfillment(ctx, reservations)
+	return r.GetShipmentByID(ctx, shipmentID)
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_TransitionShipment_Delivered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM shipments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("DeliveryShipped"))
+	// The route ends at venue 3, so everything on board lands there
+	mock.ExpectQuery("SELECT to_place_id FROM shipment_legs WHERE shipment_id = \\$1 ORDER BY sequence DESC LIMIT 1").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"to_place_id"}).AddRow(3))
+	mock.ExpectExec("UPDATE assets a SET place_id = COALESCE\\(\\$1, co.to_location_id\\)").
+		WithArgs(3, sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE assets a SET place_id = COALESCE\\(\\$1, ret.to_location_id\\)").
+		WithArgs(3, sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// Pallet 5 was loaded at the top level; its case inside moves with it
+	mock.ExpectQuery("SELECT container_id FROM shipment_containers WHERE shipment_id = \\$1 AND parent_container_id IS NULL").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"container_id"}).AddRow(5))
+	mock.ExpectExec("WITH RECURSIVE container_tree (.+) UPDATE containers SET place_id = \\$2").
+		WithArgs("{5}", 3, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("WITH RECURSIVE container_tree (.+) UPDATE assets SET place_id = \\$2").
+		WithArgs("{5}", 3, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE shipment_legs SET status = \\$1, departed_at = COALESCE\\(departed_at, \\$2\\), arrived_at = COALESCE\\(arrived_at, \\$2\\)").
+		WithArgs(domain.DeliveryDelivered, sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE shipments SET status = \\$1, updated_at = \\$2 WHERE id = \\$3").
+		WithArgs(domain.DeliveryDelivered, sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO outbox_events").
+		WithArgs(domain.EventShipmentDelivered, sqlmock.AnyArg(), domain.OutboxPending, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM shipments WHERE id = \\$1").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.TransitionShipment(ctx, 42, &domain.ShipmentTransitionRequest{Status: domain.DeliveryDelivered})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_TransitionShipment_ShippedHeldElsewhere(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM shipments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("Preparing"))
+	mock.ExpectQuery("UPDATE check_out_actions SET action_status = 'Completed', start_time = \\$1 WHERE shipment_id = \\$2 AND action_status = 'Potential' RETURNING reservation_id, asset_id").
+		WithArgs(sqlmock.AnyArg(), 42).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_id", "asset_id"}).AddRow(9, 11).AddRow(9, 12))
+	mock.ExpectQuery("SELECT h.reservation_id FROM asset_holds h").
+		WithArgs(11, 9, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_id"}))
+	mock.ExpectExec("UPDATE asset_holds SET status = \\$1").
+		WithArgs(domain.HoldConsumed, sqlmock.AnyArg(), 9, 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Asset 12 is held for reservation 8, which it would not be back in time for
+	mock.ExpectQuery("SELECT h.reservation_id FROM asset_holds h").
+		WithArgs(12, 9, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_id"}).AddRow(8))
+	mock.ExpectRollback()
+
+	s, err := repo.TransitionShipment(ctx, 42, &domain.ShipmentTransitionRequest{Status: domain.DeliveryShipped})
+	assert.Nil(t, s)
+	assert.True(t, errors.Is(err, domain.ErrHoldConflict))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
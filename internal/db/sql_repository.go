@@ -25,12 +25,12 @@ func (r *SqlRepository) CreateItemType(ctx context.Context, it *domain.ItemType)
 	it.CreatedAt = now
 	it.UpdatedAt = now
 
-	query := `INSERT INTO item_types (code, name, kind, is_active, supported_features, created_by_user_id, updated_by_user_id, schema_org, metadata, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	query := `INSERT INTO item_types (code, name, kind, is_active, supported_features, pre_buffer_minutes, post_buffer_minutes, created_by_user_id, updated_by_user_id, schema_org, metadata, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`
 
 	featuresJSON, _ := json.Marshal(it.SupportedFeatures)
 	err := r.db.QueryRowContext(ctx, query,
-		it.Code, it.Name, it.Kind, it.IsActive, featuresJSON, it.CreatedByUserID, it.UpdatedByUserID, it.SchemaOrg, it.Metadata, it.CreatedAt, it.UpdatedAt,
+		it.Code, it.Name, it.Kind, it.IsActive, featuresJSON, it.PreBufferMinutes, it.PostBufferMinutes, it.CreatedByUserID, it.UpdatedByUserID, it.SchemaOrg, it.Metadata, it.CreatedAt, it.UpdatedAt,
 	).Scan(&it.ID)
 	if err != nil {
 		return fmt.Errorf("create item_type: %w", err)
@@ -40,13 +40,13 @@ func (r *SqlRepository) CreateItemType(ctx context.Context, it *domain.ItemType)
 
 // GetItemTypeByID retrieves an item type by its ID.
 func (r *SqlRepository) GetItemTypeByID(ctx context.Context, id int64) (*domain.ItemType, error) {
-	query := `SELECT id, code, name, kind, is_active, supported_features, created_by_user_id, updated_by_user_id, schema_org, metadata, created_at, updated_at 
+	query := `SELECT id, code, name, kind, is_active, supported_features, pre_buffer_minutes, post_buffer_minutes, created_by_user_id, updated_by_user_id, schema_org, metadata, created_at, updated_at 
 	          FROM item_types WHERE id = $1`
 
 	var it domain.ItemType
 	var featuresJSON, schemaOrgJSON, metadataJSON []byte
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&it.ID, &it.Code, &it.Name, &it.Kind, &it.IsActive, &featuresJSON, &it.CreatedByUserID, &it.UpdatedByUserID, &schemaOrgJSON, &metadataJSON, &it.CreatedAt, &it.UpdatedAt,
+		&it.ID, &it.Code, &it.Name, &it.Kind, &it.IsActive, &featuresJSON, &it.PreBufferMinutes, &it.PostBufferMinutes, &it.CreatedByUserID, &it.UpdatedByUserID, &schemaOrgJSON, &metadataJSON, &it.CreatedAt, &it.UpdatedAt,
 	)
 	if err == nil {
 		json.Unmarshal(featuresJSON, &it.SupportedFeatures)
@@ -64,7 +64,7 @@ func (r *SqlRepository) GetItemTypeByID(ctx context.Context, id int64) (*domain.
 
 // ListItemTypes returns item types, optionally including inactive ones.
 func (r *SqlRepository) ListItemTypes(ctx context.Context, includeInactive bool) ([]domain.ItemType, error) {
-	query := `SELECT id, code, name, kind, is_active, supported_features, created_by_user_id, updated_by_user_id, schema_org, metadata, created_at, updated_at 
+	query := `SELECT id, code, name, kind, is_active, supported_features, pre_buffer_minutes, post_buffer_minutes, created_by_user_id, updated_by_user_id, schema_org, metadata, created_at, updated_at 
 	          FROM item_types`
 	if !includeInactive {
 		query += ` WHERE is_active = TRUE`
@@ -80,7 +80,7 @@ func (r *SqlRepository) ListItemTypes(ctx context.Context, includeInactive bool)
 	for rows.Next() {
 		var it domain.ItemType
 		var featuresJSON, schemaOrgJSON, metadataJSON []byte
-		if err := rows.Scan(&it.ID, &it.Code, &it.Name, &it.Kind, &it.IsActive, &featuresJSON, &it.CreatedByUserID, &it.UpdatedByUserID, &schemaOrgJSON, &metadataJSON, &it.CreatedAt, &it.UpdatedAt); err != nil {
+		if err := rows.Scan(&it.ID, &it.Code, &it.Name, &it.Kind, &it.IsActive, &featuresJSON, &it.PreBufferMinutes, &it.PostBufferMinutes, &it.CreatedByUserID, &it.UpdatedByUserID, &schemaOrgJSON, &metadataJSON, &it.CreatedAt, &it.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("scan item_type: %w", err)
 		}
 		json.Unmarshal(featuresJSON, &it.SupportedFeatures)
@@ -94,12 +94,13 @@ func (r *SqlRepository) ListItemTypes(ctx context.Context, includeInactive bool)
 // UpdateItemType updates an existing item type.
 func (r *SqlRepository) UpdateItemType(ctx context.Context, it *domain.ItemType) error {
 	it.UpdatedAt = time.Now()
-	query := `UPDATE item_types SET code = $1, name = $2, kind = $3, is_active = $4, supported_features = $5, updated_by_user_id = $6, schema_org = $7, metadata = $8, updated_at = $9
-	          WHERE id = $10`
+	query := `UPDATE item_types SET code = $1, name = $2, kind = $3, is_active = $4, supported_features = $5, pre_buffer_minutes = $6, post_buffer_minutes = $7,
+	                 updated_by_user_id = $8, schema_org = $9, metadata = $10, updated_at = $11
+	          WHERE id = $12`
 
 	featuresJSON, _ := json.Marshal(it.SupportedFeatures)
 	_, err := r.db.ExecContext(ctx, query,
-		it.Code, it.Name, it.Kind, it.IsActive, featuresJSON, it.UpdatedByUserID, it.SchemaOrg, it.Metadata, it.UpdatedAt, it.ID,
+		it.Code, it.Name, it.Kind, it.IsActive, featuresJSON, it.PreBufferMinutes, it.PostBufferMinutes, it.UpdatedByUserID, it.SchemaOrg, it.Metadata, it.UpdatedAt, it.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update item_type: %w", err)
@@ -397,22 +398,41 @@ func (r *SqlRepository) CreateRentalReservation(ctx context.Context, rr *domain.
 	}
 	defer tx.Rollback()
 
-	now := time.Now()
+	if err := insertRentalReservation(ctx, tx, rr, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertRentalReservation writes a reservation and its demands inside tx.
+func insertRentalReservation(ctx context.Context, tx *sql.Tx, rr *domain.RentalReservation, now time.Time) error {
 	rr.CreatedAt = now
 	rr.UpdatedAt = now
 	if rr.BookingTime.IsZero() {
 		rr.BookingTime = now
 	}
+	// Every other status is reached through the state machine
+	switch rr.ReservationStatus {
+	case "":
+		rr.ReservationStatus = domain.ReservationStatusPending
+	case domain.ReservationStatusDraft, domain.ReservationStatusPending:
+	default:
+		return fmt.Errorf("%w: new reservations start as %s or %s", domain.ErrInvalidTransition,
+			domain.ReservationStatusDraft, domain.ReservationStatusPending)
+	}
+	if rr.ReservationStatus == domain.ReservationStatusPending {
+		rr.SubmittedAt = &now
+	}
 
 	query := `INSERT INTO rental_reservations (
 		reservation_name, reservation_status, under_name_id, booking_time, 
-		start_time, end_time, provider_id, metadata, created_at, updated_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		start_time, end_time, provider_id, series_id, recurrence_id, metadata, submitted_at, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	RETURNING id`
 
-	err = tx.QueryRowContext(ctx, query,
+	err := tx.QueryRowContext(ctx, query,
 		rr.ReservationName, rr.ReservationStatus, rr.UnderNameID, rr.BookingTime,
-		rr.StartTime, rr.EndTime, rr.ProviderID, rr.Metadata, rr.CreatedAt, rr.UpdatedAt,
+		rr.StartTime, rr.EndTime, rr.ProviderID, rr.SeriesID, rr.RecurrenceID, rr.Metadata, rr.SubmittedAt, rr.CreatedAt, rr.UpdatedAt,
 	).Scan(&rr.ID)
 	if err != nil {
 		return fmt.Errorf("insert rental_reservation: %w", err)
@@ -436,21 +456,31 @@ func (r *SqlRepository) CreateRentalReservation(ctx context.Context, rr *domain.
 			return fmt.Errorf("insert demand: %w", err)
 		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
 // GetRentalReservationByID retrieves a reservation by its ID, including its demands.
 func (r *SqlRepository) GetRentalReservationByID(ctx context.Context, id int64) (*domain.RentalReservation, error) {
+	return getRentalReservation(ctx, r.db, id, false)
+}
+
+// getRentalReservation loads a reservation and its demands through q, optionally
+// locking the reservation row for the rest of the transaction.
+func getRentalReservation(ctx context.Context, q queryer, id int64, forUpdate bool) (*domain.RentalReservation, error) {
 	query := `SELECT id, reservation_name, reservation_status, under_name_id, booking_time, 
-	                 start_time, end_time, provider_id, metadata, created_at, updated_at 
+	                 start_time, end_time, provider_id, series_id, recurrence_id, metadata, 
+	                 submitted_at, approved_at, rejected_at, cancelled_at, fulfilled_at, overdue_at, updated_by_user_id, created_at, updated_at 
 	          FROM rental_reservations WHERE id = $1`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
 
 	var rr domain.RentalReservation
 	var metadataJSON []byte
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := q.QueryRowContext(ctx, query, id).Scan(
 		&rr.ID, &rr.ReservationName, &rr.ReservationStatus, &rr.UnderNameID, &rr.BookingTime,
-		&rr.StartTime, &rr.EndTime, &rr.ProviderID, &metadataJSON, &rr.CreatedAt, &rr.UpdatedAt,
+		&rr.StartTime, &rr.EndTime, &rr.ProviderID, &rr.SeriesID, &rr.RecurrenceID, &metadataJSON,
+		&rr.SubmittedAt, &rr.ApprovedAt, &rr.RejectedAt, &rr.CancelledAt, &rr.FulfilledAt, &rr.OverdueAt, &rr.UpdatedByUserID, &rr.CreatedAt, &rr.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -462,9 +492,9 @@ func (r *SqlRepository) GetRentalReservationByID(ctx context.Context, id int64)
 
 	demandQuery := `SELECT id, reservation_id, event_id, item_kind, item_id, requested_quantity, 
 	                       business_function, eligible_duration, place_id, metadata, created_at, updated_at 
-	                FROM demands WHERE reservation_id = $1`
+	                FROM demands WHERE reservation_id = $1 ORDER BY id`
 
-	rows, err := r.db.QueryContext(ctx, demandQuery, id)
+	rows, err := q.QueryContext(ctx, demandQuery, id)
 	if err != nil {
 		return nil, fmt.Errorf("query demands: %w", err)
 	}
@@ -489,7 +519,8 @@ func (r *SqlRepository) GetRentalReservationByID(ctx context.Context, id int64)
 // ListRentalReservations returns all rental reservations.
 func (r *SqlRepository) ListRentalReservations(ctx context.Context) ([]domain.RentalReservation, error) {
 	query := `SELECT id, reservation_name, reservation_status, under_name_id, booking_time, 
-	                 start_time, end_time, provider_id, metadata, created_at, updated_at 
+	                 start_time, end_time, provider_id, series_id, recurrence_id, metadata, 
+	                 submitted_at, approved_at, rejected_at, cancelled_at, fulfilled_at, overdue_at, updated_by_user_id, created_at, updated_at 
 	          FROM rental_reservations ORDER BY created_at DESC`
 
 	rows, err := r.db.QueryContext(ctx, query)
@@ -504,7 +535,8 @@ func (r *SqlRepository) ListRentalReservations(ctx context.Context) ([]domain.Re
 		var metadataJSON []byte
 		err := rows.Scan(
 			&rr.ID, &rr.ReservationName, &rr.ReservationStatus, &rr.UnderNameID, &rr.BookingTime,
-			&rr.StartTime, &rr.EndTime, &rr.ProviderID, &metadataJSON, &rr.CreatedAt, &rr.UpdatedAt,
+			&rr.StartTime, &rr.EndTime, &rr.ProviderID, &rr.SeriesID, &rr.RecurrenceID, &metadataJSON,
+			&rr.SubmittedAt, &rr.ApprovedAt, &rr.RejectedAt, &rr.CancelledAt, &rr.FulfilledAt, &rr.OverdueAt, &rr.UpdatedByUserID, &rr.CreatedAt, &rr.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan rental_reservation: %w", err)
@@ -533,13 +565,6 @@ func (r *SqlRepository) UpdateRentalReservation(ctx context.Context, rr *domain.
 	return nil
 }
 
-// UpdateRentalReservationStatus updates only the status for a reservation.
-func (r *SqlRepository) UpdateRentalReservationStatus(ctx context.Context, id int64, status domain.RentalReservationStatus) error {
-	query := "UPDATE rental_reservations SET reservation_status = $1, updated_at = $2 WHERE id = $3"
-	_, err := r.db.ExecContext(ctx, query, status, time.Now(), id)
-	return err
-}
-
 // Demand Methods
 
 func (r *SqlRepository) CreateDemand(ctx context.Context, d *domain.Demand) error {
@@ -624,9 +649,9 @@ func (r *SqlRepository) DeleteDemand(ctx context.Context, id int64) error {
 func (r *SqlRepository) CreateCheckOutAction(ctx context.Context, co *domain.CheckOutAction) error {
 	query := `INSERT INTO check_out_actions (
 		reservation_id, asset_id, agent_id, recipient_id, shipment_id, scheduled_delivery_id, start_time, 
-		from_location_id, to_location_id, action_status, metadata
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
-	return r.db.QueryRowContext(ctx, query, co.ReservationID, co.AssetID, co.AgentID, co.RecipientID, co.ShipmentID, co.ScheduledDeliveryID, co.StartTime, co.FromLocation, co.ToLocation, co.Status, co.Metadata).Scan(&co.ID)
+		from_location_id, to_location_id, action_status, metadata, kit_instance_id
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, co.ReservationID, co.AssetID, co.AgentID, co.RecipientID, co.ShipmentID, co.ScheduledDeliveryID, co.StartTime, co.FromLocation, co.ToLocation, co.Status, co.Metadata, co.KitInstanceID).Scan(&co.ID)
 }
 
 func (r *SqlRepository) CreateReturnAction(ctx context.Context, ra *domain.ReturnAction) error {
@@ -639,7 +664,7 @@ func (r *SqlRepository) CreateReturnAction(ctx context.Context, ra *domain.Retur
 
 func (r *SqlRepository) ListCheckOutActions(ctx context.Context, reservationID int64) ([]domain.CheckOutAction, error) {
 	query := `SELECT id, reservation_id, asset_id, agent_id, recipient_id, shipment_id, scheduled_delivery_id, start_time, 
-	                 from_location_id, to_location_id, action_status, metadata 
+	                 from_location_id, to_location_id, action_status, metadata, kit_instance_id 
 	          FROM check_out_actions WHERE reservation_id = $1`
 	rows, err := r.db.QueryContext(ctx, query, reservationID)
 	if err != nil {
@@ -651,7 +676,7 @@ func (r *SqlRepository) ListCheckOutActions(ctx context.Context, reservationID i
 	for rows.Next() {
 		var co domain.CheckOutAction
 		var metadataJSON []byte
-		if err := rows.Scan(&co.ID, &co.ReservationID, &co.AssetID, &co.AgentID, &co.RecipientID, &co.ShipmentID, &co.ScheduledDeliveryID, &co.StartTime, &co.FromLocation, &co.ToLocation, &co.Status, &metadataJSON); err != nil {
+		if err := rows.Scan(&co.ID, &co.ReservationID, &co.AssetID, &co.AgentID, &co.RecipientID, &co.ShipmentID, &co.ScheduledDeliveryID, &co.StartTime, &co.FromLocation, &co.ToLocation, &co.Status, &metadataJSON, &co.KitInstanceID); err != nil {
 			return nil, err
 		}
 		co.Metadata = json.RawMessage(metadataJSON)
@@ -683,16 +708,30 @@ func (r *SqlRepository) ListReturnActions(ctx context.Context, reservationID int
 	return results, nil
 }
 
+// heldLineQuery is a lateral subquery finding the demand line item type that the asset
+// moved by the aliased action was held for.
+func heldLineQuery(action string) string {
+	return `SELECT item_type_id FROM asset_holds
+	        WHERE reservation_id = ` + action + `.reservation_id AND asset_id = ` + action + `.asset_id
+	          AND status IN ('active', 'consumed')
+	        ORDER BY id DESC LIMIT 1`
+}
+
 // GetRentalFulfillmentStatus calculates the delta between demands and actual movements.
 func (r *SqlRepository) GetRentalFulfillmentStatus(ctx context.Context, reservationID int64) (*domain.RentalFulfillmentStatus, error) {
 	demands, err := r.ListDemandsByReservation(ctx, reservationID)
 	if err != nil {
 		return nil, err
 	}
+	held, err := heldSubstitutions(ctx, r.db, reservationID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Map to track fulfillment per demand
 	// For simplicity, we assume one demand per (item_kind, item_id)
 	lineMap := make(map[string]*domain.FulfillmentLine)
+	kitLines := make(map[int64]*domain.FulfillmentLine)
 	for _, d := range demands {
 		key := fmt.Sprintf("%s:%d", d.ItemKind, d.ItemID)
 		lineMap[key] = &domain.FulfillmentLine{
@@ -700,16 +739,29 @@ func (r *SqlRepository) GetRentalFulfillmentStatus(ctx context.Context, reservat
 			ItemKind:          d.ItemKind,
 			ItemID:            d.ItemID,
 			RequestedQuantity: d.Quantity,
+			Substitutions:     append(append([]domain.Substitution{}, held[d.ID]...), domain.DemandSubstitutions(d.Metadata)...),
+		}
+		if d.ItemKind == domain.DemandKindKitTemplate {
+			kitLines[d.ID] = lineMap[key]
 		}
 	}
 
-	// Better approach: use a combined query or join
+	// Assets are counted against the line their hold was for, so substitutes
+	// fulfill the item type that was requested rather than their own. Fungible
+	// stock goes out by quantity on the stock ledger.
 	coQuery := `
-		SELECT a.item_type_id, COUNT(*) 
+		SELECT COALESCE(h.item_type_id, a.item_type_id), COUNT(*),
+		       COUNT(*) FILTER (WHERE h.item_type_id <> a.item_type_id)
 		FROM check_out_actions co
 		JOIN assets a ON co.asset_id = a.id
-		WHERE co.reservation_id = $1 AND co.action_status = 'Completed'
-		GROUP BY a.item_type_id
+		LEFT JOIN LATERAL (` + heldLineQuery("co") + `) h ON true
+		WHERE co.reservation_id = $1 AND co.action_status = 'Completed' AND co.kit_instance_id IS NULL
+		GROUP BY 1
+		UNION ALL
+		SELECT item_type_id, SUM(quantity), 0
+		FROM stock_movements
+		WHERE reservation_id = $1 AND kind = 'check_out'
+		GROUP BY 1
 	`
 	rows, err := r.db.QueryContext(ctx, coQuery, reservationID)
 	if err != nil {
@@ -718,22 +770,33 @@ func (r *SqlRepository) GetRentalFulfillmentStatus(ctx context.Context, reservat
 	defer rows.Close()
 	for rows.Next() {
 		var itemID int64
-		var count int
-		if err := rows.Scan(&itemID, &count); err != nil {
+		var count, substituted int
+		if err := rows.Scan(&itemID, &count, &substituted); err != nil {
 			return nil, err
 		}
 		key := fmt.Sprintf("item_type:%d", itemID)
 		if line, ok := lineMap[key]; ok {
-			line.FulfilledQuantity = count
+			line.FulfilledQuantity += count
+			line.SubstitutedQuantity += substituted
 		}
 	}
 
 	retQuery := `
-		SELECT a.item_type_id, COUNT(*) 
+		SELECT COALESCE(h.item_type_id, a.item_type_id), COUNT(*)
 		FROM return_actions ret
 		JOIN assets a ON ret.asset_id = a.id
+		LEFT JOIN LATERAL (` + heldLineQuery("ret") + `) h ON true
 		WHERE ret.reservation_id = $1 AND ret.action_status = 'Completed'
-		GROUP BY a.item_type_id
+		  AND NOT EXISTS (
+		      SELECT 1 FROM check_out_actions co
+		      WHERE co.reservation_id = ret.reservation_id AND co.asset_id = ret.asset_id AND co.kit_instance_id IS NOT NULL
+		  )
+		GROUP BY 1
+		UNION ALL
+		SELECT item_type_id, SUM(quantity)
+		FROM stock_movements
+		WHERE reservation_id = $1 AND kind = 'return'
+		GROUP BY 1
 	`
 	rows2, err := r.db.QueryContext(ctx, retQuery, reservationID)
 	if err != nil {
@@ -748,7 +811,33 @@ func (r *SqlRepository) GetRentalFulfillmentStatus(ctx context.Context, reservat
 		}
 		key := fmt.Sprintf("item_type:%d", itemID)
 		if line, ok := lineMap[key]; ok {
-			line.ReturnedQuantity = count
+			line.ReturnedQuantity += count
+		}
+	}
+
+	// Kit demands are fulfilled per kit instance rather than per asset
+	if len(kitLines) > 0 {
+		kitQuery := `
+			SELECT demand_id, COUNT(*), COUNT(returned_at)
+			FROM kit_instances
+			WHERE reservation_id = $1
+			GROUP BY demand_id
+		`
+		rows3, err := r.db.QueryContext(ctx, kitQuery, reservationID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows3.Close()
+		for rows3.Next() {
+			var demandID int64
+			var out, returned int
+			if err := rows3.Scan(&demandID, &out, &returned); err != nil {
+				return nil, err
+			}
+			if line, ok := kitLines[demandID]; ok {
+				line.FulfilledQuantity = out
+				line.ReturnedQuantity = returned
+			}
 		}
 	}
 
@@ -787,22 +876,8 @@ func (r *SqlRepository) BatchCheckOut(ctx context.Context, reservationID int64,
 	}
 	defer tx.Rollback()
 
-	now := time.Now()
-	for _, assetID := range assetIDs {
-		// 1. Create CheckOutAction
-		coQuery := `INSERT INTO check_out_actions (reservation_id, asset_id, agent_id, start_time, from_location_id, to_location_id, action_status)
-		            VALUES ($1, $2, $3, $4, $5, $6, $7)`
-		_, err = tx.ExecContext(ctx, coQuery, reservationID, assetID, agentID, now, fromLocationID, toLocationID, "Completed")
-		if err != nil {
-			return fmt.Errorf("checkout %d: %w", assetID, err)
-		}
-
-		// 2. Update Asset
-		assetQuery := `UPDATE assets SET status = 'deployed', place_id = $1, updated_at = $2 WHERE id = $3`
-		_, err = tx.ExecContext(ctx, assetQuery, toLocationID, now, assetID)
-		if err != nil {
-			return fmt.Errorf("update asset %d: %w", assetID, err)
-		}
+	if err := checkOutAssets(ctx, tx, reservationID, assetIDs, agentID, fromLocationID, toLocationID, nil, time.Now()); err != nil {
+		return err
 	}
 
 	// 3. Update Reservation Status based on new fulfillment state
@@ -831,12 +906,52 @@ func (r *SqlRepository) BatchReturn(ctx context.Context, reservationID int64, as
 	}
 	defer tx.Rollback()
 
-	now := time.Now()
+	if err := returnAssets(ctx, tx, reservationID, assetIDs, agentID, toLocationID, time.Now()); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkOutAssets dispatches assets straight to a reservation, as completed check-outs,
+// tagged with the kit instance they went out as when there is one.
+func checkOutAssets(ctx context.Context, tx *sql.Tx, reservationID int64, assetIDs []int64, agentID int64, fromLocationID, toLocationID, kitInstanceID *int64, now time.Time) error {
+	for _, assetID := range assetIDs {
+		// 0. Respect hard allocations made for other reservations
+		if err := consumeHold(ctx, tx, reservationID, assetID, now); err != nil {
+			return err
+		}
+
+		// 1. Create CheckOutAction
+		coQuery := `INSERT INTO check_out_actions (reservation_id, asset_id, agent_id, start_time, from_location_id, to_location_id, action_status, kit_instance_id)
+		            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		_, err := tx.ExecContext(ctx, coQuery, reservationID, assetID, agentID, now, fromLocationID, toLocationID, "Completed", kitInstanceID)
+		if err != nil {
+			return fmt.Errorf("checkout %d: %w", assetID, err)
+		}
+
+		// 2. Update Asset
+		assetQuery := `UPDATE assets SET status = 'deployed', place_id = $1, updated_at = $2 WHERE id = $3`
+		_, err = tx.ExecContext(ctx, assetQuery, toLocationID, now, assetID)
+		if err != nil {
+			return fmt.Errorf("update asset %d: %w", assetID, err)
+		}
+	}
+	return nil
+}
+
+// returnAssets takes assets back from a reservation and closes out the kits they complete.
+func returnAssets(ctx context.Context, tx *sql.Tx, reservationID int64, assetIDs []int64, agentID int64, toLocationID *int64, now time.Time) error {
 	for _, assetID := range assetIDs {
 		// 1. Create ReturnAction
 		raQuery := `INSERT INTO return_actions (reservation_id, asset_id, agent_id, start_time, to_location_id, action_status)
 		            VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err = tx.ExecContext(ctx, raQuery, reservationID, assetID, agentID, now, toLocationID, "Completed")
+		_, err := tx.ExecContext(ctx, raQuery, reservationID, assetID, agentID, now, toLocationID, "Completed")
 		if err != nil {
 			return fmt.Errorf("return %d: %w", assetID, err)
 		}
@@ -849,55 +964,30 @@ func (r *SqlRepository) BatchReturn(ctx context.Context, reservationID int64, as
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// 3. Close out kit instances whose assets are all back
+	return closeReturnedKits(ctx, tx, reservationID, now)
 }
 
-// GetAvailableQuantity calculates the available inventory for an item type in a given time window.
-func (r *SqlRepository) GetAvailableQuantity(ctx context.Context, itemTypeID int64, startTime, endTime time.Time) (int, error) {
-	// 1. Get total assets for this item type (excluding retired)
-	var total int
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM assets WHERE item_type_id = $1 AND status != 'retired'", itemTypeID).Scan(&total)
-	if err != nil {
-		return 0, fmt.Errorf("count assets: %w", err)
-	}
-
-	// 2. Subtract quantities from overlapping CONFIRMED reservations
-	// Schema.org: ReservationConfirmed
-	query := `
-		SELECT COALESCE(SUM(d.requested_quantity), 0)
-		FROM demands d
-		JOIN rental_reservations rr ON d.reservation_id = rr.id
-		WHERE d.item_kind = 'item_type' 
-		  AND d.item_id = $1
-		  AND rr.reservation_status = 'ReservationConfirmed'
-		  AND rr.start_time < $3
-		  AND rr.end_time > $2
-	`
-	var reserved int
-	err = r.db.QueryRowContext(ctx, query, itemTypeID, startTime, endTime).Scan(&reserved)
-	if err != nil {
-		return 0, fmt.Errorf("sum reserved quantity: %w", err)
-	}
-
-	// 3. Subtract Ad-Hoc Usage
-	queryAdHoc := `
-		SELECT COUNT(*) FROM assets 
-		WHERE item_type_id = $1 
-		  AND status IN ('deployed', 'maintenance')
-		  AND (metadata->>'estimated_return_at' IS NULL OR (metadata->>'estimated_return_at')::timestamp > $2)
+// closeReturnedKits marks the reservation's kit instances returned once every asset
+// that went out with them has come back.
+func closeReturnedKits(ctx context.Context, tx *sql.Tx, reservationID int64, now time.Time) error {
+	kitQuery := `
+		UPDATE kit_instances ki SET returned_at = $2
+		WHERE ki.reservation_id = $1 AND ki.returned_at IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM check_out_actions co
+		      WHERE co.kit_instance_id = ki.id
+		        AND NOT EXISTS (
+		            SELECT 1 FROM return_actions ra
+		            WHERE ra.reservation_id = co.reservation_id AND ra.asset_id = co.asset_id
+		              AND ra.action_status = 'Completed' AND ra.start_time >= co.start_time
+		        )
+		  )
 	`
-	var adHoc int
-	err = r.db.QueryRowContext(ctx, queryAdHoc, itemTypeID, startTime).Scan(&adHoc)
-	if err != nil {
-		return 0, fmt.Errorf("count ad-hoc usage: %w", err)
+	if _, err := tx.ExecContext(ctx, kitQuery, reservationID, now); err != nil {
+		return fmt.Errorf("update kit instances: %w", err)
 	}
-
-	return total - reserved - adHoc, nil
+	return nil
 }
 
 // AddMaintenanceLog records a new maintenance activity.
@@ -959,8 +1049,8 @@ func (r *SqlRepository) CreateInspectionTemplate(ctx context.Context, it *domain
 	for i := range it.Fields {
 		f := &it.Fields[i]
 		f.TemplateID = it.ID
-		err = tx.QueryRowContext(ctx, "INSERT INTO inspection_fields (template_id, label, field_type, required, display_order) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-			f.TemplateID, f.Label, f.Type, f.Required, f.DisplayOrder,
+		err = tx.QueryRowContext(ctx, "INSERT INTO inspection_fields (template_id, label, field_type, required, display_order, damage_value, damage_charge) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7) RETURNING id",
+			f.TemplateID, f.Label, f.Type, f.Required, f.DisplayOrder, f.DamageValue, f.DamageCharge,
 		).Scan(&f.ID)
 		if err != nil {
 			return fmt.Errorf("insert field: %w", err)
@@ -1015,8 +1105,8 @@ func (r *SqlRepository) UpdateInspectionTemplate(ctx context.Context, it *domain
 	for i := range it.Fields {
 		f := &it.Fields[i]
 		f.TemplateID = it.ID
-		err = tx.QueryRowContext(ctx, "INSERT INTO inspection_fields (template_id, label, field_type, required, display_order) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-			f.TemplateID, f.Label, f.Type, f.Required, f.DisplayOrder,
+		err = tx.QueryRowContext(ctx, "INSERT INTO inspection_fields (template_id, label, field_type, required, display_order, damage_value, damage_charge) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7) RETURNING id",
+			f.TemplateID, f.Label, f.Type, f.Required, f.DisplayOrder, f.DamageValue, f.DamageCharge,
 		).Scan(&f.ID)
 		if err != nil {
 			return fmt.Errorf("re-insert field: %w", err)
@@ -1067,7 +1157,7 @@ func (r *SqlRepository) GetInspectionTemplate(ctx context.Context, id int64) (*d
 		return nil, fmt.Errorf("query template: %w", err)
 	}
 
-	rows, err := r.db.QueryContext(ctx, "SELECT id, template_id, label, field_type, required, display_order FROM inspection_fields WHERE template_id = $1 ORDER BY display_order", it.ID)
+	rows, err := r.db.QueryContext(ctx, "SELECT id, template_id, label, field_type, required, display_order, COALESCE(damage_value, ''), damage_charge FROM inspection_fields WHERE template_id = $1 ORDER BY display_order", it.ID)
 	if err != nil {
 		return nil, fmt.Errorf("query fields: %w", err)
 	}
@@ -1075,7 +1165,7 @@ func (r *SqlRepository) GetInspectionTemplate(ctx context.Context, id int64) (*d
 
 	for rows.Next() {
 		var f domain.InspectionField
-		if err := rows.Scan(&f.ID, &f.TemplateID, &f.Label, &f.Type, &f.Required, &f.DisplayOrder); err != nil {
+		if err := rows.Scan(&f.ID, &f.TemplateID, &f.Label, &f.Type, &f.Required, &f.DisplayOrder, &f.DamageValue, &f.DamageCharge); err != nil {
 			return nil, err
 		}
 		it.Fields = append(it.Fields, f)
@@ -1105,7 +1195,7 @@ func (r *SqlRepository) GetInspectionTemplatesForItemType(ctx context.Context, i
 		}
 
 		// Fetch fields for each template
-		fRows, err := r.db.QueryContext(ctx, "SELECT id, template_id, label, field_type, required, display_order FROM inspection_fields WHERE template_id = $1 ORDER BY display_order", t.ID)
+		fRows, err := r.db.QueryContext(ctx, "SELECT id, template_id, label, field_type, required, display_order, COALESCE(damage_value, ''), damage_charge FROM inspection_fields WHERE template_id = $1 ORDER BY display_order", t.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -1113,7 +1203,7 @@ func (r *SqlRepository) GetInspectionTemplatesForItemType(ctx context.Context, i
 
 		for fRows.Next() {
 			var f domain.InspectionField
-			if err := fRows.Scan(&f.ID, &f.TemplateID, &f.Label, &f.Type, &f.Required, &f.DisplayOrder); err != nil {
+			if err := fRows.Scan(&f.ID, &f.TemplateID, &f.Label, &f.Type, &f.Required, &f.DisplayOrder, &f.DamageValue, &f.DamageCharge); err != nil {
 				return nil, err
 			}
 			t.Fields = append(t.Fields, f)
@@ -1473,74 +1563,6 @@ func (r *SqlRepository) ListWebhooks(ctx context.Context) ([]domain.WebhookConfi
 	return results, nil
 }
 
-// GetAvailabilityTimeline returns availability data points over a range of dates.
-func (r *SqlRepository) GetAvailabilityTimeline(ctx context.Context, itemTypeID int64, start, end time.Time) ([]domain.AvailabilityPoint, error) {
-	var points []domain.AvailabilityPoint
-	// Iterate day by day
-	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
-		dayEnd := d.AddDate(0, 0, 1)
-		avail, err := r.GetAvailableQuantity(ctx, itemTypeID, d, dayEnd)
-		if err != nil {
-			return nil, err
-		}
-
-		var total int
-		err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM assets WHERE item_type_id = $1 AND status != 'retired'", itemTypeID).Scan(&total)
-		if err != nil {
-			return nil, err
-		}
-
-		points = append(points, domain.AvailabilityPoint{
-			Date:      d,
-			Available: avail,
-			Total:     total,
-		})
-	}
-	return points, nil
-}
-
-// GetShortageAlerts identifies future bottlenecks in inventory.
-func (r *SqlRepository) GetShortageAlerts(ctx context.Context) ([]domain.ShortageAlert, error) {
-	// Simplified: Check next 14 days for all item types
-	var alerts []domain.ShortageAlert
-	start := time.Now()
-	end := start.AddDate(0, 0, 14)
-
-	rows, err := r.db.QueryContext(ctx, "SELECT id, name FROM item_types WHERE is_active = true")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var itID int64
-		var itName string
-		if err := rows.Scan(&itID, &itName); err != nil {
-			continue
-		}
-
-		timeline, err := r.GetAvailabilityTimeline(ctx, itID, start, end)
-		if err != nil {
-			continue
-		}
-
-		for _, p := range timeline {
-			if p.Available < 0 {
-				alerts = append(alerts, domain.ShortageAlert{
-					ItemTypeID:    itID,
-					ItemTypeName:  itName,
-					Date:          p.Date,
-					ShortageCount: -p.Available,
-					TotalNeeded:   p.Total - p.Available,
-					TotalOwned:    p.Total,
-				})
-			}
-		}
-	}
-
-	return alerts, nil
-}
-
 // GetMaintenanceForecast predicts inspection needs based on calendar cycles and usage.
 func (r *SqlRepository) GetMaintenanceForecast(ctx context.Context) ([]domain.MaintenanceForecast, error) {
 	// Assets not inspected in > 90 days OR nearing usage limit
@@ -1878,17 +1900,17 @@ func (r *SqlRepository) CreatePlace(ctx context.Context, p *domain.Place) error
 	p.CreatedAt = now
 	p.UpdatedAt = now
 	addrJSON, _ := json.Marshal(p.Address)
-	query := `INSERT INTO places (name, description, contained_in_place_id, owner_id, category, address, is_internal, presumed_demands, metadata, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
-	return r.db.QueryRowContext(ctx, query, p.Name, p.Description, p.ContainedInPlaceID, p.OwnerID, p.Category, addrJSON, p.IsInternal, p.PresumedDemands, p.Metadata, p.CreatedAt, p.UpdatedAt).Scan(&p.ID)
+	query := `INSERT INTO places (name, description, contained_in_place_id, owner_id, category, address, is_internal, transit_minutes, presumed_demands, metadata, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, p.Name, p.Description, p.ContainedInPlaceID, p.OwnerID, p.Category, addrJSON, p.IsInternal, p.TransitMinutes, p.PresumedDemands, p.Metadata, p.CreatedAt, p.UpdatedAt).Scan(&p.ID)
 }
 
 func (r *SqlRepository) GetPlace(ctx context.Context, id int64) (*domain.Place, error) {
-	query := `SELECT id, name, description, contained_in_place_id, owner_id, category, address, is_internal, presumed_demands, metadata, created_at, updated_at FROM places WHERE id = $1`
+	query := `SELECT id, name, description, contained_in_place_id, owner_id, category, address, is_internal, transit_minutes, presumed_demands, metadata, created_at, updated_at FROM places WHERE id = $1`
 	var p domain.Place
 	var addrJSON, demandsJSON, metadataJSON []byte
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&p.ID, &p.Name, &p.Description, &p.ContainedInPlaceID, &p.OwnerID, &p.Category, &addrJSON, &p.IsInternal, &demandsJSON, &metadataJSON, &p.CreatedAt, &p.UpdatedAt,
+		&p.ID, &p.Name, &p.Description, &p.ContainedInPlaceID, &p.OwnerID, &p.Category, &addrJSON, &p.IsInternal, &p.TransitMinutes, &demandsJSON, &metadataJSON, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -1905,7 +1927,7 @@ func (r *SqlRepository) GetPlace(ctx context.Context, id int64) (*domain.Place,
 }
 
 func (r *SqlRepository) ListPlaces(ctx context.Context, ownerID *int64, parentID *int64) ([]domain.Place, error) {
-	query := `SELECT id, name, description, contained_in_place_id, owner_id, category, address, is_internal, presumed_demands, metadata, created_at, updated_at FROM places WHERE 1=1`
+	query := `SELECT id, name, description, contained_in_place_id, owner_id, category, address, is_internal, transit_minutes, presumed_demands, metadata, created_at, updated_at FROM places WHERE 1=1`
 	var args []interface{}
 	idx := 1
 	if ownerID != nil {
@@ -1930,7 +1952,7 @@ func (r *SqlRepository) ListPlaces(ctx context.Context, ownerID *int64, parentID
 		var p domain.Place
 		var addrJSON, demandsJSON, metadataJSON []byte
 		if err := rows.Scan(
-			&p.ID, &p.Name, &p.Description, &p.ContainedInPlaceID, &p.OwnerID, &p.Category, &addrJSON, &p.IsInternal, &demandsJSON, &metadataJSON, &p.CreatedAt, &p.UpdatedAt,
+			&p.ID, &p.Name, &p.Description, &p.ContainedInPlaceID, &p.OwnerID, &p.Category, &addrJSON, &p.IsInternal, &p.TransitMinutes, &demandsJSON, &metadataJSON, &p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1947,8 +1969,8 @@ func (r *SqlRepository) ListPlaces(ctx context.Context, ownerID *int64, parentID
 func (r *SqlRepository) UpdatePlace(ctx context.Context, p *domain.Place) error {
 	p.UpdatedAt = time.Now()
 	addrJSON, _ := json.Marshal(p.Address)
-	query := `UPDATE places SET name = $1, description = $2, contained_in_place_id = $3, owner_id = $4, category = $5, address = $6, is_internal = $7, presumed_demands = $8, metadata = $9, updated_at = $10 WHERE id = $11`
-	_, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.ContainedInPlaceID, p.OwnerID, p.Category, addrJSON, p.IsInternal, p.PresumedDemands, p.Metadata, p.UpdatedAt, p.ID)
+	query := `UPDATE places SET name = $1, description = $2, contained_in_place_id = $3, owner_id = $4, category = $5, address = $6, is_internal = $7, transit_minutes = $8, presumed_demands = $9, metadata = $10, updated_at = $11 WHERE id = $12`
+	_, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.ContainedInPlaceID, p.OwnerID, p.Category, addrJSON, p.IsInternal, p.TransitMinutes, p.PresumedDemands, p.Metadata, p.UpdatedAt, p.ID)
 	return err
 }
 
@@ -2062,10 +2084,10 @@ func (r *SqlRepository) GetDefaultInternalPlace(ctx context.Context) (*domain.Pl
 	// 1. Try to find an existing internal place named 'Main Warehouse'
 	var p domain.Place
 	var addrJSON, demandsJSON, metadataJSON []byte
-	query := `SELECT id, name, description, contained_in_place_id, owner_id, category, address, is_internal, presumed_demands, metadata, created_at, updated_at 
+	query := `SELECT id, name, description, contained_in_place_id, owner_id, category, address, is_internal, transit_minutes, presumed_demands, metadata, created_at, updated_at 
 	          FROM places WHERE is_internal = TRUE LIMIT 1`
 	err := r.db.QueryRowContext(ctx, query).Scan(
-		&p.ID, &p.Name, &p.Description, &p.ContainedInPlaceID, &p.OwnerID, &p.Category, &addrJSON, &p.IsInternal, &demandsJSON, &metadataJSON, &p.CreatedAt, &p.UpdatedAt,
+		&p.ID, &p.Name, &p.Description, &p.ContainedInPlaceID, &p.OwnerID, &p.Category, &addrJSON, &p.IsInternal, &p.TransitMinutes, &demandsJSON, &metadataJSON, &p.CreatedAt, &p.UpdatedAt,
 	)
 
 	if err == nil {
@@ -2370,15 +2392,18 @@ func (r *SqlRepository) CreateScheduledDelivery(ctx context.Context, sd *domain.
 	now := time.Now()
 	sd.CreatedAt = now
 	sd.UpdatedAt = now
-	query := `INSERT INTO scheduled_deliveries (event_id, target_date, notes, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	return r.db.QueryRowContext(ctx, query, sd.EventID, sd.TargetDate, sd.Notes, sd.CreatedAt, sd.UpdatedAt).Scan(&sd.ID)
+	query := `INSERT INTO scheduled_deliveries (event_id, target_date, destination_place_id, window_start, window_end, notes, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, sd.EventID, sd.TargetDate, sd.DestinationPlaceID, sd.WindowStart, sd.WindowEnd,
+		sd.Notes, sd.CreatedAt, sd.UpdatedAt).Scan(&sd.ID)
 }
 
 func (r *SqlRepository) GetScheduledDeliveryByID(ctx context.Context, id int64) (*domain.ScheduledDelivery, error) {
-	query := `SELECT id, event_id, target_date, COALESCE(notes, ''), created_at, updated_at FROM scheduled_deliveries WHERE id = $1`
+	query := `SELECT id, event_id, target_date, destination_place_id, window_start, window_end, COALESCE(notes, ''), created_at, updated_at
+	          FROM scheduled_deliveries WHERE id = $1`
 	var sd domain.ScheduledDelivery
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&sd.ID, &sd.EventID, &sd.TargetDate, &sd.Notes, &sd.CreatedAt, &sd.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&sd.ID, &sd.EventID, &sd.TargetDate, &sd.DestinationPlaceID, &sd.WindowStart, &sd.WindowEnd,
+		&sd.Notes, &sd.CreatedAt, &sd.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -2386,7 +2411,8 @@ func (r *SqlRepository) GetScheduledDeliveryByID(ctx context.Context, id int64)
 }
 
 func (r *SqlRepository) ListScheduledDeliveries(ctx context.Context, eventID *int64) ([]domain.ScheduledDelivery, error) {
-	query := `SELECT id, event_id, target_date, COALESCE(notes, ''), created_at, updated_at FROM scheduled_deliveries`
+	query := `SELECT id, event_id, target_date, destination_place_id, window_start, window_end, COALESCE(notes, ''), created_at, updated_at
+	          FROM scheduled_deliveries`
 	var args []interface{}
 	if eventID != nil {
 		query += ` WHERE event_id = $1`
@@ -2403,7 +2429,8 @@ func (r *SqlRepository) ListScheduledDeliveries(ctx context.Context, eventID *in
 	var results []domain.ScheduledDelivery
 	for rows.Next() {
 		var sd domain.ScheduledDelivery
-		if err := rows.Scan(&sd.ID, &sd.EventID, &sd.TargetDate, &sd.Notes, &sd.CreatedAt, &sd.UpdatedAt); err != nil {
+		if err := rows.Scan(&sd.ID, &sd.EventID, &sd.TargetDate, &sd.DestinationPlaceID, &sd.WindowStart, &sd.WindowEnd,
+			&sd.Notes, &sd.CreatedAt, &sd.UpdatedAt); err != nil {
 			return nil, err
 		}
 		results = append(results, sd)
@@ -2436,19 +2463,33 @@ func (r *SqlRepository) ListScheduledDeliveryItems(ctx context.Context, delivery
 	return results, nil
 }
 
+// CreateShipment creates a shipment along with its legs, if it has any.
 func (r *SqlRepository) CreateShipment(ctx context.Context, s *domain.Shipment) error {
 	now := time.Now()
 	s.CreatedAt = now
 	s.UpdatedAt = now
-	// Ensure default status
-	if s.Status == "" {
-		s.Status = "Preparing"
+	s.Status = domain.NormalizeDeliveryStatus(s.Status)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO shipments (scheduled_delivery_id, provider_id, ship_date, carrier, tracking_number, status, notes, direction, created_at, updated_at)
 	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
-	return r.db.QueryRowContext(ctx, query, s.ScheduledDeliveryID, s.ProviderID, s.ShipDate, s.Carrier, s.TrackingNumber, s.Status, s.Notes, s.Direction, s.CreatedAt, s.UpdatedAt).Scan(&s.ID)
+	err = tx.QueryRowContext(ctx, query, s.ScheduledDeliveryID, s.ProviderID, s.ShipDate, s.Carrier, s.TrackingNumber, s.Status, s.Notes, s.Direction, s.CreatedAt, s.UpdatedAt).Scan(&s.ID)
+	if err != nil {
+		return err
+	}
+	if err := insertShipmentLegs(ctx, tx, s.ID, s.Legs, now); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// GetShipmentByID retrieves a shipment with its legs, the reservations it carries and
+// its active label.
 func (r *SqlRepository) GetShipmentByID(ctx context.Context, id int64) (*domain.Shipment, error) {
 	query := `SELECT id, scheduled_delivery_id, provider_id, ship_date, COALESCE(carrier, ''), COALESCE(tracking_number, ''), status, COALESCE(notes, ''), direction, created_at, updated_at FROM shipments WHERE id = $1`
 	var s domain.Shipment
@@ -2456,7 +2497,19 @@ func (r *SqlRepository) GetShipmentByID(ctx context.Context, id int64) (*domain.
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &s, err
+	if err != nil {
+		return nil, err
+	}
+	if s.Legs, err = listShipmentLegs(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+	if s.ReservationIDs, err = shipmentReservations(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+	if s.Label, err = activeShipmentLabel(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+	return &s, nil
 }
 
 func (r *SqlRepository) ListShipments(ctx context.Context, deliveryID *int64) ([]domain.Shipment, error) {
@@ -2485,10 +2538,12 @@ func (r *SqlRepository) ListShipments(ctx context.Context, deliveryID *int64) ([
 	return results, nil
 }
 
+// UpdateShipment updates a shipment's details. Its status only changes through
+// TransitionShipment.
 func (r *SqlRepository) UpdateShipment(ctx context.Context, s *domain.Shipment) error {
 	s.UpdatedAt = time.Now()
-	query := `UPDATE shipments SET scheduled_delivery_id = $1, provider_id = $2, ship_date = $3, carrier = $4, tracking_number = $5, status = $6, notes = $7, direction = $8, updated_at = $9 WHERE id = $10`
-	_, err := r.db.ExecContext(ctx, query, s.ScheduledDeliveryID, s.ProviderID, s.ShipDate, s.Carrier, s.TrackingNumber, s.Status, s.Notes, s.Direction, s.UpdatedAt, s.ID)
+	query := `UPDATE shipments SET scheduled_delivery_id = $1, provider_id = $2, ship_date = $3, carrier = $4, tracking_number = $5, notes = $6, direction = $7, updated_at = $8 WHERE id = $9`
+	_, err := r.db.ExecContext(ctx, query, s.ScheduledDeliveryID, s.ProviderID, s.ShipDate, s.Carrier, s.TrackingNumber, s.Notes, s.Direction, s.UpdatedAt, s.ID)
 	return err
 }
 
@@ -2499,6 +2554,28 @@ func (r *SqlRepository) AllocateAssetsToShipment(ctx context.Context, shipmentID
 	}
 	defer tx.Rollback()
 
+	reservationID, err := allocateAssetsToShipment(ctx, tx, shipmentID, assetIDs, agentID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	// Re-evaluate overall status (async or after commit)
+	fStatus, err := r.GetRentalFulfillmentStatus(ctx, reservationID)
+	if err == nil {
+		r.UpdateRentalReservationStatus(ctx, reservationID, domain.RentalReservationStatus(fStatus.Status))
+	}
+
+	return nil
+}
+
+// allocateAssetsToShipment creates potential check-outs for assets on a shipment, against
+// the reservation of its scheduled delivery, and returns that reservation.
+func allocateAssetsToShipment(ctx context.Context, tx *sql.Tx, shipmentID int64, assetIDs []int64, agentID int64, now time.Time) (int64, error) {
 	// 1. Get ScheduledDelivery and ReservationID (EventID) from Shipment
 	var scheduledDeliveryID *int64
 	var reservationID int64
@@ -2506,21 +2583,20 @@ func (r *SqlRepository) AllocateAssetsToShipment(ctx context.Context, shipmentID
 	              FROM shipments s
 	              LEFT JOIN scheduled_deliveries sd ON s.scheduled_delivery_id = sd.id
 	              WHERE s.id = $1`
-	err = tx.QueryRowContext(ctx, queryInfo, shipmentID).Scan(&scheduledDeliveryID, &reservationID)
+	err := tx.QueryRowContext(ctx, queryInfo, shipmentID).Scan(&scheduledDeliveryID, &reservationID)
 	if err != nil {
-		return fmt.Errorf("getting shipment info: %w", err)
+		return 0, fmt.Errorf("getting shipment info: %w", err)
 	}
 
-	now := time.Now()
 	for _, assetID := range assetIDs {
 		// 2. Verify asset is available
 		var status string
 		err = tx.QueryRowContext(ctx, "SELECT status FROM assets WHERE id = $1", assetID).Scan(&status)
 		if err != nil {
-			return fmt.Errorf("checking asset %d: %w", assetID, err)
+			return 0, fmt.Errorf("checking asset %d: %w", assetID, err)
 		}
 		if status != "available" && status != "reserved" {
-			return fmt.Errorf("asset %d is not available (status: %s)", assetID, status)
+			return 0, fmt.Errorf("asset %d is not available (status: %s)", assetID, status)
 		}
 
 		// 3. Create CheckOutAction (Potential/Draft)
@@ -2528,26 +2604,14 @@ func (r *SqlRepository) AllocateAssetsToShipment(ctx context.Context, shipmentID
 		            VALUES ($1, $2, $3, $4, $5, $6, $7)`
 		_, err = tx.ExecContext(ctx, coQuery, reservationID, assetID, agentID, shipmentID, scheduledDeliveryID, now, "Potential")
 		if err != nil {
-			return fmt.Errorf("creating checkout for asset %d: %w", assetID, err)
+			return 0, fmt.Errorf("creating checkout for asset %d: %w", assetID, err)
 		}
 
 		// 4. Update Asset status
 		_, err = tx.ExecContext(ctx, "UPDATE assets SET status = 'reserved', updated_at = $1 WHERE id = $2", now, assetID)
 		if err != nil {
-			return fmt.Errorf("updating asset status %d: %w", assetID, err)
+			return 0, fmt.Errorf("updating asset status %d: %w", assetID, err)
 		}
 	}
-
-	err = tx.Commit()
-	if err != nil {
-		return err
-	}
-
-	// Re-evaluate overall status (async or after commit)
-	fStatus, err := r.GetRentalFulfillmentStatus(ctx, reservationID)
-	if err == nil {
-		r.UpdateRentalReservationStatus(ctx, reservationID, domain.RentalReservationStatus(fStatus.Status))
-	}
-
-	return nil
+	return reservationID, nil
 }
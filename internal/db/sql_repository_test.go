@@ -10,6 +10,36 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// Columns of the usage queries behind availability, one set per kind of consumer.
+var (
+	reservedIntervalCols = []string{"item_id", "start_time", "end_time", "quantity", "reservation_id", "demand_id", "reservation_name"}
+	adHocUsageCols       = []string{"item_type_id", "estimated_return_at", "asset_id", "asset_tag", "status"}
+	assetHoldCols        = []string{"item_type_id", "start_time", "end_time", "hold_id", "reservation_id", "demand_id", "asset_id", "asset_tag", "reservation_name"}
+	stockUsageCols       = []string{"item_type_id", "start_time", "quantity", "kind", "lot_id", "lot_number", "reservation_id", "reservation_name"}
+)
+
+var demandCols = []string{"id", "reservation_id", "event_id", "item_kind", "item_id", "requested_quantity",
+	"business_function", "eligible_duration", "place_id", "metadata", "created_at", "updated_at"}
+
+// expectRentalReservation expects reservation id to be read, locked when forUpdate is
+// set, along with demands, rows of demandCols.
+func expectRentalReservation(mock sqlmock.Sqlmock, id int64, status domain.RentalReservationStatus, start, end time.Time, forUpdate bool, demands *sqlmock.Rows) {
+	query := "SELECT id, reservation_name, (.+) FROM rental_reservations WHERE id = \\$1"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	now := time.Now()
+	mock.ExpectQuery(query).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_name", "reservation_status", "under_name_id", "booking_time",
+			"start_time", "end_time", "provider_id", "series_id", "recurrence_id", "metadata",
+			"submitted_at", "approved_at", "rejected_at", "cancelled_at", "fulfilled_at", "overdue_at", "updated_by_user_id", "created_at", "updated_at"}).
+			AddRow(id, "", status, nil, now, start, end, nil, nil, nil, []byte("{}"), nil, nil, nil, nil, nil, nil, nil, now, now))
+	mock.ExpectQuery("SELECT id, reservation_id, event_id, (.+) FROM demands WHERE reservation_id = \\$1").
+		WithArgs(id).
+		WillReturnRows(demands)
+}
+
 func TestSqlRepository_GetItemTypeByID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -20,8 +50,8 @@ func TestSqlRepository_GetItemTypeByID(t *testing.T) {
 	repo := NewSqlRepository(db)
 	ctx := context.Background()
 
-	rows := sqlmock.NewRows([]string{"id", "code", "name", "kind", "is_active", "supported_features", "created_by_user_id", "updated_by_user_id", "schema_org", "metadata", "created_at", "updated_at"}).
-		AddRow(1, "SKU123", "Item A", "serialized", true, []byte("{}"), nil, nil, []byte("{}"), []byte("{}"), time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "code", "name", "kind", "is_active", "supported_features", "pre_buffer_minutes", "post_buffer_minutes", "created_by_user_id", "updated_by_user_id", "schema_org", "metadata", "created_at", "updated_at"}).
+		AddRow(1, "SKU123", "Item A", "serialized", true, []byte("{}"), 30, 120, nil, nil, []byte("{}"), []byte("{}"), time.Now(), time.Now())
 
 	mock.ExpectQuery("SELECT (.+) FROM item_types WHERE id = \\$1").
 		WithArgs(1).
@@ -32,6 +62,7 @@ func TestSqlRepository_GetItemTypeByID(t *testing.T) {
 	assert.NotNil(t, it)
 	assert.Equal(t, int64(1), it.ID)
 	assert.Equal(t, "SKU123", it.Code)
+	assert.Equal(t, domain.TurnaroundBuffer{Pre: 30 * time.Minute, Post: 2 * time.Hour}, it.Buffer())
 }
 
 func TestSqlRepository_CreateRentalReservation(t *testing.T) {
@@ -69,6 +100,55 @@ func TestSqlRepository_CreateRentalReservation(t *testing.T) {
 	assert.Equal(t, int64(100), rr.Demands[0].ID)
 }
 
+func TestSqlRepository_CreateReservationSeries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	s := &domain.ReservationSeries{
+		ReservationName: "Weekly rehearsal",
+		RecurrenceRule:  "FREQ=WEEKLY;COUNT=3",
+		StartTime:       start,
+		EndTime:         start.Add(4 * time.Hour),
+		Exceptions:      []time.Time{start.AddDate(0, 0, 7)},
+		Demands:         []domain.Demand{{ItemKind: "item_type", ItemID: 10, Quantity: 2}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO reservation_series").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	for i, week := range []int{0, 2} {
+		occStart := start.AddDate(0, 0, 7*week)
+		mock.ExpectQuery("INSERT INTO rental_reservations").
+			WithArgs("Weekly rehearsal", domain.ReservationStatusPending, nil, sqlmock.AnyArg(), occStart, occStart.Add(4*time.Hour),
+				nil, int64(4), occStart, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20 + i))
+		mock.ExpectQuery("INSERT INTO demands").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(200 + i))
+	}
+	mock.ExpectCommit()
+
+	err = repo.CreateReservationSeries(ctx, s)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), s.ID)
+	if assert.Len(t, s.Occurrences, 2) {
+		assert.Equal(t, int64(21), s.Occurrences[1].ID)
+		assert.Equal(t, int64(201), s.Occurrences[1].Demands[0].ID)
+	}
+	assert.Empty(t, s.Demands[0].ID, "the template keeps no demand ids")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Unbounded rules are rejected before anything is written
+	err = repo.CreateReservationSeries(ctx, &domain.ReservationSeries{RecurrenceRule: "FREQ=DAILY", StartTime: start, EndTime: start.Add(time.Hour)})
+	assert.ErrorIs(t, err, domain.ErrInvalidRecurrence)
+}
+
 func TestSqlRepository_GetAvailableQuantity(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -79,24 +159,131 @@ func TestSqlRepository_GetAvailableQuantity(t *testing.T) {
 	repo := NewSqlRepository(db)
 	ctx := context.Background()
 	startTime := time.Now()
-	endTime := startTime.Add(time.Hour)
+	endTime := startTime.Add(4 * time.Hour)
+
+	// No turnaround buffers
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}).AddRow(10, 0, 0))
 
 	// Mock total assets
-	mock.ExpectQuery("SELECT COUNT(.+) FROM assets WHERE item_type_id = \\$1 AND status != 'retired'").
-		WithArgs(int64(10)).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets WHERE item_type_id = ANY\\(\\$1\\) AND status != 'retired'").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(10, 10))
+
+	// Mock overlapping reserved intervals (Confirmed status). The two reservations
+	// do not overlap each other, so only the larger one limits availability.
+	mock.ExpectQuery("SELECT d.item_id, rr.start_time - pad.pre, rr.end_time \\+ pad.post, GREATEST\\(.+\\) FROM demands d JOIN rental_reservations rr").
+		WithArgs("{10}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols).
+			AddRow(10, startTime, startTime.Add(time.Hour), 3, 2, 20, "").
+			AddRow(10, startTime.Add(2*time.Hour), startTime.Add(3*time.Hour), 4, 3, 30, ""))
+
+	// Mock ad-hoc usage: one asset in maintenance with no estimated return
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{10}", startTime).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols).AddRow(10, nil, 1, "", "maintenance"))
+
+	// Mock hard allocations: none
+	mock.ExpectQuery("SELECT a.item_type_id, h.start_time - pad.pre, h.end_time \\+ pad.post, (.+) FROM asset_holds h JOIN assets a").
+		WithArgs("{10}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols))
+
+	avail, err := repo.GetAvailableQuantity(ctx, 10, startTime, endTime)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, avail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_GetAvailableQuantity_Buffers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(4 * time.Hour)
+
+	// 30 minutes of preparation before and an hour of cleaning after every rental
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}).AddRow(10, 30, 60))
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(10, 10))
+
+	// Usage is searched across the window widened by the buffers. A rental that ended
+	// 45 minutes before the window still has its unit in cleaning until 15 minutes in
+	// (intervals come back already widened by the query).
+	mock.ExpectQuery("SELECT d.item_id, (.+) FROM demands d JOIN rental_reservations rr").
+		WithArgs("{10}", startTime.Add(-30*time.Minute), endTime.Add(time.Hour)).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols).
+			AddRow(10, startTime.Add(-5*time.Hour), startTime.Add(15*time.Minute), 4, 2, 20, "").
+			AddRow(10, endTime.Add(30*time.Minute), endTime.Add(5*time.Hour), 3, 3, 30, ""))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{10}", startTime.Add(-30*time.Minute)).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM asset_holds h JOIN assets a").
+		WithArgs("{10}", startTime.Add(-30*time.Minute), endTime.Add(time.Hour)).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols))
+
+	// The first rental blocks 4 units; the second starts inside this rental's cleaning time
+	// but not at the same moment as the first, so the low point is 10 - 4.
+	avail, err := repo.GetAvailableQuantity(ctx, 10, startTime, endTime)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, avail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_GetAvailableQuantity_Fungible(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(4 * time.Hour)
+
+	mock.ExpectQuery("SELECT id, pre_buffer_minutes, post_buffer_minutes FROM item_types").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pre_buffer_minutes", "post_buffer_minutes"}).AddRow(10, 0, 0))
 
-	// Mock overlapping reserved quantity (Confirmed status)
-	mock.ExpectQuery("SELECT COALESCE(.+) FROM demands d JOIN rental_reservations rr").
-		WithArgs(int64(10), startTime, endTime).
-		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(3))
+	// 200 cable ties owned according to the stock ledger, not counted as asset rows
+	mock.ExpectQuery("SELECT item_type_id, COUNT(.+) FROM assets (.+) UNION ALL SELECT m.item_type_id, SUM(.+) FROM stock_movements m").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}).AddRow(10, 200))
+	mock.ExpectQuery("SELECT d.item_id, (.+) FROM demands d JOIN rental_reservations rr").
+		WithArgs("{10}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(reservedIntervalCols).
+			AddRow(10, startTime, startTime.Add(3*time.Hour), 50, 2, 20, ""))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM assets a JOIN item_types it").
+		WithArgs("{10}", startTime).
+		WillReturnRows(sqlmock.NewRows(adHocUsageCols))
+	mock.ExpectQuery("SELECT a.item_type_id, (.+) FROM asset_holds h JOIN assets a").
+		WithArgs("{10}", startTime, endTime).
+		WillReturnRows(sqlmock.NewRows(assetHoldCols))
 
-	// Mock ad-hoc usage
-	mock.ExpectQuery("SELECT COUNT(.+) FROM assets WHERE item_type_id = \\$1 AND status IN").
-		WithArgs(int64(10), startTime).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	// A lot of 30 expires two hours in, and 20 are still out on a reservation that has ended
+	mock.ExpectQuery("SELECT m.item_type_id, (.+) FROM stock_movements m JOIN stock_lots l").
+		WithArgs("{10}").
+		WillReturnRows(sqlmock.NewRows(stockUsageCols).
+			AddRow(10, startTime.Add(2*time.Hour), 30, domain.ConsumerExpiry, 1, "L1", nil, "").
+			AddRow(10, nil, 20, domain.ConsumerStockOut, nil, "", 4, ""))
 
 	avail, err := repo.GetAvailableQuantity(ctx, 10, startTime, endTime)
 	assert.NoError(t, err)
-	assert.Equal(t, 7, avail)
+	assert.Equal(t, 100, avail)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
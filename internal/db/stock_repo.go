@@ -0,0 +1,278 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+// stockOnHand and stockOut give a ledger movement's (alias m) effect on stock on hand
+// at its place and on stock out on its reservation. Their sum is its effect on what
+// is owned.
+const (
+	stockOnHand = `CASE m.kind WHEN 'receipt' THEN m.quantity WHEN 'return' THEN m.quantity
+		WHEN 'write_off' THEN -m.quantity WHEN 'check_out' THEN -m.quantity ELSE 0 END`
+	stockOut = `CASE m.kind WHEN 'check_out' THEN m.quantity WHEN 'return' THEN -m.quantity
+		WHEN 'consumption' THEN -m.quantity ELSE 0 END`
+)
+
+const stockMovementColumns = `m.id, m.item_type_id, m.kind, m.quantity, m.place_id, m.lot_id, COALESCE(l.lot_number, ''),
+	m.reservation_id, COALESCE(m.note, ''), m.created_by_user_id, m.created_at`
+
+func scanStockMovement(scanner interface{ Scan(...any) error }) (*domain.StockMovement, error) {
+	var m domain.StockMovement
+	err := scanner.Scan(&m.ID, &m.ItemTypeID, &m.Kind, &m.Quantity, &m.PlaceID, &m.LotID, &m.LotNumber,
+		&m.ReservationID, &m.Note, &m.CreatedByUserID, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// listStockLots returns an item type's lots by id.
+func listStockLots(ctx context.Context, q queryer, itemTypeID int64) (map[int64]domain.StockLot, []int64, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, item_type_id, lot_number, expires_at, created_at
+		FROM stock_lots WHERE item_type_id = $1 ORDER BY expires_at NULLS LAST, id`, itemTypeID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query stock lots: %w", err)
+	}
+	defer rows.Close()
+
+	lots := make(map[int64]domain.StockLot)
+	var order []int64
+	for rows.Next() {
+		var l domain.StockLot
+		if err := rows.Scan(&l.ID, &l.ItemTypeID, &l.LotNumber, &l.ExpiresAt, &l.CreatedAt); err != nil {
+			return nil, nil, fmt.Errorf("scan stock lot: %w", err)
+		}
+		lots[l.ID] = l
+		order = append(order, l.ID)
+	}
+	return lots, order, rows.Err()
+}
+
+// loadStockSummary sums an item type's stock ledger.
+func loadStockSummary(ctx context.Context, q queryer, itemTypeID int64, lots map[int64]domain.StockLot, now time.Time) (*domain.StockSummary, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT kind, place_id, lot_id, reservation_id, SUM(quantity)
+		FROM stock_movements WHERE item_type_id = $1
+		GROUP BY kind, place_id, lot_id, reservation_id`, itemTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("query stock ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []domain.StockLedgerTotal
+	for rows.Next() {
+		var t domain.StockLedgerTotal
+		if err := rows.Scan(&t.Kind, &t.PlaceID, &t.LotID, &t.ReservationID, &t.Quantity); err != nil {
+			return nil, fmt.Errorf("scan stock ledger: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return domain.BuildStockSummary(itemTypeID, totals, lots, now), nil
+}
+
+func sameID(a, b *int64) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// fungibleItemType reports whether an item type exists and whether it is fungible.
+func fungibleItemType(ctx context.Context, q queryer, itemTypeID int64, forUpdate bool) (exists, fungible bool, err error) {
+	query := "SELECT kind FROM item_types WHERE id = $1"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	var kind domain.ItemKind
+	err = q.QueryRowContext(ctx, query, itemTypeID).Scan(&kind)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("get item type: %w", err)
+	}
+	return true, kind == domain.ItemKindFungible, nil
+}
+
+// RecordStockMovement writes a receipt, write-off, check-out, return or consumption of
+// a fungible item type to the stock ledger. Anything but a receipt is drawn from what
+// is on hand at the place, or out on the reservation, first expiring first; only
+// check-outs skip expired lots. Movements of an item type are serialized on its row,
+// and a check-out moves its reservation on to (partially) fulfilled. Invoices do not
+// bill these movements. It returns nil, nil when the item type does not exist.
+func (r *SqlRepository) RecordStockMovement(ctx context.Context, req *domain.StockMovementRequest, userID *int64) ([]domain.StockMovement, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	exists, fungible, err := fungibleItemType(ctx, tx, req.ItemTypeID, true)
+	if err != nil || !exists {
+		return nil, err
+	}
+	if !fungible {
+		return nil, fmt.Errorf("%w: item type %d is not fungible", domain.ErrStock, req.ItemTypeID)
+	}
+	if req.ReservationID != nil {
+		var found bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM rental_reservations WHERE id = $1)", *req.ReservationID).Scan(&found); err != nil {
+			return nil, fmt.Errorf("check reservation: %w", err)
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: reservation %d does not exist", domain.ErrStock, *req.ReservationID)
+		}
+	}
+
+	now := time.Now()
+	var draws []domain.LotDraw
+	if req.Kind == domain.StockReceipt {
+		draw := domain.LotDraw{Quantity: req.Quantity}
+		if req.LotNumber != "" {
+			var lotID int64
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO stock_lots (item_type_id, lot_number, expires_at, created_at) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (item_type_id, lot_number) DO UPDATE SET expires_at = COALESCE(EXCLUDED.expires_at, stock_lots.expires_at)
+				RETURNING id`, req.ItemTypeID, req.LotNumber, req.ExpiresAt, now).Scan(&lotID)
+			if err != nil {
+				return nil, fmt.Errorf("upsert stock lot: %w", err)
+			}
+			draw.LotID = &lotID
+		}
+		draws = []domain.LotDraw{draw}
+	} else {
+		lots, _, err := listStockLots(ctx, tx, req.ItemTypeID)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := loadStockSummary(ctx, tx, req.ItemTypeID, lots, now)
+		if err != nil {
+			return nil, err
+		}
+
+		// Stock leaves a place, or comes back from (or is used up on) a reservation
+		fromPlace := req.Kind == domain.StockWriteOff || req.Kind == domain.StockCheckOut
+		source := summary.Outstanding
+		if fromPlace {
+			source = summary.Levels
+		}
+		var levels []domain.StockLevel
+		for _, l := range source {
+			if fromPlace && !sameID(l.PlaceID, req.PlaceID) || !fromPlace && !sameID(l.ReservationID, req.ReservationID) {
+				continue
+			}
+			if req.LotID != nil && !sameID(l.LotID, req.LotID) {
+				continue
+			}
+			levels = append(levels, l)
+		}
+		draws, err = domain.DrawLots(levels, req.Quantity, req.Kind != domain.StockCheckOut)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]int64, 0, len(draws))
+	for _, d := range draws {
+		var id int64
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO stock_movements (item_type_id, kind, quantity, place_id, lot_id, reservation_id, note, created_by_user_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+			req.ItemTypeID, req.Kind, d.Quantity, req.PlaceID, d.LotID, req.ReservationID, req.Note, userID, now).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("insert stock movement: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	movements, err := queryStockMovements(ctx, tx, "m.id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Re-evaluate the reservation's status after commit, as asset check-outs do
+	if req.Kind == domain.StockCheckOut && req.ReservationID != nil {
+		if fStatus, err := r.GetRentalFulfillmentStatus(ctx, *req.ReservationID); err == nil {
+			r.UpdateRentalReservationStatus(ctx, *req.ReservationID, domain.RentalReservationStatus(fStatus.Status))
+		}
+	}
+	return movements, nil
+}
+
+func queryStockMovements(ctx context.Context, q queryer, where string, args ...interface{}) ([]domain.StockMovement, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+stockMovementColumns+`
+		FROM stock_movements m LEFT JOIN stock_lots l ON l.id = m.lot_id
+		WHERE `+where+` ORDER BY m.created_at, m.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []domain.StockMovement{}
+	for rows.Next() {
+		m, err := scanStockMovement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stock movement: %w", err)
+		}
+		movements = append(movements, *m)
+	}
+	return movements, rows.Err()
+}
+
+// ListStockMovements returns the stock ledger, optionally for one item type and one
+// reservation, oldest first.
+func (r *SqlRepository) ListStockMovements(ctx context.Context, itemTypeID, reservationID *int64) ([]domain.StockMovement, error) {
+	return queryStockMovements(ctx, r.db, "($1::bigint IS NULL OR m.item_type_id = $1) AND ($2::bigint IS NULL OR m.reservation_id = $2)",
+		itemTypeID, reservationID)
+}
+
+// GetStockSummary returns where a fungible item type's stock is. It returns nil, nil
+// when the item type does not exist.
+func (r *SqlRepository) GetStockSummary(ctx context.Context, itemTypeID int64) (*domain.StockSummary, error) {
+	exists, _, err := fungibleItemType(ctx, r.db, itemTypeID, false)
+	if err != nil || !exists {
+		return nil, err
+	}
+	lots, _, err := listStockLots(ctx, r.db, itemTypeID)
+	if err != nil {
+		return nil, err
+	}
+	return loadStockSummary(ctx, r.db, itemTypeID, lots, time.Now())
+}
+
+// ListStockLots returns an item type's lots, first expiring first, with what is on
+// hand of each.
+func (r *SqlRepository) ListStockLots(ctx context.Context, itemTypeID int64) ([]domain.StockLot, error) {
+	lots, order, err := listStockLots(ctx, r.db, itemTypeID)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := loadStockSummary(ctx, r.db, itemTypeID, lots, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	onHand := make(map[int64]int)
+	for _, l := range summary.Levels {
+		if l.LotID != nil {
+			onHand[*l.LotID] += l.Quantity
+		}
+	}
+
+	result := make([]domain.StockLot, 0, len(order))
+	for _, id := range order {
+		l := lots[id]
+		l.OnHand = onHand[id]
+		result = append(result, l)
+	}
+	return result, nil
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var stockMovementCols = []string{"id", "item_type_id", "kind", "quantity", "place_id", "lot_id", "lot_number",
+	"reservation_id", "note", "created_by_user_id", "created_at"}
+
+// expectStockLedger expects RecordStockMovement to lock item type 5 and load its lots
+// and ledger: lot 1 expired yesterday, lot 2 expires next week and lot 3 next month,
+// all received at place 1, with one unit of lot 2 already out on reservation 8.
+func expectStockLedger(mock sqlmock.Sqlmock, now time.Time) {
+	yesterday, nextWeek, nextMonth := now.AddDate(0, 0, -1), now.AddDate(0, 0, 7), now.AddDate(0, 1, 0)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT kind FROM item_types WHERE id = \\$1 FOR UPDATE").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"kind"}).AddRow("fungible"))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM rental_reservations WHERE id = \\$1\\)").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT id, item_type_id, lot_number, expires_at, created_at FROM stock_lots WHERE item_type_id = \\$1").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_type_id", "lot_number", "expires_at", "created_at"}).
+			AddRow(1, 5, "L1", yesterday, now).
+			AddRow(2, 5, "L2", nextWeek, now).
+			AddRow(3, 5, "L3", nextMonth, now))
+	mock.ExpectQuery("SELECT kind, place_id, lot_id, reservation_id, SUM\\(quantity\\) FROM stock_movements WHERE item_type_id = \\$1").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "place_id", "lot_id", "reservation_id", "sum"}).
+			AddRow("receipt", 1, 1, nil, 4).
+			AddRow("receipt", 1, 2, nil, 3).
+			AddRow("receipt", 1, 3, nil, 10).
+			AddRow("check_out", 1, 2, 8, 1))
+}
+
+func TestSqlRepository_RecordStockMovement_CheckOutSkipsExpiredLots(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	place, reservation := int64(1), int64(9)
+
+	expectStockLedger(mock, now)
+	// Lot 1 has expired, so the two left of lot 2 go first, then three of lot 3
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(5, domain.StockCheckOut, 2, place, 2, reservation, "", nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(101))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(5, domain.StockCheckOut, 3, place, 3, reservation, "", nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(102))
+	mock.ExpectQuery("SELECT (.+) FROM stock_movements m LEFT JOIN stock_lots l ON l.id = m.lot_id WHERE m.id = ANY\\(\\$1\\)").
+		WithArgs("{101,102}").
+		WillReturnRows(sqlmock.NewRows(stockMovementCols).
+			AddRow(101, 5, "check_out", 2, place, 2, "L2", reservation, "", nil, now).
+			AddRow(102, 5, "check_out", 3, place, 3, "L3", reservation, "", nil, now))
+	mock.ExpectCommit()
+
+	// The five checked out are all reservation 9 asked for, so it is fulfilled
+	demand := func() *sqlmock.Rows {
+		return sqlmock.NewRows(demandCols).AddRow(60, reservation, 0, "item_type", 5, 5, "", "", nil, nil, now, now)
+	}
+	mock.ExpectQuery("SELECT id, reservation_id, event_id, (.+) FROM demands WHERE reservation_id = \\$1").
+		WithArgs(reservation).
+		WillReturnRows(demand())
+	mock.ExpectQuery("SELECT h.demand_id, (.+) FROM asset_holds h").
+		WithArgs(reservation).
+		WillReturnRows(sqlmock.NewRows([]string{"demand_id", "item_type_id", "asset_item_type_id", "count", "applied_at"}))
+	mock.ExpectQuery("FROM check_out_actions co (.+) UNION ALL SELECT item_type_id, SUM\\(quantity\\), 0 FROM stock_movements").
+		WithArgs(reservation).
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count", "substituted"}).AddRow(5, 5, 0))
+	mock.ExpectQuery("FROM return_actions ret").
+		WithArgs(reservation).
+		WillReturnRows(sqlmock.NewRows([]string{"item_type_id", "count"}))
+	mock.ExpectBegin()
+	expectRentalReservation(mock, reservation, domain.ReservationStatusConfirmed, now, now.Add(8*time.Hour), true, demand())
+	mock.ExpectExec("UPDATE rental_reservations SET reservation_status = \\$1, updated_by_user_id = \\$2, updated_at = \\$3, fulfilled_at = \\$3 WHERE id = \\$4").
+		WithArgs(domain.ReservationStatusFulfilled, nil, sqlmock.AnyArg(), reservation).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO reservation_transitions").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO outbox_events").
+		WithArgs(domain.EventRentalFulfilled, sqlmock.AnyArg(), domain.OutboxPending, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	movements, err := repo.RecordStockMovement(ctx, &domain.StockMovementRequest{
+		Kind: domain.StockCheckOut, ItemTypeID: 5, Quantity: 5, PlaceID: &place, ReservationID: &reservation,
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, movements, 2)
+	assert.Equal(t, "L2", movements[0].LotNumber)
+	assert.Equal(t, "L3", movements[1].LotNumber)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_RecordStockMovement_Insufficient(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	place, reservation := int64(1), int64(9)
+
+	// Sixteen are on hand, but only the twelve in date can be checked out
+	expectStockLedger(mock, time.Now())
+	mock.ExpectRollback()
+
+	movements, err := repo.RecordStockMovement(ctx, &domain.StockMovementRequest{
+		Kind: domain.StockCheckOut, ItemTypeID: 5, Quantity: 13, PlaceID: &place, ReservationID: &reservation,
+	}, nil)
+	assert.Nil(t, movements)
+	assert.True(t, errors.Is(err, domain.ErrStock))
+	assert.Contains(t, err.Error(), "13 requested but only 12 available")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSqlRepository_RecordStockMovement_NotFungible(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	place := int64(1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT kind FROM item_types WHERE id = \\$1 FOR UPDATE").
+		WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"kind"}).AddRow("serialized"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT kind FROM item_types WHERE id = \\$1 FOR UPDATE").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"kind"}))
+	mock.ExpectRollback()
+
+	req := &domain.StockMovementRequest{Kind: domain.StockReceipt, ItemTypeID: 6, Quantity: 1, PlaceID: &place}
+	_, err = repo.RecordStockMovement(ctx, req, nil)
+	assert.True(t, errors.Is(err, domain.ErrStock))
+
+	req.ItemTypeID = 7
+	movements, err := repo.RecordStockMovement(ctx, req, nil)
+	assert.Nil(t, movements)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSqlRepository_StockLedger_Postgres runs the ledger against a real Postgres, so
+// the CASE expressions that sum it in SQL are checked against the draws made in Go.
+// It runs only when TEST_DATABASE_URL is set.
+func TestSqlRepository_StockLedger_Postgres(t *testing.T) {
+	conn := openTestSchema(t, "stock_ledger")
+	ctx := context.Background()
+
+	_, err := conn.ExecContext(ctx, "INSERT INTO item_types (id, code, name, kind) VALUES (5, 'CABLE', 'Cable tie', 'fungible')")
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, "INSERT INTO places (id, name) VALUES (1, 'Warehouse')")
+	require.NoError(t, err)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	var reservation int64
+	err = conn.QueryRowContext(ctx, "INSERT INTO rental_reservations (reservation_status, start_time, end_time) VALUES ($1, $2, $3) RETURNING id",
+		domain.ReservationStatusConfirmed, start, start.Add(8*time.Hour)).Scan(&reservation)
+	require.NoError(t, err)
+
+	repo := NewSqlRepository(conn)
+	place := int64(1)
+	record := func(req domain.StockMovementRequest) []domain.StockMovement {
+		t.Helper()
+		movements, err := repo.RecordStockMovement(ctx, &req, nil)
+		require.NoError(t, err)
+		return movements
+	}
+
+	yesterday, nextWeek := time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 7)
+	record(domain.StockMovementRequest{Kind: domain.StockReceipt, ItemTypeID: 5, Quantity: 4, PlaceID: &place, LotNumber: "OLD", ExpiresAt: &yesterday})
+	record(domain.StockMovementRequest{Kind: domain.StockReceipt, ItemTypeID: 5, Quantity: 6, PlaceID: &place, LotNumber: "NEW", ExpiresAt: &nextWeek})
+	record(domain.StockMovementRequest{Kind: domain.StockReceipt, ItemTypeID: 5, Quantity: 5, PlaceID: &place})
+
+	// The expired lot is skipped; the dated lot goes before untracked stock
+	out := record(domain.StockMovementRequest{Kind: domain.StockCheckOut, ItemTypeID: 5, Quantity: 8, PlaceID: &place, ReservationID: &reservation})
+	require.Len(t, out, 2)
+	assert.Equal(t, "NEW", out[0].LotNumber)
+	assert.Equal(t, 6, out[0].Quantity)
+	assert.Nil(t, out[1].LotID)
+	assert.Equal(t, 2, out[1].Quantity)
+
+	record(domain.StockMovementRequest{Kind: domain.StockReturn, ItemTypeID: 5, Quantity: 3, PlaceID: &place, ReservationID: &reservation})
+	record(domain.StockMovementRequest{Kind: domain.StockConsumption, ItemTypeID: 5, Quantity: 2, ReservationID: &reservation})
+	record(domain.StockMovementRequest{Kind: domain.StockWriteOff, ItemTypeID: 5, Quantity: 4, PlaceID: &place})
+
+	summary, err := repo.GetStockSummary(ctx, 5)
+	require.NoError(t, err)
+	// 15 received, 2 consumed and 4 written off (the expired lot first)
+	assert.Equal(t, 9, summary.Owned)
+	assert.Equal(t, 6, summary.OnHand)
+	assert.Equal(t, 0, summary.Expired)
+	assert.Equal(t, 3, summary.Out)
+
+	// The availability engine sums the same ledger in SQL; what is out on a reservation
+	// that has not ended yet is still owned
+	available, err := repo.GetAvailableQuantity(ctx, 5, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, summary.Owned, available)
+}
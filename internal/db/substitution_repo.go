@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const substitutionRuleColumns = `id, item_type_id, substitute_item_type_id, two_way, priority, max_quantity, auto_apply, is_active, COALESCE(notes, ''), created_at, updated_at`
+
+func scanSubstitutionRule(scanner interface{ Scan(...any) error }) (*domain.SubstitutionRule, error) {
+	var rule domain.SubstitutionRule
+	var maxQty sql.NullInt64
+	err := scanner.Scan(&rule.ID, &rule.ItemTypeID, &rule.SubstituteItemTypeID, &rule.TwoWay, &rule.Priority, &maxQty, &rule.AutoApply, &rule.IsActive, &rule.Notes, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if maxQty.Valid {
+		n := int(maxQty.Int64)
+		rule.MaxQuantity = &n
+	}
+	return &rule, nil
+}
+
+// CreateSubstitutionRule stores a new substitution rule between two item types.
+func (r *SqlRepository) CreateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error {
+	now := time.Now()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	query := `INSERT INTO substitution_rules (item_type_id, substitute_item_type_id, two_way, priority, max_quantity, auto_apply, is_active, notes, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, rule.ItemTypeID, rule.SubstituteItemTypeID, rule.TwoWay, rule.Priority, rule.MaxQuantity,
+		rule.AutoApply, rule.IsActive, rule.Notes, rule.CreatedAt, rule.UpdatedAt).Scan(&rule.ID)
+	if err != nil {
+		return fmt.Errorf("insert substitution_rule: %w", err)
+	}
+	return nil
+}
+
+// GetSubstitutionRule returns one substitution rule, or nil if it does not exist.
+func (r *SqlRepository) GetSubstitutionRule(ctx context.Context, id int64) (*domain.SubstitutionRule, error) {
+	rule, err := scanSubstitutionRule(r.db.QueryRowContext(ctx, `SELECT `+substitutionRuleColumns+` FROM substitution_rules WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get substitution_rule: %w", err)
+	}
+	return rule, nil
+}
+
+// ListSubstitutionRules returns all rules, or only those that let an item type be
+// replaced (including two-way rules where it is the substitute) when itemTypeID is set.
+func (r *SqlRepository) ListSubstitutionRules(ctx context.Context, itemTypeID *int64) ([]domain.SubstitutionRule, error) {
+	query := `SELECT ` + substitutionRuleColumns + ` FROM substitution_rules`
+	var args []any
+	if itemTypeID != nil {
+		query += ` WHERE item_type_id = $1 OR (two_way AND substitute_item_type_id = $1)`
+		args = append(args, *itemTypeID)
+	}
+	query += ` ORDER BY item_type_id, priority DESC, id`
+	return querySubstitutionRules(ctx, r.db, query, args...)
+}
+
+// UpdateSubstitutionRule overwrites a substitution rule.
+func (r *SqlRepository) UpdateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error {
+	rule.UpdatedAt = time.Now()
+	query := `UPDATE substitution_rules SET item_type_id = $1, substitute_item_type_id = $2, two_way = $3, priority = $4, max_quantity = $5,
+	                 auto_apply = $6, is_active = $7, notes = $8, updated_at = $9
+	          WHERE id = $10`
+	_, err := r.db.ExecContext(ctx, query, rule.ItemTypeID, rule.SubstituteItemTypeID, rule.TwoWay, rule.Priority, rule.MaxQuantity,
+		rule.AutoApply, rule.IsActive, rule.Notes, rule.UpdatedAt, rule.ID)
+	if err != nil {
+		return fmt.Errorf("update substitution_rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubstitutionRule removes a rule. Substitutions already recorded on demands keep
+// the rule ID for reference.
+func (r *SqlRepository) DeleteSubstitutionRule(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM substitution_rules WHERE id = $1", id); err != nil {
+		return fmt.Errorf("delete substitution_rule: %w", err)
+	}
+	return nil
+}
+
+// activeSubstitutionRules loads the active rules that can replace any of the given item types.
+func activeSubstitutionRules(ctx context.Context, q queryer, itemTypeIDs []int64) ([]domain.SubstitutionRule, error) {
+	query := `SELECT ` + substitutionRuleColumns + ` FROM substitution_rules
+	          WHERE is_active AND (item_type_id = ANY($1) OR (two_way AND substitute_item_type_id = ANY($1)))`
+	return querySubstitutionRules(ctx, q, query, pq.Array(itemTypeIDs))
+}
+
+func querySubstitutionRules(ctx context.Context, q queryer, query string, args ...any) ([]domain.SubstitutionRule, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list substitution_rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []domain.SubstitutionRule{}
+	for rows.Next() {
+		rule, err := scanSubstitutionRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan substitution_rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// ApplySubstitution accepts a proposed substitution: it pins up to quantity assets of
+// substituteItemTypeID to the demand line for itemTypeID and records the substitution
+// on the demand. The quantity is trimmed to what the line still misses, what the rule
+// still allows and what is free.
+func (r *SqlRepository) ApplySubstitution(ctx context.Context, reservationID, demandID, itemTypeID, substituteItemTypeID int64, quantity int, userID *int64) (*domain.AllocationResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var start, end time.Time
+	err = tx.QueryRowContext(ctx, "SELECT start_time, end_time FROM rental_reservations WHERE id = $1 FOR UPDATE", reservationID).Scan(&start, &end)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock reservation: %w", err)
+	}
+
+	lines, err := r.allocationLines(ctx, tx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	var line *allocationLine
+	var opt *domain.SubstituteOption
+	for i := range lines {
+		if lines[i].demandID != demandID || lines[i].itemTypeID != itemTypeID {
+			continue
+		}
+		line = &lines[i]
+		for j := range line.substitutes {
+			if line.substitutes[j].ItemTypeID == substituteItemTypeID {
+				opt = &line.substitutes[j]
+			}
+		}
+	}
+	if line == nil {
+		return nil, fmt.Errorf("%w: demand %d has no line for item_type %d", domain.ErrSubstitutionNotAllowed, demandID, itemTypeID)
+	}
+	if opt == nil {
+		return nil, fmt.Errorf("%w: item_type %d cannot replace item_type %d", domain.ErrSubstitutionNotAllowed, substituteItemTypeID, itemTypeID)
+	}
+
+	held, substituted, err := countActiveHolds(ctx, tx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	missing := line.quantity - held[[2]int64{demandID, itemTypeID}]
+	want := substituteLimit(*opt, missing, substituted[[3]int64{demandID, itemTypeID, substituteItemTypeID}])
+	if quantity < want {
+		want = quantity
+	}
+	if want <= 0 {
+		return nil, fmt.Errorf("%w: nothing left to substitute on demand %d", domain.ErrSubstitutionNotAllowed, demandID)
+	}
+
+	assetIDs, err := pickHoldableAssets(ctx, tx, substituteItemTypeID, start, end, line.transit, want, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(assetIDs) == 0 {
+		return nil, fmt.Errorf("%w: item_type %d", domain.ErrInsufficientInventory, substituteItemTypeID)
+	}
+	now := time.Now()
+	for _, assetID := range assetIDs {
+		h := &domain.AssetHold{
+			ReservationID:   reservationID,
+			DemandID:        demandID,
+			AssetID:         assetID,
+			ItemTypeID:      itemTypeID,
+			StartTime:       start,
+			EndTime:         end,
+			Status:          domain.HoldActive,
+			CreatedByUserID: userID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if err := insertAssetHold(ctx, tx, h); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := domain.Substitution{
+		ItemTypeID:           itemTypeID,
+		SubstituteItemTypeID: substituteItemTypeID,
+		Quantity:             len(assetIDs),
+		RuleID:               opt.RuleID,
+		Applied:              true,
+		AppliedAt:            &now,
+	}
+	if err := recordSubstitutions(ctx, tx, demandID, []domain.Substitution{sub}, false); err != nil {
+		return nil, err
+	}
+
+	result := &domain.AllocationResult{ReservationID: reservationID, Substitutions: []domain.Substitution{sub}}
+	result.Holds, err = listAssetHolds(ctx, tx, reservationID, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+const shippingLabelColumns = `id, shipment_id, carrier, service, tracking_number, COALESCE(label_url, ''), amount, currency, created_at, voided_at`
+
+func activeShipmentLabel(ctx context.Context, q queryer, shipmentID int64) (*domain.ShippingLabel, error) {
+	var l domain.ShippingLabel
+	err := q.QueryRowContext(ctx, `SELECT `+shippingLabelColumns+` FROM shipment_labels WHERE shipment_id = $1 AND voided_at IS NULL`, shipmentID).
+		Scan(&l.ID, &l.ShipmentID, &l.Carrier, &l.Service, &l.TrackingNumber, &l.LabelURL, &l.Amount, &l.Currency, &l.CreatedAt, &l.VoidedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query shipment label: %w", err)
+	}
+	return &l, nil
+}
+
+// GetActiveShipmentLabel returns the shipment's unvoided label, or nil if it has none.
+func (r *SqlRepository) GetActiveShipmentLabel(ctx context.Context, shipmentID int64) (*domain.ShippingLabel, error) {
+	return activeShipmentLabel(ctx, r.db, shipmentID)
+}
+
+// RecordShipmentLabel stores a label bought for a shipment and makes its carrier and
+// tracking number the shipment's own.
+func (r *SqlRepository) RecordShipmentLabel(ctx context.Context, l *domain.ShippingLabel) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM shipments WHERE id = $1 FOR UPDATE", l.ShipmentID).Scan(&id); err != nil {
+		return fmt.Errorf("lock shipment: %w", err)
+	}
+	existing, err := activeShipmentLabel(ctx, tx, l.ShipmentID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%w: %s %s", domain.ErrLabelExists, existing.Carrier, existing.TrackingNumber)
+	}
+
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO shipment_labels (shipment_id, carrier, service, tracking_number, label_url, amount, currency, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8) RETURNING id`,
+		l.ShipmentID, l.Carrier, l.Service, l.TrackingNumber, l.LabelURL, l.Amount, l.Currency, l.CreatedAt).Scan(&l.ID)
+	if err != nil {
+		return fmt.Errorf("insert shipment label: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE shipments SET carrier = $1, tracking_number = $2, updated_at = $3 WHERE id = $4",
+		l.Carrier, l.TrackingNumber, time.Now(), l.ShipmentID); err != nil {
+		return fmt.Errorf("update shipment tracking: %w", err)
+	}
+	return tx.Commit()
+}
+
+// VoidShipmentLabel marks a label void and clears the tracking number it gave its shipment.
+func (r *SqlRepository) VoidShipmentLabel(ctx context.Context, labelID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var shipmentID int64
+	var trackingNumber string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE shipment_labels SET voided_at = $1 WHERE id = $2 AND voided_at IS NULL
+		RETURNING shipment_id, tracking_number`, now, labelID).Scan(&shipmentID, &trackingNumber)
+	if err == sql.ErrNoRows {
+		return domain.ErrNoActiveLabel
+	}
+	if err != nil {
+		return fmt.Errorf("void shipment label: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE shipments SET tracking_number = NULL, updated_at = $1 WHERE id = $2 AND tracking_number = $3",
+		now, shipmentID, trackingNumber); err != nil {
+		return fmt.Errorf("clear shipment tracking: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListTrackableShipments returns the shipments with a carrier and tracking number that
+// have not yet been delivered or returned.
+func (r *SqlRepository) ListTrackableShipments(ctx context.Context) ([]domain.Shipment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, scheduled_delivery_id, provider_id, ship_date, carrier, tracking_number, status, COALESCE(notes, ''), direction, created_at, updated_at
+		FROM shipments
+		WHERE COALESCE(carrier, '') <> '' AND COALESCE(tracking_number, '') <> '' AND status NOT IN ($1, $2)
+		ORDER BY id`, domain.DeliveryDelivered, domain.DeliveryReturned)
+	if err != nil {
+		return nil, fmt.Errorf("query trackable shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var results []domain.Shipment
+	for rows.Next() {
+		var s domain.Shipment
+		if err := rows.Scan(&s.ID, &s.ScheduledDeliveryID, &s.ProviderID, &s.ShipDate, &s.Carrier, &s.TrackingNumber, &s.Status, &s.Notes, &s.Direction, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan trackable shipment: %w", err)
+		}
+		results = append(results, s)
+	}
+	return results, rows.Err()
+}
+
+// RecordTrackingEvents stores the events a carrier reported for a shipment, skipping
+// ones already stored, and walks the shipment through the transitions they imply,
+// side effects included. It returns nil, nil when the shipment does not exist.
+func (r *SqlRepository) RecordTrackingEvents(ctx context.Context, shipmentID int64, events []domain.TrackingEvent) (*domain.Shipment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var carrier, trackingNumber string
+	var status domain.DeliveryStatus
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(carrier, ''), COALESCE(tracking_number, ''), status FROM shipments WHERE id = $1 FOR UPDATE`,
+		shipmentID).Scan(&carrier, &trackingNumber, &status)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock shipment: %w", err)
+	}
+
+	now := time.Now()
+	var recorded []domain.TrackingEvent
+	for _, e := range events {
+		e.ShipmentID = shipmentID
+		if e.Carrier == "" {
+			e.Carrier = carrier
+		}
+		if e.TrackingNumber == "" {
+			e.TrackingNumber = trackingNumber
+		}
+		if e.OccurredAt.IsZero() {
+			e.OccurredAt = now
+		}
+		e.CreatedAt = now
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO shipment_tracking_events (shipment_id, carrier, tracking_number, code, description, location, occurred_at, created_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+			ON CONFLICT (shipment_id, tracking_number, code, occurred_at) DO NOTHING
+			RETURNING id`,
+			e.ShipmentID, e.Carrier, e.TrackingNumber, e.Code, e.Description, e.Location, e.OccurredAt, e.CreatedAt).Scan(&e.ID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("insert tracking event: %w", err)
+		}
+		recorded = append(recorded, e)
+	}
+
+	var reservations []int64
+	reason := fmt.Sprintf("%s tracking %s", carrier, trackingNumber)
+	for _, next := range domain.TrackingTransitions(status, recorded) {
+		shipped, err := r.transitionShipment(ctx, tx, shipmentID, status, next, reason, now)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, shipped...)
+		status = next
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.syncFulfillment(ctx, reservations)
+	return r.GetShipmentByID(ctx, shipmentID)
+}
+
+// ListTrackingEvents returns a shipment's tracking history, oldest first.
+func (r *SqlRepository) ListTrackingEvents(ctx context.Context, shipmentID int64) ([]domain.TrackingEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, shipment_id, carrier, tracking_number, code, COALESCE(description, ''), COALESCE(location, ''), occurred_at, created_at
+		FROM shipment_tracking_events WHERE shipment_id = $1
+		ORDER BY occurred_at, id`, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("query tracking events: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.TrackingEvent{}
+	for rows.Next() {
+		var e domain.TrackingEvent
+		if err := rows.Scan(&e.ID, &e.ShipmentID, &e.Carrier, &e.TrackingNumber, &e.Code, &e.Description, &e.Location, &e.OccurredAt, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan tracking event: %w", err)
+		}
+		results = append(results, e)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlRepository_RecordTrackingEvents_SkipsStoredEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSqlRepository(db)
+	ctx := context.Background()
+	inTransit := time.Now().Add(-2 * time.Hour)
+	exception := time.Now().Add(-time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM shipments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"carrier", "tracking_number", "status"}).AddRow("stub", "TRK1", "DeliveryShipped"))
+	// The in-transit scan was stored on an earlier poll
+	mock.ExpectQuery("INSERT INTO shipment_tracking_events").
+		WithArgs(42, "stub", "TRK1", domain.TrackingInTransit, "", "", inTransit, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("INSERT INTO shipment_tracking_events").
+		WithArgs(42, "stub", "TRK1", domain.TrackingException, "Address query", "Depot", exception, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	// Only the new exception moves the shipment, from shipped to delayed
+	mock.ExpectExec("UPDATE shipments SET status = \\$1, updated_at = \\$2 WHERE id = \\$3").
+		WithArgs(domain.DeliveryDelayed, sqlmock.AnyArg(), 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO outbox_events").
+		WithArgs(domain.EventShipmentDelayed, sqlmock.AnyArg(), domain.OutboxPending, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM shipments WHERE id = \\$1").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.RecordTrackingEvents(ctx, 42, []domain.TrackingEvent{
+		{Code: domain.TrackingInTransit, OccurredAt: inTransit},
+		{Code: domain.TrackingException, Description: "Address query", Location: "Depot", OccurredAt: exception},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// stampColumns maps the timestamps transitions record to their rental_reservations columns.
+var stampColumns = map[domain.ReservationTimestamp]string{
+	domain.StampSubmitted: "submitted_at",
+	domain.StampApproved:  "approved_at",
+	domain.StampRejected:  "rejected_at",
+	domain.StampCancelled: "cancelled_at",
+	domain.StampFulfilled: "fulfilled_at",
+}
+
+// TransitionRentalReservation applies a user-requested action from the reservation
+// state machine. Approval goes through the same availability check and allocation as
+// ApproveRentalReservation. It returns nil, nil when the reservation does not exist.
+func (r *SqlRepository) TransitionRentalReservation(ctx context.Context, id int64, req *domain.ReservationTransitionRequest, userID *int64) (*domain.ReservationTransitionRecord, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rr, err := getRentalReservation(ctx, tx, id, true)
+	if err != nil || rr == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var rec *domain.ReservationTransitionRecord
+	if req.Action == domain.ReservationActionApprove {
+		// approveReservationTx also tops up confirmed reservations; here approving one is refused
+		if _, err := domain.FindReservationTransition(req.Action, &domain.ReservationTransitionState{Reservation: rr, Now: now}); err != nil {
+			return nil, err
+		}
+		if _, err := r.approveReservationTx(ctx, tx, id, req.Reason, userID); err != nil {
+			return nil, err
+		}
+		rec, err = lastReservationTransition(ctx, tx, id)
+	} else {
+		rec, err = r.transitionReservationTx(ctx, tx, rr, req.Action, req.Reason, userID, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// transitionReservationTx checks a user-requested action against the state machine and
+// applies it to rr, which must be locked in tx.
+func (r *SqlRepository) transitionReservationTx(ctx context.Context, tx *sql.Tx, rr *domain.RentalReservation, action domain.ReservationAction, reason string, userID *int64, now time.Time) (*domain.ReservationTransitionRecord, error) {
+	state, err := reservationTransitionState(ctx, tx, rr, now)
+	if err != nil {
+		return nil, err
+	}
+	t, err := domain.FindReservationTransition(action, state)
+	if err != nil {
+		return nil, err
+	}
+	if t.System {
+		return nil, fmt.Errorf("%w: %s is applied by the system", domain.ErrInvalidTransition, action)
+	}
+	return r.applyReservationTransition(ctx, tx, rr, t, reason, userID, now)
+}
+
+// applyReservationTransition moves rr along t inside tx and carries out its side
+// effects: the timestamp and acting user on the reservation, the history entry, the
+// released holds and the outbox event.
+func (r *SqlRepository) applyReservationTransition(ctx context.Context, tx *sql.Tx, rr *domain.RentalReservation, t *domain.ReservationTransition, reason string, userID *int64, now time.Time) (*domain.ReservationTransitionRecord, error) {
+	rec := &domain.ReservationTransitionRecord{
+		ReservationID: rr.ID,
+		Action:        t.Action,
+		FromStatus:    rr.ReservationStatus,
+		ToStatus:      t.To,
+		Reason:        reason,
+		UserID:        userID,
+		CreatedAt:     now,
+	}
+	rr.ApplyTransition(t, now, userID)
+
+	query := "UPDATE rental_reservations SET reservation_status = $1, updated_by_user_id = $2, updated_at = $3"
+	if col, ok := stampColumns[t.Stamp]; ok {
+		query += ", " + col + " = $3"
+	}
+	query += " WHERE id = $4"
+	if _, err := tx.ExecContext(ctx, query, rr.ReservationStatus, userID, now, rr.ID); err != nil {
+		return nil, fmt.Errorf("update reservation status: %w", err)
+	}
+
+	var reasonArg interface{}
+	if reason != "" {
+		reasonArg = reason
+	}
+	err := tx.QueryRowContext(ctx, `INSERT INTO reservation_transitions (
+		reservation_id, action, from_status, to_status, reason, user_id, created_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		rec.ReservationID, rec.Action, rec.FromStatus, rec.ToStatus, reasonArg, rec.UserID, rec.CreatedAt,
+	).Scan(&rec.ID)
+	if err != nil {
+		return nil, fmt.Errorf("insert reservation_transition: %w", err)
+	}
+
+	if t.ReleasesHolds {
+		_, err := tx.ExecContext(ctx, "UPDATE asset_holds SET status = $1, updated_at = $2 WHERE reservation_id = $3 AND status = 'active'",
+			domain.HoldReleased, now, rr.ID)
+		if err != nil {
+			return nil, fmt.Errorf("release asset_holds: %w", err)
+		}
+	}
+
+	if t.Event != "" {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"reservation_id": rr.ID,
+			"action":         t.Action,
+			"from":           rec.FromStatus,
+			"status":         rec.ToStatus,
+			"reason":         reason,
+			"user_id":        userID,
+		})
+		if err := r.AppendEvent(ctx, tx, &domain.OutboxEvent{Type: t.Event, Payload: payload}); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+// reservationTransitionState gathers what the transition guards look at.
+func reservationTransitionState(ctx context.Context, q queryer, rr *domain.RentalReservation, now time.Time) (*domain.ReservationTransitionState, error) {
+	state := &domain.ReservationTransitionState{Reservation: rr, Now: now}
+	err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM check_out_actions co
+		WHERE co.reservation_id = $1 AND co.action_status = 'Completed'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM return_actions ret
+		      WHERE ret.reservation_id = co.reservation_id AND ret.asset_id = co.asset_id AND ret.start_time >= co.start_time
+		  )`, rr.ID).Scan(&state.OutstandingAssets)
+	if err != nil {
+		return nil, fmt.Errorf("count outstanding assets: %w", err)
+	}
+	return state, nil
+}
+
+// UpdateRentalReservationStatus moves a reservation to status through the system
+// transition leading there, as fulfillment does after check-outs and returns. User
+// actions such as approving or cancelling go through TransitionRentalReservation.
+func (r *SqlRepository) UpdateRentalReservationStatus(ctx context.Context, id int64, status domain.RentalReservationStatus) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rr, err := getRentalReservation(ctx, tx, id, true)
+	if err != nil {
+		return err
+	}
+	if rr == nil {
+		return fmt.Errorf("reservation %d not found", id)
+	}
+	if rr.ReservationStatus == status {
+		return nil
+	}
+	t := domain.SystemTransitionTo(rr.ReservationStatus, status)
+	if t == nil {
+		return fmt.Errorf("%w: no system transition from %s to %s", domain.ErrInvalidTransition, rr.ReservationStatus, status)
+	}
+	if _, err := r.applyReservationTransition(ctx, tx, rr, t, "", nil, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetReservationLifecycle returns a reservation's status, the transitions a user may
+// request next and its transition history. It returns nil, nil when the reservation
+// does not exist.
+func (r *SqlRepository) GetReservationLifecycle(ctx context.Context, id int64) (*domain.ReservationLifecycle, error) {
+	rr, err := getRentalReservation(ctx, r.db, id, false)
+	if err != nil || rr == nil {
+		return nil, err
+	}
+	state, err := reservationTransitionState(ctx, r.db, rr, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	history, err := listReservationTransitions(ctx, r.db, id, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ReservationLifecycle{
+		ReservationID: id,
+		Status:        rr.ReservationStatus,
+		Allowed:       domain.AllowedReservationTransitions(state),
+		History:       history,
+	}, nil
+}
+
+// lastReservationTransition returns the most recent history entry of a reservation.
+func lastReservationTransition(ctx context.Context, q queryer, id int64) (*domain.ReservationTransitionRecord, error) {
+	history, err := listReservationTransitions(ctx, q, id, 1)
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+	return &history[0], nil
+}
+
+// listReservationTransitions returns a reservation's history, newest first, limited
+// to limit entries when limit is positive.
+func listReservationTransitions(ctx context.Context, q queryer, id int64, limit int) ([]domain.ReservationTransitionRecord, error) {
+	query := `SELECT id, reservation_id, action, from_status, to_status, COALESCE(reason, ''), user_id, created_at
+	          FROM reservation_transitions WHERE reservation_id = $1 ORDER BY created_at DESC, id DESC`
+	args := []interface{}{id}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reservation_transitions: %w", err)
+	}
+	defer rows.Close()
+
+	history := []domain.ReservationTransitionRecord{}
+	for rows.Next() {
+		var rec domain.ReservationTransitionRecord
+		if err := rows.Scan(&rec.ID, &rec.ReservationID, &rec.Action, &rec.FromStatus, &rec.ToStatus, &rec.Reason, &rec.UserID, &rec.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan reservation_transition: %w", err)
+		}
+		history = append(history, rec)
+	}
+	return history, rows.Err()
+}
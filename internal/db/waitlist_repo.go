@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/lib/pq"
+)
+
+const waitlistColumns = `id, reservation_id, item_type_id, priority, auto_approve, status, requested, available,
+	created_by_user_id, notified_at, resolved_at, created_at, updated_at`
+
+// EnqueueWaitlist queues a reservation behind every item type it is short on. An
+// entry that is already open for the same item type has its priority and figures
+// refreshed instead of being duplicated.
+func (r *SqlRepository) EnqueueWaitlist(ctx context.Context, reservationID int64, shortfalls []domain.Shortfall, priority int, autoApprove bool, userID *int64) ([]domain.WaitlistEntry, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `INSERT INTO reservation_waitlist (
+		reservation_id, item_type_id, priority, auto_approve, status, requested, available, created_by_user_id, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+	ON CONFLICT (reservation_id, item_type_id) WHERE status IN ('waiting', 'notified') DO UPDATE SET
+		priority = EXCLUDED.priority, auto_approve = EXCLUDED.auto_approve,
+		requested = EXCLUDED.requested, available = EXCLUDED.available, updated_at = EXCLUDED.updated_at
+	RETURNING ` + waitlistColumns
+
+	var entries []domain.WaitlistEntry
+	for _, sf := range shortfalls {
+		e, err := scanWaitlistEntry(tx.QueryRowContext(ctx, query,
+			reservationID, sf.ItemTypeID, priority, autoApprove, domain.WaitlistWaiting, sf.Requested, sf.Available, userID, now))
+		if err != nil {
+			return nil, fmt.Errorf("enqueue waitlist: %w", err)
+		}
+		entries = append(entries, *e)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListWaitlist returns the open waitlist entries in service order: highest priority
+// first, then oldest. A non-nil itemTypeID restricts the list to that item type.
+func (r *SqlRepository) ListWaitlist(ctx context.Context, itemTypeID *int64) ([]domain.WaitlistEntry, error) {
+	query := `SELECT ` + waitlistColumns + ` FROM reservation_waitlist
+	          WHERE status IN ('waiting', 'notified') AND ($1::BIGINT IS NULL OR item_type_id = $1)
+	          ORDER BY priority DESC, created_at, id`
+	rows, err := r.db.QueryContext(ctx, query, itemTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("query reservation_waitlist: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.WaitlistEntry{}
+	for rows.Next() {
+		e, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan waitlist entry: %w", err)
+		}
+		results = append(results, *e)
+	}
+	return results, nil
+}
+
+// UpdateWaitlistPriority reorders an open entry.
+func (r *SqlRepository) UpdateWaitlistPriority(ctx context.Context, id int64, priority int) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE reservation_waitlist SET priority = $1, updated_at = $2 WHERE id = $3 AND status IN ('waiting', 'notified')",
+		priority, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("update waitlist priority: %w", err)
+	}
+	return nil
+}
+
+// SetWaitlistStatus moves open entries to a new status, stamping notified_at or
+// resolved_at as appropriate.
+func (r *SqlRepository) SetWaitlistStatus(ctx context.Context, ids []int64, status domain.WaitlistStatus) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var notifiedAt, resolvedAt *time.Time
+	switch status {
+	case domain.WaitlistNotified:
+		notifiedAt = &now
+	case domain.WaitlistPromoted, domain.WaitlistRemoved:
+		resolvedAt = &now
+	}
+	_, err := r.db.ExecContext(ctx, `UPDATE reservation_waitlist SET
+		status = $1, notified_at = COALESCE($2, notified_at), resolved_at = $3, updated_at = $4
+		WHERE id = ANY($5) AND status IN ('waiting', 'notified')`,
+		status, notifiedAt, resolvedAt, now, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("update waitlist status: %w", err)
+	}
+	return nil
+}
+
+func scanWaitlistEntry(scanner interface{ Scan(...any) error }) (*domain.WaitlistEntry, error) {
+	var e domain.WaitlistEntry
+	err := scanner.Scan(&e.ID, &e.ReservationID, &e.ItemTypeID, &e.Priority, &e.AutoApprove, &e.Status, &e.Requested, &e.Available,
+		&e.CreatedByUserID, &e.NotifiedAt, &e.ResolvedAt, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrHoldConflict is returned when an asset is already held by another reservation
+// for an overlapping window.
+var ErrHoldConflict = errors.New("asset is held by another reservation")
+
+// ErrHoldNotFound is returned when a hold does not exist or belongs to another reservation.
+var ErrHoldNotFound = errors.New("asset hold not found")
+
+// ErrHoldNotActive is returned when a released or consumed hold is changed.
+var ErrHoldNotActive = errors.New("asset hold is not active")
+
+// ErrHoldItemType is returned when an asset cannot satisfy a hold's item type.
+var ErrHoldItemType = errors.New("asset does not match the hold's item type")
+
+// AssetHoldStatus tracks the lifecycle of a hard allocation.
+type AssetHoldStatus string
+
+const (
+	HoldActive   AssetHoldStatus = "active"
+	HoldReleased AssetHoldStatus = "released" // Freed by cancellation, reallocation or manual release
+	HoldConsumed AssetHoldStatus = "consumed" // The asset was checked out for the reservation
+)
+
+// AssetHold pins a specific serialized asset to a Demand line of a reservation
+// for a bounded time window.
+type AssetHold struct {
+	ID              int64           `json:"id"`
+	ReservationID   int64           `json:"reservationId"`
+	DemandID        int64           `json:"demandId"`
+	AssetID         int64           `json:"assetId"`
+	ItemTypeID      int64           `json:"itemTypeId"` // Item type of the demand line the hold satisfies (may differ from the asset's when substituted)
+	StartTime       time.Time       `json:"startTime"`
+	EndTime         time.Time       `json:"endTime"`
+	Status          AssetHoldStatus `json:"status"`
+	CreatedByUserID *int64          `json:"createdByUserId,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
+
+// AllocationResult is the outcome of pinning assets to a reservation.
+type AllocationResult struct {
+	ReservationID int64       `json:"reservationId"`
+	Holds         []AssetHold `json:"holds"`
+	Shortfalls    []Shortfall `json:"shortfalls,omitempty"`
+	// Substitutions applied or proposed while pinning; proposals are also recorded on the demands
+	Substitutions []Substitution `json:"substitutions,omitempty"`
+}
@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrInsufficientInventory is returned when a reservation cannot be confirmed
+// because its demands exceed what is available in its window.
+var ErrInsufficientInventory = errors.New("insufficient inventory")
+
+// InsufficientInventoryError lists every item type a reservation is short on.
+// For a reservation series, Occurrences breaks the shortfalls down per occurrence.
+// It matches ErrInsufficientInventory with errors.Is.
+type InsufficientInventoryError struct {
+	Shortfalls  []Shortfall
+	Occurrences []OccurrenceShortfall
+}
+
+func (e *InsufficientInventoryError) Error() string {
+	if len(e.Shortfalls) == 0 {
+		return ErrInsufficientInventory.Error()
+	}
+	sf := e.Shortfalls[0]
+	msg := fmt.Sprintf("%v for item_type %d: requested %d, available %d", ErrInsufficientInventory, sf.ItemTypeID, sf.Requested, sf.Available)
+	if len(e.Occurrences) > 0 {
+		msg += fmt.Sprintf(" (%d occurrences short, first starting %s)", len(e.Occurrences), e.Occurrences[0].StartTime.Format(time.RFC3339))
+	}
+	return msg
+}
+
+func (e *InsufficientInventoryError) Unwrap() error { return ErrInsufficientInventory }
+
+// AvailabilityGranularity controls the bucket size used when sampling an
+// availability timeline for display.
+type AvailabilityGranularity string
+
+const (
+	GranularityHour AvailabilityGranularity = "hour"
+	GranularityDay  AvailabilityGranularity = "day"
+	GranularityWeek AvailabilityGranularity = "week"
+)
+
+// ParseAvailabilityGranularity validates a granularity string. An empty
+// string falls back to daily buckets.
+func ParseAvailabilityGranularity(s string) (AvailabilityGranularity, error) {
+	switch AvailabilityGranularity(s) {
+	case "":
+		return GranularityDay, nil
+	case GranularityHour, GranularityDay, GranularityWeek:
+		return AvailabilityGranularity(s), nil
+	}
+	return "", fmt.Errorf("unsupported granularity %q", s)
+}
+
+// Next returns the start of the bucket following the one that starts at t.
+func (g AvailabilityGranularity) Next(t time.Time) time.Time {
+	switch g {
+	case GranularityHour:
+		return t.Add(time.Hour)
+	case GranularityWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// UsageInterval is a quantity of an item type that is committed over the
+// half-open interval [Start, End). A zero Start means the usage is already in
+// effect; a zero End means it has no known end.
+type UsageInterval struct {
+	ItemTypeID int64
+	Start      time.Time
+	End        time.Time
+	Quantity   int
+	Source     string // An inventory consumer kind
+}
+
+// TurnaroundBuffer is how long a unit is out of circulation around a rental window:
+// preparation before it goes out, inspection and cleaning after it comes back.
+type TurnaroundBuffer struct {
+	Pre  time.Duration
+	Post time.Duration
+}
+
+// Occupied returns the window a rental of [start, end) takes a unit out of
+// circulation, with transit to and from the rental place added on both sides.
+func (b TurnaroundBuffer) Occupied(start, end time.Time, transit time.Duration) (time.Time, time.Time) {
+	return start.Add(-b.Pre - transit), end.Add(b.Post + transit)
+}
+
+type availabilityStep struct {
+	At        time.Time
+	Available int
+}
+
+// AvailabilityTimeline is a step function of available units for one item type,
+// built with a single sweep over the start and end points of all usage intervals.
+type AvailabilityTimeline struct {
+	ItemTypeID int64
+	Total      int
+	Buffer     TurnaroundBuffer // Already applied to the usage the timeline was built from
+
+	initial int
+	steps   []availabilityStep
+}
+
+// BuildAvailabilityTimeline sweeps the usage intervals of one item type and
+// returns the resulting availability step function.
+func BuildAvailabilityTimeline(itemTypeID int64, total int, usage []UsageInterval) *AvailabilityTimeline {
+	tl := &AvailabilityTimeline{ItemTypeID: itemTypeID, Total: total, initial: total}
+
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	var edges []edge
+	for _, u := range usage {
+		if u.Quantity == 0 {
+			continue
+		}
+		if !u.Start.IsZero() && !u.End.IsZero() && !u.End.After(u.Start) {
+			continue
+		}
+		if u.Start.IsZero() {
+			tl.initial -= u.Quantity
+		} else {
+			edges = append(edges, edge{at: u.Start, delta: -u.Quantity})
+		}
+		if !u.End.IsZero() {
+			edges = append(edges, edge{at: u.End, delta: u.Quantity})
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool { return edges[i].at.Before(edges[j].at) })
+
+	current := tl.initial
+	for i := 0; i < len(edges); {
+		at := edges[i].at
+		// Intervals are half-open, so every edge at the same instant is applied
+		// together; a unit released at t can be picked up again at t.
+		for ; i < len(edges) && edges[i].at.Equal(at); i++ {
+			current += edges[i].delta
+		}
+		if n := len(tl.steps); n > 0 && tl.steps[n-1].Available == current {
+			continue
+		}
+		if len(tl.steps) == 0 && current == tl.initial {
+			continue
+		}
+		tl.steps = append(tl.steps, availabilityStep{At: at, Available: current})
+	}
+
+	return tl
+}
+
+// AvailableAt returns the number of units available at instant t.
+func (tl *AvailabilityTimeline) AvailableAt(t time.Time) int {
+	i := sort.Search(len(tl.steps), func(i int) bool { return tl.steps[i].At.After(t) })
+	if i == 0 {
+		return tl.initial
+	}
+	return tl.steps[i-1].Available
+}
+
+// MinAvailable returns the lowest availability reached during [start, end).
+func (tl *AvailabilityTimeline) MinAvailable(start, end time.Time) int {
+	min := tl.AvailableAt(start)
+	i := sort.Search(len(tl.steps), func(i int) bool { return tl.steps[i].At.After(start) })
+	for ; i < len(tl.steps) && tl.steps[i].At.Before(end); i++ {
+		if tl.steps[i].Available < min {
+			min = tl.steps[i].Available
+		}
+	}
+	return min
+}
+
+// MinAvailableForRental returns the lowest availability over the window a rental of
+// [start, end) would occupy, including the item type's buffers and transit time.
+func (tl *AvailabilityTimeline) MinAvailableForRental(start, end time.Time, transit time.Duration) int {
+	return tl.MinAvailable(tl.Buffer.Occupied(start, end, transit))
+}
+
+// Sample buckets the timeline from start through end, reporting the lowest
+// availability inside each bucket.
+func (tl *AvailabilityTimeline) Sample(start, end time.Time, g AvailabilityGranularity) []AvailabilityPoint {
+	var points []AvailabilityPoint
+	for d := start; !d.After(end); d = g.Next(d) {
+		points = append(points, AvailabilityPoint{
+			Date:      d,
+			Available: tl.MinAvailable(d, g.Next(d)),
+			Total:     tl.Total,
+		})
+	}
+	return points
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvailabilityTimeline_SweepLine(t *testing.T) {
+	base := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+
+	tl := BuildAvailabilityTimeline(1, 10, []UsageInterval{
+		{ItemTypeID: 1, Start: at(0), End: at(4), Quantity: 3},
+		{ItemTypeID: 1, Start: at(4), End: at(8), Quantity: 5}, // back-to-back with the first
+		{ItemTypeID: 1, Start: at(6), End: at(30), Quantity: 2},
+		{ItemTypeID: 1, End: at(2), Quantity: 1}, // ad-hoc, already out
+	})
+
+	assert.Equal(t, 9, tl.AvailableAt(at(-1))) // open-start usage applies before any edge
+	assert.Equal(t, 6, tl.AvailableAt(at(0)))
+	assert.Equal(t, 7, tl.AvailableAt(at(2)))
+	assert.Equal(t, 5, tl.AvailableAt(at(4)))
+	assert.Equal(t, 3, tl.AvailableAt(at(6)))
+	assert.Equal(t, 8, tl.AvailableAt(at(8)))
+	assert.Equal(t, 10, tl.AvailableAt(at(30)))
+
+	// Half-open intervals: the window ending where the second booking starts is unaffected by it.
+	assert.Equal(t, 6, tl.MinAvailable(at(0), at(4)))
+	assert.Equal(t, 3, tl.MinAvailable(at(0), at(24)))
+	assert.Equal(t, 8, tl.MinAvailable(at(8), at(30)))
+}
+
+func TestAvailabilityTimeline_MinAvailableForRental(t *testing.T) {
+	base := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+
+	// The existing booking [10, 20) is already padded by 1h pre / 2h post: [9, 22)
+	tl := BuildAvailabilityTimeline(1, 1, []UsageInterval{{ItemTypeID: 1, Start: at(9), End: at(22), Quantity: 1}})
+	tl.Buffer = TurnaroundBuffer{Pre: time.Hour, Post: 2 * time.Hour}
+
+	assert.Equal(t, 1, tl.MinAvailable(at(0), at(9)))
+	assert.Equal(t, 0, tl.MinAvailableForRental(at(0), at(9), 0)) // its own post buffer runs into the booking
+	assert.Equal(t, 1, tl.MinAvailableForRental(at(0), at(7), 0)) // back-to-back with both buffers
+	assert.Equal(t, 0, tl.MinAvailableForRental(at(0), at(7), time.Hour))
+	assert.Equal(t, 1, tl.MinAvailableForRental(at(23), at(30), 0)) // pre buffer ends where the booking's post buffer ends
+	assert.Equal(t, 0, tl.MinAvailableForRental(at(22), at(30), 0))
+
+	start, end := tl.Buffer.Occupied(at(10), at(20), 30*time.Minute)
+	assert.Equal(t, at(9).Add(-30*time.Minute), start)
+	assert.Equal(t, at(22).Add(30*time.Minute), end)
+}
+
+func TestAvailabilityTimeline_Sample(t *testing.T) {
+	base := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	tl := BuildAvailabilityTimeline(1, 4, []UsageInterval{
+		{Start: base.Add(30 * time.Hour), End: base.Add(31 * time.Hour), Quantity: 6},
+	})
+
+	days := tl.Sample(base, base.AddDate(0, 0, 2), GranularityDay)
+	assert.Len(t, days, 3)
+	assert.Equal(t, 4, days[0].Available)
+	assert.Equal(t, -2, days[1].Available)
+	assert.Equal(t, 4, days[2].Available)
+
+	hours := tl.Sample(base.Add(29*time.Hour), base.Add(31*time.Hour), GranularityHour)
+	assert.Len(t, hours, 3)
+	assert.Equal(t, []int{4, -2, 4}, []int{hours[0].Available, hours[1].Available, hours[2].Available})
+
+	weeks := tl.Sample(base, base.AddDate(0, 0, 7), GranularityWeek)
+	assert.Len(t, weeks, 2)
+	assert.Equal(t, -2, weeks[0].Available)
+}
+
+func TestParseAvailabilityGranularity(t *testing.T) {
+	g, err := ParseAvailabilityGranularity("")
+	assert.NoError(t, err)
+	assert.Equal(t, GranularityDay, g)
+
+	g, err = ParseAvailabilityGranularity("hour")
+	assert.NoError(t, err)
+	assert.Equal(t, GranularityHour, g)
+
+	_, err = ParseAvailabilityGranularity("minute")
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoActiveLabel is returned when a shipment has no unvoided label to act on.
+var ErrNoActiveLabel = errors.New("shipment has no active label")
+
+// ErrLabelExists is returned when a label is bought for a shipment that already has one.
+var ErrLabelExists = errors.New("shipment already has an active label")
+
+// Carrier is a parcel or freight carrier that can price, label and track shipments.
+type Carrier interface {
+	QuoteRates(ctx context.Context, req *RateRequest) ([]RateQuote, error)
+	CreateLabel(ctx context.Context, req *LabelRequest) (*ShippingLabel, error)
+	VoidLabel(ctx context.Context, trackingNumber string) error
+	GetTrackingEvents(ctx context.Context, trackingNumber string) ([]TrackingEvent, error)
+}
+
+// Parcel is one package handed to a carrier.
+type Parcel struct {
+	WeightGrams int `json:"weightGrams"`
+	LengthCm    int `json:"lengthCm,omitempty"`
+	WidthCm     int `json:"widthCm,omitempty"`
+	HeightCm    int `json:"heightCm,omitempty"`
+}
+
+// RateRequest asks a carrier what it would charge to move parcels between two places.
+type RateRequest struct {
+	Carrier     string         `json:"carrier,omitempty"` // Empty to ask every registered carrier
+	FromPlaceID int64          `json:"fromPlaceId"`
+	ToPlaceID   int64          `json:"toPlaceId"`
+	From        *PostalAddress `json:"from,omitempty"`
+	To          *PostalAddress `json:"to,omitempty"`
+	ShipDate    time.Time      `json:"shipDate"`
+	Parcels     []Parcel       `json:"parcels"`
+}
+
+// RateQuote is one service a carrier offers for a RateRequest.
+type RateQuote struct {
+	Carrier           string     `json:"carrier"`
+	Service           string     `json:"service"`
+	Amount            int64      `json:"amount"` // Minor units
+	Currency          string     `json:"currency"`
+	EstimatedDelivery *time.Time `json:"estimatedDelivery,omitempty"`
+}
+
+// LabelRequest buys a label for a shipment.
+type LabelRequest struct {
+	ShipmentID int64 `json:"shipmentId"`
+	RateRequest
+	Service string `json:"service"`
+}
+
+// ShippingLabel is a label bought from a carrier. Voiding it frees the shipment for
+// a new one.
+type ShippingLabel struct {
+	ID             int64      `json:"id"`
+	ShipmentID     int64      `json:"shipmentId"`
+	Carrier        string     `json:"carrier"`
+	Service        string     `json:"service"`
+	TrackingNumber string     `json:"trackingNumber"`
+	LabelURL       string     `json:"labelUrl,omitempty"`
+	Amount         int64      `json:"amount"`
+	Currency       string     `json:"currency"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	VoidedAt       *time.Time `json:"voidedAt,omitempty"`
+}
+
+// TrackingCode is a carrier-neutral tracking milestone.
+type TrackingCode string
+
+const (
+	TrackingLabelCreated     TrackingCode = "LabelCreated"
+	TrackingPickedUp         TrackingCode = "PickedUp"
+	TrackingInTransit        TrackingCode = "InTransit"
+	TrackingOutForDelivery   TrackingCode = "OutForDelivery"
+	TrackingDelivered        TrackingCode = "Delivered"
+	TrackingException        TrackingCode = "Exception"
+	TrackingReturnedToSender TrackingCode = "ReturnedToSender"
+)
+
+// DeliveryStatus maps a tracking milestone onto the shipment status it implies.
+// Unknown codes imply none.
+func (c TrackingCode) DeliveryStatus() (DeliveryStatus, bool) {
+	switch c {
+	case TrackingLabelCreated:
+		return DeliveryPreparing, true
+	case TrackingPickedUp, TrackingInTransit, TrackingOutForDelivery:
+		return DeliveryShipped, true
+	case TrackingException:
+		return DeliveryDelayed, true
+	case TrackingDelivered:
+		return DeliveryDelivered, true
+	case TrackingReturnedToSender:
+		return DeliveryReturned, true
+	}
+	return "", false
+}
+
+// TrackingEvent is one scan or status update reported by a carrier.
+type TrackingEvent struct {
+	ID             int64        `json:"id"`
+	ShipmentID     int64        `json:"shipmentId"`
+	Carrier        string       `json:"carrier"`
+	TrackingNumber string       `json:"trackingNumber"`
+	Code           TrackingCode `json:"code"`
+	Description    string       `json:"description,omitempty"`
+	Location       string       `json:"location,omitempty"`
+	OccurredAt     time.Time    `json:"occurredAt"`
+	CreatedAt      time.Time    `json:"createdAt"`
+}
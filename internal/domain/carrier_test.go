@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackingTransitions(t *testing.T) {
+	t0 := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	events := []TrackingEvent{
+		{Code: TrackingDelivered, OccurredAt: t0.Add(26 * time.Hour)},
+		{Code: TrackingLabelCreated, OccurredAt: t0},
+		{Code: TrackingInTransit, OccurredAt: t0.Add(2 * time.Hour)},
+		{Code: TrackingException, OccurredAt: t0.Add(5 * time.Hour)},
+		{Code: TrackingOutForDelivery, OccurredAt: t0.Add(20 * time.Hour)},
+	}
+	assert.Equal(t, []DeliveryStatus{DeliveryShipped, DeliveryDelayed, DeliveryShipped, DeliveryDelivered},
+		TrackingTransitions("Preparing", events))
+
+	// Late or repeated scans never move a shipment backwards
+	assert.Empty(t, TrackingTransitions(DeliveryDelivered, events[1:3]))
+	// A first poll that already reports delivery still passes through shipped
+	assert.Equal(t, []DeliveryStatus{DeliveryShipped, DeliveryDelivered}, TrackingTransitions(DeliveryPreparing, events[:1]))
+	assert.Equal(t, []DeliveryStatus{DeliveryReturned},
+		TrackingTransitions(DeliveryShipped, []TrackingEvent{{Code: TrackingReturnedToSender, OccurredAt: t0}}))
+}
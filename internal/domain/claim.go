@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidClaim is returned when a claim cannot be opened or moved to the requested status.
+var ErrInvalidClaim = errors.New("invalid claim")
+
+type ClaimType string
+
+const (
+	ClaimDamage ClaimType = "damage" // Backed by an inspection that recorded damage
+	ClaimLoss   ClaimType = "loss"   // Backed by a check-out that was never returned
+)
+
+type ClaimStatus string
+
+const (
+	ClaimOpen        ClaimStatus = "open"
+	ClaimUnderReview ClaimStatus = "under_review"
+	ClaimSettled     ClaimStatus = "settled"
+	ClaimDenied      ClaimStatus = "denied"
+)
+
+// claimTransitions lists the statuses each status may move to. Settled and denied
+// claims can be reopened for review, e.g. when a settlement is disputed.
+var claimTransitions = map[ClaimStatus][]ClaimStatus{
+	ClaimOpen:        {ClaimUnderReview, ClaimSettled, ClaimDenied},
+	ClaimUnderReview: {ClaimSettled, ClaimDenied},
+	ClaimSettled:     {ClaimUnderReview},
+	ClaimDenied:      {ClaimUnderReview},
+}
+
+// Claim holds the renter responsible for an asset that came back damaged or did not
+// come back at all.
+type Claim struct {
+	ID                     int64           `json:"id"`
+	ReservationID          int64           `json:"reservationId"`
+	AssetID                int64           `json:"assetId"`
+	Type                   ClaimType       `json:"type"`
+	Status                 ClaimStatus     `json:"status"`
+	InspectionSubmissionID *int64          `json:"inspectionSubmissionId,omitempty"` // Damage claims
+	CheckOutID             *int64          `json:"checkOutId,omitempty"`             // Loss claims
+	ResponsiblePersonID    *int64          `json:"responsiblePersonId,omitempty"`    // Defaults to the person the reservation is under
+	ResponsibleCompanyID   *int64          `json:"responsibleCompanyId,omitempty"`   // Defaults to that person's company
+	Description            string          `json:"description,omitempty"`
+	Currency               string          `json:"currency,omitempty"`
+	ClaimedAmount          int64           `json:"claimedAmount"` // Minor units; damage claims default to the inspection's damage charges
+	SettledAmount          *int64          `json:"settledAmount,omitempty"`
+	ResolutionNote         string          `json:"resolutionNote,omitempty"`
+	Evidence               []ClaimEvidence `json:"evidence"`
+	CreatedByUserID        *int64          `json:"createdByUserId,omitempty"`
+	SettledAt              *time.Time      `json:"settledAt,omitempty"`
+	CreatedAt              time.Time       `json:"createdAt"`
+	UpdatedAt              time.Time       `json:"updatedAt"`
+}
+
+// ClaimEvidence is a photo or note backing a claim. Image responses of the claim's
+// inspection are attached when it is opened.
+type ClaimEvidence struct {
+	ID                   int64     `json:"id"`
+	ClaimID              int64     `json:"claimId"`
+	URL                  string    `json:"url,omitempty"`
+	Note                 string    `json:"note,omitempty"`
+	InspectionResponseID *int64    `json:"inspectionResponseId,omitempty"`
+	AddedByUserID        *int64    `json:"addedByUserId,omitempty"`
+	CreatedAt            time.Time `json:"createdAt"`
+}
+
+// ClaimStatusUpdate moves a claim to a new status. Settling requires the agreed amount.
+type ClaimStatusUpdate struct {
+	Status         ClaimStatus `json:"status"`
+	SettledAmount  *int64      `json:"settledAmount,omitempty"`
+	ResolutionNote string      `json:"resolutionNote,omitempty"`
+}
+
+// ClaimFilter narrows ListClaims; zero fields match everything.
+type ClaimFilter struct {
+	ReservationID *int64
+	AssetID       *int64
+	Status        ClaimStatus
+}
+
+// Validate checks a new claim's type and the record backing it.
+func (c *Claim) Validate() error {
+	switch c.Type {
+	case ClaimDamage:
+		if c.InspectionSubmissionID == nil {
+			return fmt.Errorf("%w: damage claims need an inspectionSubmissionId", ErrInvalidClaim)
+		}
+	case ClaimLoss:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidClaim, c.Type)
+	}
+	if c.ReservationID == 0 || c.AssetID == 0 {
+		return fmt.Errorf("%w: reservationId and assetId are required", ErrInvalidClaim)
+	}
+	if c.ClaimedAmount < 0 {
+		return fmt.Errorf("%w: claimedAmount cannot be negative", ErrInvalidClaim)
+	}
+	return nil
+}
+
+// ApplyStatus moves the claim to upd.Status if the claim's current status allows it.
+func (c *Claim) ApplyStatus(upd *ClaimStatusUpdate, at time.Time) error {
+	allowed := false
+	for _, to := range claimTransitions[c.Status] {
+		if to == upd.Status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: cannot move a %s claim to %s", ErrInvalidClaim, c.Status, upd.Status)
+	}
+
+	switch upd.Status {
+	case ClaimSettled:
+		if upd.SettledAmount == nil || *upd.SettledAmount < 0 {
+			return fmt.Errorf("%w: settling needs a settledAmount", ErrInvalidClaim)
+		}
+		amount := *upd.SettledAmount
+		c.SettledAmount = &amount
+		c.SettledAt = &at
+	case ClaimUnderReview:
+		c.SettledAmount, c.SettledAt = nil, nil
+	}
+	c.Status = upd.Status
+	if upd.ResolutionNote != "" {
+		c.ResolutionNote = upd.ResolutionNote
+	}
+	c.UpdatedAt = at
+	return nil
+}
+
+// Event is the outbox event announcing that the claim reached its current status.
+func (c *Claim) Event() EventType {
+	switch c.Status {
+	case ClaimUnderReview:
+		return EventClaimUnderReview
+	case ClaimSettled:
+		return EventClaimSettled
+	case ClaimDenied:
+		return EventClaimDenied
+	}
+	return EventClaimOpened
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaim_Validate(t *testing.T) {
+	submissionID := int64(4)
+	assert.NoError(t, (&Claim{Type: ClaimDamage, ReservationID: 1, AssetID: 2, InspectionSubmissionID: &submissionID}).Validate())
+	assert.NoError(t, (&Claim{Type: ClaimLoss, ReservationID: 1, AssetID: 2}).Validate())
+
+	for name, c := range map[string]*Claim{
+		"damage without inspection": {Type: ClaimDamage, ReservationID: 1, AssetID: 2},
+		"unknown type":              {Type: "theft", ReservationID: 1, AssetID: 2},
+		"missing asset":             {Type: ClaimLoss, ReservationID: 1},
+		"negative amount":           {Type: ClaimLoss, ReservationID: 1, AssetID: 2, ClaimedAmount: -1},
+	} {
+		assert.True(t, errors.Is(c.Validate(), ErrInvalidClaim), name)
+	}
+}
+
+func TestClaim_ApplyStatus(t *testing.T) {
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	c := &Claim{Status: ClaimOpen}
+
+	// Settling needs the agreed amount
+	err := c.ApplyStatus(&ClaimStatusUpdate{Status: ClaimSettled}, now)
+	assert.True(t, errors.Is(err, ErrInvalidClaim))
+	assert.Equal(t, ClaimOpen, c.Status)
+
+	require.NoError(t, c.ApplyStatus(&ClaimStatusUpdate{Status: ClaimUnderReview}, now))
+	assert.Equal(t, EventClaimUnderReview, c.Event())
+
+	amount := int64(9000)
+	require.NoError(t, c.ApplyStatus(&ClaimStatusUpdate{Status: ClaimSettled, SettledAmount: &amount, ResolutionNote: "Agreed with customer"}, now))
+	assert.Equal(t, ClaimSettled, c.Status)
+	assert.Equal(t, int64(9000), *c.SettledAmount)
+	assert.Equal(t, now, *c.SettledAt)
+	assert.Equal(t, "Agreed with customer", c.ResolutionNote)
+	assert.Equal(t, EventClaimSettled, c.Event())
+
+	// A settled claim can only be reopened for review, which clears the settlement
+	assert.True(t, errors.Is(c.ApplyStatus(&ClaimStatusUpdate{Status: ClaimDenied}, now), ErrInvalidClaim))
+	require.NoError(t, c.ApplyStatus(&ClaimStatusUpdate{Status: ClaimUnderReview}, now))
+	assert.Nil(t, c.SettledAmount)
+	assert.Nil(t, c.SettledAt)
+	assert.Equal(t, "Agreed with customer", c.ResolutionNote)
+
+	require.NoError(t, c.ApplyStatus(&ClaimStatusUpdate{Status: ClaimDenied}, now))
+	assert.Equal(t, EventClaimDenied, c.Event())
+	assert.True(t, errors.Is(c.ApplyStatus(&ClaimStatusUpdate{Status: ClaimOpen}, now), ErrInvalidClaim))
+}
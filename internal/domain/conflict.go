@@ -0,0 +1,247 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// Inventory consumer kinds, i.e. what is taking units of an item type out of circulation.
+const (
+	ConsumerReservation = "reservation" // Confirmed reservation not yet pinned to assets
+	ConsumerHold        = "hold"        // Asset pinned to a reservation
+	ConsumerDeployed    = "deployed"    // Asset out until its estimated return
+	ConsumerMaintenance = "maintenance" // Asset in maintenance until its estimated return
+	ConsumerExpiry      = "expiry"      // Stock lot on hand that can no longer go out once it expires
+	ConsumerStockOut    = "stock_out"   // Stock still out on a reservation that has ended
+)
+
+// InventoryConsumer is one reservation, hold, asset or stock lot taking units of an item type
+// over [Start, End), turnaround buffers and transit included. A nil Start means it is
+// already in effect; a nil End means it has no known end.
+type InventoryConsumer struct {
+	Kind            string     `json:"kind"`
+	ItemTypeID      int64      `json:"itemTypeId"`
+	Quantity        int        `json:"quantity"`
+	Start           *time.Time `json:"start,omitempty"`
+	End             *time.Time `json:"end,omitempty"`
+	ReservationID   *int64     `json:"reservationId,omitempty"`
+	ReservationName string     `json:"reservationName,omitempty"`
+	DemandID        *int64     `json:"demandId,omitempty"`
+	HoldID          *int64     `json:"holdId,omitempty"`
+	AssetID         *int64     `json:"assetId,omitempty"`
+	AssetTag        string     `json:"assetTag,omitempty"`
+	LotID           *int64     `json:"lotId,omitempty"`
+	LotNumber       string     `json:"lotNumber,omitempty"`
+}
+
+// Usage expresses the consumer as a usage interval for availability timelines.
+func (c *InventoryConsumer) Usage() UsageInterval {
+	u := UsageInterval{ItemTypeID: c.ItemTypeID, Quantity: c.Quantity, Source: c.Kind}
+	if c.Start != nil {
+		u.Start = *c.Start
+	}
+	if c.End != nil {
+		u.End = *c.End
+	}
+	return u
+}
+
+// overlaps reports whether the consumer takes units at any point of [start, end).
+func (c *InventoryConsumer) overlaps(start, end time.Time) bool {
+	return (c.Start == nil || c.Start.Before(end)) && (c.End == nil || c.End.After(start))
+}
+
+// TimeWindow is a half-open interval [Start, End).
+type TimeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ItemTypeConflict is one item type a demand needs, how much of it is left in the
+// reservation's window and what is using the rest.
+type ItemTypeConflict struct {
+	ItemTypeID  int64               `json:"itemTypeId"`
+	Requested   int                 `json:"requested"`
+	Owned       int                 `json:"owned"`
+	Available   int                 `json:"available"` // Lowest point over the window, buffers and transit included
+	Short       int                 `json:"short"`     // Units substitutes could not make up either
+	Substitutes []int64             `json:"substitutes,omitempty"`
+	Consumers   []InventoryConsumer `json:"consumers"`
+}
+
+// DemandConflict breaks one demand down into the item types it needs.
+type DemandConflict struct {
+	DemandID  int64              `json:"demandId"`
+	ItemKind  string             `json:"itemKind"`
+	ItemID    int64              `json:"itemId"`
+	Quantity  int                `json:"requestedQuantity"`
+	ItemTypes []ItemTypeConflict `json:"itemTypes"`
+}
+
+// ReservationConflicts explains whether a reservation fits its window, what it would
+// compete with, and the earliest window of the same length in which it fits.
+type ReservationConflicts struct {
+	ReservationID int64            `json:"reservationId"`
+	StartTime     time.Time        `json:"startTime"`
+	EndTime       time.Time        `json:"endTime"`
+	Fits          bool             `json:"fits"`
+	Demands       []DemandConflict `json:"demands"`
+	EarliestFit   *TimeWindow      `json:"earliestFit,omitempty"` // Nil when nothing fits before SearchUntil
+	SearchUntil   time.Time        `json:"searchUntil"`
+}
+
+// ConflictLine is one per-item-type requirement of a demand, kits already expanded.
+type ConflictLine struct {
+	DemandID    int64
+	Requirement ComponentRequirement
+	Transit     time.Duration
+}
+
+// ConflictInput is what ExplainConflicts needs from the database. Consumers must cover
+// every item type the lines draw on, substitutes included, from the requested window
+// through SearchUntil plus the reservation's length, and must leave out the
+// reservation's own usage.
+type ConflictInput struct {
+	Reservation *RentalReservation
+	Lines       []ConflictLine
+	Owned       map[int64]int
+	Buffers     map[int64]TurnaroundBuffer
+	Consumers   []InventoryConsumer
+	SearchFrom  time.Time // Earliest start considered for EarliestFit
+	SearchUntil time.Time // Latest start considered for EarliestFit
+}
+
+// ExplainConflicts checks the reservation's lines against its window the way approval
+// does, attributing the used inventory to the consumers behind it, and searches for
+// the earliest window in which the whole reservation fits.
+func ExplainConflicts(in ConflictInput) *ReservationConflicts {
+	rr := in.Reservation
+	var transit time.Duration
+	var itemTypeIDs []int64
+	reqs := make([]ComponentRequirement, 0, len(in.Lines))
+	for _, line := range in.Lines {
+		if line.Transit > transit {
+			transit = line.Transit
+		}
+		reqs = append(reqs, line.Requirement)
+		itemTypeIDs = append(itemTypeIDs, line.Requirement.ItemTypeID)
+		itemTypeIDs = append(itemTypeIDs, line.Requirement.Substitutes...)
+	}
+	timelines := BuildConsumerTimelines(itemTypeIDs, in.Owned, in.Buffers, in.Consumers)
+
+	avail := make(map[int64]int, len(timelines))
+	for id, tl := range timelines {
+		avail[id] = tl.MinAvailableForRental(rr.StartTime, rr.EndTime, transit)
+	}
+	_, missing := allocateRequirements(reqs, avail)
+
+	out := &ReservationConflicts{
+		ReservationID: rr.ID,
+		StartTime:     rr.StartTime,
+		EndTime:       rr.EndTime,
+		Fits:          true,
+		Demands:       []DemandConflict{},
+		SearchUntil:   in.SearchUntil,
+	}
+	byDemand := make(map[int64]int)
+	for _, d := range rr.Demands {
+		byDemand[d.ID] = len(out.Demands)
+		out.Demands = append(out.Demands, DemandConflict{DemandID: d.ID, ItemKind: d.ItemKind, ItemID: d.ItemID, Quantity: d.Quantity, ItemTypes: []ItemTypeConflict{}})
+	}
+	for i, line := range in.Lines {
+		id := line.Requirement.ItemTypeID
+		itc := ItemTypeConflict{
+			ItemTypeID:  id,
+			Requested:   line.Requirement.Quantity,
+			Owned:       in.Owned[id],
+			Available:   avail[id],
+			Short:       missing[i],
+			Substitutes: line.Requirement.Substitutes,
+			Consumers:   []InventoryConsumer{},
+		}
+		from, to := timelines[id].Buffer.Occupied(rr.StartTime, rr.EndTime, transit)
+		for _, c := range in.Consumers {
+			if c.ItemTypeID == id && c.overlaps(from, to) {
+				itc.Consumers = append(itc.Consumers, c)
+			}
+		}
+		if itc.Short > 0 {
+			out.Fits = false
+		}
+		if j, ok := byDemand[line.DemandID]; ok {
+			out.Demands[j].ItemTypes = append(out.Demands[j].ItemTypes, itc)
+		}
+	}
+
+	if out.Fits {
+		out.EarliestFit = &TimeWindow{Start: rr.StartTime, End: rr.EndTime}
+	} else {
+		out.EarliestFit = EarliestFit(reqs, in.SearchFrom, in.SearchUntil, rr.EndTime.Sub(rr.StartTime), transit, timelines)
+	}
+	return out
+}
+
+// BuildConsumerTimelines builds an availability timeline per item type from the units
+// owned and the consumers using them.
+func BuildConsumerTimelines(itemTypeIDs []int64, owned map[int64]int, buffers map[int64]TurnaroundBuffer, consumers []InventoryConsumer) map[int64]*AvailabilityTimeline {
+	usage := make(map[int64][]UsageInterval)
+	for i := range consumers {
+		c := &consumers[i]
+		usage[c.ItemTypeID] = append(usage[c.ItemTypeID], c.Usage())
+	}
+	timelines := make(map[int64]*AvailabilityTimeline, len(itemTypeIDs))
+	for _, id := range itemTypeIDs {
+		if timelines[id] == nil {
+			timelines[id] = BuildAvailabilityTimeline(id, owned[id], usage[id])
+			timelines[id].Buffer = buffers[id]
+		}
+	}
+	return timelines
+}
+
+// EarliestFit returns the earliest window of the given length, starting between from
+// and until, in which every requirement can be covered from each of pools, or nil if
+// there is none. Availability only rises where a timeline steps up, so besides from
+// the only starts worth trying are those whose buffered window begins at a step.
+func EarliestFit(reqs []ComponentRequirement, from, until time.Time, length, transit time.Duration, pools ...map[int64]*AvailabilityTimeline) *TimeWindow {
+	if until.Before(from) {
+		return nil
+	}
+	candidates := []time.Time{from}
+	for _, timelines := range pools {
+		for _, tl := range timelines {
+			for _, step := range tl.steps {
+				t := step.At.Add(tl.Buffer.Pre + transit)
+				if t.After(from) && !t.After(until) {
+					candidates = append(candidates, t)
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	for i, start := range candidates {
+		if i > 0 && start.Equal(candidates[i-1]) {
+			continue
+		}
+		end := start.Add(length)
+		if fitsAll(reqs, start, end, transit, pools) {
+			return &TimeWindow{Start: start, End: end}
+		}
+	}
+	return nil
+}
+
+// fitsAll reports whether the requirements can be covered over [start, end) from every pool.
+func fitsAll(reqs []ComponentRequirement, start, end time.Time, transit time.Duration, pools []map[int64]*AvailabilityTimeline) bool {
+	for _, timelines := range pools {
+		avail := make(map[int64]int, len(timelines))
+		for id, tl := range timelines {
+			avail[id] = tl.MinAvailableForRental(start, end, transit)
+		}
+		if _, short := AllocateRequirements(reqs, avail); len(short) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainConflicts(t *testing.T) {
+	day := 24 * time.Hour
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := start.Add(d); return &t }
+	otherID, assetID, holdID := int64(5), int64(7), int64(9)
+
+	in := ConflictInput{
+		Reservation: &RentalReservation{
+			ID: 1, StartTime: start, EndTime: start.Add(2 * day),
+			Demands: []Demand{
+				{ID: 100, ItemKind: DemandKindItemType, ItemID: 10, Quantity: 2},
+				{ID: 101, ItemKind: DemandKindItemType, ItemID: 20, Quantity: 1},
+			},
+		},
+		Lines: []ConflictLine{
+			{DemandID: 100, Requirement: ComponentRequirement{ItemTypeID: 10, Quantity: 2}},
+			{DemandID: 101, Requirement: ComponentRequirement{ItemTypeID: 20, Quantity: 1}},
+		},
+		Owned:   map[int64]int{10: 2, 20: 1},
+		Buffers: map[int64]TurnaroundBuffer{10: {Pre: time.Hour}},
+		Consumers: []InventoryConsumer{
+			{Kind: ConsumerReservation, ItemTypeID: 10, Quantity: 1, Start: at(-day), End: at(day), ReservationID: &otherID},
+			{Kind: ConsumerDeployed, ItemTypeID: 10, Quantity: 1, End: at(3 * day), AssetID: &assetID},
+			{Kind: ConsumerHold, ItemTypeID: 10, Quantity: 1, Start: at(10 * day), End: at(11 * day), HoldID: &holdID},
+		},
+		SearchFrom:  start,
+		SearchUntil: start.Add(30 * day),
+	}
+
+	out := ExplainConflicts(in)
+	assert.False(t, out.Fits)
+	require.Len(t, out.Demands, 2)
+
+	cameras := out.Demands[0].ItemTypes[0]
+	assert.Equal(t, 0, cameras.Available)
+	assert.Equal(t, 2, cameras.Short)
+	require.Len(t, cameras.Consumers, 2, "the hold after the window is not a conflict")
+	assert.Equal(t, ConsumerReservation, cameras.Consumers[0].Kind)
+	assert.Equal(t, ConsumerDeployed, cameras.Consumers[1].Kind)
+
+	tripods := out.Demands[1].ItemTypes[0]
+	assert.Equal(t, 1, tripods.Available)
+	assert.Equal(t, 0, tripods.Short)
+	assert.Empty(t, tripods.Consumers)
+
+	// Both cameras are back once the deployed one returns, plus the prep buffer
+	require.NotNil(t, out.EarliestFit)
+	assert.Equal(t, start.Add(3*day+time.Hour), out.EarliestFit.Start)
+	assert.Equal(t, start.Add(5*day+time.Hour), out.EarliestFit.End)
+
+	in.SearchUntil = start.Add(2 * day)
+	assert.Nil(t, ExplainConflicts(in).EarliestFit)
+
+	// Without the competing usage the requested window itself fits
+	in.Consumers = nil
+	out = ExplainConflicts(in)
+	assert.True(t, out.Fits)
+	assert.Equal(t, &TimeWindow{Start: start, End: start.Add(2 * day)}, out.EarliestFit)
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrContainer is returned when assets or containers cannot be packed, nested or
+// moved as asked.
+var ErrContainer = errors.New("container error")
+
+// ContainerKind is the sort of thing a container is.
+type ContainerKind string
+
+const (
+	ContainerCase   ContainerKind = "case"
+	ContainerPallet ContainerKind = "pallet"
+	ContainerCrate  ContainerKind = "crate"
+)
+
+// Container is a road case, pallet or crate. It holds assets and other containers,
+// and wherever it goes, its contents go too.
+type Container struct {
+	ID                int64         `json:"id"`
+	Tag               string        `json:"tag"` // Barcode or RFID on the container
+	Name              string        `json:"name"`
+	Kind              ContainerKind `json:"kind"`
+	ParentContainerID *int64        `json:"parentContainerId,omitempty"`
+	PlaceID           *int64        `json:"placeId,omitempty"`
+	CreatedAt         time.Time     `json:"createdAt"`
+	UpdatedAt         time.Time     `json:"updatedAt"`
+
+	Assets     []ContainerAsset `json:"assets,omitempty"`
+	Containers []Container      `json:"containers,omitempty"` // Nested containers, with their own contents
+}
+
+// Validate checks a container before it is saved.
+func (c *Container) Validate() error {
+	c.Tag = strings.TrimSpace(c.Tag)
+	if c.Tag == "" || c.Name == "" {
+		return errors.New("tag and name are required")
+	}
+	switch c.Kind {
+	case ContainerCase, ContainerPallet, ContainerCrate:
+		return nil
+	}
+	return fmt.Errorf("kind must be %s, %s or %s", ContainerCase, ContainerPallet, ContainerCrate)
+}
+
+// AssetCount counts the assets in the container and everything nested in it.
+func (c *Container) AssetCount() int {
+	n := len(c.Assets)
+	for i := range c.Containers {
+		n += c.Containers[i].AssetCount()
+	}
+	return n
+}
+
+// ContainerAsset is an asset inside a container, or on a shipment's manifest.
+type ContainerAsset struct {
+	AssetID      int64      `json:"assetId"`
+	AssetTag     string     `json:"assetTag,omitempty"`
+	SerialNumber string     `json:"serialNumber,omitempty"`
+	ItemTypeID   int64      `json:"itemTypeId"`
+	ItemTypeName string     `json:"itemTypeName,omitempty"`
+	ContainerID  *int64     `json:"containerId,omitempty"`
+	PackedAt     *time.Time `json:"packedAt,omitempty"` // Manifests only: when it was scanned onto the shipment
+}
+
+// BuildContainerTree nests containers and assets by parent. Containers whose parent is
+// not among them are roots; assets outside every container are returned as loose.
+func BuildContainerTree(containers []Container, assets []ContainerAsset) ([]Container, []ContainerAsset) {
+	byID := make(map[int64]bool, len(containers))
+	for _, c := range containers {
+		byID[c.ID] = true
+	}
+
+	var loose []ContainerAsset
+	assetsIn := make(map[int64][]ContainerAsset)
+	for _, a := range assets {
+		if a.ContainerID == nil || !byID[*a.ContainerID] {
+			loose = append(loose, a)
+			continue
+		}
+		assetsIn[*a.ContainerID] = append(assetsIn[*a.ContainerID], a)
+	}
+	childrenOf := make(map[int64][]Container)
+	var roots []Container
+	for _, c := range containers {
+		if c.ParentContainerID == nil || !byID[*c.ParentContainerID] {
+			roots = append(roots, c)
+			continue
+		}
+		childrenOf[*c.ParentContainerID] = append(childrenOf[*c.ParentContainerID], c)
+	}
+
+	var fill func(c *Container)
+	fill = func(c *Container) {
+		c.Assets = assetsIn[c.ID]
+		c.Containers = childrenOf[c.ID]
+		sort.Slice(c.Containers, func(i, j int) bool { return c.Containers[i].Tag < c.Containers[j].Tag })
+		for i := range c.Containers {
+			fill(&c.Containers[i])
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Tag < roots[j].Tag })
+	for i := range roots {
+		fill(&roots[i])
+	}
+	return roots, loose
+}
+
+// ContainerContentsRequest puts assets and containers into a container, or takes them out.
+type ContainerContentsRequest struct {
+	AssetIDs     []int64 `json:"assetIds,omitempty"`
+	ContainerIDs []int64 `json:"containerIds,omitempty"`
+}
+
+// ContainerMoveRequest checks a container, and so everything in it, out to a
+// reservation or back in from one.
+type ContainerMoveRequest struct {
+	ReservationID  int64  `json:"reservationId"`
+	FromLocationID *int64 `json:"fromLocationId,omitempty"`
+	ToLocationID   *int64 `json:"toLocationId,omitempty"`
+}
+
+// PackScan is a scan of an asset or container tag while packing a shipment.
+type PackScan struct {
+	Code        string `json:"code"`
+	ContainerID *int64 `json:"containerId,omitempty"` // Container on the shipment to pack into; loose when empty
+}
+
+// Manifest lists what a shipment carries, container by container.
+type Manifest struct {
+	ShipmentID     int64            `json:"shipmentId"`
+	Direction      string           `json:"direction"`
+	Carrier        string           `json:"carrier,omitempty"`
+	TrackingNumber string           `json:"trackingNumber,omitempty"`
+	Status         DeliveryStatus   `json:"deliveryStatus"`
+	GeneratedAt    time.Time        `json:"generatedAt"`
+	Containers     []Container      `json:"containers"`
+	Loose          []ContainerAsset `json:"loose"`    // Packed outside any container
+	Unpacked       []ContainerAsset `json:"unpacked"` // Allocated to the shipment but not scanned yet
+	PackedCount    int              `json:"packedCount"`
+	TotalCount     int              `json:"totalCount"`
+}
+
+// ManifestRow is one asset on a manifest, flattened for spreadsheets.
+type ManifestRow struct {
+	ContainerPath string // e.g. "PAL-1 / CASE-7"; empty for loose and unpacked assets
+	Asset         ContainerAsset
+	Packed        bool
+}
+
+// Rows flattens the manifest, containers first in tag order, then loose and unpacked assets.
+func (m *Manifest) Rows() []ManifestRow {
+	var rows []ManifestRow
+	var walk func(c *Container, path string)
+	walk = func(c *Container, path string) {
+		if path != "" {
+			path += " / "
+		}
+		path += c.Tag
+		for _, a := range c.Assets {
+			rows = append(rows, ManifestRow{ContainerPath: path, Asset: a, Packed: true})
+		}
+		for i := range c.Containers {
+			walk(&c.Containers[i], path)
+		}
+	}
+	for i := range m.Containers {
+		walk(&m.Containers[i], "")
+	}
+	for _, a := range m.Loose {
+		rows = append(rows, ManifestRow{Asset: a, Packed: true})
+	}
+	for _, a := range m.Unpacked {
+		rows = append(rows, ManifestRow{Asset: a})
+	}
+	return rows
+}
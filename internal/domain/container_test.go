@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainer_Validate(t *testing.T) {
+	assert.NoError(t, (&Container{Tag: " CASE-1 ", Name: "Mic case", Kind: ContainerCase}).Validate())
+	assert.Error(t, (&Container{Tag: "CASE-1", Kind: ContainerCase}).Validate())
+	assert.Error(t, (&Container{Tag: "BOX-1", Name: "Box", Kind: "box"}).Validate())
+}
+
+func TestBuildContainerTree(t *testing.T) {
+	pallet, caseA, caseB, elsewhere := int64(1), int64(2), int64(3), int64(99)
+	containers := []Container{
+		{ID: caseB, Tag: "CASE-B", ParentContainerID: &pallet},
+		{ID: pallet, Tag: "PAL-1"},
+		{ID: caseA, Tag: "CASE-A", ParentContainerID: &pallet},
+	}
+	assets := []ContainerAsset{
+		{AssetID: 10, AssetTag: "MIC-1", ContainerID: &caseA},
+		{AssetID: 11, AssetTag: "MIC-2", ContainerID: &caseA},
+		{AssetID: 12, AssetTag: "CAB-1", ContainerID: &pallet},
+		{AssetID: 13, AssetTag: "LITE-1"},
+		{AssetID: 14, AssetTag: "LITE-2", ContainerID: &elsewhere},
+	}
+
+	roots, loose := BuildContainerTree(containers, assets)
+	require.Len(t, roots, 1)
+	assert.Equal(t, "PAL-1", roots[0].Tag)
+	require.Len(t, roots[0].Containers, 2)
+	assert.Equal(t, "CASE-A", roots[0].Containers[0].Tag)
+	assert.Len(t, roots[0].Containers[0].Assets, 2)
+	assert.Len(t, roots[0].Assets, 1)
+	assert.Equal(t, 3, roots[0].AssetCount())
+	assert.Len(t, loose, 2)
+
+	m := &Manifest{Containers: roots, Loose: loose, Unpacked: []ContainerAsset{{AssetID: 15}}}
+	rows := m.Rows()
+	require.Len(t, rows, 6)
+	assert.Equal(t, "PAL-1", rows[0].ContainerPath)
+	assert.Equal(t, "PAL-1 / CASE-A", rows[1].ContainerPath)
+	assert.Equal(t, "", rows[4].ContainerPath)
+	assert.True(t, rows[4].Packed)
+	assert.False(t, rows[5].Packed)
+}
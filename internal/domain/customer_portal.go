@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CustomerTokenPrefix starts every customer portal token, which tells them apart from
+// staff JWTs and makes a leaked one easy to spot.
+const CustomerTokenPrefix = "rms_ct_"
+
+// ErrCustomerScope is returned when a customer token is issued for a person who holds
+// no current role at the company it is scoped to.
+var ErrCustomerScope = errors.New("person has no current role at the company")
+
+// PortalAvailabilityMaxWindow and PortalAvailabilityMaxBuckets bound how far ahead,
+// and how finely, a customer can sample an item type's availability.
+const (
+	PortalAvailabilityMaxWindow  = 180 * 24 * time.Hour
+	PortalAvailabilityMaxBuckets = 400
+)
+
+// ValidatePortalAvailability checks an availability query from the portal stays within
+// the window and bucket limits.
+func ValidatePortalAvailability(start, end time.Time, g AvailabilityGranularity) error {
+	if end.Before(start) {
+		return errors.New("end must not be before start")
+	}
+	if end.Sub(start) > PortalAvailabilityMaxWindow {
+		return fmt.Errorf("window may span at most %d days", int(PortalAvailabilityMaxWindow.Hours()/24))
+	}
+	buckets := 0
+	for d := start; !d.After(end); d = g.Next(d) {
+		if buckets++; buckets > PortalAvailabilityMaxBuckets {
+			return fmt.Errorf("at most %d %s buckets may be requested", PortalAvailabilityMaxBuckets, g)
+		}
+	}
+	return nil
+}
+
+// CustomerToken lets a person at a customer company use the portal API. Everything it
+// can see or create is limited to reservations made under people of that company.
+// Only a hash of the token is stored; the plaintext is returned once, on creation.
+type CustomerToken struct {
+	ID              int64      `json:"id"`
+	CompanyID       int64      `json:"companyId"`
+	PersonID        int64      `json:"personId"`
+	Name            string     `json:"name,omitempty"`
+	Hint            string     `json:"hint"`            // Leading characters of the token, for recognising it
+	Token           string     `json:"token,omitempty"` // Plaintext, only set in the creation response
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt       *time.Time `json:"revokedAt,omitempty"`
+	LastUsedAt      *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedByUserID *int64     `json:"createdByUserId,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// Validate checks a token request before it is issued.
+func (t *CustomerToken) Validate(now time.Time) error {
+	if t.CompanyID == 0 || t.PersonID == 0 {
+		return errors.New("companyId and personId are required")
+	}
+	if t.ExpiresAt != nil && !t.ExpiresAt.After(now) {
+		return errors.New("expiresAt must be in the future")
+	}
+	return nil
+}
+
+// Issue generates the token's secret, setting Token and Hint, and returns the hash to
+// store.
+func (t *CustomerToken) Issue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate customer token: %w", err)
+	}
+	t.Token = CustomerTokenPrefix + hex.EncodeToString(b)
+	t.Hint = t.Token[:len(CustomerTokenPrefix)+6]
+	return HashCustomerToken(t.Token), nil
+}
+
+// HashCustomerToken is how a presented token is looked up.
+func HashCustomerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PrepareDraft turns a reservation submitted through the portal into a draft under the
+// token's person, dropping everything only staff may set.
+func (t *CustomerToken) PrepareDraft(rr *RentalReservation) error {
+	if len(rr.Demands) == 0 {
+		return errors.New("at least one demand is required")
+	}
+	for i, d := range rr.Demands {
+		if d.ItemKind != DemandKindItemType && d.ItemKind != DemandKindKitTemplate {
+			return fmt.Errorf("demand %d: itemKind must be %s or %s", i, DemandKindItemType, DemandKindKitTemplate)
+		}
+		if d.ItemID == 0 || d.Quantity <= 0 {
+			return fmt.Errorf("demand %d: itemId and a positive requestedQuantity are required", i)
+		}
+		rr.Demands[i].ID = 0
+		rr.Demands[i].ReservationID = 0
+		rr.Demands[i].EventID = 0
+	}
+	if !rr.EndTime.After(rr.StartTime) {
+		return errors.New("endTime must be after startTime")
+	}
+
+	personID := t.PersonID
+	*rr = RentalReservation{
+		ReservationName:   rr.ReservationName,
+		ReservationStatus: ReservationStatusDraft,
+		UnderNameID:       &personID,
+		StartTime:         rr.StartTime,
+		EndTime:           rr.EndTime,
+		Metadata:          rr.Metadata,
+		Demands:           rr.Demands,
+	}
+	return nil
+}
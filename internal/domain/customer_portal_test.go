@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerToken_Issue(t *testing.T) {
+	tok := &CustomerToken{CompanyID: 7, PersonID: 42}
+	hash, err := tok.Issue()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(tok.Token, CustomerTokenPrefix))
+	assert.True(t, strings.HasPrefix(tok.Token, tok.Hint))
+	assert.Equal(t, HashCustomerToken(tok.Token), hash)
+	assert.NotContains(t, hash, tok.Token)
+
+	other := &CustomerToken{}
+	otherHash, err := other.Issue()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, otherHash)
+}
+
+func TestCustomerToken_Validate(t *testing.T) {
+	now := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	past, later := now.Add(-time.Hour), now.Add(time.Hour)
+
+	assert.NoError(t, (&CustomerToken{CompanyID: 7, PersonID: 42, ExpiresAt: &later}).Validate(now))
+	assert.Error(t, (&CustomerToken{PersonID: 42}).Validate(now))
+	assert.Error(t, (&CustomerToken{CompanyID: 7, PersonID: 42, ExpiresAt: &past}).Validate(now))
+}
+
+func TestValidatePortalAvailability(t *testing.T) {
+	start := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, ValidatePortalAvailability(start, start.AddDate(0, 0, 90), GranularityDay))
+	assert.NoError(t, ValidatePortalAvailability(start, start.AddDate(0, 0, 14), GranularityHour))
+	assert.Error(t, ValidatePortalAvailability(start, start.AddDate(0, 0, -1), GranularityDay))
+	assert.Error(t, ValidatePortalAvailability(start, start.AddDate(2, 0, 0), GranularityWeek))
+	// Within the window but too many hourly buckets
+	assert.Error(t, ValidatePortalAvailability(start, start.AddDate(0, 0, 30), GranularityHour))
+}
+
+func TestCustomerToken_PrepareDraft(t *testing.T) {
+	tok := &CustomerToken{CompanyID: 7, PersonID: 42}
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	someoneElse, provider, series := int64(9), int64(1), int64(5)
+
+	rr := &RentalReservation{
+		ID:                99,
+		ReservationName:   "Summer show",
+		ReservationStatus: ReservationStatusConfirmed,
+		UnderNameID:       &someoneElse,
+		ProviderID:        &provider,
+		SeriesID:          &series,
+		StartTime:         start,
+		EndTime:           start.Add(24 * time.Hour),
+		Demands:           []Demand{{ID: 3, ReservationID: 99, EventID: 12, ItemKind: DemandKindItemType, ItemID: 10, Quantity: 2}},
+	}
+	require.NoError(t, tok.PrepareDraft(rr))
+	assert.Equal(t, int64(0), rr.ID)
+	assert.Equal(t, "Summer show", rr.ReservationName)
+	assert.Equal(t, ReservationStatusDraft, rr.ReservationStatus)
+	assert.Equal(t, int64(42), *rr.UnderNameID)
+	assert.Nil(t, rr.ProviderID)
+	assert.Nil(t, rr.SeriesID)
+	assert.Equal(t, Demand{ItemKind: DemandKindItemType, ItemID: 10, Quantity: 2}, rr.Demands[0])
+
+	assert.Error(t, tok.PrepareDraft(&RentalReservation{StartTime: start, EndTime: start.Add(time.Hour)}))
+	assert.Error(t, tok.PrepareDraft(&RentalReservation{StartTime: start, EndTime: start,
+		Demands: []Demand{{ItemKind: DemandKindItemType, ItemID: 10, Quantity: 1}}}))
+	assert.Error(t, tok.PrepareDraft(&RentalReservation{StartTime: start, EndTime: start.Add(time.Hour),
+		Demands: []Demand{{ItemKind: DemandKindItemType, ItemID: 10}}}))
+}
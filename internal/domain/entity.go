@@ -78,6 +78,7 @@ type Place struct {
 	Category           *string         `json:"category,omitempty"` // "site", "room", "zone", etc.
 	Address            *PostalAddress  `json:"address,omitempty"`
 	IsInternal         bool            `json:"is_internal"`
+	TransitMinutes     int             `json:"transit_minutes"` // One-way transit from the warehouse
 	PresumedDemands    json.RawMessage `json:"presumed_demands,omitempty"`
 	Metadata           json.RawMessage `json:"metadata,omitempty"`
 	CreatedAt          time.Time       `json:"created_at"`
@@ -8,18 +8,40 @@ import (
 type EventType string
 
 const (
-	EventAssetCreated        EventType = "asset.created"
-	EventAssetUpdated        EventType = "asset.updated"
-	EventAssetTransitioned   EventType = "asset.status_changed"
-	EventRentalSubmitted     EventType = "rental.submitted"
-	EventRentalApproved      EventType = "rental.approved"
-	EventItemTypeCreated     EventType = "item_type.created"
-	EventInspectionSubmitted EventType = "inspection.submitted"
-	EventInspectionSummary   EventType = "inspection.completed"
-	EventAssetPowerAction    EventType = "asset.power_action"
-	EventAssetRecalled       EventType = "asset.recalled"
-	EventAssetCheckOut       EventType = "asset.checked_out"
-	EventAssetReturn         EventType = "asset.returned"
+	EventAssetCreated             EventType = "asset.created"
+	EventAssetUpdated             EventType = "asset.updated"
+	EventAssetTransitioned        EventType = "asset.status_changed"
+	EventRentalSubmitted          EventType = "rental.submitted"
+	EventRentalApproved           EventType = "rental.approved"
+	EventRentalModified           EventType = "rental.modified"
+	EventRentalRejected           EventType = "rental.rejected"
+	EventRentalCancelled          EventType = "rental.cancelled"
+	EventRentalPartiallyFulfilled EventType = "rental.partially_fulfilled"
+	EventRentalFulfilled          EventType = "rental.fulfilled"
+	EventRentalOverdue            EventType = "rental.overdue"
+	EventWaitlistPromoted         EventType = "waitlist.promoted"
+	EventWaitlistAvailable        EventType = "waitlist.available"
+	EventItemTypeCreated          EventType = "item_type.created"
+	EventInspectionSubmitted      EventType = "inspection.submitted"
+	EventInspectionSummary        EventType = "inspection.completed"
+	EventAssetPowerAction         EventType = "asset.power_action"
+	EventAssetRecalled            EventType = "asset.recalled"
+	EventAssetCheckOut            EventType = "asset.checked_out"
+	EventAssetReturn              EventType = "asset.returned"
+	EventAssetTransferred         EventType = "asset.transferred"
+	EventInvoiceCreated           EventType = "invoice.created"
+	EventClaimOpened              EventType = "claim.opened"
+	EventClaimUnderReview         EventType = "claim.under_review"
+	EventClaimSettled             EventType = "claim.settled"
+	EventClaimDenied              EventType = "claim.denied"
+	EventClaimEvidenceAdded       EventType = "claim.evidence_added"
+	EventShipmentShipped          EventType = "shipment.shipped"
+	EventShipmentDelayed          EventType = "shipment.delayed"
+	EventShipmentLost             EventType = "shipment.lost"
+	EventShipmentDelivered        EventType = "shipment.delivered"
+	EventShipmentReturned         EventType = "shipment.returned"
+	EventPickListCompleted        EventType = "pick_list.completed"
+	EventReceivingClosed          EventType = "receiving.closed"
 )
 
 type OutboxStatus string
@@ -19,6 +19,10 @@ type InspectionField struct {
 	Type         FieldType `json:"type"`
 	Required     bool      `json:"required"`
 	DisplayOrder int       `json:"display_order"`
+	// DamageValue is the response that means the asset came back damaged (e.g. "false"
+	// for "Screen intact?"); DamageCharge is billed to the renter when it is given.
+	DamageValue  string `json:"damage_value,omitempty"`
+	DamageCharge int64  `json:"damage_charge,omitempty"`
 }
 
 type InspectionTemplate struct {
@@ -0,0 +1,298 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidInvoice is returned when an invoice cannot be issued for the requested period.
+var ErrInvalidInvoice = errors.New("invalid invoice")
+
+type InvoiceLineKind string
+
+const (
+	InvoiceLineUsage  InvoiceLineKind = "usage"
+	InvoiceLineLate   InvoiceLineKind = "late_return"
+	InvoiceLineDamage InvoiceLineKind = "damage"
+)
+
+// Invoice bills a reservation for what was actually checked out over a period.
+// Consecutive invoices for one reservation cover adjoining periods; the final one ends
+// when the last asset came back. Only serialized assets are billed; fungible stock
+// moved through the stock ledger is not.
+type Invoice struct {
+	ID              int64         `json:"id"`
+	Number          string        `json:"number"` // e.g. INV-000042
+	ReservationID   int64         `json:"reservationId"`
+	CompanyID       *int64        `json:"companyId,omitempty"`
+	Currency        string        `json:"currency,omitempty"`
+	PeriodStart     time.Time     `json:"periodStart"`
+	PeriodEnd       time.Time     `json:"periodEnd"`
+	IsFinal         bool          `json:"isFinal"`
+	Lines           []InvoiceLine `json:"lines"`
+	Total           int64         `json:"total"`
+	CreatedByUserID *int64        `json:"createdByUserId,omitempty"`
+	CreatedAt       time.Time     `json:"createdAt"`
+}
+
+// InvoiceLine is one charge. Usage lines that no rate card covers are kept with a
+// zero amount and a note so they can be priced by hand.
+type InvoiceLine struct {
+	ID                     int64           `json:"id"`
+	Kind                   InvoiceLineKind `json:"kind"`
+	Description            string          `json:"description"`
+	AssetID                int64           `json:"assetId"`
+	ItemTypeID             int64           `json:"itemTypeId"`
+	RateCardID             *int64          `json:"rateCardId,omitempty"`
+	CheckOutID             *int64          `json:"checkOutId,omitempty"`
+	ReturnID               *int64          `json:"returnId,omitempty"`
+	InspectionSubmissionID *int64          `json:"inspectionSubmissionId,omitempty"`
+	Start                  *time.Time      `json:"start,omitempty"`
+	End                    *time.Time      `json:"end,omitempty"`
+	Days                   int             `json:"days,omitempty"`      // Billable days for usage and late lines
+	Breakdown              string          `json:"breakdown,omitempty"` // e.g. "1 week + 2 days"
+	Amount                 int64           `json:"amount"`
+	Note                   string          `json:"note,omitempty"`
+}
+
+// InvoiceRequest asks for the next invoice of a reservation.
+type InvoiceRequest struct {
+	Through *time.Time `json:"through,omitempty"` // End of the period; defaults to now
+	Final   bool       `json:"final"`             // Close out the reservation; every asset must be back
+}
+
+// AssetUsage is one asset's checkout on a reservation and, once it is back, its return.
+type AssetUsage struct {
+	AssetID       int64
+	AssetTag      string
+	ItemTypeID    int64
+	DemandID      *int64 // The demand line the asset went out for, when known
+	KitInstanceID *int64 // Set on the components of a kit checked out as a whole
+	CheckOutID    int64
+	Out           time.Time
+	ReturnID      *int64
+	In            *time.Time
+	BilledDays    int   // Billable days earlier invoices charged for this checkout
+	Billed        int64 // What earlier invoices charged for this checkout's usage
+}
+
+// DamageFinding is an inspection response, given after an asset came back, that
+// matches its field's damage value.
+type DamageFinding struct {
+	SubmissionID int64
+	AssetID      int64
+	ItemTypeID   int64
+	Field        string
+	Charge       int64
+	InspectedAt  time.Time
+}
+
+// InvoiceInput is everything an invoice is built from. Damages should only hold
+// findings no earlier invoice has billed. RateCards holds the cards the quote refers
+// to, active or not, and the active cards for usage the quote does not cover.
+type InvoiceInput struct {
+	ReservationID int64
+	DueBack       time.Time // The reservation's end time
+	CompanyID     *int64
+	PeriodStart   time.Time
+	PeriodEnd     time.Time
+	Final         bool
+	Usage         []AssetUsage
+	Damages       []DamageFinding
+	Quote         *Quote // Stored at approval; nil prices everything at the current cards
+	RateCards     []RateCard
+}
+
+// BuildInvoice prices the usage falling in [PeriodStart, PeriodEnd), the late fees of
+// returns made in the period and the given damage findings. Usage is priced from the
+// checkout through the period end, less what earlier invoices billed for it, so a
+// rental invoiced over several periods costs what one invoice would. Each checkout is
+// priced on the card its demand line was quoted with, and a kit the quote priced as
+// a whole is billed once per kit instance on the kit's card.
+func BuildInvoice(in InvoiceInput) (*Invoice, error) {
+	// A final invoice may be empty of usage, to bill damage found after the last period
+	if in.PeriodEnd.Before(in.PeriodStart) || (!in.Final && in.PeriodEnd.Equal(in.PeriodStart)) {
+		return nil, fmt.Errorf("%w: nothing to bill between %s and %s", ErrInvalidInvoice,
+			in.PeriodStart.Format(time.RFC3339), in.PeriodEnd.Format(time.RFC3339))
+	}
+	if in.Final {
+		out := 0
+		for _, u := range in.Usage {
+			if u.In == nil || u.In.After(in.PeriodEnd) {
+				out++
+			}
+		}
+		if out > 0 {
+			return nil, fmt.Errorf("%w: %d assets are still checked out", ErrInvalidInvoice, out)
+		}
+	}
+
+	inv := &Invoice{
+		ReservationID: in.ReservationID,
+		CompanyID:     in.CompanyID,
+		PeriodStart:   in.PeriodStart,
+		PeriodEnd:     in.PeriodEnd,
+		IsFinal:       in.Final,
+		Lines:         []InvoiceLine{},
+	}
+
+	for _, u := range in.billedUsage() {
+		checkOutID := u.CheckOutID
+		line := InvoiceLine{
+			Kind:        InvoiceLineUsage,
+			Description: fmt.Sprintf("Rental of %s", assetLabel(u)),
+			AssetID:     u.AssetID,
+			ItemTypeID:  u.ItemTypeID,
+			CheckOutID:  &checkOutID,
+		}
+
+		start, end := u.Out, in.PeriodEnd
+		if start.Before(in.PeriodStart) {
+			start = in.PeriodStart
+		}
+		if u.In != nil && u.In.Before(end) {
+			end = *u.In
+		}
+		billsUsage := end.After(start)
+		returned := u.In != nil && u.In.After(in.PeriodStart) && !u.In.After(in.PeriodEnd)
+		if !billsUsage && !returned {
+			continue
+		}
+
+		card := in.rateCard(u)
+		note := ""
+		switch {
+		case card == nil:
+			note = fmt.Sprintf("no rate card for item_type %d", u.ItemTypeID)
+		case inv.Currency != "" && card.Currency != inv.Currency:
+			note = fmt.Sprintf("rate card %d is in %s, invoice is in %s", card.ID, card.Currency, inv.Currency)
+		}
+		if note == "" && inv.Currency == "" {
+			inv.Currency = card.Currency
+		}
+
+		if billsUsage {
+			line.Start, line.End = &start, &end
+			if note != "" {
+				line.Note = note
+			} else {
+				cardID := card.ID
+				line.RateCardID = &cardID
+				line.Days = card.BillableDays(u.Out, end)
+				line.Amount, line.Breakdown = card.Price(line.Days)
+				if line.Amount < card.MinimumCharge {
+					line.Amount = card.MinimumCharge
+					line.Breakdown = "minimum charge"
+				}
+				if u.BilledDays > 0 || u.Billed > 0 {
+					line.Days -= u.BilledDays
+					line.Amount -= u.Billed
+					line.Breakdown = fmt.Sprintf("%s to date, less %s invoiced before", line.Breakdown, plural(u.BilledDays, "day"))
+				}
+			}
+			inv.add(line)
+		}
+
+		if returned && note == "" && card.LateFeePerDay > 0 {
+			grace := time.Duration(card.LateGraceMinutes) * time.Minute
+			if u.In.After(in.DueBack.Add(grace)) {
+				cardID, returnID := card.ID, *u.ReturnID
+				dueBack := in.DueBack
+				late := InvoiceLine{
+					Kind:        InvoiceLineLate,
+					Description: fmt.Sprintf("Late return of %s", assetLabel(u)),
+					AssetID:     u.AssetID,
+					ItemTypeID:  u.ItemTypeID,
+					RateCardID:  &cardID,
+					CheckOutID:  &checkOutID,
+					ReturnID:    &returnID,
+					Start:       &dueBack,
+					End:         u.In,
+					Days:        int((u.In.Sub(in.DueBack) + 24*time.Hour - 1) / (24 * time.Hour)),
+				}
+				late.Amount = int64(late.Days) * card.LateFeePerDay
+				inv.add(late)
+			}
+		}
+	}
+
+	for _, d := range in.Damages {
+		submissionID := d.SubmissionID
+		inv.add(InvoiceLine{
+			Kind:                   InvoiceLineDamage,
+			Description:            fmt.Sprintf("Damage to asset %d: %s", d.AssetID, d.Field),
+			AssetID:                d.AssetID,
+			ItemTypeID:             d.ItemTypeID,
+			InspectionSubmissionID: &submissionID,
+			Amount:                 d.Charge,
+		})
+	}
+	return inv, nil
+}
+
+// billedUsage returns the usage to bill, with the components of each kit instance
+// the quote priced as a whole folded into one usage of the kit's item type. The kit
+// is billed on its first component's checkout, from the first component out until
+// the last one is back.
+func (in InvoiceInput) billedUsage() []AssetUsage {
+	if in.Quote == nil {
+		return in.Usage
+	}
+	usage := make([]AssetUsage, 0, len(in.Usage))
+	kits := make(map[int64]int) // Kit instance to its index in usage
+	for _, u := range in.Usage {
+		if u.KitInstanceID == nil || u.DemandID == nil {
+			usage = append(usage, u)
+			continue
+		}
+		kitLine := in.Quote.kitLine(*u.DemandID)
+		if kitLine == nil {
+			usage = append(usage, u)
+			continue
+		}
+		i, ok := kits[*u.KitInstanceID]
+		if !ok {
+			kits[*u.KitInstanceID] = len(usage)
+			u.ItemTypeID = kitLine.ItemTypeID
+			u.AssetTag = fmt.Sprintf("kit %d", *u.KitInstanceID)
+			usage = append(usage, u)
+			continue
+		}
+		kit := &usage[i]
+		if u.Out.Before(kit.Out) {
+			kit.Out = u.Out
+		}
+		if kit.In != nil && (u.In == nil || u.In.After(*kit.In)) {
+			kit.ReturnID, kit.In = u.ReturnID, u.In
+		}
+	}
+	return usage
+}
+
+// rateCard returns the card a usage is priced with: the one its demand line was
+// quoted with, or the current card for its item type when the quote has none.
+func (in InvoiceInput) rateCard(u AssetUsage) *RateCard {
+	if in.Quote != nil {
+		if id := in.Quote.rateCardID(u.DemandID, u.ItemTypeID); id != 0 {
+			for i := range in.RateCards {
+				if in.RateCards[i].ID == id {
+					return &in.RateCards[i]
+				}
+			}
+		}
+	}
+	return SelectRateCard(in.RateCards, u.ItemTypeID, in.CompanyID)
+}
+
+func (inv *Invoice) add(line InvoiceLine) {
+	inv.Lines = append(inv.Lines, line)
+	inv.Total += line.Amount
+}
+
+func assetLabel(u AssetUsage) string {
+	if u.AssetTag != "" {
+		return u.AssetTag
+	}
+	return fmt.Sprintf("asset %d", u.AssetID)
+}
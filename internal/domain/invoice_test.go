@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInvoice(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	dueBack := start.Add(10 * 24 * time.Hour)
+	returnedLate := dueBack.Add(30 * time.Hour)
+	returnID := int64(50)
+
+	in := InvoiceInput{
+		ReservationID: 7,
+		DueBack:       dueBack,
+		PeriodStart:   start,
+		PeriodEnd:     start.Add(7 * 24 * time.Hour),
+		Usage: []AssetUsage{
+			{AssetID: 1, AssetTag: "CAM-1", ItemTypeID: 10, CheckOutID: 100, Out: start, ReturnID: &returnID, In: &returnedLate},
+			{AssetID: 2, ItemTypeID: 20, CheckOutID: 101, Out: start.Add(6 * 24 * time.Hour)},
+			{AssetID: 3, ItemTypeID: 99, CheckOutID: 102, Out: start},
+		},
+		RateCards: []RateCard{
+			{ID: 1, ItemTypeID: 10, Currency: "USD", DailyRate: 1000, WeeklyRate: 5000, LateFeePerDay: 700, LateGraceMinutes: 60, WeekendRule: WeekendStandard, IsActive: true},
+			{ID: 2, ItemTypeID: 20, Currency: "USD", DailyRate: 200, MinimumCharge: 1500, WeekendRule: WeekendStandard, IsActive: true},
+		},
+	}
+
+	// First week: usage only, the minimum on the asset checked out this period
+	inv, err := BuildInvoice(in)
+	require.NoError(t, err)
+	require.Len(t, inv.Lines, 3)
+	assert.Equal(t, "USD", inv.Currency)
+	assert.Equal(t, int64(5000), inv.Lines[0].Amount)
+	assert.Equal(t, int64(1500), inv.Lines[1].Amount)
+	assert.Equal(t, "minimum charge", inv.Lines[1].Breakdown)
+	assert.Equal(t, int64(0), inv.Lines[2].Amount)
+	assert.NotEmpty(t, inv.Lines[2].Note)
+	assert.Equal(t, int64(6500), inv.Total)
+
+	_, err = BuildInvoice(InvoiceInput{PeriodStart: start, PeriodEnd: start})
+	assert.True(t, errors.Is(err, ErrInvalidInvoice))
+
+	// Final invoice is refused while anything is out
+	in.PeriodStart, in.PeriodEnd, in.Final = in.PeriodEnd, returnedLate, true
+	_, err = BuildInvoice(in)
+	assert.True(t, errors.Is(err, ErrInvalidInvoice))
+
+	// Second period: the rest of the late asset's usage, less the first week already
+	// billed, its late fee and a damage finding
+	in.Final = false
+	in.Usage = in.Usage[:1]
+	in.Usage[0].BilledDays, in.Usage[0].Billed = 7, 5000
+	in.Damages = []DamageFinding{{SubmissionID: 9, AssetID: 1, ItemTypeID: 10, Field: "Lens intact?", Charge: 12000}}
+	inv, err = BuildInvoice(in)
+	require.NoError(t, err)
+	require.Len(t, inv.Lines, 3)
+	assert.Equal(t, 5, inv.Lines[0].Days)
+	assert.Equal(t, int64(5000), inv.Lines[0].Amount, "partial weeks are capped at the weekly rate")
+	assert.Equal(t, InvoiceLineLate, inv.Lines[1].Kind)
+	assert.Equal(t, 2, inv.Lines[1].Days)
+	assert.Equal(t, int64(1400), inv.Lines[1].Amount)
+	assert.Equal(t, InvoiceLineDamage, inv.Lines[2].Kind)
+	assert.Equal(t, int64(5000+1400+12000), inv.Total)
+}
+
+func TestBuildInvoice_SplitPeriodsMatchOneInvoice(t *testing.T) {
+	out := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) // A Monday
+	in := out.Add(9*24*time.Hour + 2*time.Hour)
+	returnID := int64(50)
+	usage := []AssetUsage{{AssetID: 1, ItemTypeID: 10, CheckOutID: 100, Out: out, ReturnID: &returnID, In: &in}}
+	card := RateCard{ID: 1, ItemTypeID: 10, Currency: "USD", DailyRate: 1000, WeeklyRate: 4500, MinimumCharge: 1500, WeekendRule: WeekendFree, IsActive: true}
+
+	whole, err := BuildInvoice(InvoiceInput{DueBack: in, PeriodStart: out, PeriodEnd: in, Final: true, Usage: usage, RateCards: []RateCard{card}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5500), whole.Total, "a week and a day, the weekend free")
+
+	// Cut mid-afternoon on a Thursday, at the start of the weekend and at its end, as
+	// an hourly worker invoicing "through now" would
+	for _, cut := range []time.Time{out.Add(3*24*time.Hour + 6*time.Hour + 30*time.Minute), out.Add(4*24*time.Hour + 15*time.Hour), out.Add(6*24*time.Hour + 15*time.Hour)} {
+		first, err := BuildInvoice(InvoiceInput{DueBack: in, PeriodStart: out, PeriodEnd: cut, Usage: usage, RateCards: []RateCard{card}})
+		require.NoError(t, err)
+		billed := usage[0]
+		billed.BilledDays, billed.Billed = first.Lines[0].Days, first.Lines[0].Amount
+		rest, err := BuildInvoice(InvoiceInput{DueBack: in, PeriodStart: cut, PeriodEnd: in, Final: true, Usage: []AssetUsage{billed}, RateCards: []RateCard{card}})
+		require.NoError(t, err)
+		assert.Equal(t, whole.Total, first.Total+rest.Total, "cut at %s", cut)
+		assert.Equal(t, whole.Lines[0].Days, first.Lines[0].Days+rest.Lines[0].Days, "cut at %s", cut)
+	}
+}
+
+func TestBuildInvoice_PricedFromQuote(t *testing.T) {
+	out := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	back := out.Add(3 * 24 * time.Hour)
+	later := back.Add(2 * time.Hour)
+	returnIDs := []int64{60, 61, 62}
+	kitDemand, cameraDemand := int64(5), int64(6)
+	kitInstance := int64(40)
+
+	// Approved with the kit priced as a whole on card 3 and cameras on card 1
+	quote := &Quote{Lines: []QuoteLine{
+		{DemandID: kitDemand, ItemKind: DemandKindKitTemplate, ItemID: 8, ItemTypeID: 30, RateCardID: 3, Quantity: 1},
+		{DemandID: cameraDemand, ItemKind: DemandKindItemType, ItemID: 10, ItemTypeID: 10, RateCardID: 1, Quantity: 1},
+	}}
+	in := InvoiceInput{
+		DueBack:     later,
+		PeriodStart: out,
+		PeriodEnd:   later,
+		Final:       true,
+		Usage: []AssetUsage{
+			{AssetID: 1, ItemTypeID: 10, DemandID: &kitDemand, KitInstanceID: &kitInstance, CheckOutID: 100, Out: out, ReturnID: &returnIDs[0], In: &back},
+			{AssetID: 2, ItemTypeID: 20, DemandID: &kitDemand, KitInstanceID: &kitInstance, CheckOutID: 101, Out: out, ReturnID: &returnIDs[1], In: &later},
+			{AssetID: 3, ItemTypeID: 10, DemandID: &cameraDemand, CheckOutID: 102, Out: out, ReturnID: &returnIDs[2], In: &back},
+		},
+		Quote: quote,
+		RateCards: []RateCard{
+			// Card 1 was deactivated after approval and card 4 replaced it
+			{ID: 1, ItemTypeID: 10, Currency: "USD", DailyRate: 1000, WeekendRule: WeekendStandard},
+			{ID: 4, ItemTypeID: 10, Currency: "USD", DailyRate: 9000, WeekendRule: WeekendStandard, IsActive: true},
+			{ID: 2, ItemTypeID: 20, Currency: "USD", DailyRate: 500, WeekendRule: WeekendStandard, IsActive: true},
+			{ID: 3, ItemTypeID: 30, Currency: "USD", DailyRate: 2500, WeekendRule: WeekendStandard, IsActive: true},
+		},
+	}
+
+	inv, err := BuildInvoice(in)
+	require.NoError(t, err)
+	require.Len(t, inv.Lines, 2)
+
+	// The kit is one line on the kit's card, out until its last component came back
+	kit := inv.Lines[0]
+	assert.Equal(t, int64(30), kit.ItemTypeID)
+	assert.Equal(t, int64(100), *kit.CheckOutID)
+	require.NotNil(t, kit.RateCardID)
+	assert.Equal(t, int64(3), *kit.RateCardID)
+	assert.Equal(t, 4, kit.Days)
+	assert.Equal(t, int64(4*2500), kit.Amount)
+
+	// The camera keeps the card it was quoted on
+	camera := inv.Lines[1]
+	require.NotNil(t, camera.RateCardID)
+	assert.Equal(t, int64(1), *camera.RateCardID)
+	assert.Equal(t, int64(3*1000), camera.Amount)
+	assert.Equal(t, int64(4*2500+3*1000), inv.Total)
+
+	// Without a quote every asset is priced on its current card
+	in.Quote = nil
+	inv, err = BuildInvoice(in)
+	require.NoError(t, err)
+	require.Len(t, inv.Lines, 3)
+	assert.Equal(t, int64(4), *inv.Lines[0].RateCardID)
+	assert.Equal(t, int64(2), *inv.Lines[1].RateCardID)
+}
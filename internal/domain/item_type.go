@@ -29,6 +29,8 @@ type ItemType struct {
 	Kind              ItemKind          `json:"kind"` // Maps to schema.org/category
 	IsActive          bool              `json:"is_active"`
 	SupportedFeatures LifecycleFeatures `json:"supported_features"`
+	PreBufferMinutes  int               `json:"pre_buffer_minutes"`  // Preparation before a rental starts
+	PostBufferMinutes int               `json:"post_buffer_minutes"` // Inspection and cleaning after a rental ends
 	CreatedByUserID   *int64            `json:"created_by_user_id,omitempty"`
 	UpdatedByUserID   *int64            `json:"updated_by_user_id,omitempty"`
 	SchemaOrg         json.RawMessage   `json:"schema_org,omitempty" swaggertype:"string" example:"{}"`
@@ -36,3 +38,11 @@ type ItemType struct {
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 }
+
+// Buffer returns the turnaround the item type needs around each rental.
+func (it *ItemType) Buffer() TurnaroundBuffer {
+	return TurnaroundBuffer{
+		Pre:  time.Duration(it.PreBufferMinutes) * time.Minute,
+		Post: time.Duration(it.PostBufferMinutes) * time.Minute,
+	}
+}
@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrKitMismatch is returned when a set of assets cannot be checked out as a kit.
+var ErrKitMismatch = errors.New("assets do not match kit")
+
+// KitTemplate defines what a rentable kit is made of. It may be linked to an
+// ItemType of kind "kit" so the kit shows up in the catalog.
+type KitTemplate struct {
+	ID          int64           `json:"id"`
+	ItemTypeID  *int64          `json:"item_type_id,omitempty"`
+	Code        string          `json:"code"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	IsActive    bool            `json:"is_active"`
+	Components  []KitComponent  `json:"components"`
+	Metadata    json.RawMessage `json:"metadata,omitempty" swaggertype:"string" example:"{}"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// KitComponent is one line of a kit: a quantity of an item type, optionally
+// satisfiable by other item types when the primary one is short.
+type KitComponent struct {
+	ID                    int64   `json:"id"`
+	KitTemplateID         int64   `json:"kit_template_id"`
+	ItemTypeID            int64   `json:"item_type_id"`
+	Quantity              int     `json:"quantity"`
+	IsOptional            bool    `json:"is_optional"`
+	SubstituteItemTypeIDs []int64 `json:"substitute_item_type_ids,omitempty"`
+}
+
+// KitInstance records one physical build of a kit that was checked out against
+// a kit_template demand, and which assets made it up.
+type KitInstance struct {
+	ID            int64      `json:"id"`
+	ReservationID int64      `json:"reservationId"`
+	DemandID      int64      `json:"demandId"`
+	KitTemplateID int64      `json:"kitTemplateId"`
+	AssetIDs      []int64    `json:"assetIds"`
+	CheckedOutAt  time.Time  `json:"checkedOutAt"`
+	ReturnedAt    *time.Time `json:"returnedAt,omitempty"`
+}
+
+// ComponentRequirement is the number of units of one item type needed to
+// satisfy a demand, with the item types allowed to stand in for it. SubstituteMax
+// caps how many units a substitute may cover; substitutes not in it are unlimited.
+type ComponentRequirement struct {
+	ItemTypeID    int64         `json:"item_type_id"`
+	Quantity      int           `json:"quantity"`
+	Substitutes   []int64       `json:"substitutes,omitempty"`
+	SubstituteMax map[int64]int `json:"substitute_max,omitempty"`
+}
+
+// Shortfall describes a requirement that could not be covered.
+type Shortfall struct {
+	ItemTypeID int64 `json:"item_type_id"`
+	Requested  int   `json:"requested"`
+	Available  int   `json:"available"`
+}
+
+// Requirements expands qty kits into per-component requirements. Optional
+// components are only included when includeOptional is set.
+func (k *KitTemplate) Requirements(qty int, includeOptional bool) []ComponentRequirement {
+	var reqs []ComponentRequirement
+	for _, c := range k.Components {
+		if c.IsOptional && !includeOptional {
+			continue
+		}
+		reqs = append(reqs, ComponentRequirement{
+			ItemTypeID:  c.ItemTypeID,
+			Quantity:    c.Quantity * qty,
+			Substitutes: c.SubstituteItemTypeIDs,
+		})
+	}
+	return reqs
+}
+
+// ItemTypeIDs returns every item type a kit can draw on, including substitutes.
+func (k *KitTemplate) ItemTypeIDs() []int64 {
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, c := range k.Components {
+		for _, id := range append([]int64{c.ItemTypeID}, c.SubstituteItemTypeIDs...) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// AllocateRequirements checks whether the requirements fit into the given
+// per-item-type availability. Each requirement draws on its primary item type
+// first and then on its substitutes in order, up to any per-substitute cap. It
+// returns the units drawn per item type and any requirements that could not be
+// covered.
+func AllocateRequirements(reqs []ComponentRequirement, avail map[int64]int) (map[int64]int, []Shortfall) {
+	drawn, missing := allocateRequirements(reqs, avail)
+	var shortfalls []Shortfall
+	for i, req := range reqs {
+		if missing[i] > 0 {
+			shortfalls = append(shortfalls, Shortfall{
+				ItemTypeID: req.ItemTypeID,
+				Requested:  req.Quantity,
+				Available:  req.Quantity - missing[i],
+			})
+		}
+	}
+	return drawn, shortfalls
+}
+
+// allocateRequirements does the work of AllocateRequirements, returning the units
+// each requirement is missing in requirement order.
+func allocateRequirements(reqs []ComponentRequirement, avail map[int64]int) (map[int64]int, []int) {
+	remaining := make(map[int64]int, len(avail))
+	for id, n := range avail {
+		remaining[id] = n
+	}
+	drawn := make(map[int64]int)
+
+	take := func(id int64, want int) int {
+		n := remaining[id]
+		if n > want {
+			n = want
+		}
+		if n < 0 {
+			n = 0
+		}
+		remaining[id] -= n
+		drawn[id] += n
+		return n
+	}
+
+	// Primaries first so a substitute is never consumed by one line while
+	// another line still needs it as its own primary.
+	missing := make([]int, len(reqs))
+	for i, req := range reqs {
+		missing[i] = req.Quantity - take(req.ItemTypeID, req.Quantity)
+	}
+	for i, req := range reqs {
+		for _, sub := range req.Substitutes {
+			if missing[i] == 0 {
+				break
+			}
+			want := missing[i]
+			if limit, ok := req.SubstituteMax[sub]; ok && limit < want {
+				want = limit
+			}
+			missing[i] -= take(sub, want)
+		}
+	}
+	return drawn, missing
+}
+
+// Buildable returns how many complete kits can be assembled from the given
+// per-item-type availability, counting required components only.
+func (k *KitTemplate) Buildable(avail map[int64]int) int {
+	if len(k.Requirements(1, false)) == 0 {
+		return 0
+	}
+	// Upper bound: no kit can be built beyond the whole pool of its item types.
+	hi := 0
+	for _, id := range k.ItemTypeIDs() {
+		if avail[id] > 0 {
+			hi += avail[id]
+		}
+	}
+	lo := 0
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if _, short := AllocateRequirements(k.Requirements(mid, false), avail); len(short) == 0 {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// MatchAssets checks that a set of assets (given by their item type IDs) forms
+// exactly one instance of the kit. Substitutes may stand in for a component,
+// optional components may be present or absent, and nothing else is allowed.
+func (k *KitTemplate) MatchAssets(assetItemTypes []int64) error {
+	pool := make(map[int64]int)
+	for _, id := range assetItemTypes {
+		pool[id]++
+	}
+
+	need := func(optional bool) error {
+		for _, c := range k.Components {
+			if c.IsOptional != optional {
+				continue
+			}
+			missing := c.Quantity
+			for _, id := range append([]int64{c.ItemTypeID}, c.SubstituteItemTypeIDs...) {
+				n := pool[id]
+				if n > missing {
+					n = missing
+				}
+				pool[id] -= n
+				missing -= n
+			}
+			if missing > 0 && !optional {
+				return fmt.Errorf("%w: kit %s is missing %d of item_type %d", ErrKitMismatch, k.Code, missing, c.ItemTypeID)
+			}
+		}
+		return nil
+	}
+	if err := need(false); err != nil {
+		return err
+	}
+	need(true)
+
+	for id, n := range pool {
+		if n > 0 {
+			return fmt.Errorf("%w: item_type %d is not part of kit %s", ErrKitMismatch, id, k.Code)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stageKit() *KitTemplate {
+	return &KitTemplate{
+		Code: "STAGE-KIT",
+		Components: []KitComponent{
+			{ItemTypeID: 1, Quantity: 2},                                    // speakers
+			{ItemTypeID: 2, Quantity: 1, SubstituteItemTypeIDs: []int64{3}}, // mixer, or the older mixer
+			{ItemTypeID: 4, Quantity: 4, IsOptional: true},                  // cables
+		},
+	}
+}
+
+func TestKitTemplate_Buildable(t *testing.T) {
+	kit := stageKit()
+
+	// Speakers allow 3 kits, mixers only 1 + 1 substitute
+	assert.Equal(t, 2, kit.Buildable(map[int64]int{1: 6, 2: 1, 3: 1}))
+	// Optional cables never limit the count
+	assert.Equal(t, 3, kit.Buildable(map[int64]int{1: 6, 2: 5, 4: 0}))
+	assert.Equal(t, 0, kit.Buildable(map[int64]int{1: 1, 2: 5}))
+}
+
+func TestAllocateRequirements_Substitutes(t *testing.T) {
+	reqs := []ComponentRequirement{
+		{ItemTypeID: 2, Quantity: 3, Substitutes: []int64{3}},
+		{ItemTypeID: 3, Quantity: 1},
+	}
+
+	// Item type 3 is first reserved as its own primary, so only one unit is left to substitute.
+	drawn, short := AllocateRequirements(reqs, map[int64]int{2: 1, 3: 2})
+	assert.Equal(t, map[int64]int{2: 1, 3: 2}, drawn)
+	assert.Equal(t, []Shortfall{{ItemTypeID: 2, Requested: 3, Available: 2}}, short)
+
+	_, short = AllocateRequirements(reqs, map[int64]int{2: 1, 3: 3})
+	assert.Empty(t, short)
+
+	// A capped substitute covers at most its cap, the next one takes the rest.
+	capped := []ComponentRequirement{{ItemTypeID: 2, Quantity: 4, Substitutes: []int64{3, 5}, SubstituteMax: map[int64]int{3: 1}}}
+	drawn, short = AllocateRequirements(capped, map[int64]int{2: 1, 3: 5, 5: 1})
+	assert.Equal(t, map[int64]int{2: 1, 3: 1, 5: 1}, drawn)
+	assert.Equal(t, []Shortfall{{ItemTypeID: 2, Requested: 4, Available: 3}}, short)
+}
+
+func TestKitTemplate_MatchAssets(t *testing.T) {
+	kit := stageKit()
+
+	assert.NoError(t, kit.MatchAssets([]int64{1, 1, 2}))
+	assert.NoError(t, kit.MatchAssets([]int64{1, 1, 3, 4, 4}))
+
+	err := kit.MatchAssets([]int64{1, 2})
+	assert.True(t, errors.Is(err, ErrKitMismatch))
+
+	err = kit.MatchAssets([]int64{1, 1, 2, 9})
+	assert.True(t, errors.Is(err, ErrKitMismatch))
+}
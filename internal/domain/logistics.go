@@ -9,6 +9,7 @@ import (
 type RentalReservationStatus string
 
 const (
+	ReservationStatusDraft              RentalReservationStatus = "ReservationDraft" // Not yet submitted for approval
 	ReservationStatusPending            RentalReservationStatus = "ReservationPending"
 	ReservationStatusConfirmed          RentalReservationStatus = "ReservationConfirmed"
 	ReservationStatusCancelled          RentalReservationStatus = "ReservationCancelled"
@@ -22,6 +23,8 @@ type DeliveryStatus string
 const (
 	DeliveryPreparing DeliveryStatus = "DeliveryPreparing"
 	DeliveryShipped   DeliveryStatus = "DeliveryShipped"
+	DeliveryDelayed   DeliveryStatus = "DeliveryDelayed" // Under way but held up, e.g. a carrier exception
+	DeliveryLost      DeliveryStatus = "DeliveryLost"
 	DeliveryDelivered DeliveryStatus = "DeliveryDelivered"
 	DeliveryReturned  DeliveryStatus = "DeliveryReturned"
 )
@@ -36,13 +39,28 @@ type RentalReservation struct {
 	StartTime         time.Time               `json:"startTime"`                 // Expected start
 	EndTime           time.Time               `json:"endTime"`                   // Expected end
 	ProviderID        *int64                  `json:"providerId,omitempty"`      // Reference to Organization/Company
+	SeriesID          *int64                  `json:"seriesId,omitempty"`        // Set for occurrences of a ReservationSeries
+	RecurrenceID      *time.Time              `json:"recurrenceId,omitempty"`    // Original start of the occurrence in its series
 	Metadata          json.RawMessage         `json:"metadata,omitempty"`
+	SubmittedAt       *time.Time              `json:"submittedAt,omitempty"`
+	ApprovedAt        *time.Time              `json:"approvedAt,omitempty"`
+	RejectedAt        *time.Time              `json:"rejectedAt,omitempty"`
+	CancelledAt       *time.Time              `json:"cancelledAt,omitempty"`
+	FulfilledAt       *time.Time              `json:"fulfilledAt,omitempty"`
+	OverdueAt         *time.Time              `json:"overdueAt,omitempty"`       // Set while assets are out past EndTime
+	UpdatedByUserID   *int64                  `json:"updatedByUserId,omitempty"` // Who made the last status transition
 	CreatedAt         time.Time               `json:"createdAt"`
 	UpdatedAt         time.Time               `json:"updatedAt"`
 
 	Demands []Demand `json:"demands,omitempty"`
 }
 
+// Demand item kinds.
+const (
+	DemandKindItemType    = "item_type"
+	DemandKindKitTemplate = "kit_template"
+)
+
 // Demand tracks the requirement for a specific type of asset, aligning with schema.org/Demand.
 type Demand struct {
 	ID               int64           `json:"id"`
@@ -68,6 +86,7 @@ type CheckOutAction struct {
 	RecipientID         *int64          `json:"recipientId"`         // Person receiving the asset
 	ShipmentID          *int64          `json:"shipmentId"`          // Link to Shipment
 	ScheduledDeliveryID *int64          `json:"scheduledDeliveryId"` // Link to ScheduledDelivery (if no shipment)
+	KitInstanceID       *int64          `json:"kitInstanceId"`       // Set when the asset went out as part of a kit
 	StartTime           time.Time       `json:"startTime"`           // When the checkout happened
 	FromLocation        *int64          `json:"fromLocationId"`      // PlaceID (Warehouse)
 	ToLocation          *int64          `json:"toLocationId"`        // PlaceID (Event Location)
@@ -98,6 +117,9 @@ type FulfillmentLine struct {
 	FulfilledQuantity int    `json:"fulfilledQuantity"`
 	ReturnedQuantity  int    `json:"returnedQuantity"`
 	RemainingQuantity int    `json:"remainingQuantity"`
+	// SubstitutedQuantity is the part of FulfilledQuantity checked out as a substitute item type
+	SubstitutedQuantity int            `json:"substitutedQuantity,omitempty"`
+	Substitutions       []Substitution `json:"substitutions,omitempty"`
 }
 
 // RentalFulfillmentStatus summarizes the fulfillment state of a reservation.
@@ -109,12 +131,15 @@ type RentalFulfillmentStatus struct {
 
 // ScheduledDelivery represents a planned delivery for a specific Event/SeasonPlan (schema.org/DeliveryEvent).
 type ScheduledDelivery struct {
-	ID         int64     `json:"id"`
-	EventID    int64     `json:"eventId"` // Replacing seasonPlanId for generic use
-	TargetDate time.Time `json:"availableFrom"`
-	Notes      string    `json:"notes,omitempty"`
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	ID                 int64      `json:"id"`
+	EventID            int64      `json:"eventId"` // Replacing seasonPlanId for generic use
+	TargetDate         time.Time  `json:"availableFrom"`
+	DestinationPlaceID *int64     `json:"destinationPlaceId,omitempty"` // Drop-off Place; defaults to the reservation's demand location
+	WindowStart        *time.Time `json:"windowStart,omitempty"`        // Earliest the customer can receive it
+	WindowEnd          *time.Time `json:"windowEnd,omitempty"`          // Latest the customer can receive it
+	Notes              string     `json:"notes,omitempty"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
 }
 
 // ScheduledDeliveryItem represents the required items for a scheduled delivery (schema.org/Demand).
@@ -139,4 +164,8 @@ type Shipment struct {
 	Direction           string         `json:"direction"` // 'outbound' or 'inbound'
 	CreatedAt           time.Time      `json:"createdAt"`
 	UpdatedAt           time.Time      `json:"updatedAt"`
+
+	Legs           []ShipmentLeg  `json:"legs,omitempty"`           // Multi-leg route, in order
+	ReservationIDs []int64        `json:"reservationIds,omitempty"` // Reservations with check-outs on board
+	Label          *ShippingLabel `json:"label,omitempty"`          // Active carrier label, if one was bought
 }
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOverdueEscalations are the delays past a reservation's end time at which a
+// still-outstanding asset is escalated again.
+var DefaultOverdueEscalations = []time.Duration{time.Hour, 24 * time.Hour, 72 * time.Hour}
+
+// OverdueAsset is a completed check-out with no return whose reservation has ended.
+type OverdueAsset struct {
+	ReservationID   int64      `json:"reservationId"`
+	ReservationName string     `json:"reservationName,omitempty"`
+	UnderNameID     *int64     `json:"underNameId,omitempty"`
+	AssetID         int64      `json:"assetId"`
+	AssetTag        string     `json:"assetTag,omitempty"`
+	CheckOutID      int64      `json:"checkOutId"`
+	CheckedOutAt    time.Time  `json:"checkedOutAt"`
+	DueAt           time.Time  `json:"dueAt"` // The reservation's end time
+	OverdueMinutes  int64      `json:"overdueMinutes"`
+	EscalationLevel int        `json:"escalationLevel"` // Escalation steps already announced
+	LastEscalatedAt *time.Time `json:"lastEscalatedAt,omitempty"`
+}
+
+// OverdueReport lists everything out past its reservation's end time.
+type OverdueReport struct {
+	GeneratedAt  time.Time      `json:"generatedAt"`
+	Reservations int            `json:"reservations"` // Distinct reservations with overdue assets
+	Assets       []OverdueAsset `json:"assets"`
+}
+
+// NewOverdueReport summarizes overdue assets as of now.
+func NewOverdueReport(assets []OverdueAsset, now time.Time) *OverdueReport {
+	seen := make(map[int64]bool)
+	for _, a := range assets {
+		seen[a.ReservationID] = true
+	}
+	return &OverdueReport{GeneratedAt: now, Reservations: len(seen), Assets: assets}
+}
+
+// EscalationLevel returns how many of steps have elapsed between due and now.
+func EscalationLevel(steps []time.Duration, due, now time.Time) int {
+	late := now.Sub(due)
+	level := 0
+	for _, step := range steps {
+		if late >= step {
+			level++
+		}
+	}
+	return level
+}
+
+// ParseEscalationSteps parses a comma separated list of delays such as "1h,1d,3d".
+// Besides the units time.ParseDuration knows, a "d" suffix counts whole days. The
+// steps are returned in ascending order.
+func ParseEscalationSteps(s string) ([]time.Duration, error) {
+	var steps []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var d time.Duration
+		if days, ok := strings.CutSuffix(part, "d"); ok {
+			n, err := strconv.Atoi(days)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escalation step %q", part)
+			}
+			d = time.Duration(n) * 24 * time.Hour
+		} else {
+			var err error
+			if d, err = time.ParseDuration(part); err != nil {
+				return nil, fmt.Errorf("invalid escalation step %q", part)
+			}
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("escalation step %q must be positive", part)
+		}
+		steps = append(steps, d)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no escalation steps in %q", s)
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i] < steps[j] })
+	return steps, nil
+}
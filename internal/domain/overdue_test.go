@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscalationLevel(t *testing.T) {
+	due := time.Date(2026, 5, 1, 17, 0, 0, 0, time.UTC)
+	steps := DefaultOverdueEscalations
+
+	assert.Equal(t, 0, EscalationLevel(steps, due, due.Add(59*time.Minute)))
+	assert.Equal(t, 1, EscalationLevel(steps, due, due.Add(time.Hour)))
+	assert.Equal(t, 2, EscalationLevel(steps, due, due.Add(30*time.Hour)))
+	assert.Equal(t, 3, EscalationLevel(steps, due, due.Add(10*24*time.Hour)))
+}
+
+func TestParseEscalationSteps(t *testing.T) {
+	steps, err := ParseEscalationSteps("3d, 1h,1d")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Hour, 24 * time.Hour, 72 * time.Hour}, steps)
+
+	steps, err = ParseEscalationSteps("30m,90m")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{30 * time.Minute, 90 * time.Minute}, steps)
+
+	for _, s := range []string{"", " , ", "1w", "xd", "-1h", "0d"} {
+		_, err := ParseEscalationSteps(s)
+		assert.Error(t, err, s)
+	}
+}
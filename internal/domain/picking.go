@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrPickList is returned when a pick list operation does not fit the list's state, or a
+// scanned asset does not belong on it.
+var ErrPickList = errors.New("pick list error")
+
+// PickListStatus tracks a pick list from generation to hand-off.
+type PickListStatus string
+
+const (
+	PickListOpen      PickListStatus = "Open"
+	PickListCompleted PickListStatus = "Completed"
+	PickListCancelled PickListStatus = "Cancelled"
+)
+
+// PickList tells warehouse staff which assets to pull for a scheduled delivery, and
+// records what they have scanned.
+type PickList struct {
+	ID                  int64          `json:"id"`
+	ScheduledDeliveryID int64          `json:"scheduledDeliveryId"`
+	ShipmentID          *int64         `json:"shipmentId,omitempty"`    // Shipment the picks are allocated to on completion
+	SourcePlaceID       *int64         `json:"sourcePlaceId,omitempty"` // Warehouse to pick from; anywhere when empty
+	Status              PickListStatus `json:"status"`
+	CreatedByUserID     *int64         `json:"createdByUserId,omitempty"`
+	CreatedAt           time.Time      `json:"createdAt"`
+	CompletedAt         *time.Time     `json:"completedAt,omitempty"`
+
+	Lines []PickLine `json:"lines,omitempty"`
+	Stops []PickStop `json:"stops,omitempty"` // The lines' tasks grouped by storage place, in walking order
+}
+
+// PickLine is one item type a delivery item needs. Kit items expand to a line per
+// component type.
+type PickLine struct {
+	ID             int64      `json:"id"`
+	PickListID     int64      `json:"pickListId"`
+	DeliveryItemID int64      `json:"deliveryItemId"`
+	ItemTypeID     int64      `json:"itemTypeId"`
+	Requested      int        `json:"requestedQuantity"`
+	Picked         int        `json:"pickedQuantity"`
+	Short          int        `json:"shortQuantity"` // Requested assets that are neither picked nor assigned
+	Tasks          []PickTask `json:"tasks,omitempty"`
+}
+
+// PickTask is one asset to pull from a storage place.
+type PickTask struct {
+	ID             int64      `json:"id"`
+	LineID         int64      `json:"lineId"`
+	AssetID        int64      `json:"assetId"`
+	AssetTag       string     `json:"assetTag,omitempty"`
+	PlaceID        *int64     `json:"placeId,omitempty"`
+	PlacePath      string     `json:"placePath,omitempty"` // e.g. "Warehouse / Aisle 3 / Shelf B"
+	PickedAt       *time.Time `json:"pickedAt,omitempty"`
+	PickedByUserID *int64     `json:"pickedByUserId,omitempty"`
+}
+
+// PickStop is a storage place and the tasks to do there.
+type PickStop struct {
+	PlaceID   *int64     `json:"placeId,omitempty"`
+	PlacePath string     `json:"placePath"`
+	Tasks     []PickTask `json:"tasks"`
+}
+
+// PickListRequest generates a pick list for a scheduled delivery.
+type PickListRequest struct {
+	SourcePlaceID *int64 `json:"sourcePlaceId,omitempty"`
+	ShipmentID    *int64 `json:"shipmentId,omitempty"`
+}
+
+// PickScan is a barcode or RFID read of an asset tag or serial number.
+type PickScan struct {
+	Code string `json:"code"`
+}
+
+// CompletePickRequest hands a pick list's scanned assets to a shipment.
+type CompletePickRequest struct {
+	ShipmentID *int64 `json:"shipmentId,omitempty"` // Overrides the list's shipment
+}
+
+// Tally counts picks and shorts on every line from its tasks. While the list is open
+// a line is short by the assets that could not be assigned to it; once completed, by
+// those that were not picked.
+func (pl *PickList) Tally() {
+	for i := range pl.Lines {
+		line := &pl.Lines[i]
+		line.Picked = 0
+		for _, t := range line.Tasks {
+			if t.PickedAt != nil {
+				line.Picked++
+			}
+		}
+		covered := len(line.Tasks)
+		if pl.Status == PickListCompleted {
+			covered = line.Picked
+		}
+		line.Short = line.Requested - covered
+		if line.Short < 0 {
+			line.Short = 0
+		}
+	}
+}
+
+// Shorts returns the lines that cannot be filled in full.
+func (pl *PickList) Shorts() []PickLine {
+	var shorts []PickLine
+	for _, line := range pl.Lines {
+		if line.Short > 0 {
+			shorts = append(shorts, line)
+		}
+	}
+	return shorts
+}
+
+// GroupStops groups the list's tasks by storage place, ordered by place path so that
+// neighbouring shelves follow each other. Unplaced assets come last.
+func (pl *PickList) GroupStops() {
+	byPlace := make(map[int64]int)
+	var stops []PickStop
+	unplaced := PickStop{PlacePath: "Unknown location"}
+	for _, line := range pl.Lines {
+		for _, t := range line.Tasks {
+			if t.PlaceID == nil {
+				unplaced.Tasks = append(unplaced.Tasks, t)
+				continue
+			}
+			i, ok := byPlace[*t.PlaceID]
+			if !ok {
+				i = len(stops)
+				byPlace[*t.PlaceID] = i
+				stops = append(stops, PickStop{PlaceID: t.PlaceID, PlacePath: t.PlacePath})
+			}
+			stops[i].Tasks = append(stops[i].Tasks, t)
+		}
+	}
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].PlacePath < stops[j].PlacePath })
+	for _, stop := range stops {
+		sort.SliceStable(stop.Tasks, func(i, j int) bool { return stop.Tasks[i].AssetTag < stop.Tasks[j].AssetTag })
+	}
+	if len(unplaced.Tasks) > 0 {
+		stops = append(stops, unplaced)
+	}
+	pl.Stops = stops
+}
+
+// PickedAssetIDs returns the assets scanned so far.
+func (pl *PickList) PickedAssetIDs() []int64 {
+	var ids []int64
+	for _, line := range pl.Lines {
+		for _, t := range line.Tasks {
+			if t.PickedAt != nil {
+				ids = append(ids, t.AssetID)
+			}
+		}
+	}
+	return ids
+}
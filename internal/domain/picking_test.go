@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickList_Tally(t *testing.T) {
+	now := time.Now()
+	pl := &PickList{
+		Status: PickListOpen,
+		Lines: []PickLine{
+			{ID: 1, Requested: 3, Tasks: []PickTask{{AssetID: 10, PickedAt: &now}, {AssetID: 11}}},
+			{ID: 2, Requested: 1, Tasks: []PickTask{{AssetID: 20, PickedAt: &now}}},
+		},
+	}
+	pl.Tally()
+	assert.Equal(t, 1, pl.Lines[0].Picked)
+	assert.Equal(t, 1, pl.Lines[0].Short) // Only two of three could be assigned
+	assert.Equal(t, 0, pl.Lines[1].Short)
+	assert.Len(t, pl.Shorts(), 1)
+	assert.Equal(t, []int64{10, 20}, pl.PickedAssetIDs())
+
+	// Once completed, the unpicked assignment counts as short too
+	pl.Status = PickListCompleted
+	pl.Tally()
+	assert.Equal(t, 2, pl.Lines[0].Short)
+}
+
+func TestPickList_GroupStops(t *testing.T) {
+	shelfA, shelfB := int64(5), int64(6)
+	pl := &PickList{
+		Lines: []PickLine{
+			{Tasks: []PickTask{
+				{AssetID: 1, AssetTag: "CAM-2", PlaceID: &shelfB, PlacePath: "Warehouse / Aisle 1 / Shelf B"},
+				{AssetID: 2, AssetTag: "CAM-1", PlaceID: &shelfB, PlacePath: "Warehouse / Aisle 1 / Shelf B"},
+				{AssetID: 3, AssetTag: "CAM-3"},
+			}},
+			{Tasks: []PickTask{
+				{AssetID: 4, AssetTag: "MIC-1", PlaceID: &shelfA, PlacePath: "Warehouse / Aisle 1 / Shelf A"},
+			}},
+		},
+	}
+	pl.GroupStops()
+
+	assert.Len(t, pl.Stops, 3)
+	assert.Equal(t, &shelfA, pl.Stops[0].PlaceID)
+	assert.Equal(t, &shelfB, pl.Stops[1].PlaceID)
+	assert.Equal(t, "CAM-1", pl.Stops[1].Tasks[0].AssetTag)
+	assert.Nil(t, pl.Stops[2].PlaceID)
+	assert.Equal(t, "Unknown location", pl.Stops[2].PlacePath)
+}
@@ -0,0 +1,361 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WeekendRule sets how Saturdays and Sundays count toward a rental's billable days.
+type WeekendRule string
+
+const (
+	WeekendStandard WeekendRule = "standard" // Every day is charged
+	WeekendFree     WeekendRule = "free"     // Saturdays and Sundays are not charged
+	WeekendOneDay   WeekendRule = "one_day"  // A Saturday and the Sunday after it are charged as one day
+)
+
+// Day counts at which the weekly and monthly rates apply.
+const (
+	daysPerWeek  = 7
+	daysPerMonth = 30
+)
+
+// RateCard prices rentals of one item type. A card with a CompanyID overrides the
+// item type's general card for that company. Amounts are in minor currency units
+// (cents); a zero weekly or monthly rate means the tier is not offered.
+type RateCard struct {
+	ID               int64       `json:"id"`
+	ItemTypeID       int64       `json:"item_type_id"`
+	CompanyID        *int64      `json:"company_id,omitempty"`
+	Currency         string      `json:"currency"`
+	DailyRate        int64       `json:"daily_rate"`
+	WeeklyRate       int64       `json:"weekly_rate,omitempty"`
+	MonthlyRate      int64       `json:"monthly_rate,omitempty"` // Per 30 days
+	MinimumCharge    int64       `json:"minimum_charge,omitempty"`
+	WeekendRule      WeekendRule `json:"weekend_rule"`
+	ChargeBuffers    bool        `json:"charge_buffers"`             // Bill the item type's turnaround buffers as rental time
+	LateFeePerDay    int64       `json:"late_fee_per_day,omitempty"` // Charged on top of usage for each day returned late
+	LateGraceMinutes int         `json:"late_grace_minutes,omitempty"`
+	IsActive         bool        `json:"is_active"`
+	Notes            string      `json:"notes,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// Validate checks the card's rates and rules.
+func (rc *RateCard) Validate() error {
+	if rc.ItemTypeID == 0 {
+		return fmt.Errorf("item_type_id is required")
+	}
+	if len(rc.Currency) != 3 {
+		return fmt.Errorf("currency must be a three-letter ISO 4217 code")
+	}
+	if rc.DailyRate <= 0 {
+		return fmt.Errorf("daily_rate must be positive")
+	}
+	if rc.WeeklyRate < 0 || rc.MonthlyRate < 0 || rc.MinimumCharge < 0 || rc.LateFeePerDay < 0 {
+		return fmt.Errorf("rates, minimum_charge and late_fee_per_day cannot be negative")
+	}
+	if rc.LateGraceMinutes < 0 {
+		return fmt.Errorf("late_grace_minutes cannot be negative")
+	}
+	switch rc.WeekendRule {
+	case WeekendStandard, WeekendFree, WeekendOneDay:
+	default:
+		return fmt.Errorf("unknown weekend_rule %q", rc.WeekendRule)
+	}
+	return nil
+}
+
+// BillableDays counts the 24-hour days in [start, end) that are charged under the
+// card's weekend rule. Days take their weekday from start's time zone. A rental that
+// falls entirely on free weekend days is still charged one day.
+func (rc *RateCard) BillableDays(start, end time.Time) int {
+	if !end.After(start) {
+		return 0
+	}
+	days := int((end.Sub(start) + 24*time.Hour - 1) / (24 * time.Hour))
+	if rc.WeekendRule == WeekendStandard || rc.WeekendRule == "" {
+		return days
+	}
+
+	charged := 0
+	for i := 0; i < days; i++ {
+		wd := start.AddDate(0, 0, i).Weekday()
+		switch rc.WeekendRule {
+		case WeekendFree:
+			if wd == time.Saturday || wd == time.Sunday {
+				continue
+			}
+		case WeekendOneDay:
+			if wd == time.Sunday && i > 0 {
+				continue
+			}
+		}
+		charged++
+	}
+	if charged == 0 {
+		charged = 1
+	}
+	return charged
+}
+
+// Price charges days billable days, using monthly, then weekly, then daily rates and
+// never charging more for a remainder than the next tier up would cost.
+func (rc *RateCard) Price(days int) (int64, string) {
+	if days <= 0 {
+		return 0, ""
+	}
+	weekly := rc.WeeklyRate
+	if weekly == 0 {
+		weekly = daysPerWeek * rc.DailyRate
+	}
+	priceWeeks := func(d int) (int64, []string) {
+		var parts []string
+		w, rem := d/daysPerWeek, d%daysPerWeek
+		total := int64(w) * weekly
+		if w > 0 {
+			parts = append(parts, plural(w, "week"))
+		}
+		if rem > 0 {
+			if int64(rem)*rc.DailyRate > weekly {
+				total += weekly
+				parts = append(parts, plural(rem, "day")+" at the weekly rate")
+			} else {
+				total += int64(rem) * rc.DailyRate
+				parts = append(parts, plural(rem, "day"))
+			}
+		}
+		return total, parts
+	}
+
+	monthly := rc.MonthlyRate
+	if monthly == 0 {
+		monthly, _ = priceWeeks(daysPerMonth)
+	}
+	m, rem := days/daysPerMonth, days%daysPerMonth
+	total := int64(m) * monthly
+	var parts []string
+	if m > 0 {
+		parts = append(parts, plural(m, "month"))
+	}
+	if rem > 0 {
+		remTotal, remParts := priceWeeks(rem)
+		if remTotal > monthly {
+			total += monthly
+			parts = append(parts, plural(rem, "day")+" at the monthly rate")
+		} else {
+			total += remTotal
+			parts = append(parts, remParts...)
+		}
+	}
+	return total, strings.Join(parts, " + ")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// SelectRateCard picks the card that prices itemTypeID for companyID: the company's
+// own active card if it has one, otherwise the general one. Among equals the most
+// recently created card wins.
+func SelectRateCard(cards []RateCard, itemTypeID int64, companyID *int64) *RateCard {
+	var general, override *RateCard
+	for i := range cards {
+		c := &cards[i]
+		if !c.IsActive || c.ItemTypeID != itemTypeID {
+			continue
+		}
+		switch {
+		case c.CompanyID == nil:
+			if general == nil || c.ID > general.ID {
+				general = c
+			}
+		case companyID != nil && *c.CompanyID == *companyID:
+			if override == nil || c.ID > override.ID {
+				override = c
+			}
+		}
+	}
+	if override != nil {
+		return override
+	}
+	return general
+}
+
+// Quote prices a reservation. Lines that cannot be priced, for lack of a rate card or
+// because their card is in another currency, are listed in Unpriced and left out of
+// the total.
+type Quote struct {
+	ID            int64       `json:"id,omitempty"` // Set on snapshots stored at approval
+	ReservationID *int64      `json:"reservationId,omitempty"`
+	CompanyID     *int64      `json:"companyId,omitempty"`
+	Currency      string      `json:"currency,omitempty"`
+	StartTime     time.Time   `json:"startTime"`
+	EndTime       time.Time   `json:"endTime"`
+	Lines         []QuoteLine `json:"lines"`
+	Unpriced      []QuoteLine `json:"unpriced,omitempty"`
+	Total         int64       `json:"total"`
+	QuotedAt      time.Time   `json:"quotedAt"`
+}
+
+// QuoteLine prices the units of one item type on a demand line. Kits without a rate
+// card of their own are priced per component, one line each.
+type QuoteLine struct {
+	DemandID       int64     `json:"demandId,omitempty"`
+	ItemKind       string    `json:"itemKind"`
+	ItemID         int64     `json:"itemId"`
+	ItemTypeID     int64     `json:"itemTypeId"`
+	KitTemplateID  *int64    `json:"kitTemplateId,omitempty"` // Set on kit component lines
+	RateCardID     int64     `json:"rateCardId,omitempty"`
+	Quantity       int       `json:"quantity"`
+	BillableStart  time.Time `json:"billableStart"`
+	BillableEnd    time.Time `json:"billableEnd"`
+	BillableDays   int       `json:"billableDays"`
+	Breakdown      string    `json:"breakdown,omitempty"` // e.g. "1 week + 2 days"
+	MinimumApplied bool      `json:"minimumApplied,omitempty"`
+	UnitPrice      int64     `json:"unitPrice"`
+	Amount         int64     `json:"amount"`
+	Note           string    `json:"note,omitempty"` // Why the line is unpriced
+}
+
+// PricingInput is the catalog data a quote is priced from.
+type PricingInput struct {
+	CompanyID *int64
+	RateCards []RateCard
+	ItemTypes map[int64]*ItemType    // For turnaround buffers
+	Kits      map[int64]*KitTemplate // Kit templates referenced by kit demands
+	Now       time.Time
+}
+
+// PriceReservation quotes every demand line of rr. The quote's currency is the
+// currency of the first card used.
+func PriceReservation(rr *RentalReservation, in PricingInput) *Quote {
+	q := &Quote{
+		CompanyID: in.CompanyID,
+		StartTime: rr.StartTime,
+		EndTime:   rr.EndTime,
+		Lines:     []QuoteLine{},
+		QuotedAt:  in.Now,
+	}
+	if rr.ID != 0 {
+		id := rr.ID
+		q.ReservationID = &id
+	}
+
+	for _, d := range rr.Demands {
+		base := QuoteLine{DemandID: d.ID, ItemKind: d.ItemKind, ItemID: d.ItemID}
+		switch d.ItemKind {
+		case DemandKindItemType:
+			line := base
+			line.ItemTypeID = d.ItemID
+			q.add(rr, in, line, d.Quantity)
+
+		case DemandKindKitTemplate:
+			kit := in.Kits[d.ItemID]
+			if kit == nil {
+				line := base
+				line.Quantity = d.Quantity
+				line.Note = fmt.Sprintf("kit template %d not found", d.ItemID)
+				q.Unpriced = append(q.Unpriced, line)
+				continue
+			}
+			// A kit listed in the catalog is priced as a whole when it has a card
+			if kit.ItemTypeID != nil && SelectRateCard(in.RateCards, *kit.ItemTypeID, in.CompanyID) != nil {
+				line := base
+				line.ItemTypeID = *kit.ItemTypeID
+				q.add(rr, in, line, d.Quantity)
+				continue
+			}
+			kitID := kit.ID
+			for _, req := range kit.Requirements(d.Quantity, false) {
+				line := base
+				line.ItemTypeID = req.ItemTypeID
+				line.KitTemplateID = &kitID
+				q.add(rr, in, line, req.Quantity)
+			}
+
+		default:
+			line := base
+			line.Quantity = d.Quantity
+			line.Note = fmt.Sprintf("unknown item kind %q", d.ItemKind)
+			q.Unpriced = append(q.Unpriced, line)
+		}
+	}
+
+	sort.SliceStable(q.Unpriced, func(i, j int) bool { return q.Unpriced[i].DemandID < q.Unpriced[j].DemandID })
+	return q
+}
+
+// add prices qty units of line.ItemTypeID over the reservation window.
+func (q *Quote) add(rr *RentalReservation, in PricingInput, line QuoteLine, qty int) {
+	line.Quantity = qty
+	line.BillableStart, line.BillableEnd = rr.StartTime, rr.EndTime
+
+	card := SelectRateCard(in.RateCards, line.ItemTypeID, in.CompanyID)
+	if card == nil {
+		line.Note = fmt.Sprintf("no rate card for item_type %d", line.ItemTypeID)
+		q.Unpriced = append(q.Unpriced, line)
+		return
+	}
+	line.RateCardID = card.ID
+	if q.Currency == "" {
+		q.Currency = card.Currency
+	}
+	if card.Currency != q.Currency {
+		line.Note = fmt.Sprintf("rate card %d is in %s, quote is in %s", card.ID, card.Currency, q.Currency)
+		q.Unpriced = append(q.Unpriced, line)
+		return
+	}
+
+	if it := in.ItemTypes[line.ItemTypeID]; it != nil && card.ChargeBuffers {
+		buf := it.Buffer()
+		line.BillableStart = line.BillableStart.Add(-buf.Pre)
+		line.BillableEnd = line.BillableEnd.Add(buf.Post)
+	}
+	line.BillableDays = card.BillableDays(line.BillableStart, line.BillableEnd)
+	line.UnitPrice, line.Breakdown = card.Price(line.BillableDays)
+	if line.UnitPrice < card.MinimumCharge {
+		line.UnitPrice = card.MinimumCharge
+		line.MinimumApplied = true
+	}
+	line.Amount = line.UnitPrice * int64(qty)
+	q.Lines = append(q.Lines, line)
+	q.Total += line.Amount
+}
+
+// kitLine returns the line pricing a kit demand as a whole, or nil if the kit was
+// priced per component or the demand is not a kit.
+func (q *Quote) kitLine(demandID int64) *QuoteLine {
+	for i := range q.Lines {
+		l := &q.Lines[i]
+		if l.DemandID == demandID && l.ItemKind == DemandKindKitTemplate && l.KitTemplateID == nil {
+			return l
+		}
+	}
+	return nil
+}
+
+// rateCardID returns the card the quote priced units of itemTypeID on a demand line
+// with, or 0 if it has no such line. Without a demand line, the item type's own
+// demand lines are searched. A demand line priced as a whole covers any item type
+// sent for it, so substitutes bill at the agreed price.
+func (q *Quote) rateCardID(demandID *int64, itemTypeID int64) int64 {
+	for _, l := range q.Lines {
+		if demandID != nil {
+			if l.DemandID == *demandID && (l.ItemTypeID == itemTypeID || l.KitTemplateID == nil) {
+				return l.RateCardID
+			}
+			continue
+		}
+		if l.ItemKind == DemandKindItemType && l.ItemTypeID == itemTypeID {
+			return l.RateCardID
+		}
+	}
+	return 0
+}
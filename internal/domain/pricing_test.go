@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateCard_Price(t *testing.T) {
+	rc := &RateCard{DailyRate: 1000, WeeklyRate: 5000, MonthlyRate: 15000, WeekendRule: WeekendStandard}
+
+	tests := []struct {
+		days      int
+		want      int64
+		breakdown string
+	}{
+		{1, 1000, "1 day"},
+		{5, 5000, "5 days"},
+		{6, 5000, "6 days at the weekly rate"},
+		{9, 7000, "1 week + 2 days"},
+		{25, 15000, "25 days at the monthly rate"},
+		{32, 17000, "1 month + 2 days"},
+	}
+	for _, tt := range tests {
+		got, breakdown := rc.Price(tt.days)
+		assert.Equal(t, tt.want, got, "%d days", tt.days)
+		assert.Equal(t, tt.breakdown, breakdown, "%d days", tt.days)
+	}
+
+	dailyOnly := &RateCard{DailyRate: 1000}
+	got, _ := dailyOnly.Price(31)
+	assert.Equal(t, int64(31000), got, "missing tiers fall back to the daily rate")
+}
+
+func TestRateCard_BillableDays(t *testing.T) {
+	fri := time.Date(2026, 3, 6, 9, 0, 0, 0, time.UTC)
+	mon := fri.AddDate(0, 0, 3)
+
+	rc := &RateCard{WeekendRule: WeekendStandard}
+	assert.Equal(t, 3, rc.BillableDays(fri, mon))
+	assert.Equal(t, 4, rc.BillableDays(fri, mon.Add(time.Minute)), "partial days are charged in full")
+
+	rc.WeekendRule = WeekendFree
+	assert.Equal(t, 1, rc.BillableDays(fri, mon))
+	assert.Equal(t, 1, rc.BillableDays(fri.AddDate(0, 0, 1), mon), "weekend-only rentals are charged one day")
+
+	rc.WeekendRule = WeekendOneDay
+	assert.Equal(t, 2, rc.BillableDays(fri, mon))
+}
+
+func TestPriceReservation(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	companyID := int64(3)
+	kitTypeID := int64(40)
+
+	rr := &RentalReservation{
+		ID:        12,
+		StartTime: start,
+		EndTime:   start.Add(48 * time.Hour),
+		Demands: []Demand{
+			{ID: 1, ItemKind: DemandKindItemType, ItemID: 10, Quantity: 2},
+			{ID: 2, ItemKind: DemandKindKitTemplate, ItemID: 7, Quantity: 1},
+			{ID: 3, ItemKind: DemandKindKitTemplate, ItemID: 8, Quantity: 1},
+			{ID: 4, ItemKind: DemandKindItemType, ItemID: 99, Quantity: 1},
+		},
+	}
+	in := PricingInput{
+		CompanyID: &companyID,
+		RateCards: []RateCard{
+			{ID: 1, ItemTypeID: 10, Currency: "USD", DailyRate: 1000, WeekendRule: WeekendStandard, IsActive: true},
+			{ID: 2, ItemTypeID: 10, CompanyID: &companyID, Currency: "USD", DailyRate: 800, WeekendRule: WeekendStandard, IsActive: true},
+			{ID: 3, ItemTypeID: 20, Currency: "USD", DailyRate: 500, MinimumCharge: 2000, WeekendRule: WeekendStandard, IsActive: true},
+			{ID: 4, ItemTypeID: 30, Currency: "USD", DailyRate: 100, WeekendRule: WeekendStandard, ChargeBuffers: true, IsActive: true},
+			{ID: 5, ItemTypeID: kitTypeID, Currency: "USD", DailyRate: 3000, WeekendRule: WeekendStandard, IsActive: true},
+		},
+		ItemTypes: map[int64]*ItemType{
+			30: {ID: 30, PreBufferMinutes: 60, PostBufferMinutes: 24 * 60},
+		},
+		Kits: map[int64]*KitTemplate{
+			7: {ID: 7, Components: []KitComponent{
+				{ItemTypeID: 20, Quantity: 1},
+				{ItemTypeID: 30, Quantity: 3},
+				{ItemTypeID: 50, Quantity: 1, IsOptional: true},
+			}},
+			8: {ID: 8, ItemTypeID: &kitTypeID},
+		},
+		Now: start.Add(-time.Hour),
+	}
+
+	q := PriceReservation(rr, in)
+	require.Len(t, q.Lines, 4)
+	assert.Equal(t, "USD", q.Currency)
+
+	assert.Equal(t, int64(2), q.Lines[0].RateCardID, "company card overrides the general one")
+	assert.Equal(t, int64(3200), q.Lines[0].Amount)
+
+	assert.Equal(t, int64(20), q.Lines[1].ItemTypeID)
+	assert.True(t, q.Lines[1].MinimumApplied)
+	assert.Equal(t, int64(2000), q.Lines[1].Amount)
+
+	assert.Equal(t, 4, q.Lines[2].BillableDays, "buffers extend the billable window")
+	assert.Equal(t, int64(1200), q.Lines[2].Amount)
+
+	assert.Equal(t, kitTypeID, q.Lines[3].ItemTypeID, "kits with a card are priced whole")
+	assert.Equal(t, int64(6000), q.Lines[3].Amount)
+
+	require.Len(t, q.Unpriced, 1)
+	assert.Equal(t, int64(4), q.Unpriced[0].DemandID)
+	assert.Equal(t, int64(3200+2000+1200+6000), q.Total)
+	assert.Equal(t, int64(12), *q.ReservationID)
+}
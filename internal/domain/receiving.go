@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrReceiving is returned when a receiving session cannot be opened, scanned into or
+// closed in its current state.
+var ErrReceiving = errors.New("receiving error")
+
+// ReceivingStatus tracks a receiving session at the dock.
+type ReceivingStatus string
+
+const (
+	ReceivingOpen   ReceivingStatus = "Open"
+	ReceivingClosed ReceivingStatus = "Closed"
+)
+
+// ReceivingOutcome is how a scanned tag compares with what the reservation has out.
+type ReceivingOutcome string
+
+const (
+	ReceivedExpected         ReceivingOutcome = "expected"          // Out on the session's reservation
+	ReceivedWrongReservation ReceivingOutcome = "wrong_reservation" // Out, but on another reservation
+	ReceivedExtra            ReceivingOutcome = "extra"             // A known asset that was not out at all
+	ReceivedUnknown          ReceivingOutcome = "unknown"           // No asset or container has the tag
+)
+
+// ReceivingSession reconciles what comes off an inbound shipment against what was
+// checked out on the reservation it returns from.
+type ReceivingSession struct {
+	ID             int64           `json:"id"`
+	ShipmentID     int64           `json:"shipmentId"`
+	ReservationID  int64           `json:"reservationId"`
+	ToPlaceID      *int64          `json:"toPlaceId,omitempty"` // Where received assets are put away
+	Status         ReceivingStatus `json:"status"`
+	OpenedByUserID *int64          `json:"openedByUserId,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	ClosedAt       *time.Time      `json:"closedAt,omitempty"`
+
+	Scans  []ReceivingScan    `json:"scans,omitempty"`
+	Report *DiscrepancyReport `json:"report,omitempty"` // Running while open; final once closed
+}
+
+// ReceivingSessionRequest opens a receiving session. The reservation defaults to the
+// one the shipment's scheduled delivery is for.
+type ReceivingSessionRequest struct {
+	ReservationID *int64 `json:"reservationId,omitempty"`
+	ToPlaceID     *int64 `json:"toPlaceId,omitempty"`
+}
+
+// ReceivingScan is one tag read during a session.
+type ReceivingScan struct {
+	ID              int64            `json:"id"`
+	Code            string           `json:"code"`
+	AssetID         *int64           `json:"assetId,omitempty"`
+	AssetTag        string           `json:"assetTag,omitempty"`
+	ItemTypeID      *int64           `json:"itemTypeId,omitempty"`
+	Outcome         ReceivingOutcome `json:"outcome"`
+	ReservationID   *int64           `json:"reservationId,omitempty"` // Reservation a wrong-reservation asset is out on
+	ScannedAt       time.Time        `json:"scannedAt"`
+	ScannedByUserID *int64           `json:"scannedByUserId,omitempty"`
+}
+
+// ReceivingAsset is an asset the reservation has out, and on close, where it was routed.
+type ReceivingAsset struct {
+	AssetID      int64       `json:"assetId"`
+	AssetTag     string      `json:"assetTag,omitempty"`
+	ItemTypeID   int64       `json:"itemTypeId"`
+	RoutedStatus AssetStatus `json:"routedStatus,omitempty"`
+	Inspections  []string    `json:"inspections,omitempty"` // Templates due before it can go back out
+}
+
+// DiscrepancyReport compares a session's scans with what the reservation has out.
+type DiscrepancyReport struct {
+	ExpectedCount    int              `json:"expectedCount"`
+	ReceivedCount    int              `json:"receivedCount"`
+	Received         []ReceivingAsset `json:"received"`
+	Missing          []ReceivingAsset `json:"missing"`
+	WrongReservation []ReceivingScan  `json:"wrongReservation"`
+	Extra            []ReceivingScan  `json:"extra"`
+	Unknown          []string         `json:"unknown"` // Tags that matched nothing
+}
+
+// Clean reports whether everything expected came back and nothing else did.
+func (r *DiscrepancyReport) Clean() bool {
+	return len(r.Missing) == 0 && len(r.WrongReservation) == 0 && len(r.Extra) == 0 && len(r.Unknown) == 0
+}
+
+// BuildDiscrepancyReport sorts a session's scans against the assets the reservation
+// has out.
+func BuildDiscrepancyReport(expected []ReceivingAsset, scans []ReceivingScan) *DiscrepancyReport {
+	report := &DiscrepancyReport{
+		ExpectedCount:    len(expected),
+		Received:         []ReceivingAsset{},
+		Missing:          []ReceivingAsset{},
+		WrongReservation: []ReceivingScan{},
+		Extra:            []ReceivingScan{},
+		Unknown:          []string{},
+	}
+
+	scanned := make(map[int64]bool)
+	for _, s := range scans {
+		switch s.Outcome {
+		case ReceivedExpected:
+			scanned[*s.AssetID] = true
+		case ReceivedWrongReservation:
+			report.WrongReservation = append(report.WrongReservation, s)
+		case ReceivedExtra:
+			report.Extra = append(report.Extra, s)
+		case ReceivedUnknown:
+			report.Unknown = append(report.Unknown, s.Code)
+		}
+	}
+	for _, a := range expected {
+		if scanned[a.AssetID] {
+			report.Received = append(report.Received, a)
+		} else {
+			report.Missing = append(report.Missing, a)
+		}
+	}
+	report.ReceivedCount = len(report.Received)
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i].AssetTag < report.Missing[j].AssetTag })
+	return report
+}
+
+// RouteReturn decides where a returned asset goes: to maintenance when its item type
+// has inspections to perform, otherwise straight back into stock.
+func RouteReturn(templates []InspectionTemplate) (AssetStatus, []string) {
+	if len(templates) == 0 {
+		return AssetStatusAvailable, nil
+	}
+	names := make([]string, 0, len(templates))
+	for _, t := range templates {
+		names = append(names, t.Name)
+	}
+	return AssetStatusMaintenance, names
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDiscrepancyReport(t *testing.T) {
+	mic1, mic2, cable, light, other := int64(1), int64(2), int64(3), int64(4), int64(77)
+	expected := []ReceivingAsset{
+		{AssetID: mic1, AssetTag: "MIC-1", ItemTypeID: 10},
+		{AssetID: mic2, AssetTag: "MIC-2", ItemTypeID: 10},
+		{AssetID: cable, AssetTag: "CAB-1", ItemTypeID: 20},
+	}
+	scans := []ReceivingScan{
+		{Code: "MIC-1", AssetID: &mic1, Outcome: ReceivedExpected},
+		{Code: "CAB-1", AssetID: &cable, Outcome: ReceivedExpected},
+		{Code: "LITE-1", AssetID: &light, Outcome: ReceivedWrongReservation, ReservationID: &other},
+		{Code: "???", Outcome: ReceivedUnknown},
+	}
+
+	report := BuildDiscrepancyReport(expected, scans)
+	assert.Equal(t, 3, report.ExpectedCount)
+	assert.Equal(t, 2, report.ReceivedCount)
+	assert.Len(t, report.Missing, 1)
+	assert.Equal(t, "MIC-2", report.Missing[0].AssetTag)
+	assert.Len(t, report.WrongReservation, 1)
+	assert.Empty(t, report.Extra)
+	assert.Equal(t, []string{"???"}, report.Unknown)
+	assert.False(t, report.Clean())
+
+	clean := BuildDiscrepancyReport(expected[:1], scans[:1])
+	assert.True(t, clean.Clean())
+}
+
+func TestRouteReturn(t *testing.T) {
+	status, inspections := RouteReturn(nil)
+	assert.Equal(t, AssetStatusAvailable, status)
+	assert.Empty(t, inspections)
+
+	status, inspections = RouteReturn([]InspectionTemplate{{ID: 1, Name: "Battery check"}, {ID: 2, Name: "Visual"}})
+	assert.Equal(t, AssetStatusMaintenance, status)
+	assert.Equal(t, []string{"Battery check", "Visual"}, inspections)
+}
@@ -0,0 +1,416 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence is returned for an RRULE that cannot be parsed, or that a
+// reservation series cannot be materialized from.
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// maxRecurrencePeriods stops the expansion of rules whose filters rarely (or never)
+// match, such as FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30.
+const maxRecurrencePeriods = 10000
+
+// RecurrenceFrequency is the FREQ of an RRULE.
+type RecurrenceFrequency string
+
+const (
+	FrequencyDaily   RecurrenceFrequency = "DAILY"
+	FrequencyWeekly  RecurrenceFrequency = "WEEKLY"
+	FrequencyMonthly RecurrenceFrequency = "MONTHLY"
+	FrequencyYearly  RecurrenceFrequency = "YEARLY"
+)
+
+// RecurrenceWeekday is one BYDAY entry: a weekday, optionally restricted to the Nth
+// (or, when N is negative, Nth from last) such weekday of the month.
+type RecurrenceWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// RecurrenceRule is the subset of an RFC 5545 RRULE used to repeat reservations:
+// FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH and WKST. BYDAY ordinals
+// always count within a month, also for yearly rules.
+type RecurrenceRule struct {
+	Frequency  RecurrenceFrequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []RecurrenceWeekday
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func rruleWeekdayName(d time.Weekday) string {
+	for name, wd := range rruleWeekdays {
+		if wd == d {
+			return name
+		}
+	}
+	return ""
+}
+
+// ParseRecurrenceRule parses an RRULE value, with or without the "RRULE:" prefix.
+func ParseRecurrenceRule(s string) (*RecurrenceRule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	rule := &RecurrenceRule{Interval: 1, WeekStart: time.Monday}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRecurrence, part)
+		}
+		switch key {
+		case "FREQ":
+			switch f := RecurrenceFrequency(value); f {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				rule.Frequency = f
+			default:
+				return nil, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRecurrence, value)
+			}
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: %s must be a positive integer", ErrInvalidRecurrence, key)
+			}
+			if key == "INTERVAL" {
+				rule.Interval = n
+			} else {
+				rule.Count = n
+			}
+		case "UNTIL":
+			t, err := parseRecurrenceTime(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = t
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				if len(v) < 2 {
+					return nil, fmt.Errorf("%w: invalid BYDAY %q", ErrInvalidRecurrence, v)
+				}
+				wd, ok := rruleWeekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("%w: invalid BYDAY %q", ErrInvalidRecurrence, v)
+				}
+				n := 0
+				if ord := v[:len(v)-2]; ord != "" {
+					var err error
+					if n, err = strconv.Atoi(ord); err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("%w: invalid BYDAY %q", ErrInvalidRecurrence, v)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, RecurrenceWeekday{N: n, Weekday: wd})
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("%w: invalid BYMONTHDAY %q", ErrInvalidRecurrence, v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("%w: invalid BYMONTH %q", ErrInvalidRecurrence, v)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			wd, ok := rruleWeekdays[value]
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid WKST %q", ErrInvalidRecurrence, value)
+			}
+			rule.WeekStart = wd
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %s", ErrInvalidRecurrence, key)
+		}
+	}
+
+	if rule.Frequency == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot both be set", ErrInvalidRecurrence)
+	}
+	if rule.Frequency == FrequencyWeekly && len(rule.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("%w: BYMONTHDAY cannot be used with FREQ=WEEKLY", ErrInvalidRecurrence)
+	}
+	if rule.Frequency == FrequencyDaily || rule.Frequency == FrequencyWeekly {
+		for _, d := range rule.ByDay {
+			if d.N != 0 {
+				return nil, fmt.Errorf("%w: BYDAY ordinals need FREQ=MONTHLY or YEARLY", ErrInvalidRecurrence)
+			}
+		}
+	}
+	return rule, nil
+}
+
+// parseRecurrenceTime reads an UNTIL value. Floating times are taken as UTC and a
+// bare date includes the whole day.
+func parseRecurrenceTime(v string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102T150405", v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", v); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: invalid UNTIL %q", ErrInvalidRecurrence, v)
+}
+
+// Bounded reports whether the rule ends on its own, through COUNT or UNTIL.
+func (r *RecurrenceRule) Bounded() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// String formats the rule as an RRULE value.
+func (r *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = rruleWeekdayName(d.Weekday)
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+rruleWeekdayName(r.WeekStart))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences expands the rule from dtstart and returns at most limit occurrence
+// starts in order. Every occurrence keeps dtstart's wall-clock time in its location.
+// Starts listed in exceptions are skipped but still count toward COUNT, as EXDATE
+// does in RFC 5545.
+func (r *RecurrenceRule) Occurrences(dtstart time.Time, exceptions []time.Time, limit int) []time.Time {
+	var out []time.Time
+	matched := 0
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		for _, t := range r.periodCandidates(dtstart, period) {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return out
+			}
+			if r.Count > 0 && matched >= r.Count {
+				return out
+			}
+			matched++
+			if containsTime(exceptions, t) {
+				continue
+			}
+			out = append(out, t)
+			if len(out) >= limit {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// periodCandidates lists, in order, the starts the rule produces in the nth period
+// (day, week, month or year) counted from dtstart.
+func (r *RecurrenceRule) periodCandidates(dtstart time.Time, n int) []time.Time {
+	step := n * r.Interval
+	switch r.Frequency {
+	case FrequencyDaily:
+		day := dtstart.AddDate(0, 0, step)
+		if r.inMonths(day.Month()) && r.onMonthDay(day) && r.onWeekday(day.Weekday()) {
+			return []time.Time{day}
+		}
+		return nil
+
+	case FrequencyWeekly:
+		offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := dtstart.AddDate(0, 0, 7*step-offset)
+		var out []time.Time
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != dtstart.Weekday() {
+				continue
+			}
+			if r.onWeekday(day.Weekday()) && r.inMonths(day.Month()) {
+				out = append(out, day)
+			}
+		}
+		return out
+
+	case FrequencyMonthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, 0, 0, 0, 0, dtstart.Location())
+		if !r.inMonths(first.Month()) {
+			return nil
+		}
+		return r.monthCandidates(dtstart, first)
+
+	case FrequencyYearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{dtstart.Month()}
+		}
+		months = append([]time.Month(nil), months...)
+		sort.Slice(months, func(i, j int) bool { return months[i] < months[j] })
+		var out []time.Time
+		for _, m := range months {
+			out = append(out, r.monthCandidates(dtstart, time.Date(dtstart.Year()+step, m, 1, 0, 0, 0, 0, dtstart.Location()))...)
+		}
+		return out
+	}
+	return nil
+}
+
+// monthCandidates lists the starts in the month beginning at first. Without BYDAY or
+// BYMONTHDAY the rule repeats on dtstart's day of the month, skipping months that
+// are too short.
+func (r *RecurrenceRule) monthCandidates(dtstart, first time.Time) []time.Time {
+	daysIn := first.AddDate(0, 1, -1).Day()
+	seen := make(map[int]bool)
+	var days []int
+	add := func(d int) {
+		if d >= 1 && d <= daysIn && !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = daysIn + d + 1
+			}
+			if len(r.ByDay) == 0 || r.onNthWeekday(first, daysIn, d) {
+				add(d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for d := 1; d <= daysIn; d++ {
+			if r.onNthWeekday(first, daysIn, d) {
+				add(d)
+			}
+		}
+	default:
+		add(dtstart.Day())
+	}
+	sort.Ints(days)
+
+	hh, mm, ss := dtstart.Clock()
+	out := make([]time.Time, len(days))
+	for i, d := range days {
+		out[i] = time.Date(first.Year(), first.Month(), d, hh, mm, ss, 0, dtstart.Location())
+	}
+	return out
+}
+
+// onNthWeekday reports whether day d of the month starting at first matches a BYDAY
+// entry, honouring ordinals.
+func (r *RecurrenceRule) onNthWeekday(first time.Time, daysIn, d int) bool {
+	wd := time.Weekday((int(first.Weekday()) + d - 1) % 7)
+	for _, bd := range r.ByDay {
+		if bd.Weekday != wd {
+			continue
+		}
+		switch {
+		case bd.N == 0:
+			return true
+		case bd.N > 0 && (d-1)/7+1 == bd.N:
+			return true
+		case bd.N < 0 && (daysIn-d)/7+1 == -bd.N:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RecurrenceRule) inMonths(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RecurrenceRule) onWeekday(wd time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, bd := range r.ByDay {
+		if bd.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RecurrenceRule) onMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysIn := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, d := range r.ByMonthDay {
+		if d == t.Day() || daysIn+d+1 == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTime(ts []time.Time, t time.Time) bool {
+	for _, x := range ts {
+		if x.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
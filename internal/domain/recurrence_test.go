@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurrenceRule_Occurrences(t *testing.T) {
+	// Monday 2026-03-02 09:00
+	dtstart := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 9, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		rrule      string
+		exceptions []time.Time
+		want       []time.Time
+	}{
+		{
+			name:  "daily interval",
+			rrule: "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			want:  []time.Time{day(3, 2), day(3, 4), day(3, 6)},
+		},
+		{
+			name:  "weekly on two days until a date",
+			rrule: "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20260311",
+			want:  []time.Time{day(3, 2), day(3, 4), day(3, 9), day(3, 11)},
+		},
+		{
+			name:       "exceptions still count toward COUNT",
+			rrule:      "FREQ=WEEKLY;COUNT=3",
+			exceptions: []time.Time{day(3, 9)},
+			want:       []time.Time{day(3, 2), day(3, 16)},
+		},
+		{
+			name:  "first monday of every other month",
+			rrule: "FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO;COUNT=3",
+			want:  []time.Time{day(3, 2), day(5, 4), day(7, 6)},
+		},
+		{
+			name:  "last friday",
+			rrule: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=2",
+			want:  []time.Time{day(3, 27), day(4, 24)},
+		},
+		{
+			name:  "negative month day",
+			rrule: "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			want:  []time.Time{day(3, 31), day(4, 30), day(5, 31)},
+		},
+		{
+			name:  "yearly in two months",
+			rrule: "FREQ=YEARLY;BYMONTH=3,9;COUNT=3",
+			want:  []time.Time{day(3, 2), day(9, 2), time.Date(2027, 3, 2, 9, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(tt.rrule)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rule.Occurrences(dtstart, tt.exceptions, 100))
+		})
+	}
+}
+
+func TestParseRecurrenceRule(t *testing.T) {
+	rule, err := ParseRecurrenceRule("FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR;UNTIL=20261231T000000Z;WKST=SU")
+	require.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2;UNTIL=20261231T000000Z;BYDAY=1MO,-1FR;WKST=SU", rule.String())
+	assert.True(t, rule.Bounded())
+
+	for _, bad := range []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260401",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		_, err := ParseRecurrenceRule(bad)
+		assert.True(t, errors.Is(err, ErrInvalidRecurrence), "expected %q to be rejected", bad)
+	}
+}
+
+func TestReservationSeries_SplitAt(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	week := func(n int) time.Time { return start.AddDate(0, 0, 7*n) }
+	s := ReservationSeries{
+		ID:             3,
+		RecurrenceRule: "FREQ=WEEKLY;COUNT=6",
+		StartTime:      start,
+		EndTime:        start.Add(4 * time.Hour),
+		Exceptions:     []time.Time{week(1), week(4)},
+	}
+
+	tail, err := s.SplitAt(week(3))
+	require.NoError(t, err)
+
+	head, err := s.OccurrenceStarts()
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{week(0), week(2)}, head)
+	rest, err := tail.OccurrenceStarts()
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{week(3), week(5)}, rest)
+	assert.Equal(t, int64(0), tail.ID)
+	assert.Equal(t, week(3).Add(4*time.Hour), tail.EndTime)
+}
+
+func TestReservationPatch_ForOccurrence(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	from := RentalReservation{
+		ID: 1, StartTime: start, EndTime: start.Add(8 * time.Hour),
+		Demands: []Demand{{ID: 10, ItemKind: DemandKindItemType, ItemID: 5, Quantity: 2}},
+	}
+	next := start.AddDate(0, 0, 7)
+	to := RentalReservation{
+		ID: 2, StartTime: next, EndTime: next.Add(8 * time.Hour),
+		Demands: []Demand{{ID: 20, ItemKind: DemandKindItemType, ItemID: 5, Quantity: 2}},
+	}
+
+	qty := 4
+	newStart, newEnd := start.Add(time.Hour), start.Add(10*time.Hour)
+	patch := ReservationPatch{
+		StartTime: &newStart,
+		EndTime:   &newEnd,
+		Demands: []DemandPatch{
+			{ID: 10, Quantity: &qty},
+			{ID: 99, Remove: true}, // Not on this occurrence; dropped
+			{ItemKind: DemandKindItemType, ItemID: 6, Quantity: &qty},
+		},
+	}
+
+	out := patch.ForOccurrence(&from, &to)
+	assert.Equal(t, next.Add(time.Hour), *out.StartTime)
+	assert.Equal(t, next.Add(10*time.Hour), *out.EndTime)
+	assert.Equal(t, []DemandPatch{
+		{ID: 20, Quantity: &qty},
+		{ItemKind: DemandKindItemType, ItemID: 6, Quantity: &qty},
+	}, out.Demands)
+
+	s := ReservationSeries{
+		RecurrenceRule: "FREQ=WEEKLY;BYDAY=MO;COUNT=4",
+		StartTime:      start,
+		EndTime:        start.Add(8 * time.Hour),
+		Demands:        []Demand{{ItemKind: DemandKindItemType, ItemID: 5, Quantity: 2}},
+	}
+	require.NoError(t, s.ApplyPatch(&from, &patch))
+	assert.Equal(t, newStart, s.StartTime)
+	assert.Equal(t, []Demand{
+		{ItemKind: DemandKindItemType, ItemID: 5, Quantity: 4},
+		{ItemKind: DemandKindItemType, ItemID: 6, Quantity: 4},
+	}, s.Demands)
+
+	// BYDAY pins the series to Mondays
+	tuesday, tuesdayEnd := start.AddDate(0, 0, 1), start.AddDate(0, 0, 1).Add(8*time.Hour)
+	err := s.ApplyPatch(&from, &ReservationPatch{StartTime: &tuesday, EndTime: &tuesdayEnd})
+	assert.True(t, errors.Is(err, ErrInvalidReservationChange))
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrReservationNotModifiable is returned when a reservation is in a status that
+	// no longer accepts changes (cancelled or already fulfilled).
+	ErrReservationNotModifiable = errors.New("reservation cannot be modified in its current status")
+	// ErrInvalidReservationChange is returned when a patch is malformed or refers to
+	// demand lines that do not belong to the reservation.
+	ErrInvalidReservationChange = errors.New("invalid reservation change")
+)
+
+// ReservationPatch is a partial update of a reservation and its demand lines.
+// Nil fields are left untouched.
+type ReservationPatch struct {
+	ReservationName *string         `json:"reservationName,omitempty"`
+	StartTime       *time.Time      `json:"startTime,omitempty"`
+	EndTime         *time.Time      `json:"endTime,omitempty"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+	Demands         []DemandPatch   `json:"demands,omitempty"`
+}
+
+// DemandPatch changes, removes or (when ID is zero) adds one demand line.
+type DemandPatch struct {
+	ID       int64  `json:"id,omitempty"`
+	ItemKind string `json:"itemKind,omitempty"`
+	ItemID   int64  `json:"itemId,omitempty"`
+	Quantity *int   `json:"requestedQuantity,omitempty"`
+	Remove   bool   `json:"remove,omitempty"`
+}
+
+// Apply returns a copy of rr with the patch applied, along with a description of
+// every field that actually changed. rr itself is not modified.
+func (p *ReservationPatch) Apply(rr RentalReservation) (RentalReservation, []string, error) {
+	out := rr
+	out.Demands = append([]Demand(nil), rr.Demands...)
+	var changed []string
+
+	if p.ReservationName != nil && *p.ReservationName != rr.ReservationName {
+		out.ReservationName = *p.ReservationName
+		changed = append(changed, "reservationName")
+	}
+	if p.StartTime != nil && !p.StartTime.Equal(rr.StartTime) {
+		out.StartTime = *p.StartTime
+		changed = append(changed, "startTime")
+	}
+	if p.EndTime != nil && !p.EndTime.Equal(rr.EndTime) {
+		out.EndTime = *p.EndTime
+		changed = append(changed, "endTime")
+	}
+	if !out.EndTime.After(out.StartTime) {
+		return rr, nil, fmt.Errorf("%w: endTime must be after startTime", ErrInvalidReservationChange)
+	}
+	if len(p.Metadata) > 0 && string(p.Metadata) != string(rr.Metadata) {
+		out.Metadata = p.Metadata
+		changed = append(changed, "metadata")
+	}
+
+	for _, dp := range p.Demands {
+		if dp.Quantity != nil && *dp.Quantity <= 0 {
+			return rr, nil, fmt.Errorf("%w: requestedQuantity must be positive", ErrInvalidReservationChange)
+		}
+
+		if dp.ID == 0 {
+			if dp.Remove {
+				return rr, nil, fmt.Errorf("%w: cannot remove a demand without an id", ErrInvalidReservationChange)
+			}
+			if dp.ItemKind != DemandKindItemType && dp.ItemKind != DemandKindKitTemplate {
+				return rr, nil, fmt.Errorf("%w: unsupported itemKind %q", ErrInvalidReservationChange, dp.ItemKind)
+			}
+			if dp.ItemID == 0 || dp.Quantity == nil {
+				return rr, nil, fmt.Errorf("%w: new demands need itemId and requestedQuantity", ErrInvalidReservationChange)
+			}
+			out.Demands = append(out.Demands, Demand{
+				ReservationID: rr.ID,
+				ItemKind:      dp.ItemKind,
+				ItemID:        dp.ItemID,
+				Quantity:      *dp.Quantity,
+			})
+			changed = append(changed, fmt.Sprintf("demand added: %s %d x%d", dp.ItemKind, dp.ItemID, *dp.Quantity))
+			continue
+		}
+
+		idx := -1
+		for i := range out.Demands {
+			if out.Demands[i].ID == dp.ID {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return rr, nil, fmt.Errorf("%w: demand %d does not belong to reservation %d", ErrInvalidReservationChange, dp.ID, rr.ID)
+		}
+
+		if dp.Remove {
+			out.Demands = append(out.Demands[:idx], out.Demands[idx+1:]...)
+			changed = append(changed, fmt.Sprintf("demand %d removed", dp.ID))
+			continue
+		}
+		d := &out.Demands[idx]
+		if dp.ItemKind != "" && dp.ItemKind != d.ItemKind {
+			if dp.ItemKind != DemandKindItemType && dp.ItemKind != DemandKindKitTemplate {
+				return rr, nil, fmt.Errorf("%w: unsupported itemKind %q", ErrInvalidReservationChange, dp.ItemKind)
+			}
+			d.ItemKind = dp.ItemKind
+			changed = append(changed, fmt.Sprintf("demand %d itemKind", dp.ID))
+		}
+		if dp.ItemID != 0 && dp.ItemID != d.ItemID {
+			d.ItemID = dp.ItemID
+			changed = append(changed, fmt.Sprintf("demand %d itemId", dp.ID))
+		}
+		if dp.Quantity != nil && *dp.Quantity != d.Quantity {
+			d.Quantity = *dp.Quantity
+			changed = append(changed, fmt.Sprintf("demand %d requestedQuantity", dp.ID))
+		}
+	}
+
+	return out, changed, nil
+}
+
+// ReservationChange is one versioned entry in a reservation's change history.
+type ReservationChange struct {
+	ID              int64                   `json:"id"`
+	ReservationID   int64                   `json:"reservationId"`
+	Version         int                     `json:"version"`
+	ChangedByUserID *int64                  `json:"changedByUserId,omitempty"`
+	ChangedFields   []string                `json:"changedFields"`
+	StatusBefore    RentalReservationStatus `json:"statusBefore"`
+	StatusAfter     RentalReservationStatus `json:"statusAfter"`
+	Before          json.RawMessage         `json:"before"` // Reservation snapshot including demands
+	After           json.RawMessage         `json:"after"`
+	CreatedAt       time.Time               `json:"createdAt"`
+}
+
+// ReservationChangeResult reports the outcome of applying a ReservationPatch.
+type ReservationChangeResult struct {
+	Reservation *RentalReservation `json:"reservation"`
+	Change      *ReservationChange `json:"change,omitempty"` // Nil when the patch changed nothing
+	Requeued    bool               `json:"requeued"`         // A confirmed reservation was moved back to pending
+	Shortfalls  []Shortfall        `json:"shortfalls,omitempty"`
+}
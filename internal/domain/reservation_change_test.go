@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservationPatch_Apply(t *testing.T) {
+	start := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	rr := RentalReservation{
+		ID:        7,
+		StartTime: start,
+		EndTime:   start.Add(8 * time.Hour),
+		Demands: []Demand{
+			{ID: 1, ReservationID: 7, ItemKind: DemandKindItemType, ItemID: 10, Quantity: 2},
+			{ID: 2, ReservationID: 7, ItemKind: DemandKindItemType, ItemID: 11, Quantity: 1},
+		},
+	}
+
+	newEnd := start.Add(24 * time.Hour)
+	three, one := 3, 1
+	p := ReservationPatch{
+		EndTime: &newEnd,
+		Demands: []DemandPatch{
+			{ID: 1, Quantity: &three},
+			{ID: 2, Remove: true},
+			{ItemKind: DemandKindKitTemplate, ItemID: 4, Quantity: &one},
+		},
+	}
+
+	out, changed, err := p.Apply(rr)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"endTime", "demand 1 requestedQuantity", "demand 2 removed", "demand added: kit_template 4 x1"}, changed)
+	assert.Equal(t, newEnd, out.EndTime)
+	assert.Len(t, out.Demands, 2)
+	assert.Equal(t, 3, out.Demands[0].Quantity)
+	assert.Equal(t, int64(4), out.Demands[1].ItemID)
+
+	// The original is left untouched
+	assert.Equal(t, 2, rr.Demands[0].Quantity)
+	assert.Len(t, rr.Demands, 2)
+
+	_, _, err = (&ReservationPatch{Demands: []DemandPatch{{ID: 99, Remove: true}}}).Apply(rr)
+	assert.True(t, errors.Is(err, ErrInvalidReservationChange))
+
+	early := start.Add(-time.Hour)
+	_, _, err = (&ReservationPatch{EndTime: &early}).Apply(rr)
+	assert.True(t, errors.Is(err, ErrInvalidReservationChange))
+}
@@ -0,0 +1,261 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MaxSeriesOccurrences bounds how many reservations one series may materialize.
+const MaxSeriesOccurrences = 366
+
+// ReservationSeries repeats a reservation on an RFC 5545 recurrence rule. Every
+// occurrence is materialized as its own RentalReservation linked back to the series,
+// so holds, check-outs and changes keep working per occurrence.
+type ReservationSeries struct {
+	ID              int64           `json:"id"`
+	ReservationName string          `json:"reservationName,omitempty"`
+	RecurrenceRule  string          `json:"recurrenceRule"`       // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10
+	StartTime       time.Time       `json:"startTime"`            // DTSTART: start of the first occurrence
+	EndTime         time.Time       `json:"endTime"`              // End of the first occurrence; sets every occurrence's length
+	Exceptions      []time.Time     `json:"exceptions,omitempty"` // EXDATE: occurrence starts that are skipped
+	UnderNameID     *int64          `json:"underNameId,omitempty"`
+	ProviderID      *int64          `json:"providerId,omitempty"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+
+	Demands     []Demand            `json:"demands,omitempty"` // Template copied onto every new occurrence
+	Occurrences []RentalReservation `json:"occurrences,omitempty"`
+}
+
+// Rule parses the series' recurrence rule.
+func (s *ReservationSeries) Rule() (*RecurrenceRule, error) {
+	return ParseRecurrenceRule(s.RecurrenceRule)
+}
+
+// OccurrenceStarts expands the series into the start of every occurrence. The rule
+// must end through COUNT or UNTIL within MaxSeriesOccurrences.
+func (s *ReservationSeries) OccurrenceStarts() ([]time.Time, error) {
+	if !s.EndTime.After(s.StartTime) {
+		return nil, fmt.Errorf("%w: endTime must be after startTime", ErrInvalidRecurrence)
+	}
+	rule, err := s.Rule()
+	if err != nil {
+		return nil, err
+	}
+	if !rule.Bounded() {
+		return nil, fmt.Errorf("%w: a reservation series needs COUNT or UNTIL", ErrInvalidRecurrence)
+	}
+	starts := rule.Occurrences(s.StartTime, s.Exceptions, MaxSeriesOccurrences+1)
+	if len(starts) == 0 {
+		return nil, fmt.Errorf("%w: the rule produces no occurrences", ErrInvalidRecurrence)
+	}
+	if len(starts) > MaxSeriesOccurrences {
+		return nil, fmt.Errorf("%w: more than %d occurrences", ErrInvalidRecurrence, MaxSeriesOccurrences)
+	}
+	return starts, nil
+}
+
+// Occurrence builds the pending reservation for the occurrence starting at start,
+// with a copy of the series' demand template.
+func (s *ReservationSeries) Occurrence(start time.Time) RentalReservation {
+	recurrenceID := start
+	rr := RentalReservation{
+		ReservationName:   s.ReservationName,
+		ReservationStatus: ReservationStatusPending,
+		UnderNameID:       s.UnderNameID,
+		StartTime:         start,
+		EndTime:           start.Add(s.EndTime.Sub(s.StartTime)),
+		ProviderID:        s.ProviderID,
+		Metadata:          s.Metadata,
+		RecurrenceID:      &recurrenceID,
+	}
+	if s.ID != 0 {
+		rr.SeriesID = &s.ID
+	}
+	for _, d := range s.Demands {
+		d.ID = 0
+		d.ReservationID = 0
+		rr.Demands = append(rr.Demands, d)
+	}
+	return rr
+}
+
+// SplitAt ends the series before the occurrence originally starting at at and
+// returns a new series carrying the rule from that occurrence on. A COUNT is shared
+// out between the two so the total number of occurrences does not change.
+func (s *ReservationSeries) SplitAt(at time.Time) (ReservationSeries, error) {
+	rule, err := s.Rule()
+	if err != nil {
+		return ReservationSeries{}, err
+	}
+	if !at.After(s.StartTime) {
+		return ReservationSeries{}, fmt.Errorf("%w: cannot split a series at its first occurrence", ErrInvalidRecurrence)
+	}
+
+	tail := *s
+	tail.ID = 0
+	tail.Occurrences = nil
+	tail.StartTime = at
+	tail.EndTime = at.Add(s.EndTime.Sub(s.StartTime))
+	tail.Exceptions = nil
+	var headExceptions []time.Time
+	for _, ex := range s.Exceptions {
+		if ex.Before(at) {
+			headExceptions = append(headExceptions, ex)
+		} else {
+			tail.Exceptions = append(tail.Exceptions, ex)
+		}
+	}
+
+	tailRule := *rule
+	if rule.Count > 0 {
+		before := 0
+		for _, t := range rule.Occurrences(s.StartTime, nil, rule.Count) {
+			if t.Before(at) {
+				before++
+			}
+		}
+		tailRule.Count = rule.Count - before
+	}
+	tail.RecurrenceRule = tailRule.String()
+
+	headRule := *rule
+	headRule.Count = 0
+	headRule.Until = at.Add(-time.Second)
+	s.RecurrenceRule = headRule.String()
+	s.Exceptions = headExceptions
+	return tail, nil
+}
+
+// Move sets the series' window to [start, end), shifting its exceptions along with
+// DTSTART. BYDAY, BYMONTHDAY and BYMONTH pin occurrences to calendar days, so rules
+// using them only allow the first occurrence to move within its own date.
+func (s *ReservationSeries) Move(start, end time.Time) error {
+	if !end.After(start) {
+		return fmt.Errorf("%w: endTime must be after startTime", ErrInvalidReservationChange)
+	}
+	rule, err := s.Rule()
+	if err != nil {
+		return err
+	}
+	oy, om, od := s.StartTime.Date()
+	ny, nm, nd := start.In(s.StartTime.Location()).Date()
+	if (oy != ny || om != nm || od != nd) && (len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0 || len(rule.ByMonth) > 0) {
+		return fmt.Errorf("%w: occurrences of %s cannot move to another day", ErrInvalidReservationChange, s.RecurrenceRule)
+	}
+
+	shift := start.Sub(s.StartTime)
+	for i := range s.Exceptions {
+		s.Exceptions[i] = s.Exceptions[i].Add(shift)
+	}
+	s.StartTime, s.EndTime = start, end
+	return nil
+}
+
+// ApplyPatch carries a patch written against occurrence from over to the series'
+// own window, name, metadata and demand template, which later occurrences copy.
+func (s *ReservationSeries) ApplyPatch(from *RentalReservation, patch *ReservationPatch) error {
+	tmpl := s.Occurrence(s.StartTime)
+	// Template lines have no IDs of their own; number them so the patch can address them
+	for i := range tmpl.Demands {
+		tmpl.Demands[i].ID = int64(i + 1)
+	}
+	p := patch.ForOccurrence(from, &tmpl)
+	updated, _, err := p.Apply(tmpl)
+	if err != nil {
+		return err
+	}
+	if err := s.Move(updated.StartTime, updated.EndTime); err != nil {
+		return err
+	}
+	s.ReservationName = updated.ReservationName
+	s.Metadata = updated.Metadata
+	s.Demands = updated.Demands
+	for i := range s.Demands {
+		s.Demands[i].ID = 0
+	}
+	return nil
+}
+
+// SeriesApproval reports the occurrences confirmed by approving a series.
+type SeriesApproval struct {
+	SeriesID    int64              `json:"seriesId"`
+	Allocations []AllocationResult `json:"allocations"`
+}
+
+// SeriesEditScope selects which occurrences an edit made on one occurrence applies to.
+type SeriesEditScope string
+
+const (
+	SeriesEditThis      SeriesEditScope = "this"
+	SeriesEditFollowing SeriesEditScope = "following" // This occurrence and every later one; splits the series
+	SeriesEditAll       SeriesEditScope = "all"
+)
+
+// SeriesEdit changes or cancels occurrences of a series. The patch is written against
+// the occurrence in ReservationID and carried over to the others by ForOccurrence.
+type SeriesEdit struct {
+	Scope         SeriesEditScope  `json:"scope"`
+	ReservationID int64            `json:"reservationId"`
+	Patch         ReservationPatch `json:"patch"`
+	Cancel        bool             `json:"cancel,omitempty"` // Cancel the occurrences instead of patching them
+}
+
+// SeriesEditResult reports the outcome of a SeriesEdit.
+type SeriesEditResult struct {
+	Series    *ReservationSeries        `json:"series"`                // The series the edited occurrences now belong to
+	SplitFrom *int64                    `json:"splitFromId,omitempty"` // Set when a "following" edit split the series
+	Changes   []ReservationChangeResult `json:"changes,omitempty"`
+	Cancelled []int64                   `json:"cancelled,omitempty"`
+	Skipped   []int64                   `json:"skipped,omitempty"` // Occurrences already cancelled or fulfilled
+}
+
+// ForOccurrence rewrites a patch made against one occurrence of a series so it can be
+// applied to another: new start and end times become the same shift relative to the
+// other occurrence, and existing demand lines are matched by item rather than by ID.
+// Demand patches with no matching line on the other occurrence are dropped.
+func (p *ReservationPatch) ForOccurrence(from, to *RentalReservation) ReservationPatch {
+	out := ReservationPatch{ReservationName: p.ReservationName, Metadata: p.Metadata}
+	if p.StartTime != nil {
+		t := to.StartTime.Add(p.StartTime.Sub(from.StartTime))
+		out.StartTime = &t
+	}
+	if p.EndTime != nil {
+		t := to.EndTime.Add(p.EndTime.Sub(from.EndTime))
+		out.EndTime = &t
+	}
+
+	for _, dp := range p.Demands {
+		if dp.ID == 0 {
+			out.Demands = append(out.Demands, dp)
+			continue
+		}
+		var src *Demand
+		for i := range from.Demands {
+			if from.Demands[i].ID == dp.ID {
+				src = &from.Demands[i]
+				break
+			}
+		}
+		if src == nil {
+			continue
+		}
+		for _, d := range to.Demands {
+			if d.ItemKind == src.ItemKind && d.ItemID == src.ItemID {
+				dp.ID = d.ID
+				out.Demands = append(out.Demands, dp)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// OccurrenceShortfall lists what one occurrence of a series is short on.
+type OccurrenceShortfall struct {
+	ReservationID int64       `json:"reservationId"`
+	StartTime     time.Time   `json:"startTime"`
+	Shortfalls    []Shortfall `json:"shortfalls"`
+}
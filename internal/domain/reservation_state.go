@@ -0,0 +1,272 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTransition is returned when an action is not allowed from a reservation's
+// current status, or its guard refuses it.
+var ErrInvalidTransition = errors.New("reservation transition not allowed")
+
+// ReservationAction names a transition between reservation statuses.
+type ReservationAction string
+
+const (
+	ReservationActionSubmit           ReservationAction = "submit"
+	ReservationActionApprove          ReservationAction = "approve"
+	ReservationActionReject           ReservationAction = "reject"
+	ReservationActionCancel           ReservationAction = "cancel"
+	ReservationActionRequeue          ReservationAction = "requeue" // A change to a confirmed reservation could not be covered
+	ReservationActionPartiallyFulfill ReservationAction = "partially_fulfill"
+	ReservationActionFulfill          ReservationAction = "fulfill"
+	ReservationActionReopen           ReservationAction = "reopen" // Nothing is dispatched against the reservation any more
+)
+
+// ReservationTimestamp names the timestamp a transition records on the reservation.
+type ReservationTimestamp string
+
+const (
+	StampSubmitted ReservationTimestamp = "submitted"
+	StampApproved  ReservationTimestamp = "approved"
+	StampRejected  ReservationTimestamp = "rejected"
+	StampCancelled ReservationTimestamp = "cancelled"
+	StampFulfilled ReservationTimestamp = "fulfilled"
+)
+
+// ReservationTransitionState is what transition guards are evaluated against.
+type ReservationTransitionState struct {
+	Reservation       *RentalReservation
+	OutstandingAssets int // Assets checked out against the reservation and not yet returned
+	Now               time.Time
+}
+
+// ReservationTransition is one row of the reservation state machine. Applying it sets
+// the status, records Stamp and the acting user on the reservation, appends Event to
+// the outbox and writes an entry to the reservation's transition history.
+type ReservationTransition struct {
+	Action        ReservationAction
+	From          []RentalReservationStatus
+	To            RentalReservationStatus
+	System        bool                 // Applied by the system as a side effect, never requested directly
+	Stamp         ReservationTimestamp // Timestamp recorded on the reservation, if any
+	Event         EventType            // Outbox event appended, if any
+	ReleasesHolds bool
+	Guard         func(*ReservationTransitionState) error
+}
+
+// ReservationTransitions is the reservation state machine. Approval is also subject to
+// an availability check that needs the database, which the repository performs.
+var ReservationTransitions = []ReservationTransition{
+	{
+		Action: ReservationActionSubmit,
+		From:   []RentalReservationStatus{ReservationStatusDraft},
+		To:     ReservationStatusPending,
+		Stamp:  StampSubmitted,
+		Event:  EventRentalSubmitted,
+		Guard:  guardSubmittable,
+	},
+	{
+		Action: ReservationActionApprove,
+		From:   []RentalReservationStatus{ReservationStatusPending},
+		To:     ReservationStatusConfirmed,
+		Stamp:  StampApproved,
+		Event:  EventRentalApproved,
+		Guard:  guardNotEnded,
+	},
+	{
+		Action:        ReservationActionReject,
+		From:          []RentalReservationStatus{ReservationStatusPending},
+		To:            ReservationStatusCancelled,
+		Stamp:         StampRejected,
+		Event:         EventRentalRejected,
+		ReleasesHolds: true,
+	},
+	{
+		Action:        ReservationActionCancel,
+		From:          []RentalReservationStatus{ReservationStatusDraft, ReservationStatusPending, ReservationStatusConfirmed},
+		To:            ReservationStatusCancelled,
+		Stamp:         StampCancelled,
+		Event:         EventRentalCancelled,
+		ReleasesHolds: true,
+		Guard:         guardNothingOut,
+	},
+	{
+		Action:        ReservationActionRequeue,
+		From:          []RentalReservationStatus{ReservationStatusConfirmed},
+		To:            ReservationStatusPending,
+		System:        true,
+		ReleasesHolds: true,
+	},
+	{
+		Action: ReservationActionPartiallyFulfill,
+		From:   []RentalReservationStatus{ReservationStatusConfirmed, ReservationStatusFulfilled},
+		To:     ReservationStatusPartiallyFulfilled,
+		System: true,
+		Event:  EventRentalPartiallyFulfilled,
+	},
+	{
+		Action: ReservationActionFulfill,
+		From:   []RentalReservationStatus{ReservationStatusConfirmed, ReservationStatusPartiallyFulfilled},
+		To:     ReservationStatusFulfilled,
+		System: true,
+		Stamp:  StampFulfilled,
+		Event:  EventRentalFulfilled,
+	},
+	{
+		Action: ReservationActionReopen,
+		From:   []RentalReservationStatus{ReservationStatusPartiallyFulfilled, ReservationStatusFulfilled},
+		To:     ReservationStatusConfirmed,
+		System: true,
+	},
+}
+
+func guardSubmittable(s *ReservationTransitionState) error {
+	if !s.Reservation.EndTime.After(s.Reservation.StartTime) {
+		return errors.New("end time must be after start time")
+	}
+	if len(s.Reservation.Demands) == 0 {
+		return errors.New("reservation has no demands")
+	}
+	return nil
+}
+
+func guardNotEnded(s *ReservationTransitionState) error {
+	if !s.Now.Before(s.Reservation.EndTime) {
+		return fmt.Errorf("reservation ended at %s", s.Reservation.EndTime.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func guardNothingOut(s *ReservationTransitionState) error {
+	if s.OutstandingAssets > 0 {
+		return fmt.Errorf("%d assets are still checked out", s.OutstandingAssets)
+	}
+	return nil
+}
+
+// allows reports whether t leaves from status.
+func (t *ReservationTransition) allows(status RentalReservationStatus) bool {
+	for _, from := range t.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// check runs the transition's guard, if any.
+func (t *ReservationTransition) check(state *ReservationTransitionState) error {
+	if t.Guard == nil {
+		return nil
+	}
+	return t.Guard(state)
+}
+
+// FindReservationTransition returns the transition action takes from the reservation's
+// current status once its guard has passed.
+func FindReservationTransition(action ReservationAction, state *ReservationTransitionState) (*ReservationTransition, error) {
+	status := state.Reservation.ReservationStatus
+	for i := range ReservationTransitions {
+		t := &ReservationTransitions[i]
+		if t.Action != action {
+			continue
+		}
+		if !t.allows(status) {
+			return nil, fmt.Errorf("%w: cannot %s a reservation in status %s", ErrInvalidTransition, action, status)
+		}
+		if err := t.check(state); err != nil {
+			return nil, fmt.Errorf("%w: cannot %s: %v", ErrInvalidTransition, action, err)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidTransition, action)
+}
+
+// SystemTransitionTo returns the system transition from one status to another, or nil
+// if the state machine has none.
+func SystemTransitionTo(from, to RentalReservationStatus) *ReservationTransition {
+	for i := range ReservationTransitions {
+		t := &ReservationTransitions[i]
+		if t.System && t.To == to && t.allows(from) {
+			return t
+		}
+	}
+	return nil
+}
+
+// AllowedTransition is an action a user may request on a reservation, with the reason
+// its guard currently refuses it, if any.
+type AllowedTransition struct {
+	Action  ReservationAction       `json:"action"`
+	To      RentalReservationStatus `json:"to"`
+	Allowed bool                    `json:"allowed"`
+	Reason  string                  `json:"reason,omitempty"`
+}
+
+// AllowedReservationTransitions lists the user-requestable transitions leaving the
+// reservation's current status.
+func AllowedReservationTransitions(state *ReservationTransitionState) []AllowedTransition {
+	allowed := []AllowedTransition{}
+	for i := range ReservationTransitions {
+		t := &ReservationTransitions[i]
+		if t.System || !t.allows(state.Reservation.ReservationStatus) {
+			continue
+		}
+		at := AllowedTransition{Action: t.Action, To: t.To, Allowed: true}
+		if err := t.check(state); err != nil {
+			at.Allowed = false
+			at.Reason = err.Error()
+		}
+		allowed = append(allowed, at)
+	}
+	return allowed
+}
+
+// ApplyTransition moves the reservation along t, recording its timestamp and who did it.
+func (rr *RentalReservation) ApplyTransition(t *ReservationTransition, at time.Time, userID *int64) {
+	rr.ReservationStatus = t.To
+	rr.UpdatedAt = at
+	rr.UpdatedByUserID = userID
+	stamp := at
+	switch t.Stamp {
+	case StampSubmitted:
+		rr.SubmittedAt = &stamp
+	case StampApproved:
+		rr.ApprovedAt = &stamp
+	case StampRejected:
+		rr.RejectedAt = &stamp
+	case StampCancelled:
+		rr.CancelledAt = &stamp
+	case StampFulfilled:
+		rr.FulfilledAt = &stamp
+	}
+}
+
+// ReservationTransitionRequest asks for a user-requestable transition.
+type ReservationTransitionRequest struct {
+	Action ReservationAction `json:"action"`
+	Reason string            `json:"reason,omitempty"`
+}
+
+// ReservationTransitionRecord is one applied transition in a reservation's history.
+type ReservationTransitionRecord struct {
+	ID            int64                   `json:"id"`
+	ReservationID int64                   `json:"reservationId"`
+	Action        ReservationAction       `json:"action"`
+	FromStatus    RentalReservationStatus `json:"fromStatus"`
+	ToStatus      RentalReservationStatus `json:"toStatus"`
+	Reason        string                  `json:"reason,omitempty"`
+	UserID        *int64                  `json:"userId,omitempty"`
+	CreatedAt     time.Time               `json:"createdAt"`
+}
+
+// ReservationLifecycle is a reservation's current status, the transitions it can take
+// next and the ones it has taken.
+type ReservationLifecycle struct {
+	ReservationID int64                         `json:"reservationId"`
+	Status        RentalReservationStatus       `json:"status"`
+	Allowed       []AllowedTransition           `json:"allowed"`
+	History       []ReservationTransitionRecord `json:"history"`
+}
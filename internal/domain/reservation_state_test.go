@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindReservationTransition(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	rr := &RentalReservation{
+		ID:                1,
+		ReservationStatus: ReservationStatusDraft,
+		StartTime:         now.Add(24 * time.Hour),
+		EndTime:           now.Add(48 * time.Hour),
+	}
+	state := &ReservationTransitionState{Reservation: rr, Now: now}
+
+	_, err := FindReservationTransition(ReservationActionSubmit, state)
+	assert.True(t, errors.Is(err, ErrInvalidTransition), "drafts without demands cannot be submitted")
+
+	rr.Demands = []Demand{{ItemKind: DemandKindItemType, ItemID: 5, Quantity: 1}}
+	tr, err := FindReservationTransition(ReservationActionSubmit, state)
+	require.NoError(t, err)
+	userID := int64(7)
+	rr.ApplyTransition(tr, now, &userID)
+	assert.Equal(t, ReservationStatusPending, rr.ReservationStatus)
+	assert.Equal(t, now, *rr.SubmittedAt)
+	assert.Equal(t, &userID, rr.UpdatedByUserID)
+
+	_, err = FindReservationTransition(ReservationActionFulfill, state)
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+	_, err = FindReservationTransition("archive", state)
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+
+	rr.ReservationStatus = ReservationStatusConfirmed
+	state.OutstandingAssets = 2
+	_, err = FindReservationTransition(ReservationActionCancel, state)
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+	assert.Contains(t, err.Error(), "2 assets are still checked out")
+
+	assert.Equal(t, ReservationActionRequeue, SystemTransitionTo(ReservationStatusConfirmed, ReservationStatusPending).Action)
+	assert.Nil(t, SystemTransitionTo(ReservationStatusPending, ReservationStatusConfirmed), "approval is never a side effect")
+}
+
+func TestAllowedReservationTransitions(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	rr := &RentalReservation{
+		ReservationStatus: ReservationStatusPending,
+		StartTime:         now.Add(-48 * time.Hour),
+		EndTime:           now.Add(-24 * time.Hour),
+	}
+
+	allowed := AllowedReservationTransitions(&ReservationTransitionState{Reservation: rr, Now: now})
+	assert.Equal(t, []AllowedTransition{
+		{Action: ReservationActionApprove, To: ReservationStatusConfirmed, Reason: "reservation ended at 2026-03-01T09:00:00Z"},
+		{Action: ReservationActionReject, To: ReservationStatusCancelled, Allowed: true},
+		{Action: ReservationActionCancel, To: ReservationStatusCancelled, Allowed: true},
+	}, allowed)
+
+	rr.ReservationStatus = ReservationStatusFulfilled
+	assert.Empty(t, AllowedReservationTransitions(&ReservationTransitionState{Reservation: rr, Now: now}))
+}
@@ -0,0 +1,366 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Item type metadata keys giving the per-unit load of an item for route planning.
+const (
+	MetadataWeightKg = "weight_kg"
+	MetadataVolumeM3 = "volume_m3"
+)
+
+// Place metadata keys giving its coordinates, in decimal degrees.
+const (
+	MetadataLatitude  = "latitude"
+	MetadataLongitude = "longitude"
+)
+
+// Defaults for route planning when the request leaves them out.
+const (
+	DefaultServiceMinutes = 20 // Time to unload at each stop
+	DefaultSpeedKmh       = 40 // Average road speed, traffic included
+	roadFactor            = 1.3
+)
+
+// GeoPoint is a latitude and longitude in decimal degrees.
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// PlaceCoordinates reads a place's coordinates from its metadata, or nil when it has none.
+func PlaceCoordinates(metadata json.RawMessage) *GeoPoint {
+	if len(metadata) == 0 {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(metadata, &m); err != nil {
+		return nil
+	}
+	lat, latOK := m[MetadataLatitude].(float64)
+	lng, lngOK := m[MetadataLongitude].(float64)
+	if !latOK || !lngOK {
+		return nil
+	}
+	return &GeoPoint{Latitude: lat, Longitude: lng}
+}
+
+// ItemLoad reads an item type's per-unit weight and volume from its metadata. Missing
+// values count as zero.
+func ItemLoad(metadata json.RawMessage) (weightKg, volumeM3 float64) {
+	if len(metadata) == 0 {
+		return 0, 0
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(metadata, &m); err != nil {
+		return 0, 0
+	}
+	weightKg, _ = m[MetadataWeightKg].(float64)
+	volumeM3, _ = m[MetadataVolumeM3].(float64)
+	return weightKg, volumeM3
+}
+
+// DistanceKm is the great-circle distance between two points.
+func DistanceKm(a, b GeoPoint) float64 {
+	const earthRadiusKm = 6371.0
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// Vehicle is a truck or van available for the day.
+type Vehicle struct {
+	Name        string  `json:"name"`
+	MaxWeightKg float64 `json:"maxWeightKg,omitempty"` // Zero means no limit
+	MaxVolumeM3 float64 `json:"maxVolumeM3,omitempty"` // Zero means no limit
+}
+
+// RoutePlanRequest plans the day's scheduled deliveries across a fleet of vehicles
+// leaving from one depot.
+type RoutePlanRequest struct {
+	Date           time.Time  `json:"date"`                     // Day whose deliveries are planned
+	DepotPlaceID   int64      `json:"depotPlaceId"`             // Where every vehicle loads and returns
+	DeliveryIDs    []int64    `json:"deliveryIds,omitempty"`    // Defaults to all the day's deliveries
+	Vehicles       []Vehicle  `json:"vehicles"`                 // At least one
+	DepartAt       *time.Time `json:"departAt,omitempty"`       // Defaults to 06:00 on the day
+	ServiceMinutes int        `json:"serviceMinutes,omitempty"` // Defaults to DefaultServiceMinutes
+	SpeedKmh       float64    `json:"speedKmh,omitempty"`       // Defaults to DefaultSpeedKmh
+}
+
+// Validate checks a route plan request and fills in its defaults.
+func (req *RoutePlanRequest) Validate() error {
+	if req.Date.IsZero() || req.DepotPlaceID == 0 {
+		return errors.New("date and depotPlaceId are required")
+	}
+	if len(req.Vehicles) == 0 {
+		return errors.New("at least one vehicle is required")
+	}
+	for i, v := range req.Vehicles {
+		if v.Name == "" {
+			return fmt.Errorf("vehicle %d needs a name", i+1)
+		}
+		if v.MaxWeightKg < 0 || v.MaxVolumeM3 < 0 {
+			return fmt.Errorf("vehicle %q has a negative capacity", v.Name)
+		}
+	}
+	if req.ServiceMinutes < 0 || req.SpeedKmh < 0 {
+		return errors.New("serviceMinutes and speedKmh cannot be negative")
+	}
+	if req.ServiceMinutes == 0 {
+		req.ServiceMinutes = DefaultServiceMinutes
+	}
+	if req.SpeedKmh == 0 {
+		req.SpeedKmh = DefaultSpeedKmh
+	}
+	if req.DepartAt == nil {
+		y, m, d := req.Date.Date()
+		depart := time.Date(y, m, d, 6, 0, 0, 0, req.Date.Location())
+		req.DepartAt = &depart
+	}
+	return nil
+}
+
+// RouteStop is a delivery to route: where it goes, when it may arrive and what it weighs.
+type RouteStop struct {
+	DeliveryID     int64     `json:"deliveryId"`
+	PlaceID        int64     `json:"placeId"`
+	PlaceName      string    `json:"placeName,omitempty"`
+	Point          *GeoPoint `json:"point,omitempty"`
+	TransitMinutes int       `json:"-"` // The place's transit time from the warehouse, for places without coordinates
+	WindowStart    time.Time `json:"windowStart"`
+	WindowEnd      time.Time `json:"windowEnd"`
+	WeightKg       float64   `json:"weightKg"`
+	VolumeM3       float64   `json:"volumeM3"`
+}
+
+// PlannedStop is a stop on a vehicle's route with its timings.
+type PlannedStop struct {
+	RouteStop
+	Sequence    int       `json:"sequence"`
+	ArriveAt    time.Time `json:"arriveAt"`
+	WaitMinutes int       `json:"waitMinutes,omitempty"` // Early arrival held until the window opens
+	DepartAt    time.Time `json:"departAt"`
+}
+
+// VehicleRoute is one vehicle's ordered stops, leaving and returning to the depot.
+type VehicleRoute struct {
+	Vehicle      Vehicle       `json:"vehicle"`
+	Stops        []PlannedStop `json:"stops"`
+	DepartAt     time.Time     `json:"departAt"`
+	ReturnAt     time.Time     `json:"returnAt"`
+	DriveMinutes int           `json:"driveMinutes"`
+	WeightKg     float64       `json:"weightKg"`
+	VolumeM3     float64       `json:"volumeM3"`
+}
+
+// UnroutedStop is a delivery no vehicle could take.
+type UnroutedStop struct {
+	DeliveryID int64  `json:"deliveryId"`
+	Reason     string `json:"reason"`
+}
+
+// RoutePlan is the outcome of route planning for a day.
+type RoutePlan struct {
+	Date         time.Time      `json:"date"`
+	DepotPlaceID int64          `json:"depotPlaceId"`
+	Routes       []VehicleRoute `json:"routes"`
+	Unrouted     []UnroutedStop `json:"unrouted"`
+}
+
+// routePlanner holds what the solver needs to time routes.
+type routePlanner struct {
+	depot          *GeoPoint
+	stops          []RouteStop
+	departAt       time.Time
+	serviceMinutes int
+	speedKmh       float64
+}
+
+// travel estimates the drive between two stops, or from or to the depot when an index
+// is -1. Without coordinates on both ends it falls back on the places' transit times
+// from the warehouse, going via the depot.
+func (p *routePlanner) travel(from, to int) time.Duration {
+	if from == to {
+		return 0
+	}
+	point := func(i int) *GeoPoint {
+		if i < 0 {
+			return p.depot
+		}
+		return p.stops[i].Point
+	}
+	if a, b := point(from), point(to); a != nil && b != nil {
+		hours := DistanceKm(*a, *b) * roadFactor / p.speedKmh
+		return time.Duration(hours * float64(time.Hour)).Round(time.Minute)
+	}
+	transit := 0
+	for _, i := range []int{from, to} {
+		if i >= 0 {
+			transit += p.stops[i].TransitMinutes
+		}
+	}
+	return time.Duration(transit) * time.Minute
+}
+
+// simulate times a route of stop indexes. It reports whether every stop is reached
+// within its window, when the vehicle gets back and how long it spent driving.
+func (p *routePlanner) simulate(route []int) (bool, time.Time, time.Duration) {
+	now, prev := p.departAt, -1
+	var drive time.Duration
+	for _, i := range route {
+		leg := p.travel(prev, i)
+		drive += leg
+		now = now.Add(leg)
+		if now.Before(p.stops[i].WindowStart) {
+			now = p.stops[i].WindowStart
+		}
+		if now.After(p.stops[i].WindowEnd) {
+			return false, now, drive
+		}
+		now = now.Add(time.Duration(p.serviceMinutes) * time.Minute)
+		prev = i
+	}
+	leg := p.travel(prev, -1)
+	return true, now.Add(leg), drive + leg
+}
+
+// PlanRoutes assigns stops to vehicles and orders them with a local heuristic: each
+// stop, earliest deadline first, is inserted where it adds the least time to any route
+// that keeps within capacity and every time window, then each route is improved by
+// 2-opt. Stops that fit nowhere are returned as unrouted.
+func PlanRoutes(req *RoutePlanRequest, depot *GeoPoint, stops []RouteStop) *RoutePlan {
+	p := &routePlanner{depot: depot, stops: stops, departAt: *req.DepartAt, serviceMinutes: req.ServiceMinutes, speedKmh: req.SpeedKmh}
+	plan := &RoutePlan{Date: req.Date, DepotPlaceID: req.DepotPlaceID, Routes: []VehicleRoute{}, Unrouted: []UnroutedStop{}}
+
+	order := make([]int, len(stops))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		sa, sb := stops[order[a]], stops[order[b]]
+		if !sa.WindowEnd.Equal(sb.WindowEnd) {
+			return sa.WindowEnd.Before(sb.WindowEnd)
+		}
+		return sa.DeliveryID < sb.DeliveryID
+	})
+
+	routes := make([][]int, len(req.Vehicles))
+	weight := make([]float64, len(req.Vehicles))
+	volume := make([]float64, len(req.Vehicles))
+	fits := func(v int, s RouteStop) bool {
+		veh := req.Vehicles[v]
+		return (veh.MaxWeightKg == 0 || weight[v]+s.WeightKg <= veh.MaxWeightKg) &&
+			(veh.MaxVolumeM3 == 0 || volume[v]+s.VolumeM3 <= veh.MaxVolumeM3)
+	}
+
+	for _, i := range order {
+		s := stops[i]
+		bestVehicle, bestPos := -1, 0
+		var bestCost time.Duration
+		anyFits := false
+		for v := range req.Vehicles {
+			if !fits(v, s) {
+				continue
+			}
+			anyFits = true
+			_, baseEnd, _ := p.simulate(routes[v])
+			for pos := 0; pos <= len(routes[v]); pos++ {
+				candidate := insertAt(routes[v], pos, i)
+				ok, end, _ := p.simulate(candidate)
+				if !ok {
+					continue
+				}
+				// Added time to the route; an empty vehicle is only used when it pays off
+				cost := end.Sub(baseEnd)
+				if len(routes[v]) == 0 {
+					cost = end.Sub(p.departAt)
+				}
+				if bestVehicle < 0 || cost < bestCost {
+					bestVehicle, bestPos, bestCost = v, pos, cost
+				}
+			}
+		}
+		if bestVehicle < 0 {
+			reason := "no vehicle reaches it within its time window"
+			if !anyFits {
+				reason = "exceeds the remaining capacity of every vehicle"
+			}
+			plan.Unrouted = append(plan.Unrouted, UnroutedStop{DeliveryID: s.DeliveryID, Reason: reason})
+			continue
+		}
+		routes[bestVehicle] = insertAt(routes[bestVehicle], bestPos, i)
+		weight[bestVehicle] += s.WeightKg
+		volume[bestVehicle] += s.VolumeM3
+	}
+
+	for v, route := range routes {
+		if len(route) == 0 {
+			continue
+		}
+		route = p.twoOpt(route)
+		plan.Routes = append(plan.Routes, p.timeRoute(req.Vehicles[v], route))
+	}
+	return plan
+}
+
+// insertAt returns a copy of route with stop inserted before position pos.
+func insertAt(route []int, pos, stop int) []int {
+	out := make([]int, 0, len(route)+1)
+	out = append(out, route[:pos]...)
+	out = append(out, stop)
+	return append(out, route[pos:]...)
+}
+
+// twoOpt reverses stretches of the route while that brings the vehicle back sooner
+// without missing a window.
+func (p *routePlanner) twoOpt(route []int) []int {
+	_, bestEnd, _ := p.simulate(route)
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(route)-1; i++ {
+			for j := i + 1; j < len(route); j++ {
+				candidate := append([]int(nil), route...)
+				for a, b := i, j; a < b; a, b = a+1, b-1 {
+					candidate[a], candidate[b] = candidate[b], candidate[a]
+				}
+				if ok, end, _ := p.simulate(candidate); ok && end.Before(bestEnd) {
+					route, bestEnd, improved = candidate, end, true
+				}
+			}
+		}
+	}
+	return route
+}
+
+// timeRoute lays out a vehicle's route with arrival and departure times at each stop.
+func (p *routePlanner) timeRoute(vehicle Vehicle, route []int) VehicleRoute {
+	vr := VehicleRoute{Vehicle: vehicle, DepartAt: p.departAt}
+	now, prev := p.departAt, -1
+	for n, i := range route {
+		s := p.stops[i]
+		now = now.Add(p.travel(prev, i))
+		stop := PlannedStop{RouteStop: s, Sequence: n + 1, ArriveAt: now}
+		if now.Before(s.WindowStart) {
+			stop.WaitMinutes = int(s.WindowStart.Sub(now).Minutes())
+			now = s.WindowStart
+		}
+		now = now.Add(time.Duration(p.serviceMinutes) * time.Minute)
+		stop.DepartAt = now
+		vr.Stops = append(vr.Stops, stop)
+		vr.WeightKg += s.WeightKg
+		vr.VolumeM3 += s.VolumeM3
+		prev = i
+	}
+	_, returnAt, drive := p.simulate(route)
+	vr.ReturnAt = returnAt
+	vr.DriveMinutes = int(drive.Minutes())
+	return vr
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutePlanRequest_Validate(t *testing.T) {
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	req := RoutePlanRequest{Date: day, DepotPlaceID: 1, Vehicles: []Vehicle{{Name: "Van 1"}}}
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, DefaultServiceMinutes, req.ServiceMinutes)
+	assert.Equal(t, float64(DefaultSpeedKmh), req.SpeedKmh)
+	assert.Equal(t, time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC), *req.DepartAt)
+
+	assert.Error(t, (&RoutePlanRequest{Date: day, Vehicles: []Vehicle{{Name: "Van 1"}}}).Validate())
+	assert.Error(t, (&RoutePlanRequest{Date: day, DepotPlaceID: 1}).Validate())
+	assert.Error(t, (&RoutePlanRequest{Date: day, DepotPlaceID: 1, Vehicles: []Vehicle{{}}}).Validate())
+	assert.Error(t, (&RoutePlanRequest{Date: day, DepotPlaceID: 1, Vehicles: []Vehicle{{Name: "Van 1", MaxWeightKg: -1}}}).Validate())
+}
+
+func TestPlaceCoordinatesAndItemLoad(t *testing.T) {
+	p := PlaceCoordinates(json.RawMessage(`{"latitude": 51.5, "longitude": -0.12}`))
+	if assert.NotNil(t, p) {
+		assert.Equal(t, GeoPoint{Latitude: 51.5, Longitude: -0.12}, *p)
+	}
+	assert.Nil(t, PlaceCoordinates(json.RawMessage(`{"latitude": 51.5}`)))
+	assert.Nil(t, PlaceCoordinates(nil))
+
+	weight, volume := ItemLoad(json.RawMessage(`{"weight_kg": 12.5, "volume_m3": 0.2}`))
+	assert.Equal(t, 12.5, weight)
+	assert.Equal(t, 0.2, volume)
+	weight, volume = ItemLoad(nil)
+	assert.Zero(t, weight)
+	assert.Zero(t, volume)
+
+	// One degree of longitude at the equator
+	assert.InDelta(t, 111.2, DistanceKm(GeoPoint{}, GeoPoint{Longitude: 1}), 0.1)
+}
+
+func TestPlanRoutes(t *testing.T) {
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+	depot := &GeoPoint{Latitude: 0, Longitude: 0}
+	east := func(deg float64) *GeoPoint { return &GeoPoint{Longitude: deg} }
+
+	t.Run("time windows order the stops", func(t *testing.T) {
+		req := &RoutePlanRequest{Date: day, DepotPlaceID: 1, Vehicles: []Vehicle{{Name: "Van 1"}}}
+		assert.NoError(t, req.Validate())
+		stops := []RouteStop{
+			{DeliveryID: 1, PlaceID: 11, Point: east(0.1), WindowStart: at(10), WindowEnd: at(12)},
+			{DeliveryID: 2, PlaceID: 12, Point: east(0.2), WindowStart: at(0), WindowEnd: at(8)},
+			{DeliveryID: 3, PlaceID: 13, Point: east(0.15), WindowStart: at(8), WindowEnd: at(9)},
+		}
+
+		plan := PlanRoutes(req, depot, stops)
+		assert.Empty(t, plan.Unrouted)
+		if assert.Len(t, plan.Routes, 1) {
+			route := plan.Routes[0]
+			var order []int64
+			for _, s := range route.Stops {
+				order = append(order, s.DeliveryID)
+			}
+			assert.Equal(t, []int64{2, 3, 1}, order)
+			assert.True(t, route.Stops[0].ArriveAt.Before(at(8)))
+			// The last stop waits for its window to open
+			last := route.Stops[2]
+			assert.Positive(t, last.WaitMinutes)
+			assert.Equal(t, at(10).Add(20*time.Minute), last.DepartAt)
+			assert.True(t, route.ReturnAt.After(last.DepartAt))
+			assert.Equal(t, 3, route.Stops[2].Sequence)
+		}
+	})
+
+	t.Run("capacity spreads stops across vehicles", func(t *testing.T) {
+		req := &RoutePlanRequest{Date: day, DepotPlaceID: 1, Vehicles: []Vehicle{
+			{Name: "Van 1", MaxWeightKg: 100},
+			{Name: "Van 2", MaxWeightKg: 100},
+		}}
+		assert.NoError(t, req.Validate())
+		stops := []RouteStop{
+			{DeliveryID: 1, PlaceID: 11, Point: east(0.1), WindowStart: at(0), WindowEnd: at(24), WeightKg: 80},
+			{DeliveryID: 2, PlaceID: 12, Point: east(0.2), WindowStart: at(0), WindowEnd: at(24), WeightKg: 80},
+			{DeliveryID: 3, PlaceID: 13, Point: east(0.3), WindowStart: at(0), WindowEnd: at(24), WeightKg: 150},
+		}
+
+		plan := PlanRoutes(req, depot, stops)
+		assert.Len(t, plan.Routes, 2)
+		for _, route := range plan.Routes {
+			assert.Len(t, route.Stops, 1)
+			assert.Equal(t, 80.0, route.WeightKg)
+		}
+		if assert.Len(t, plan.Unrouted, 1) {
+			assert.Equal(t, int64(3), plan.Unrouted[0].DeliveryID)
+			assert.Contains(t, plan.Unrouted[0].Reason, "capacity")
+		}
+	})
+
+	t.Run("unreachable windows and transit fallback", func(t *testing.T) {
+		req := &RoutePlanRequest{Date: day, DepotPlaceID: 1, Vehicles: []Vehicle{{Name: "Van 1"}}}
+		assert.NoError(t, req.Validate())
+		stops := []RouteStop{
+			{DeliveryID: 1, PlaceID: 11, TransitMinutes: 45, WindowStart: at(0), WindowEnd: at(24)},
+			{DeliveryID: 2, PlaceID: 12, Point: east(0.1), WindowStart: at(0), WindowEnd: at(5)},
+		}
+
+		plan := PlanRoutes(req, depot, stops)
+		if assert.Len(t, plan.Unrouted, 1) {
+			assert.Equal(t, int64(2), plan.Unrouted[0].DeliveryID)
+			assert.Contains(t, plan.Unrouted[0].Reason, "time window")
+		}
+		if assert.Len(t, plan.Routes, 1) && assert.Len(t, plan.Routes[0].Stops, 1) {
+			route := plan.Routes[0]
+			assert.Equal(t, at(6).Add(45*time.Minute), route.Stops[0].ArriveAt)
+			assert.Equal(t, at(6).Add(110*time.Minute), route.ReturnAt)
+			assert.Equal(t, 90, route.DriveMinutes)
+		}
+	})
+}
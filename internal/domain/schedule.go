@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultScheduleHorizon is how far ahead a schedule request without a latest end is
+// searched.
+const DefaultScheduleHorizon = 90 * 24 * time.Hour
+
+// ScheduleRequest asks for the earliest slot at which a set of demands fits for a given
+// duration, optionally creating the reservation there.
+type ScheduleRequest struct {
+	Demands         []Demand   `json:"demands"`
+	DurationMinutes int        `json:"durationMinutes"`
+	EarliestStart   *time.Time `json:"earliestStart,omitempty"` // Defaults to now
+	LatestEnd       *time.Time `json:"latestEnd,omitempty"`     // Defaults to the search horizon
+	SourcePlaceID   *int64     `json:"sourcePlaceId,omitempty"` // Only draw on assets and stock at this place or places inside it
+
+	Create          bool   `json:"create,omitempty"`          // Create the reservation at the slot found
+	Draft           bool   `json:"draft,omitempty"`           // Create it as a draft rather than submitting it
+	ReservationName string `json:"reservationName,omitempty"` // For the created reservation
+	UnderNameID     *int64 `json:"underNameId,omitempty"`     // For the created reservation
+}
+
+// Duration is how long the requested rental lasts.
+func (req *ScheduleRequest) Duration() time.Duration {
+	return time.Duration(req.DurationMinutes) * time.Minute
+}
+
+// Validate checks the demands and the window of a schedule request.
+func (req *ScheduleRequest) Validate() error {
+	if len(req.Demands) == 0 {
+		return errors.New("at least one demand is required")
+	}
+	for i, d := range req.Demands {
+		if d.ItemKind != DemandKindItemType && d.ItemKind != DemandKindKitTemplate {
+			return fmt.Errorf("demand %d: itemKind must be %s or %s", i, DemandKindItemType, DemandKindKitTemplate)
+		}
+		if d.ItemID == 0 || d.Quantity <= 0 {
+			return fmt.Errorf("demand %d: itemId and a positive requestedQuantity are required", i)
+		}
+	}
+	if req.DurationMinutes <= 0 {
+		return errors.New("durationMinutes must be positive")
+	}
+	if req.EarliestStart != nil && req.LatestEnd != nil && req.LatestEnd.Sub(*req.EarliestStart) < req.Duration() {
+		return errors.New("the window between earliestStart and latestEnd is shorter than the duration")
+	}
+	return nil
+}
+
+// SearchRange returns the earliest and latest start times the request allows as of now.
+func (req *ScheduleRequest) SearchRange(now time.Time) (from, until time.Time) {
+	from = now
+	if req.EarliestStart != nil && req.EarliestStart.After(now) {
+		from = *req.EarliestStart
+	}
+	until = from.Add(DefaultScheduleHorizon)
+	if req.LatestEnd != nil {
+		until = req.LatestEnd.Add(-req.Duration())
+	}
+	return from, until
+}
+
+// Reservation builds the reservation to create at slot.
+func (req *ScheduleRequest) Reservation(slot *TimeWindow) *RentalReservation {
+	rr := &RentalReservation{
+		ReservationName:   req.ReservationName,
+		ReservationStatus: ReservationStatusPending,
+		UnderNameID:       req.UnderNameID,
+		StartTime:         slot.Start,
+		EndTime:           slot.End,
+		Demands:           append([]Demand(nil), req.Demands...),
+	}
+	if req.Draft {
+		rr.ReservationStatus = ReservationStatusDraft
+	}
+	return rr
+}
+
+// ScheduleResult is the earliest slot found for a schedule request, and the
+// reservation created there when one was asked for.
+type ScheduleResult struct {
+	Slot        *TimeWindow        `json:"slot,omitempty"` // Nil when nothing fits in the search range
+	SearchFrom  time.Time          `json:"searchFrom"`
+	SearchUntil time.Time          `json:"searchUntil"`
+	Reservation *RentalReservation `json:"reservation,omitempty"`
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleRequest_Validate(t *testing.T) {
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(3 * time.Hour)
+	demands := []Demand{{ItemKind: DemandKindItemType, ItemID: 10, Quantity: 2}}
+
+	assert.NoError(t, (&ScheduleRequest{Demands: demands, DurationMinutes: 180, EarliestStart: &start, LatestEnd: &end}).Validate())
+	assert.Error(t, (&ScheduleRequest{DurationMinutes: 60}).Validate())
+	assert.Error(t, (&ScheduleRequest{Demands: demands}).Validate())
+	assert.Error(t, (&ScheduleRequest{Demands: []Demand{{ItemKind: "asset", ItemID: 1, Quantity: 1}}, DurationMinutes: 60}).Validate())
+	assert.Error(t, (&ScheduleRequest{Demands: demands, DurationMinutes: 181, EarliestStart: &start, LatestEnd: &end}).Validate())
+}
+
+func TestScheduleRequest_SearchRange(t *testing.T) {
+	now := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	past, later, latestEnd := now.Add(-time.Hour), now.Add(24*time.Hour), now.Add(72*time.Hour)
+
+	from, until := (&ScheduleRequest{DurationMinutes: 60, EarliestStart: &past}).SearchRange(now)
+	assert.Equal(t, now, from)
+	assert.Equal(t, now.Add(DefaultScheduleHorizon), until)
+
+	from, until = (&ScheduleRequest{DurationMinutes: 120, EarliestStart: &later, LatestEnd: &latestEnd}).SearchRange(now)
+	assert.Equal(t, later, from)
+	assert.Equal(t, latestEnd.Add(-2*time.Hour), until)
+}
+
+func TestEarliestFit_SourcePlace(t *testing.T) {
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	reqs := []ComponentRequirement{{ItemTypeID: 10, Quantity: 2}}
+
+	// Fleet-wide there are 4 units with one on hold for the first day; the source
+	// place holds 2 of them, one of which is out until day 2
+	fleet := BuildConsumerTimelines([]int64{10}, map[int64]int{10: 4}, nil, []InventoryConsumer{
+		{Kind: ConsumerHold, ItemTypeID: 10, Quantity: 1, Start: &start, End: ptrTime(start.Add(24 * time.Hour))},
+	})
+	place := BuildConsumerTimelines([]int64{10}, map[int64]int{10: 2}, nil, []InventoryConsumer{
+		{Kind: ConsumerDeployed, ItemTypeID: 10, Quantity: 1, End: ptrTime(start.Add(48 * time.Hour))},
+	})
+
+	slot := EarliestFit(reqs, start, start.Add(30*24*time.Hour), 4*time.Hour, 0, fleet)
+	require.NotNil(t, slot)
+	assert.Equal(t, start, slot.Start)
+
+	slot = EarliestFit(reqs, start, start.Add(30*24*time.Hour), 4*time.Hour, 0, fleet, place)
+	require.NotNil(t, slot)
+	assert.Equal(t, start.Add(48*time.Hour), slot.Start)
+	assert.Equal(t, start.Add(52*time.Hour), slot.End)
+
+	assert.Nil(t, EarliestFit(reqs, start, start.Add(24*time.Hour), 4*time.Hour, 0, fleet, place))
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
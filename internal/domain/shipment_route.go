@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidRoute is returned when a shipment's legs do not form a route, or when what
+// is loaded onto a shipment does not fit its route.
+var ErrInvalidRoute = errors.New("invalid shipment route")
+
+// ErrInvalidTransfer is returned when assets cannot move between the reservations asked for.
+var ErrInvalidTransfer = errors.New("invalid transfer")
+
+// ShipmentLeg is one hop of a shipment, e.g. warehouse to hub, with its own carrier
+// and tracking. A shipment's legs chain: each starts where the one before it ended.
+type ShipmentLeg struct {
+	ID                 int64          `json:"id"`
+	ShipmentID         int64          `json:"shipmentId"`
+	Sequence           int            `json:"sequence"` // 1-based position in the route
+	FromPlaceID        int64          `json:"fromPlaceId"`
+	ToPlaceID          int64          `json:"toPlaceId"`
+	Carrier            string         `json:"carrier,omitempty"`
+	TrackingNumber     string         `json:"trackingNumber,omitempty"`
+	EstimatedDeparture *time.Time     `json:"estimatedDeparture,omitempty"`
+	EstimatedArrival   *time.Time     `json:"estimatedArrival,omitempty"`
+	Status             DeliveryStatus `json:"deliveryStatus"`
+	DepartedAt         *time.Time     `json:"departedAt,omitempty"`
+	ArrivedAt          *time.Time     `json:"arrivedAt,omitempty"`
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"updatedAt"`
+}
+
+// ValidateRoute checks that legs chain from place to place with consistent ETAs and
+// numbers them in order.
+func ValidateRoute(legs []ShipmentLeg) error {
+	if len(legs) == 0 {
+		return fmt.Errorf("%w: at least one leg is required", ErrInvalidRoute)
+	}
+	for i := range legs {
+		leg := &legs[i]
+		if leg.FromPlaceID == 0 || leg.ToPlaceID == 0 {
+			return fmt.Errorf("%w: leg %d needs fromPlaceId and toPlaceId", ErrInvalidRoute, i+1)
+		}
+		if leg.FromPlaceID == leg.ToPlaceID {
+			return fmt.Errorf("%w: leg %d starts and ends at place %d", ErrInvalidRoute, i+1, leg.FromPlaceID)
+		}
+		if leg.EstimatedDeparture != nil && leg.EstimatedArrival != nil && leg.EstimatedArrival.Before(*leg.EstimatedDeparture) {
+			return fmt.Errorf("%w: leg %d arrives before it departs", ErrInvalidRoute, i+1)
+		}
+		if i > 0 {
+			prev := &legs[i-1]
+			if leg.FromPlaceID != prev.ToPlaceID {
+				return fmt.Errorf("%w: leg %d starts at place %d but leg %d ends at place %d", ErrInvalidRoute, i+1, leg.FromPlaceID, i, prev.ToPlaceID)
+			}
+			if leg.EstimatedDeparture != nil && prev.EstimatedArrival != nil && leg.EstimatedDeparture.Before(*prev.EstimatedArrival) {
+				return fmt.Errorf("%w: leg %d departs before leg %d arrives", ErrInvalidRoute, i+1, i)
+			}
+		}
+		leg.Sequence = i + 1
+		if leg.Status == "" {
+			leg.Status = DeliveryPreparing
+		}
+	}
+	return nil
+}
+
+// Stops returns the places a route delivers to, in order: where each leg ends.
+func Stops(legs []ShipmentLeg) []int64 {
+	stops := make([]int64, 0, len(legs))
+	for _, leg := range legs {
+		stops = append(stops, leg.ToPlaceID)
+	}
+	return stops
+}
+
+// ConsolidationRequest loads the unshipped check-outs of several reservations onto one
+// shipment.
+type ConsolidationRequest struct {
+	ReservationIDs []int64 `json:"reservationIds"`
+}
+
+// ConsolidationResult lists the check-outs a consolidation put on the shipment, by reservation.
+type ConsolidationResult struct {
+	ShipmentID  int64             `json:"shipmentId"`
+	CheckOutIDs map[int64][]int64 `json:"checkOutIds"`
+}
+
+// TransferRequest moves assets that are out on one reservation straight onto another,
+// typically from one venue to the next, without a trip back to the warehouse.
+type TransferRequest struct {
+	FromReservationID int64   `json:"fromReservationId"`
+	ToReservationID   int64   `json:"toReservationId"`
+	AssetIDs          []int64 `json:"assetIds"`
+	ToPlaceID         *int64  `json:"toPlaceId,omitempty"`  // Where the assets are going; defaults to where they are
+	ShipmentID        *int64  `json:"shipmentId,omitempty"` // Shipment carrying them, if any
+}
+
+// Validate checks a transfer request before it touches the database.
+func (req *TransferRequest) Validate() error {
+	if req.FromReservationID == 0 || req.ToReservationID == 0 {
+		return errors.New("fromReservationId and toReservationId are required")
+	}
+	if req.FromReservationID == req.ToReservationID {
+		return errors.New("fromReservationId and toReservationId must differ")
+	}
+	if len(req.AssetIDs) == 0 {
+		return errors.New("at least one asset is required")
+	}
+	seen := make(map[int64]bool, len(req.AssetIDs))
+	for _, id := range req.AssetIDs {
+		if seen[id] {
+			return fmt.Errorf("asset %d is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// CanReceiveTransfer reports whether assets may be checked out against a reservation
+// in the given status.
+func CanReceiveTransfer(status RentalReservationStatus) bool {
+	return status == ReservationStatusConfirmed || status == ReservationStatusPartiallyFulfilled ||
+		status == ReservationStatusFulfilled
+}
+
+// AssetTransfer records one asset handed over between reservations: the return that
+// closed it out on the first and the check-out that opened it on the second.
+type AssetTransfer struct {
+	ID                int64     `json:"id"`
+	AssetID           int64     `json:"assetId"`
+	FromReservationID int64     `json:"fromReservationId"`
+	ToReservationID   int64     `json:"toReservationId"`
+	ReturnActionID    int64     `json:"returnActionId"`
+	CheckOutActionID  int64     `json:"checkOutActionId"`
+	FromPlaceID       *int64    `json:"fromPlaceId,omitempty"`
+	ToPlaceID         *int64    `json:"toPlaceId,omitempty"`
+	ShipmentID        *int64    `json:"shipmentId,omitempty"`
+	AgentID           int64     `json:"agentId"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
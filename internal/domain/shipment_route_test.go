@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRoute(t *testing.T) {
+	dep := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	arr := dep.Add(4 * time.Hour)
+	early := dep.Add(time.Hour)
+
+	legs := []ShipmentLeg{
+		{FromPlaceID: 1, ToPlaceID: 2, EstimatedDeparture: &dep, EstimatedArrival: &arr},
+		{FromPlaceID: 2, ToPlaceID: 3, Status: DeliveryShipped},
+	}
+	require.NoError(t, ValidateRoute(legs))
+	assert.Equal(t, 1, legs[0].Sequence)
+	assert.Equal(t, 2, legs[1].Sequence)
+	assert.Equal(t, DeliveryPreparing, legs[0].Status)
+	assert.Equal(t, DeliveryShipped, legs[1].Status)
+	assert.Equal(t, []int64{2, 3}, Stops(legs))
+
+	assert.ErrorIs(t, ValidateRoute(nil), ErrInvalidRoute)
+	assert.ErrorIs(t, ValidateRoute([]ShipmentLeg{{FromPlaceID: 1, ToPlaceID: 1}}), ErrInvalidRoute)
+	assert.ErrorIs(t, ValidateRoute([]ShipmentLeg{{FromPlaceID: 1, ToPlaceID: 2}, {FromPlaceID: 3, ToPlaceID: 4}}), ErrInvalidRoute)
+	assert.ErrorIs(t, ValidateRoute([]ShipmentLeg{{FromPlaceID: 1, ToPlaceID: 2, EstimatedDeparture: &arr, EstimatedArrival: &dep}}), ErrInvalidRoute)
+	assert.ErrorIs(t, ValidateRoute([]ShipmentLeg{
+		{FromPlaceID: 1, ToPlaceID: 2, EstimatedArrival: &arr},
+		{FromPlaceID: 2, ToPlaceID: 3, EstimatedDeparture: &early},
+	}), ErrInvalidRoute)
+}
+
+func TestTransferRequest_Validate(t *testing.T) {
+	assert.NoError(t, (&TransferRequest{FromReservationID: 1, ToReservationID: 2, AssetIDs: []int64{10, 11}}).Validate())
+	assert.Error(t, (&TransferRequest{FromReservationID: 1, AssetIDs: []int64{10}}).Validate())
+	assert.Error(t, (&TransferRequest{FromReservationID: 1, ToReservationID: 1, AssetIDs: []int64{10}}).Validate())
+	assert.Error(t, (&TransferRequest{FromReservationID: 1, ToReservationID: 2}).Validate())
+	assert.Error(t, (&TransferRequest{FromReservationID: 1, ToReservationID: 2, AssetIDs: []int64{10, 10}}).Validate())
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalidShipmentTransition is returned when a shipment cannot move to the status asked for.
+var ErrInvalidShipmentTransition = errors.New("invalid shipment transition")
+
+// shipmentTransitions lists where a shipment may go from each status. Delayed and
+// lost shipments can still turn up; returned is final.
+var shipmentTransitions = map[DeliveryStatus][]DeliveryStatus{
+	DeliveryPreparing: {DeliveryShipped},
+	DeliveryShipped:   {DeliveryDelayed, DeliveryLost, DeliveryDelivered, DeliveryReturned},
+	DeliveryDelayed:   {DeliveryShipped, DeliveryLost, DeliveryDelivered, DeliveryReturned},
+	DeliveryLost:      {DeliveryDelivered, DeliveryReturned},
+	DeliveryDelivered: {DeliveryReturned},
+}
+
+// NormalizeDeliveryStatus maps the legacy "Preparing" default, and an empty status,
+// onto DeliveryPreparing.
+func NormalizeDeliveryStatus(s DeliveryStatus) DeliveryStatus {
+	if s == "" || s == "Preparing" {
+		return DeliveryPreparing
+	}
+	return s
+}
+
+// ValidateShipmentTransition checks that a shipment may move from one status to another.
+func ValidateShipmentTransition(from, to DeliveryStatus) error {
+	from, to = NormalizeDeliveryStatus(from), NormalizeDeliveryStatus(to)
+	for _, next := range shipmentTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrInvalidShipmentTransition, from, to)
+}
+
+// ShipmentEventType returns the outbox event raised when a shipment reaches status.
+func ShipmentEventType(status DeliveryStatus) (EventType, bool) {
+	switch status {
+	case DeliveryShipped:
+		return EventShipmentShipped, true
+	case DeliveryDelayed:
+		return EventShipmentDelayed, true
+	case DeliveryLost:
+		return EventShipmentLost, true
+	case DeliveryDelivered:
+		return EventShipmentDelivered, true
+	case DeliveryReturned:
+		return EventShipmentReturned, true
+	}
+	return "", false
+}
+
+// ShipmentTransitionRequest moves a shipment to a new delivery status.
+type ShipmentTransitionRequest struct {
+	Status DeliveryStatus `json:"deliveryStatus"`
+	Reason string         `json:"reason,omitempty"` // Recorded on the event, e.g. why a shipment is delayed
+}
+
+// Dispatched reports whether the shipment has left, so its label can no longer be voided.
+func (s *Shipment) Dispatched() bool {
+	return NormalizeDeliveryStatus(s.Status) != DeliveryPreparing
+}
+
+// TrackingTransitions returns the statuses a shipment in current passes through as
+// events are applied in the order they occurred. Events that would move it backwards,
+// or repeat its status, are ignored. A shipment still preparing when a later milestone
+// arrives, e.g. a first poll that already reports delivery, passes through shipped on
+// the way so that shipping's side effects are not skipped.
+func TrackingTransitions(current DeliveryStatus, events []TrackingEvent) []DeliveryStatus {
+	sorted := make([]TrackingEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].OccurredAt.Before(sorted[j].OccurredAt) })
+
+	status := NormalizeDeliveryStatus(current)
+	var steps []DeliveryStatus
+	for _, e := range sorted {
+		next, ok := e.Code.DeliveryStatus()
+		if !ok || next == status {
+			continue
+		}
+		if ValidateShipmentTransition(status, next) != nil {
+			if status != DeliveryPreparing || ValidateShipmentTransition(DeliveryShipped, next) != nil {
+				continue
+			}
+			steps = append(steps, DeliveryShipped)
+		}
+		steps = append(steps, next)
+		status = next
+	}
+	return steps
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateShipmentTransition(t *testing.T) {
+	assert.NoError(t, ValidateShipmentTransition("Preparing", DeliveryShipped))
+	assert.NoError(t, ValidateShipmentTransition(DeliveryShipped, DeliveryDelayed))
+	assert.NoError(t, ValidateShipmentTransition(DeliveryDelayed, DeliveryShipped))
+	assert.NoError(t, ValidateShipmentTransition(DeliveryLost, DeliveryDelivered))
+	assert.NoError(t, ValidateShipmentTransition(DeliveryDelivered, DeliveryReturned))
+
+	assert.ErrorIs(t, ValidateShipmentTransition(DeliveryPreparing, DeliveryDelivered), ErrInvalidShipmentTransition)
+	assert.ErrorIs(t, ValidateShipmentTransition(DeliveryDelivered, DeliveryShipped), ErrInvalidShipmentTransition)
+	assert.ErrorIs(t, ValidateShipmentTransition(DeliveryReturned, DeliveryShipped), ErrInvalidShipmentTransition)
+	assert.ErrorIs(t, ValidateShipmentTransition(DeliveryShipped, DeliveryShipped), ErrInvalidShipmentTransition)
+	assert.ErrorIs(t, ValidateShipmentTransition(DeliveryShipped, "Teleported"), ErrInvalidShipmentTransition)
+}
+
+func TestShipment_Dispatched(t *testing.T) {
+	assert.False(t, (&Shipment{Status: "Preparing"}).Dispatched())
+	assert.False(t, (&Shipment{Status: DeliveryPreparing}).Dispatched())
+	assert.True(t, (&Shipment{Status: DeliveryShipped}).Dispatched())
+	assert.True(t, (&Shipment{Status: DeliveryLost}).Dispatched())
+}
@@ -0,0 +1,299 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrStock is returned when a stock movement cannot be recorded, typically because
+// there is not enough stock on hand or out on the reservation to cover it.
+var ErrStock = errors.New("stock error")
+
+// StockMovementKind is the kind of entry in the stock ledger of a fungible item type.
+type StockMovementKind string
+
+const (
+	StockReceipt     StockMovementKind = "receipt"     // Stock arriving at a place
+	StockWriteOff    StockMovementKind = "write_off"   // Stock lost, damaged or expired at a place
+	StockCheckOut    StockMovementKind = "check_out"   // Stock leaving a place on a reservation
+	StockReturn      StockMovementKind = "return"      // Stock coming back from a reservation to a place
+	StockConsumption StockMovementKind = "consumption" // Stock used up on a reservation, never to return
+)
+
+// effect gives the sign a movement of the kind has on stock on hand at its place and
+// on stock out on its reservation.
+func (k StockMovementKind) effect() (onHand, out int) {
+	switch k {
+	case StockReceipt:
+		return 1, 0
+	case StockWriteOff:
+		return -1, 0
+	case StockCheckOut:
+		return -1, 1
+	case StockReturn:
+		return 1, -1
+	case StockConsumption:
+		return 0, -1
+	}
+	return 0, 0
+}
+
+// Valid reports whether the kind is one the ledger records.
+func (k StockMovementKind) Valid() bool {
+	switch k {
+	case StockReceipt, StockWriteOff, StockCheckOut, StockReturn, StockConsumption:
+		return true
+	}
+	return false
+}
+
+// StockLot is a batch of a fungible item type received under one lot number.
+type StockLot struct {
+	ID         int64      `json:"id"`
+	ItemTypeID int64      `json:"itemTypeId"`
+	LotNumber  string     `json:"lotNumber"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	OnHand     int        `json:"onHand"` // Across all places
+}
+
+// Expired reports whether the lot is past its expiry at t.
+func (l *StockLot) Expired(t time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(t)
+}
+
+// StockMovement is an entry in the stock ledger.
+type StockMovement struct {
+	ID              int64             `json:"id"`
+	ItemTypeID      int64             `json:"itemTypeId"`
+	Kind            StockMovementKind `json:"kind"`
+	Quantity        int               `json:"quantity"`
+	PlaceID         *int64            `json:"placeId,omitempty"`
+	LotID           *int64            `json:"lotId,omitempty"`
+	LotNumber       string            `json:"lotNumber,omitempty"`
+	ReservationID   *int64            `json:"reservationId,omitempty"`
+	Note            string            `json:"note,omitempty"`
+	CreatedByUserID *int64            `json:"createdByUserId,omitempty"`
+	CreatedAt       time.Time         `json:"createdAt"`
+}
+
+// StockMovementRequest records stock moving in, out or being used up. Check-outs,
+// write-offs, returns and consumption without a lot are drawn from lots first
+// expiring first, so one request may record several movements.
+type StockMovementRequest struct {
+	Kind          StockMovementKind `json:"kind"`
+	ItemTypeID    int64             `json:"itemTypeId"`
+	Quantity      int               `json:"quantity"`
+	PlaceID       *int64            `json:"placeId,omitempty"`       // Where stock is received, written off, checked out from or returned to
+	ReservationID *int64            `json:"reservationId,omitempty"` // Required to check out, return or consume
+	LotID         *int64            `json:"lotId,omitempty"`         // Draw from this lot only
+	LotNumber     string            `json:"lotNumber,omitempty"`     // Lot a receipt goes into, created if new
+	ExpiresAt     *time.Time        `json:"expiresAt,omitempty"`     // Expiry of a new lot
+	Note          string            `json:"note,omitempty"`
+}
+
+// Validate checks the request carries what its kind needs.
+func (req *StockMovementRequest) Validate() error {
+	if !req.Kind.Valid() {
+		return fmt.Errorf("unknown stock movement kind %q", req.Kind)
+	}
+	if req.ItemTypeID == 0 || req.Quantity <= 0 {
+		return errors.New("itemTypeId and a positive quantity are required")
+	}
+	if req.Kind != StockConsumption && req.PlaceID == nil {
+		return fmt.Errorf("placeId is required for a %s", req.Kind)
+	}
+	switch req.Kind {
+	case StockCheckOut, StockReturn, StockConsumption:
+		if req.ReservationID == nil {
+			return fmt.Errorf("reservationId is required for a %s", req.Kind)
+		}
+	}
+	if req.Kind == StockReceipt {
+		if req.LotID != nil {
+			return errors.New("a receipt names its lot by lotNumber")
+		}
+	} else if req.LotNumber != "" || req.ExpiresAt != nil {
+		return errors.New("lotNumber and expiresAt are only accepted on a receipt")
+	}
+	if req.ExpiresAt != nil && req.LotNumber == "" {
+		return errors.New("expiresAt needs a lotNumber")
+	}
+	return nil
+}
+
+// StockLedgerTotal is the summed quantity of one kind of movement for a place, lot
+// and reservation.
+type StockLedgerTotal struct {
+	Kind          StockMovementKind
+	PlaceID       *int64
+	LotID         *int64
+	ReservationID *int64
+	Quantity      int
+}
+
+// StockLevel is what is on hand at a place, or out on a reservation, from one lot.
+type StockLevel struct {
+	PlaceID       *int64     `json:"placeId,omitempty"`
+	ReservationID *int64     `json:"reservationId,omitempty"`
+	LotID         *int64     `json:"lotId,omitempty"`
+	LotNumber     string     `json:"lotNumber,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	Quantity      int        `json:"quantity"`
+	Expired       bool       `json:"expired,omitempty"`
+}
+
+// StockSummary is where a fungible item type's stock is: on hand at places, out on
+// reservations, and what has left for good.
+type StockSummary struct {
+	ItemTypeID  int64        `json:"itemTypeId"`
+	Owned       int          `json:"owned"`     // On hand and out
+	OnHand      int          `json:"onHand"`    // Including expired lots
+	Expired     int          `json:"expired"`   // On hand but past expiry
+	Available   int          `json:"available"` // On hand and in date
+	Out         int          `json:"out"`
+	Consumed    int          `json:"consumed"`
+	WrittenOff  int          `json:"writtenOff"`
+	Levels      []StockLevel `json:"levels"`      // On hand, per place and lot
+	Outstanding []StockLevel `json:"outstanding"` // Out, per reservation and lot
+}
+
+// BuildStockSummary sums an item type's ledger into levels per place and lot and
+// what is out per reservation and lot.
+func BuildStockSummary(itemTypeID int64, totals []StockLedgerTotal, lots map[int64]StockLot, now time.Time) *StockSummary {
+	s := &StockSummary{ItemTypeID: itemTypeID, Levels: []StockLevel{}, Outstanding: []StockLevel{}}
+
+	type key struct{ owner, lot int64 }
+	onHand := make(map[key]*StockLevel)
+	out := make(map[key]*StockLevel)
+	level := func(m map[key]*StockLevel, owner *int64, lotID *int64, reservation bool) *StockLevel {
+		k := key{}
+		if owner != nil {
+			k.owner = *owner
+		}
+		if lotID != nil {
+			k.lot = *lotID
+		}
+		if l, ok := m[k]; ok {
+			return l
+		}
+		l := &StockLevel{LotID: lotID}
+		if reservation {
+			l.ReservationID = owner
+		} else {
+			l.PlaceID = owner
+		}
+		if lotID != nil {
+			lot := lots[*lotID]
+			l.LotNumber, l.ExpiresAt, l.Expired = lot.LotNumber, lot.ExpiresAt, lot.Expired(now)
+		}
+		m[k] = l
+		return l
+	}
+
+	for _, t := range totals {
+		onHandSign, outSign := t.Kind.effect()
+		if onHandSign != 0 {
+			level(onHand, t.PlaceID, t.LotID, false).Quantity += onHandSign * t.Quantity
+		}
+		if outSign != 0 {
+			level(out, t.ReservationID, t.LotID, true).Quantity += outSign * t.Quantity
+		}
+		switch t.Kind {
+		case StockConsumption:
+			s.Consumed += t.Quantity
+		case StockWriteOff:
+			s.WrittenOff += t.Quantity
+		}
+	}
+
+	for _, l := range onHand {
+		if l.Quantity == 0 {
+			continue
+		}
+		s.OnHand += l.Quantity
+		if l.Expired {
+			s.Expired += l.Quantity
+		}
+		s.Levels = append(s.Levels, *l)
+	}
+	for _, l := range out {
+		if l.Quantity == 0 {
+			continue
+		}
+		s.Out += l.Quantity
+		s.Outstanding = append(s.Outstanding, *l)
+	}
+	s.Available = s.OnHand - s.Expired
+	s.Owned = s.OnHand + s.Out
+	sortLevels(s.Levels)
+	sortLevels(s.Outstanding)
+	return s
+}
+
+// sortLevels orders levels first expiring first, lots without expiry and untracked
+// stock last.
+func sortLevels(levels []StockLevel) {
+	id := func(p *int64) int64 {
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	sort.SliceStable(levels, func(i, j int) bool {
+		a, b := levels[i], levels[j]
+		if (a.ExpiresAt == nil) != (b.ExpiresAt == nil) {
+			return a.ExpiresAt != nil
+		}
+		if a.ExpiresAt != nil && !a.ExpiresAt.Equal(*b.ExpiresAt) {
+			return a.ExpiresAt.Before(*b.ExpiresAt)
+		}
+		if (a.LotID == nil) != (b.LotID == nil) {
+			return a.LotID != nil
+		}
+		if id(a.LotID) != id(b.LotID) {
+			return id(a.LotID) < id(b.LotID)
+		}
+		if id(a.PlaceID) != id(b.PlaceID) {
+			return id(a.PlaceID) < id(b.PlaceID)
+		}
+		return id(a.ReservationID) < id(b.ReservationID)
+	})
+}
+
+// LotDraw is a quantity taken from one lot, or from untracked stock when LotID is nil.
+type LotDraw struct {
+	LotID    *int64
+	Quantity int
+}
+
+// DrawLots takes quantity from levels first expiring first. Expired lots are skipped
+// unless includeExpired is set, as when writing stock off or taking it back.
+func DrawLots(levels []StockLevel, quantity int, includeExpired bool) ([]LotDraw, error) {
+	ordered := append([]StockLevel(nil), levels...)
+	sortLevels(ordered)
+
+	var draws []LotDraw
+	remaining, available := quantity, 0
+	for _, l := range ordered {
+		if l.Quantity <= 0 || (l.Expired && !includeExpired) {
+			continue
+		}
+		available += l.Quantity
+		if remaining == 0 {
+			continue
+		}
+		take := l.Quantity
+		if take > remaining {
+			take = remaining
+		}
+		draws = append(draws, LotDraw{LotID: l.LotID, Quantity: take})
+		remaining -= take
+	}
+	if remaining > 0 {
+		return nil, fmt.Errorf("%w: %d requested but only %d available", ErrStock, quantity, available)
+	}
+	return draws, nil
+}
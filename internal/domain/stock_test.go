@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockMovementRequest_Validate(t *testing.T) {
+	place, reservation, lot := int64(1), int64(2), int64(3)
+	expires := time.Now().AddDate(0, 6, 0)
+
+	valid := []StockMovementRequest{
+		{Kind: StockReceipt, ItemTypeID: 10, Quantity: 500, PlaceID: &place, LotNumber: "L-1", ExpiresAt: &expires},
+		{Kind: StockReceipt, ItemTypeID: 10, Quantity: 500, PlaceID: &place},
+		{Kind: StockCheckOut, ItemTypeID: 10, Quantity: 50, PlaceID: &place, ReservationID: &reservation, LotID: &lot},
+		{Kind: StockReturn, ItemTypeID: 10, Quantity: 20, PlaceID: &place, ReservationID: &reservation},
+		{Kind: StockConsumption, ItemTypeID: 10, Quantity: 30, ReservationID: &reservation},
+		{Kind: StockWriteOff, ItemTypeID: 10, Quantity: 5, PlaceID: &place},
+	}
+	for _, req := range valid {
+		assert.NoError(t, req.Validate(), req.Kind)
+	}
+
+	invalid := []StockMovementRequest{
+		{Kind: "transfer", ItemTypeID: 10, Quantity: 1, PlaceID: &place},
+		{Kind: StockReceipt, ItemTypeID: 10, Quantity: 0, PlaceID: &place},
+		{Kind: StockReceipt, ItemTypeID: 10, Quantity: 1},
+		{Kind: StockReceipt, ItemTypeID: 10, Quantity: 1, PlaceID: &place, ExpiresAt: &expires},
+		{Kind: StockCheckOut, ItemTypeID: 10, Quantity: 1, PlaceID: &place},
+		{Kind: StockConsumption, ItemTypeID: 10, Quantity: 1},
+		{Kind: StockCheckOut, ItemTypeID: 10, Quantity: 1, PlaceID: &place, ReservationID: &reservation, LotNumber: "L-1"},
+	}
+	for _, req := range invalid {
+		assert.Error(t, req.Validate(), req.Kind)
+	}
+}
+
+func TestBuildStockSummary(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	warehouse, van, reservation := int64(1), int64(2), int64(7)
+	fresh, stale := int64(100), int64(101)
+	freshExpiry, staleExpiry := now.AddDate(0, 3, 0), now.AddDate(0, 0, -1)
+	lots := map[int64]StockLot{
+		fresh: {ID: fresh, LotNumber: "FRESH", ExpiresAt: &freshExpiry},
+		stale: {ID: stale, LotNumber: "STALE", ExpiresAt: &staleExpiry},
+	}
+
+	totals := []StockLedgerTotal{
+		{Kind: StockReceipt, PlaceID: &warehouse, LotID: &fresh, Quantity: 200},
+		{Kind: StockReceipt, PlaceID: &warehouse, LotID: &stale, Quantity: 40},
+		{Kind: StockReceipt, PlaceID: &van, Quantity: 25},
+		{Kind: StockCheckOut, PlaceID: &warehouse, LotID: &fresh, ReservationID: &reservation, Quantity: 100},
+		{Kind: StockReturn, PlaceID: &warehouse, LotID: &fresh, ReservationID: &reservation, Quantity: 30},
+		{Kind: StockConsumption, LotID: &fresh, ReservationID: &reservation, Quantity: 50},
+		{Kind: StockWriteOff, PlaceID: &warehouse, LotID: &stale, Quantity: 10},
+	}
+
+	s := BuildStockSummary(10, totals, lots, now)
+	assert.Equal(t, 130+30+25, s.OnHand)
+	assert.Equal(t, 30, s.Expired)
+	assert.Equal(t, 155, s.Available)
+	assert.Equal(t, 20, s.Out)
+	assert.Equal(t, 205, s.Owned)
+	assert.Equal(t, 50, s.Consumed)
+	assert.Equal(t, 10, s.WrittenOff)
+
+	// First expiring first: the expired lot, then the fresh one, then untracked stock
+	if assert.Len(t, s.Levels, 3) {
+		assert.Equal(t, "STALE", s.Levels[0].LotNumber)
+		assert.True(t, s.Levels[0].Expired)
+		assert.Equal(t, "FRESH", s.Levels[1].LotNumber)
+		assert.Equal(t, 130, s.Levels[1].Quantity)
+		assert.Nil(t, s.Levels[2].LotID)
+		assert.Equal(t, &van, s.Levels[2].PlaceID)
+	}
+	if assert.Len(t, s.Outstanding, 1) {
+		assert.Equal(t, &reservation, s.Outstanding[0].ReservationID)
+		assert.Equal(t, 20, s.Outstanding[0].Quantity)
+	}
+}
+
+func TestDrawLots(t *testing.T) {
+	past, soon, later := time.Now().AddDate(0, -1, 0), time.Now().AddDate(0, 1, 0), time.Now().AddDate(1, 0, 0)
+	a, b, c := int64(1), int64(2), int64(3)
+	levels := []StockLevel{
+		{LotID: nil, Quantity: 100},
+		{LotID: &b, ExpiresAt: &later, Quantity: 50},
+		{LotID: &a, ExpiresAt: &soon, Quantity: 30},
+		{LotID: &c, ExpiresAt: &past, Quantity: 40, Expired: true},
+	}
+
+	draws, err := DrawLots(levels, 60, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []LotDraw{{LotID: &a, Quantity: 30}, {LotID: &b, Quantity: 30}}, draws)
+
+	draws, err = DrawLots(levels, 170, false)
+	assert.NoError(t, err)
+	assert.Len(t, draws, 3)
+	assert.Nil(t, draws[2].LotID)
+
+	_, err = DrawLots(levels, 181, false)
+	assert.True(t, errors.Is(err, ErrStock))
+	assert.Contains(t, err.Error(), "only 180 available")
+
+	// Expired stock can still be written off or taken back
+	draws, err = DrawLots(levels, 10, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []LotDraw{{LotID: &c, Quantity: 10}}, draws)
+}
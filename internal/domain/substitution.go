@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrSubstitutionNotAllowed is returned when a substitute is requested for a demand
+// line that no rule (or kit component) allows it on, or whose cap is used up.
+var ErrSubstitutionNotAllowed = errors.New("substitution not allowed")
+
+// SubstitutionRule lets one item type stand in for another when the requested one
+// is short. A two-way rule also works in reverse.
+type SubstitutionRule struct {
+	ID                   int64     `json:"id"`
+	ItemTypeID           int64     `json:"item_type_id"`            // The requested item type
+	SubstituteItemTypeID int64     `json:"substitute_item_type_id"` // The item type that may be used instead
+	TwoWay               bool      `json:"two_way"`
+	Priority             int       `json:"priority"`               // Higher priority substitutes are tried first
+	MaxQuantity          *int      `json:"max_quantity,omitempty"` // Cap on substituted units per demand line; nil is unlimited
+	AutoApply            bool      `json:"auto_apply"`             // Allocation pins substitutes without asking a dispatcher
+	IsActive             bool      `json:"is_active"`
+	Notes                string    `json:"notes,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// SubstituteOption is one item type a requested item type may be replaced with,
+// resolved from a rule (or a kit component, which has no rule).
+type SubstituteOption struct {
+	ItemTypeID  int64
+	RuleID      int64 // Zero for kit component substitutes
+	MaxQuantity int   // Zero is unlimited
+	AutoApply   bool
+}
+
+// SubstitutesFor resolves the active rules that let other item types replace
+// itemTypeID, best first: higher priority, then lower rule ID.
+func SubstitutesFor(itemTypeID int64, rules []SubstitutionRule) []SubstituteOption {
+	sorted := append([]SubstitutionRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	seen := map[int64]bool{itemTypeID: true}
+	var opts []SubstituteOption
+	for _, rule := range sorted {
+		if !rule.IsActive {
+			continue
+		}
+		var sub int64
+		switch {
+		case rule.ItemTypeID == itemTypeID:
+			sub = rule.SubstituteItemTypeID
+		case rule.TwoWay && rule.SubstituteItemTypeID == itemTypeID:
+			sub = rule.ItemTypeID
+		default:
+			continue
+		}
+		if seen[sub] {
+			continue
+		}
+		seen[sub] = true
+		opt := SubstituteOption{ItemTypeID: sub, RuleID: rule.ID, AutoApply: rule.AutoApply}
+		if rule.MaxQuantity != nil {
+			opt.MaxQuantity = *rule.MaxQuantity
+		}
+		opts = append(opts, opt)
+	}
+	return opts
+}
+
+// Substitution records units of a demand line covered by a different item type.
+// Proposed substitutions await a dispatcher and are kept on the demand's metadata;
+// applied ones are the line's holds on assets of the substitute item type.
+type Substitution struct {
+	ItemTypeID           int64      `json:"itemTypeId"` // The item type the demand line asked for
+	SubstituteItemTypeID int64      `json:"substituteItemTypeId"`
+	Quantity             int        `json:"quantity"`
+	RuleID               int64      `json:"ruleId,omitempty"`
+	Applied              bool       `json:"applied"`
+	AppliedAt            *time.Time `json:"appliedAt,omitempty"`
+}
+
+// DemandSubstitutions reads the substitution proposals recorded on a demand's
+// metadata. Applied entries left there by earlier versions are ignored.
+func DemandSubstitutions(metadata json.RawMessage) []Substitution {
+	if len(metadata) == 0 {
+		return nil
+	}
+	var m struct {
+		Substitutions []Substitution `json:"substitutions"`
+	}
+	if err := json.Unmarshal(metadata, &m); err != nil {
+		return nil
+	}
+	var proposals []Substitution
+	for _, s := range m.Substitutions {
+		if !s.Applied {
+			proposals = append(proposals, s)
+		}
+	}
+	return proposals
+}
+
+// MergeSubstitutions folds subs into the proposals recorded on a demand's metadata and
+// returns the new metadata. Applied substitutions are not stored, since their holds
+// already record them, but settle any proposal for the same item type pair; a new
+// proposal replaces the previous one. With clearProposals set, earlier proposals are
+// dropped first, as when the whole reservation is re-evaluated. Other metadata keys
+// are kept as they are.
+func MergeSubstitutions(metadata json.RawMessage, subs []Substitution, clearProposals bool) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if len(metadata) > 0 && string(metadata) != "null" {
+		if err := json.Unmarshal(metadata, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := []Substitution{}
+	if !clearProposals {
+		merged = append(merged, DemandSubstitutions(metadata)...)
+	}
+
+	for _, s := range subs {
+		kept := merged[:0]
+		for _, m := range merged {
+			// Settled by the applied substitution, or superseded by the new proposal
+			if m.ItemTypeID != s.ItemTypeID || m.SubstituteItemTypeID != s.SubstituteItemTypeID {
+				kept = append(kept, m)
+			}
+		}
+		merged = kept
+		if !s.Applied {
+			merged = append(merged, s)
+		}
+	}
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	fields["substitutions"] = raw
+	return json.Marshal(fields)
+}
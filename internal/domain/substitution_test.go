@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstitutesFor(t *testing.T) {
+	two := 2
+	rules := []SubstitutionRule{
+		{ID: 1, ItemTypeID: 10, SubstituteItemTypeID: 11, Priority: 1, IsActive: true},
+		{ID: 2, ItemTypeID: 10, SubstituteItemTypeID: 12, Priority: 5, MaxQuantity: &two, AutoApply: true, IsActive: true},
+		{ID: 3, ItemTypeID: 13, SubstituteItemTypeID: 10, Priority: 3, TwoWay: true, IsActive: true},
+		{ID: 4, ItemTypeID: 14, SubstituteItemTypeID: 10, Priority: 9, IsActive: true}, // one-way the other direction
+		{ID: 5, ItemTypeID: 10, SubstituteItemTypeID: 15, Priority: 9, IsActive: false},
+	}
+
+	assert.Equal(t, []SubstituteOption{
+		{ItemTypeID: 12, RuleID: 2, MaxQuantity: 2, AutoApply: true},
+		{ItemTypeID: 13, RuleID: 3},
+		{ItemTypeID: 11, RuleID: 1},
+	}, SubstitutesFor(10, rules))
+
+	// The two-way rule also lets 10 replace 13; the one-way rule only lets 10 replace 14.
+	assert.Equal(t, []SubstituteOption{{ItemTypeID: 10, RuleID: 3}}, SubstitutesFor(13, rules))
+	assert.Equal(t, []SubstituteOption{{ItemTypeID: 10, RuleID: 4}}, SubstitutesFor(14, rules))
+	assert.Empty(t, SubstitutesFor(11, rules))
+}
+
+func TestMergeSubstitutions(t *testing.T) {
+	meta := json.RawMessage(`{"note":"stage left","substitutions":[{"itemTypeId":10,"substituteItemTypeId":11,"quantity":1,"applied":true},{"itemTypeId":10,"substituteItemTypeId":12,"quantity":2,"applied":false},{"itemTypeId":10,"substituteItemTypeId":13,"quantity":1,"applied":false}]}`)
+
+	// Applied entries are never read back from metadata
+	assert.Equal(t, []Substitution{
+		{ItemTypeID: 10, SubstituteItemTypeID: 12, Quantity: 2},
+		{ItemTypeID: 10, SubstituteItemTypeID: 13, Quantity: 1},
+	}, DemandSubstitutions(meta))
+
+	// Applying 12 settles its proposal without being stored itself
+	out, err := MergeSubstitutions(meta, []Substitution{{ItemTypeID: 10, SubstituteItemTypeID: 12, Quantity: 1, Applied: true}}, false)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"stage left"`, string(mustField(t, out, "note")))
+	assert.Equal(t, []Substitution{{ItemTypeID: 10, SubstituteItemTypeID: 13, Quantity: 1}}, DemandSubstitutions(out))
+	assert.JSONEq(t, `[{"itemTypeId":10,"substituteItemTypeId":13,"quantity":1,"applied":false}]`, string(mustField(t, out, "substitutions")))
+
+	// Re-evaluating drops stale proposals
+	out, err = MergeSubstitutions(meta, []Substitution{{ItemTypeID: 10, SubstituteItemTypeID: 14, Quantity: 1}}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []Substitution{{ItemTypeID: 10, SubstituteItemTypeID: 14, Quantity: 1}}, DemandSubstitutions(out))
+}
+
+func mustField(t *testing.T, raw json.RawMessage, key string) json.RawMessage {
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(raw, &fields))
+	return fields[key]
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// WaitlistStatus tracks a queued reservation through promotion.
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting  WaitlistStatus = "waiting"
+	WaitlistNotified WaitlistStatus = "notified" // Inventory is available; an approver has been told
+	WaitlistPromoted WaitlistStatus = "promoted" // The reservation was confirmed
+	WaitlistRemoved  WaitlistStatus = "removed"  // Withdrawn, cancelled or otherwise no longer pending
+)
+
+// IsOpen reports whether the entry is still in the queue.
+func (s WaitlistStatus) IsOpen() bool {
+	return s == WaitlistWaiting || s == WaitlistNotified
+}
+
+// WaitlistEntry queues a pending reservation behind one item type it is short on.
+// A reservation short on several item types has one entry per item type.
+type WaitlistEntry struct {
+	ID              int64          `json:"id"`
+	ReservationID   int64          `json:"reservationId"`
+	ItemTypeID      int64          `json:"itemTypeId"`
+	Priority        int            `json:"priority"`    // Higher is served first
+	AutoApprove     bool           `json:"autoApprove"` // Confirm automatically instead of notifying an approver
+	Status          WaitlistStatus `json:"status"`
+	Requested       int            `json:"requested"`
+	Available       int            `json:"available"` // Availability when the entry was queued
+	CreatedByUserID *int64         `json:"createdByUserId,omitempty"`
+	NotifiedAt      *time.Time     `json:"notifiedAt,omitempty"`
+	ResolvedAt      *time.Time     `json:"resolvedAt,omitempty"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+}
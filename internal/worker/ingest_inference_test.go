@@ -75,6 +75,15 @@ func (m *MockRepository) UpdateRentalReservation(ctx context.Context, rr *domain
 func (m *MockRepository) UpdateRentalReservationStatus(ctx context.Context, id int64, s domain.RentalReservationStatus) error {
 	return nil
 }
+func (m *MockRepository) ApproveRentalReservation(ctx context.Context, id int64, userID *int64) (*domain.AllocationResult, error) {
+	return nil, nil
+}
+func (m *MockRepository) ModifyRentalReservation(ctx context.Context, id int64, patch *domain.ReservationPatch, userID *int64) (*domain.ReservationChangeResult, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListReservationChanges(ctx context.Context, reservationID int64) ([]domain.ReservationChange, error) {
+	return nil, nil
+}
 func (m *MockRepository) CreateDemand(ctx context.Context, d *domain.Demand) error { return nil }
 func (m *MockRepository) ListDemandsByReservation(ctx context.Context, id int64) ([]domain.Demand, error) {
 	return nil, nil
@@ -206,10 +215,10 @@ func (m *MockRepository) GetSettings(ctx context.Context) (map[string]json.RawMe
 func (m *MockRepository) UpdateSetting(ctx context.Context, k string, v json.RawMessage) error {
 	return nil
 }
-func (m *MockRepository) GetAvailabilityTimeline(ctx context.Context, id int64, s, e time.Time) ([]domain.AvailabilityPoint, error) {
+func (m *MockRepository) GetAvailabilityTimeline(ctx context.Context, id int64, s, e time.Time, g domain.AvailabilityGranularity) ([]domain.AvailabilityPoint, error) {
 	return nil, nil
 }
-func (m *MockRepository) GetShortageAlerts(ctx context.Context) ([]domain.ShortageAlert, error) {
+func (m *MockRepository) GetShortageAlerts(ctx context.Context, g domain.AvailabilityGranularity) ([]domain.ShortageAlert, error) {
 	return nil, nil
 }
 func (m *MockRepository) GetMaintenanceForecast(ctx context.Context) ([]domain.MaintenanceForecast, error) {
@@ -261,6 +270,329 @@ func (m *MockRepository) MarkEventFailed(ctx context.Context, id int64, em strin
 func (m *MockRepository) ListWebhooks(ctx context.Context) ([]domain.WebhookConfig, error) {
 	return nil, nil
 }
+func (m *MockRepository) CreateShowCompany(ctx context.Context, sc *domain.ShowCompany) error {
+	return nil
+}
+func (m *MockRepository) GetShowCompany(ctx context.Context, id int64) (*domain.ShowCompany, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateSeason(ctx context.Context, s *domain.Season) error { return nil }
+func (m *MockRepository) ListSeasonsForCompany(ctx context.Context, id int64) ([]domain.Season, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateRing(ctx context.Context, r *domain.Ring) error { return nil }
+func (m *MockRepository) ListRingsForCompany(ctx context.Context, id int64) ([]domain.Ring, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateShow(ctx context.Context, s *domain.Show) error { return nil }
+func (m *MockRepository) GetShowByID(ctx context.Context, id int64) (*domain.Show, error) {
+	return nil, nil
+}
+func (m *MockRepository) AddRingToShow(ctx context.Context, sr *domain.ShowRing) error { return nil }
+func (m *MockRepository) GetRingsForShow(ctx context.Context, id int64) ([]domain.ShowRing, error) {
+	return nil, nil
+}
+func (m *MockRepository) SetShowRingLoadout(ctx context.Context, id int64, items []domain.RingLoadoutItem) error {
+	return nil
+}
+func (m *MockRepository) CreateScheduledDelivery(ctx context.Context, sd *domain.ScheduledDelivery) error {
+	return nil
+}
+func (m *MockRepository) GetScheduledDeliveryByID(ctx context.Context, id int64) (*domain.ScheduledDelivery, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListScheduledDeliveries(ctx context.Context, eid *int64) ([]domain.ScheduledDelivery, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateScheduledDeliveryItem(ctx context.Context, item *domain.ScheduledDeliveryItem) error {
+	return nil
+}
+func (m *MockRepository) ListScheduledDeliveryItems(ctx context.Context, id int64) ([]domain.ScheduledDeliveryItem, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateShipment(ctx context.Context, s *domain.Shipment) error { return nil }
+func (m *MockRepository) GetShipmentByID(ctx context.Context, id int64) (*domain.Shipment, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListShipments(ctx context.Context, did *int64) ([]domain.Shipment, error) {
+	return nil, nil
+}
+func (m *MockRepository) UpdateShipment(ctx context.Context, s *domain.Shipment) error { return nil }
+func (m *MockRepository) AllocateAssetsToShipment(ctx context.Context, sid int64, ids []int64, aid int64) error {
+	return nil
+}
+func (m *MockRepository) SetShipmentLegs(ctx context.Context, sid int64, legs []domain.ShipmentLeg) (*domain.Shipment, error) {
+	return nil, nil
+}
+func (m *MockRepository) ConsolidateShipment(ctx context.Context, sid int64, rids []int64) (*domain.ConsolidationResult, error) {
+	return nil, nil
+}
+func (m *MockRepository) TransferAssets(ctx context.Context, req *domain.TransferRequest, aid int64) ([]domain.AssetTransfer, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListAssetTransfers(ctx context.Context, rid int64) ([]domain.AssetTransfer, error) {
+	return nil, nil
+}
+func (m *MockRepository) TransitionShipment(ctx context.Context, sid int64, req *domain.ShipmentTransitionRequest) (*domain.Shipment, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetActiveShipmentLabel(ctx context.Context, sid int64) (*domain.ShippingLabel, error) {
+	return nil, nil
+}
+func (m *MockRepository) RecordShipmentLabel(ctx context.Context, l *domain.ShippingLabel) error {
+	return nil
+}
+func (m *MockRepository) VoidShipmentLabel(ctx context.Context, lid int64) error { return nil }
+func (m *MockRepository) ListTrackableShipments(ctx context.Context) ([]domain.Shipment, error) {
+	return nil, nil
+}
+func (m *MockRepository) RecordTrackingEvents(ctx context.Context, sid int64, events []domain.TrackingEvent) (*domain.Shipment, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListTrackingEvents(ctx context.Context, sid int64) ([]domain.TrackingEvent, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreatePickList(ctx context.Context, did int64, req *domain.PickListRequest, uid *int64) (*domain.PickList, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetPickList(ctx context.Context, id int64) (*domain.PickList, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListPickLists(ctx context.Context, did *int64) ([]domain.PickList, error) {
+	return nil, nil
+}
+func (m *MockRepository) ScanPickList(ctx context.Context, id int64, code string, uid *int64) (*domain.PickList, error) {
+	return nil, nil
+}
+func (m *MockRepository) CompletePickList(ctx context.Context, id int64, sid *int64, aid int64) (*domain.PickList, error) {
+	return nil, nil
+}
+func (m *MockRepository) CancelPickList(ctx context.Context, id int64) (*domain.PickList, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateContainer(ctx context.Context, c *domain.Container) error {
+	return nil
+}
+func (m *MockRepository) GetContainer(ctx context.Context, id int64) (*domain.Container, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListContainers(ctx context.Context, pid *int64) ([]domain.Container, error) {
+	return nil, nil
+}
+func (m *MockRepository) AddToContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error) {
+	return nil, nil
+}
+func (m *MockRepository) RemoveFromContainer(ctx context.Context, id int64, req *domain.ContainerContentsRequest) (*domain.Container, error) {
+	return nil, nil
+}
+func (m *MockRepository) CheckOutContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, aid int64) (*domain.Container, error) {
+	return nil, nil
+}
+func (m *MockRepository) ReturnContainer(ctx context.Context, id int64, req *domain.ContainerMoveRequest, aid int64) (*domain.Container, error) {
+	return nil, nil
+}
+func (m *MockRepository) PackShipment(ctx context.Context, sid int64, scan *domain.PackScan, aid int64) (*domain.Manifest, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetShipmentManifest(ctx context.Context, sid int64) (*domain.Manifest, error) {
+	return nil, nil
+}
+func (m *MockRepository) OpenReceivingSession(ctx context.Context, sid int64, req *domain.ReceivingSessionRequest, uid *int64) (*domain.ReceivingSession, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetReceivingSession(ctx context.Context, id int64) (*domain.ReceivingSession, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListReceivingSessions(ctx context.Context, sid int64) ([]domain.ReceivingSession, error) {
+	return nil, nil
+}
+func (m *MockRepository) ScanReceiving(ctx context.Context, id int64, code string, uid *int64) (*domain.ReceivingSession, error) {
+	return nil, nil
+}
+func (m *MockRepository) CloseReceivingSession(ctx context.Context, id int64, aid int64) (*domain.ReceivingSession, error) {
+	return nil, nil
+}
+func (m *MockRepository) PlanDeliveryRoutes(ctx context.Context, req *domain.RoutePlanRequest) (*domain.RoutePlan, error) {
+	return nil, nil
+}
+func (m *MockRepository) RecordStockMovement(ctx context.Context, req *domain.StockMovementRequest, uid *int64) ([]domain.StockMovement, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListStockMovements(ctx context.Context, itid, rid *int64) ([]domain.StockMovement, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetStockSummary(ctx context.Context, itid int64) (*domain.StockSummary, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListStockLots(ctx context.Context, itid int64) ([]domain.StockLot, error) {
+	return nil, nil
+}
+func (m *MockRepository) AllocateReservation(ctx context.Context, rid int64, uid *int64) (*domain.AllocationResult, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListAssetHolds(ctx context.Context, rid int64) ([]domain.AssetHold, error) {
+	return nil, nil
+}
+func (m *MockRepository) ReallocateHold(ctx context.Context, rid, hid int64, aid *int64, uid *int64) (*domain.AssetHold, error) {
+	return nil, nil
+}
+func (m *MockRepository) SwapHolds(ctx context.Context, rid, a, b int64) error         { return nil }
+func (m *MockRepository) ReleaseReservationHolds(ctx context.Context, rid int64) error { return nil }
+func (m *MockRepository) ApplySubstitution(ctx context.Context, rid, did, itemTypeID, subID int64, qty int, uid *int64) (*domain.AllocationResult, error) {
+	return nil, nil
+}
+func (m *MockRepository) CheckReservationAvailability(ctx context.Context, id int64) ([]domain.Shortfall, error) {
+	return nil, nil
+}
+func (m *MockRepository) EnqueueWaitlist(ctx context.Context, reservationID int64, shortfalls []domain.Shortfall, priority int, autoApprove bool, userID *int64) ([]domain.WaitlistEntry, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListWaitlist(ctx context.Context, itemTypeID *int64) ([]domain.WaitlistEntry, error) {
+	return nil, nil
+}
+func (m *MockRepository) UpdateWaitlistPriority(ctx context.Context, id int64, priority int) error {
+	return nil
+}
+func (m *MockRepository) SetWaitlistStatus(ctx context.Context, ids []int64, status domain.WaitlistStatus) error {
+	return nil
+}
+func (m *MockRepository) GetKitAvailableQuantity(ctx context.Context, id int64, s, e time.Time) (int, error) {
+	return 0, nil
+}
+func (m *MockRepository) CreateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error {
+	return nil
+}
+func (m *MockRepository) GetKitTemplate(ctx context.Context, id int64) (*domain.KitTemplate, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListKitTemplates(ctx context.Context) ([]domain.KitTemplate, error) {
+	return nil, nil
+}
+func (m *MockRepository) UpdateKitTemplate(ctx context.Context, kt *domain.KitTemplate) error {
+	return nil
+}
+func (m *MockRepository) DeleteKitTemplate(ctx context.Context, id int64) error { return nil }
+func (m *MockRepository) CheckOutKit(ctx context.Context, rid, did int64, ids []int64, aid int64, from, to *int64) (*domain.KitInstance, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListKitInstances(ctx context.Context, rid int64) ([]domain.KitInstance, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error {
+	return nil
+}
+func (m *MockRepository) GetSubstitutionRule(ctx context.Context, id int64) (*domain.SubstitutionRule, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListSubstitutionRules(ctx context.Context, itemTypeID *int64) ([]domain.SubstitutionRule, error) {
+	return nil, nil
+}
+func (m *MockRepository) UpdateSubstitutionRule(ctx context.Context, rule *domain.SubstitutionRule) error {
+	return nil
+}
+func (m *MockRepository) DeleteSubstitutionRule(ctx context.Context, id int64) error { return nil }
+func (m *MockRepository) TransitionRentalReservation(ctx context.Context, id int64, req *domain.ReservationTransitionRequest, userID *int64) (*domain.ReservationTransitionRecord, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetReservationLifecycle(ctx context.Context, id int64) (*domain.ReservationLifecycle, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateRateCard(ctx context.Context, rc *domain.RateCard) error { return nil }
+func (m *MockRepository) GetRateCard(ctx context.Context, id int64) (*domain.RateCard, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListRateCards(ctx context.Context, itemTypeID *int64) ([]domain.RateCard, error) {
+	return nil, nil
+}
+func (m *MockRepository) UpdateRateCard(ctx context.Context, rc *domain.RateCard) error { return nil }
+func (m *MockRepository) DeleteRateCard(ctx context.Context, id int64) error            { return nil }
+func (m *MockRepository) QuoteReservation(ctx context.Context, rr *domain.RentalReservation, companyID *int64) (*domain.Quote, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetReservationQuote(ctx context.Context, reservationID int64) (*domain.Quote, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateInvoice(ctx context.Context, reservationID int64, req *domain.InvoiceRequest, userID *int64) (*domain.Invoice, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetInvoice(ctx context.Context, id int64) (*domain.Invoice, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListInvoices(ctx context.Context, reservationID int64) ([]domain.Invoice, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListReservationsDueForInvoice(ctx context.Context, before time.Time) ([]int64, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetReservationConflicts(ctx context.Context, id int64, horizon time.Duration) (*domain.ReservationConflicts, error) {
+	return nil, nil
+}
+func (m *MockRepository) FindEarliestSlot(ctx context.Context, req *domain.ScheduleRequest) (*domain.ScheduleResult, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListOverdueAssets(ctx context.Context, now time.Time) ([]domain.OverdueAsset, error) {
+	return nil, nil
+}
+func (m *MockRepository) EscalateOverdueAsset(ctx context.Context, a *domain.OverdueAsset, level int, now time.Time) error {
+	return nil
+}
+func (m *MockRepository) SyncOverdueReservations(ctx context.Context, now time.Time) (int64, int64, error) {
+	return 0, 0, nil
+}
+func (m *MockRepository) CreateClaim(ctx context.Context, c *domain.Claim, userID *int64) error {
+	return nil
+}
+func (m *MockRepository) GetClaim(ctx context.Context, id int64) (*domain.Claim, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListClaims(ctx context.Context, filter domain.ClaimFilter) ([]domain.Claim, error) {
+	return nil, nil
+}
+func (m *MockRepository) AddClaimEvidence(ctx context.Context, ev *domain.ClaimEvidence) error {
+	return nil
+}
+func (m *MockRepository) UpdateClaimStatus(ctx context.Context, id int64, upd *domain.ClaimStatusUpdate, userID *int64) (*domain.Claim, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateCustomerToken(ctx context.Context, t *domain.CustomerToken, hash string) error {
+	return nil
+}
+func (m *MockRepository) ListCustomerTokens(ctx context.Context, companyID *int64) ([]domain.CustomerToken, error) {
+	return nil, nil
+}
+func (m *MockRepository) RevokeCustomerToken(ctx context.Context, id int64) (*domain.CustomerToken, error) {
+	return nil, nil
+}
+func (m *MockRepository) AuthenticateCustomerToken(ctx context.Context, hash string, now time.Time) (*domain.CustomerToken, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListCompanyReservations(ctx context.Context, companyID int64) ([]domain.RentalReservation, error) {
+	return nil, nil
+}
+func (m *MockRepository) GetCompanyReservation(ctx context.Context, companyID, id int64) (*domain.RentalReservation, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListCompanyShipments(ctx context.Context, companyID int64, reservationID *int64) ([]domain.Shipment, error) {
+	return nil, nil
+}
+func (m *MockRepository) CreateReservationSeries(ctx context.Context, s *domain.ReservationSeries) error {
+	return nil
+}
+func (m *MockRepository) GetReservationSeries(ctx context.Context, id int64) (*domain.ReservationSeries, error) {
+	return nil, nil
+}
+func (m *MockRepository) ListReservationSeries(ctx context.Context) ([]domain.ReservationSeries, error) {
+	return nil, nil
+}
+func (m *MockRepository) ApproveReservationSeries(ctx context.Context, id int64, userID *int64) (*domain.SeriesApproval, error) {
+	return nil, nil
+}
+func (m *MockRepository) CheckSeriesAvailability(ctx context.Context, id int64) ([]domain.OccurrenceShortfall, error) {
+	return nil, nil
+}
+func (m *MockRepository) EditReservationSeries(ctx context.Context, id int64, edit *domain.SeriesEdit, userID *int64) (*domain.SeriesEditResult, error) {
+	return nil, nil
+}
 
 func TestIngestWorker_ItemTypeInference(t *testing.T) {
 	repo := new(MockRepository)
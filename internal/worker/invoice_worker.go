@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/db"
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// InvoiceWorker issues partial invoices for long rentals, one per billing period while
+// assets stay out. Final invoices are left to staff once everything is back.
+type InvoiceWorker struct {
+	repo   db.Repository
+	period time.Duration
+}
+
+func NewInvoiceWorker(repo db.Repository, period time.Duration) *InvoiceWorker {
+	return &InvoiceWorker{repo: repo, period: period}
+}
+
+func (w *InvoiceWorker) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.IssuePeriodicInvoices(ctx, time.Now())
+		}
+	}
+}
+
+// IssuePeriodicInvoices invoices every reservation whose last invoice, or first
+// check-out, is a full period old, through now.
+func (w *InvoiceWorker) IssuePeriodicInvoices(ctx context.Context, now time.Time) {
+	ids, err := w.repo.ListReservationsDueForInvoice(ctx, now.Add(-w.period))
+	if err != nil {
+		log.Printf("Failed to list reservations due for invoice: %v", err)
+		return
+	}
+	for _, id := range ids {
+		inv, err := w.repo.CreateInvoice(ctx, id, &domain.InvoiceRequest{Through: &now}, nil)
+		if err != nil {
+			if !errors.Is(err, domain.ErrInvalidInvoice) {
+				log.Printf("Failed to invoice reservation %d: %v", id, err)
+			}
+			continue
+		}
+		if inv != nil {
+			log.Printf("Issued periodic invoice %s for reservation %d", inv.Number, id)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+// invoiceRepo records the calls the invoice worker makes on top of the dummy repository.
+type invoiceRepo struct {
+	MockRepository
+}
+
+func (m *invoiceRepo) ListReservationsDueForInvoice(ctx context.Context, before time.Time) ([]int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *invoiceRepo) CreateInvoice(ctx context.Context, reservationID int64, req *domain.InvoiceRequest, userID *int64) (*domain.Invoice, error) {
+	args := m.Called(ctx, reservationID, req, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Invoice), args.Error(1)
+}
+
+func TestInvoiceWorker_IssuePeriodicInvoices(t *testing.T) {
+	repo := new(invoiceRepo)
+	w := NewInvoiceWorker(repo, 30*24*time.Hour)
+	ctx := context.Background()
+	now := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	repo.On("ListReservationsDueForInvoice", ctx, now.Add(-30*24*time.Hour)).Return([]int64{3, 4}, nil)
+	through := mock.MatchedBy(func(req *domain.InvoiceRequest) bool {
+		return !req.Final && req.Through != nil && req.Through.Equal(now)
+	})
+	repo.On("CreateInvoice", ctx, int64(3), through, (*int64)(nil)).Return(&domain.Invoice{ID: 1, Number: "INV-000001"}, nil)
+	repo.On("CreateInvoice", ctx, int64(4), through, (*int64)(nil)).Return(nil, fmt.Errorf("%w: nothing to bill", domain.ErrInvalidInvoice))
+
+	w.IssuePeriodicInvoices(ctx, now)
+	repo.AssertExpectations(t)
+}
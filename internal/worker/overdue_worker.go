@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/db"
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// OverdueWorker watches for assets kept past their reservation's end time. It flags
+// the reservations as overdue and escalates each outstanding asset once per step.
+type OverdueWorker struct {
+	repo  db.Repository
+	steps []time.Duration
+}
+
+func NewOverdueWorker(repo db.Repository, steps []time.Duration) *OverdueWorker {
+	if len(steps) == 0 {
+		steps = domain.DefaultOverdueEscalations
+	}
+	return &OverdueWorker{repo: repo, steps: steps}
+}
+
+func (w *OverdueWorker) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.CheckOverdue(ctx, time.Now())
+		}
+	}
+}
+
+// CheckOverdue syncs the overdue flag on reservations and escalates every outstanding
+// asset that has passed a step it was not yet escalated for. After downtime only the
+// latest step reached is announced.
+func (w *OverdueWorker) CheckOverdue(ctx context.Context, now time.Time) {
+	marked, cleared, err := w.repo.SyncOverdueReservations(ctx, now)
+	if err != nil {
+		log.Printf("Failed to sync overdue reservations: %v", err)
+	} else if marked > 0 || cleared > 0 {
+		log.Printf("Overdue reservations: %d marked, %d cleared", marked, cleared)
+	}
+
+	assets, err := w.repo.ListOverdueAssets(ctx, now)
+	if err != nil {
+		log.Printf("Failed to list overdue assets: %v", err)
+		return
+	}
+	for i := range assets {
+		a := &assets[i]
+		level := domain.EscalationLevel(w.steps, a.DueAt, now)
+		if level <= a.EscalationLevel {
+			continue
+		}
+		if err := w.repo.EscalateOverdueAsset(ctx, a, level, now); err != nil {
+			log.Printf("Failed to escalate overdue asset %d on reservation %d: %v", a.AssetID, a.ReservationID, err)
+		}
+	}
+}
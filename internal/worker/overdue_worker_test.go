@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+// overdueRepo records the calls the overdue worker makes on top of the dummy repository.
+type overdueRepo struct {
+	MockRepository
+}
+
+func (m *overdueRepo) SyncOverdueReservations(ctx context.Context, now time.Time) (int64, int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *overdueRepo) ListOverdueAssets(ctx context.Context, now time.Time) ([]domain.OverdueAsset, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]domain.OverdueAsset), args.Error(1)
+}
+
+func (m *overdueRepo) EscalateOverdueAsset(ctx context.Context, a *domain.OverdueAsset, level int, now time.Time) error {
+	args := m.Called(ctx, a, level, now)
+	return args.Error(0)
+}
+
+func TestOverdueWorker_CheckOverdue(t *testing.T) {
+	repo := new(overdueRepo)
+	w := NewOverdueWorker(repo, nil)
+	ctx := context.Background()
+	due := time.Date(2026, 5, 1, 17, 0, 0, 0, time.UTC)
+	now := due.Add(26 * time.Hour)
+
+	repo.On("SyncOverdueReservations", ctx, now).Return(int64(2), int64(0), nil)
+	repo.On("ListOverdueAssets", ctx, now).Return([]domain.OverdueAsset{
+		{ReservationID: 1, AssetID: 10, CheckOutID: 100, DueAt: due, EscalationLevel: 1}, // Past +1d, announced +1h
+		{ReservationID: 1, AssetID: 11, CheckOutID: 101, DueAt: due, EscalationLevel: 2}, // Already announced +1d
+		{ReservationID: 2, AssetID: 12, CheckOutID: 102, DueAt: due.Add(25 * time.Hour)}, // Due 1h ago
+		{ReservationID: 3, AssetID: 13, CheckOutID: 103, DueAt: due.Add(26 * time.Hour)}, // Just due
+	}, nil)
+	repo.On("EscalateOverdueAsset", ctx, mock.MatchedBy(func(a *domain.OverdueAsset) bool { return a.CheckOutID == 100 }), 2, now).Return(nil)
+	repo.On("EscalateOverdueAsset", ctx, mock.MatchedBy(func(a *domain.OverdueAsset) bool { return a.CheckOutID == 102 }), 1, now).Return(nil)
+
+	w.CheckOverdue(ctx, now)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "EscalateOverdueAsset", 2)
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/carrier"
+	"github.com/desmond/rental-management-system/internal/db"
+)
+
+// TrackingWorker polls carriers for the shipments still on their way and records
+// what they report, which moves the shipments' delivery status along.
+type TrackingWorker struct {
+	repo     db.Repository
+	carriers *carrier.Registry
+}
+
+func NewTrackingWorker(repo db.Repository, carriers *carrier.Registry) *TrackingWorker {
+	return &TrackingWorker{repo: repo, carriers: carriers}
+}
+
+func (w *TrackingWorker) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.PollTracking(ctx)
+		}
+	}
+}
+
+// PollTracking fetches tracking for every trackable shipment. Shipments whose carrier
+// is not registered are skipped; they can still be updated by posting events.
+func (w *TrackingWorker) PollTracking(ctx context.Context) {
+	shipments, err := w.repo.ListTrackableShipments(ctx)
+	if err != nil {
+		log.Printf("Failed to list trackable shipments: %v", err)
+		return
+	}
+	for _, s := range shipments {
+		c, err := w.carriers.Get(s.Carrier)
+		if err != nil {
+			continue
+		}
+		events, err := c.GetTrackingEvents(ctx, s.TrackingNumber)
+		if err != nil {
+			log.Printf("Failed to fetch tracking for shipment %d (%s %s): %v", s.ID, s.Carrier, s.TrackingNumber, err)
+			continue
+		}
+		if len(events) == 0 {
+			continue
+		}
+		updated, err := w.repo.RecordTrackingEvents(ctx, s.ID, events)
+		if err != nil {
+			log.Printf("Failed to record tracking for shipment %d: %v", s.ID, err)
+			continue
+		}
+		if updated != nil && updated.Status != s.Status {
+			log.Printf("Shipment %d is now %s", s.ID, updated.Status)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/desmond/rental-management-system/internal/carrier"
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+// trackingRepo records the calls the tracking worker makes on top of the dummy repository.
+type trackingRepo struct {
+	MockRepository
+}
+
+func (m *trackingRepo) ListTrackableShipments(ctx context.Context) ([]domain.Shipment, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Shipment), args.Error(1)
+}
+
+func (m *trackingRepo) RecordTrackingEvents(ctx context.Context, shipmentID int64, events []domain.TrackingEvent) (*domain.Shipment, error) {
+	args := m.Called(ctx, shipmentID, events)
+	return args.Get(0).(*domain.Shipment), args.Error(1)
+}
+
+func TestTrackingWorker_PollTracking(t *testing.T) {
+	stub := carrier.NewStubCarrier("stub", "")
+	stub.Push("STUB1", domain.TrackingEvent{Code: domain.TrackingInTransit})
+	carriers := carrier.NewRegistry()
+	carriers.Register("stub", stub)
+
+	repo := new(trackingRepo)
+	w := NewTrackingWorker(repo, carriers)
+	ctx := context.Background()
+
+	repo.On("ListTrackableShipments", ctx).Return([]domain.Shipment{
+		{ID: 1, Carrier: "stub", TrackingNumber: "STUB1", Status: domain.DeliveryPreparing},
+		{ID: 2, Carrier: "stub", TrackingNumber: "STUB2", Status: domain.DeliveryPreparing}, // Nothing reported yet
+		{ID: 3, Carrier: "pigeon", TrackingNumber: "P1", Status: domain.DeliveryShipped},    // Not registered
+	}, nil)
+	repo.On("RecordTrackingEvents", ctx, int64(1), mock.MatchedBy(func(events []domain.TrackingEvent) bool {
+		return len(events) == 1 && events[0].Code == domain.TrackingInTransit && events[0].TrackingNumber == "STUB1"
+	})).Return(&domain.Shipment{ID: 1, Status: domain.DeliveryShipped}, nil)
+
+	w.PollTracking(ctx)
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "RecordTrackingEvents", 1)
+}
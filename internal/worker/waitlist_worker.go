@@ -0,0 +1,224 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/db"
+	"github.com/desmond/rental-management-system/internal/domain"
+)
+
+// WaitlistWorker re-evaluates queued reservations as inventory frees up through
+// returns, cancellations or assets coming back from maintenance.
+type WaitlistWorker struct {
+	repo db.Repository
+}
+
+func NewWaitlistWorker(repo db.Repository) *WaitlistWorker {
+	return &WaitlistWorker{repo: repo}
+}
+
+func (w *WaitlistWorker) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.ProcessQueue(ctx)
+		}
+	}
+}
+
+// ProcessQueue walks the open waitlist in priority order. Auto-approve reservations
+// are confirmed as soon as they fit; the others raise a waitlist.available event for
+// an approver. While a reservation is still short its item types are held back over
+// its window, buffers included, so lower-priority entries for overlapping windows
+// cannot jump the queue. One that fits and awaits an approver holds nothing back;
+// entries behind it are checked against the same inventory and may claim it first.
+// Entries whose reservation has already ended are removed.
+func (w *WaitlistWorker) ProcessQueue(ctx context.Context) {
+	entries, err := w.repo.ListWaitlist(ctx, nil)
+	if err != nil {
+		log.Printf("Failed to list waitlist: %v", err)
+		return
+	}
+
+	var order []int64
+	byReservation := make(map[int64][]domain.WaitlistEntry)
+	for _, e := range entries {
+		if _, ok := byReservation[e.ReservationID]; !ok {
+			order = append(order, e.ReservationID)
+		}
+		byReservation[e.ReservationID] = append(byReservation[e.ReservationID], e)
+	}
+
+	q := &waitlistPass{
+		buffers: make(map[int64]domain.TurnaroundBuffer),
+		held:    make(map[int64][]heldWindow),
+	}
+	for _, reservationID := range order {
+		group := byReservation[reservationID]
+		if err := w.processReservation(ctx, reservationID, group, q); err != nil {
+			log.Printf("Failed to process waitlist for reservation %d: %v", reservationID, err)
+		}
+	}
+}
+
+func (w *WaitlistWorker) processReservation(ctx context.Context, reservationID int64, group []domain.WaitlistEntry, q *waitlistPass) error {
+	rr, err := w.repo.GetRentalReservationByID(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+	if rr == nil || rr.ReservationStatus != domain.ReservationStatusPending {
+		// Approved by hand, cancelled or deleted since it was queued
+		status := domain.WaitlistRemoved
+		if rr != nil && rr.ReservationStatus != domain.ReservationStatusCancelled {
+			status = domain.WaitlistPromoted
+		}
+		return w.repo.SetWaitlistStatus(ctx, waitlistIDs(group, nil), status)
+	}
+	if rr.EndTime.Before(time.Now()) {
+		// The rental window has passed, so it can never be approved
+		return w.repo.SetWaitlistStatus(ctx, waitlistIDs(group, nil), domain.WaitlistRemoved)
+	}
+
+	windows, err := w.occupiedWindows(ctx, q, rr, group)
+	if err != nil {
+		return err
+	}
+	if q.blocked(windows) {
+		return nil
+	}
+
+	autoApprove := false
+	for _, e := range group {
+		autoApprove = autoApprove || e.AutoApprove
+	}
+
+	if autoApprove {
+		_, err := w.repo.ApproveRentalReservation(ctx, reservationID, nil)
+		if errors.Is(err, domain.ErrInsufficientInventory) {
+			q.holdBack(windows)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Once confirmed the reservation counts against inventory itself, so the
+		// entries behind it are evaluated against what is left.
+		if err := w.repo.SetWaitlistStatus(ctx, waitlistIDs(group, nil), domain.WaitlistPromoted); err != nil {
+			return err
+		}
+		return w.emit(ctx, domain.EventWaitlistPromoted, reservationID, itemTypeIDs(group))
+	}
+
+	shortfalls, err := w.repo.CheckReservationAvailability(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+	if len(shortfalls) > 0 {
+		q.holdBack(windows)
+		// Inventory that was offered has gone again; notify afresh next time it frees up.
+		notified := domain.WaitlistNotified
+		return w.repo.SetWaitlistStatus(ctx, waitlistIDs(group, &notified), domain.WaitlistWaiting)
+	}
+
+	waiting := domain.WaitlistWaiting
+	ids := waitlistIDs(group, &waiting)
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := w.repo.SetWaitlistStatus(ctx, ids, domain.WaitlistNotified); err != nil {
+		return err
+	}
+	return w.emit(ctx, domain.EventWaitlistAvailable, reservationID, itemTypeIDs(group))
+}
+
+// waitlistPass is the state of one walk over the waitlist: the turnaround buffers
+// looked up so far and the windows earmarked by higher-priority entries.
+type waitlistPass struct {
+	buffers map[int64]domain.TurnaroundBuffer
+	held    map[int64][]heldWindow
+}
+
+// heldWindow is the span a waitlisted reservation would take units of an item type
+// out of circulation, turnaround buffers included.
+type heldWindow struct {
+	ItemTypeID int64
+	Start      time.Time
+	End        time.Time
+}
+
+// occupiedWindows returns the buffered window the reservation occupies for each of
+// its waitlisted item types.
+func (w *WaitlistWorker) occupiedWindows(ctx context.Context, q *waitlistPass, rr *domain.RentalReservation, group []domain.WaitlistEntry) ([]heldWindow, error) {
+	windows := make([]heldWindow, 0, len(group))
+	for _, e := range group {
+		buf, ok := q.buffers[e.ItemTypeID]
+		if !ok {
+			it, err := w.repo.GetItemTypeByID(ctx, e.ItemTypeID)
+			if err != nil {
+				return nil, err
+			}
+			if it != nil {
+				buf = it.Buffer()
+			}
+			q.buffers[e.ItemTypeID] = buf
+		}
+		start, end := buf.Occupied(rr.StartTime, rr.EndTime, 0)
+		windows = append(windows, heldWindow{ItemTypeID: e.ItemTypeID, Start: start, End: end})
+	}
+	return windows, nil
+}
+
+// blocked reports whether any of the windows overlaps one already earmarked for
+// the same item type.
+func (q *waitlistPass) blocked(windows []heldWindow) bool {
+	for _, w := range windows {
+		for _, h := range q.held[w.ItemTypeID] {
+			if w.Start.Before(h.End) && h.Start.Before(w.End) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q *waitlistPass) holdBack(windows []heldWindow) {
+	for _, w := range windows {
+		q.held[w.ItemTypeID] = append(q.held[w.ItemTypeID], w)
+	}
+}
+
+func (w *WaitlistWorker) emit(ctx context.Context, eventType domain.EventType, reservationID int64, ids []int64) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"reservation_id": reservationID,
+		"item_type_ids":  ids,
+	})
+	return w.repo.AppendEvent(ctx, nil, &domain.OutboxEvent{Type: eventType, Payload: payload})
+}
+
+// waitlistIDs returns the IDs of the entries, optionally only those in one status.
+func waitlistIDs(entries []domain.WaitlistEntry, status *domain.WaitlistStatus) []int64 {
+	var ids []int64
+	for _, e := range entries {
+		if status == nil || e.Status == *status {
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids
+}
+
+func itemTypeIDs(entries []domain.WaitlistEntry) []int64 {
+	ids := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ItemTypeID)
+	}
+	return ids
+}
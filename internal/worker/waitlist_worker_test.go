@@ -0,0 +1,231 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/desmond/rental-management-system/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+// waitlistRepo records the calls the waitlist worker makes on top of the dummy repository.
+type waitlistRepo struct {
+	MockRepository
+	itemTypes map[int64]*domain.ItemType
+}
+
+func (m *waitlistRepo) GetItemTypeByID(ctx context.Context, id int64) (*domain.ItemType, error) {
+	return m.itemTypes[id], nil
+}
+
+func (m *waitlistRepo) ListWaitlist(ctx context.Context, itemTypeID *int64) ([]domain.WaitlistEntry, error) {
+	args := m.Called(ctx, itemTypeID)
+	return args.Get(0).([]domain.WaitlistEntry), args.Error(1)
+}
+
+func (m *waitlistRepo) GetRentalReservationByID(ctx context.Context, id int64) (*domain.RentalReservation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*domain.RentalReservation), args.Error(1)
+}
+
+func (m *waitlistRepo) ApproveRentalReservation(ctx context.Context, id int64, userID *int64) (*domain.AllocationResult, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AllocationResult), args.Error(1)
+}
+
+func (m *waitlistRepo) CheckReservationAvailability(ctx context.Context, id int64) ([]domain.Shortfall, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]domain.Shortfall), args.Error(1)
+}
+
+func (m *waitlistRepo) SetWaitlistStatus(ctx context.Context, ids []int64, status domain.WaitlistStatus) error {
+	args := m.Called(ctx, ids, status)
+	return args.Error(0)
+}
+
+func (m *waitlistRepo) AppendEvent(ctx context.Context, tx *sql.Tx, event *domain.OutboxEvent) error {
+	args := m.Called(ctx, tx, event)
+	return args.Error(0)
+}
+
+func TestWaitlistWorker_ProcessQueue(t *testing.T) {
+	repo := new(waitlistRepo)
+	w := NewWaitlistWorker(repo)
+	ctx := context.Background()
+
+	pending := func(id int64) *domain.RentalReservation {
+		return &domain.RentalReservation{ID: id, ReservationStatus: domain.ReservationStatusPending, EndTime: time.Now().Add(48 * time.Hour)}
+	}
+
+	repo.On("ListWaitlist", ctx, (*int64)(nil)).Return([]domain.WaitlistEntry{
+		{ID: 11, ReservationID: 1, ItemTypeID: 100, Priority: 10, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 21, ReservationID: 2, ItemTypeID: 100, Priority: 5, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 31, ReservationID: 3, ItemTypeID: 200, Priority: 3, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 41, ReservationID: 4, ItemTypeID: 300, Priority: 1, Status: domain.WaitlistWaiting},
+		{ID: 51, ReservationID: 5, ItemTypeID: 400, Priority: 1, Status: domain.WaitlistWaiting},
+	}, nil)
+
+	// 1 is still short, which holds item type 100 back for it
+	repo.On("GetRentalReservationByID", ctx, int64(1)).Return(pending(1), nil)
+	repo.On("ApproveRentalReservation", ctx, int64(1), (*int64)(nil)).
+		Return(nil, &domain.InsufficientInventoryError{Shortfalls: []domain.Shortfall{{ItemTypeID: 100, Requested: 2, Available: 1}}})
+
+	// 2 queues behind 1 on the same item type and must not be approved ahead of it
+	repo.On("GetRentalReservationByID", ctx, int64(2)).Return(pending(2), nil)
+
+	// 3 fits and is auto-approved
+	repo.On("GetRentalReservationByID", ctx, int64(3)).Return(pending(3), nil)
+	repo.On("ApproveRentalReservation", ctx, int64(3), (*int64)(nil)).Return(&domain.AllocationResult{ReservationID: 3}, nil)
+	repo.On("SetWaitlistStatus", ctx, []int64{31}, domain.WaitlistPromoted).Return(nil)
+	repo.On("AppendEvent", ctx, (*sql.Tx)(nil), mock.MatchedBy(func(e *domain.OutboxEvent) bool {
+		return e.Type == domain.EventWaitlistPromoted
+	})).Return(nil).Once()
+
+	// 4 fits but needs an approver
+	repo.On("GetRentalReservationByID", ctx, int64(4)).Return(pending(4), nil)
+	repo.On("CheckReservationAvailability", ctx, int64(4)).Return([]domain.Shortfall(nil), nil)
+	repo.On("SetWaitlistStatus", ctx, []int64{41}, domain.WaitlistNotified).Return(nil)
+	repo.On("AppendEvent", ctx, (*sql.Tx)(nil), mock.MatchedBy(func(e *domain.OutboxEvent) bool {
+		return e.Type == domain.EventWaitlistAvailable
+	})).Return(nil).Once()
+
+	// 5 was cancelled in the meantime
+	repo.On("GetRentalReservationByID", ctx, int64(5)).
+		Return(&domain.RentalReservation{ID: 5, ReservationStatus: domain.ReservationStatusCancelled}, nil)
+	repo.On("SetWaitlistStatus", ctx, []int64{51}, domain.WaitlistRemoved).Return(nil)
+
+	w.ProcessQueue(ctx)
+
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "ApproveRentalReservation", ctx, int64(2), mock.Anything)
+}
+
+func TestWaitlistWorker_ProcessQueue_DeadEntries(t *testing.T) {
+	repo := new(waitlistRepo)
+	w := NewWaitlistWorker(repo)
+	ctx := context.Background()
+
+	repo.On("ListWaitlist", ctx, (*int64)(nil)).Return([]domain.WaitlistEntry{
+		{ID: 11, ReservationID: 1, ItemTypeID: 100, Priority: 10, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 21, ReservationID: 2, ItemTypeID: 200, Priority: 9, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 31, ReservationID: 3, ItemTypeID: 100, Priority: 5, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 41, ReservationID: 4, ItemTypeID: 200, Priority: 5, AutoApprove: true, Status: domain.WaitlistWaiting},
+	}, nil)
+
+	// 1 ended yesterday: it is dropped rather than retried, and does not hold 100 back
+	repo.On("GetRentalReservationByID", ctx, int64(1)).Return(&domain.RentalReservation{
+		ID: 1, ReservationStatus: domain.ReservationStatusPending, EndTime: time.Now().Add(-24 * time.Hour),
+	}, nil)
+	repo.On("SetWaitlistStatus", ctx, []int64{11}, domain.WaitlistRemoved).Return(nil)
+
+	// 2 fails for a reason other than inventory, which does not hold 200 back either
+	future := time.Now().Add(48 * time.Hour)
+	repo.On("GetRentalReservationByID", ctx, int64(2)).Return(&domain.RentalReservation{
+		ID: 2, ReservationStatus: domain.ReservationStatusPending, EndTime: future,
+	}, nil)
+	repo.On("ApproveRentalReservation", ctx, int64(2), (*int64)(nil)).Return(nil, errors.New("connection reset"))
+
+	for _, id := range []int64{3, 4} {
+		repo.On("GetRentalReservationByID", ctx, id).Return(&domain.RentalReservation{
+			ID: id, ReservationStatus: domain.ReservationStatusPending, EndTime: future,
+		}, nil)
+		repo.On("ApproveRentalReservation", ctx, id, (*int64)(nil)).Return(&domain.AllocationResult{ReservationID: id}, nil)
+		repo.On("SetWaitlistStatus", ctx, []int64{id*10 + 1}, domain.WaitlistPromoted).Return(nil)
+	}
+	repo.On("AppendEvent", ctx, (*sql.Tx)(nil), mock.Anything).Return(nil)
+
+	w.ProcessQueue(ctx)
+
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "ApproveRentalReservation", ctx, int64(1), mock.Anything)
+}
+
+func TestWaitlistWorker_ProcessQueue_DisjointWindows(t *testing.T) {
+	repo := &waitlistRepo{itemTypes: map[int64]*domain.ItemType{
+		100: {ID: 100, PreBufferMinutes: 120, PostBufferMinutes: 240},
+	}}
+	w := NewWaitlistWorker(repo)
+	ctx := context.Background()
+
+	june := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Hour)
+	july := june.Add(30 * 24 * time.Hour)
+	window := func(id int64, start time.Time) *domain.RentalReservation {
+		return &domain.RentalReservation{
+			ID: id, ReservationStatus: domain.ReservationStatusPending,
+			StartTime: start, EndTime: start.Add(48 * time.Hour),
+		}
+	}
+
+	repo.On("ListWaitlist", ctx, (*int64)(nil)).Return([]domain.WaitlistEntry{
+		{ID: 11, ReservationID: 1, ItemTypeID: 100, Priority: 10, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 21, ReservationID: 2, ItemTypeID: 100, Priority: 5, AutoApprove: true, Status: domain.WaitlistWaiting},
+		{ID: 31, ReservationID: 3, ItemTypeID: 100, Priority: 3, Status: domain.WaitlistWaiting},
+		{ID: 41, ReservationID: 4, ItemTypeID: 100, Priority: 1, AutoApprove: true, Status: domain.WaitlistWaiting},
+	}, nil)
+
+	// 1 in June can never fit and holds item type 100 back for June only
+	repo.On("GetRentalReservationByID", ctx, int64(1)).Return(window(1, june), nil)
+	repo.On("ApproveRentalReservation", ctx, int64(1), (*int64)(nil)).
+		Return(nil, &domain.InsufficientInventoryError{Shortfalls: []domain.Shortfall{{ItemTypeID: 100, Requested: 5, Available: 1}}})
+
+	// 2 in July does not overlap and is auto-approved
+	repo.On("GetRentalReservationByID", ctx, int64(2)).Return(window(2, july), nil)
+	repo.On("ApproveRentalReservation", ctx, int64(2), (*int64)(nil)).Return(&domain.AllocationResult{ReservationID: 2}, nil)
+	repo.On("SetWaitlistStatus", ctx, []int64{21}, domain.WaitlistPromoted).Return(nil)
+	repo.On("AppendEvent", ctx, (*sql.Tx)(nil), mock.MatchedBy(func(e *domain.OutboxEvent) bool {
+		return e.Type == domain.EventWaitlistPromoted
+	})).Return(nil).Once()
+
+	// 3 starts right after 1 ends but inside its post buffer, so it stays behind 1
+	repo.On("GetRentalReservationByID", ctx, int64(3)).Return(window(3, june.Add(50*time.Hour)), nil)
+
+	// 4 starts a week after 1, clear of its buffers, and is auto-approved
+	repo.On("GetRentalReservationByID", ctx, int64(4)).Return(window(4, june.Add(7*24*time.Hour)), nil)
+	repo.On("ApproveRentalReservation", ctx, int64(4), (*int64)(nil)).Return(&domain.AllocationResult{ReservationID: 4}, nil)
+	repo.On("SetWaitlistStatus", ctx, []int64{41}, domain.WaitlistPromoted).Return(nil)
+	repo.On("AppendEvent", ctx, (*sql.Tx)(nil), mock.MatchedBy(func(e *domain.OutboxEvent) bool {
+		return e.Type == domain.EventWaitlistPromoted
+	})).Return(nil).Once()
+
+	w.ProcessQueue(ctx)
+
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "CheckReservationAvailability", ctx, int64(3))
+}
+
+func TestWaitlistWorker_ProcessQueue_NotifiedDoesNotBlock(t *testing.T) {
+	repo := new(waitlistRepo)
+	w := NewWaitlistWorker(repo)
+	ctx := context.Background()
+
+	pending := func(id int64) *domain.RentalReservation {
+		return &domain.RentalReservation{ID: id, ReservationStatus: domain.ReservationStatusPending, EndTime: time.Now().Add(48 * time.Hour)}
+	}
+
+	repo.On("ListWaitlist", ctx, (*int64)(nil)).Return([]domain.WaitlistEntry{
+		{ID: 11, ReservationID: 1, ItemTypeID: 100, Priority: 10, Status: domain.WaitlistNotified},
+		{ID: 21, ReservationID: 2, ItemTypeID: 100, Priority: 5, AutoApprove: true, Status: domain.WaitlistWaiting},
+	}, nil)
+
+	// 1 fits and was already offered to an approver who has not acted yet
+	repo.On("GetRentalReservationByID", ctx, int64(1)).Return(pending(1), nil)
+	repo.On("CheckReservationAvailability", ctx, int64(1)).Return([]domain.Shortfall(nil), nil)
+
+	// 2 overlaps on the same item type but inventory covers it as well
+	repo.On("GetRentalReservationByID", ctx, int64(2)).Return(pending(2), nil)
+	repo.On("ApproveRentalReservation", ctx, int64(2), (*int64)(nil)).Return(&domain.AllocationResult{ReservationID: 2}, nil)
+	repo.On("SetWaitlistStatus", ctx, []int64{21}, domain.WaitlistPromoted).Return(nil)
+	repo.On("AppendEvent", ctx, (*sql.Tx)(nil), mock.MatchedBy(func(e *domain.OutboxEvent) bool {
+		return e.Type == domain.EventWaitlistPromoted
+	})).Return(nil).Once()
+
+	w.ProcessQueue(ctx)
+
+	repo.AssertExpectations(t)
+}